@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"nofx/logger"
 	"nofx/manager"
+	"nofx/market"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -68,6 +71,10 @@ func (s *Server) setupRoutes() {
 		// Trader列表
 		api.GET("/traders", s.handleTraderList)
 
+		// 行情数据缓存命中统计和Binance请求权重限流统计
+		api.GET("/market/cache", s.handleMarketCache)
+		api.GET("/market/ratelimit", s.handleRateLimit)
+
 		// 指定trader的数据（使用query参数 ?trader_id=xxx）
 		api.GET("/status", s.handleStatus)
 		api.GET("/account", s.handleAccount)
@@ -77,6 +84,9 @@ func (s *Server) setupRoutes() {
 		api.GET("/statistics", s.handleStatistics)
 		api.GET("/equity-history", s.handleEquityHistory)
 		api.GET("/performance", s.handlePerformance)
+		api.GET("/calibration", s.handleCalibration)
+		api.GET("/candidates", s.handleCandidates)
+		api.GET("/ai-archive", s.handleAIArchive)
 	}
 }
 
@@ -401,6 +411,140 @@ func (s *Server) handlePerformance(c *gin.Context) {
 	c.JSON(http.StatusOK, performance)
 }
 
+// calibrationLookbackCycles 置信度校准的分析周期数（比表现分析更长，保证每个区间有足够样本）
+const calibrationLookbackCycles = 1000
+
+// handleCalibration 置信度校准（AI置信度区间 vs 实际胜率）
+func (s *Server) handleCalibration(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	performance, err := trader.GetDecisionLogger().AnalyzePerformance(calibrationLookbackCycles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("分析置信度校准失败: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total_trades": performance.TotalTrades,
+		"buckets":      performance.Calibration,
+	})
+}
+
+// 候选池历史查询的周期数（默认约1天的3分钟周期）
+const (
+	defaultCandidateCycles = 480
+	maxCandidateCycles     = 5000
+)
+
+// handleCandidates 候选池历史：不带symbol时返回最近一个周期的候选池，带symbol时返回该币种在最近cycles个周期是否进入候选池
+func (s *Server) handleCandidates(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	symbol := c.Query("symbol")
+	if symbol == "" {
+		records, err := trader.GetDecisionLogger().GetLatestRecords(1)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": fmt.Sprintf("获取决策日志失败: %v", err),
+			})
+			return
+		}
+		if len(records) == 0 {
+			c.JSON(http.StatusOK, gin.H{"candidates": []logger.CandidateSnapshot{}})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"cycle_number":    records[0].CycleNumber,
+			"timestamp":       records[0].Timestamp,
+			"candidate_coins": records[0].CandidateCoins,
+			"candidates":      records[0].CandidatePool,
+		})
+		return
+	}
+
+	cycles := defaultCandidateCycles
+	if v := c.Query("cycles"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxCandidateCycles {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cycles必须是1-%d之间的整数", maxCandidateCycles)})
+			return
+		}
+		cycles = n
+	}
+
+	history, err := trader.GetDecisionLogger().GetCandidateHistory(market.Normalize(symbol), cycles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("获取候选池历史失败: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// handleAIArchive 指定周期的AI请求和原始响应（周期ID见决策日志的cycle_id）
+func (s *Server) handleAIArchive(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	cycleID := c.Query("cycle_id")
+	if cycleID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少cycle_id参数"})
+		return
+	}
+
+	entries, err := trader.GetAIArchive(cycleID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"cycle_id": cycleID, "calls": entries})
+}
+
+// handleMarketCache 行情数据缓存命中统计
+func (s *Server) handleMarketCache(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"stats":         market.CacheStats(),
+		"kline_buffers": market.KlineBufferStats(),
+	})
+}
+
+// handleRateLimit Binance请求权重限流统计
+func (s *Server) handleRateLimit(c *gin.Context) {
+	c.JSON(http.StatusOK, market.RateLimitStats())
+}
+
 // Start 启动服务器
 func (s *Server) Start() error {
 	addr := fmt.Sprintf(":%d", s.port)
@@ -416,6 +560,8 @@ func (s *Server) Start() error {
 	log.Printf("  • GET  /api/statistics?trader_id=xxx - 指定trader的统计信息")
 	log.Printf("  • GET  /api/equity-history?trader_id=xxx - 指定trader的收益率历史数据")
 	log.Printf("  • GET  /api/performance?trader_id=xxx - 指定trader的AI学习表现分析")
+	log.Printf("  • GET  /api/candidates?trader_id=xxx&symbol=XXX - 候选池历史（币种是否被考虑过）")
+	log.Printf("  • GET  /api/ai-archive?trader_id=xxx&cycle_id=xxx - 指定周期的AI请求/原始响应归档")
 	log.Printf("  • GET  /health               - 健康检查")
 	log.Println()
 
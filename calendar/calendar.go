@@ -0,0 +1,173 @@
+package calendar
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认设置
+const defaultLookahead = 24 * time.Hour // 提示词中展示该时间内的事件
+
+// 事件影响等级（只有高影响事件触发禁止开仓窗口）
+const (
+	ImpactHigh   = "high"
+	ImpactMedium = "medium"
+	ImpactLow    = "low"
+)
+
+// Event 经济日历事件（FOMC、CPI、代币解锁等）
+type Event struct {
+	Title   string    `json:"title"`
+	Time    time.Time `json:"time"`
+	Impact  string    `json:"impact"`            // high / medium / low
+	Symbols []string  `json:"symbols,omitempty"` // 只影响这些币种（如代币解锁），为空表示影响全市场
+
+	BlackoutStart time.Time `json:"blackout_start,omitempty"` // 禁止开仓窗口（未启用或非高影响事件时为零值）
+	BlackoutEnd   time.Time `json:"blackout_end,omitempty"`
+}
+
+// Affects 事件是否影响该币种
+func (e *Event) Affects(symbol string) bool {
+	if len(e.Symbols) == 0 {
+		return true
+	}
+	for _, s := range e.Symbols {
+		if strings.EqualFold(s, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// InBlackout now是否处于该事件的禁止开仓窗口
+func (e *Event) InBlackout(now time.Time) bool {
+	return !e.BlackoutStart.IsZero() && !now.Before(e.BlackoutStart) && now.Before(e.BlackoutEnd)
+}
+
+var (
+	mu             sync.Mutex
+	file           string
+	fileModTime    time.Time
+	events         []Event
+	lookahead      = defaultLookahead
+	blackoutBefore time.Duration
+	blackoutAfter  time.Duration
+)
+
+// Load 加载日历文件并设置展示范围和禁止开仓窗口（lookaheadHours为0时使用默认值，before/after都为0表示不启用禁止开仓窗口）。
+// 文件修改后下次读取时自动重新加载
+func Load(path string, lookaheadHours, beforeMinutes, afterMinutes int) error {
+	mu.Lock()
+	defer mu.Unlock()
+	file = path
+	lookahead = defaultLookahead
+	if lookaheadHours > 0 {
+		lookahead = time.Duration(lookaheadHours) * time.Hour
+	}
+	blackoutBefore = time.Duration(beforeMinutes) * time.Minute
+	blackoutAfter = time.Duration(afterMinutes) * time.Minute
+	return reloadLocked(true)
+}
+
+// Enabled 是否加载了日历文件
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return file != ""
+}
+
+// reloadLocked 文件有修改时重新加载（调用方需持有mu）
+func reloadLocked(force bool) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return fmt.Errorf("读取日历文件失败: %w", err)
+	}
+	if !force && info.ModTime().Equal(fileModTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("读取日历文件失败: %w", err)
+	}
+	var loaded []Event
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("解析日历文件失败: %w", err)
+	}
+	for i, e := range loaded {
+		if e.Title == "" || e.Time.IsZero() {
+			return fmt.Errorf("第%d个事件缺少title或time", i+1)
+		}
+		switch e.Impact {
+		case ImpactHigh, ImpactMedium, ImpactLow:
+		default:
+			return fmt.Errorf("事件 %q 的impact必须是 high/medium/low", e.Title)
+		}
+	}
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].Time.Before(loaded[j].Time) })
+
+	events = loaded
+	fileModTime = info.ModTime()
+	return nil
+}
+
+// Upcoming 即将发生的事件（展示范围内，以及禁止开仓窗口尚未结束的已发生事件），按时间升序。
+// 未加载日历时返回nil
+func Upcoming(now time.Time) ([]Event, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if file == "" {
+		return nil, nil
+	}
+	if err := reloadLocked(false); err != nil {
+		return nil, err
+	}
+
+	var result []Event
+	for _, e := range events {
+		if e.Impact == ImpactHigh && (blackoutBefore > 0 || blackoutAfter > 0) {
+			e.BlackoutStart = e.Time.Add(-blackoutBefore)
+			e.BlackoutEnd = e.Time.Add(blackoutAfter)
+		}
+		if (e.Time.After(now.Add(lookahead)) || e.Time.Before(now)) && !e.InBlackout(now) {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// Format 事件描述（按时间升序，带距now的时长和禁止开仓标记）
+func Format(upcoming []Event, now time.Time) string {
+	var sb strings.Builder
+	for _, e := range upcoming {
+		when := "in " + formatDuration(e.Time.Sub(now))
+		if !e.Time.After(now) {
+			when = formatDuration(now.Sub(e.Time)) + " ago"
+		}
+		sb.WriteString(fmt.Sprintf("- %s UTC (%s): %s [%s impact]", e.Time.UTC().Format("01-02 15:04"), when, e.Title, e.Impact))
+		if len(e.Symbols) > 0 {
+			sb.WriteString(" - " + strings.Join(e.Symbols, ", "))
+		}
+		if e.InBlackout(now) {
+			sb.WriteString(fmt.Sprintf(" ⛔ no new positions until %s UTC", e.BlackoutEnd.UTC().Format("15:04")))
+		} else if !e.BlackoutStart.IsZero() {
+			sb.WriteString(fmt.Sprintf(" (no new positions %s-%s UTC)", e.BlackoutStart.UTC().Format("15:04"), e.BlackoutEnd.UTC().Format("15:04")))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// formatDuration 时长简写（分钟/小时）
+func formatDuration(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%.1fh", d.Hours())
+}
@@ -3,7 +3,11 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"nofx/market"
+	"nofx/pool"
+	"nofx/proxy"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -11,16 +15,26 @@ import (
 type TraderConfig struct {
 	ID      string `json:"id"`
 	Name    string `json:"name"`
-	Enabled bool   `json:"enabled"` // 是否启用该trader
-	AIModel string `json:"ai_model"` // "qwen" or "deepseek"
+	Enabled bool   `json:"enabled"`  // 是否启用该trader
+	AIModel string `json:"ai_model"` // "qwen", "deepseek", "openai", "anthropic", "gemini", "azure", "openrouter", "local", "custom" or "mock"
 
 	// 交易平台选择（二选一）
-	Exchange string `json:"exchange"` // "binance" or "hyperliquid"
+	Exchange string `json:"exchange"`          // "binance", "bybit", "okx", "hyperliquid", "aster" or "paper"
+	Testnet  bool   `json:"testnet,omitempty"` // 下单使用交易所的合约测试网（OKX为模拟盘），需填写测试网单独申请的API Key
 
 	// 币安配置
 	BinanceAPIKey    string `json:"binance_api_key,omitempty"`
 	BinanceSecretKey string `json:"binance_secret_key,omitempty"`
 
+	// Bybit配置（统一账户API Key，需要合约交易权限）
+	BybitAPIKey    string `json:"bybit_api_key,omitempty"`
+	BybitSecretKey string `json:"bybit_secret_key,omitempty"`
+
+	// OKX配置（API Key需要交易权限，passphrase为创建API Key时设置的密码）
+	OKXAPIKey     string `json:"okx_api_key,omitempty"`
+	OKXSecretKey  string `json:"okx_secret_key,omitempty"`
+	OKXPassphrase string `json:"okx_passphrase,omitempty"`
+
 	// Hyperliquid配置
 	HyperliquidPrivateKey string `json:"hyperliquid_private_key,omitempty"`
 	HyperliquidWalletAddr string `json:"hyperliquid_wallet_addr,omitempty"`
@@ -32,36 +46,359 @@ type TraderConfig struct {
 	AsterPrivateKey string `json:"aster_private_key,omitempty"` // Aster API钱包私钥
 
 	// AI配置
-	QwenKey     string `json:"qwen_key,omitempty"`
-	DeepSeekKey string `json:"deepseek_key,omitempty"`
+	QwenKey      string `json:"qwen_key,omitempty"`
+	DeepSeekKey  string `json:"deepseek_key,omitempty"`
+	OpenAIKey    string `json:"openai_key,omitempty"`
+	AnthropicKey string `json:"anthropic_key,omitempty"`
+	GeminiKey    string `json:"gemini_key,omitempty"`
+	ModelName    string `json:"model_name,omitempty"` // 覆盖内置提供商的默认模型（如"gpt-4o-mini"、"claude-opus-4-1"），local必填（如"qwen2.5:14b"）
+
+	// 本地模型配置（Ollama或vLLM、LM Studio等OpenAI兼容服务）
+	LocalAPIURL string `json:"local_api_url,omitempty"` // 服务地址（默认Ollama的http://localhost:11434/v1）
+	LocalAPIKey string `json:"local_api_key,omitempty"` // 服务启用了密钥时填写（可选）
+
+	// Azure OpenAI配置（ai_model为azure时按部署名称调用）
+	AzureEndpoint   string `json:"azure_endpoint,omitempty"`    // 资源地址（如https://my-resource.openai.azure.com）
+	AzureAPIKey     string `json:"azure_api_key,omitempty"`     // 资源密钥（api-key请求头）
+	AzureDeployment string `json:"azure_deployment,omitempty"`  // 部署名称
+	AzureAPIVersion string `json:"azure_api_version,omitempty"` // API版本（默认2024-10-21）
+
+	// OpenRouter配置（ai_model为openrouter时使用，model_name为OpenRouter模型ID）
+	OpenRouterKey string            `json:"openrouter_key,omitempty"`
+	OpenRouter    *OpenRouterConfig `json:"openrouter,omitempty"` // 模型路由选项（可选）
+
+	// 模拟AI配置（ai_model为mock时按脚本返回响应，不调用真实API）
+	MockResponsesFile string `json:"mock_responses_file,omitempty"` // 响应脚本（JSON数组，留空时始终返回空决策）
 
 	// 自定义AI API配置（支持任何OpenAI格式的API）
-	CustomAPIURL    string `json:"custom_api_url,omitempty"`
-	CustomAPIKey    string `json:"custom_api_key,omitempty"`
-	CustomModelName string `json:"custom_model_name,omitempty"`
+	CustomAPIURL     string `json:"custom_api_url,omitempty"`
+	CustomAPIKey     string `json:"custom_api_key,omitempty"`
+	CustomModelName  string `json:"custom_model_name,omitempty"`
+	StructuredOutput bool   `json:"structured_output,omitempty"` // 使用json_schema结构化输出（需API支持，openai或OpenAI兼容的自定义API）
 
 	InitialBalance      float64 `json:"initial_balance"`
 	ScanIntervalMinutes int     `json:"scan_interval_minutes"`
+
+	// 策略配置
+	PromptTemplateFile   string  `json:"prompt_template_file,omitempty"`   // 自定义system prompt模板文件（Go text/template语法，留空使用内置规则）
+	ValidationRetries    int     `json:"validation_retries,omitempty"`     // 决策校验失败后让AI修正的最大轮数（默认2，-1表示禁用）
+	MinRiskReward        float64 `json:"min_risk_reward,omitempty"`        // 开仓最小风险回报比（默认3.0，即1:3）
+	MaxPositions         int     `json:"max_positions,omitempty"`          // 最大同时持仓数量（默认3）
+	DecisionMemory       int     `json:"decision_memory,omitempty"`        // 提示词中展示的最近决策数量（默认10，-1表示禁用）
+	InvalidationAction   string  `json:"invalidation_action,omitempty"`    // invalidation_rule触发时："close"（默认，自动平仓）或 "flag"（仅提示AI）
+	TradeCooldownMinutes int     `json:"trade_cooldown_minutes,omitempty"` // 平仓后同一币种重新开仓的冷却时间（分钟，默认15，-1表示禁用）
+	PromptTokenBudget    int     `json:"prompt_token_budget,omitempty"`    // 用户prompt的token预算（超出时截断序列、按评分裁剪候选币种，0表示不限制）
+	HedgeMode            bool    `json:"hedge_mode,omitempty"`             // 双向持仓模式：允许同一币种同时持有多仓和空仓（仅币安支持）
+	FearGreed            bool    `json:"fear_greed,omitempty"`             // 在提示词中加入恐惧贪婪指数（含7天历史，作为反向情绪指标）
+	DepegThresholdPct    float64 `json:"depeg_threshold_pct,omitempty"`    // USDT/USDC偏离1美元超过该百分比时视为脱锚（默认0.5）
+	PauseOnDepeg         bool    `json:"pause_on_depeg,omitempty"`         // 稳定币脱锚期间禁止开新仓
+	MaxSectorPositions   int     `json:"max_sector_positions,omitempty"`   // 同一板块（L1、meme、AI、DeFi等）最大同时持仓数量（0表示不限制）
+	MaxSpreadPct         float64 `json:"max_spread_pct,omitempty"`         // 候选币种最大买卖价差（百分比，默认0.1，-1表示禁用）
+	MinDepthMultiple     float64 `json:"min_depth_multiple,omitempty"`     // 候选币种中间价±0.5%内较薄一侧的深度至少为最大仓位价值的N倍（默认1，-1表示禁用）
+
+	// 多模型集成投票（可选）
+	Ensemble *EnsembleConfig `json:"ensemble,omitempty"`
+
+	// Kelly仓位计算（可选）
+	PositionSizing *PositionSizingConfig `json:"position_sizing,omitempty"`
+
+	// 额外K线周期（可选）："default"适用于所有币种，币种名称的条目覆盖default，如 {"default": ["15m", "1h"], "BTCUSDT": ["1m", "15m", "1h", "1d"]}
+	Timeframes map[string][]string `json:"timeframes,omitempty"`
+
+	// 可选指标（可选）：在所有周期的序列中额外计算，如 ["obv", "stochastic", "ichimoku"]
+	Indicators []string `json:"indicators,omitempty"`
+
+	// 行情数据格式（可选，按币种层级选择完整或紧凑格式）
+	MarketDataFormat *MarketDataFormatConfig `json:"market_data_format,omitempty"`
+
+	// 风控审核模型（可选，通常使用更便宜的模型逐条审核决策，可否决）
+	RiskOfficer *AIModelConfig `json:"risk_officer,omitempty"`
+
+	// 主模型故障切换链（可选，超时、429或5xx时按顺序改用下一个模型）
+	AIFallbacks []AIModelConfig `json:"ai_fallbacks,omitempty"`
+
+	// 按风险路由模型（可选，没有持仓、挂单和高分候选币种的周期改用便宜的模型）
+	ModelRouting *ModelRoutingConfig `json:"model_routing,omitempty"`
+
+	// 每个AI模型每分钟最多请求数（可选，0表示不限制；限流和过载响应会指数退避重试）
+	AIRequestsPerMinute int `json:"ai_requests_per_minute,omitempty"`
+
+	// AI调用的重试和熔断策略（可选，未配置的字段使用默认值：最多4次尝试，2-30秒抖动退避，连续5次失败熔断5分钟）
+	AIRetry *AIRetryConfig `json:"ai_retry,omitempty"`
+
+	// 主模型使用流式输出（提供商支持时边生成边输出思维链，决策数组完整后立即解析）
+	AIStream bool `json:"ai_stream,omitempty"`
+
+	// AI可在推理过程中调用工具（订单簿、K线、完整行情）的最大轮数（0表示不启用，需提供商支持原生工具调用）
+	AIToolRounds int `json:"ai_tool_rounds,omitempty"`
+
+	// K线图：把持仓和候选币种的K线图（PNG）随prompt发送给支持视觉输入的模型（可选）
+	ChartImages *ChartImagesConfig `json:"chart_images,omitempty"`
+
+	// 归档每次AI请求和原始响应（含耗时和token用量）的保留天数，按周期ID查询，用于复盘亏损交易（0表示不归档）
+	AIArchiveDays int `json:"ai_archive_days,omitempty"`
+
+	// AI生成参数（可选，作用于本trader的所有模型，ensemble、risk_officer、ai_fallbacks中的模型可用params单独覆盖）
+	AIParams *AIParamsConfig `json:"ai_params,omitempty"`
+
+	// 交易成本模型（可选，开仓前检查止盈扣除手续费、滑点和资金费后是否仍有足够期望收益）
+	CostModel *CostModelConfig `json:"cost_model,omitempty"`
+
+	// 模拟交易参数（可选，仅exchange为paper时生效，未配置的字段使用默认值）
+	Paper *PaperConfig `json:"paper,omitempty"`
+
+	// 移动止损管理（可选）：AI设置的移动止损改用交易所原生移动止损单，或按规则为持仓自动跟踪止损
+	TrailingStop *TrailingStopConfig `json:"trailing_stop,omitempty"`
+
+	// 市价开仓的执行方式（可选）：chase先用只做maker的限价单在盘口追价，节省taker手续费
+	EntryExecution *EntryExecutionConfig `json:"entry_execution,omitempty"`
+}
+
+// PaperConfig 模拟交易配置（用实时行情撮合，不动用真实资金）
+type PaperConfig struct {
+	TakerFeePct float64 `json:"taker_fee_pct,omitempty"` // 市价成交手续费率（%，默认0.05）
+	MakerFeePct float64 `json:"maker_fee_pct,omitempty"` // 限价挂单成交手续费率（%，默认0.02）
+	SlippagePct float64 `json:"slippage_pct,omitempty"`  // 市价成交滑点（%，默认0.02）
+}
+
+// TrailingStopConfig 移动止损配置
+type TrailingStopConfig struct {
+	Mode        string  `json:"mode,omitempty"`         // AI设置的移动止损："local"（默认，本地跟踪最优价并更新止损单）或 "exchange"（交易所原生移动止损单，仅币安）
+	ActivationR float64 `json:"activation_r,omitempty"` // 规则跟踪：浮盈达到N倍初始风险（入场价到止损价的距离）后激活（默认2）
+	ATRMultiple float64 `json:"atr_multiple,omitempty"` // 规则跟踪：止损与最优价的距离为N倍ATR（0表示不启用规则）
+	ATRInterval string  `json:"atr_interval,omitempty"` // 规则跟踪使用的ATR周期（默认1h）
+}
+
+// EntryExecutionConfig 开仓执行配置
+type EntryExecutionConfig struct {
+	Mode           string `json:"mode,omitempty"`            // "market"（默认，直接市价成交）或 "chase"（只做maker的限价单挂在买一/卖一，追价后剩余部分市价成交）
+	MaxReprices    int    `json:"max_reprices,omitempty"`    // chase：首次挂单后最多改价次数（默认3）
+	RepriceSeconds int    `json:"reprice_seconds,omitempty"` // chase：每次挂单等待成交的秒数（默认10）
+
+	// 大仓位拆单（两项比例都为0时不拆单）：单笔超过上限时分成多笔定时成交
+	MaxVolumeFraction float64 `json:"max_volume_fraction,omitempty"` // 单笔不超过近15分钟平均1分钟成交额的比例（如0.05）
+	MaxDepthFraction  float64 `json:"max_depth_fraction,omitempty"`  // 单笔不超过吃单一侧±0.5%盘口深度的比例（如0.2）
+	SliceSeconds      int     `json:"slice_seconds,omitempty"`       // 两笔之间的间隔秒数（默认30）
+	MaxSlices         int     `json:"max_slices,omitempty"`          // 最多拆分笔数（默认5）
+}
+
+// PositionSizingConfig Kelly公式仓位计算配置
+type PositionSizingConfig struct {
+	Mode          string  `json:"mode"`                     // "cap"（Kelly仓位作为AI仓位上限）或 "override"（直接替换AI仓位）
+	KellyFraction float64 `json:"kelly_fraction,omitempty"` // 使用的Kelly比例（默认0.25，即四分之一Kelly）
+	MinTrades     int     `json:"min_trades,omitempty"`     // 至少N笔已平仓交易后才启用（默认10）
+}
+
+// MarketDataFormatConfig 各层级币种的行情数据格式（"full"完整序列 或 "compact"统计摘要+最近几个点，默认full）
+type MarketDataFormatConfig struct {
+	Positions         string `json:"positions,omitempty"`           // 持仓币种
+	TopCandidates     string `json:"top_candidates,omitempty"`      // 评分最高的top_candidate_count个候选币种
+	Candidates        string `json:"candidates,omitempty"`          // 其余候选币种
+	TopCandidateCount int    `json:"top_candidate_count,omitempty"` // 高优先级候选币种数量
+}
+
+// CostModelConfig 交易成本模型配置（0表示使用默认值）
+type CostModelConfig struct {
+	TakerFeePct     float64 `json:"taker_fee_pct,omitempty"`     // 单边taker手续费（百分比，默认0.04）
+	SlippagePct     float64 `json:"slippage_pct,omitempty"`      // 单边预估滑点（百分比，默认0.05）
+	HoldHours       float64 `json:"hold_hours,omitempty"`        // 预期持仓时间，用于估算资金费（小时，默认8）
+	MinCostMultiple float64 `json:"min_cost_multiple,omitempty"` // 扣除成本后的止盈收益至少为总成本的N倍（默认2）
+	Action          string  `json:"action,omitempty"`            // 不达标时："warn"（默认，仅记录日志）或 "reject"（校验失败，让AI修正）
+}
+
+// AIPriceConfig AI模型价格（USD / 百万token）
+type AIPriceConfig struct {
+	Input  float64 `json:"input"`  // 输入token
+	Output float64 `json:"output"` // 输出token
+}
+
+// AIModelConfig 单个AI模型配置（用于集成投票等需要多个模型的场景）
+type AIModelConfig struct {
+	AIModel   string `json:"ai_model"`             // "qwen", "deepseek", "openai", "anthropic", "gemini", "azure", "openrouter", "local", "custom" or "mock"
+	APIKey    string `json:"api_key"`              // 对应平台的API密钥（local和mock可选）
+	APIURL    string `json:"api_url,omitempty"`    // 自定义API地址（custom必填，local可选，默认Ollama；azure为资源地址）
+	ModelName string `json:"model_name,omitempty"` // 模型名称（custom和local必填，其他可选覆盖默认模型；azure为部署名称）
+
+	APIVersion        string            `json:"api_version,omitempty"`         // azure的API版本（可选）
+	OpenRouter        *OpenRouterConfig `json:"openrouter,omitempty"`          // openrouter的模型路由选项（可选）
+	MockResponsesFile string            `json:"mock_responses_file,omitempty"` // mock的响应脚本（可选）
+
+	Params *AIParamsConfig `json:"params,omitempty"` // 该模型的生成参数（可选，覆盖trader的ai_params）
+}
+
+// OpenRouterConfig OpenRouter模型路由配置
+type OpenRouterConfig struct {
+	Models        []string `json:"models,omitempty"`         // 备用模型ID（主模型不可用时由OpenRouter依次改用）
+	ProviderOrder []string `json:"provider_order,omitempty"` // 优先使用的底层提供商（如 "Anthropic"）
+	Sort          string   `json:"sort,omitempty"`           // 底层提供商排序："price"、"throughput" 或 "latency"
+}
+
+// validate 验证OpenRouter路由配置
+func (o *OpenRouterConfig) validate() error {
+	if o.Sort != "" && o.Sort != "price" && o.Sort != "throughput" && o.Sort != "latency" {
+		return fmt.Errorf("openrouter.sort必须是 'price'、'throughput' 或 'latency'")
+	}
+	return nil
+}
+
+// AIParamsConfig AI生成参数（未配置的字段使用默认值）
+type AIParamsConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`      // 采样温度（默认0.5）
+	TopP            float64  `json:"top_p,omitempty"`            // 核采样（默认使用提供商默认值）
+	MaxTokens       int      `json:"max_tokens,omitempty"`       // 回复最大token数（默认2000）
+	ReasoningEffort string   `json:"reasoning_effort,omitempty"` // 推理模型的推理强度（OpenAI兼容API）："minimal"、"low"、"medium"或"high"
+	ThinkingBudget  int      `json:"thinking_budget,omitempty"`  // 思考token预算（Anthropic至少1024，Gemini；默认0关闭思考）
+	Stop            []string `json:"stop,omitempty"`             // 停止序列
+}
+
+// ModelRoutingConfig 按风险路由模型配置
+type ModelRoutingConfig struct {
+	CheapModel        AIModelConfig `json:"cheap_model"`         // 低风险周期使用的便宜/快速模型
+	MinCandidateScore float64       `json:"min_candidate_score"` // 任一候选币种评分达到该值时使用主模型（0表示只按持仓和挂单判断）
+}
+
+// ChartImagesConfig K线图配置
+type ChartImagesConfig struct {
+	MaxSymbols int    `json:"max_symbols"`        // 每周期最多绘制的币种数（持仓优先，然后按候选顺序）
+	Interval   string `json:"interval,omitempty"` // K线周期（默认15m）
+	Bars       int    `json:"bars,omitempty"`     // 每张图的K线根数（默认80，最多200）
+}
+
+// AIRetryConfig AI调用的重试和熔断策略
+type AIRetryConfig struct {
+	MaxAttempts            int     `json:"max_attempts,omitempty"`             // 每次调用最多尝试次数（含首次，默认4）
+	BaseBackoffSeconds     float64 `json:"base_backoff_seconds,omitempty"`     // 首次重试前等待秒数，之后指数增长并加随机抖动（默认2）
+	MaxBackoffSeconds      float64 `json:"max_backoff_seconds,omitempty"`      // 单次等待上限秒数（默认30）
+	BreakerThreshold       int     `json:"breaker_threshold,omitempty"`        // 连续N次调用失败后熔断，期间跳过周期（默认5，-1关闭熔断）
+	BreakerCooldownSeconds int     `json:"breaker_cooldown_seconds,omitempty"` // 熔断冷却秒数，到期后试探一次，成功则恢复（默认300）
+}
+
+// EnsembleConfig 多模型集成投票配置
+type EnsembleConfig struct {
+	Models   []AIModelConfig `json:"models"`    // 额外参与投票的模型（trader主模型自动参与）
+	Mode     string          `json:"mode"`      // "majority"（多数票）或 "confidence"（置信度加权）
+	MinAgree int             `json:"min_agree"` // 至少N个模型给出相同动作才执行（默认过半数）
+
+	LatencyBudgetSeconds int `json:"latency_budget_seconds,omitempty"` // 所有模型并行调用的等待上限，超时未回复的模型不参与投票（0表示等待全部，受周期截止时间限制）
+}
+
+// NewsConfig 新闻数据源配置
+type NewsConfig struct {
+	Provider     string `json:"provider"`                // 新闻数据源（目前支持 "cryptopanic"）
+	APIKey       string `json:"api_key"`                 // 数据源API密钥
+	MaxHeadlines int    `json:"max_headlines,omitempty"` // 每个币种展示的标题数（默认3）
+	MaxAgeHours  int    `json:"max_age_hours,omitempty"` // 只展示该时间内的新闻（小时，默认24）
+}
+
+// ProxyConfig 代理配置（http://、https://、socks5://、socks5h://，"direct"表示直连；未配置时使用环境变量HTTP_PROXY/HTTPS_PROXY）
+type ProxyConfig struct {
+	URL      string            `json:"url,omitempty"`      // 所有请求的默认代理
+	AI       string            `json:"ai,omitempty"`       // AI模型API（覆盖url）
+	Exchange string            `json:"exchange,omitempty"` // 交易所交易和行情接口（币安、Hyperliquid、Aster，覆盖url）
+	Hosts    map[string]string `json:"hosts,omitempty"`    // 按主机覆盖（优先级最高，如 {"api.deepseek.com": "direct"}）
+}
+
+// validate 验证代理配置
+func (p *ProxyConfig) validate() error {
+	for name, proxyURL := range map[string]string{"url": p.URL, "ai": p.AI, "exchange": p.Exchange} {
+		if err := proxy.Validate(proxyURL); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	for host, proxyURL := range p.Hosts {
+		if err := proxy.Validate(proxyURL); err != nil {
+			return fmt.Errorf("hosts[%s]: %w", host, err)
+		}
+	}
+	return nil
+}
+
+// WhaleAlertConfig 大额链上转账数据源配置
+type WhaleAlertConfig struct {
+	Provider     string `json:"provider"`                // 数据源（目前支持 "whale_alert"）
+	APIKey       string `json:"api_key"`                 // 数据源API密钥
+	MinValueUSD  int    `json:"min_value_usd,omitempty"` // 只统计不低于该金额的转账（USD，默认且最低500000）
+	MaxTransfers int    `json:"max_transfers,omitempty"` // 每个币种展示的转账笔数（默认3）
+}
+
+// CalendarConfig 经济日历配置（事件列表从JSON文件读取，文件修改后自动重新加载）
+type CalendarConfig struct {
+	File                  string `json:"file"`                              // 日历文件路径（事件数组：title、time、impact、可选symbols）
+	LookaheadHours        int    `json:"lookahead_hours,omitempty"`         // 提示词中展示该时间内的事件（小时，默认24）
+	BlackoutBeforeMinutes int    `json:"blackout_before_minutes,omitempty"` // 高影响事件前N分钟禁止开新仓（0=不限制）
+	BlackoutAfterMinutes  int    `json:"blackout_after_minutes,omitempty"`  // 高影响事件后N分钟禁止开新仓（0=不限制）
+}
+
+// CandidateSourceConfig 候选币种来源配置（内置ai500、oi_top、vol_top、movers、new_listing、funding；其他名称为自定义HTTP筛选器，需要配置url）
+type CandidateSourceConfig struct {
+	Name  string `json:"name"`            // 来源名称
+	URL   string `json:"url,omitempty"`   // 自定义筛选器接口地址（返回 {"success":true,"data":{"candidates":[{"symbol","score","tags"}]}}）
+	Limit int    `json:"limit,omitempty"` // 按评分取前N个（0=不限制）
+}
+
+// SocialConfig 社交热度候选来源配置
+type SocialConfig struct {
+	Provider string `json:"provider"`          // 数据源："coingecko"（热搜，无需API Key）或 "lunarcrush"（Galaxy Score排名）
+	APIKey   string `json:"api_key,omitempty"` // 数据源API密钥（lunarcrush必填）
+	Limit    int    `json:"limit,omitempty"`   // 取热度榜前N个（默认10，映射不到合约的币种会被跳过）
+}
+
+// OITopWindowConfig 持仓量增长排名的统计窗口
+type OITopWindowConfig struct {
+	Window string `json:"window"`          // 统计窗口（15m/1h/4h）
+	Limit  int    `json:"limit,omitempty"` // 取排名前N个（0=使用API默认值）
+}
+
+// NewListingConfig 新上线永续合约的处理方式
+type NewListingConfig struct {
+	Mode           string  `json:"mode"`                       // "candidate"=加入候选池（带new_listing标签），"blacklist"=从候选池排除
+	MaxAgeHours    int     `json:"max_age_hours,omitempty"`    // 上线时间在该范围内视为新上线（小时，默认72）
+	MinQuoteVolume float64 `json:"min_quote_volume,omitempty"` // candidate模式下加入候选池所需的最低24小时成交额（USDT，默认5000万）
 }
 
 // LeverageConfig 杠杆配置
 type LeverageConfig struct {
 	BTCETHLeverage  int `json:"btc_eth_leverage"` // BTC和ETH的杠杆倍数（主账户建议5-50，子账户≤5）
 	AltcoinLeverage int `json:"altcoin_leverage"` // 山寨币的杠杆倍数（主账户建议5-20，子账户≤5）
+
+	Symbols map[string]SymbolLimitConfig `json:"symbols,omitempty"` // 单币种限制（覆盖上面的BTC/ETH或山寨币默认值）
+}
+
+// SymbolLimitConfig 单币种风控限制（0表示使用默认值）
+type SymbolLimitConfig struct {
+	MaxLeverage         int     `json:"max_leverage,omitempty"`          // 最大杠杆倍数
+	MaxPositionMultiple float64 `json:"max_position_multiple,omitempty"` // 最大仓位价值（账户净值的倍数）
+	MaxRiskPct          float64 `json:"max_risk_pct,omitempty"`          // 单笔最大风险（账户净值百分比）
 }
 
 // Config 总配置
 type Config struct {
-	Traders            []TraderConfig `json:"traders"`
-	UseDefaultCoins    bool           `json:"use_default_coins"` // 是否使用默认主流币种列表
-	DefaultCoins       []string       `json:"default_coins"`     // 默认主流币种池
-	CoinPoolAPIURL     string         `json:"coin_pool_api_url"`
-	OITopAPIURL        string         `json:"oi_top_api_url"`
-	APIServerPort      int            `json:"api_server_port"`
-	MaxDailyLoss       float64        `json:"max_daily_loss"`
-	MaxDrawdown        float64        `json:"max_drawdown"`
-	StopTradingMinutes int            `json:"stop_trading_minutes"`
-	Leverage           LeverageConfig `json:"leverage"` // 杠杆配置
+	Traders            []TraderConfig           `json:"traders"`
+	UseDefaultCoins    bool                     `json:"use_default_coins"`   // 是否使用默认主流币种列表
+	DefaultCoins       []string                 `json:"default_coins"`       // 默认主流币种池
+	Watchlist          []string                 `json:"watchlist,omitempty"` // 自选币种（始终加入候选池）
+	Blacklist          []string                 `json:"blacklist,omitempty"` // 禁止交易的币种（从候选池排除，AI对其开仓/持有的决策会被拒绝，只允许平仓）
+	CoinPoolAPIURL     string                   `json:"coin_pool_api_url"`
+	OITopAPIURL        string                   `json:"oi_top_api_url"`
+	OITopWindows       []OITopWindowConfig      `json:"oi_top_windows,omitempty"`       // 持仓量增长排名的统计窗口（可多个，默认使用API URL自带的参数）
+	CandidateSources   []CandidateSourceConfig  `json:"candidate_sources,omitempty"`    // 候选币种来源（按顺序合并去重，默认ai500前20 + oi_top）
+	PoolRefreshMinutes int                      `json:"pool_refresh_minutes,omitempty"` // 候选币种池刷新间隔（分钟，与决策周期无关，间隔内复用上次结果；0=每个周期刷新）
+	NewListings        *NewListingConfig        `json:"new_listings,omitempty"`         // 新上线永续合约（可选，加入候选池或排除）
+	Social             *SocialConfig            `json:"social,omitempty"`               // 社交热度币种（可选，带social标签加入候选池）
+	Sectors            map[string][]string      `json:"sectors,omitempty"`              // 补充或覆盖内置板块映射（板块 -> 币种列表，如 {"AI": ["FET", "TAO"]}）
+	MarketSource       string                   `json:"market_source,omitempty"`        // 行情数据源（默认binance）
+	AIPrices           map[string]AIPriceConfig `json:"ai_prices,omitempty"`            // 补充或覆盖AI模型价格（模型名称前缀 -> 每百万token价格，用于费用统计）
+	AICacheMinutes     int                      `json:"ai_cache_minutes,omitempty"`     // AI响应缓存有效期（分钟，相同模型和prompt直接复用，崩溃重启或重复试运行不重复付费；0=不缓存）
+	MarketStream       bool                     `json:"market_stream,omitempty"`        // 使用WebSocket行情流（K线/资金费率实时推送，减少REST轮询）
+	News               *NewsConfig              `json:"news,omitempty"`                 // 新闻标题和情绪（可选，在提示词中附加候选币种的近期新闻）
+	WhaleAlerts        *WhaleAlertConfig        `json:"whale_alerts,omitempty"`         // 大额链上转账（可选，在提示词中附加最近1小时交易所流入/流出）
+	Calendar           *CalendarConfig          `json:"calendar,omitempty"`             // 经济日历（可选，提示词中附加即将发生的事件，可在高影响事件前后禁止开仓）
+	Proxy              *ProxyConfig             `json:"proxy,omitempty"`                // HTTP/SOCKS代理（可选，AI接口和交易所接口可分别设置）
+	APIServerPort      int                      `json:"api_server_port"`
+	MaxDailyLoss       float64                  `json:"max_daily_loss"`
+	MaxDrawdown        float64                  `json:"max_drawdown"`
+	StopTradingMinutes int                      `json:"stop_trading_minutes"`
+	Leverage           LeverageConfig           `json:"leverage"` // 杠杆配置
 }
 
 // LoadConfig 从文件加载配置
@@ -122,16 +459,16 @@ func (c *Config) Validate() error {
 		if trader.Name == "" {
 			return fmt.Errorf("trader[%d]: Name不能为空", i)
 		}
-		if trader.AIModel != "qwen" && trader.AIModel != "deepseek" && trader.AIModel != "custom" {
-			return fmt.Errorf("trader[%d]: ai_model必须是 'qwen', 'deepseek' 或 'custom'", i)
+		if !isValidAIModel(trader.AIModel) {
+			return fmt.Errorf("trader[%d]: ai_model必须是 %s", i, aiModelNames)
 		}
 
 		// 验证交易平台配置
 		if trader.Exchange == "" {
 			trader.Exchange = "binance" // 默认使用币安
 		}
-		if trader.Exchange != "binance" && trader.Exchange != "hyperliquid" && trader.Exchange != "aster" {
-			return fmt.Errorf("trader[%d]: exchange必须是 'binance', 'hyperliquid' 或 'aster'", i)
+		if trader.Exchange != "binance" && trader.Exchange != "bybit" && trader.Exchange != "okx" && trader.Exchange != "hyperliquid" && trader.Exchange != "aster" && trader.Exchange != "paper" {
+			return fmt.Errorf("trader[%d]: exchange必须是 'binance', 'bybit', 'okx', 'hyperliquid', 'aster' 或 'paper'", i)
 		}
 
 		// 根据平台验证对应的密钥
@@ -139,6 +476,14 @@ func (c *Config) Validate() error {
 			if trader.BinanceAPIKey == "" || trader.BinanceSecretKey == "" {
 				return fmt.Errorf("trader[%d]: 使用币安时必须配置binance_api_key和binance_secret_key", i)
 			}
+		} else if trader.Exchange == "bybit" {
+			if trader.BybitAPIKey == "" || trader.BybitSecretKey == "" {
+				return fmt.Errorf("trader[%d]: 使用Bybit时必须配置bybit_api_key和bybit_secret_key", i)
+			}
+		} else if trader.Exchange == "okx" {
+			if trader.OKXAPIKey == "" || trader.OKXSecretKey == "" || trader.OKXPassphrase == "" {
+				return fmt.Errorf("trader[%d]: 使用OKX时必须配置okx_api_key, okx_secret_key和okx_passphrase", i)
+			}
 		} else if trader.Exchange == "hyperliquid" {
 			if trader.HyperliquidPrivateKey == "" {
 				return fmt.Errorf("trader[%d]: 使用Hyperliquid时必须配置hyperliquid_private_key", i)
@@ -149,12 +494,39 @@ func (c *Config) Validate() error {
 			}
 		}
 
+		if trader.Testnet && (trader.Exchange == "aster" || trader.Exchange == "paper") {
+			return fmt.Errorf("trader[%d]: testnet仅支持币安、Bybit、OKX和Hyperliquid（%s没有测试网）", i, trader.Exchange)
+		}
+
 		if trader.AIModel == "qwen" && trader.QwenKey == "" {
 			return fmt.Errorf("trader[%d]: 使用Qwen时必须配置qwen_key", i)
 		}
 		if trader.AIModel == "deepseek" && trader.DeepSeekKey == "" {
 			return fmt.Errorf("trader[%d]: 使用DeepSeek时必须配置deepseek_key", i)
 		}
+		if trader.AIModel == "openai" && trader.OpenAIKey == "" {
+			return fmt.Errorf("trader[%d]: 使用OpenAI时必须配置openai_key", i)
+		}
+		if trader.AIModel == "anthropic" && trader.AnthropicKey == "" {
+			return fmt.Errorf("trader[%d]: 使用Anthropic时必须配置anthropic_key", i)
+		}
+		if trader.AIModel == "gemini" && trader.GeminiKey == "" {
+			return fmt.Errorf("trader[%d]: 使用Gemini时必须配置gemini_key", i)
+		}
+		if trader.AIModel == "azure" && (trader.AzureEndpoint == "" || trader.AzureAPIKey == "" || trader.AzureDeployment == "") {
+			return fmt.Errorf("trader[%d]: 使用Azure OpenAI时必须配置azure_endpoint、azure_api_key和azure_deployment", i)
+		}
+		if trader.AIModel == "openrouter" && (trader.OpenRouterKey == "" || trader.ModelName == "") {
+			return fmt.Errorf("trader[%d]: 使用OpenRouter时必须配置openrouter_key和model_name", i)
+		}
+		if trader.OpenRouter != nil {
+			if err := trader.OpenRouter.validate(); err != nil {
+				return fmt.Errorf("trader[%d]: %w", i, err)
+			}
+		}
+		if trader.AIModel == "local" && trader.ModelName == "" {
+			return fmt.Errorf("trader[%d]: 使用本地模型时必须配置model_name", i)
+		}
 		if trader.AIModel == "custom" {
 			if trader.CustomAPIURL == "" {
 				return fmt.Errorf("trader[%d]: 使用自定义API时必须配置custom_api_url", i)
@@ -166,6 +538,133 @@ func (c *Config) Validate() error {
 				return fmt.Errorf("trader[%d]: 使用自定义API时必须配置custom_model_name", i)
 			}
 		}
+		if trader.HedgeMode && trader.Exchange != "binance" {
+			return fmt.Errorf("trader[%d]: hedge_mode仅支持币安（%s不支持双向持仓）", i, trader.Exchange)
+		}
+		if trader.MinRiskReward < 0 {
+			return fmt.Errorf("trader[%d]: min_risk_reward不能为负数", i)
+		}
+		if trader.MaxPositions < 0 {
+			return fmt.Errorf("trader[%d]: max_positions不能为负数", i)
+		}
+		if trader.MaxSectorPositions < 0 {
+			return fmt.Errorf("trader[%d]: max_sector_positions不能为负数", i)
+		}
+		if trader.PromptTokenBudget < 0 {
+			return fmt.Errorf("trader[%d]: prompt_token_budget不能为负数", i)
+		}
+		if trader.DepegThresholdPct < 0 {
+			return fmt.Errorf("trader[%d]: depeg_threshold_pct不能为负数", i)
+		}
+		if trader.InvalidationAction != "" && trader.InvalidationAction != "close" && trader.InvalidationAction != "flag" {
+			return fmt.Errorf("trader[%d]: invalidation_action必须是 'close' 或 'flag'", i)
+		}
+		if trader.PositionSizing != nil {
+			if trader.PositionSizing.Mode != "cap" && trader.PositionSizing.Mode != "override" {
+				return fmt.Errorf("trader[%d]: position_sizing.mode必须是 'cap' 或 'override'", i)
+			}
+			if trader.PositionSizing.KellyFraction < 0 || trader.PositionSizing.KellyFraction > 1 {
+				return fmt.Errorf("trader[%d]: position_sizing.kelly_fraction必须在0-1之间", i)
+			}
+		}
+		for key, intervals := range trader.Timeframes {
+			for _, interval := range intervals {
+				if _, ok := market.SupportedIntervals[interval]; !ok {
+					return fmt.Errorf("trader[%d]: timeframes[%s]: 不支持的K线周期 %q（3m和4h始终包含）", i, key, interval)
+				}
+			}
+		}
+		for _, indicator := range trader.Indicators {
+			if _, ok := market.OptionalIndicators[indicator]; !ok {
+				return fmt.Errorf("trader[%d]: indicators: 不支持的指标 %q（可选 obv、stochastic、ichimoku）", i, indicator)
+			}
+		}
+		if f := trader.MarketDataFormat; f != nil {
+			for _, format := range []string{f.Positions, f.TopCandidates, f.Candidates} {
+				if format != "" && format != "full" && format != "compact" {
+					return fmt.Errorf("trader[%d]: market_data_format的取值必须是 'full' 或 'compact'", i)
+				}
+			}
+			if f.TopCandidateCount < 0 {
+				return fmt.Errorf("trader[%d]: market_data_format.top_candidate_count不能为负数", i)
+			}
+		}
+		if m := trader.CostModel; m != nil {
+			if m.TakerFeePct < 0 || m.SlippagePct < 0 || m.HoldHours < 0 || m.MinCostMultiple < 0 {
+				return fmt.Errorf("trader[%d]: cost_model的数值不能为负数", i)
+			}
+			if m.Action != "" && m.Action != "warn" && m.Action != "reject" {
+				return fmt.Errorf("trader[%d]: cost_model.action必须是 'warn' 或 'reject'", i)
+			}
+		}
+		if trader.RiskOfficer != nil {
+			if err := trader.RiskOfficer.validate(); err != nil {
+				return fmt.Errorf("trader[%d]: risk_officer: %w", i, err)
+			}
+		}
+		if trader.AIRequestsPerMinute < 0 {
+			return fmt.Errorf("trader[%d]: ai_requests_per_minute不能为负数", i)
+		}
+		if trader.AIRetry != nil {
+			if err := trader.AIRetry.validate(); err != nil {
+				return fmt.Errorf("trader[%d]: ai_retry: %w", i, err)
+			}
+		}
+		if trader.AIParams != nil {
+			if err := trader.AIParams.validate(); err != nil {
+				return fmt.Errorf("trader[%d]: ai_params: %w", i, err)
+			}
+		}
+		if trader.AIToolRounds < 0 {
+			return fmt.Errorf("trader[%d]: ai_tool_rounds不能为负数", i)
+		}
+		if trader.ModelRouting != nil {
+			if err := trader.ModelRouting.CheapModel.validate(); err != nil {
+				return fmt.Errorf("trader[%d]: model_routing.cheap_model: %w", i, err)
+			}
+			if trader.ModelRouting.MinCandidateScore < 0 {
+				return fmt.Errorf("trader[%d]: model_routing.min_candidate_score不能为负数", i)
+			}
+		}
+		if trader.Paper != nil {
+			if err := trader.Paper.validate(); err != nil {
+				return fmt.Errorf("trader[%d]: paper: %w", i, err)
+			}
+		}
+		if trader.TrailingStop != nil {
+			if err := trader.TrailingStop.validate(); err != nil {
+				return fmt.Errorf("trader[%d]: trailing_stop: %w", i, err)
+			}
+			if trader.TrailingStop.Mode == "exchange" && trader.Exchange != "binance" {
+				return fmt.Errorf("trader[%d]: trailing_stop.mode为exchange时仅支持币安（%s请使用local）", i, trader.Exchange)
+			}
+		}
+		if trader.EntryExecution != nil {
+			if err := trader.EntryExecution.validate(); err != nil {
+				return fmt.Errorf("trader[%d]: entry_execution: %w", i, err)
+			}
+			if trader.EntryExecution.Mode == "chase" && (trader.Exchange == "aster" || trader.Exchange == "paper") {
+				return fmt.Errorf("trader[%d]: entry_execution.mode为chase时不支持%s（需要只做maker的限价单）", i, trader.Exchange)
+			}
+		}
+		if trader.ChartImages != nil {
+			if err := trader.ChartImages.validate(); err != nil {
+				return fmt.Errorf("trader[%d]: chart_images: %w", i, err)
+			}
+		}
+		if trader.AIArchiveDays < 0 {
+			return fmt.Errorf("trader[%d]: ai_archive_days不能为负数", i)
+		}
+		for j := range trader.AIFallbacks {
+			if err := trader.AIFallbacks[j].validate(); err != nil {
+				return fmt.Errorf("trader[%d]: ai_fallbacks[%d]: %w", i, j, err)
+			}
+		}
+		if trader.Ensemble != nil {
+			if err := trader.Ensemble.validate(); err != nil {
+				return fmt.Errorf("trader[%d]: %w", i, err)
+			}
+		}
 		if trader.InitialBalance <= 0 {
 			return fmt.Errorf("trader[%d]: initial_balance必须大于0", i)
 		}
@@ -174,6 +673,102 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.MarketSource == "" {
+		c.MarketSource = market.SourceBinance
+	}
+	if !market.HasSource(c.MarketSource) {
+		return fmt.Errorf("market_source必须是以下之一: %v", market.SourceNames())
+	}
+	if c.MarketStream && c.MarketSource != market.SourceBinance {
+		return fmt.Errorf("market_stream目前只支持binance行情数据源")
+	}
+
+	blacklisted := make(map[string]bool, len(c.Blacklist))
+	for _, symbol := range c.Blacklist {
+		blacklisted[market.Normalize(strings.TrimSpace(symbol))] = true
+	}
+	for _, symbol := range c.Watchlist {
+		if blacklisted[market.Normalize(strings.TrimSpace(symbol))] {
+			return fmt.Errorf("%s 不能同时出现在watchlist和blacklist中", symbol)
+		}
+	}
+
+	seenSources := make(map[string]bool)
+	for i := range c.CandidateSources {
+		source := &c.CandidateSources[i]
+		if err := source.validate(); err != nil {
+			return fmt.Errorf("candidate_sources[%d]: %w", i, err)
+		}
+		if seenSources[source.Name] {
+			return fmt.Errorf("candidate_sources[%d]: 来源 %q 重复", i, source.Name)
+		}
+		seenSources[source.Name] = true
+	}
+
+	seenWindows := make(map[string]bool)
+	for i, w := range c.OITopWindows {
+		if !pool.ValidOITopWindow(w.Window) {
+			return fmt.Errorf("oi_top_windows[%d]: window必须是以下之一: %v", i, pool.OITopWindows)
+		}
+		if w.Limit < 0 {
+			return fmt.Errorf("oi_top_windows[%d]: limit不能为负数", i)
+		}
+		if seenWindows[w.Window] {
+			return fmt.Errorf("oi_top_windows[%d]: 窗口 %s 重复", i, w.Window)
+		}
+		seenWindows[w.Window] = true
+	}
+
+	for model, price := range c.AIPrices {
+		if price.Input < 0 || price.Output < 0 {
+			return fmt.Errorf("ai_prices[%s]: 价格不能为负数", model)
+		}
+	}
+
+	if c.AICacheMinutes < 0 {
+		return fmt.Errorf("ai_cache_minutes不能为负数")
+	}
+
+	if c.PoolRefreshMinutes < 0 {
+		return fmt.Errorf("pool_refresh_minutes不能为负数")
+	}
+
+	if c.NewListings != nil {
+		if err := c.NewListings.validate(); err != nil {
+			return fmt.Errorf("new_listings: %w", err)
+		}
+	}
+
+	if c.Social != nil {
+		if err := c.Social.validate(); err != nil {
+			return fmt.Errorf("social: %w", err)
+		}
+	}
+
+	if c.News != nil {
+		if err := c.News.validate(); err != nil {
+			return fmt.Errorf("news: %w", err)
+		}
+	}
+
+	if c.Proxy != nil {
+		if err := c.Proxy.validate(); err != nil {
+			return fmt.Errorf("proxy: %w", err)
+		}
+	}
+
+	if c.WhaleAlerts != nil {
+		if err := c.WhaleAlerts.validate(); err != nil {
+			return fmt.Errorf("whale_alerts: %w", err)
+		}
+	}
+
+	if c.Calendar != nil {
+		if err := c.Calendar.validate(); err != nil {
+			return fmt.Errorf("calendar: %w", err)
+		}
+	}
+
 	if c.APIServerPort <= 0 {
 		c.APIServerPort = 8080 // 默认8080端口
 	}
@@ -191,7 +786,289 @@ func (c *Config) Validate() error {
 	if c.Leverage.AltcoinLeverage > 5 {
 		fmt.Printf("⚠️  警告: 山寨币杠杆设置为%dx，如果使用子账户可能会失败（子账户限制≤5x）\n", c.Leverage.AltcoinLeverage)
 	}
+	for symbol, limit := range c.Leverage.Symbols {
+		if limit.MaxLeverage < 0 || limit.MaxPositionMultiple < 0 || limit.MaxRiskPct < 0 {
+			return fmt.Errorf("leverage.symbols[%s]: 限制值不能为负数", symbol)
+		}
+	}
+
+	return nil
+}
+
+// aiModelNames 支持的AI提供商（用于错误提示）
+const aiModelNames = "'qwen', 'deepseek', 'openai', 'anthropic', 'gemini', 'azure', 'openrouter', 'local', 'custom' 或 'mock'"
+
+// isValidAIModel 是否为支持的AI提供商
+func isValidAIModel(model string) bool {
+	switch model {
+	case "qwen", "deepseek", "openai", "anthropic", "gemini", "azure", "openrouter", "local", "custom", "mock":
+		return true
+	}
+	return false
+}
+
+// validate 验证单个AI模型配置
+func (m *AIModelConfig) validate() error {
+	if !isValidAIModel(m.AIModel) {
+		return fmt.Errorf("ai_model必须是 %s", aiModelNames)
+	}
+	if m.Params != nil {
+		if err := m.Params.validate(); err != nil {
+			return fmt.Errorf("params: %w", err)
+		}
+	}
+	if m.AIModel == "local" {
+		if m.ModelName == "" {
+			return fmt.Errorf("使用本地模型时必须配置model_name")
+		}
+		return nil
+	}
+	if m.AIModel == "mock" {
+		return nil
+	}
+	if m.APIKey == "" {
+		return fmt.Errorf("api_key不能为空")
+	}
+	if m.AIModel == "custom" && (m.APIURL == "" || m.ModelName == "") {
+		return fmt.Errorf("使用自定义API时必须配置api_url和model_name")
+	}
+	if m.AIModel == "azure" && (m.APIURL == "" || m.ModelName == "") {
+		return fmt.Errorf("使用Azure OpenAI时必须配置api_url（资源地址）和model_name（部署名称）")
+	}
+	if m.AIModel == "openrouter" && m.ModelName == "" {
+		return fmt.Errorf("使用OpenRouter时必须配置model_name（如 \"anthropic/claude-sonnet-4\"）")
+	}
+	if m.OpenRouter != nil {
+		if err := m.OpenRouter.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// validate 验证AI生成参数
+func (p *AIParamsConfig) validate() error {
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2) {
+		return fmt.Errorf("temperature必须在0-2之间")
+	}
+	if p.TopP < 0 || p.TopP > 1 {
+		return fmt.Errorf("top_p必须在0-1之间")
+	}
+	if p.MaxTokens < 0 || p.ThinkingBudget < 0 {
+		return fmt.Errorf("max_tokens和thinking_budget不能为负数")
+	}
+	switch p.ReasoningEffort {
+	case "", "minimal", "low", "medium", "high":
+	default:
+		return fmt.Errorf("reasoning_effort必须是 'minimal'、'low'、'medium' 或 'high'")
+	}
+	return nil
+}
+
+// validate 验证移动止损配置
+func (t *TrailingStopConfig) validate() error {
+	if t.Mode != "" && t.Mode != "local" && t.Mode != "exchange" {
+		return fmt.Errorf("mode必须是 'local' 或 'exchange'")
+	}
+	if t.ActivationR < 0 || t.ATRMultiple < 0 {
+		return fmt.Errorf("activation_r和atr_multiple不能为负数")
+	}
+	if t.ATRInterval != "" && !market.Interval(t.ATRInterval).Valid() {
+		return fmt.Errorf("不支持的K线周期 %q", t.ATRInterval)
+	}
+	return nil
+}
+
+// validate 验证开仓执行配置（追价不超过2分钟、拆单不超过5分钟，避免拖住决策周期）
+func (e *EntryExecutionConfig) validate() error {
+	if e.Mode != "" && e.Mode != "market" && e.Mode != "chase" {
+		return fmt.Errorf("mode必须是 'market' 或 'chase'")
+	}
+	if e.MaxReprices < 0 || e.MaxReprices > 10 {
+		return fmt.Errorf("max_reprices必须在0-10之间")
+	}
+	if e.RepriceSeconds < 0 || e.RepriceSeconds > 60 {
+		return fmt.Errorf("reprice_seconds必须在0-60之间")
+	}
+	reprices, seconds := e.MaxReprices, e.RepriceSeconds
+	if reprices == 0 {
+		reprices = 3
+	}
+	if seconds == 0 {
+		seconds = 10
+	}
+	if (reprices+1)*seconds > 120 {
+		return fmt.Errorf("(max_reprices+1) × reprice_seconds不能超过120秒")
+	}
+
+	if e.MaxVolumeFraction < 0 || e.MaxVolumeFraction > 1 || e.MaxDepthFraction < 0 || e.MaxDepthFraction > 1 {
+		return fmt.Errorf("max_volume_fraction和max_depth_fraction必须在0-1之间")
+	}
+	if e.SliceSeconds < 0 || e.MaxSlices < 0 || e.MaxSlices > 20 {
+		return fmt.Errorf("slice_seconds不能为负数，max_slices必须在0-20之间")
+	}
+	slices, interval := e.MaxSlices, e.SliceSeconds
+	if slices == 0 {
+		slices = 5
+	}
+	if interval == 0 {
+		interval = 30
+	}
+	if (slices-1)*interval > 300 {
+		return fmt.Errorf("(max_slices-1) × slice_seconds不能超过300秒")
+	}
+	return nil
+}
+
+// validate 验证K线图配置
+func (c *ChartImagesConfig) validate() error {
+	if c.MaxSymbols <= 0 {
+		return fmt.Errorf("max_symbols必须大于0")
+	}
+	if c.Interval != "" && !market.Interval(c.Interval).Valid() {
+		return fmt.Errorf("不支持的K线周期 %q", c.Interval)
+	}
+	if c.Bars < 0 || c.Bars > 200 {
+		return fmt.Errorf("bars必须在0-200之间")
+	}
+	return nil
+}
+
+// validate 验证模拟交易配置
+func (p *PaperConfig) validate() error {
+	if p.TakerFeePct < 0 || p.MakerFeePct < 0 || p.SlippagePct < 0 {
+		return fmt.Errorf("taker_fee_pct、maker_fee_pct和slippage_pct不能为负数")
+	}
+	if p.TakerFeePct > 1 || p.MakerFeePct > 1 || p.SlippagePct > 1 {
+		return fmt.Errorf("taker_fee_pct、maker_fee_pct和slippage_pct不能超过1（%%）")
+	}
+	return nil
+}
+
+// validate 验证AI重试和熔断策略
+func (r *AIRetryConfig) validate() error {
+	if r.MaxAttempts < 0 || r.BaseBackoffSeconds < 0 || r.MaxBackoffSeconds < 0 || r.BreakerCooldownSeconds < 0 {
+		return fmt.Errorf("max_attempts、base_backoff_seconds、max_backoff_seconds和breaker_cooldown_seconds不能为负数")
+	}
+	if r.MaxBackoffSeconds > 0 && r.BaseBackoffSeconds > r.MaxBackoffSeconds {
+		return fmt.Errorf("base_backoff_seconds不能大于max_backoff_seconds")
+	}
+	if r.BreakerThreshold < -1 {
+		return fmt.Errorf("breaker_threshold必须大于0（-1关闭熔断）")
+	}
+	return nil
+}
+
+// validate 验证候选币种来源配置
+func (s *CandidateSourceConfig) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("name不能为空")
+	}
+	if s.Limit < 0 {
+		return fmt.Errorf("limit不能为负数")
+	}
+	builtin := pool.HasSource(s.Name)
+	if builtin && s.URL != "" {
+		return fmt.Errorf("内置来源 %q 不需要配置url", s.Name)
+	}
+	if !builtin && s.URL == "" {
+		return fmt.Errorf("自定义来源 %q 必须配置url", s.Name)
+	}
+	return nil
+}
+
+// validate 验证新上线合约配置
+func (n *NewListingConfig) validate() error {
+	if n.Mode != pool.NewListingCandidate && n.Mode != pool.NewListingBlacklist {
+		return fmt.Errorf("mode必须是 'candidate' 或 'blacklist'")
+	}
+	if n.MaxAgeHours < 0 || n.MinQuoteVolume < 0 {
+		return fmt.Errorf("max_age_hours和min_quote_volume不能为负数")
+	}
+	return nil
+}
+
+// validate 验证社交热度来源配置
+func (s *SocialConfig) validate() error {
+	if s.Provider != pool.SocialCoinGecko && s.Provider != pool.SocialLunarCrush {
+		return fmt.Errorf("provider必须是 'coingecko' 或 'lunarcrush'")
+	}
+	if s.Provider == pool.SocialLunarCrush && s.APIKey == "" {
+		return fmt.Errorf("lunarcrush必须配置api_key")
+	}
+	if s.Limit < 0 {
+		return fmt.Errorf("limit不能为负数")
+	}
+	return nil
+}
+
+// validate 验证新闻数据源配置
+func (n *NewsConfig) validate() error {
+	if n.Provider != "cryptopanic" {
+		return fmt.Errorf("provider必须是 'cryptopanic'")
+	}
+	if n.APIKey == "" {
+		return fmt.Errorf("api_key不能为空")
+	}
+	if n.MaxHeadlines < 0 || n.MaxAgeHours < 0 {
+		return fmt.Errorf("max_headlines和max_age_hours不能为负数")
+	}
+	return nil
+}
+
+// validate 验证大额转账数据源配置
+func (w *WhaleAlertConfig) validate() error {
+	if w.Provider != "whale_alert" {
+		return fmt.Errorf("provider必须是 'whale_alert'")
+	}
+	if w.APIKey == "" {
+		return fmt.Errorf("api_key不能为空")
+	}
+	if w.MinValueUSD < 0 || w.MaxTransfers < 0 {
+		return fmt.Errorf("min_value_usd和max_transfers不能为负数")
+	}
+	return nil
+}
+
+// validate 验证经济日历配置
+func (c *CalendarConfig) validate() error {
+	if c.File == "" {
+		return fmt.Errorf("file不能为空")
+	}
+	if c.LookaheadHours < 0 || c.BlackoutBeforeMinutes < 0 || c.BlackoutAfterMinutes < 0 {
+		return fmt.Errorf("lookahead_hours、blackout_before_minutes和blackout_after_minutes不能为负数")
+	}
+	return nil
+}
+
+// validate 验证集成投票配置并设置默认值
+func (e *EnsembleConfig) validate() error {
+	if len(e.Models) == 0 {
+		return fmt.Errorf("ensemble.models不能为空")
+	}
+	for j, m := range e.Models {
+		if err := m.validate(); err != nil {
+			return fmt.Errorf("ensemble.models[%d]: %w", j, err)
+		}
+	}
+
+	if e.Mode == "" {
+		e.Mode = "majority"
+	}
+	if e.Mode != "majority" && e.Mode != "confidence" {
+		return fmt.Errorf("ensemble.mode必须是 'majority' 或 'confidence'")
+	}
+
+	totalModels := len(e.Models) + 1 // 包含trader主模型
+	if e.MinAgree <= 0 {
+		e.MinAgree = totalModels/2 + 1 // 默认过半数
+	}
+	if e.MinAgree > totalModels {
+		return fmt.Errorf("ensemble.min_agree(%d)不能超过模型总数(%d)", e.MinAgree, totalModels)
+	}
+	if e.LatencyBudgetSeconds < 0 {
+		return fmt.Errorf("ensemble.latency_budget_seconds不能为负数")
+	}
 	return nil
 }
 
@@ -0,0 +1,36 @@
+package decision
+
+import (
+	"fmt"
+	"strings"
+)
+
+// blacklisted Whether the user has marked the symbol as never-trade
+func (ctx *Context) blacklisted(symbol string) bool {
+	for _, s := range ctx.Blacklist {
+		if s == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+// validateBlacklist Reject any decision on a blacklisted symbol except exiting an existing position
+func validateBlacklist(d *Decision, ctx *Context) error {
+	if !ctx.blacklisted(d.Symbol) {
+		return nil
+	}
+	switch d.Action {
+	case "close_long", "close_short", "reduce_long", "reduce_short":
+		return nil
+	}
+	return fmt.Errorf("%s is blacklisted (never trade): only close/reduce of an existing position is allowed, got %s", d.Symbol, d.Action)
+}
+
+// buildBlacklistPrompt Never-trade symbols (empty if no blacklist configured)
+func buildBlacklistPrompt(ctx *Context) string {
+	if len(ctx.Blacklist) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Blacklisted (never trade, only close existing positions): %s\n\n", strings.Join(ctx.Blacklist, ", "))
+}
@@ -0,0 +1,31 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/calendar"
+	"strings"
+)
+
+// validateEventBlackout Reject new positions inside a high-impact event's blackout window
+func validateEventBlackout(d *Decision, ctx *Context) error {
+	now := ctx.now()
+	for _, e := range ctx.Events {
+		if e.InBlackout(now) && e.Affects(d.Symbol) {
+			return fmt.Errorf("no new positions around %q (%s UTC): %s is blocked until %s UTC",
+				e.Title, e.Time.UTC().Format("01-02 15:04"), d.Symbol, e.BlackoutEnd.UTC().Format("15:04"))
+		}
+	}
+	return nil
+}
+
+// buildCalendarPrompt Upcoming high-impact events (empty when no calendar is loaded or nothing is scheduled)
+func buildCalendarPrompt(ctx *Context) string {
+	if len(ctx.Events) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("## Upcoming Events\n\n")
+	sb.WriteString(calendar.Format(ctx.Events, ctx.now()))
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,50 @@
+package decision
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// minCalibrationTrades Closed trades with a recorded confidence needed before calibration is shown
+const minCalibrationTrades = 5
+
+// calibrationBucket Mirror of logger.CalibrationBucket (read through a JSON round-trip like the Sharpe ratio)
+type calibrationBucket struct {
+	MinConfidence int     `json:"min_confidence"`
+	MaxConfidence int     `json:"max_confidence"`
+	Trades        int     `json:"trades"`
+	WinRate       float64 `json:"win_rate"`
+	AvgPnL        float64 `json:"avg_pn_l"`
+}
+
+// buildCalibrationPrompt Realized win rate per stated-confidence bucket, so the AI can recalibrate
+func buildCalibrationPrompt(ctx *Context) string {
+	if ctx.Performance == nil {
+		return ""
+	}
+	var perf struct {
+		Calibration []calibrationBucket `json:"calibration"`
+	}
+	jsonData, err := json.Marshal(ctx.Performance)
+	if err != nil || json.Unmarshal(jsonData, &perf) != nil {
+		return ""
+	}
+
+	total := 0
+	for _, b := range perf.Calibration {
+		total += b.Trades
+	}
+	if total < minCalibrationTrades {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## 🎯 Confidence Calibration (stated confidence → realized win rate)\n\n")
+	for _, b := range perf.Calibration {
+		sb.WriteString(fmt.Sprintf("- Confidence %d-%d: %.0f%% win rate over %d trades (avg PnL %+.2f USDT)\n",
+			b.MinConfidence, b.MaxConfidence, b.WinRate, b.Trades, b.AvgPnL))
+	}
+	sb.WriteString("\nIf a bucket's win rate is far below its confidence, you are overconfident there - lower your confidence (and size) accordingly.\n\n")
+	return sb.String()
+}
@@ -0,0 +1,121 @@
+package decision
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"nofx/market"
+	"nofx/mcp"
+	"strings"
+	"sync"
+)
+
+// Chart image defaults
+const (
+	defaultChartInterval = market.Interval("15m")
+	defaultChartBars     = 80
+)
+
+// ChartConfig Candlestick chart images attached to the user prompt for vision-capable models
+type ChartConfig struct {
+	MaxSymbols int             // Charts per cycle: open positions first, then candidates in order (0 = disabled)
+	Interval   market.Interval // Kline interval of the charts (empty = 15m)
+	Bars       int             // Candles per chart (0 = 80)
+}
+
+// interval Chart interval with the default applied
+func (c ChartConfig) interval() market.Interval {
+	if c.Interval == "" {
+		return defaultChartInterval
+	}
+	return c.Interval
+}
+
+// bars Candles per chart with the default applied
+func (c ChartConfig) bars() int {
+	if c.Bars <= 0 {
+		return defaultChartBars
+	}
+	return c.Bars
+}
+
+// chartSymbols Symbols to chart: positions first, then candidates with usable market data, up to MaxSymbols
+func (ctx *Context) chartSymbols() []string {
+	var symbols []string
+	seen := make(map[string]bool)
+	add := func(symbol string) {
+		if len(symbols) >= ctx.Charts.MaxSymbols || seen[symbol] || ctx.MarketDataMap[symbol] == nil {
+			return
+		}
+		seen[symbol] = true
+		symbols = append(symbols, symbol)
+	}
+	for _, pos := range ctx.Positions {
+		add(pos.Symbol)
+	}
+	for _, coin := range ctx.CandidateCoins {
+		add(coin.Symbol)
+	}
+	return symbols
+}
+
+// loadCharts Render the cycle's chart images concurrently (failed charts are skipped and logged;
+// rendering stops waiting once cycleCtx ends)
+func (ctx *Context) loadCharts(cycleCtx context.Context) {
+	ctx.ChartImages = nil
+	if ctx.Charts.MaxSymbols <= 0 {
+		return
+	}
+	symbols := ctx.chartSymbols()
+	charts := make([]*market.ChartImage, len(symbols))
+	var wg sync.WaitGroup
+	for i, symbol := range symbols {
+		wg.Add(1)
+		go func(i int, symbol string) {
+			defer wg.Done()
+			chart, err := market.RenderChart(symbol, ctx.Charts.interval(), ctx.Charts.bars())
+			if err != nil {
+				log.Printf("⚠️  Failed to render %s chart: %v", symbol, err)
+				return
+			}
+			charts[i] = chart
+		}(i, symbol)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-cycleCtx.Done():
+		log.Printf("⚠️  Chart rendering aborted: %v", cycleCtx.Err())
+		return
+	}
+
+	for _, chart := range charts {
+		if chart != nil {
+			ctx.ChartImages = append(ctx.ChartImages, chart)
+		}
+	}
+}
+
+// attachCharts Attach the cycle's charts to the last user message with a caption listing them in order
+// (only for vision-capable clients; a fallback model without vision receives the text alone)
+func attachCharts(messages []mcp.Message, ctx *Context, client *mcp.Client) []mcp.Message {
+	if len(ctx.ChartImages) == 0 || !client.SupportsImages() || len(messages) == 0 {
+		return messages
+	}
+	last := &messages[len(messages)-1]
+
+	var sb strings.Builder
+	sb.WriteString("\n\n## Charts\n\n")
+	sb.WriteString("Candlestick charts are attached when the model accepts images, in this order ")
+	sb.WriteString("(green/red candles, orange EMA20, blue EMA50, volume bars below, RSI14 in the bottom panel with dashed 30/70 lines):\n")
+	for i, chart := range ctx.ChartImages {
+		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, chart.Caption()))
+		last.Images = append(last.Images, mcp.Image{MIMEType: "image/png", Data: chart.PNG})
+	}
+	last.Content += sb.String()
+	return messages
+}
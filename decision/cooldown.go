@@ -0,0 +1,76 @@
+package decision
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultTradeCooldownMinutes Default minutes before a closed symbol may be re-opened
+const defaultTradeCooldownMinutes = 15
+
+// tradeCooldown Re-entry cooldown after a close (0 = disabled)
+func (ctx *Context) tradeCooldown() time.Duration {
+	if ctx.TradeCooldownMinutes < 0 {
+		return 0
+	}
+	if ctx.TradeCooldownMinutes == 0 {
+		return defaultTradeCooldownMinutes * time.Minute
+	}
+	return time.Duration(ctx.TradeCooldownMinutes) * time.Minute
+}
+
+// now Time the context was built (so replays of a saved context see the same cooldowns)
+func (ctx *Context) now() time.Time {
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", ctx.CurrentTime, time.Local); err == nil {
+		return t
+	}
+	return time.Now()
+}
+
+// cooldownRemaining Time left before symbol may be re-opened (0 if not cooling down)
+func (ctx *Context) cooldownRemaining(symbol string) time.Duration {
+	cooldown := ctx.tradeCooldown()
+	closedAt, exists := ctx.RecentCloses[symbol]
+	if cooldown <= 0 || !exists {
+		return 0
+	}
+	remaining := time.UnixMilli(closedAt).Add(cooldown).Sub(ctx.now())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// validateCooldown Reject re-opening a symbol that was closed within the cooldown window
+func validateCooldown(d *Decision, ctx *Context) error {
+	if remaining := ctx.cooldownRemaining(d.Symbol); remaining > 0 {
+		return fmt.Errorf("%s was closed less than %d minutes ago, cannot re-open for another %d minutes",
+			d.Symbol, int(ctx.tradeCooldown().Minutes()), int(math.Ceil(remaining.Minutes())))
+	}
+	return nil
+}
+
+// buildCooldownPrompt List symbols that cannot be re-opened yet (empty when none)
+func buildCooldownPrompt(ctx *Context) string {
+	var symbols []string
+	for symbol := range ctx.RecentCloses {
+		if ctx.cooldownRemaining(symbol) > 0 {
+			symbols = append(symbols, symbol)
+		}
+	}
+	if len(symbols) == 0 {
+		return ""
+	}
+	sort.Strings(symbols)
+
+	var sb strings.Builder
+	sb.WriteString("## Re-entry Cooldown (open_long/open_short will be rejected)\n\n")
+	for _, symbol := range symbols {
+		sb.WriteString(fmt.Sprintf("- %s: recently closed, %d minutes left\n", symbol, int(math.Ceil(ctx.cooldownRemaining(symbol).Minutes()))))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
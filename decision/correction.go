@@ -0,0 +1,93 @@
+package decision
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"nofx/mcp"
+)
+
+// defaultMaxCorrections Default number of times the AI may correct a rejected response
+const defaultMaxCorrections = 2
+
+// callAndParse Call the AI and parse its response; on parse/validation failure, feed the error back
+// to the model and let it correct the decision array (bounded by ctx.MaxCorrections)
+func callAndParse(cycleCtx context.Context, ctx *Context, mcpClient *mcp.Client, systemPrompt, userPrompt string) (*FullDecision, error) {
+	// Structured output when the provider supports it, otherwise free-text parsing
+	structured := mcpClient.SupportsStructuredOutput()
+	var schema *mcp.ResponseSchema
+	if structured {
+		systemPrompt += structuredOutputInstruction
+		schema = decisionResponseSchema()
+	}
+	// Native tool calling: the model fetches extra data on demand (not combined with structured output)
+	tools := !structured && ctx.MaxToolRounds > 0 && mcpClient.SupportsTools()
+	if tools {
+		systemPrompt += toolUseInstruction(ctx.MaxToolRounds)
+	}
+
+	maxCorrections := ctx.MaxCorrections
+	if maxCorrections == 0 {
+		maxCorrections = defaultMaxCorrections
+	}
+
+	messages := attachCharts(mcp.BuildMessages(systemPrompt, userPrompt), ctx, mcpClient)
+	var last *FullDecision // Last parsed (rejected) decision, kept if a correction call fails
+	var lastErr error
+	for round := 0; ; round++ {
+		var aiResponse string
+		var err error
+		if tools {
+			aiResponse, err = mcpClient.CallWithTools(cycleCtx, messages, decisionTools(), executeTool, ctx.MaxToolRounds)
+		} else if !structured && mcpClient.SupportsStreaming() {
+			// Streamed: the chain of thought is logged as it arrives and parsing starts once the array closes
+			progress := &streamProgress{}
+			aiResponse, err = mcpClient.CallConversationStream(cycleCtx, messages, nil, progress.handle)
+		} else {
+			aiResponse, err = mcpClient.CallConversation(cycleCtx, messages, schema)
+		}
+		if err != nil {
+			if last == nil {
+				return nil, fmt.Errorf("failed to call AI API: %w", err)
+			}
+			// Keep the earlier response (chain of thought, raw output) for the log instead of discarding it
+			log.Printf("⚠️  AI correction round %d/%d failed: %v", round, maxCorrections, err)
+			return last, fmt.Errorf("failed to parse AI response: %w (correction round %d failed: %v)\n\n=== AI Chain of Thought ===\n%s", lastErr, round, err, last.CoTTrace)
+		}
+
+		var decision *FullDecision
+		if structured {
+			decision, err = parseStructuredResponse(aiResponse, ctx)
+		} else {
+			decision, err = parseFullDecisionResponse(aiResponse, ctx)
+		}
+		decision.Corrections = round
+		decision.RawResponse = aiResponse
+		decision.Provider = mcpClient.LastServed()
+		decision.CoTSections = parseCoTSections(decision.CoTTrace)
+		if err == nil {
+			if round > 0 {
+				log.Printf("✓ AI corrected its decisions after %d round(s)", round)
+			}
+			return decision, nil
+		}
+
+		if round >= maxCorrections {
+			return decision, fmt.Errorf("failed to parse AI response: %w\n\n=== AI Chain of Thought ===\n%s", err, decision.CoTTrace)
+		}
+
+		last, lastErr = decision, err
+		log.Printf("⚠️  AI response rejected (%v), requesting correction (%d/%d)", err, round+1, maxCorrections)
+		messages = append(messages,
+			mcp.Message{Role: "assistant", Content: aiResponse},
+			mcp.Message{Role: "user", Content: buildCorrectionPrompt(err)},
+		)
+	}
+}
+
+// buildCorrectionPrompt Follow-up message explaining why the previous response was rejected
+func buildCorrectionPrompt(err error) string {
+	return fmt.Sprintf("Your previous response was rejected by the validator:\n\n%v\n\n"+
+		"Fix the offending decision (adjust its parameters, or change it to wait/hold if the trade no longer meets the constraints) "+
+		"and output the COMPLETE decision list again in the same format. Do not repeat the same mistake.", err)
+}
@@ -0,0 +1,110 @@
+package decision
+
+import (
+	"fmt"
+	"math"
+	"nofx/market"
+	"sort"
+	"strings"
+)
+
+// Correlation summary limits
+const (
+	correlationThreshold = 0.8 // Pairs at or above this |correlation| are listed
+	correlationMinPoints = 20  // Minimum overlapping returns for a meaningful correlation
+	correlationMaxPairs  = 10  // Most correlated pairs shown in the prompt
+)
+
+// correlationPair Return correlation between two symbols
+type correlationPair struct {
+	A, B string
+	Corr float64
+}
+
+// pearson Correlation of the last n points of a and b (n = shorter length), ok=false when too short or flat
+func pearson(a, b []float64) (float64, bool) {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	if n < correlationMinPoints {
+		return 0, false
+	}
+	a, b = a[len(a)-n:], b[len(b)-n:]
+
+	var meanA, meanB float64
+	for i := 0; i < n; i++ {
+		meanA += a[i]
+		meanB += b[i]
+	}
+	meanA /= float64(n)
+	meanB /= float64(n)
+
+	var cov, varA, varB float64
+	for i := 0; i < n; i++ {
+		da, db := a[i]-meanA, b[i]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA == 0 || varB == 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(varA*varB), true
+}
+
+// correlatedPairs Highly correlated pairs among the given symbols, strongest first.
+// Only symbols whose intraday series share the same interval are compared
+func correlatedPairs(symbols []string, dataMap map[string]*market.Data) []correlationPair {
+	var pairs []correlationPair
+	for i := 0; i < len(symbols); i++ {
+		a := dataMap[symbols[i]]
+		if a == nil {
+			continue
+		}
+		for j := i + 1; j < len(symbols); j++ {
+			b := dataMap[symbols[j]]
+			if b == nil || a.IntradayInterval != b.IntradayInterval {
+				continue
+			}
+			corr, ok := pearson(a.Returns, b.Returns)
+			if ok && math.Abs(corr) >= correlationThreshold {
+				pairs = append(pairs, correlationPair{A: symbols[i], B: symbols[j], Corr: corr})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return math.Abs(pairs[i].Corr) > math.Abs(pairs[j].Corr) })
+	return pairs
+}
+
+// buildCorrelationPrompt Highly correlated pairs among positions and displayed candidates
+func buildCorrelationPrompt(ctx *Context, symbols []string) string {
+	pairs := correlatedPairs(symbols, ctx.MarketDataMap)
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	held := make(map[string][]string)
+	for _, pos := range ctx.Positions {
+		held[pos.Symbol] = append(held[pos.Symbol], pos.Side)
+	}
+	coin := func(symbol string) string {
+		name := strings.Replace(symbol, "USDT", "", 1)
+		if sides := held[symbol]; len(sides) > 0 {
+			name += fmt.Sprintf(" (held %s)", strings.Join(sides, "/"))
+		}
+		return name
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## Correlated Coins (intraday returns, |corr| ≥ %.1f)\n\n", correlationThreshold))
+	for i, pair := range pairs {
+		if i >= correlationMaxPairs {
+			sb.WriteString(fmt.Sprintf("- ... %d more pairs\n", len(pairs)-correlationMaxPairs))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("- %s and %s corr %.2f\n", coin(pair.A), coin(pair.B), pair.Corr))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,102 @@
+package decision
+
+import (
+	"strings"
+)
+
+// CoTSections Labeled parts of the chain of thought (empty when the model skipped a section)
+type CoTSections struct {
+	MarketRegime     string `json:"market_regime,omitempty"`
+	PositionReview   string `json:"position_review,omitempty"`
+	NewOpportunities string `json:"new_opportunities,omitempty"`
+	RiskCheck        string `json:"risk_check,omitempty"`
+}
+
+// cotSectionHeadings Section headings the model is asked to use, in prompt order
+var cotSectionHeadings = []string{"MARKET REGIME", "POSITION REVIEW", "NEW OPPORTUNITIES", "RISK CHECK"}
+
+// section Pointer to the field for a heading
+func (s *CoTSections) section(heading string) *string {
+	switch heading {
+	case "MARKET REGIME":
+		return &s.MarketRegime
+	case "POSITION REVIEW":
+		return &s.PositionReview
+	case "NEW OPPORTUNITIES":
+		return &s.NewOpportunities
+	case "RISK CHECK":
+		return &s.RiskCheck
+	}
+	return nil
+}
+
+// buildCoTInstructions Chain-of-thought layout requested in the system prompt
+func buildCoTInstructions() string {
+	var sb strings.Builder
+	sb.WriteString("**Chain of Thought**: Before the decision JSON, write your analysis under these exact headings:\n")
+	sb.WriteString("## MARKET REGIME - Overall market conditions and trend direction (BTC leadership, volatility, funding)\n")
+	sb.WriteString("## POSITION REVIEW - Each open position: thesis still valid? invalidation conditions checked?\n")
+	sb.WriteString("## NEW OPPORTUNITIES - Candidate setups with entry/exit logic from the technical indicators\n")
+	sb.WriteString("## RISK CHECK - Available capital, position sizing, risk-reward and confidence calibration\n\n")
+	return sb.String()
+}
+
+// parseCoTSections Split a chain of thought into its labeled sections.
+// Headings are matched case-insensitively with markdown decoration, numbering and a trailing colon ignored;
+// text before the first heading is dropped. Returns nil when no heading is found.
+func parseCoTSections(cot string) *CoTSections {
+	sections := &CoTSections{}
+	var current *string
+	var body []string
+	found := false
+
+	flush := func() {
+		if current != nil {
+			*current = strings.TrimSpace(strings.Join(body, "\n"))
+		}
+		body = body[:0]
+	}
+
+	for _, line := range strings.Split(cot, "\n") {
+		if heading, rest, ok := matchCoTHeading(line); ok {
+			flush()
+			current = sections.section(heading)
+			found = true
+			if rest != "" {
+				body = append(body, rest)
+			}
+			continue
+		}
+		body = append(body, line)
+	}
+	flush()
+
+	if !found {
+		return nil
+	}
+	return sections
+}
+
+// matchCoTHeading Whether line is one of cotSectionHeadings, e.g. "## Market Regime", "**1. RISK CHECK:**"
+// or "Market regime - trending up" (also returns any text after the heading on the same line)
+func matchCoTHeading(line string) (string, string, bool) {
+	trimmed := strings.TrimLeft(strings.TrimSpace(line), "#*_ ")
+	trimmed = strings.TrimLeft(trimmed, "0123456789.) ")
+
+	for _, heading := range cotSectionHeadings {
+		if len(trimmed) < len(heading) || !strings.EqualFold(trimmed[:len(heading)], heading) {
+			continue
+		}
+		rest := strings.TrimLeft(trimmed[len(heading):], "*_ ")
+		if rest == "" {
+			return heading, "", true
+		}
+		// "Market regime looks bullish" is prose, not a heading
+		for _, sep := range []string{":", "-", "—"} {
+			if strings.HasPrefix(rest, sep) {
+				return heading, strings.TrimSpace(strings.TrimLeft(rest[len(sep):], "*_ ")), true
+			}
+		}
+	}
+	return "", "", false
+}
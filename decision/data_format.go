@@ -0,0 +1,42 @@
+package decision
+
+import "nofx/market"
+
+// Market data formats for the user prompt
+const (
+	DataFormatFull    = "full"    // Complete series (market.Format)
+	DataFormatCompact = "compact" // Summary stats + last few points (market.FormatCompact)
+)
+
+// MarketDataFormat Format per coin tier (empty = full), so low-priority candidates use fewer tokens
+type MarketDataFormat struct {
+	Positions         string // Coins with open positions
+	TopCandidates     string // The TopCandidateCount highest-ranked candidates
+	Candidates        string // All other candidates
+	TopCandidateCount int    // Number of candidates in the top tier
+}
+
+// marketDataFormats Format for each symbol shown in the prompt
+func (ctx *Context) marketDataFormats() map[string]string {
+	cfg := ctx.MarketDataFormat
+	formats := make(map[string]string)
+	for i, symbol := range rankedCandidates(ctx) {
+		if i < cfg.TopCandidateCount {
+			formats[symbol] = cfg.TopCandidates
+		} else {
+			formats[symbol] = cfg.Candidates
+		}
+	}
+	for _, pos := range ctx.Positions {
+		formats[pos.Symbol] = cfg.Positions
+	}
+	return formats
+}
+
+// formatMarketData Render one coin's market data in the given format
+func formatMarketData(data *market.Data, format string) string {
+	if format == DataFormatCompact {
+		return market.FormatCompact(data)
+	}
+	return market.Format(data)
+}
@@ -0,0 +1,49 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/market"
+	"strings"
+)
+
+// defaultDepegThresholdPct Default deviation from $1 (in %) that counts as a stablecoin depeg
+const defaultDepegThresholdPct = 0.5
+
+// depegThreshold Effective depeg threshold in percent
+func (ctx *Context) depegThreshold() float64 {
+	if ctx.DepegThresholdPct > 0 {
+		return ctx.DepegThresholdPct
+	}
+	return defaultDepegThresholdPct
+}
+
+// depegged Whether a stablecoin is currently beyond the depeg threshold
+func (ctx *Context) depegged() bool {
+	return ctx.Stablecoins != nil && ctx.Stablecoins.Depegged(ctx.depegThreshold())
+}
+
+// validateDepeg Reject new positions while a stablecoin is depegged (only when PauseOnDepeg is set)
+func validateDepeg(ctx *Context) error {
+	if !ctx.PauseOnDepeg || !ctx.depegged() {
+		return nil
+	}
+	coin, pct := ctx.Stablecoins.Deviation()
+	return fmt.Errorf("new positions are paused: %s is %+.2f%% off its $1 peg (threshold %g%%)", coin, pct, ctx.depegThreshold())
+}
+
+// buildDepegPrompt Stablecoin depeg warning (empty while all stablecoins hold their peg)
+func buildDepegPrompt(ctx *Context) string {
+	if !ctx.depegged() {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("⚠️ **STABLECOIN DEPEG**: " + market.FormatStablecoins(ctx.Stablecoins) + "\n")
+	sb.WriteString("Account equity, margin and PnL are stablecoin-denominated - USD-quoted prices are distorted and exchange stress is likely.")
+	if ctx.PauseOnDepeg {
+		sb.WriteString(" New positions are paused (open_long/open_short will be rejected); manage or close existing positions only.")
+	} else {
+		sb.WriteString(" Prefer reducing exposure over opening new positions.")
+	}
+	sb.WriteString("\n\n")
+	return sb.String()
+}
@@ -1,34 +1,50 @@
 package decision
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"nofx/calendar"
 	"nofx/market"
 	"nofx/mcp"
+	"nofx/news"
 	"nofx/pool"
+	"nofx/whale"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 )
 
 // PositionInfo Position information
 type PositionInfo struct {
-	Symbol                string  `json:"symbol"`
-	Side                  string  `json:"side"` // "long" or "short"
-	EntryPrice            float64 `json:"entry_price"`
-	MarkPrice             float64 `json:"mark_price"`
-	Quantity              float64 `json:"quantity"`
-	Leverage              int     `json:"leverage"`
-	UnrealizedPnL         float64 `json:"unrealized_pnl"`
-	UnrealizedPnLPct      float64 `json:"unrealized_pnl_pct"`
-	LiquidationPrice      float64 `json:"liquidation_price"`
-	MarginUsed            float64 `json:"margin_used"`
-	UpdateTime            int64   `json:"update_time"` // Position update timestamp (milliseconds)
-	StopLoss              float64 `json:"stop_loss,omitempty"`
-	TakeProfit            float64 `json:"take_profit,omitempty"`
-	InvalidationCondition string  `json:"invalidation_condition,omitempty"`
-	Confidence            int     `json:"confidence,omitempty"` // 0-100
-	RiskUSD               float64 `json:"risk_usd,omitempty"`
+	Symbol                string            `json:"symbol"`
+	Side                  string            `json:"side"` // "long" or "short"
+	EntryPrice            float64           `json:"entry_price"`
+	MarkPrice             float64           `json:"mark_price"`
+	Quantity              float64           `json:"quantity"`
+	Leverage              int               `json:"leverage"`
+	UnrealizedPnL         float64           `json:"unrealized_pnl"`
+	UnrealizedPnLPct      float64           `json:"unrealized_pnl_pct"`
+	LiquidationPrice      float64           `json:"liquidation_price"`
+	MarginUsed            float64           `json:"margin_used"`
+	UpdateTime            int64             `json:"update_time"` // Position update timestamp (milliseconds)
+	StopLoss              float64           `json:"stop_loss,omitempty"`
+	TakeProfit            float64           `json:"take_profit,omitempty"`
+	InvalidationCondition string            `json:"invalidation_condition,omitempty"`
+	Confidence            int               `json:"confidence,omitempty"` // 0-100
+	RiskUSD               float64           `json:"risk_usd,omitempty"`
+	TrailingActivation    float64           `json:"trailing_activation_price,omitempty"`
+	TrailingCallbackRate  float64           `json:"trailing_callback_rate,omitempty"` // Percent
+	TrailingATRMultiple   float64           `json:"trailing_atr_multiple,omitempty"`  // Rule-based trailing distance in ATRs (set by the executor, not the AI)
+	TrailingOnExchange    bool              `json:"trailing_on_exchange,omitempty"`   // Trailing stop is an exchange-native order instead of local stop updates
+	InvalidationRule      *InvalidationRule `json:"invalidation_rule,omitempty"`
+	InvalidationTriggered string            `json:"invalidation_triggered,omitempty"` // Description of the triggered rule (flag mode)
 }
 
 // AccountInfo Account information
@@ -44,8 +60,19 @@ type AccountInfo struct {
 
 // CandidateCoin Candidate coin (from coin pool)
 type CandidateCoin struct {
-	Symbol  string   `json:"symbol"`
-	Sources []string `json:"sources"` // Sources: "ai500" and/or "oi_top"
+	Symbol  string       `json:"symbol"`
+	Sources []string     `json:"sources"`         // Candidate sources, e.g. "ai500" and/or "oi_top"
+	Score   float64      `json:"score,omitempty"` // Highest score across sources (0 if no source scores it)
+	Tags    []string     `json:"tags,omitempty"`  // Tags attached by screeners
+	Ranks   []SourceRank `json:"ranks,omitempty"` // Rank and score within each source (watchlist entries are unranked)
+}
+
+// SourceRank A candidate's position within one source's list
+type SourceRank struct {
+	Source string  `json:"source"`
+	Rank   int     `json:"rank"`            // 1 = top of the source
+	Total  int     `json:"total"`           // Number of symbols the source returned
+	Score  float64 `json:"score,omitempty"` // Source score (0 if the source doesn't score)
 }
 
 // OITopData Open interest growth Top data (for AI decision reference)
@@ -58,75 +85,162 @@ type OITopData struct {
 	NetShort          float64 // Net short position
 }
 
-// Context Trading context (complete information passed to AI)
+// Context Trading context (complete information passed to AI).
+// Every field except PromptTemplate needs a JSON tag: Save/Load serialize the context as a whole
 type Context struct {
-	CurrentTime     string                  `json:"current_time"`
-	RuntimeMinutes  int                     `json:"runtime_minutes"`
-	CallCount       int                     `json:"call_count"`
-	Account         AccountInfo             `json:"account"`
-	Positions       []PositionInfo          `json:"positions"`
-	CandidateCoins  []CandidateCoin         `json:"candidate_coins"`
-	MarketDataMap   map[string]*market.Data `json:"-"` // Not serialized, but used internally
-	OITopDataMap    map[string]*OITopData   `json:"-"` // OI Top data mapping
-	Performance     interface{}             `json:"-"` // Historical performance analysis (logger.PerformanceAnalysis)
-	BTCETHLeverage  int                     `json:"-"` // BTC/ETH leverage multiplier (read from config)
-	AltcoinLeverage int                     `json:"-"` // Altcoin leverage multiplier (read from config)
+	CurrentTime          string                        `json:"current_time"`
+	RuntimeMinutes       int                           `json:"runtime_minutes"`
+	CallCount            int                           `json:"call_count"`
+	Account              AccountInfo                   `json:"account"`
+	Positions            []PositionInfo                `json:"positions"`
+	CandidateCoins       []CandidateCoin               `json:"candidate_coins"`
+	MarketDataMap        map[string]*market.Data       `json:"market_data"`                    // Market data per symbol
+	OITopDataMap         map[string]*OITopData         `json:"oi_top_data"`                    // OI Top data mapping
+	Performance          interface{}                   `json:"performance,omitempty"`          // Historical performance analysis (logger.PerformanceAnalysis)
+	BTCETHLeverage       int                           `json:"btc_eth_leverage"`               // BTC/ETH leverage multiplier (read from config)
+	AltcoinLeverage      int                           `json:"altcoin_leverage"`               // Altcoin leverage multiplier (read from config)
+	PromptTemplate       *template.Template            `json:"-"`                              // Custom system prompt template (nil = built-in rules)
+	MaxCorrections       int                           `json:"max_corrections"`                // Max correction rounds after validation failure (0 = default, <0 = disabled)
+	MinRiskReward        float64                       `json:"min_risk_reward"`                // Minimum risk-reward ratio for new positions (0 = default 3.0)
+	MaxPositions         int                           `json:"max_positions"`                  // Maximum concurrent positions (0 = default 3)
+	SymbolLimits         map[string]SymbolLimit        `json:"symbol_limits,omitempty"`        // Per-symbol leverage/sizing/risk overrides
+	RecentDecisions      []DecisionMemory              `json:"recent_decisions,omitempty"`     // Recent executed decisions with outcomes (oldest first)
+	PositionSizing       PositionSizing                `json:"position_sizing"`                // Optional Kelly sizing of new positions
+	TradeCooldownMinutes int                           `json:"trade_cooldown_minutes"`         // Minutes before a closed symbol may be re-opened (0 = default 15, <0 = disabled)
+	RecentCloses         map[string]int64              `json:"recent_closes,omitempty"`        // symbol -> last close time (milliseconds)
+	PromptTokenBudget    int                           `json:"prompt_token_budget,omitempty"`  // Approximate token limit for the user prompt (0 = unlimited)
+	MarketDataFormat     MarketDataFormat              `json:"market_data_format"`             // Full or compact market data per coin tier
+	Timeframes           map[string][]string           `json:"timeframes,omitempty"`           // Extra kline intervals per symbol ("default" applies to all others)
+	Indicators           []string                      `json:"indicators,omitempty"`           // Optional indicator series to compute (see market.OptionalIndicators)
+	CostModel            CostModel                     `json:"cost_model"`                     // Fees, slippage and hold time for the expected value check
+	PendingEntries       []PendingEntry                `json:"pending_entries,omitempty"`      // Resting limit entries that have not filled yet
+	HedgeMode            bool                          `json:"hedge_mode,omitempty"`           // Account holds long and short on the same symbol independently
+	MarketRegime         *market.RegimeData            `json:"market_regime,omitempty"`        // Overall market regime (from BTC)
+	Macro                *market.MacroData             `json:"macro,omitempty"`                // BTC dominance, total market cap and BTC 24h move (nil if unavailable)
+	FearGreed            bool                          `json:"fear_greed,omitempty"`           // Include the Fear & Greed index in the prompt
+	FearGreedIndex       *market.FearGreedData         `json:"fear_greed_index,omitempty"`     // Fear & Greed index with 7-day history (nil if disabled or unavailable)
+	News                 map[string]*news.SymbolNews   `json:"news,omitempty"`                 // Recent headlines per symbol (only symbols with news; nil if no provider configured)
+	Whales               map[string]*whale.SymbolFlow  `json:"whales,omitempty"`               // Last hour's large exchange inflows/outflows per symbol (nil if no provider configured)
+	Events               []calendar.Event              `json:"events,omitempty"`               // Upcoming calendar events and their no-new-positions windows (nil if no calendar loaded)
+	Stablecoins          *market.StablecoinData        `json:"stablecoins,omitempty"`          // USDT/USDC USD prices (nil if unavailable)
+	DepegThresholdPct    float64                       `json:"depeg_threshold_pct,omitempty"`  // Deviation from $1 that counts as a depeg (0 = default 0.5%)
+	PauseOnDepeg         bool                          `json:"pause_on_depeg,omitempty"`       // Reject new positions while a stablecoin is depegged
+	DataIssues           map[string][]string           `json:"data_issues,omitempty"`          // Market data quality issues per symbol (gaps, stale or zero-price klines)
+	ExcludedSymbols      []string                      `json:"excluded_symbols,omitempty"`     // Candidates dropped this cycle because their market data was unreliable
+	Blacklist            []string                      `json:"blacklist,omitempty"`            // User never-trade symbols (any decision other than closing is rejected)
+	Sectors              map[string]string             `json:"sectors,omitempty"`              // Sector per position/candidate symbol (L1, L2, meme, AI, DeFi; unmapped symbols absent)
+	MaxSectorPositions   int                           `json:"max_sector_positions,omitempty"` // Maximum concurrent positions per sector (0 = unlimited)
+	MaxSpreadPct         float64                       `json:"max_spread_pct,omitempty"`       // Skip candidates with a wider bid/ask spread in % (0 = default 0.1, negative disables)
+	MinDepthMultiple     float64                       `json:"min_depth_multiple,omitempty"`   // Skip candidates whose ±0.5% depth is below this multiple of the max position value (0 = default 1, negative disables)
+	MaxToolRounds        int                           `json:"max_tool_rounds,omitempty"`      // Rounds of native tool calls (order book, klines) the AI may make per response (0 = tools disabled)
+	ExchangeRules        bool                          `json:"exchange_rules,omitempty"`       // Check opens against the exchange's symbol rules (only when the rules match the traded exchange)
+	SymbolInfo           map[string]*market.SymbolInfo `json:"symbol_info,omitempty"`          // Tick/step size, min notional and leverage brackets per symbol (nil unless ExchangeRules)
+	Charts               ChartConfig                   `json:"charts"`                         // Candlestick chart images for vision-capable models (MaxSymbols 0 = disabled)
+	ChartImages          []*market.ChartImage          `json:"chart_images,omitempty"`         // Charts rendered this cycle, attached to the user prompt in order
+}
+
+// defaultMinRiskReward Default minimum risk-reward ratio (1:3)
+const defaultMinRiskReward = 3.0
+
+// minRiskReward Minimum risk-reward ratio for new positions
+func (ctx *Context) minRiskReward() float64 {
+	if ctx.MinRiskReward > 0 {
+		return ctx.MinRiskReward
+	}
+	return defaultMinRiskReward
+}
+
+// Trailing stop callback rate bounds (percent)
+const (
+	minTrailingCallbackRate = 0.1
+	maxTrailingCallbackRate = 10.0
+)
+
+// maxPositions Maximum number of concurrent positions
+func (ctx *Context) maxPositions() int {
+	if ctx.MaxPositions > 0 {
+		return ctx.MaxPositions
+	}
+	return defaultMaxPositions
 }
 
 // Decision AI trading decision
 type Decision struct {
-	Symbol                string  `json:"symbol"`
-	Action                string  `json:"action"` // "open_long", "open_short", "close_long", "close_short", "hold", "wait"
-	Leverage              int     `json:"leverage,omitempty"`
-	PositionSizeUSD       float64 `json:"position_size_usd,omitempty"`
-	StopLoss              float64 `json:"stop_loss,omitempty"`
-	TakeProfit            float64 `json:"take_profit,omitempty"`
-	InvalidationCondition string  `json:"invalidation_condition,omitempty"` // Mandatory for new positions
-	Confidence            int     `json:"confidence,omitempty"`             // Confidence level (0-100)
-	RiskUSD               float64 `json:"risk_usd,omitempty"`                // Maximum USD risk
-	Reasoning             string  `json:"reasoning"`
+	Symbol                string            `json:"symbol"`
+	Action                string            `json:"action"` // "open_long", "open_short", "close_long", "close_short", "reduce_long", "reduce_short", "hold", "wait"
+	Leverage              int               `json:"leverage,omitempty"`
+	PositionSizeUSD       float64           `json:"position_size_usd,omitempty"`
+	StopLoss              float64           `json:"stop_loss,omitempty"`
+	TakeProfit            float64           `json:"take_profit,omitempty"`
+	InvalidationCondition string            `json:"invalidation_condition,omitempty"`    // Mandatory for new positions
+	Confidence            int               `json:"confidence,omitempty"`                // Confidence level (0-100)
+	RiskUSD               float64           `json:"risk_usd,omitempty"`                  // Maximum USD risk
+	ClosePercentage       float64           `json:"close_percentage,omitempty"`          // Percentage of position to close for reduce_* (0-100, exclusive)
+	TrailingActivation    float64           `json:"trailing_activation_price,omitempty"` // Optional: price at which the trailing stop starts following
+	TrailingCallbackRate  float64           `json:"trailing_callback_rate,omitempty"`    // Optional: trailing distance from the best price, in percent
+	InvalidationRule      *InvalidationRule `json:"invalidation_rule,omitempty"`         // Optional: machine-checkable form of invalidation_condition
+	EntryType             string            `json:"entry_type,omitempty"`                // Optional: "market" (default) or "limit"
+	EntryPrice            float64           `json:"entry_price,omitempty"`               // Limit entries: resting order price
+	ExpiryMinutes         int               `json:"expiry_minutes,omitempty"`            // Limit entries: cancel if not filled in time (0 = default 60)
+	Reasoning             string            `json:"reasoning"`
 }
 
 // FullDecision AI's complete decision (includes chain of thought)
 type FullDecision struct {
-	UserPrompt string     `json:"user_prompt"` // Input prompt sent to AI
-	CoTTrace   string     `json:"cot_trace"`   // Chain of thought analysis (AI output)
-	Decisions  []Decision `json:"decisions"`   // Specific decision list
-	Timestamp  time.Time  `json:"timestamp"`
+	UserPrompt     string              `json:"user_prompt"`               // Input prompt sent to AI
+	RawResponse    string              `json:"raw_response,omitempty"`    // Final raw AI response (input for Replay)
+	Provider       string              `json:"provider,omitempty"`        // provider/model that produced the response (a fallback after failover)
+	CoTTrace       string              `json:"cot_trace"`                 // Chain of thought analysis (AI output)
+	CoTSections    *CoTSections        `json:"cot_sections,omitempty"`    // Chain of thought split into labeled sections (nil if the model used no headings)
+	Decisions      []Decision          `json:"decisions"`                 // Specific decision list
+	ModelTraces    []ModelTrace        `json:"model_traces,omitempty"`    // Per-model outputs (ensemble mode only)
+	Corrections    int                 `json:"corrections,omitempty"`     // Number of correction rounds after validation failures
+	HookRejections []string            `json:"hook_rejections,omitempty"` // Decisions dropped by pre-execution hooks
+	Vetoes         []string            `json:"vetoes,omitempty"`          // Decisions turned into "wait" by the risk officer
+	PromptBudget   *PromptBudgetReport `json:"prompt_budget,omitempty"`   // What was cut from the user prompt to fit the token budget
+	Timestamp      time.Time           `json:"timestamp"`
 }
 
-// GetFullDecision Get AI's complete trading decision (batch analyze all symbols and positions)
-func GetFullDecision(ctx *Context, mcpClient *mcp.Client) (*FullDecision, error) {
+// GetFullDecision Get AI's complete trading decision (batch analyze all symbols and positions).
+// cycleCtx carries the cycle's deadline and is cancelled on shutdown; market fetches and AI calls stop when it ends
+func GetFullDecision(cycleCtx context.Context, ctx *Context, mcpClient *mcp.Client) (*FullDecision, error) {
 	// 1. Fetch market data for all symbols
-	if err := fetchMarketDataForContext(ctx); err != nil {
+	if err := fetchMarketDataForContext(cycleCtx, ctx); err != nil {
 		return nil, fmt.Errorf("failed to fetch market data: %w", err)
 	}
 
 	// 2. Build System Prompt (fixed rules, can be cached) and User Prompt (dynamic data)
-	systemPrompt := buildSystemPrompt(ctx.BTCETHLeverage, ctx.AltcoinLeverage)
-	userPrompt := buildUserPrompt(ctx)
-
-	// 3. Call AI API (using system + user prompt)
-	aiResponse, err := mcpClient.CallWithMessages(systemPrompt, userPrompt)
+	systemPrompt, err := renderSystemPrompt(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call AI API: %w", err)
+		return nil, err
 	}
+	userPrompt, budgetReport := buildBudgetedUserPrompt(ctx)
 
-	// 4. Parse AI response
-	decision, err := parseFullDecisionResponse(aiResponse, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse AI response: %w", err)
+	// 3. Call AI API and parse response (structured output when the provider supports it)
+	decision, err := callAndParse(cycleCtx, ctx, mcpClient, systemPrompt, userPrompt)
+	if decision == nil {
+		return nil, err
+	}
+
+	// 4. Run user-registered hooks on validated decisions
+	if err == nil {
+		decision.Decisions, decision.HookRejections = applyDecisionHooks(decision.Decisions, ctx)
 	}
 
 	decision.Timestamp = time.Now()
 	decision.UserPrompt = userPrompt // Save input prompt
-	return decision, nil
+	decision.PromptBudget = budgetReport
+	return decision, err
 }
 
 // fetchMarketDataForContext Fetch market data and OI data for all symbols in context
-func fetchMarketDataForContext(ctx *Context) error {
+// (optional sources are skipped once cycleCtx has ended)
+func fetchMarketDataForContext(cycleCtx context.Context, ctx *Context) error {
 	ctx.MarketDataMap = make(map[string]*market.Data)
 	ctx.OITopDataMap = make(map[string]*OITopData)
+	ctx.DataIssues = make(map[string][]string)
+	ctx.ExcludedSymbols = nil
+	ctx.SymbolInfo = nil
 
 	// Collect all symbols that need data
 	symbolSet := make(map[string]bool)
@@ -145,24 +259,64 @@ func fetchMarketDataForContext(ctx *Context) error {
 		symbolSet[coin.Symbol] = true
 	}
 
-	// Concurrently fetch market data
 	// Position symbol set (used to determine whether to skip OI check)
 	positionSymbols := make(map[string]bool)
 	for _, pos := range ctx.Positions {
 		positionSymbols[pos.Symbol] = true
 	}
 
-	for symbol := range symbolSet {
-		data, err := market.Get(symbol)
-		if err != nil {
-			// Single symbol failure doesn't affect overall, just log error
+	// Concurrently fetch market data (bounded workers, per-symbol timeout)
+	results := fetchMarketDataConcurrently(cycleCtx, symbolSet, ctx.timeframesFor, ctx.Indicators)
+	if err := cycleCtx.Err(); err != nil {
+		return fmt.Errorf("market data fetch aborted: %w", err)
+	}
+	ctx.loadMarketRegime(cycleCtx, results)
+
+	// Macro context (doesn't affect main flow)
+	macro, err := market.GetMacro()
+	if err != nil {
+		log.Printf("⚠️  Failed to fetch macro market data: %v", err)
+	}
+	ctx.Macro = macro
+
+	// Stablecoin prices (margin is stablecoin-denominated; doesn't affect main flow)
+	if ctx.Stablecoins, err = market.GetStablecoins(); err != nil {
+		log.Printf("⚠️  Failed to fetch stablecoin prices: %v", err)
+	} else if ctx.depegged() {
+		log.Printf("⚠️  %s", market.FormatStablecoins(ctx.Stablecoins))
+	}
+
+	ctx.FearGreedIndex = nil
+	if ctx.FearGreed {
+		if ctx.FearGreedIndex, err = market.GetFearGreed(); err != nil {
+			log.Printf("⚠️  Failed to fetch Fear & Greed index: %v", err)
+		}
+	}
+
+	var fetchErrs []error
+	for symbol, result := range results {
+		if result.err != nil {
+			// Single symbol failure doesn't affect overall, errors are aggregated below
+			fetchErrs = append(fetchErrs, fmt.Errorf("%s: %w", symbol, result.err))
 			continue
 		}
+		data := result.data
 
 		// ⚠️ Liquidity filter: Skip symbols with open interest value below 15M USD (no long or short)
 		// Open interest value = Open interest × Current price
 		// But existing positions must be kept (need to decide whether to close)
 		isExistingPosition := positionSymbols[symbol]
+
+		// Data quality: stale or zero-price candidates are dropped, positions are kept and annotated
+		if len(data.DataIssues) > 0 {
+			ctx.DataIssues[symbol] = data.DataIssues
+			if data.Unreliable && !isExistingPosition {
+				log.Printf("⚠️  %s market data unreliable, skipping symbol: %s", symbol, strings.Join(data.DataIssues, "; "))
+				ctx.ExcludedSymbols = append(ctx.ExcludedSymbols, symbol)
+				continue
+			}
+		}
+
 		if !isExistingPosition && data.OpenInterest != nil && data.CurrentPrice > 0 {
 			// Calculate open interest value (USD) = Open interest × Current price
 			oiValue := data.OpenInterest.Latest * data.CurrentPrice
@@ -174,9 +328,62 @@ func fetchMarketDataForContext(ctx *Context) error {
 			}
 		}
 
+		// Order book filter: skip candidates whose spread or ±0.5% depth can't absorb a full-size position
+		if !isExistingPosition {
+			if issue := ctx.liquidityIssue(data); issue != "" {
+				log.Printf("⚠️  %s order book too thin (%s), skipping symbol", symbol, issue)
+				continue
+			}
+		}
+
 		ctx.MarketDataMap[symbol] = data
 	}
 
+	if ctx.ExchangeRules {
+		ctx.loadSymbolInfo()
+	}
+
+	if len(fetchErrs) > 0 {
+		err := errors.Join(fetchErrs...)
+		log.Printf("⚠️  Market data unavailable for %d/%d symbols:\n%v", len(fetchErrs), len(symbolSet), err)
+		if len(fetchErrs) == len(symbolSet) {
+			return err
+		}
+	}
+
+	// Recent headlines (doesn't affect main flow)
+	ctx.News = nil
+	if news.Enabled() {
+		symbols := make([]string, 0, len(ctx.MarketDataMap))
+		for symbol := range ctx.MarketDataMap {
+			symbols = append(symbols, symbol)
+		}
+		sort.Strings(symbols)
+		if ctx.News, err = news.Get(symbols); err != nil {
+			log.Printf("⚠️  Failed to fetch news: %v", err)
+		}
+	}
+
+	// Calendar events (doesn't affect main flow)
+	ctx.Events = nil
+	if calendar.Enabled() {
+		if ctx.Events, err = calendar.Upcoming(ctx.now()); err != nil {
+			log.Printf("⚠️  Failed to load calendar: %v", err)
+		}
+	}
+
+	// Whale transfers (doesn't affect main flow)
+	ctx.Whales = nil
+	if whale.Enabled() {
+		symbols := make([]string, 0, len(ctx.MarketDataMap))
+		for symbol := range ctx.MarketDataMap {
+			symbols = append(symbols, symbol)
+		}
+		if ctx.Whales, err = whale.Get(symbols); err != nil {
+			log.Printf("⚠️  Failed to fetch whale transfers: %v", err)
+		}
+	}
+
 	// Load OI Top data (doesn't affect main flow)
 	oiPositions, err := pool.GetOITopPositions()
 	if err == nil {
@@ -194,9 +401,62 @@ func fetchMarketDataForContext(ctx *Context) error {
 		}
 	}
 
+	// Chart images for vision-capable models (doesn't affect main flow)
+	ctx.loadCharts(cycleCtx)
+
 	return nil
 }
 
+// Market data fetch limits
+const (
+	marketDataWorkers = 8                // Concurrent requests to the exchange
+	marketDataTimeout = 15 * time.Second // Per-symbol timeout
+)
+
+// marketDataResult Market data (or error) for one symbol
+type marketDataResult struct {
+	data *market.Data
+	err  error
+}
+
+// fetchMarketDataConcurrently Fetch market data for all symbols with a bounded worker pool
+func fetchMarketDataConcurrently(cycleCtx context.Context, symbols map[string]bool, timeframes func(symbol string) []string, indicators []string) map[string]marketDataResult {
+	results := make(map[string]marketDataResult, len(symbols))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, marketDataWorkers)
+
+	for symbol := range symbols {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(symbol string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			data, err := fetchMarketDataWithTimeout(cycleCtx, symbol, timeframes(symbol), indicators)
+			mu.Lock()
+			results[symbol] = marketDataResult{data: data, err: err}
+			mu.Unlock()
+		}(symbol)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// fetchMarketDataWithTimeout market.GetWithIndicators with a deadline: the requests are cancelled
+// when marketDataTimeout elapses or cycleCtx ends, so a hung request no longer blocks the cycle
+func fetchMarketDataWithTimeout(cycleCtx context.Context, symbol string, intervals, indicators []string) (*market.Data, error) {
+	ctx, cancel := context.WithTimeout(cycleCtx, marketDataTimeout)
+	defer cancel()
+
+	data, err := market.GetWithIndicatorsContext(ctx, symbol, intervals, indicators)
+	if err != nil && cycleCtx.Err() == nil && ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("timed out after %v", marketDataTimeout)
+	}
+	return data, err
+}
+
 // calculateMaxCandidates Calculate the number of candidate coins to analyze based on account status
 func calculateMaxCandidates(ctx *Context) int {
 	// Directly return the total number of coins in candidate pool
@@ -207,8 +467,10 @@ func calculateMaxCandidates(ctx *Context) int {
 
 // buildSystemPrompt Build System Prompt (fixed rules, can be cached)
 // Note: accountEquity is NOT included here to enable prompt caching - it changes during runtime
-func buildSystemPrompt(btcEthLeverage, altcoinLeverage int) string {
+func buildSystemPrompt(ctx *Context) string {
 	var sb strings.Builder
+	btcEthLeverage, altcoinLeverage := ctx.BTCETHLeverage, ctx.AltcoinLeverage
+	minRR := ctx.minRiskReward()
 
 	// === Core Mission ===
 	sb.WriteString("You are an expert systematic cryptocurrency futures trader. Your primary objectives are:\n\n")
@@ -225,23 +487,13 @@ func buildSystemPrompt(btcEthLeverage, altcoinLeverage int) string {
 	// === Position Management ===
 	sb.WriteString("# 📊 Position Management\n\n")
 	sb.WriteString(fmt.Sprintf("- Altcoins: 0.8x-1.5x account equity (%dx leverage) | BTC/ETH: 5x-10x account equity (%dx leverage)\n", altcoinLeverage, btcEthLeverage))
-	sb.WriteString("- Maximum 3 positions total (quality > quantity)\n")
+	sb.WriteString(buildSymbolLimitsPrompt(ctx))
+	sb.WriteString(fmt.Sprintf("- Maximum %d positions total (quality > quantity)\n", ctx.maxPositions()))
 	sb.WriteString("- **No pyramiding allowed** - Size positions correctly from the start, no adding to existing positions\n")
-	sb.WriteString("- Only one position per coin at a time\n")
-	sb.WriteString("- For each coin, choose exactly ONE action per trading cycle:\n")
-	sb.WriteString("  - **open_long** - Enter a long position (only if flat)\n")
-	sb.WriteString("  - **open_short** - Enter a short position (only if flat)\n")
-	sb.WriteString("  - **close_long** - Exit long position\n")
-	sb.WriteString("  - **close_short** - Exit short position\n")
-	sb.WriteString("  - **hold** - Maintain existing position\n")
-	sb.WriteString("  - **wait** - No action, wait for better opportunity\n\n")
+	sb.WriteString(buildActionRules(ctx))
 
 	// === Hard Constraints (Risk Control) ===
-	sb.WriteString("# ⚖️ Hard Constraints (Risk Control)\n\n")
-	sb.WriteString("1. **Risk-Reward Ratio**: Must be ≥ 1:3 (take 1% risk, earn 3%+ profit) - This is the MINIMUM threshold\n")
-	sb.WriteString("2. **Maximum Positions**: 3 symbols (quality > quantity)\n")
-	sb.WriteString("3. **Margin**: Total usage rate ≤ 90%\n")
-	sb.WriteString("4. **Transaction Costs**: Always factor in fees, slippage, and funding rates in profit calculations\n\n")
+	sb.WriteString(buildHardConstraints(ctx))
 
 	// === Short Trading Incentive ===
 	sb.WriteString("# 📉 Long/Short Balance\n\n")
@@ -253,7 +505,7 @@ func buildSystemPrompt(btcEthLeverage, altcoinLeverage int) string {
 	sb.WriteString(fmt.Sprintf("- Use leverage based on confidence level (maximum: Altcoins %dx, BTC/ETH %dx)\n", altcoinLeverage, btcEthLeverage))
 	sb.WriteString("- Always set: **Stop loss**, **Take profit**, **Invalidation condition**\n")
 	sb.WriteString("- Risk per trade should be calibrated based on confidence level (0-100 scale)\n")
-	sb.WriteString("- Risk-reward ratio must be ≥ 1:3 (minimum threshold)\n\n")
+	sb.WriteString(fmt.Sprintf("- Risk-reward ratio must be ≥ 1:%g (minimum threshold)\n\n", minRR))
 
 	// === Trading Frequency Awareness ===
 	sb.WriteString("# ⏱️ Trading Frequency Awareness\n\n")
@@ -270,8 +522,36 @@ func buildSystemPrompt(btcEthLeverage, altcoinLeverage int) string {
 	sb.WriteString("Only open positions on **strong signals**, wait if uncertain.\n\n")
 	sb.WriteString("**Complete data you have access to**:\n")
 	sb.WriteString("- 📊 **Raw sequences**: 3-minute price sequence (MidPrices array) + 4-hour candlestick sequence\n")
-	sb.WriteString("- 📈 **Technical sequences**: EMA20 sequence, MACD sequence, RSI7 sequence, RSI14 sequence\n")
+	if len(ctx.Timeframes) > 0 {
+		sb.WriteString(fmt.Sprintf("- 🕒 **Timeframes**: %s\n", describeTimeframes(ctx)))
+	}
+	sb.WriteString("- 📈 **Technical sequences**: EMA20 sequence, MACD sequence, RSI7 sequence, RSI14 sequence, Bollinger Band width, ATR14, session VWAP, ADX14 (trend strength)\n")
+	if len(ctx.Indicators) > 0 {
+		sb.WriteString(fmt.Sprintf("- 🧮 **Optional indicators**: %s\n", describeIndicators(ctx)))
+	}
 	sb.WriteString("- 💰 **Capital sequences**: Volume sequence, Open Interest (OI) sequence, funding rate\n")
+	sb.WriteString("- 🔀 **Taker flow**: Taker buy vs sell volume per candle and cumulative volume delta (CVD) - CVD rising while price stalls means absorption by passive sellers, price rising on falling CVD is a weak, short-covering move\n")
+	sb.WriteString("- 📚 **Order book**: Spread, top-of-book and depth within ±0.5% - keep position_size_usd small relative to the depth on the side you trade, thin books mean extra slippage\n")
+	sb.WriteString("- 💥 **Liquidations**: Long vs short liquidation volume over 5m / 1h - clusters of forced selling (longs) or buying (shorts) mark cascades, squeezes and exhaustion points\n")
+	sb.WriteString("- 🔀 **Spot–perp basis**: Perp premium / discount to spot (now and per intraday candle) - a widening premium means leveraged longs are overheated, a discount means shorts are crowded\n")
+	sb.WriteString("- 🧱 **Key levels**: Nearest supports / resistances from swing highs/lows, the prior day range and volume nodes - place stop-losses beyond the nearest level and take-profits just before the next one\n")
+	sb.WriteString("- 📊 **Volume profile**: Intraday POC and value area - acceptance outside value favours continuation, a rejection back inside favours rotation to the POC\n")
+	sb.WriteString("- 🥇 **Screener rank**: Each candidate's rank (and score, where the source scores) within every source that picked it - a top-ranked coin picked by several sources deserves more weight than one scraping the bottom of a single list\n")
+	sb.WriteString("- 🏷️ **Screener tags**: Why a candidate was picked - `short_squeeze` (extreme negative funding or a flip to negative: crowded shorts, fuel for a squeeze up) and `long_squeeze` (extreme positive funding: crowded longs, vulnerable to a flush) need a trigger, not just the funding reading\n")
+	sb.WriteString("- ⚖️ **Long/short ratio**: Top-trader position ratio vs all-account ratio (now and 1h ago) - crowded retail positioning against top traders often precedes a move the other way\n")
+	sb.WriteString("- 🌐 **Macro**: BTC 24h move, BTC dominance and TOTAL / TOTAL2 (ex-BTC) market cap changes - rising dominance with falling TOTAL2 means money is leaving altcoins, so frame every altcoin long against BTC\n")
+	if len(ctx.Events) > 0 {
+		sb.WriteString("- 📅 **Calendar**: Scheduled high-impact events (FOMC, CPI, token unlocks) - expect spreads to widen and stops to be run around them; avoid opening just before one and never hold a tight stop through it. Opens inside a marked no-new-positions window are rejected\n")
+	}
+	if ctx.Whales != nil {
+		sb.WriteString("- 🐋 **Whale transfers**: Large on-chain transfers into / out of exchanges over the last hour - big inflows are potential sell pressure, big outflows are coins leaving to be held; a flow signal to weigh against price action, not a trigger on its own\n")
+	}
+	if ctx.News != nil {
+		sb.WriteString("- 📰 **News**: Latest headlines per coin with a coarse sentiment score - never open against a fresh major headline (listing, delisting, hack, unlock, ETF); let the first move settle before fading it\n")
+	}
+	if ctx.FearGreed {
+		sb.WriteString("- 😱 **Fear & Greed index**: Contrarian sentiment gauge (0 extreme fear - 100 extreme greed) with 7-day history - extreme greed favors tighter profit taking and caution on new longs, extreme fear favors caution on new shorts; use it as a filter, never as an entry signal on its own\n")
+	}
 	sb.WriteString("- 🎯 **Filter tags**: AI500 score / OI_Top ranking (if annotated)\n\n")
 	sb.WriteString("**Analysis methods** (completely up to you):\n")
 	sb.WriteString("- Freely use sequence data, you can do but not limited to: trend analysis, pattern recognition, support/resistance, technical resistance levels, Fibonacci, volatility band calculations\n")
@@ -282,7 +562,13 @@ func buildSystemPrompt(btcEthLeverage, altcoinLeverage int) string {
 	sb.WriteString("- Single dimension (only looking at one indicator)\n")
 	sb.WriteString("- Contradictory (price up but volume declining)\n")
 	sb.WriteString("- Sideways consolidation\n")
-	sb.WriteString("- Just closed position recently (<15 minutes)\n\n")
+	if cooldown := ctx.tradeCooldown(); cooldown > 0 {
+		sb.WriteString(fmt.Sprintf("- Just closed position recently (<%d minutes, enforced: such opens are rejected)\n\n", int(cooldown.Minutes())))
+	} else {
+		sb.WriteString("- Just closed position recently\n\n")
+	}
+
+	writeRegimeGuidance(&sb)
 
 	// === Sharpe Ratio Self-Evolution ===
 	sb.WriteString("# 🧬 Sharpe Ratio Self-Evolution\n\n")
@@ -321,19 +607,7 @@ func buildSystemPrompt(btcEthLeverage, altcoinLeverage int) string {
 	sb.WriteString("- Think in terms of risk-adjusted returns, not just absolute profits\n\n")
 
 	// === Output Format ===
-	sb.WriteString("# 📤 Output Format\n\n")
-	sb.WriteString("**Chain of Thought**: Before making decisions, analyze:\n")
-	sb.WriteString("1. Current market conditions and trend direction\n")
-	sb.WriteString("2. Position review - check all invalidation conditions\n")
-	sb.WriteString("3. Risk assessment - available capital and position sizing\n")
-	sb.WriteString("4. Entry/exit logic based on technical indicators\n")
-	sb.WriteString("5. Confidence calibration based on signal strength\n\n")
-	sb.WriteString("**JSON Decision Array**:\n\n")
-	sb.WriteString("```json\n[\n")
-	sb.WriteString(fmt.Sprintf("  {\"symbol\": \"BTCUSDT\", \"action\": \"open_short\", \"leverage\": %d, \"position_size_usd\": 5000, \"stop_loss\": 97000, \"take_profit\": 91000, \"confidence\": 85, \"risk_usd\": 300, \"reasoning\": \"Downtrend + MACD bearish crossover\", \"invalidation_condition\": \"If 4-hour MACD crosses above 500\"},\n", btcEthLeverage))
-	sb.WriteString("  {\"symbol\": \"ETHUSDT\", \"action\": \"close_long\", \"reasoning\": \"Invalidation condition triggered\"}\n")
-	sb.WriteString("]\n```\n\n")
-    sb.WriteString("**Required for opening positions**: symbol, action, leverage, position_size_usd, stop_loss, take_profit, invalidation_condition, confidence, risk_usd, reasoning\n\n")
+	sb.WriteString(buildOutputRules(ctx))
 
 	// === Key Reminders ===
 	sb.WriteString("---\n\n")
@@ -342,20 +616,85 @@ func buildSystemPrompt(btcEthLeverage, altcoinLeverage int) string {
 	sb.WriteString("- Avoid over-trading - Quality over quantity\n")
 	sb.WriteString("- Invalidation conditions are mandatory - Monitor constantly\n")
 	sb.WriteString("- Confidence-based scaling - Use confidence for leverage and risk\n")
-	sb.WriteString("- Risk-reward ratio ≥ 1:3 - Never compromise\n")
+	sb.WriteString(fmt.Sprintf("- Risk-reward ratio ≥ 1:%g - Never compromise\n", minRR))
 	sb.WriteString("- Shorting = Longing - Both are profit tools\n")
 	sb.WriteString("- Better to miss than make low-quality trades\n")
 
 	return sb.String()
 }
 
+// buildActionRules Position-mode rules and the allowed actions (shared with custom templates as {{.ActionRules}})
+func buildActionRules(ctx *Context) string {
+	var sb strings.Builder
+	sb.WriteString(describePositionMode(ctx))
+	sb.WriteString("- **Correlated coins are one trade** - Coins listed under Correlated Coins move together; a same-direction position in a coin highly correlated with one you hold doubles the same risk instead of diversifying it\n")
+	sb.WriteString("- For each coin, choose exactly ONE action per trading cycle:\n")
+	sb.WriteString("  - **open_long** - Enter a long position (only if flat)\n")
+	sb.WriteString("  - **open_short** - Enter a short position (only if flat)\n")
+	sb.WriteString("  - **close_long** - Exit long position\n")
+	sb.WriteString("  - **close_short** - Exit short position\n")
+	sb.WriteString("  - **reduce_long** / **reduce_short** - Take partial profit: close `close_percentage`% of the position, keep the rest running with the original stop loss and take profit\n")
+	sb.WriteString("  - **hold** - Maintain existing position\n")
+	sb.WriteString("  - **wait** - No action, wait for better opportunity\n\n")
+	return sb.String()
+}
+
+// buildHardConstraints Risk limits enforced by the validator (shared with custom templates as {{.HardConstraints}})
+func buildHardConstraints(ctx *Context) string {
+	var sb strings.Builder
+	minRR := ctx.minRiskReward()
+	sb.WriteString("# ⚖️ Hard Constraints (Risk Control)\n\n")
+	sb.WriteString(fmt.Sprintf("1. **Risk-Reward Ratio**: Must be ≥ 1:%g (take 1%% risk, earn %g%%+ profit) - This is the MINIMUM threshold\n", minRR, minRR))
+	sb.WriteString(fmt.Sprintf("2. **Maximum Positions**: %d symbols (quality > quantity)\n", ctx.maxPositions()))
+	sb.WriteString("3. **Margin**: Total usage rate ≤ 90%\n")
+	sb.WriteString(fmt.Sprintf("4. **Transaction Costs**: %s\n", describeCostModel(ctx)))
+	sb.WriteString(fmt.Sprintf("5. **Volatility-Scaled Stops**: Stop loss must be ≥ %gx the coin's intraday ATR14 (ATR%% of price is in each coin's Volatility line) - in high volatility, widen the stop and cut position_size_usd so risk_usd stays the same, rather than using a fixed notional\n", minStopATRMultiple))
+	if cooldown := ctx.tradeCooldown(); cooldown > 0 {
+		sb.WriteString(fmt.Sprintf("6. **Cooldown**: No new position in a coin within %d minutes of closing it (such opens are rejected)\n", int(cooldown.Minutes())))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// buildOutputRules Output format and field rules the parser expects (shared with custom templates as {{.OutputRules}})
+func buildOutputRules(ctx *Context) string {
+	var sb strings.Builder
+	btcEthLeverage := ctx.BTCETHLeverage
+	sb.WriteString("# 📤 Output Format\n\n")
+	sb.WriteString(buildCoTInstructions())
+	sb.WriteString("**JSON Decision Array**:\n\n")
+	sb.WriteString("```json\n[\n")
+	sb.WriteString(fmt.Sprintf("  {\"symbol\": \"BTCUSDT\", \"action\": \"open_short\", \"leverage\": %d, \"position_size_usd\": 5000, \"stop_loss\": 97000, \"take_profit\": 91000, \"confidence\": 85, \"risk_usd\": 300, \"reasoning\": \"Downtrend + MACD bearish crossover\", \"invalidation_condition\": \"If 4-hour MACD crosses above 500\", \"invalidation_rule\": {\"indicator\": \"4h_macd\", \"op\": \">\", \"value\": 500}},\n", btcEthLeverage))
+	sb.WriteString("  {\"symbol\": \"ETHUSDT\", \"action\": \"close_long\", \"reasoning\": \"Invalidation condition triggered\"},\n")
+	sb.WriteString("  {\"symbol\": \"SOLUSDT\", \"action\": \"reduce_short\", \"close_percentage\": 50, \"reasoning\": \"First target reached, lock in half\"}\n")
+	sb.WriteString("]\n```\n\n")
+	sb.WriteString("**Required for opening positions**: symbol, action, leverage, position_size_usd, stop_loss, take_profit, invalidation_condition, confidence, risk_usd, reasoning\n")
+	sb.WriteString("**Required for reducing positions**: symbol, action, close_percentage, reasoning\n")
+	sb.WriteString(fmt.Sprintf("**Optional trailing stop (opening only)**: trailing_activation_price (between stop loss and take profit) + trailing_callback_rate (%g-%g%%). Once price reaches activation, the stop loss follows the best price at the callback distance and only ever tightens\n", minTrailingCallbackRate, maxTrailingCallbackRate))
+	sb.WriteString(fmt.Sprintf("**Optional limit entry (opening only)**: entry_type \"limit\" + entry_price + expiry_minutes (1-%d, default %d) rests an order at support/resistance instead of buying/selling at market. entry_price must be between stop loss and take profit, below the current price for longs and above it for shorts. Unfilled orders are cancelled at expiry; stop loss and take profit are placed once it fills\n", maxLimitExpiryMinutes, defaultLimitExpiryMinutes))
+	sb.WriteString(fmt.Sprintf("**Optional invalidation_rule (opening only)**: {\"indicator\", \"op\", \"value\"} checked automatically every cycle; when it triggers the position is closed or flagged for you. op: >, >=, <, <=. indicator: %s\n\n", invalidationIndicatorNames()))
+	return sb.String()
+}
+
+// userPromptStatusLine Opening line of the user prompt (runtime, time and call count change on every call)
+const userPromptStatusLine = "It has been %d minutes since you started trading. The current time is %s and you've been invoked %d times. Below, we are providing you with a variety of state data, price data, and predictive signals so you can discover alpha. Below that is your current account information, value, performance, positions, etc."
+
+// volatileStatusPattern Matches the status line's per-call values
+var volatileStatusPattern = regexp.MustCompile(`It has been \d+ minutes since you started trading\. The current time is .+? and you've been invoked \d+ times\.`)
+
+func init() {
+	// The response cache must ignore the status line, otherwise a restart or dry run can never reuse a response
+	mcp.SetCacheNormalizer(func(content string) string {
+		return volatileStatusPattern.ReplaceAllString(content, "")
+	})
+}
+
 // buildUserPrompt Build User Prompt (dynamic data)
 func buildUserPrompt(ctx *Context) string {
 	var sb strings.Builder
 
 	// System status
-	sb.WriteString(fmt.Sprintf("It has been %d minutes since you started trading. The current time is %s and you've been invoked %d times. Below, we are providing you with a variety of state data, price data, and predictive signals so you can discover alpha. Below that is your current account information, value, performance, positions, etc.\n\n",
-		ctx.RuntimeMinutes, ctx.CurrentTime, ctx.CallCount))
+	sb.WriteString(fmt.Sprintf(userPromptStatusLine+"\n\n", ctx.RuntimeMinutes, ctx.CurrentTime, ctx.CallCount))
 
 	// Explicit ordering statement
 	sb.WriteString("**ALL OF THE PRICE OR SIGNAL DATA BELOW IS ORDERED: OLDEST → NEWEST**\n\n")
@@ -363,11 +702,22 @@ func buildUserPrompt(ctx *Context) string {
 
 	// Show all coins' market data upfront (equal treatment)
 	sb.WriteString("## CURRENT MARKET STATE FOR ALL COINS\n\n")
-	
+	if ctx.Macro != nil {
+		sb.WriteString("Macro: " + market.FormatMacro(ctx.Macro) + "\n\n")
+	}
+	if ctx.MarketRegime != nil {
+		sb.WriteString("Overall market (BTC): " + market.FormatRegime(ctx.MarketRegime) + "\n\n")
+	}
+	if ctx.FearGreedIndex != nil {
+		sb.WriteString(market.FormatFearGreed(ctx.FearGreedIndex) + "\n\n")
+	}
+	sb.WriteString(buildCalendarPrompt(ctx))
+	sb.WriteString(buildDepegPrompt(ctx))
+
 	// Collect all symbols to display
 	allSymbols := make([]string, 0)
 	symbolSet := make(map[string]bool)
-	
+
 	// Add position symbols
 	for _, pos := range ctx.Positions {
 		if !symbolSet[pos.Symbol] {
@@ -375,29 +725,69 @@ func buildUserPrompt(ctx *Context) string {
 			symbolSet[pos.Symbol] = true
 		}
 	}
-	
+
 	// Add candidate coin symbols
+	candidateTags := make(map[string][]string)
+	candidateRanks := make(map[string][]SourceRank)
 	for _, coin := range ctx.CandidateCoins {
 		if !symbolSet[coin.Symbol] && ctx.MarketDataMap[coin.Symbol] != nil {
 			allSymbols = append(allSymbols, coin.Symbol)
 			symbolSet[coin.Symbol] = true
 		}
+		if len(coin.Tags) > 0 {
+			candidateTags[coin.Symbol] = coin.Tags
+		}
+		if len(coin.Ranks) > 0 {
+			candidateRanks[coin.Symbol] = coin.Ranks
+		}
 	}
-	
-	// Display all coins' data
+
+	// Display all coins' data (full or compact depending on the coin's tier)
+	formats := ctx.marketDataFormats()
 	for _, symbol := range allSymbols {
 		marketData := ctx.MarketDataMap[symbol]
 		if marketData == nil {
 			continue
 		}
-		
+
 		// Get coin name (remove USDT suffix for display)
 		coinName := strings.Replace(symbol, "USDT", "", 1)
-		sb.WriteString(fmt.Sprintf("### ALL %s DATA\n\n", coinName))
-		sb.WriteString(market.Format(marketData))
+		if formats[symbol] == DataFormatCompact {
+			sb.WriteString(fmt.Sprintf("### %s DATA (compact summary)\n\n", coinName))
+		} else {
+			sb.WriteString(fmt.Sprintf("### ALL %s DATA\n\n", coinName))
+		}
+		if sector := ctx.sector(symbol); sector != "" {
+			sb.WriteString(fmt.Sprintf("Sector: %s\n\n", sector))
+		}
+		if tags := candidateTags[symbol]; len(tags) > 0 {
+			sb.WriteString(fmt.Sprintf("Screener tags: %s\n\n", strings.Join(tags, ", ")))
+		}
+		if ranks := candidateRanks[symbol]; len(ranks) > 0 {
+			sb.WriteString(fmt.Sprintf("Screener rank: %s\n\n", formatSourceRanks(ranks)))
+		}
+		sb.WriteString(formatMarketData(marketData, formats[symbol]))
+		if n := ctx.News[symbol]; n != nil {
+			sb.WriteString(news.Format(n))
+		}
+		if w := ctx.Whales[symbol]; w != nil {
+			sb.WriteString(whale.Format(w))
+		}
 		sb.WriteString("\n")
 	}
 
+	// Never-trade symbols
+	sb.WriteString(buildBlacklistPrompt(ctx))
+	sb.WriteString(buildSectorPrompt(ctx))
+
+	// Candidates dropped for unreliable market data
+	if len(ctx.ExcludedSymbols) > 0 {
+		sb.WriteString(fmt.Sprintf("Excluded this cycle (stale or invalid market data, do not trade): %s\n\n", strings.Join(ctx.ExcludedSymbols, ", ")))
+	}
+
+	// Coins that move together (one trade, not several)
+	sb.WriteString(buildCorrelationPrompt(ctx, allSymbols))
+
 	// Account information with Total Return %
 	sb.WriteString("## HERE IS YOUR ACCOUNT INFORMATION & PERFORMANCE\n\n")
 	sb.WriteString(fmt.Sprintf("Current Total Return (percent): %.2f%%\n\n", ctx.Account.TotalPnLPct))
@@ -407,36 +797,65 @@ func buildUserPrompt(ctx *Context) string {
 
 	// Positions with exit plan
 	if len(ctx.Positions) > 0 {
+		hedged := hedgedSides(ctx)
 		for _, pos := range ctx.Positions {
 			// Calculate notional USD
 			notionalUSD := pos.Quantity * pos.MarkPrice
-			
+
 			sb.WriteString(fmt.Sprintf("{'symbol': '%s', 'quantity': %.2f, 'entry_price': %.2f, 'current_price': %.2f, 'liquidation_price': %.2f, 'unrealized_pnl': %.2f, 'leverage': %d, 'side': '%s'",
 				pos.Symbol, pos.Quantity, pos.EntryPrice, pos.MarkPrice, pos.LiquidationPrice, pos.UnrealizedPnL, pos.Leverage, pos.Side))
-			
+			if hedged[pos.Symbol] {
+				sb.WriteString(fmt.Sprintf(", 'hedged_with': '%s'", oppositeSide(pos.Side)))
+			}
+
 			// Add exit plan if available
 			if pos.StopLoss > 0 || pos.TakeProfit > 0 || pos.InvalidationCondition != "" {
 				sb.WriteString(fmt.Sprintf(", 'exit_plan': {'profit_target': %.2f, 'stop_loss': %.2f, 'invalidation_condition': '%s'}",
 					pos.TakeProfit, pos.StopLoss, pos.InvalidationCondition))
 			}
-			
+			if pos.InvalidationRule != nil {
+				sb.WriteString(fmt.Sprintf(", 'invalidation_rule': '%s'", pos.InvalidationRule))
+			}
+			if pos.InvalidationTriggered != "" {
+				sb.WriteString(fmt.Sprintf(", 'INVALIDATION_TRIGGERED': '%s'", pos.InvalidationTriggered))
+			}
+			if pos.TrailingCallbackRate > 0 {
+				sb.WriteString(fmt.Sprintf(", 'trailing_stop': {'activation_price': %.2f, 'callback_rate_pct': %.2f}",
+					pos.TrailingActivation, pos.TrailingCallbackRate))
+			} else if pos.TrailingATRMultiple > 0 {
+				sb.WriteString(fmt.Sprintf(", 'trailing_stop': {'activation_price': %.2f, 'atr_multiple': %g}",
+					pos.TrailingActivation, pos.TrailingATRMultiple))
+			}
+
 			// Add confidence and risk if available
 			if pos.Confidence > 0 {
 				// Convert confidence from 0-100 to 0-1 scale for display
 				confidence01 := float64(pos.Confidence) / 100.0
 				sb.WriteString(fmt.Sprintf(", 'confidence': %.2f", confidence01))
 			}
-			
+
 			if pos.RiskUSD > 0 {
 				sb.WriteString(fmt.Sprintf(", 'risk_usd': %.2f", pos.RiskUSD))
 			}
-			
+
 			sb.WriteString(fmt.Sprintf(", 'notional_usd': %.2f}\n\n", notionalUSD))
 		}
 	} else {
 		sb.WriteString("None\n\n")
 	}
-	
+
+	// Resting limit entries
+	sb.WriteString(buildPendingEntriesPrompt(ctx))
+
+	// Symbols that cannot be re-opened yet
+	sb.WriteString(buildCooldownPrompt(ctx))
+
+	// Recent decisions and their outcomes
+	sb.WriteString(buildDecisionMemoryPrompt(ctx))
+
+	// Stated confidence vs realized win rate
+	sb.WriteString(buildCalibrationPrompt(ctx))
+
 	// Sharpe Ratio
 	if ctx.Performance != nil {
 		type PerformanceData struct {
@@ -453,26 +872,41 @@ func buildUserPrompt(ctx *Context) string {
 	return sb.String()
 }
 
-// parseFullDecisionResponse Parse AI's complete decision response
-func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthLeverage, altcoinLeverage int) (*FullDecision, error) {
-	// 1. Extract chain of thought
-	cotTrace := extractCoTTrace(aiResponse)
+// formatSourceRanks Render source ranks, e.g. "ai500 #3 of 20 (score 87.5), oi_top #5 of 20"
+func formatSourceRanks(ranks []SourceRank) string {
+	parts := make([]string, 0, len(ranks))
+	for _, r := range ranks {
+		part := fmt.Sprintf("%s #%d of %d", r.Source, r.Rank, r.Total)
+		if r.Score != 0 {
+			part += fmt.Sprintf(" (score %.1f)", r.Score)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
 
-	// 2. Extract JSON decision list
-	decisions, err := extractDecisions(aiResponse)
+// parseFullDecisionResponse Parse AI's complete decision response
+func parseFullDecisionResponse(aiResponse string, ctx *Context) (*FullDecision, error) {
+	// 1. Extract JSON decision list
+	decisions, jsonStart, err := extractDecisions(aiResponse)
 	if err != nil {
 		return &FullDecision{
-			CoTTrace:  cotTrace,
+			CoTTrace:  extractCoTTrace(aiResponse),
 			Decisions: []Decision{},
-		}, fmt.Errorf("failed to extract decisions: %w\n\n=== AI Chain of Thought ===\n%s", err, cotTrace)
+		}, fmt.Errorf("failed to extract decisions: %w", err)
 	}
 
-	// 3. Validate decisions
-	if err := validateDecisions(decisions, accountEquity, btcEthLeverage, altcoinLeverage); err != nil {
+	// 2. Chain of thought is everything before the decision JSON
+	cotTrace := strings.TrimSpace(aiResponse[:jsonStart])
+	cotTrace = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(cotTrace, "```json"), "```"))
+
+	// 3. Validate decisions (symbols written in another naming convention are mapped first)
+	resolveDecisionSymbols(decisions, ctx)
+	if err := validateDecisions(decisions, ctx); err != nil {
 		return &FullDecision{
 			CoTTrace:  cotTrace,
 			Decisions: decisions,
-		}, fmt.Errorf("decision validation failed: %w\n\n=== AI Chain of Thought ===\n%s", err, cotTrace)
+		}, fmt.Errorf("decision validation failed: %w", err)
 	}
 
 	return &FullDecision{
@@ -495,37 +929,114 @@ func extractCoTTrace(response string) string {
 	return strings.TrimSpace(response)
 }
 
-// extractDecisions 提取JSON决策列表
-func extractDecisions(response string) ([]Decision, error) {
-	// 直接查找JSON数组 - 找第一个完整的JSON数组
-	arrayStart := strings.Index(response, "[")
-	if arrayStart == -1 {
-		return nil, fmt.Errorf("无法找到JSON数组起始")
+// extractDecisions 提取JSON决策列表，返回决策及JSON在响应中的起始位置
+// 兼容：markdown代码块、{"decisions": [...]} 对象包装、单个决策对象、尾随逗号，
+// 并跳过思维链中出现的非决策数组（如 [1h, 4h]）
+func extractDecisions(response string) ([]Decision, int, error) {
+	var emptyStart = -1
+	var lastErr error
+
+	for start := 0; start < len(response); start++ {
+		if response[start] != '[' && response[start] != '{' {
+			continue
+		}
+		end := findMatchingBracket(response, start)
+		if end == -1 {
+			continue
+		}
+
+		decisions, err := parseDecisionJSON(response[start : end+1])
+		if err != nil {
+			// 跳过整个片段，避免把解析失败的数组中的单个对象误当作完整决策
+			lastErr = err
+			start = end
+			continue
+		}
+		if len(decisions) == 0 {
+			// 空数组可能只是思维链中的示例，继续寻找非空决策，找不到时再使用
+			if emptyStart == -1 {
+				emptyStart = start
+			}
+			start = end
+			continue
+		}
+		return decisions, start, nil
 	}
 
-	// 从 [ 开始，匹配括号找到对应的 ]
-	arrayEnd := findMatchingBracket(response, arrayStart)
-	if arrayEnd == -1 {
-		return nil, fmt.Errorf("无法找到JSON数组结束")
+	if emptyStart != -1 {
+		return []Decision{}, emptyStart, nil
+	}
+	if lastErr != nil {
+		return nil, 0, fmt.Errorf("无法找到有效的JSON决策: %w", lastErr)
 	}
+	return nil, 0, fmt.Errorf("无法找到JSON数组起始")
+}
 
-	jsonContent := strings.TrimSpace(response[arrayStart : arrayEnd+1])
+// parseDecisionJSON 解析单个JSON片段：决策数组、{"decisions": [...]} 包装或单个决策对象
+func parseDecisionJSON(jsonContent string) ([]Decision, error) {
+	// 🔧 修复常见的JSON格式错误：中文引号、尾随逗号
+	jsonContent = removeTrailingCommas(fixMissingQuotes(strings.TrimSpace(jsonContent)))
 
-	// 🔧 修复常见的JSON格式错误：缺少引号的字段值
-	// 匹配: "reasoning": 内容"}  或  "reasoning": 内容}  (没有引号)
-	// 修复为: "reasoning": "内容"}
-	// 使用简单的字符串扫描而不是正则表达式
-	jsonContent = fixMissingQuotes(jsonContent)
+	if strings.HasPrefix(jsonContent, "{") {
+		var wrapper map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(jsonContent), &wrapper); err != nil {
+			return nil, fmt.Errorf("JSON解析失败: %w\nJSON内容: %s", err, jsonContent)
+		}
+		if raw, exists := wrapper["decisions"]; exists {
+			jsonContent = string(raw)
+		} else if _, hasAction := wrapper["action"]; hasAction {
+			jsonContent = "[" + jsonContent + "]"
+		} else {
+			return nil, fmt.Errorf("JSON对象不是决策: %s", jsonContent)
+		}
+	}
 
-	// 解析JSON
 	var decisions []Decision
 	if err := json.Unmarshal([]byte(jsonContent), &decisions); err != nil {
 		return nil, fmt.Errorf("JSON解析失败: %w\nJSON内容: %s", err, jsonContent)
 	}
-
+	for _, d := range decisions {
+		if d.Symbol == "" || d.Action == "" {
+			return nil, fmt.Errorf("JSON数组不是决策列表（缺少symbol或action）: %s", jsonContent)
+		}
+	}
 	return decisions, nil
 }
 
+// removeTrailingCommas 删除 ] 或 } 前多余的逗号（忽略字符串内部）
+func removeTrailingCommas(jsonStr string) string {
+	var sb strings.Builder
+	inString, escaped := false, false
+	for i := 0; i < len(jsonStr); i++ {
+		c := jsonStr[i]
+		if inString {
+			sb.WriteByte(c)
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		if c == '"' {
+			inString = true
+		}
+		if c == ',' {
+			j := i + 1
+			for j < len(jsonStr) && strings.ContainsRune(" \t\r\n", rune(jsonStr[j])) {
+				j++
+			}
+			if j < len(jsonStr) && (jsonStr[j] == ']' || jsonStr[j] == '}') {
+				continue
+			}
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
 // fixMissingQuotes Replace Chinese quotes with English quotes (avoid IME auto-conversion)
 func fixMissingQuotes(jsonStr string) string {
 	jsonStr = strings.ReplaceAll(jsonStr, "\u201c", "\"") // "
@@ -535,28 +1046,89 @@ func fixMissingQuotes(jsonStr string) string {
 	return jsonStr
 }
 
-// validateDecisions Validate all decisions (requires account info and leverage config)
-func validateDecisions(decisions []Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int) error {
+// validateDecisions Validate all decisions (requires account info and risk config from context)
+func validateDecisions(decisions []Decision, ctx *Context) error {
 	for i, decision := range decisions {
-		if err := validateDecision(&decision, accountEquity, btcEthLeverage, altcoinLeverage); err != nil {
+		if err := validateDecision(&decision, ctx); err != nil {
 			return fmt.Errorf("decision #%d validation failed: %w", i+1, err)
 		}
 	}
+	if err := validatePositionCount(decisions, ctx); err != nil {
+		return err
+	}
+	if err := validateSectorExposure(decisions, ctx); err != nil {
+		return err
+	}
+	return validateSides(decisions, ctx)
+}
+
+// defaultMaxPositions Default maximum number of concurrent positions (enforced by validatePositionCount)
+const defaultMaxPositions = 3
+
+// validatePositionCount Ensure opens don't push the position count above the limit (closes are executed first)
+func validatePositionCount(decisions []Decision, ctx *Context) error {
+	held := make(map[string]bool)
+	for _, pos := range ctx.Positions {
+		held[pos.Symbol+"_"+pos.Side] = true
+	}
+
+	count := len(held)
+	for _, d := range decisions {
+		var key string
+		switch d.Action {
+		case "close_long":
+			key = d.Symbol + "_long"
+		case "close_short":
+			key = d.Symbol + "_short"
+		default:
+			continue
+		}
+		if held[key] {
+			delete(held, key)
+			count--
+		}
+	}
+
+	maxPositions := ctx.maxPositions()
+	for i, d := range decisions {
+		if d.Action != "open_long" && d.Action != "open_short" {
+			continue
+		}
+		count++
+		if count > maxPositions {
+			return fmt.Errorf("decision #%d validation failed: %s %s would exceed the maximum of %d positions (currently holding %d)",
+				i+1, d.Action, d.Symbol, maxPositions, len(ctx.Positions))
+		}
+	}
 	return nil
 }
 
-// findMatchingBracket Find matching closing bracket
+// findMatchingBracket Find matching closing bracket for '[' or '{' (brackets inside strings are ignored)
 func findMatchingBracket(s string, start int) int {
-	if start >= len(s) || s[start] != '[' {
+	if start >= len(s) || (s[start] != '[' && s[start] != '{') {
 		return -1
 	}
 
 	depth := 0
+	inString, escaped := false, false
 	for i := start; i < len(s); i++ {
-		switch s[i] {
-		case '[':
+		c := s[i]
+		if inString {
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '[', '{':
 			depth++
-		case ']':
+		case ']', '}':
 			depth--
 			if depth == 0 {
 				return i
@@ -568,30 +1140,62 @@ func findMatchingBracket(s string, start int) int {
 }
 
 // validateDecision Validate single decision validity
-func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoinLeverage int) error {
+func validateDecision(d *Decision, ctx *Context) error {
+	accountEquity := ctx.Account.TotalEquity
+
 	// Validate action
 	validActions := map[string]bool{
-		"open_long":   true,
-		"open_short":  true,
-		"close_long":  true,
-		"close_short": true,
-		"hold":        true,
-		"wait":        true,
+		"open_long":    true,
+		"open_short":   true,
+		"close_long":   true,
+		"close_short":  true,
+		"reduce_long":  true,
+		"reduce_short": true,
+		"hold":         true,
+		"wait":         true,
 	}
 
 	if !validActions[d.Action] {
 		return fmt.Errorf("invalid action: %s", d.Action)
 	}
+	if err := validateBlacklist(d, ctx); err != nil {
+		return err
+	}
+
+	// Partial close must reference an existing position and a sane percentage
+	if d.Action == "reduce_long" || d.Action == "reduce_short" {
+		if d.ClosePercentage <= 0 || d.ClosePercentage >= 100 {
+			return fmt.Errorf("close_percentage must be between 0-100 (exclusive) for %s, use close_long/close_short to exit fully: %.2f", d.Action, d.ClosePercentage)
+		}
+		side := strings.TrimPrefix(d.Action, "reduce_")
+		found := false
+		for _, pos := range ctx.Positions {
+			if pos.Symbol == d.Symbol && pos.Side == side {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s has no %s position to reduce", d.Symbol, side)
+		}
+	}
 
 	// Opening positions must provide complete parameters
 	if d.Action == "open_long" || d.Action == "open_short" {
-		// Use configured leverage limit based on symbol
-		maxLeverage := altcoinLeverage          // Altcoins use configured leverage
-		maxPositionValue := accountEquity * 1.5 // Altcoins max 1.5x account equity
-		if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
-			maxLeverage = btcEthLeverage          // BTC and ETH use configured leverage
-			maxPositionValue = accountEquity * 10 // BTC/ETH max 10x account equity
+		// Use configured limits based on symbol (BTC/ETH vs altcoin defaults, per-symbol overrides)
+		if err := validateCooldown(d, ctx); err != nil {
+			return err
+		}
+		if err := validateEventBlackout(d, ctx); err != nil {
+			return err
 		}
+		if err := validateDepeg(ctx); err != nil {
+			return err
+		}
+
+		limit := ctx.symbolLimit(d.Symbol)
+		maxLeverage := limit.MaxLeverage
+		maxPositionValue := accountEquity * limit.MaxPositionMultiple
 
 		if d.Leverage <= 0 || d.Leverage > maxLeverage {
 			return fmt.Errorf("leverage must be between 1-%d (%s, current config limit %dx): %d", maxLeverage, d.Symbol, maxLeverage, d.Leverage)
@@ -602,15 +1206,20 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 		// Validate position value limit (add 1% tolerance to avoid floating point precision issues)
 		tolerance := maxPositionValue * 0.01 // 1% tolerance
 		if d.PositionSizeUSD > maxPositionValue+tolerance {
-			if d.Symbol == "BTCUSDT" || d.Symbol == "ETHUSDT" {
-				return fmt.Errorf("BTC/ETH single symbol position value cannot exceed %.0f USDT (10x account equity), actual: %.0f", maxPositionValue, d.PositionSizeUSD)
-			} else {
-				return fmt.Errorf("altcoin single symbol position value cannot exceed %.0f USDT (1.5x account equity), actual: %.0f", maxPositionValue, d.PositionSizeUSD)
+			return fmt.Errorf("%s single symbol position value cannot exceed %.0f USDT (%gx account equity), actual: %.0f", d.Symbol, maxPositionValue, limit.MaxPositionMultiple, d.PositionSizeUSD)
+		}
+		if limit.MaxRiskPct > 0 {
+			maxRiskUSD := accountEquity * limit.MaxRiskPct / 100
+			if d.RiskUSD > maxRiskUSD {
+				return fmt.Errorf("%s risk_usd cannot exceed %.2f USDT (%g%% of account equity), actual: %.2f", d.Symbol, maxRiskUSD, limit.MaxRiskPct, d.RiskUSD)
 			}
 		}
 		if d.StopLoss <= 0 || d.TakeProfit <= 0 {
 			return fmt.Errorf("stop loss and take profit must be greater than 0")
 		}
+		if err := validateExchangeRules(d, ctx); err != nil {
+			return err
+		}
 
 		// Validate invalidation condition is provided (MANDATORY)
 		if d.InvalidationCondition == "" {
@@ -631,15 +1240,38 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 			}
 		}
 
-		// Validate risk-reward ratio (must be ≥1:3)
-		// Calculate entry price (assume current market price)
-		var entryPrice float64
-		if d.Action == "open_long" {
-			// Long: entry price between stop loss and take profit
-			entryPrice = d.StopLoss + (d.TakeProfit-d.StopLoss)*0.2 // Assume entry at 20% position
-		} else {
-			// Short: entry price between stop loss and take profit
-			entryPrice = d.StopLoss - (d.StopLoss-d.TakeProfit)*0.2 // Assume entry at 20% position
+		// Validate optional limit entry
+		if err := validateEntry(d, ctx); err != nil {
+			return err
+		}
+
+		// Validate optional machine-checkable invalidation rule
+		if d.InvalidationRule != nil {
+			if err := d.InvalidationRule.Validate(); err != nil {
+				return err
+			}
+		}
+
+		// Validate optional trailing stop
+		if d.TrailingActivation != 0 || d.TrailingCallbackRate != 0 {
+			if d.TrailingCallbackRate < minTrailingCallbackRate || d.TrailingCallbackRate > maxTrailingCallbackRate {
+				return fmt.Errorf("trailing_callback_rate must be between %g-%g%%: %.2f", minTrailingCallbackRate, maxTrailingCallbackRate, d.TrailingCallbackRate)
+			}
+			if d.TrailingActivation <= math.Min(d.StopLoss, d.TakeProfit) || d.TrailingActivation >= math.Max(d.StopLoss, d.TakeProfit) {
+				return fmt.Errorf("trailing_activation_price must be between stop loss and take profit: %.4f [Stop Loss:%.4f Take Profit:%.4f]",
+					d.TrailingActivation, d.StopLoss, d.TakeProfit)
+			}
+		}
+
+		// Validate risk-reward ratio (must be ≥ configured minimum, default 1:3)
+		// Entry price: limit entries use their own price, market entries fill at the current market price
+		entryPrice := d.EntryPrice
+		if !d.IsLimitEntry() {
+			marketData, exists := ctx.MarketDataMap[d.Symbol]
+			if !exists || marketData.CurrentPrice <= 0 {
+				return fmt.Errorf("%s has no current market price, cannot check risk-reward ratio", d.Symbol)
+			}
+			entryPrice = marketData.CurrentPrice
 		}
 
 		var riskPercent, rewardPercent, riskRewardRatio float64
@@ -657,10 +1289,21 @@ func validateDecision(d *Decision, accountEquity float64, btcEthLeverage, altcoi
 			}
 		}
 
-		// Hard constraint: risk-reward ratio must be ≥3.0
-		if riskRewardRatio < 3.0 {
-			return fmt.Errorf("risk-reward ratio too low (%.2f:1), must be ≥3.0:1 [Risk:%.2f%% Reward:%.2f%%] [Stop Loss:%.2f Take Profit:%.2f]",
-				riskRewardRatio, riskPercent, rewardPercent, d.StopLoss, d.TakeProfit)
+		// Hard constraint: risk-reward ratio must be ≥ minimum
+		minRR := ctx.minRiskReward()
+		if riskRewardRatio < minRR {
+			return fmt.Errorf("risk-reward ratio too low (%.2f:1), must be ≥%.1f:1 [Risk:%.2f%% Reward:%.2f%%] [Entry:%.4f Stop Loss:%.2f Take Profit:%.2f]",
+				riskRewardRatio, minRR, riskPercent, rewardPercent, entryPrice, d.StopLoss, d.TakeProfit)
+		}
+
+		// Stop must clear normal intraday noise
+		if err := validateStopDistance(d, ctx); err != nil {
+			return err
+		}
+
+		// Fees, slippage and funding must leave a meaningful edge
+		if err := validateExpectedValue(d, ctx, entryPrice); err != nil {
+			return err
 		}
 	}
 
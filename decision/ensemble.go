@@ -0,0 +1,262 @@
+package decision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"nofx/mcp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ensemble merge modes
+const (
+	EnsembleMajority   = "majority"   // Action with the most votes wins
+	EnsembleConfidence = "confidence" // Action with the highest summed confidence wins
+)
+
+// EnsembleConfig Ensemble voting configuration
+type EnsembleConfig struct {
+	Mode     string // EnsembleMajority or EnsembleConfidence
+	MinAgree int    // Minimum number of models that must agree before an action is executed
+
+	// LatencyBudget Time the models get to answer; slower ones are cancelled and left out of the vote
+	// (0 waits for every model, bounded only by the cycle deadline)
+	LatencyBudget time.Duration
+}
+
+// ModelTrace Single model's output in ensemble mode (kept for audit)
+type ModelTrace struct {
+	Model       string       `json:"model"`
+	CoTTrace    string       `json:"cot_trace"`
+	CoTSections *CoTSections `json:"cot_sections,omitempty"`
+	RawResponse string       `json:"raw_response,omitempty"`
+	Decisions   []Decision   `json:"decisions"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// GetEnsembleDecision Call multiple AI models in parallel and merge their decisions by vote
+func GetEnsembleDecision(cycleCtx context.Context, ctx *Context, clients []*mcp.Client, cfg EnsembleConfig) (*FullDecision, error) {
+	if len(clients) == 0 {
+		return nil, fmt.Errorf("ensemble requires at least one AI client")
+	}
+	if cfg.MinAgree <= 0 {
+		cfg.MinAgree = len(clients)/2 + 1
+	}
+
+	// 1. Fetch market data once, shared by all models
+	if err := fetchMarketDataForContext(cycleCtx, ctx); err != nil {
+		return nil, fmt.Errorf("failed to fetch market data: %w", err)
+	}
+
+	// 2. All models receive identical prompts
+	systemPrompt, err := renderSystemPrompt(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userPrompt, budgetReport := buildBudgetedUserPrompt(ctx)
+
+	// 3. Call all models concurrently under a shared deadline; whoever hasn't answered by then is dropped
+	callCtx := cycleCtx
+	if cfg.LatencyBudget > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(cycleCtx, cfg.LatencyBudget)
+		defer cancel()
+	}
+	started := time.Now()
+	traces := make([]ModelTrace, len(clients))
+	errs := make([]error, len(clients))
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func(i int, client *mcp.Client) {
+			defer wg.Done()
+			trace := ModelTrace{Model: fmt.Sprintf("%s/%s", client.Provider.Name(), client.Model)}
+
+			decision, err := callAndParse(callCtx, ctx, client, systemPrompt, userPrompt)
+			if decision != nil {
+				if decision.Provider != "" {
+					trace.Model = decision.Provider // Failover may have answered with another model
+				}
+				trace.CoTTrace = decision.CoTTrace
+				trace.CoTSections = decision.CoTSections
+				trace.RawResponse = decision.RawResponse
+			}
+			if err != nil {
+				errs[i] = err
+				trace.Error = err.Error()
+				if callCtx.Err() != nil && cycleCtx.Err() == nil {
+					trace.Error = fmt.Sprintf("no valid response within the %v latency budget: %v", cfg.LatencyBudget, err)
+				}
+				traces[i] = trace
+				return
+			}
+			trace.Decisions = decision.Decisions
+			traces[i] = trace
+		}(i, client)
+	}
+	wg.Wait()
+
+	// 4. Merge votes from models that returned valid decisions
+	var valid [][]Decision
+	for _, trace := range traces {
+		if trace.Error != "" {
+			log.Printf("⚠️  Ensemble model %s failed: %s", trace.Model, trace.Error)
+			continue
+		}
+		valid = append(valid, trace.Decisions)
+	}
+	log.Printf("🗳️  Ensemble: %d/%d models answered in %v", len(valid), len(clients), time.Since(started).Round(time.Millisecond))
+	if len(valid) > 0 && len(valid) < cfg.MinAgree {
+		log.Printf("⚠️  Ensemble: only %d models answered, fewer than min_agree %d - no action can pass the vote", len(valid), cfg.MinAgree)
+	}
+
+	result := &FullDecision{
+		UserPrompt:   userPrompt,
+		CoTTrace:     joinModelTraces(traces),
+		ModelTraces:  traces,
+		PromptBudget: budgetReport,
+		Timestamp:    time.Now(),
+	}
+
+	if len(valid) == 0 {
+		result.Decisions = []Decision{}
+		if allCircuitOpen(errs) {
+			return result, fmt.Errorf("all %d ensemble models failed: %w", len(clients), mcp.ErrCircuitOpen)
+		}
+		return result, fmt.Errorf("all %d ensemble models failed", len(clients))
+	}
+
+	// Each model's list was validated on its own; the merged set must pass the cross-decision checks too
+	merged, dropped := dropInvalidDecisions(mergeDecisionVotes(valid, cfg), ctx)
+	for _, reason := range dropped {
+		log.Printf("  Ensemble: dropped %s", reason)
+	}
+	result.Decisions, result.HookRejections = applyDecisionHooks(merged, ctx)
+	result.HookRejections = append(dropped, result.HookRejections...)
+	return result, nil
+}
+
+// allCircuitOpen Whether every model failed only because its endpoint's circuit breaker is open
+func allCircuitOpen(errs []error) bool {
+	for _, err := range errs {
+		if !errors.Is(err, mcp.ErrCircuitOpen) {
+			return false
+		}
+	}
+	return len(errs) > 0
+}
+
+// dropInvalidDecisions Keep merged decisions while the kept set still passes validation (per-decision rules,
+// position count, sector exposure and sides), returning the reasons for dropped ones.
+// Closes and other non-open actions are considered before opens so freed slots count, opens in vote order.
+func dropInvalidDecisions(decisions []Decision, ctx *Context) ([]Decision, []string) {
+	kept := make([]Decision, 0, len(decisions))
+	var dropped []string
+	for _, opens := range []bool{false, true} {
+		for i := range decisions {
+			d := decisions[i]
+			if isOpen := d.Action == "open_long" || d.Action == "open_short"; isOpen != opens {
+				continue
+			}
+			if err := validateDecisions(append(kept[:len(kept):len(kept)], d), ctx); err != nil {
+				dropped = append(dropped, fmt.Sprintf("%s %s: %v", d.Symbol, d.Action, err))
+				continue
+			}
+			kept = append(kept, d)
+		}
+	}
+	return kept, dropped
+}
+
+// mergeDecisionVotes Merge per-model decision lists, keeping only actions that at least MinAgree models agree on
+func mergeDecisionVotes(modelDecisions [][]Decision, cfg EnsembleConfig) []Decision {
+	type actionVotes struct {
+		count      int
+		confidence int
+		best       Decision // Highest-confidence decision for this action (parameters are taken from it)
+	}
+
+	var symbolOrder []string
+	votes := make(map[string]map[string]*actionVotes) // symbol -> action -> votes
+
+	for _, decisions := range modelDecisions {
+		voted := make(map[string]bool) // Each model votes at most once per symbol+action
+		for _, d := range decisions {
+			key := d.Symbol + "|" + d.Action
+			if voted[key] {
+				continue
+			}
+			voted[key] = true
+
+			if _, exists := votes[d.Symbol]; !exists {
+				votes[d.Symbol] = make(map[string]*actionVotes)
+				symbolOrder = append(symbolOrder, d.Symbol)
+			}
+			v, exists := votes[d.Symbol][d.Action]
+			if !exists {
+				v = &actionVotes{best: d}
+				votes[d.Symbol][d.Action] = v
+			}
+			v.count++
+			v.confidence += d.Confidence
+			if d.Confidence > v.best.Confidence {
+				v.best = d
+			}
+		}
+	}
+
+	var merged []Decision
+	for _, symbol := range symbolOrder {
+		var winner *actionVotes
+		for _, v := range votes[symbol] {
+			if v.count < cfg.MinAgree {
+				continue
+			}
+			if winner == nil || ensembleScore(v.count, v.confidence, cfg.Mode) > ensembleScore(winner.count, winner.confidence, cfg.Mode) {
+				winner = v
+			}
+		}
+		if winner == nil {
+			log.Printf("  Ensemble: no action on %s reached %d votes, skipping", symbol, cfg.MinAgree)
+			continue
+		}
+
+		d := winner.best
+		d.Reasoning = fmt.Sprintf("[ensemble %d/%d] %s", winner.count, len(modelDecisions), d.Reasoning)
+		merged = append(merged, d)
+	}
+
+	if merged == nil {
+		merged = []Decision{}
+	}
+	return merged
+}
+
+// ensembleScore Score used to rank competing actions on the same symbol
+func ensembleScore(count, confidence int, mode string) int {
+	if mode == EnsembleConfidence {
+		return confidence
+	}
+	// Majority: vote count first, summed confidence breaks ties
+	return count*10000 + confidence
+}
+
+// joinModelTraces Concatenate all models' chain of thought for display
+func joinModelTraces(traces []ModelTrace) string {
+	var sb strings.Builder
+	for _, trace := range traces {
+		sb.WriteString(fmt.Sprintf("=== %s ===\n", trace.Model))
+		if trace.Error != "" {
+			sb.WriteString(fmt.Sprintf("ERROR: %s\n", trace.Error))
+		}
+		if trace.CoTTrace != "" {
+			sb.WriteString(trace.CoTTrace)
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSpace(sb.String())
+}
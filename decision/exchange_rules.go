@@ -0,0 +1,50 @@
+package decision
+
+import (
+	"fmt"
+	"log"
+	"nofx/market"
+)
+
+// loadSymbolInfo Fetch exchange symbol rules for every symbol with market data (missing rules skip the check)
+func (ctx *Context) loadSymbolInfo() {
+	ctx.SymbolInfo = make(map[string]*market.SymbolInfo, len(ctx.MarketDataMap))
+	for symbol := range ctx.MarketDataMap {
+		info, err := market.GetSymbolInfo(symbol)
+		if err != nil {
+			log.Printf("⚠️  %s symbol rules unavailable: %v", symbol, err)
+			continue
+		}
+		ctx.SymbolInfo[symbol] = info
+	}
+}
+
+// validateExchangeRules Reject opens the exchange would refuse: below min notional / min quantity, or leverage above the bracket for the size
+func validateExchangeRules(d *Decision, ctx *Context) error {
+	info, exists := ctx.SymbolInfo[d.Symbol]
+	if !exists {
+		return nil
+	}
+
+	if info.MinNotional > 0 && d.PositionSizeUSD < info.MinNotional {
+		return fmt.Errorf("%s position_size_usd %.2f is below the exchange minimum order value of %.2f USDT", d.Symbol, d.PositionSizeUSD, info.MinNotional)
+	}
+
+	entryPrice := d.EntryPrice
+	if !d.IsLimitEntry() {
+		if marketData, ok := ctx.MarketDataMap[d.Symbol]; ok {
+			entryPrice = marketData.CurrentPrice
+		}
+	}
+	if entryPrice > 0 && info.MinQty > 0 {
+		if quantity := info.FloorQuantity(d.PositionSizeUSD / entryPrice); quantity < info.MinQty {
+			return fmt.Errorf("%s position_size_usd %.2f buys %g at %.4f, below the exchange minimum quantity of %g", d.Symbol, d.PositionSizeUSD, quantity, entryPrice, info.MinQty)
+		}
+	}
+
+	if maxLeverage := info.MaxLeverage(d.PositionSizeUSD); maxLeverage > 0 && d.Leverage > maxLeverage {
+		return fmt.Errorf("%s allows at most %dx leverage for a %.0f USDT position (exchange leverage bracket), got %dx - lower leverage or position_size_usd",
+			d.Symbol, maxLeverage, d.PositionSizeUSD, d.Leverage)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package decision
+
+import (
+	"fmt"
+	"log"
+	"math"
+)
+
+// Cost model actions when a trade's take profit barely clears its costs
+const (
+	CostActionWarn   = "warn"   // Log a warning and keep the decision
+	CostActionReject = "reject" // Fail validation (the AI may correct it)
+)
+
+// Cost model defaults (Binance USDT-M taker fee, typical market-order slippage on liquid pairs)
+const (
+	defaultTakerFeePct     = 0.04 // Percent of notional per side
+	defaultSlippagePct     = 0.05 // Percent of notional per side
+	defaultHoldHours       = 8.0  // One funding interval
+	defaultMinCostMultiple = 2.0  // Net take-profit must be at least 2x the round-trip costs
+	fundingIntervalHours   = 8.0
+)
+
+// CostModel Trading costs used by the expected value check (zero fields use the defaults)
+type CostModel struct {
+	TakerFeePct     float64 // Taker fee per side, percent of notional
+	SlippagePct     float64 // Estimated slippage per side, percent of notional
+	HoldHours       float64 // Expected holding time used to project funding
+	MinCostMultiple float64 // Required net take-profit as a multiple of total costs
+	Action          string  // CostActionWarn (default) or CostActionReject
+}
+
+// withDefaults Cost model with zero fields replaced by defaults
+func (m CostModel) withDefaults() CostModel {
+	if m.TakerFeePct <= 0 {
+		m.TakerFeePct = defaultTakerFeePct
+	}
+	if m.SlippagePct <= 0 {
+		m.SlippagePct = defaultSlippagePct
+	}
+	if m.HoldHours <= 0 {
+		m.HoldHours = defaultHoldHours
+	}
+	if m.MinCostMultiple <= 0 {
+		m.MinCostMultiple = defaultMinCostMultiple
+	}
+	if m.Action == "" {
+		m.Action = CostActionWarn
+	}
+	return m
+}
+
+// TradeEV Expected value breakdown of an open decision (USDT)
+type TradeEV struct {
+	Fees       float64 // Round-trip taker fees
+	Slippage   float64 // Round-trip slippage
+	Funding    float64 // Projected funding over the hold time (negative = income)
+	NetProfit  float64 // Take-profit gain after costs
+	NetLoss    float64 // Stop-loss loss after costs
+	WinRate    float64 // Win probability used (confidence, 0-1)
+	ExpectedPL float64 // WinRate × NetProfit − (1 − WinRate) × NetLoss
+}
+
+// Costs Total round-trip costs
+func (ev TradeEV) Costs() float64 {
+	return ev.Fees + ev.Slippage + ev.Funding
+}
+
+// computeTradeEV Expected value of an open decision entered at entryPrice
+func computeTradeEV(d *Decision, entryPrice, fundingRate float64, model CostModel) TradeEV {
+	notional := d.PositionSizeUSD
+	grossProfit := notional * math.Abs(d.TakeProfit-entryPrice) / entryPrice
+	grossLoss := notional * math.Abs(entryPrice-d.StopLoss) / entryPrice
+
+	ev := TradeEV{
+		Fees:     notional * model.TakerFeePct / 100 * 2,
+		Slippage: notional * model.SlippagePct / 100 * 2,
+		// Longs pay positive funding, shorts receive it
+		Funding: notional * fundingRate * model.HoldHours / fundingIntervalHours,
+	}
+	if d.Action == "open_short" {
+		ev.Funding = -ev.Funding
+	}
+
+	ev.NetProfit = grossProfit - ev.Costs()
+	ev.NetLoss = grossLoss + ev.Costs()
+	ev.WinRate = float64(d.Confidence) / 100
+	if ev.WinRate <= 0 || ev.WinRate > 1 {
+		ev.WinRate = 0.5
+	}
+	ev.ExpectedPL = ev.WinRate*ev.NetProfit - (1-ev.WinRate)*ev.NetLoss
+	return ev
+}
+
+// validateExpectedValue Check that the take profit clears fees, slippage and funding by a safe margin
+func validateExpectedValue(d *Decision, ctx *Context, fallbackEntry float64) error {
+	model := ctx.CostModel.withDefaults()
+
+	entryPrice, fundingRate := fallbackEntry, 0.0
+	if marketData, exists := ctx.MarketDataMap[d.Symbol]; exists {
+		fundingRate = marketData.FundingRate
+		if marketData.CurrentPrice > 0 && !d.IsLimitEntry() {
+			entryPrice = marketData.CurrentPrice
+		}
+	}
+	if entryPrice <= 0 || d.PositionSizeUSD <= 0 {
+		return nil
+	}
+
+	ev := computeTradeEV(d, entryPrice, fundingRate, model)
+	if ev.NetProfit >= ev.Costs()*model.MinCostMultiple && ev.ExpectedPL > 0 {
+		return nil
+	}
+
+	err := fmt.Errorf("%s %s take profit barely clears trading costs: net profit %.2f vs costs %.2f USDT (fees %.2f, slippage %.2f, funding %.2f over %gh), expected P/L %.2f at %.0f%% confidence - widen the target or skip the trade",
+		d.Symbol, d.Action, ev.NetProfit, ev.Costs(), ev.Fees, ev.Slippage, ev.Funding, model.HoldHours, ev.ExpectedPL, ev.WinRate*100)
+	if model.Action == CostActionReject {
+		return err
+	}
+	log.Printf("⚠️  %v", err)
+	return nil
+}
+
+// describeCostModel Cost assumptions for the system prompt
+func describeCostModel(ctx *Context) string {
+	model := ctx.CostModel.withDefaults()
+	return fmt.Sprintf("Taker fee %.3f%% + slippage %.3f%% per side, plus funding over ~%gh of holding. Net take-profit must be ≥ %gx these costs with positive expected value at your confidence",
+		model.TakerFeePct, model.SlippagePct, model.HoldHours, model.MinCostMultiple)
+}
@@ -0,0 +1,92 @@
+package decision
+
+import (
+	"fmt"
+	"strings"
+)
+
+// oppositeSide "long" <-> "short"
+func oppositeSide(side string) string {
+	if side == "long" {
+		return "short"
+	}
+	return "long"
+}
+
+// openSide Side opened by an open_long / open_short decision ("" for other actions)
+func openSide(action string) string {
+	switch action {
+	case "open_long":
+		return "long"
+	case "open_short":
+		return "short"
+	}
+	return ""
+}
+
+// validateSides Check opens against the account's position mode (closes are executed first).
+// A side that is already held cannot be opened again, and both sides of a symbol cannot be opened in one cycle.
+// Without hedge mode a symbol holds one side only: opening the opposite side requires closing the current one in the same cycle.
+func validateSides(decisions []Decision, ctx *Context) error {
+	held := make(map[string]bool)
+	for _, pos := range ctx.Positions {
+		held[pos.Symbol+"_"+pos.Side] = true
+	}
+	for _, d := range decisions {
+		switch d.Action {
+		case "close_long":
+			delete(held, d.Symbol+"_long")
+		case "close_short":
+			delete(held, d.Symbol+"_short")
+		}
+	}
+
+	opened := make(map[string]bool)
+	for i, d := range decisions {
+		side := openSide(d.Action)
+		if side == "" {
+			continue
+		}
+		if held[d.Symbol+"_"+side] {
+			return fmt.Errorf("decision #%d validation failed: %s already has a %s position (no pyramiding, close it first to re-enter)", i+1, d.Symbol, side)
+		}
+		opposite := oppositeSide(side)
+		if opened[d.Symbol+"_"+opposite] {
+			return fmt.Errorf("decision #%d validation failed: cannot open both long and short on %s in the same cycle", i+1, d.Symbol)
+		}
+		if !ctx.HedgeMode && held[d.Symbol+"_"+opposite] {
+			return fmt.Errorf("decision #%d validation failed: %s holds a %s position and hedge mode is disabled, add close_%s before %s to reverse",
+				i+1, d.Symbol, opposite, opposite, d.Action)
+		}
+		opened[d.Symbol+"_"+side] = true
+	}
+	return nil
+}
+
+// hedgedSides Symbols holding both a long and a short position
+func hedgedSides(ctx *Context) map[string]bool {
+	sides := make(map[string]int)
+	for _, pos := range ctx.Positions {
+		sides[pos.Symbol]++
+	}
+	hedged := make(map[string]bool)
+	for symbol, n := range sides {
+		if n > 1 {
+			hedged[symbol] = true
+		}
+	}
+	return hedged
+}
+
+// describePositionMode Position mode rules for the system prompt
+func describePositionMode(ctx *Context) string {
+	var sb strings.Builder
+	if ctx.HedgeMode {
+		sb.WriteString("- **Hedge mode is ON**: a coin may hold a long and a short position at the same time. Each side is managed independently - ")
+		sb.WriteString("close_long / reduce_long only touch the long side, close_short / reduce_short only the short side\n")
+		sb.WriteString("- Only one position per coin and side; never open both sides of a coin in the same cycle\n")
+	} else {
+		sb.WriteString("- Only one position per coin at a time (to reverse, close the current side and open the other in the same cycle)\n")
+	}
+	return sb.String()
+}
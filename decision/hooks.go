@@ -0,0 +1,74 @@
+package decision
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DecisionHook Custom filter run on each validated decision before execution.
+// Returning an error drops that decision (the rest of the batch is still executed).
+type DecisionHook func(d *Decision, ctx *Context) error
+
+var (
+	hooksMu sync.RWMutex
+	hooks   []DecisionHook
+)
+
+// RegisterDecisionHook Add a hook to the pre-execution chain (hooks run in registration order)
+func RegisterDecisionHook(hook DecisionHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = append(hooks, hook)
+}
+
+// applyDecisionHooks Run the hook chain on every decision, returning kept decisions and rejection reasons
+func applyDecisionHooks(decisions []Decision, ctx *Context) ([]Decision, []string) {
+	var chain []DecisionHook
+	if kelly := kellySizingHook(ctx); kelly != nil {
+		chain = append(chain, kelly) // Built-in sizing runs first so user hooks see the final size
+	}
+	hooksMu.RLock()
+	chain = append(chain, hooks...)
+	hooksMu.RUnlock()
+
+	if len(chain) == 0 {
+		return decisions, nil
+	}
+
+	kept := make([]Decision, 0, len(decisions))
+	var rejections []string
+	for i := range decisions {
+		d := decisions[i]
+		if err := runHookChain(chain, &d, ctx); err != nil {
+			rejections = append(rejections, fmt.Sprintf("%s %s: %v", d.Symbol, d.Action, err))
+			continue
+		}
+		kept = append(kept, d)
+	}
+	return kept, rejections
+}
+
+// runHookChain Stop at the first hook that rejects the decision
+func runHookChain(chain []DecisionHook, d *Decision, ctx *Context) error {
+	for _, hook := range chain {
+		if err := hook(d, ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BlockSymbols Hook that rejects new positions on the given symbols (closing existing ones is still allowed)
+func BlockSymbols(symbols ...string) DecisionHook {
+	blocked := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		blocked[strings.ToUpper(symbol)] = true
+	}
+	return func(d *Decision, ctx *Context) error {
+		if blocked[d.Symbol] && strings.HasPrefix(d.Action, "open_") {
+			return fmt.Errorf("symbol %s is blocked", d.Symbol)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,153 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/market"
+	"sort"
+	"strings"
+)
+
+// InvalidationRule Machine-checkable invalidation condition, e.g. {"indicator": "4h_macd", "op": ">", "value": 500}
+type InvalidationRule struct {
+	Indicator string  `json:"indicator"` // See invalidationIndicators
+	Op        string  `json:"op"`        // ">", ">=", "<", "<="
+	Value     float64 `json:"value"`
+}
+
+// invalidationIndicators Indicators a rule can reference, read from market.Data
+var invalidationIndicators = map[string]func(d *market.Data) (float64, bool){
+	"price":           func(d *market.Data) (float64, bool) { return d.CurrentPrice, true },
+	"price_change_1h": func(d *market.Data) (float64, bool) { return d.PriceChange1h, true },
+	"price_change_4h": func(d *market.Data) (float64, bool) { return d.PriceChange4h, true },
+	"funding_rate":    func(d *market.Data) (float64, bool) { return d.FundingRate, true },
+	"3m_ema20":        func(d *market.Data) (float64, bool) { return d.CurrentEMA20, true },
+	"3m_macd":         func(d *market.Data) (float64, bool) { return d.CurrentMACD, true },
+	"3m_rsi7":         func(d *market.Data) (float64, bool) { return d.CurrentRSI7, true },
+	"basis_pct": func(d *market.Data) (float64, bool) {
+		return d.BasisPct, d.MarkPrice > 0 && d.IndexPrice > 0
+	},
+	"3m_rsi14": func(d *market.Data) (float64, bool) {
+		if d.IntradaySeries == nil {
+			return 0, false
+		}
+		return lastValue(d.IntradaySeries.RSI14Values)
+	},
+	"3m_adx14": func(d *market.Data) (float64, bool) {
+		if d.IntradaySeries == nil {
+			return 0, false
+		}
+		return lastValue(d.IntradaySeries.ADX14Values)
+	},
+	"3m_bb_width": func(d *market.Data) (float64, bool) {
+		if d.IntradaySeries == nil {
+			return 0, false
+		}
+		return lastValue(d.IntradaySeries.BBWidthValues)
+	},
+	"3m_vwap": func(d *market.Data) (float64, bool) {
+		if d.IntradaySeries == nil {
+			return 0, false
+		}
+		return lastValue(d.IntradaySeries.VWAPValues)
+	},
+	"4h_ema20": func(d *market.Data) (float64, bool) {
+		if d.LongerTermContext == nil {
+			return 0, false
+		}
+		return d.LongerTermContext.EMA20, true
+	},
+	"4h_ema50": func(d *market.Data) (float64, bool) {
+		if d.LongerTermContext == nil {
+			return 0, false
+		}
+		return d.LongerTermContext.EMA50, true
+	},
+	"4h_atr14": func(d *market.Data) (float64, bool) {
+		if d.LongerTermContext == nil {
+			return 0, false
+		}
+		return d.LongerTermContext.ATR14, true
+	},
+	"4h_macd": func(d *market.Data) (float64, bool) {
+		if d.LongerTermContext == nil {
+			return 0, false
+		}
+		return lastValue(d.LongerTermContext.MACDValues)
+	},
+	"4h_adx14": func(d *market.Data) (float64, bool) {
+		if d.LongerTermContext == nil {
+			return 0, false
+		}
+		return lastValue(d.LongerTermContext.ADX14Values)
+	},
+	"4h_bb_width": func(d *market.Data) (float64, bool) {
+		if d.LongerTermContext == nil {
+			return 0, false
+		}
+		return lastValue(d.LongerTermContext.BBWidthValues)
+	},
+	"4h_rsi14": func(d *market.Data) (float64, bool) {
+		if d.LongerTermContext == nil {
+			return 0, false
+		}
+		return lastValue(d.LongerTermContext.RSI14Values)
+	},
+}
+
+// lastValue Latest value of a series
+func lastValue(values []float64) (float64, bool) {
+	if len(values) == 0 {
+		return 0, false
+	}
+	return values[len(values)-1], true
+}
+
+// invalidationIndicatorNames Sorted indicator names (for prompts and error messages)
+func invalidationIndicatorNames() string {
+	names := make([]string, 0, len(invalidationIndicators))
+	for name := range invalidationIndicators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Validate Check that the rule references a known indicator and operator
+func (r *InvalidationRule) Validate() error {
+	if _, exists := invalidationIndicators[r.Indicator]; !exists {
+		return fmt.Errorf("unknown invalidation_rule indicator %q (supported: %s)", r.Indicator, invalidationIndicatorNames())
+	}
+	switch r.Op {
+	case ">", ">=", "<", "<=":
+		return nil
+	default:
+		return fmt.Errorf("invalid invalidation_rule op %q (supported: >, >=, <, <=)", r.Op)
+	}
+}
+
+// Evaluate Check the rule against current market data, returning whether it triggered and the observed value
+func (r *InvalidationRule) Evaluate(data *market.Data) (bool, float64, error) {
+	if err := r.Validate(); err != nil {
+		return false, 0, err
+	}
+	current, ok := invalidationIndicators[r.Indicator](data)
+	if !ok {
+		return false, 0, fmt.Errorf("indicator %s unavailable for %s", r.Indicator, data.Symbol)
+	}
+
+	switch r.Op {
+	case ">":
+		return current > r.Value, current, nil
+	case ">=":
+		return current >= r.Value, current, nil
+	case "<":
+		return current < r.Value, current, nil
+	default:
+		return current <= r.Value, current, nil
+	}
+}
+
+// String Human-readable form, e.g. "4h_macd > 500"
+func (r *InvalidationRule) String() string {
+	return fmt.Sprintf("%s %s %g", r.Indicator, r.Op, r.Value)
+}
@@ -0,0 +1,107 @@
+package decision
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Entry types for open_long / open_short
+const (
+	EntryTypeMarket = "market" // Fill immediately at the market price (default)
+	EntryTypeLimit  = "limit"  // Rest a limit order at entry_price until it fills or expires
+)
+
+// Limit entry expiry bounds (minutes)
+const (
+	defaultLimitExpiryMinutes = 60
+	maxLimitExpiryMinutes     = 1440
+)
+
+// PendingEntry Resting limit entry that has not filled yet
+type PendingEntry struct {
+	Symbol           string  `json:"symbol"`
+	Side             string  `json:"side"` // "long" or "short"
+	EntryPrice       float64 `json:"entry_price"`
+	PositionSizeUSD  float64 `json:"position_size_usd"`
+	StopLoss         float64 `json:"stop_loss"`
+	TakeProfit       float64 `json:"take_profit"`
+	ExpiresInMinutes int     `json:"expires_in_minutes"`
+	Quantity         float64 `json:"quantity"`
+	FilledQuantity   float64 `json:"filled_quantity,omitempty"` // Already filled part (partial fill, protected as a position)
+}
+
+// IsLimitEntry Whether an open decision rests a limit order instead of taking the market
+func (d *Decision) IsLimitEntry() bool {
+	return strings.EqualFold(d.EntryType, EntryTypeLimit)
+}
+
+// EntryExpiry How long a limit entry may rest before it is cancelled
+func (d *Decision) EntryExpiry() time.Duration {
+	minutes := d.ExpiryMinutes
+	if minutes <= 0 {
+		minutes = defaultLimitExpiryMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// validateEntry Check entry_type / entry_price / expiry_minutes of an open decision.
+// A limit price must sit between stop loss and take profit, on the favorable side of the current price
+// (below it for longs, above it for shorts) - otherwise it would fill immediately like a market order.
+func validateEntry(d *Decision, ctx *Context) error {
+	if d.EntryType != "" && !strings.EqualFold(d.EntryType, EntryTypeMarket) && !d.IsLimitEntry() {
+		return fmt.Errorf("entry_type must be '%s' or '%s': %s", EntryTypeMarket, EntryTypeLimit, d.EntryType)
+	}
+	if !d.IsLimitEntry() {
+		return nil
+	}
+
+	if d.EntryPrice <= 0 {
+		return fmt.Errorf("entry_price must be greater than 0 for limit entries")
+	}
+	if d.ExpiryMinutes < 0 || d.ExpiryMinutes > maxLimitExpiryMinutes {
+		return fmt.Errorf("expiry_minutes must be between 1-%d (0 = default %d): %d", maxLimitExpiryMinutes, defaultLimitExpiryMinutes, d.ExpiryMinutes)
+	}
+
+	var currentPrice float64
+	if marketData, exists := ctx.MarketDataMap[d.Symbol]; exists {
+		currentPrice = marketData.CurrentPrice
+	}
+
+	if d.Action == "open_long" {
+		if d.EntryPrice <= d.StopLoss || d.EntryPrice >= d.TakeProfit {
+			return fmt.Errorf("long entry_price must be between stop loss and take profit: %.4f [Stop Loss:%.4f Take Profit:%.4f]", d.EntryPrice, d.StopLoss, d.TakeProfit)
+		}
+		if currentPrice > 0 && d.EntryPrice >= currentPrice {
+			return fmt.Errorf("long limit entry_price %.4f must be below the current price %.4f (use a market entry to buy now)", d.EntryPrice, currentPrice)
+		}
+	} else {
+		if d.EntryPrice >= d.StopLoss || d.EntryPrice <= d.TakeProfit {
+			return fmt.Errorf("short entry_price must be between take profit and stop loss: %.4f [Stop Loss:%.4f Take Profit:%.4f]", d.EntryPrice, d.StopLoss, d.TakeProfit)
+		}
+		if currentPrice > 0 && d.EntryPrice <= currentPrice {
+			return fmt.Errorf("short limit entry_price %.4f must be above the current price %.4f (use a market entry to sell now)", d.EntryPrice, currentPrice)
+		}
+	}
+	return nil
+}
+
+// buildPendingEntriesPrompt List resting limit entries (empty when none)
+func buildPendingEntriesPrompt(ctx *Context) string {
+	if len(ctx.PendingEntries) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Pending Limit Entries (resting orders)\n\n")
+	for _, e := range ctx.PendingEntries {
+		filled := ""
+		if e.FilledQuantity > 0 && e.Quantity > 0 {
+			filled = fmt.Sprintf(" | %.0f%% filled", e.FilledQuantity/e.Quantity*100)
+		}
+		sb.WriteString(fmt.Sprintf("- %s %s @ %.4f | Size %.2f USDT | SL %.4f | TP %.4f | expires in %d min%s\n",
+			e.Symbol, strings.ToUpper(e.Side), e.EntryPrice, e.PositionSizeUSD, e.StopLoss, e.TakeProfit, e.ExpiresInMinutes, filled))
+	}
+	sb.WriteString("\nA new open decision for the same symbol and side is rejected while its entry is pending.\n\n")
+	return sb.String()
+}
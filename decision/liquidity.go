@@ -0,0 +1,64 @@
+package decision
+
+import (
+	"fmt"
+	"math"
+	"nofx/market"
+)
+
+// Default order book thresholds for candidates
+const (
+	defaultMaxSpreadPct     = 0.1 // Best bid/ask spread (% of mid)
+	defaultMinDepthMultiple = 1.0 // Thinner side of the ±0.5% book vs the maximum position value
+)
+
+// maxSpreadPct Effective spread limit (0 = disabled)
+func (ctx *Context) maxSpreadPct() float64 {
+	switch {
+	case ctx.MaxSpreadPct < 0:
+		return 0
+	case ctx.MaxSpreadPct > 0:
+		return ctx.MaxSpreadPct
+	}
+	return defaultMaxSpreadPct
+}
+
+// minDepthMultiple Effective depth requirement (0 = disabled)
+func (ctx *Context) minDepthMultiple() float64 {
+	switch {
+	case ctx.MinDepthMultiple < 0:
+		return 0
+	case ctx.MinDepthMultiple > 0:
+		return ctx.MinDepthMultiple
+	}
+	return defaultMinDepthMultiple
+}
+
+// liquidityIssue Why the order book is too weak to absorb a full-size position ("" if fine or depth unavailable)
+func (ctx *Context) liquidityIssue(data *market.Data) string {
+	depth := data.Depth
+	if depth == nil {
+		return ""
+	}
+	if limit := ctx.maxSpreadPct(); limit > 0 && depth.SpreadPct > limit {
+		return fmt.Sprintf("spread %.3f%% > %g%%", depth.SpreadPct, limit)
+	}
+
+	multiple := ctx.minDepthMultiple()
+	if multiple <= 0 {
+		return ""
+	}
+	required := ctx.Account.TotalEquity * ctx.symbolLimit(data.Symbol).MaxPositionMultiple * multiple
+	// A partial side only covers part of the ±0.5% range, so its real depth is larger than measured
+	bid, ask := depth.BidDepthUSD, depth.AskDepthUSD
+	if depth.BidPartial {
+		bid = math.Inf(1)
+	}
+	if depth.AskPartial {
+		ask = math.Inf(1)
+	}
+	if thinner := math.Min(bid, ask); thinner < required {
+		return fmt.Sprintf("±0.5%% depth %.0f USDT < %.0f USDT needed for a full-size position", thinner, required)
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package decision
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxMemoryReasoningLen Reasoning is truncated to keep the history compact
+const maxMemoryReasoningLen = 120
+
+// DecisionMemory One past executed decision and its outcome
+type DecisionMemory struct {
+	Time        string   // Execution time (e.g. "01-02 15:04")
+	Symbol      string   // Trading pair
+	Action      string   // open_long, close_short, ...
+	Price       float64  // Execution price
+	Reasoning   string   // AI reasoning at the time
+	RealizedPnL *float64 // Realized PnL in USDT (close/reduce actions only, nil if unknown)
+	Error       string   // Execution error (empty on success)
+}
+
+// buildDecisionMemoryPrompt Render recent decisions and their outcomes (empty when no history)
+func buildDecisionMemoryPrompt(ctx *Context) string {
+	if len(ctx.RecentDecisions) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## YOUR RECENT DECISIONS (oldest → newest)\n\n")
+	for _, m := range ctx.RecentDecisions {
+		sb.WriteString(fmt.Sprintf("- [%s] %s %s @ %.4f", m.Time, m.Action, m.Symbol, m.Price))
+		if m.RealizedPnL != nil {
+			sb.WriteString(fmt.Sprintf(" | realized PnL: %+.2f USDT", *m.RealizedPnL))
+		}
+		if m.Error != "" {
+			sb.WriteString(fmt.Sprintf(" | FAILED: %s", truncateRunes(m.Error, maxMemoryReasoningLen)))
+		}
+		if m.Reasoning != "" {
+			sb.WriteString(fmt.Sprintf(" | reasoning: %s", truncateRunes(m.Reasoning, maxMemoryReasoningLen)))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\nDo not re-enter a trade you just closed unless the setup has clearly changed, and learn from recent losses.\n\n")
+	return sb.String()
+}
+
+// truncateRunes Shorten s to at most n runes (appends "..." when cut)
+func truncateRunes(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
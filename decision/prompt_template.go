@@ -0,0 +1,65 @@
+package decision
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// PromptTemplateData Variables injected into a custom system prompt template
+type PromptTemplateData struct {
+	BTCETHLeverage  int                    // BTC/ETH leverage limit
+	AltcoinLeverage int                    // Altcoin leverage limit
+	MaxPositions    int                    // Maximum concurrent positions
+	MinRiskReward   float64                // Minimum risk-reward ratio (e.g. 3 means 1:3)
+	SymbolLimits    map[string]SymbolLimit // Per-symbol overrides (may be empty)
+	Timeframes      string                 // Description of the timeframes in the market data
+	ActionRules     string                 // Position-mode rules and allowed actions (reduce, hedge)
+	HardConstraints string                 // Risk limits the validator enforces (risk-reward, costs, ATR stops, cooldown)
+	OutputRules     string                 // Output format and field rules (JSON example, limit entry, invalidation_rule) - required for decisions to parse
+}
+
+// LoadPromptTemplate Load a system prompt template from file (Go text/template syntax)
+func LoadPromptTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	return ParsePromptTemplate(filepath.Base(path), string(data))
+}
+
+// ParsePromptTemplate Parse a system prompt template from text (e.g. embedded with go:embed)
+func ParsePromptTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// renderSystemPrompt Build System Prompt from the custom template if set, otherwise use built-in rules
+func renderSystemPrompt(ctx *Context) (string, error) {
+	if ctx.PromptTemplate == nil {
+		return buildSystemPrompt(ctx), nil
+	}
+
+	data := PromptTemplateData{
+		BTCETHLeverage:  ctx.BTCETHLeverage,
+		AltcoinLeverage: ctx.AltcoinLeverage,
+		MaxPositions:    ctx.maxPositions(),
+		MinRiskReward:   ctx.minRiskReward(),
+		SymbolLimits:    ctx.SymbolLimits,
+		Timeframes:      describeTimeframes(ctx),
+		ActionRules:     buildActionRules(ctx),
+		HardConstraints: buildHardConstraints(ctx),
+		OutputRules:     buildOutputRules(ctx),
+	}
+
+	var sb strings.Builder
+	if err := ctx.PromptTemplate.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,37 @@
+package decision
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// regimeBenchmark Symbol whose regime describes the market as a whole
+const regimeBenchmark = "BTCUSDT"
+
+// loadMarketRegime Set ctx.MarketRegime from the benchmark's market data, fetching it
+// when the benchmark is not among this cycle's symbols
+func (ctx *Context) loadMarketRegime(cycleCtx context.Context, results map[string]marketDataResult) {
+	ctx.MarketRegime = nil
+	if result, ok := results[regimeBenchmark]; ok && result.err == nil {
+		ctx.MarketRegime = result.data.Regime
+		return
+	}
+
+	data, err := fetchMarketDataWithTimeout(cycleCtx, regimeBenchmark, ctx.timeframesFor(regimeBenchmark), nil)
+	if err != nil {
+		log.Printf("⚠️  Failed to fetch %s market data for the market regime: %v", regimeBenchmark, err)
+		return
+	}
+	ctx.MarketRegime = data.Regime
+}
+
+// writeRegimeGuidance Regime-conditional rules for the system prompt
+func writeRegimeGuidance(sb *strings.Builder) {
+	sb.WriteString("# 🌦️ Market Regime\n\n")
+	sb.WriteString("Each coin carries a regime label computed on its longer-term timeframe (ADX14, ATR3/ATR14, realized volatility), and the overall market regime is taken from BTC:\n")
+	sb.WriteString("- **trending** (ADX ≥ 25): Trade in the direction of the trend, enter on pullbacks to EMA20/VWAP, let winners run with wider take profits - do not fade the move\n")
+	sb.WriteString("- **ranging** (low ADX): Fade the range extremes, take profit near the opposite side of the range, avoid chasing breakouts that lack volume and CVD confirmation\n")
+	sb.WriteString("- **volatile** (ATR or realized volatility expanding sharply): Cut position size, widen stops to clear the noise, require higher confidence - sitting out is often the best trade\n")
+	sb.WriteString("- When BTC is volatile, treat altcoin signals with extra caution - altcoins follow BTC moves with larger swings\n\n")
+}
@@ -0,0 +1,42 @@
+package decision
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Replay Re-run parsing, validation and the hook chain on a recorded AI response against a saved context,
+// without fetching market data or calling the AI. Use with Context.Load to reproduce a past cycle offline.
+func Replay(ctx *Context, aiResponse string) (*FullDecision, error) {
+	var decision *FullDecision
+	var err error
+	if isStructuredResponse(aiResponse) {
+		decision, err = parseStructuredResponse(aiResponse, ctx)
+	} else {
+		decision, err = parseFullDecisionResponse(aiResponse, ctx)
+	}
+	if err == nil {
+		decision.Decisions, decision.HookRejections = applyDecisionHooks(decision.Decisions, ctx)
+	}
+
+	decision.Timestamp = time.Now()
+	decision.UserPrompt, decision.PromptBudget = buildBudgetedUserPrompt(ctx)
+	decision.RawResponse = aiResponse
+	decision.CoTSections = parseCoTSections(decision.CoTTrace)
+	return decision, err
+}
+
+// isStructuredResponse Whether the response is a structured-output object (chain_of_thought + decisions)
+func isStructuredResponse(aiResponse string) bool {
+	trimmed := strings.TrimSpace(aiResponse)
+	if !strings.HasPrefix(trimmed, "{") {
+		return false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(trimmed), &fields); err != nil {
+		return false
+	}
+	_, hasCoT := fields["chain_of_thought"]
+	return hasCoT
+}
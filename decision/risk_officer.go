@@ -0,0 +1,141 @@
+package decision
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"nofx/mcp"
+	"strings"
+)
+
+// RiskVerdict Risk officer's ruling on one decision
+type RiskVerdict struct {
+	Index     int    `json:"index"`   // 1-based index into the reviewed decision list
+	Verdict   string `json:"verdict"` // "approve" or "veto"
+	Reasoning string `json:"reasoning"`
+}
+
+// ReviewDecisions Ask a second (usually cheaper) model to approve or veto each actionable decision.
+// Vetoed decisions are replaced by "wait"; the returned strings describe each veto for the logs.
+func ReviewDecisions(cycleCtx context.Context, ctx *Context, decisions []Decision, client *mcp.Client) ([]Decision, []string, error) {
+	var reviewed []int // Indexes of decisions that change positions
+	for i, d := range decisions {
+		if d.Action != "hold" && d.Action != "wait" {
+			reviewed = append(reviewed, i)
+		}
+	}
+	if len(reviewed) == 0 {
+		return decisions, nil, nil
+	}
+
+	response, err := client.CallWithMessages(cycleCtx, buildRiskOfficerSystemPrompt(ctx), buildRiskOfficerUserPrompt(ctx, decisions, reviewed))
+	if err != nil {
+		return decisions, nil, fmt.Errorf("risk officer call failed: %w", err)
+	}
+	verdicts, err := parseRiskVerdicts(response)
+	if err != nil {
+		return decisions, nil, err
+	}
+
+	result := make([]Decision, len(decisions))
+	copy(result, decisions)
+	var vetoes []string
+	for _, v := range verdicts {
+		if !strings.EqualFold(v.Verdict, "veto") || v.Index < 1 || v.Index > len(reviewed) {
+			continue
+		}
+		i := reviewed[v.Index-1]
+		original := decisions[i]
+		vetoes = append(vetoes, fmt.Sprintf("%s %s: %s", original.Symbol, original.Action, v.Reasoning))
+		result[i] = Decision{
+			Symbol:    original.Symbol,
+			Action:    "wait",
+			Reasoning: fmt.Sprintf("Vetoed by risk officer (%s): %s", original.Action, v.Reasoning),
+		}
+	}
+	return result, vetoes, nil
+}
+
+// buildRiskOfficerSystemPrompt Hard constraints the reviewer checks against
+func buildRiskOfficerSystemPrompt(ctx *Context) string {
+	var sb strings.Builder
+	sb.WriteString("You are the risk officer of a crypto perpetual futures trading desk. ")
+	sb.WriteString("A trader has proposed the decisions below. Review each one strictly against the hard constraints and the trader's recent performance. ")
+	sb.WriteString("Veto only decisions that break a constraint or are clearly reckless given the account state; do not second-guess market views.\n\n")
+
+	sb.WriteString("# Hard Constraints\n\n")
+	sb.WriteString(fmt.Sprintf("- Risk-reward ratio of new positions must be ≥ 1:%.1f\n", ctx.minRiskReward()))
+	sb.WriteString(fmt.Sprintf("- At most %d concurrent positions\n", ctx.maxPositions()))
+	sb.WriteString(fmt.Sprintf("- Leverage: BTC/ETH ≤ %dx, altcoins ≤ %dx\n", ctx.BTCETHLeverage, ctx.AltcoinLeverage))
+	sb.WriteString(fmt.Sprintf("- Position value: BTC/ETH ≤ %.0fx equity, altcoins ≤ %.1fx equity\n", btcEthPositionMultiple, altcoinPositionMultiple))
+	sb.WriteString("- Margin usage should stay ≤ 90%\n")
+	sb.WriteString("- Every new position needs a stop loss, take profit and invalidation condition\n")
+	if cooldown := ctx.tradeCooldown(); cooldown > 0 {
+		sb.WriteString(fmt.Sprintf("- A symbol closed less than %d minutes ago must not be re-opened\n", int(cooldown.Minutes())))
+	}
+	sb.WriteString(buildSymbolLimitsPrompt(ctx))
+	sb.WriteString("\n")
+
+	sb.WriteString("# Output Format\n\n")
+	sb.WriteString("Output ONLY a JSON array with one verdict per decision, no other text:\n")
+	sb.WriteString("[{\"index\": 1, \"verdict\": \"approve\", \"reasoning\": \"within limits\"}, {\"index\": 2, \"verdict\": \"veto\", \"reasoning\": \"third altcoin long while the last 3 trades lost\"}]\n")
+	return sb.String()
+}
+
+// buildRiskOfficerUserPrompt Account state, recent performance and the decisions under review
+func buildRiskOfficerUserPrompt(ctx *Context, decisions []Decision, reviewed []int) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("**Time**: %s\n\n", ctx.CurrentTime))
+	sb.WriteString(fmt.Sprintf("**Account**: Equity %.2f | Available %.2f | PnL %+.2f%% | Margin used %.1f%% | Positions %d\n\n",
+		ctx.Account.TotalEquity, ctx.Account.AvailableBalance, ctx.Account.TotalPnLPct, ctx.Account.MarginUsedPct, ctx.Account.PositionCount))
+
+	if len(ctx.Positions) > 0 {
+		sb.WriteString("## Current Positions\n\n")
+		for _, pos := range ctx.Positions {
+			sb.WriteString(fmt.Sprintf("- %s %s | Entry %.4f | Mark %.4f | PnL %+.2f%% | Leverage %dx | Margin %.2f\n",
+				pos.Symbol, strings.ToUpper(pos.Side), pos.EntryPrice, pos.MarkPrice, pos.UnrealizedPnLPct, pos.Leverage, pos.MarginUsed))
+		}
+		sb.WriteString("\n")
+	}
+
+	if ctx.Performance != nil {
+		var perf struct {
+			TotalTrades int     `json:"total_trades"`
+			WinRate     float64 `json:"win_rate"`
+			SharpeRatio float64 `json:"sharpe_ratio"`
+		}
+		if jsonData, err := json.Marshal(ctx.Performance); err == nil {
+			if err := json.Unmarshal(jsonData, &perf); err == nil && perf.TotalTrades > 0 {
+				sb.WriteString(fmt.Sprintf("## Recent Performance\n\nTrades %d | Win rate %.1f%% | Sharpe %.2f\n\n", perf.TotalTrades, perf.WinRate, perf.SharpeRatio))
+			}
+		}
+	}
+	sb.WriteString(buildCooldownPrompt(ctx))
+	sb.WriteString(buildDecisionMemoryPrompt(ctx))
+
+	sb.WriteString("## Decisions Under Review\n\n")
+	for n, i := range reviewed {
+		d := decisions[i]
+		decisionJSON, _ := json.Marshal(d)
+		sb.WriteString(fmt.Sprintf("%d. %s\n", n+1, decisionJSON))
+	}
+	return sb.String()
+}
+
+// parseRiskVerdicts Extract the verdict array from the reviewer's response
+func parseRiskVerdicts(response string) ([]RiskVerdict, error) {
+	start := strings.Index(response, "[")
+	if start == -1 {
+		return nil, fmt.Errorf("risk officer response contains no JSON array")
+	}
+	end := findMatchingBracket(response, start)
+	if end == -1 {
+		return nil, fmt.Errorf("risk officer response has an unterminated JSON array")
+	}
+
+	var verdicts []RiskVerdict
+	if err := json.Unmarshal([]byte(removeTrailingCommas(response[start:end+1])), &verdicts); err != nil {
+		return nil, fmt.Errorf("failed to parse risk officer verdicts: %w", err)
+	}
+	return verdicts, nil
+}
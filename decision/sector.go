@@ -0,0 +1,90 @@
+package decision
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sector Sector of a symbol ("" if unmapped)
+func (ctx *Context) sector(symbol string) string {
+	return ctx.Sectors[symbol]
+}
+
+// sectorCounts Open positions per sector (unmapped symbols are not counted)
+func (ctx *Context) sectorCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, pos := range ctx.Positions {
+		if sector := ctx.sector(pos.Symbol); sector != "" {
+			counts[sector]++
+		}
+	}
+	return counts
+}
+
+// validateSectorExposure Ensure opens don't push any sector above MaxSectorPositions (closes are executed first)
+func validateSectorExposure(decisions []Decision, ctx *Context) error {
+	if ctx.MaxSectorPositions <= 0 {
+		return nil
+	}
+
+	counts := ctx.sectorCounts()
+	held := make(map[string]bool)
+	for _, pos := range ctx.Positions {
+		held[pos.Symbol+"_"+pos.Side] = true
+	}
+	for _, d := range decisions {
+		key := ""
+		switch d.Action {
+		case "close_long":
+			key = d.Symbol + "_long"
+		case "close_short":
+			key = d.Symbol + "_short"
+		}
+		if key != "" && held[key] {
+			delete(held, key)
+			if sector := ctx.sector(d.Symbol); sector != "" {
+				counts[sector]--
+			}
+		}
+	}
+
+	for i, d := range decisions {
+		if d.Action != "open_long" && d.Action != "open_short" {
+			continue
+		}
+		sector := ctx.sector(d.Symbol)
+		if sector == "" {
+			continue
+		}
+		counts[sector]++
+		if counts[sector] > ctx.MaxSectorPositions {
+			return fmt.Errorf("decision #%d validation failed: %s %s would exceed the maximum of %d positions in the %s sector",
+				i+1, d.Action, d.Symbol, ctx.MaxSectorPositions, sector)
+		}
+	}
+	return nil
+}
+
+// buildSectorPrompt Per-sector position limit and current usage (empty if no limit configured)
+func buildSectorPrompt(ctx *Context) string {
+	if ctx.MaxSectorPositions <= 0 {
+		return ""
+	}
+	counts := ctx.sectorCounts()
+	sectors := make([]string, 0, len(counts))
+	for sector := range counts {
+		sectors = append(sectors, sector)
+	}
+	sort.Strings(sectors)
+
+	usage := "none"
+	if len(sectors) > 0 {
+		parts := make([]string, 0, len(sectors))
+		for _, sector := range sectors {
+			parts = append(parts, fmt.Sprintf("%s %d/%d", sector, counts[sector], ctx.MaxSectorPositions))
+		}
+		usage = strings.Join(parts, ", ")
+	}
+	return fmt.Sprintf("Sector limit: at most %d concurrent positions per sector (current: %s)\n\n", ctx.MaxSectorPositions, usage)
+}
@@ -0,0 +1,138 @@
+package decision
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+)
+
+// Position sizing modes
+const (
+	SizingCap      = "cap"      // Kelly size is an upper bound on the AI's position_size_usd
+	SizingOverride = "override" // Kelly size replaces the AI's position_size_usd
+)
+
+// Position sizing defaults
+const (
+	defaultKellyFraction  = 0.25 // Quarter Kelly
+	defaultKellyMinTrades = 10   // Minimum closed trades before the historical edge is trusted
+)
+
+// PositionSizing Kelly-criterion sizing configuration (zero value = disabled)
+type PositionSizing struct {
+	Mode          string  // SizingCap or SizingOverride ("" = disabled)
+	KellyFraction float64 // Fraction of full Kelly to use (0 = default 0.25)
+	MinTrades     int     // Closed trades required before sizing applies (0 = default 10)
+}
+
+// kellyEdge Realized edge read from the performance analysis
+type kellyEdge struct {
+	TotalTrades int     `json:"total_trades"`
+	WinRate     float64 `json:"win_rate"` // Percent
+	AvgWin      float64 `json:"avg_win"`
+	AvgLoss     float64 `json:"avg_loss"` // Negative
+}
+
+// kellySizingHook Built-in hook that sizes new positions by fractional Kelly (nil when disabled)
+func kellySizingHook(ctx *Context) DecisionHook {
+	sizing := ctx.PositionSizing
+	if sizing.Mode != SizingCap && sizing.Mode != SizingOverride {
+		return nil
+	}
+	if sizing.KellyFraction <= 0 {
+		sizing.KellyFraction = defaultKellyFraction
+	}
+	if sizing.MinTrades <= 0 {
+		sizing.MinTrades = defaultKellyMinTrades
+	}
+
+	var edge kellyEdge
+	if ctx.Performance != nil {
+		if jsonData, err := json.Marshal(ctx.Performance); err == nil {
+			json.Unmarshal(jsonData, &edge)
+		}
+	}
+
+	return func(d *Decision, ctx *Context) error {
+		if d.Action != "open_long" && d.Action != "open_short" {
+			return nil
+		}
+		if edge.TotalTrades < sizing.MinTrades {
+			return nil // Not enough history, keep the AI's size
+		}
+
+		size, err := kellyPositionSize(d, ctx, edge, sizing.KellyFraction)
+		if err != nil {
+			return err
+		}
+		if size <= 0 {
+			return nil
+		}
+
+		if sizing.Mode == SizingOverride || size < d.PositionSizeUSD {
+			log.Printf("  📐 Kelly sizing %s %s: %.2f → %.2f USDT", d.Symbol, d.Action, d.PositionSizeUSD, size)
+			resized := *d
+			if resized.PositionSizeUSD > 0 {
+				resized.RiskUSD *= size / resized.PositionSizeUSD
+			}
+			resized.PositionSizeUSD = size
+
+			// The AI's size was validated; the new size must still respect risk, exchange, liquidity and cost limits
+			if err := validateDecision(&resized, ctx); err != nil {
+				return fmt.Errorf("kelly-sized position of %.2f USDT fails validation: %w", size, err)
+			}
+			*d = resized
+		}
+		return nil
+	}
+}
+
+// kellyPositionSize Position value so that the amount lost at stop loss equals the fractional Kelly bet
+func kellyPositionSize(d *Decision, ctx *Context, edge kellyEdge, fraction float64) (float64, error) {
+	// Entry price: limit entries use their own price, market entries fill at the current market price (same as validateDecision)
+	entryPrice := d.EntryPrice
+	if !d.IsLimitEntry() {
+		marketData, exists := ctx.MarketDataMap[d.Symbol]
+		if !exists {
+			return 0, nil
+		}
+		entryPrice = marketData.CurrentPrice
+	}
+	if entryPrice <= 0 || d.StopLoss <= 0 {
+		return 0, nil
+	}
+	stopDistance := math.Abs(entryPrice-d.StopLoss) / entryPrice
+	if stopDistance <= 0 {
+		return 0, nil
+	}
+
+	// Payoff ratio from realized trades, falling back to the decision's own reward/risk
+	payoff := 0.0
+	if edge.AvgWin > 0 && edge.AvgLoss < 0 {
+		payoff = edge.AvgWin / -edge.AvgLoss
+	} else if d.TakeProfit > 0 {
+		payoff = math.Abs(d.TakeProfit-entryPrice) / math.Abs(entryPrice-d.StopLoss)
+	}
+	if payoff <= 0 {
+		return 0, nil
+	}
+
+	p := edge.WinRate / 100
+	kelly := p - (1-p)/payoff
+	if kelly <= 0 {
+		return 0, fmt.Errorf("no positive edge for new positions (win rate %.1f%%, payoff %.2f)", edge.WinRate, payoff)
+	}
+
+	// Scale by confidence so weaker signals get proportionally smaller bets
+	confidence := float64(d.Confidence) / 100
+	if confidence <= 0 || confidence > 1 {
+		confidence = 1
+	}
+	riskUSD := ctx.Account.TotalEquity * kelly * fraction * confidence
+	size := riskUSD / stopDistance
+
+	// Never exceed the symbol's position value limit
+	maxPositionValue := ctx.Account.TotalEquity * ctx.symbolLimit(d.Symbol).MaxPositionMultiple
+	return math.Min(size, maxPositionValue), nil
+}
@@ -0,0 +1,111 @@
+package decision
+
+import (
+	"encoding/json"
+	"fmt"
+	"nofx/market"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// contextSnapshot On-disk form of a Context: every field is serialized through its JSON tag.
+// PromptTemplate is not serialized (a replay uses whatever template it is given).
+type contextSnapshot struct {
+	SavedAt time.Time `json:"saved_at"`
+	*Context
+}
+
+// snapshotExcludedFields Context fields intentionally left out of snapshots
+var snapshotExcludedFields = map[string]bool{"PromptTemplate": true}
+
+// checkSnapshotFields Every other Context field must have a JSON tag, a json:"-" field would silently drop out of Save/Load
+func checkSnapshotFields() error {
+	t := reflect.TypeOf(Context{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if snapshotExcludedFields[field.Name] {
+			continue
+		}
+		if field.PkgPath != "" || field.Tag.Get("json") == "-" {
+			return fmt.Errorf("context field %s is not serialized in snapshots", field.Name)
+		}
+	}
+	return nil
+}
+
+// Save Write the full context (market data, OI data, performance, limits) to path as JSON
+func (ctx *Context) Save(path string) error {
+	if err := checkSnapshotFields(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(contextSnapshot{SavedAt: time.Now(), Context: ctx}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize context: %w", err)
+	}
+	if err := verifyRoundTrip(data); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	// Write to a temp file first so an interrupted write never leaves a truncated snapshot
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write context snapshot: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// verifyRoundTrip Loading the snapshot and saving it again must give the same JSON
+// (catches fields whose type does not survive a round-trip, e.g. unexported state or custom marshalers)
+func verifyRoundTrip(data []byte) error {
+	restored := contextSnapshot{Context: &Context{}}
+	if err := json.Unmarshal(data, &restored); err != nil {
+		return fmt.Errorf("context snapshot does not load back: %w", err)
+	}
+	again, err := json.Marshal(restored)
+	if err != nil {
+		return fmt.Errorf("failed to serialize restored context: %w", err)
+	}
+
+	var want, got interface{}
+	if err := json.Unmarshal(data, &want); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(again, &got); err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(want, got) {
+		return fmt.Errorf("context snapshot does not survive a round-trip")
+	}
+	return nil
+}
+
+// Load Restore a context previously written by Save (PromptTemplate is left unchanged).
+// Performance is restored as generic JSON values, which every consumer reads through a JSON round-trip.
+func (ctx *Context) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read context snapshot: %w", err)
+	}
+
+	snapshot := contextSnapshot{Context: &Context{}}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to parse context snapshot: %w", err)
+	}
+
+	restored := *snapshot.Context
+	restored.PromptTemplate = ctx.PromptTemplate
+	if restored.MarketDataMap == nil {
+		restored.MarketDataMap = make(map[string]*market.Data)
+	}
+	if restored.OITopDataMap == nil {
+		restored.OITopDataMap = make(map[string]*OITopData)
+	}
+
+	*ctx = restored
+	return nil
+}
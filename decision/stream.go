@@ -0,0 +1,58 @@
+package decision
+
+import (
+	"log"
+	"strings"
+)
+
+// maxStreamLogLine Longest chain-of-thought line echoed to the log while streaming
+const maxStreamLogLine = 200
+
+// streamProgress Stream handler for the decision call: echoes the chain of thought line by line as it
+// arrives and ends the stream as soon as a complete, non-empty decision array has been received
+type streamProgress struct {
+	logged  int  // bytes of the response already echoed
+	checked int  // bytes of the response already scanned for a closing bracket
+	inJSON  bool // the decision JSON has started, stop echoing
+}
+
+// handle Implements mcp.StreamHandler
+func (p *streamProgress) handle(text string) bool {
+	p.logLines(text)
+
+	// Only re-parse when a bracket that could close the decision array has arrived
+	if !strings.ContainsAny(text[p.checked:], "]}") {
+		return false
+	}
+	p.checked = len(text)
+
+	// Require the array itself to be closed: a single object inside a still-open array
+	// would otherwise look like a complete one-decision response
+	decisions, start, err := extractDecisions(text)
+	if err != nil || len(decisions) == 0 || text[start] != '[' {
+		return false
+	}
+	log.Printf("⚡ Decision array complete after %d chars, stopped streaming", len(text))
+	return true
+}
+
+// logLines Log every completed chain-of-thought line, up to the start of the decision JSON
+func (p *streamProgress) logLines(text string) {
+	for !p.inJSON {
+		end := strings.IndexByte(text[p.logged:], '\n')
+		if end == -1 {
+			return
+		}
+		line := strings.TrimSpace(text[p.logged : p.logged+end])
+		p.logged += end + 1
+
+		if strings.HasPrefix(line, "[") || strings.HasPrefix(line, "```") {
+			p.inJSON = true
+			return
+		}
+		if line == "" {
+			continue
+		}
+		log.Printf("💭 %s", truncateRunes(line, maxStreamLogLine))
+	}
+}
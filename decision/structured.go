@@ -0,0 +1,115 @@
+package decision
+
+import (
+	"encoding/json"
+	"fmt"
+	"nofx/mcp"
+)
+
+// structuredOutputInstruction Appended to the system prompt when the provider enforces a JSON schema
+const structuredOutputInstruction = "\n\n# 🧾 Structured Output\n\n" +
+	"Your reply is constrained to a JSON object. Put your full chain of thought in `chain_of_thought` " +
+	"(keeping the section headings above) " +
+	"and the decision array in `decisions`. Use null for fields that do not apply to an action.\n"
+
+// structuredResponse Shape of a schema-constrained AI reply
+type structuredResponse struct {
+	ChainOfThought string     `json:"chain_of_thought"`
+	Decisions      []Decision `json:"decisions"`
+}
+
+// decisionResponseSchema JSON schema describing structuredResponse (strict mode: every field required, optional ones nullable)
+func decisionResponseSchema() *mcp.ResponseSchema {
+	nullable := func(t string) map[string]interface{} {
+		return map[string]interface{}{"type": []string{t, "null"}}
+	}
+
+	decision := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"symbol": map[string]interface{}{"type": "string"},
+			"action": map[string]interface{}{
+				"type": "string",
+				"enum": []string{"open_long", "open_short", "close_long", "close_short", "reduce_long", "reduce_short", "hold", "wait"},
+			},
+			"leverage":                  nullable("integer"),
+			"position_size_usd":         nullable("number"),
+			"stop_loss":                 nullable("number"),
+			"take_profit":               nullable("number"),
+			"invalidation_condition":    nullable("string"),
+			"confidence":                nullable("integer"),
+			"risk_usd":                  nullable("number"),
+			"close_percentage":          nullable("number"),
+			"trailing_activation_price": nullable("number"),
+			"trailing_callback_rate":    nullable("number"),
+			"invalidation_rule": map[string]interface{}{
+				"type": []string{"object", "null"},
+				"properties": map[string]interface{}{
+					"indicator": map[string]interface{}{"type": "string"},
+					"op":        map[string]interface{}{"type": "string", "enum": []string{">", ">=", "<", "<="}},
+					"value":     map[string]interface{}{"type": "number"},
+				},
+				"required":             []string{"indicator", "op", "value"},
+				"additionalProperties": false,
+			},
+			"entry_type": map[string]interface{}{
+				"type": []string{"string", "null"},
+				"enum": []interface{}{"market", "limit", nil},
+			},
+			"entry_price":    nullable("number"),
+			"expiry_minutes": nullable("integer"),
+			"reasoning":      map[string]interface{}{"type": "string"},
+		},
+		"required": []string{
+			"symbol", "action", "leverage", "position_size_usd", "stop_loss", "take_profit",
+			"invalidation_condition", "confidence", "risk_usd", "close_percentage",
+			"trailing_activation_price", "trailing_callback_rate", "invalidation_rule",
+			"entry_type", "entry_price", "expiry_minutes", "reasoning",
+		},
+		"additionalProperties": false,
+	}
+
+	return &mcp.ResponseSchema{
+		Name: "trading_decisions",
+		Schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"chain_of_thought": map[string]interface{}{"type": "string"},
+				"decisions": map[string]interface{}{
+					"type":  "array",
+					"items": decision,
+				},
+			},
+			"required":             []string{"chain_of_thought", "decisions"},
+			"additionalProperties": false,
+		},
+	}
+}
+
+// parseStructuredResponse Parse a schema-constrained AI reply (no bracket scanning needed)
+func parseStructuredResponse(aiResponse string, ctx *Context) (*FullDecision, error) {
+	var resp structuredResponse
+	if err := json.Unmarshal([]byte(aiResponse), &resp); err != nil {
+		return &FullDecision{
+			CoTTrace:  aiResponse,
+			Decisions: []Decision{},
+		}, fmt.Errorf("failed to decode structured output: %w", err)
+	}
+	if resp.Decisions == nil {
+		resp.Decisions = []Decision{}
+	}
+
+	// Symbols written in another naming convention are mapped first, same as the text path
+	resolveDecisionSymbols(resp.Decisions, ctx)
+	if err := validateDecisions(resp.Decisions, ctx); err != nil {
+		return &FullDecision{
+			CoTTrace:  resp.ChainOfThought,
+			Decisions: resp.Decisions,
+		}, fmt.Errorf("decision validation failed: %w", err)
+	}
+
+	return &FullDecision{
+		CoTTrace:  resp.ChainOfThought,
+		Decisions: resp.Decisions,
+	}, nil
+}
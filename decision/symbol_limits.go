@@ -0,0 +1,72 @@
+package decision
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SymbolLimit Per-symbol risk limits (zero fields fall back to the BTC/ETH or altcoin defaults)
+type SymbolLimit struct {
+	MaxLeverage         int     // Maximum leverage
+	MaxPositionMultiple float64 // Maximum position value as a multiple of account equity
+	MaxRiskPct          float64 // Maximum risk_usd as a percentage of account equity (0 = unlimited)
+}
+
+// Default position value multiples of account equity
+const (
+	btcEthPositionMultiple  = 10.0
+	altcoinPositionMultiple = 1.5
+)
+
+// symbolLimit Effective limits for a symbol: BTC/ETH vs altcoin defaults, overridden by per-symbol config
+func (ctx *Context) symbolLimit(symbol string) SymbolLimit {
+	limit := SymbolLimit{
+		MaxLeverage:         ctx.AltcoinLeverage,
+		MaxPositionMultiple: altcoinPositionMultiple,
+	}
+	if symbol == "BTCUSDT" || symbol == "ETHUSDT" {
+		limit.MaxLeverage = ctx.BTCETHLeverage
+		limit.MaxPositionMultiple = btcEthPositionMultiple
+	}
+
+	override, exists := ctx.SymbolLimits[symbol]
+	if !exists {
+		return limit
+	}
+	if override.MaxLeverage > 0 {
+		limit.MaxLeverage = override.MaxLeverage
+	}
+	if override.MaxPositionMultiple > 0 {
+		limit.MaxPositionMultiple = override.MaxPositionMultiple
+	}
+	if override.MaxRiskPct > 0 {
+		limit.MaxRiskPct = override.MaxRiskPct
+	}
+	return limit
+}
+
+// buildSymbolLimitsPrompt Render per-symbol overrides for the system prompt (empty when none configured)
+func buildSymbolLimitsPrompt(ctx *Context) string {
+	if len(ctx.SymbolLimits) == 0 {
+		return ""
+	}
+
+	symbols := make([]string, 0, len(ctx.SymbolLimits))
+	for symbol := range ctx.SymbolLimits {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	var sb strings.Builder
+	sb.WriteString("- Per-symbol limits (override the defaults above):\n")
+	for _, symbol := range symbols {
+		limit := ctx.symbolLimit(symbol)
+		sb.WriteString(fmt.Sprintf("  - %s: max %dx leverage, max %gx account equity", symbol, limit.MaxLeverage, limit.MaxPositionMultiple))
+		if limit.MaxRiskPct > 0 {
+			sb.WriteString(fmt.Sprintf(", max risk %g%% of equity", limit.MaxRiskPct))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,36 @@
+package decision
+
+import (
+	"log"
+	"nofx/market"
+)
+
+// resolveDecisionSymbols Map symbols the AI wrote in another naming convention (PEPE, kPEPE, PEPE/USDT)
+// onto the position or candidate symbol they refer to (e.g. 1000PEPEUSDT)
+func resolveDecisionSymbols(decisions []Decision, ctx *Context) {
+	known := make(map[string]bool)
+	byBase := make(map[string]string)
+	add := func(symbol string) {
+		known[symbol] = true
+		if base := market.BaseAsset(symbol); byBase[base] == "" {
+			byBase[base] = symbol
+		}
+	}
+	for _, pos := range ctx.Positions {
+		add(pos.Symbol)
+	}
+	for _, coin := range ctx.CandidateCoins {
+		add(coin.Symbol)
+	}
+
+	for i := range decisions {
+		symbol := decisions[i].Symbol
+		if symbol == "" || known[symbol] {
+			continue
+		}
+		if mapped := byBase[market.BaseAsset(symbol)]; mapped != "" {
+			log.Printf("🔀 Decision symbol %s resolved to %s", symbol, mapped)
+			decisions[i].Symbol = mapped
+		}
+	}
+}
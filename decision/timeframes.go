@@ -0,0 +1,55 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/market"
+	"sort"
+	"strings"
+)
+
+// defaultTimeframesKey Timeframes entry applied to symbols without their own entry
+const defaultTimeframesKey = "default"
+
+// timeframesFor Extra kline intervals fetched and rendered for a symbol (3m and 4h are always included)
+func (ctx *Context) timeframesFor(symbol string) []string {
+	if intervals, exists := ctx.Timeframes[symbol]; exists {
+		return intervals
+	}
+	return ctx.Timeframes[defaultTimeframesKey]
+}
+
+// describeTimeframes Available timeframes for the system prompt, e.g.
+// "3-minute and 4-hour series for all coins, plus 15m, 1h for all coins; BTCUSDT: 1m, 1d instead"
+func describeTimeframes(ctx *Context) string {
+	desc := "3-minute and 4-hour series for all coins"
+	if defaults := ctx.Timeframes[defaultTimeframesKey]; len(defaults) > 0 {
+		desc += fmt.Sprintf(", plus %s for all coins", strings.Join(defaults, ", "))
+	}
+
+	var symbols []string
+	for symbol := range ctx.Timeframes {
+		if symbol != defaultTimeframesKey {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		intervals := ctx.Timeframes[symbol]
+		if len(intervals) == 0 {
+			desc += fmt.Sprintf("; %s: 3-minute and 4-hour only", symbol)
+		} else {
+			desc += fmt.Sprintf("; %s: %s instead", symbol, strings.Join(intervals, ", "))
+		}
+	}
+	return desc
+}
+
+// describeIndicators Optional indicator series included in every timeframe, e.g.
+// "On-balance volume, Ichimoku cloud (9, 26, 52) on every timeframe"
+func describeIndicators(ctx *Context) string {
+	names := make([]string, 0, len(ctx.Indicators))
+	for _, indicator := range ctx.Indicators {
+		names = append(names, market.OptionalIndicators[indicator])
+	}
+	return strings.Join(names, ", ") + " on every timeframe"
+}
@@ -0,0 +1,232 @@
+package decision
+
+import (
+	"fmt"
+	"log"
+	"nofx/market"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// budgetSeriesPoints Points kept per series when a coin's data is truncated to save tokens
+const budgetSeriesPoints = 4
+
+// PromptBudgetReport How much of the user prompt was cut to fit the token budget
+type PromptBudgetReport struct {
+	Budget           int      `json:"budget"`
+	OriginalTokens   int      `json:"original_tokens"`
+	FinalTokens      int      `json:"final_tokens"`
+	TruncatedSymbols []string `json:"truncated_symbols,omitempty"` // Series shortened to the last few points
+	DroppedSymbols   []string `json:"dropped_symbols,omitempty"`   // Candidates removed from the prompt
+}
+
+// String Summary for logs, e.g. "~41200 → ~15800 tokens (budget 16000), truncated 12, dropped 5: ..."
+func (r *PromptBudgetReport) String() string {
+	s := fmt.Sprintf("~%d → ~%d tokens (budget %d), truncated %d, dropped %d",
+		r.OriginalTokens, r.FinalTokens, r.Budget, len(r.TruncatedSymbols), len(r.DroppedSymbols))
+	if len(r.DroppedSymbols) > 0 {
+		s += ": " + strings.Join(r.DroppedSymbols, ", ")
+	}
+	return s
+}
+
+// estimateTokens Rough token count: ~4 ASCII characters per token, one token per non-ASCII rune
+func estimateTokens(s string) int {
+	ascii, other := 0, 0
+	for i := 0; i < len(s); {
+		if s[i] < utf8.RuneSelf {
+			ascii++
+			i++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		other++
+		i += size
+	}
+	return (ascii+3)/4 + other
+}
+
+// buildBudgetedUserPrompt Build the user prompt within ctx.PromptTokenBudget: first truncate candidate
+// series, then drop candidates from the lowest score up, and finally truncate position series.
+// Returns a nil report when no budget is set or nothing had to be cut.
+func buildBudgetedUserPrompt(ctx *Context) (string, *PromptBudgetReport) {
+	prompt := buildUserPrompt(ctx)
+	tokens := estimateTokens(prompt)
+	if ctx.PromptTokenBudget <= 0 || tokens <= ctx.PromptTokenBudget {
+		return prompt, nil
+	}
+
+	report := &PromptBudgetReport{Budget: ctx.PromptTokenBudget, OriginalTokens: tokens}
+	view := *ctx
+	view.MarketDataMap = make(map[string]*market.Data, len(ctx.MarketDataMap))
+	for symbol, data := range ctx.MarketDataMap {
+		view.MarketDataMap[symbol] = data
+	}
+	rebuild := func() bool {
+		prompt = buildUserPrompt(&view)
+		tokens = estimateTokens(prompt)
+		return tokens <= ctx.PromptTokenBudget
+	}
+
+	candidates := rankedCandidates(ctx)
+	positions := make([]string, 0, len(ctx.Positions))
+	for _, pos := range ctx.Positions {
+		if view.MarketDataMap[pos.Symbol] != nil {
+			positions = append(positions, pos.Symbol)
+		}
+	}
+
+	// 1. Shorten candidate series
+	for _, symbol := range candidates {
+		view.MarketDataMap[symbol] = truncateSeries(view.MarketDataMap[symbol], budgetSeriesPoints)
+		report.TruncatedSymbols = append(report.TruncatedSymbols, symbol)
+	}
+	fits := rebuild()
+
+	// 2. Drop candidates, lowest priority first
+	for i := len(candidates) - 1; i >= 0 && !fits; i-- {
+		delete(view.MarketDataMap, candidates[i])
+		report.DroppedSymbols = append(report.DroppedSymbols, candidates[i])
+		fits = rebuild()
+	}
+
+	// 3. Positions are never dropped, only shortened
+	if !fits {
+		for _, symbol := range positions {
+			view.MarketDataMap[symbol] = truncateSeries(view.MarketDataMap[symbol], budgetSeriesPoints)
+			report.TruncatedSymbols = append(report.TruncatedSymbols, symbol)
+		}
+		rebuild()
+	}
+
+	dropped := make(map[string]bool, len(report.DroppedSymbols))
+	for _, symbol := range report.DroppedSymbols {
+		dropped[symbol] = true
+	}
+	truncated := report.TruncatedSymbols[:0]
+	for _, symbol := range report.TruncatedSymbols {
+		if !dropped[symbol] {
+			truncated = append(truncated, symbol)
+		}
+	}
+	report.TruncatedSymbols = truncated
+
+	report.FinalTokens = tokens
+	if tokens > ctx.PromptTokenBudget {
+		log.Printf("⚠️  User prompt still exceeds token budget after trimming: %s", report)
+	}
+	return prompt, report
+}
+
+// rankedCandidates Non-position candidates with market data, highest priority first
+// (listed in both pools, then AI500 score, then OI Top rank)
+func rankedCandidates(ctx *Context) []string {
+	held := make(map[string]bool, len(ctx.Positions))
+	for _, pos := range ctx.Positions {
+		held[pos.Symbol] = true
+	}
+
+	var coins []CandidateCoin
+	for _, coin := range ctx.CandidateCoins {
+		if !held[coin.Symbol] && ctx.MarketDataMap[coin.Symbol] != nil {
+			coins = append(coins, coin)
+		}
+	}
+
+	oiRank := func(symbol string) int {
+		if oi, exists := ctx.OITopDataMap[symbol]; exists && oi.Rank > 0 {
+			return oi.Rank
+		}
+		return 1 << 30
+	}
+	sort.SliceStable(coins, func(i, j int) bool {
+		a, b := coins[i], coins[j]
+		if len(a.Sources) != len(b.Sources) {
+			return len(a.Sources) > len(b.Sources)
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if oiRank(a.Symbol) != oiRank(b.Symbol) {
+			return oiRank(a.Symbol) < oiRank(b.Symbol)
+		}
+		return a.Symbol < b.Symbol
+	})
+
+	symbols := make([]string, len(coins))
+	for i, coin := range coins {
+		symbols[i] = coin.Symbol
+	}
+	return symbols
+}
+
+// truncateSeries Copy of data with every series cut to its last n points
+func truncateSeries(data *market.Data, n int) *market.Data {
+	if data == nil {
+		return nil
+	}
+	last := func(values []float64) []float64 {
+		if len(values) > n {
+			return values[len(values)-n:]
+		}
+		return values
+	}
+	lastOptional := func(s *market.IndicatorSeries) *market.IndicatorSeries {
+		if s == nil {
+			return nil
+		}
+		series := *s
+		series.OBVValues = last(series.OBVValues)
+		series.StochKValues = last(series.StochKValues)
+		series.StochDValues = last(series.StochDValues)
+		series.TenkanValues = last(series.TenkanValues)
+		series.KijunValues = last(series.KijunValues)
+		series.SenkouAValues = last(series.SenkouAValues)
+		series.SenkouBValues = last(series.SenkouBValues)
+		return &series
+	}
+	lastSeries := func(s *market.IntradayData) *market.IntradayData {
+		series := *s
+		series.MidPrices = last(series.MidPrices)
+		series.EMA20Values = last(series.EMA20Values)
+		series.MACDValues = last(series.MACDValues)
+		series.RSI7Values = last(series.RSI7Values)
+		series.RSI14Values = last(series.RSI14Values)
+		series.BBWidthValues = last(series.BBWidthValues)
+		series.ATR14Values = last(series.ATR14Values)
+		series.VWAPValues = last(series.VWAPValues)
+		series.ADX14Values = last(series.ADX14Values)
+		series.TakerBuyValues = last(series.TakerBuyValues)
+		series.TakerSellValues = last(series.TakerSellValues)
+		series.CVDValues = last(series.CVDValues)
+		series.Optional = lastOptional(series.Optional)
+		return &series
+	}
+
+	truncated := *data
+	if data.IntradaySeries != nil {
+		truncated.IntradaySeries = lastSeries(data.IntradaySeries)
+	}
+	if data.LongerTermContext != nil {
+		longer := *data.LongerTermContext
+		longer.MACDValues = last(longer.MACDValues)
+		longer.RSI14Values = last(longer.RSI14Values)
+		longer.BBWidthValues = last(longer.BBWidthValues)
+		longer.ATR14Values = last(longer.ATR14Values)
+		longer.VWAPValues = last(longer.VWAPValues)
+		longer.ADX14Values = last(longer.ADX14Values)
+		longer.TakerBuyValues = last(longer.TakerBuyValues)
+		longer.TakerSellValues = last(longer.TakerSellValues)
+		longer.CVDValues = last(longer.CVDValues)
+		longer.Optional = lastOptional(longer.Optional)
+		truncated.LongerTermContext = &longer
+	}
+	if len(data.ExtraTimeframes) > 0 {
+		truncated.ExtraTimeframes = make([]market.TimeframeData, len(data.ExtraTimeframes))
+		for i, tf := range data.ExtraTimeframes {
+			truncated.ExtraTimeframes[i] = market.TimeframeData{Interval: tf.Interval, Series: lastSeries(tf.Series)}
+		}
+	}
+	return &truncated
+}
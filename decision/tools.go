@@ -0,0 +1,138 @@
+package decision
+
+import (
+	"encoding/json"
+	"fmt"
+	"nofx/market"
+	"nofx/mcp"
+	"strings"
+	"time"
+)
+
+// Kline tool limits
+const (
+	defaultToolKlines = 30
+	maxToolKlines     = 100
+)
+
+// toolUseInstruction Appended to the system prompt when the model may call tools
+func toolUseInstruction(rounds int) string {
+	return fmt.Sprintf("\n\n# 🔧 Tools\n\n"+
+		"The prompt only carries a fixed snapshot per coin. If you need more to decide, call a tool: "+
+		"`get_orderbook` (spread, ±0.5%% depth and imbalance), `get_klines` (recent candles on any supported interval) or "+
+		"`get_market_data` (the full indicator snapshot, e.g. for a coin outside the candidate list). "+
+		"Only call a tool when the answer could change a decision; you have at most %d round(s) of calls. "+
+		"Then reply with your chain of thought and decision array exactly as specified above.\n", rounds)
+}
+
+// decisionTools Tools the AI may call mid-reasoning instead of the prompt pre-loading every piece of data
+func decisionTools() []mcp.Tool {
+	symbol := map[string]interface{}{"type": "string", "description": "Perpetual symbol, e.g. BTCUSDT"}
+	var intervals []string
+	for _, interval := range market.Intervals() {
+		intervals = append(intervals, string(interval))
+	}
+
+	return []mcp.Tool{
+		{
+			Name:        "get_orderbook",
+			Description: "Current order book summary: best bid/ask, spread, bid and ask depth within ±0.5% of mid price (USD) and imbalance.",
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"symbol": symbol},
+				"required":   []string{"symbol"},
+			},
+		},
+		{
+			Name:        "get_klines",
+			Description: "Recent OHLCV candles (oldest first, the last one is still open).",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"symbol":   symbol,
+					"interval": map[string]interface{}{"type": "string", "enum": intervals},
+					"limit":    map[string]interface{}{"type": "integer", "description": fmt.Sprintf("Number of candles (default %d, max %d)", defaultToolKlines, maxToolKlines)},
+				},
+				"required": []string{"symbol", "interval"},
+			},
+		},
+		{
+			Name:        "get_market_data",
+			Description: "Full indicator snapshot (price, EMA, MACD, RSI, ATR, open interest, funding, 3m and 4h series) in the same format as the prompt.",
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"symbol": symbol},
+				"required":   []string{"symbol"},
+			},
+		},
+	}
+}
+
+// executeTool Run one tool call; failures are returned as text so the model can carry on without the data
+func executeTool(call mcp.ToolCall) string {
+	var args struct {
+		Symbol   string `json:"symbol"`
+		Interval string `json:"interval"`
+		Limit    int    `json:"limit"`
+	}
+	if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+		return fmt.Sprintf("error: invalid arguments %s: %v", call.Arguments, err)
+	}
+	if args.Symbol == "" {
+		return "error: symbol is required"
+	}
+
+	var result string
+	var err error
+	switch call.Name {
+	case "get_orderbook":
+		result, err = toolOrderbook(args.Symbol)
+	case "get_klines":
+		result, err = toolKlines(args.Symbol, market.Interval(args.Interval), args.Limit)
+	case "get_market_data":
+		var data *market.Data
+		if data, err = market.Get(args.Symbol); err == nil {
+			result = market.Format(data)
+		}
+	default:
+		return fmt.Sprintf("error: unknown tool %q", call.Name)
+	}
+	if err != nil {
+		return fmt.Sprintf("error: %v", err)
+	}
+	return result
+}
+
+// toolOrderbook Order book summary for get_orderbook
+func toolOrderbook(symbol string) (string, error) {
+	depth, err := market.GetDepth(symbol)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s best bid %.6g (qty %.4g), best ask %.6g (qty %.4g), spread %.3f%%\n"+
+		"depth within ±0.5%%: bids $%.0f, asks $%.0f, imbalance %+.2f",
+		market.Canonical(symbol), depth.BestBid, depth.BestBidQty, depth.BestAsk, depth.BestAskQty, depth.SpreadPct,
+		depth.BidDepthUSD, depth.AskDepthUSD, depth.Imbalance), nil
+}
+
+// toolKlines Candle table for get_klines
+func toolKlines(symbol string, interval market.Interval, limit int) (string, error) {
+	if limit <= 0 {
+		limit = defaultToolKlines
+	}
+	if limit > maxToolKlines {
+		limit = maxToolKlines
+	}
+	klines, err := market.GetKlines(symbol, interval, limit)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s candles (UTC open time, open, high, low, close, volume):\n", market.Canonical(symbol), interval)
+	for _, k := range klines {
+		fmt.Fprintf(&sb, "%s %.6g %.6g %.6g %.6g %.4g\n",
+			time.UnixMilli(k.OpenTime).UTC().Format("01-02 15:04"), k.Open, k.High, k.Low, k.Close, k.Volume)
+	}
+	return sb.String(), nil
+}
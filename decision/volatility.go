@@ -0,0 +1,33 @@
+package decision
+
+import (
+	"fmt"
+	"math"
+)
+
+// minStopATRMultiple Minimum stop distance in multiples of the intraday ATR14 (tighter stops sit inside normal noise)
+const minStopATRMultiple = 1.0
+
+// validateStopDistance Reject new positions whose stop loss is closer to entry than the symbol's intraday ATR allows
+func validateStopDistance(d *Decision, ctx *Context) error {
+	marketData, exists := ctx.MarketDataMap[d.Symbol]
+	if !exists || marketData.Volatility == nil || marketData.Volatility.ATRPct <= 0 {
+		return nil
+	}
+
+	entryPrice := marketData.CurrentPrice
+	if d.IsLimitEntry() {
+		entryPrice = d.EntryPrice
+	}
+	if entryPrice <= 0 {
+		return nil
+	}
+
+	stopPct := math.Abs(entryPrice-d.StopLoss) / entryPrice * 100
+	minStopPct := marketData.Volatility.ATRPct * minStopATRMultiple
+	if stopPct < minStopPct {
+		return fmt.Errorf("%s stop loss %.4f is only %.3f%% from entry %.4f, below %gx ATR14 (%.3f%%) - widen the stop and reduce position_size_usd to keep risk_usd unchanged",
+			d.Symbol, d.StopLoss, stopPct, entryPrice, minStopATRMultiple, minStopPct)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package logger
+
+import "time"
+
+// AIUsage 单个周期的AI token用量和费用（主模型、集成投票、风控审核合计）
+type AIUsage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CacheReadTokens  int     `json:"cache_read_tokens,omitempty"`  // 命中提示缓存的输入token数
+	CacheWriteTokens int     `json:"cache_write_tokens,omitempty"` // 写入提示缓存的输入token数
+	CostUSD          float64 `json:"cost_usd"`                     // 按模型价格表计算（未知模型和本地模型不计费，已扣除缓存折扣）
+	CacheSavingsUSD  float64 `json:"cache_savings_usd,omitempty"`  // 提示缓存节省的费用
+}
+
+// AICostSummary 最近的AI费用（直接抵扣收益）
+type AICostSummary struct {
+	TodayUSD    float64 `json:"today_usd"`    // 今日费用
+	WeekUSD     float64 `json:"week_usd"`     // 最近7天费用（含今日）
+	TodayTokens int     `json:"today_tokens"` // 今日token总数（输入+输出）
+	WeekTokens  int     `json:"week_tokens"`  // 最近7天token总数
+	WeekCycles  int     `json:"week_cycles"`  // 最近7天有用量记录的周期数
+
+	TodayCacheSavingsUSD float64 `json:"today_cache_savings_usd"` // 今日提示缓存节省的费用
+	WeekCacheSavingsUSD  float64 `json:"week_cache_savings_usd"`  // 最近7天提示缓存节省的费用
+	WeekCacheHitRate     float64 `json:"week_cache_hit_rate"`     // 最近7天输入token的缓存命中率（0-1）
+}
+
+// GetAICost 统计今日和最近7天的AI费用
+func (l *DecisionLogger) GetAICost(now time.Time) (*AICostSummary, error) {
+	summary := &AICostSummary{}
+	weekPrompt, weekCacheRead := 0, 0
+	for day := 0; day < 7; day++ {
+		records, err := l.GetRecordByDate(now.AddDate(0, 0, -day))
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			if record.AIUsage == nil {
+				continue
+			}
+			tokens := record.AIUsage.PromptTokens + record.AIUsage.CompletionTokens
+			summary.WeekUSD += record.AIUsage.CostUSD
+			summary.WeekTokens += tokens
+			summary.WeekCycles++
+			summary.WeekCacheSavingsUSD += record.AIUsage.CacheSavingsUSD
+			weekPrompt += record.AIUsage.PromptTokens
+			weekCacheRead += record.AIUsage.CacheReadTokens
+			if day == 0 {
+				summary.TodayUSD += record.AIUsage.CostUSD
+				summary.TodayTokens += tokens
+				summary.TodayCacheSavingsUSD += record.AIUsage.CacheSavingsUSD
+			}
+		}
+	}
+	if weekPrompt > 0 {
+		summary.WeekCacheHitRate = float64(weekCacheRead) / float64(weekPrompt)
+	}
+	return summary, nil
+}
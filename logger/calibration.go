@@ -0,0 +1,50 @@
+package logger
+
+// calibrationBucketWidth 置信度分桶宽度（0-9, 10-19, ..., 90-100）
+const calibrationBucketWidth = 10
+
+// CalibrationBucket 置信度校准分桶：AI给出的置信度区间与实际胜率的对比
+type CalibrationBucket struct {
+	MinConfidence int     `json:"min_confidence"` // 区间下限（含）
+	MaxConfidence int     `json:"max_confidence"` // 区间上限（含）
+	Trades        int     `json:"trades"`         // 交易数
+	Wins          int     `json:"wins"`           // 盈利交易数
+	WinRate       float64 `json:"win_rate"`       // 实际胜率（百分比）
+	AvgPnL        float64 `json:"avg_pn_l"`       // 平均盈亏（USDT）
+}
+
+// buildCalibration 按开仓置信度分桶统计实际胜率（未记录置信度的交易不计入，空桶不输出）
+func buildCalibration(trades []TradeOutcome) []CalibrationBucket {
+	buckets := make([]CalibrationBucket, 100/calibrationBucketWidth)
+	for i := range buckets {
+		buckets[i].MinConfidence = i * calibrationBucketWidth
+		buckets[i].MaxConfidence = (i+1)*calibrationBucketWidth - 1
+	}
+	buckets[len(buckets)-1].MaxConfidence = 100
+
+	for _, trade := range trades {
+		if trade.Confidence <= 0 || trade.Confidence > 100 {
+			continue
+		}
+		i := trade.Confidence / calibrationBucketWidth
+		if i >= len(buckets) {
+			i = len(buckets) - 1 // 100归入最后一个桶
+		}
+		buckets[i].Trades++
+		buckets[i].AvgPnL += trade.PnL
+		if trade.PnL > 0 {
+			buckets[i].Wins++
+		}
+	}
+
+	var result []CalibrationBucket
+	for _, b := range buckets {
+		if b.Trades == 0 {
+			continue
+		}
+		b.WinRate = float64(b.Wins) / float64(b.Trades) * 100
+		b.AvgPnL /= float64(b.Trades)
+		result = append(result, b)
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+package logger
+
+// CandidateSnapshot 单个周期候选池中的一个币种
+type CandidateSnapshot struct {
+	Symbol  string   `json:"symbol"`
+	Sources []string `json:"sources"`         // 来源（例如"ai500"/"oi_top"/"watchlist"）
+	Score   float64  `json:"score,omitempty"` // 各来源中的最高评分
+	Tags    []string `json:"tags,omitempty"`  // 来源附加的标签
+}
+
+// CandidateCycle 币种在某个周期是否在候选池中
+type CandidateCycle struct {
+	CycleNumber int                `json:"cycle_number"`
+	Timestamp   string             `json:"timestamp"`
+	Considered  bool               `json:"considered"`          // 是否在候选池中
+	Candidate   *CandidateSnapshot `json:"candidate,omitempty"` // 候选信息（未进入候选池时为nil）
+	PoolSize    int                `json:"pool_size"`           // 该周期候选池大小
+}
+
+// CandidateHistory 币种在最近若干周期的候选池记录（用于回答"币种X拉升时是否被考虑过"）
+type CandidateHistory struct {
+	Symbol           string           `json:"symbol"`
+	Cycles           int              `json:"cycles"`            // 统计的周期数
+	ConsideredCycles int              `json:"considered_cycles"` // 进入候选池的周期数
+	FirstConsidered  string           `json:"first_considered,omitempty"`
+	LastConsidered   string           `json:"last_considered,omitempty"`
+	History          []CandidateCycle `json:"history"` // 按时间从旧到新
+}
+
+// GetCandidateHistory 统计币种在最近lookbackCycles个周期的候选池记录
+// （早期记录没有保存候选池详情时，根据候选币种列表判断，来源和评分为空）
+func (l *DecisionLogger) GetCandidateHistory(symbol string, lookbackCycles int) (*CandidateHistory, error) {
+	records, err := l.GetLatestRecords(lookbackCycles)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &CandidateHistory{Symbol: symbol, Cycles: len(records), History: make([]CandidateCycle, 0, len(records))}
+	for _, record := range records {
+		cycle := CandidateCycle{
+			CycleNumber: record.CycleNumber,
+			Timestamp:   record.Timestamp.Format("2006-01-02 15:04:05"),
+			PoolSize:    len(record.CandidateCoins),
+		}
+		if len(record.CandidatePool) > 0 {
+			cycle.PoolSize = len(record.CandidatePool)
+			for i := range record.CandidatePool {
+				if record.CandidatePool[i].Symbol == symbol {
+					cycle.Candidate = &record.CandidatePool[i]
+					break
+				}
+			}
+			cycle.Considered = cycle.Candidate != nil
+		} else {
+			for _, s := range record.CandidateCoins {
+				if s == symbol {
+					cycle.Considered = true
+					break
+				}
+			}
+		}
+
+		if cycle.Considered {
+			history.ConsideredCycles++
+			if history.FirstConsidered == "" {
+				history.FirstConsidered = cycle.Timestamp
+			}
+			history.LastConsidered = cycle.Timestamp
+		}
+		history.History = append(history.History, cycle)
+	}
+	return history, nil
+}
@@ -12,18 +12,25 @@ import (
 
 // DecisionRecord 决策记录
 type DecisionRecord struct {
-	Timestamp      time.Time          `json:"timestamp"`       // 决策时间
-	CycleNumber    int                `json:"cycle_number"`    // 周期编号
-	InputPrompt    string             `json:"input_prompt"`    // 发送给AI的输入prompt
-	CoTTrace       string             `json:"cot_trace"`       // AI思维链（输出）
-	DecisionJSON   string             `json:"decision_json"`   // 决策JSON
-	AccountState   AccountSnapshot    `json:"account_state"`   // 账户状态快照
-	Positions      []PositionSnapshot `json:"positions"`       // 持仓快照
-	CandidateCoins []string           `json:"candidate_coins"` // 候选币种列表
-	Decisions      []DecisionAction   `json:"decisions"`       // 执行的决策
-	ExecutionLog   []string           `json:"execution_log"`   // 执行日志
-	Success        bool               `json:"success"`         // 是否成功
-	ErrorMessage   string             `json:"error_message"`   // 错误信息（如果有）
+	Timestamp       time.Time           `json:"timestamp"`                   // 决策时间
+	CycleNumber     int                 `json:"cycle_number"`                // 周期编号
+	CycleID         string              `json:"cycle_id,omitempty"`          // 周期ID（用于查询该周期的AI请求/响应归档）
+	InputPrompt     string              `json:"input_prompt"`                // 发送给AI的输入prompt
+	RawResponse     string              `json:"raw_response,omitempty"`      // AI原始响应（用于离线回放）
+	AIProvider      string              `json:"ai_provider,omitempty"`       // 产生决策的模型（provider/model，故障切换时为备用模型）
+	AIUsage         *AIUsage            `json:"ai_usage,omitempty"`          // 本周期AI token用量和费用
+	CoTTrace        string              `json:"cot_trace"`                   // AI思维链（输出）
+	CoTSectionsJSON string              `json:"cot_sections_json,omitempty"` // 思维链分段（市场状态/持仓回顾/新机会/风险检查）
+	DecisionJSON    string              `json:"decision_json"`               // 决策JSON
+	ModelTracesJSON string              `json:"model_traces_json,omitempty"` // 集成投票时各模型的原始输出
+	AccountState    AccountSnapshot     `json:"account_state"`               // 账户状态快照
+	Positions       []PositionSnapshot  `json:"positions"`                   // 持仓快照
+	CandidateCoins  []string            `json:"candidate_coins"`             // 候选币种列表
+	CandidatePool   []CandidateSnapshot `json:"candidate_pool,omitempty"`    // 候选池详情（来源、评分、标签）
+	Decisions       []DecisionAction    `json:"decisions"`                   // 执行的决策
+	ExecutionLog    []string            `json:"execution_log"`               // 执行日志
+	Success         bool                `json:"success"`                     // 是否成功
+	ErrorMessage    string              `json:"error_message"`               // 错误信息（如果有）
 }
 
 // AccountSnapshot 账户状态快照
@@ -49,15 +56,17 @@ type PositionSnapshot struct {
 
 // DecisionAction 决策动作
 type DecisionAction struct {
-	Action    string    `json:"action"`    // open_long, open_short, close_long, close_short
-	Symbol    string    `json:"symbol"`    // 币种
-	Quantity  float64   `json:"quantity"`  // 数量
-	Leverage  int       `json:"leverage"`  // 杠杆（开仓时）
-	Price     float64   `json:"price"`     // 执行价格
-	OrderID   int64     `json:"order_id"`  // 订单ID
-	Timestamp time.Time `json:"timestamp"` // 执行时间
-	Success   bool      `json:"success"`   // 是否成功
-	Error     string    `json:"error"`     // 错误信息
+	Action     string    `json:"action"`               // open_long, open_short, close_long, close_short, reduce_long, reduce_short
+	Symbol     string    `json:"symbol"`               // 币种
+	Quantity   float64   `json:"quantity"`             // 数量
+	Leverage   int       `json:"leverage"`             // 杠杆（开仓时）
+	Price      float64   `json:"price"`                // 执行价格
+	OrderID    int64     `json:"order_id"`             // 订单ID
+	Timestamp  time.Time `json:"timestamp"`            // 执行时间
+	Success    bool      `json:"success"`              // 是否成功
+	Error      string    `json:"error"`                // 错误信息
+	Reasoning  string    `json:"reasoning,omitempty"`  // AI决策理由
+	Confidence int       `json:"confidence,omitempty"` // AI置信度（0-100，开仓时）
 }
 
 // DecisionLogger 决策日志记录器
@@ -269,36 +278,39 @@ type Statistics struct {
 
 // TradeOutcome 单笔交易结果
 type TradeOutcome struct {
-	Symbol        string    `json:"symbol"`         // 币种
-	Side          string    `json:"side"`           // long/short
-	Quantity      float64   `json:"quantity"`       // 仓位数量
-	Leverage      int       `json:"leverage"`       // 杠杆倍数
-	OpenPrice     float64   `json:"open_price"`     // 开仓价
-	ClosePrice    float64   `json:"close_price"`    // 平仓价
-	PositionValue float64   `json:"position_value"` // 仓位价值（quantity × openPrice）
-	MarginUsed    float64   `json:"margin_used"`    // 保证金使用（positionValue / leverage）
-	PnL           float64   `json:"pn_l"`           // 盈亏（USDT）
-	PnLPct        float64   `json:"pn_l_pct"`       // 盈亏百分比（相对保证金）
-	Duration      string    `json:"duration"`       // 持仓时长
-	OpenTime      time.Time `json:"open_time"`      // 开仓时间
-	CloseTime     time.Time `json:"close_time"`     // 平仓时间
-	WasStopLoss   bool      `json:"was_stop_loss"`  // 是否止损
+	Symbol        string    `json:"symbol"`               // 币种
+	Side          string    `json:"side"`                 // long/short
+	Quantity      float64   `json:"quantity"`             // 仓位数量
+	Leverage      int       `json:"leverage"`             // 杠杆倍数
+	OpenPrice     float64   `json:"open_price"`           // 开仓价
+	ClosePrice    float64   `json:"close_price"`          // 平仓价
+	PositionValue float64   `json:"position_value"`       // 仓位价值（quantity × openPrice）
+	MarginUsed    float64   `json:"margin_used"`          // 保证金使用（positionValue / leverage）
+	PnL           float64   `json:"pn_l"`                 // 盈亏（USDT）
+	PnLPct        float64   `json:"pn_l_pct"`             // 盈亏百分比（相对保证金）
+	Duration      string    `json:"duration"`             // 持仓时长
+	OpenTime      time.Time `json:"open_time"`            // 开仓时间
+	CloseTime     time.Time `json:"close_time"`           // 平仓时间
+	WasStopLoss   bool      `json:"was_stop_loss"`        // 是否止损
+	Confidence    int       `json:"confidence,omitempty"` // 开仓时AI给出的置信度（0表示未记录）
 }
 
 // PerformanceAnalysis 交易表现分析
 type PerformanceAnalysis struct {
-	TotalTrades   int                           `json:"total_trades"`   // 总交易数
-	WinningTrades int                           `json:"winning_trades"` // 盈利交易数
-	LosingTrades  int                           `json:"losing_trades"`  // 亏损交易数
-	WinRate       float64                       `json:"win_rate"`       // 胜率
-	AvgWin        float64                       `json:"avg_win"`        // 平均盈利
-	AvgLoss       float64                       `json:"avg_loss"`       // 平均亏损
-	ProfitFactor  float64                       `json:"profit_factor"`  // 盈亏比
-	SharpeRatio   float64                       `json:"sharpe_ratio"`   // 夏普比率（风险调整后收益）
-	RecentTrades  []TradeOutcome                `json:"recent_trades"`  // 最近N笔交易
-	SymbolStats   map[string]*SymbolPerformance `json:"symbol_stats"`   // 各币种表现
-	BestSymbol    string                        `json:"best_symbol"`    // 表现最好的币种
-	WorstSymbol   string                        `json:"worst_symbol"`   // 表现最差的币种
+	TotalTrades   int                           `json:"total_trades"`          // 总交易数
+	WinningTrades int                           `json:"winning_trades"`        // 盈利交易数
+	LosingTrades  int                           `json:"losing_trades"`         // 亏损交易数
+	WinRate       float64                       `json:"win_rate"`              // 胜率
+	AvgWin        float64                       `json:"avg_win"`               // 平均盈利
+	AvgLoss       float64                       `json:"avg_loss"`              // 平均亏损
+	ProfitFactor  float64                       `json:"profit_factor"`         // 盈亏比
+	SharpeRatio   float64                       `json:"sharpe_ratio"`          // 夏普比率（风险调整后收益）
+	RecentTrades  []TradeOutcome                `json:"recent_trades"`         // 最近N笔交易
+	SymbolStats   map[string]*SymbolPerformance `json:"symbol_stats"`          // 各币种表现
+	BestSymbol    string                        `json:"best_symbol"`           // 表现最好的币种
+	WorstSymbol   string                        `json:"worst_symbol"`          // 表现最差的币种
+	Calibration   []CalibrationBucket           `json:"calibration,omitempty"` // 置信度校准（各置信度区间的实际胜率）
+	AICost        *AICostSummary                `json:"ai_cost,omitempty"`     // 今日/最近7天AI费用
 }
 
 // SymbolPerformance 币种表现统计
@@ -347,9 +359,9 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 
 				symbol := action.Symbol
 				side := ""
-				if action.Action == "open_long" || action.Action == "close_long" {
+				if action.Action == "open_long" || action.Action == "close_long" || action.Action == "reduce_long" {
 					side = "long"
-				} else if action.Action == "open_short" || action.Action == "close_short" {
+				} else if action.Action == "open_short" || action.Action == "close_short" || action.Action == "reduce_short" {
 					side = "short"
 				}
 				posKey := symbol + "_" + side
@@ -358,11 +370,12 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 				case "open_long", "open_short":
 					// 记录开仓
 					openPositions[posKey] = map[string]interface{}{
-						"side":      side,
-						"openPrice": action.Price,
-						"openTime":  action.Timestamp,
-						"quantity":  action.Quantity,
-						"leverage":  action.Leverage,
+						"side":       side,
+						"openPrice":  action.Price,
+						"openTime":   action.Timestamp,
+						"quantity":   action.Quantity,
+						"leverage":   action.Leverage,
+						"confidence": action.Confidence,
 					}
 				case "close_long", "close_short":
 					// 移除已平仓记录
@@ -381,9 +394,9 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 
 			symbol := action.Symbol
 			side := ""
-			if action.Action == "open_long" || action.Action == "close_long" {
+			if action.Action == "open_long" || action.Action == "close_long" || action.Action == "reduce_long" {
 				side = "long"
-			} else if action.Action == "open_short" || action.Action == "close_short" {
+			} else if action.Action == "open_short" || action.Action == "close_short" || action.Action == "reduce_short" {
 				side = "short"
 			}
 			posKey := symbol + "_" + side // 使用symbol_side作为key，区分多空持仓
@@ -392,14 +405,15 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 			case "open_long", "open_short":
 				// 更新开仓记录（可能已经在预填充时记录过了）
 				openPositions[posKey] = map[string]interface{}{
-					"side":      side,
-					"openPrice": action.Price,
-					"openTime":  action.Timestamp,
-					"quantity":  action.Quantity,
-					"leverage":  action.Leverage,
+					"side":       side,
+					"openPrice":  action.Price,
+					"openTime":   action.Timestamp,
+					"quantity":   action.Quantity,
+					"leverage":   action.Leverage,
+					"confidence": action.Confidence,
 				}
 
-			case "close_long", "close_short":
+			case "close_long", "close_short", "reduce_long", "reduce_short":
 				// 查找对应的开仓记录（可能来自预填充或当前窗口）
 				if openPos, exists := openPositions[posKey]; exists {
 					openPrice := openPos["openPrice"].(float64)
@@ -407,6 +421,14 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 					side := openPos["side"].(string)
 					quantity := openPos["quantity"].(float64)
 					leverage := openPos["leverage"].(int)
+					confidence := openPos["confidence"].(int)
+
+					// 部分平仓：只结算平掉的数量，剩余部分继续持有
+					remaining := 0.0
+					if (action.Action == "reduce_long" || action.Action == "reduce_short") && action.Quantity < quantity {
+						remaining = quantity - action.Quantity
+						quantity = action.Quantity
+					}
 
 					// 计算实际盈亏（USDT）
 					// 合约交易 PnL 计算：quantity × 价格差
@@ -441,6 +463,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 						Duration:      action.Timestamp.Sub(openTime).String(),
 						OpenTime:      openTime,
 						CloseTime:     action.Timestamp,
+						Confidence:    confidence,
 					}
 
 					analysis.RecentTrades = append(analysis.RecentTrades, outcome)
@@ -471,8 +494,12 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 						stats.LosingTrades++
 					}
 
-					// 移除已平仓记录
-					delete(openPositions, posKey)
+					// 移除已平仓记录（部分平仓则更新剩余数量）
+					if remaining > 0 {
+						openPos["quantity"] = remaining
+					} else {
+						delete(openPositions, posKey)
+					}
 				}
 			}
 		}
@@ -522,6 +549,9 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 		}
 	}
 
+	// 置信度校准（使用全部交易，在截断最近交易列表之前计算）
+	analysis.Calibration = buildCalibration(analysis.RecentTrades)
+
 	// 只保留最近的交易（倒序：最新的在前）
 	if len(analysis.RecentTrades) > 10 {
 		// 反转数组，让最新的在前
@@ -539,6 +569,11 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 	// 计算夏普比率（需要至少2个数据点）
 	analysis.SharpeRatio = l.calculateSharpeRatio(records)
 
+	// AI费用（按日期统计，与lookback窗口无关）
+	if cost, err := l.GetAICost(time.Now()); err == nil && cost.WeekCycles > 0 {
+		analysis.AICost = cost
+	}
+
 	return analysis, nil
 }
 
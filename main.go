@@ -4,16 +4,29 @@ import (
 	"fmt"
 	"log"
 	"nofx/api"
+	"nofx/calendar"
 	"nofx/config"
 	"nofx/manager"
+	"nofx/market"
+	"nofx/mcp"
+	"nofx/news"
 	"nofx/pool"
+	"nofx/proxy"
+	"nofx/whale"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 )
 
 func main() {
+	// 离线回放模式
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		runReplay(os.Args[2:])
+		return
+	}
+
 	fmt.Println("╔════════════════════════════════════════════════════════════╗")
 	fmt.Println("║    🏆 AI模型交易竞赛系统 - Qwen vs DeepSeek               ║")
 	fmt.Println("╚════════════════════════════════════════════════════════════╝")
@@ -34,6 +47,25 @@ func main() {
 	log.Printf("✓ 配置加载成功，共%d个trader参赛", len(cfg.Traders))
 	fmt.Println()
 
+	// 代理（必须在创建任何API客户端之前设置）
+	if cfg.Proxy != nil {
+		if err := proxy.SetDefault(cfg.Proxy.URL); err != nil {
+			log.Fatalf("❌ 设置代理失败: %v", err)
+		}
+		if err := proxy.SetKind(proxy.KindAI, cfg.Proxy.AI); err != nil {
+			log.Fatalf("❌ 设置AI接口代理失败: %v", err)
+		}
+		if err := proxy.SetKind(proxy.KindExchange, cfg.Proxy.Exchange); err != nil {
+			log.Fatalf("❌ 设置交易所接口代理失败: %v", err)
+		}
+		for host, proxyURL := range cfg.Proxy.Hosts {
+			if err := proxy.SetHost(host, proxyURL); err != nil {
+				log.Fatalf("❌ 设置%s的代理失败: %v", host, err)
+			}
+		}
+		log.Printf("✓ 已启用代理配置（按主机覆盖%d个）", len(cfg.Proxy.Hosts))
+	}
+
 	// 设置默认主流币种列表
 	pool.SetDefaultCoins(cfg.DefaultCoins)
 
@@ -52,6 +84,113 @@ func main() {
 		pool.SetOITopAPI(cfg.OITopAPIURL)
 		log.Printf("✓ 已配置OI Top API")
 	}
+	if len(cfg.OITopWindows) > 0 {
+		windows := make([]pool.OITopWindow, 0, len(cfg.OITopWindows))
+		for _, w := range cfg.OITopWindows {
+			windows = append(windows, pool.OITopWindow{Window: w.Window, Limit: w.Limit})
+		}
+		pool.SetOITopWindows(windows)
+		log.Printf("✓ OI Top统计窗口: %d个", len(windows))
+	}
+
+	// 币种池刷新间隔（独立于决策周期）
+	if cfg.PoolRefreshMinutes > 0 {
+		pool.SetRefreshInterval(time.Duration(cfg.PoolRefreshMinutes) * time.Minute)
+		log.Printf("✓ 币种池每%d分钟刷新一次", cfg.PoolRefreshMinutes)
+	}
+
+	// 候选币种来源（未配置时使用默认的AI500 + OI Top）
+	if len(cfg.CandidateSources) > 0 {
+		settings := make([]pool.SourceSetting, 0, len(cfg.CandidateSources))
+		for _, source := range cfg.CandidateSources {
+			if source.URL != "" {
+				pool.RegisterSource(pool.NewHTTPSource(source.Name, source.URL))
+			}
+			settings = append(settings, pool.SourceSetting{Name: source.Name, Limit: source.Limit})
+		}
+		if err := pool.SetSources(settings); err != nil {
+			log.Fatalf("❌ 设置候选币种来源失败: %v", err)
+		}
+		log.Printf("✓ 候选币种来源: %d个", len(settings))
+	}
+
+	// 自选币种和黑名单
+	if len(cfg.Watchlist) > 0 {
+		pool.SetWatchlist(cfg.Watchlist)
+		log.Printf("✓ 自选币种: %v", cfg.Watchlist)
+	}
+	if len(cfg.Blacklist) > 0 {
+		pool.SetBlacklist(cfg.Blacklist)
+		log.Printf("✓ 禁止交易币种: %v", cfg.Blacklist)
+	}
+
+	// 板块映射
+	if len(cfg.Sectors) > 0 {
+		pool.SetSectors(cfg.Sectors)
+		log.Printf("✓ 已加载自定义板块映射（%d个板块）", len(cfg.Sectors))
+	}
+
+	// 社交热度候选来源
+	if cfg.Social != nil {
+		pool.RegisterSource(pool.NewSocialSource(cfg.Social.Provider, cfg.Social.APIKey, cfg.Social.Limit))
+		pool.EnableSource(pool.SourceSocial)
+		log.Printf("✓ 已启用社交热度候选来源: %s", cfg.Social.Provider)
+	}
+
+	// 新上线永续合约
+	if cfg.NewListings != nil {
+		pool.SetNewListings(cfg.NewListings.Mode, cfg.NewListings.MaxAgeHours, cfg.NewListings.MinQuoteVolume)
+		log.Printf("✓ 新上线合约处理方式: %s", cfg.NewListings.Mode)
+	}
+
+	// AI模型价格（费用统计）
+	for model, price := range cfg.AIPrices {
+		mcp.SetModelPrice(model, mcp.Price{Input: price.Input, Output: price.Output})
+	}
+
+	// AI响应缓存
+	if cfg.AICacheMinutes > 0 {
+		mcp.SetResponseCache("ai_cache", time.Duration(cfg.AICacheMinutes)*time.Minute)
+		log.Printf("✓ AI响应缓存有效期: %d分钟", cfg.AICacheMinutes)
+	}
+
+	// 行情数据源
+	if err := market.SetSource(cfg.MarketSource); err != nil {
+		log.Fatalf("❌ 设置行情数据源失败: %v", err)
+	}
+	log.Printf("✓ 行情数据源: %s", cfg.MarketSource)
+
+	// 新闻数据源
+	if cfg.News != nil {
+		news.SetProvider(news.NewCryptoPanic(cfg.News.APIKey), cfg.News.MaxHeadlines, cfg.News.MaxAgeHours)
+		log.Printf("✓ 已启用新闻数据源: %s", cfg.News.Provider)
+	}
+
+	// 经济日历
+	if cfg.Calendar != nil {
+		if err := calendar.Load(cfg.Calendar.File, cfg.Calendar.LookaheadHours, cfg.Calendar.BlackoutBeforeMinutes, cfg.Calendar.BlackoutAfterMinutes); err != nil {
+			log.Fatalf("❌ 加载经济日历失败: %v", err)
+		}
+		log.Printf("✓ 已加载经济日历: %s", cfg.Calendar.File)
+	}
+
+	// 大额链上转账数据源
+	if cfg.WhaleAlerts != nil {
+		whale.SetProvider(whale.NewWhaleAlert(cfg.WhaleAlerts.APIKey, cfg.WhaleAlerts.MinValueUSD), cfg.WhaleAlerts.MaxTransfers)
+		log.Printf("✓ 已启用大额转账数据源: %s", cfg.WhaleAlerts.Provider)
+	}
+
+	// 全市场强平流
+	if err := market.EnableLiquidationFeed(); err != nil {
+		log.Printf("⚠ 订阅强平流失败，行情数据中将不包含强平统计: %v", err)
+	}
+
+	// WebSocket行情流
+	if cfg.MarketStream {
+		if err := market.EnableStream(); err != nil {
+			log.Printf("⚠ 启用WebSocket行情流失败，继续使用REST轮询: %v", err)
+		}
+	}
 
 	// 创建TraderManager
 	traderManager := manager.NewTraderManager()
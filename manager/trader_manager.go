@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"log"
 	"nofx/config"
+	"nofx/decision"
+	"nofx/market"
+	"nofx/mcp"
 	"nofx/trader"
 	"sync"
 	"time"
@@ -13,6 +16,7 @@ import (
 type TraderManager struct {
 	traders map[string]*trader.AutoTrader // key: trader ID
 	mu      sync.RWMutex
+	running sync.WaitGroup // 正在运行的trader主循环
 }
 
 // NewTraderManager 创建trader管理器
@@ -37,8 +41,14 @@ func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string,
 		Name:                  cfg.Name,
 		AIModel:               cfg.AIModel,
 		Exchange:              cfg.Exchange,
+		Testnet:               cfg.Testnet,
 		BinanceAPIKey:         cfg.BinanceAPIKey,
 		BinanceSecretKey:      cfg.BinanceSecretKey,
+		BybitAPIKey:           cfg.BybitAPIKey,
+		BybitSecretKey:        cfg.BybitSecretKey,
+		OKXAPIKey:             cfg.OKXAPIKey,
+		OKXSecretKey:          cfg.OKXSecretKey,
+		OKXPassphrase:         cfg.OKXPassphrase,
 		HyperliquidPrivateKey: cfg.HyperliquidPrivateKey,
 		HyperliquidWalletAddr: cfg.HyperliquidWalletAddr,
 		HyperliquidTestnet:    cfg.HyperliquidTestnet,
@@ -49,9 +59,23 @@ func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string,
 		UseQwen:               cfg.AIModel == "qwen",
 		DeepSeekKey:           cfg.DeepSeekKey,
 		QwenKey:               cfg.QwenKey,
+		OpenAIKey:             cfg.OpenAIKey,
+		AnthropicKey:          cfg.AnthropicKey,
+		GeminiKey:             cfg.GeminiKey,
+		ModelName:             cfg.ModelName,
+		LocalAPIURL:           cfg.LocalAPIURL,
+		LocalAPIKey:           cfg.LocalAPIKey,
+		MockResponsesFile:     cfg.MockResponsesFile,
+		AzureEndpoint:         cfg.AzureEndpoint,
+		AzureAPIKey:           cfg.AzureAPIKey,
+		AzureDeployment:       cfg.AzureDeployment,
+		AzureAPIVersion:       cfg.AzureAPIVersion,
+		OpenRouterKey:         cfg.OpenRouterKey,
+		OpenRouter:            openRouterOptions(cfg.OpenRouter),
 		CustomAPIURL:          cfg.CustomAPIURL,
 		CustomAPIKey:          cfg.CustomAPIKey,
 		CustomModelName:       cfg.CustomModelName,
+		StructuredOutput:      cfg.StructuredOutput,
 		ScanInterval:          cfg.GetScanInterval(),
 		InitialBalance:        cfg.InitialBalance,
 		BTCETHLeverage:        leverage.BTCETHLeverage,  // 使用配置的杠杆倍数
@@ -59,8 +83,185 @@ func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string,
 		MaxDailyLoss:          maxDailyLoss,
 		MaxDrawdown:           maxDrawdown,
 		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
+		PromptTemplateFile:    cfg.PromptTemplateFile,
+		ValidationRetries:     cfg.ValidationRetries,
+		MinRiskReward:         cfg.MinRiskReward,
+		MaxPositions:          cfg.MaxPositions,
+		DecisionMemory:        cfg.DecisionMemory,
+		InvalidationAction:    cfg.InvalidationAction,
+		TradeCooldown:         cfg.TradeCooldownMinutes,
+		PromptTokenBudget:     cfg.PromptTokenBudget,
+		HedgeMode:             cfg.HedgeMode,
+		FearGreed:             cfg.FearGreed,
+		DepegThreshold:        cfg.DepegThresholdPct,
+		PauseOnDepeg:          cfg.PauseOnDepeg,
+		MaxSectorPositions:    cfg.MaxSectorPositions,
+		MaxSpreadPct:          cfg.MaxSpreadPct,
+		MinDepthMultiple:      cfg.MinDepthMultiple,
+		Timeframes:            cfg.Timeframes,
+		Indicators:            cfg.Indicators,
 	}
 
+	// 单币种限制
+	if len(leverage.Symbols) > 0 {
+		traderConfig.SymbolLimits = make(map[string]decision.SymbolLimit, len(leverage.Symbols))
+		for symbol, limit := range leverage.Symbols {
+			traderConfig.SymbolLimits[symbol] = decision.SymbolLimit{
+				MaxLeverage:         limit.MaxLeverage,
+				MaxPositionMultiple: limit.MaxPositionMultiple,
+				MaxRiskPct:          limit.MaxRiskPct,
+			}
+		}
+	}
+
+	// Kelly仓位计算
+	if cfg.PositionSizing != nil {
+		traderConfig.PositionSizing = decision.PositionSizing{
+			Mode:          cfg.PositionSizing.Mode,
+			KellyFraction: cfg.PositionSizing.KellyFraction,
+			MinTrades:     cfg.PositionSizing.MinTrades,
+		}
+	}
+
+	// 多模型集成投票
+	if cfg.Ensemble != nil {
+		for _, m := range cfg.Ensemble.Models {
+			traderConfig.EnsembleModels = append(traderConfig.EnsembleModels, trader.AIModelSpec{
+				AIModel:   m.AIModel,
+				APIKey:    m.APIKey,
+				APIURL:    m.APIURL,
+				ModelName: m.ModelName,
+				Params:    generationParams(m.Params, cfg.AIParams),
+
+				APIVersion:        m.APIVersion,
+				OpenRouter:        openRouterOptions(m.OpenRouter),
+				MockResponsesFile: m.MockResponsesFile,
+			})
+		}
+		traderConfig.EnsembleMode = cfg.Ensemble.Mode
+		traderConfig.EnsembleMinAgree = cfg.Ensemble.MinAgree
+		traderConfig.EnsembleLatencyBudget = time.Duration(cfg.Ensemble.LatencyBudgetSeconds) * time.Second
+	}
+
+	// 行情数据格式
+	if cfg.MarketDataFormat != nil {
+		traderConfig.MarketDataFormat = decision.MarketDataFormat{
+			Positions:         cfg.MarketDataFormat.Positions,
+			TopCandidates:     cfg.MarketDataFormat.TopCandidates,
+			Candidates:        cfg.MarketDataFormat.Candidates,
+			TopCandidateCount: cfg.MarketDataFormat.TopCandidateCount,
+		}
+	}
+
+	// 交易成本模型
+	if cfg.CostModel != nil {
+		traderConfig.CostModel = decision.CostModel{
+			TakerFeePct:     cfg.CostModel.TakerFeePct,
+			SlippagePct:     cfg.CostModel.SlippagePct,
+			HoldHours:       cfg.CostModel.HoldHours,
+			MinCostMultiple: cfg.CostModel.MinCostMultiple,
+			Action:          cfg.CostModel.Action,
+		}
+	}
+
+	// 风控审核模型
+	if cfg.RiskOfficer != nil {
+		traderConfig.RiskOfficer = &trader.AIModelSpec{
+			AIModel:   cfg.RiskOfficer.AIModel,
+			APIKey:    cfg.RiskOfficer.APIKey,
+			APIURL:    cfg.RiskOfficer.APIURL,
+			ModelName: cfg.RiskOfficer.ModelName,
+			Params:    generationParams(cfg.RiskOfficer.Params, cfg.AIParams),
+
+			APIVersion:        cfg.RiskOfficer.APIVersion,
+			OpenRouter:        openRouterOptions(cfg.RiskOfficer.OpenRouter),
+			MockResponsesFile: cfg.RiskOfficer.MockResponsesFile,
+		}
+	}
+
+	// 按风险路由的便宜模型
+	if cfg.ModelRouting != nil {
+		cheap := cfg.ModelRouting.CheapModel
+		traderConfig.CheapModel = &trader.AIModelSpec{
+			AIModel:   cheap.AIModel,
+			APIKey:    cheap.APIKey,
+			APIURL:    cheap.APIURL,
+			ModelName: cheap.ModelName,
+			Params:    generationParams(cheap.Params, cfg.AIParams),
+
+			APIVersion:        cheap.APIVersion,
+			OpenRouter:        openRouterOptions(cheap.OpenRouter),
+			MockResponsesFile: cheap.MockResponsesFile,
+		}
+		traderConfig.EscalationScore = cfg.ModelRouting.MinCandidateScore
+	}
+
+	traderConfig.AIRequestsPerMinute = cfg.AIRequestsPerMinute
+	if cfg.AIRetry != nil {
+		traderConfig.AIRetry = &mcp.RetryPolicy{
+			MaxAttempts:      cfg.AIRetry.MaxAttempts,
+			BaseBackoff:      time.Duration(cfg.AIRetry.BaseBackoffSeconds * float64(time.Second)),
+			MaxBackoff:       time.Duration(cfg.AIRetry.MaxBackoffSeconds * float64(time.Second)),
+			BreakerThreshold: cfg.AIRetry.BreakerThreshold,
+			BreakerCooldown:  time.Duration(cfg.AIRetry.BreakerCooldownSeconds) * time.Second,
+		}
+	}
+	traderConfig.AIStream = cfg.AIStream
+	traderConfig.AIToolRounds = cfg.AIToolRounds
+	if cfg.ChartImages != nil {
+		traderConfig.Charts = decision.ChartConfig{
+			MaxSymbols: cfg.ChartImages.MaxSymbols,
+			Interval:   market.Interval(cfg.ChartImages.Interval),
+			Bars:       cfg.ChartImages.Bars,
+		}
+	}
+	traderConfig.AIArchiveDays = cfg.AIArchiveDays
+	if cfg.TrailingStop != nil {
+		traderConfig.TrailingStop = trader.TrailingStopOptions{
+			OnExchange:  cfg.TrailingStop.Mode == "exchange",
+			ActivationR: cfg.TrailingStop.ActivationR,
+			ATRMultiple: cfg.TrailingStop.ATRMultiple,
+			ATRInterval: market.Interval(cfg.TrailingStop.ATRInterval),
+		}
+	}
+	if cfg.EntryExecution != nil {
+		traderConfig.EntryExecution = trader.EntryExecutionOptions{
+			Chase:           cfg.EntryExecution.Mode == "chase",
+			MaxReprices:     cfg.EntryExecution.MaxReprices,
+			RepriceInterval: time.Duration(cfg.EntryExecution.RepriceSeconds) * time.Second,
+
+			MaxVolumeFraction: cfg.EntryExecution.MaxVolumeFraction,
+			MaxDepthFraction:  cfg.EntryExecution.MaxDepthFraction,
+			SliceInterval:     time.Duration(cfg.EntryExecution.SliceSeconds) * time.Second,
+			MaxSlices:         cfg.EntryExecution.MaxSlices,
+		}
+	}
+	if cfg.Paper != nil {
+		traderConfig.Paper = trader.PaperOptions{
+			TakerFeePct: cfg.Paper.TakerFeePct,
+			MakerFeePct: cfg.Paper.MakerFeePct,
+			SlippagePct: cfg.Paper.SlippagePct,
+		}
+	}
+
+	// 主模型故障切换链
+	for _, m := range cfg.AIFallbacks {
+		traderConfig.AIFallbacks = append(traderConfig.AIFallbacks, trader.AIModelSpec{
+			AIModel:   m.AIModel,
+			APIKey:    m.APIKey,
+			APIURL:    m.APIURL,
+			ModelName: m.ModelName,
+			Params:    generationParams(m.Params, cfg.AIParams),
+
+			APIVersion:        m.APIVersion,
+			OpenRouter:        openRouterOptions(m.OpenRouter),
+			MockResponsesFile: m.MockResponsesFile,
+		})
+	}
+
+	// AI生成参数
+	traderConfig.AIParams = generationParams(nil, cfg.AIParams)
+
 	// 创建trader实例
 	at, err := trader.NewAutoTrader(traderConfig)
 	if err != nil {
@@ -72,6 +273,37 @@ func (tm *TraderManager) AddTrader(cfg config.TraderConfig, coinPoolURL string,
 	return nil
 }
 
+// generationParams 模型自己的生成参数优先，否则使用trader的ai_params（都未配置时返回nil，使用默认值）
+func generationParams(own, traderParams *config.AIParamsConfig) *mcp.GenerationParams {
+	p := own
+	if p == nil {
+		p = traderParams
+	}
+	if p == nil {
+		return nil
+	}
+	return &mcp.GenerationParams{
+		Temperature:     p.Temperature,
+		TopP:            p.TopP,
+		MaxTokens:       p.MaxTokens,
+		ReasoningEffort: p.ReasoningEffort,
+		ThinkingBudget:  p.ThinkingBudget,
+		Stop:            p.Stop,
+	}
+}
+
+// openRouterOptions 转换OpenRouter路由配置（未配置时返回nil）
+func openRouterOptions(cfg *config.OpenRouterConfig) *mcp.OpenRouterOptions {
+	if cfg == nil {
+		return nil
+	}
+	return &mcp.OpenRouterOptions{
+		Models:        cfg.Models,
+		ProviderOrder: cfg.ProviderOrder,
+		Sort:          cfg.Sort,
+	}
+}
+
 // GetTrader 获取指定ID的trader
 func (tm *TraderManager) GetTrader(id string) (*trader.AutoTrader, error) {
 	tm.mu.RLock()
@@ -115,7 +347,9 @@ func (tm *TraderManager) StartAll() {
 
 	log.Println("🚀 启动所有Trader...")
 	for id, t := range tm.traders {
+		tm.running.Add(1)
 		go func(traderID string, at *trader.AutoTrader) {
+			defer tm.running.Done()
 			log.Printf("▶️  启动 %s...", at.GetName())
 			if err := at.Run(); err != nil {
 				log.Printf("❌ %s 运行错误: %v", at.GetName(), err)
@@ -124,15 +358,16 @@ func (tm *TraderManager) StartAll() {
 	}
 }
 
-// StopAll 停止所有trader
+// StopAll 停止所有trader，并等待进行中的周期退出（中止的AI调用和行情获取会立即返回）
 func (tm *TraderManager) StopAll() {
 	tm.mu.RLock()
-	defer tm.mu.RUnlock()
-
 	log.Println("⏹  停止所有Trader...")
 	for _, t := range tm.traders {
 		t.Stop()
 	}
+	tm.mu.RUnlock()
+
+	tm.running.Wait()
 }
 
 // GetComparisonData 获取对比数据
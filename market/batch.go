@@ -0,0 +1,123 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+)
+
+// batchKey 全市场批量数据的缓存key（同一数据源的所有币种共用一次请求）
+const batchKey = "*"
+
+// getAllPremiumIndex 从当前数据源批量获取全市场标记价格、指数价格和资金费率（带缓存）
+func getAllPremiumIndex(ctx context.Context, source Source) (map[string]*PremiumIndex, error) {
+	return cached(ctx, CachePremiumIndex, source.Name()+"|"+batchKey, source.AllPremiumIndex)
+}
+
+// getAllTickers 从当前数据源批量获取全市场最新成交价（带缓存）
+func getAllTickers(ctx context.Context, source Source) (map[string]float64, error) {
+	return cached(ctx, CacheTicker, source.Name()+"|"+batchKey, source.AllTickers)
+}
+
+// getAllTickers24h 从当前数据源批量获取全市场24小时统计（带缓存）
+func getAllTickers24h(ctx context.Context, source Source) (map[string]*Ticker24h, error) {
+	return cached(ctx, CacheTicker24h, source.Name()+"|"+batchKey, source.AllTickers24h)
+}
+
+// fetchAllPremiumIndex 从Binance一次获取所有合约的标记价格、指数价格和资金费率（权重10，相当于10个单币种请求）
+func fetchAllPremiumIndex(ctx context.Context) (map[string]*PremiumIndex, error) {
+	body, err := fetchBody(ctx, "https://fapi.binance.com/fapi/v1/premiumIndex")
+	if err != nil {
+		return nil, err
+	}
+
+	var results []struct {
+		Symbol          string `json:"symbol"`
+		MarkPrice       string `json:"markPrice"`
+		IndexPrice      string `json:"indexPrice"`
+		LastFundingRate string `json:"lastFundingRate"`
+	}
+	if err := json.Unmarshal(body, &results); err != nil {
+		return nil, err
+	}
+
+	premiums := make(map[string]*PremiumIndex, len(results))
+	for _, result := range results {
+		premium := &PremiumIndex{}
+		premium.MarkPrice, _ = strconv.ParseFloat(result.MarkPrice, 64)
+		premium.IndexPrice, _ = strconv.ParseFloat(result.IndexPrice, 64)
+		premium.FundingRate, _ = strconv.ParseFloat(result.LastFundingRate, 64)
+		premiums[result.Symbol] = premium
+	}
+	return premiums, nil
+}
+
+// fetchAllTickers 从Binance一次获取所有合约的最新成交价（权重2）
+func fetchAllTickers(ctx context.Context) (map[string]float64, error) {
+	body, err := fetchBody(ctx, "https://fapi.binance.com/fapi/v1/ticker/price")
+	if err != nil {
+		return nil, err
+	}
+
+	var results []struct {
+		Symbol string `json:"symbol"`
+		Price  string `json:"price"`
+	}
+	if err := json.Unmarshal(body, &results); err != nil {
+		return nil, err
+	}
+
+	prices := make(map[string]float64, len(results))
+	for _, result := range results {
+		if price, err := strconv.ParseFloat(result.Price, 64); err == nil {
+			prices[result.Symbol] = price
+		}
+	}
+	return prices, nil
+}
+
+// fetchAllTickers24h 从Binance一次获取所有合约的24小时成交额和涨跌幅（权重40）
+func fetchAllTickers24h(ctx context.Context) (map[string]*Ticker24h, error) {
+	body, err := fetchBody(ctx, "https://fapi.binance.com/fapi/v1/ticker/24hr")
+	if err != nil {
+		return nil, err
+	}
+
+	var results []struct {
+		Symbol             string `json:"symbol"`
+		QuoteVolume        string `json:"quoteVolume"`
+		PriceChangePercent string `json:"priceChangePercent"`
+	}
+	if err := json.Unmarshal(body, &results); err != nil {
+		return nil, err
+	}
+
+	tickers := make(map[string]*Ticker24h, len(results))
+	for _, result := range results {
+		ticker := &Ticker24h{}
+		ticker.QuoteVolume, _ = strconv.ParseFloat(result.QuoteVolume, 64)
+		ticker.PriceChangePct, _ = strconv.ParseFloat(result.PriceChangePercent, 64)
+		tickers[result.Symbol] = ticker
+	}
+	return tickers, nil
+}
+
+// fetchBody 请求行情接口并读取响应
+func fetchBody(ctx context.Context, url string) ([]byte, error) {
+	resp, err := getContext(ctx, httpClient, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("请求失败 (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
@@ -0,0 +1,389 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"nofx/proxy"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SourceBybit Bybit USDT永续合约行情数据源
+const SourceBybit = "bybit"
+
+// Bybit v5 REST接口主机（行情和交易共用；测试网只用于交易，行情始终来自主网）
+const (
+	BybitHost        = "api.bybit.com"
+	BybitTestnetHost = "api-testnet.bybit.com"
+)
+
+// bybitClient Bybit行情请求使用的客户端（Bybit按接口独立限流，不占用币安的权重额度）
+var bybitClient = &http.Client{Timeout: 10 * time.Second}
+
+// bybitIntervals K线周期 -> Bybit的interval参数
+var bybitIntervals = map[Interval]string{
+	Interval1m:  "1",
+	Interval3m:  "3",
+	Interval5m:  "5",
+	Interval15m: "15",
+	Interval30m: "30",
+	Interval1h:  "60",
+	Interval2h:  "120",
+	Interval4h:  "240",
+	Interval6h:  "360",
+	Interval12h: "720",
+	Interval1d:  "D",
+}
+
+func init() {
+	proxy.Register(proxy.KindExchange, BybitHost)
+	proxy.Register(proxy.KindExchange, BybitTestnetHost)
+	RegisterSource(bybitSource{})
+}
+
+// bybitSource Bybit v5公开行情接口数据源（linear类别即USDT永续合约，币种格式与币安相同）
+type bybitSource struct{}
+
+func (bybitSource) Name() string { return SourceBybit }
+
+func (bybitSource) Klines(ctx context.Context, symbol string, interval Interval, limit int) ([]Kline, error) {
+	return fetchBybitKlines(ctx, "linear", symbol, interval, 0, limit)
+}
+
+func (bybitSource) KlinesSince(ctx context.Context, symbol string, interval Interval, startTime int64, limit int) ([]Kline, error) {
+	return fetchBybitKlines(ctx, "linear", symbol, interval, startTime, limit)
+}
+
+func (bybitSource) SpotKlines(ctx context.Context, symbol string, interval Interval, limit int) ([]Kline, error) {
+	return fetchBybitKlines(ctx, "spot", symbol, interval, 0, limit)
+}
+
+// OpenInterest 最近1小时（12个5分钟数据点）的持仓量，平均值为真实均值
+func (bybitSource) OpenInterest(ctx context.Context, symbol string) (*OIData, error) {
+	var result struct {
+		List []struct {
+			OpenInterest string `json:"openInterest"`
+		} `json:"list"`
+	}
+	query := url.Values{"category": {"linear"}, "symbol": {symbol}, "intervalTime": {"5min"}, "limit": {"12"}}
+	if err := fetchBybit(ctx, "/v5/market/open-interest", query, &result); err != nil {
+		return nil, err
+	}
+	if len(result.List) == 0 {
+		return nil, fmt.Errorf("%s 持仓量数据为空", symbol)
+	}
+
+	sum := 0.0
+	for _, item := range result.List {
+		oi, _ := strconv.ParseFloat(item.OpenInterest, 64)
+		sum += oi
+	}
+	latest, _ := strconv.ParseFloat(result.List[0].OpenInterest, 64) // 按时间降序
+	return &OIData{Latest: latest, Average: sum / float64(len(result.List))}, nil
+}
+
+func (bybitSource) PremiumIndex(ctx context.Context, symbol string) (*PremiumIndex, error) {
+	tickers, err := fetchBybitTickers(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	ticker, ok := tickers[symbol]
+	if !ok {
+		return nil, fmt.Errorf("未找到 %s 的行情", symbol)
+	}
+	return ticker.premiumIndex(), nil
+}
+
+func (bybitSource) Ticker(ctx context.Context, symbol string) (float64, error) {
+	tickers, err := fetchBybitTickers(ctx, symbol)
+	if err != nil {
+		return 0, err
+	}
+	ticker, ok := tickers[symbol]
+	if !ok {
+		return 0, fmt.Errorf("未找到 %s 的行情", symbol)
+	}
+	return strconv.ParseFloat(ticker.LastPrice, 64)
+}
+
+func (bybitSource) AllPremiumIndex(ctx context.Context) (map[string]*PremiumIndex, error) {
+	tickers, err := fetchBybitTickers(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	premiums := make(map[string]*PremiumIndex, len(tickers))
+	for symbol, ticker := range tickers {
+		premiums[symbol] = ticker.premiumIndex()
+	}
+	return premiums, nil
+}
+
+func (bybitSource) AllTickers(ctx context.Context) (map[string]float64, error) {
+	tickers, err := fetchBybitTickers(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	prices := make(map[string]float64, len(tickers))
+	for symbol, ticker := range tickers {
+		if price, err := strconv.ParseFloat(ticker.LastPrice, 64); err == nil {
+			prices[symbol] = price
+		}
+	}
+	return prices, nil
+}
+
+func (bybitSource) AllTickers24h(ctx context.Context) (map[string]*Ticker24h, error) {
+	tickers, err := fetchBybitTickers(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*Ticker24h, len(tickers))
+	for symbol, ticker := range tickers {
+		t := &Ticker24h{}
+		t.QuoteVolume, _ = strconv.ParseFloat(ticker.Turnover24h, 64)
+		change, _ := strconv.ParseFloat(ticker.Price24hPcnt, 64)
+		t.PriceChangePct = change * 100 // Bybit返回小数形式
+		result[symbol] = t
+	}
+	return result, nil
+}
+
+func (bybitSource) Depth(ctx context.Context, symbol string) (*DepthData, error) {
+	var result struct {
+		Bids [][]string `json:"b"`
+		Asks [][]string `json:"a"`
+	}
+	query := url.Values{"category": {"linear"}, "symbol": {symbol}, "limit": {"200"}}
+	if err := fetchBybit(ctx, "/v5/market/orderbook", query, &result); err != nil {
+		return nil, err
+	}
+	bids := parseDepthLevels(result.Bids)
+	asks := parseDepthLevels(result.Asks)
+	if len(bids) == 0 || len(asks) == 0 {
+		return nil, fmt.Errorf("%s 订单簿为空", symbol)
+	}
+	return calculateDepth(bids, asks), nil
+}
+
+// LongShortRatio Bybit只提供全市场账户多空比（没有大户持仓多空比，对应字段为0）
+func (bybitSource) LongShortRatio(ctx context.Context, symbol string) (*LongShortData, error) {
+	var result struct {
+		List []struct {
+			BuyRatio  string `json:"buyRatio"`
+			SellRatio string `json:"sellRatio"`
+		} `json:"list"`
+	}
+	query := url.Values{"category": {"linear"}, "symbol": {symbol}, "period": {"5min"}, "limit": {strconv.Itoa(longShortLimit)}}
+	if err := fetchBybit(ctx, "/v5/market/account-ratio", query, &result); err != nil {
+		return nil, fmt.Errorf("获取全市场多空比失败: %w", err)
+	}
+	if len(result.List) == 0 {
+		return nil, fmt.Errorf("%s 多空比数据为空", symbol)
+	}
+
+	// 按时间降序：第一个为最新值，最后一个为1小时前
+	points := make([]ratioPoint, 0, len(result.List))
+	for _, item := range result.List {
+		buy, _ := strconv.ParseFloat(item.BuyRatio, 64)
+		sell, _ := strconv.ParseFloat(item.SellRatio, 64)
+		point := ratioPoint{longPct: buy * 100}
+		if sell > 0 {
+			point.ratio = buy / sell
+		}
+		points = append(points, point)
+	}
+	data := &LongShortData{GlobalRatio: points[0].ratio, GlobalLongPct: points[0].longPct}
+	if len(points) == longShortLimit {
+		data.GlobalRatio1hAgo = points[len(points)-1].ratio
+	}
+	return data, nil
+}
+
+// ExchangeInfo 所有USDT永续合约的交易规则（状态和合约类型转换为币安的写法）
+func (bybitSource) ExchangeInfo(ctx context.Context) (map[string]*SymbolInfo, error) {
+	infos := make(map[string]*SymbolInfo)
+	cursor := ""
+	for {
+		var result struct {
+			List []struct {
+				Symbol       string `json:"symbol"`
+				ContractType string `json:"contractType"`
+				Status       string `json:"status"`
+				QuoteCoin    string `json:"quoteCoin"`
+				LaunchTime   string `json:"launchTime"`
+				PriceFilter  struct {
+					TickSize string `json:"tickSize"`
+				} `json:"priceFilter"`
+				LotSizeFilter struct {
+					QtyStep          string `json:"qtyStep"`
+					MinOrderQty      string `json:"minOrderQty"`
+					MinNotionalValue string `json:"minNotionalValue"`
+				} `json:"lotSizeFilter"`
+			} `json:"list"`
+			NextPageCursor string `json:"nextPageCursor"`
+		}
+		query := url.Values{"category": {"linear"}, "limit": {"1000"}}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		if err := fetchBybit(ctx, "/v5/market/instruments-info", query, &result); err != nil {
+			return nil, err
+		}
+
+		for _, s := range result.List {
+			if s.QuoteCoin != "USDT" {
+				continue
+			}
+			info := &SymbolInfo{Symbol: s.Symbol, Status: strings.ToUpper(s.Status)}
+			if s.ContractType == "LinearPerpetual" {
+				info.ContractType = "PERPETUAL"
+			} else {
+				info.ContractType = s.ContractType
+			}
+			info.TickSize, _ = strconv.ParseFloat(s.PriceFilter.TickSize, 64)
+			info.StepSize, _ = strconv.ParseFloat(s.LotSizeFilter.QtyStep, 64)
+			info.MinQty, _ = strconv.ParseFloat(s.LotSizeFilter.MinOrderQty, 64)
+			info.MinNotional, _ = strconv.ParseFloat(s.LotSizeFilter.MinNotionalValue, 64)
+			info.PricePrecision = stepPrecision(info.TickSize, 8)
+			info.QuantityPrecision = stepPrecision(info.StepSize, 3)
+			info.OnboardDate, _ = strconv.ParseInt(s.LaunchTime, 10, 64)
+			infos[s.Symbol] = info
+		}
+
+		if result.NextPageCursor == "" || len(result.List) == 0 {
+			break
+		}
+		cursor = result.NextPageCursor
+	}
+	return infos, nil
+}
+
+// bybitTicker Bybit行情快照（tickers接口同时返回最新价、标记价格、资金费率和24小时统计）
+type bybitTicker struct {
+	Symbol       string `json:"symbol"`
+	LastPrice    string `json:"lastPrice"`
+	IndexPrice   string `json:"indexPrice"`
+	MarkPrice    string `json:"markPrice"`
+	FundingRate  string `json:"fundingRate"`
+	Turnover24h  string `json:"turnover24h"`
+	Price24hPcnt string `json:"price24hPcnt"`
+}
+
+func (t *bybitTicker) premiumIndex() *PremiumIndex {
+	premium := &PremiumIndex{}
+	premium.MarkPrice, _ = strconv.ParseFloat(t.MarkPrice, 64)
+	premium.IndexPrice, _ = strconv.ParseFloat(t.IndexPrice, 64)
+	premium.FundingRate, _ = strconv.ParseFloat(t.FundingRate, 64)
+	return premium
+}
+
+// fetchBybitTickers 获取行情快照（symbol为空时获取所有USDT永续合约）
+func fetchBybitTickers(ctx context.Context, symbol string) (map[string]*bybitTicker, error) {
+	var result struct {
+		List []*bybitTicker `json:"list"`
+	}
+	query := url.Values{"category": {"linear"}}
+	if symbol != "" {
+		query.Set("symbol", symbol)
+	}
+	if err := fetchBybit(ctx, "/v5/market/tickers", query, &result); err != nil {
+		return nil, err
+	}
+	tickers := make(map[string]*bybitTicker, len(result.List))
+	for _, ticker := range result.List {
+		tickers[ticker.Symbol] = ticker
+	}
+	return tickers, nil
+}
+
+// fetchBybitKlines 获取K线（Bybit按时间降序返回，转换为升序；startTime>0时获取该时间之后的K线）。
+// Bybit的K线没有主动买入量，TakerBuyVolume为0
+func fetchBybitKlines(ctx context.Context, category, symbol string, interval Interval, startTime int64, limit int) ([]Kline, error) {
+	bybitInterval, ok := bybitIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("Bybit不支持K线周期 %s", interval)
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+	query := url.Values{
+		"category": {category},
+		"symbol":   {symbol},
+		"interval": {bybitInterval},
+		"limit":    {strconv.Itoa(limit)},
+	}
+	duration := interval.Duration().Milliseconds()
+	if startTime > 0 {
+		// 只传start时返回的是最近limit根，需同时给出结束时间
+		query.Set("start", strconv.FormatInt(startTime, 10))
+		query.Set("end", strconv.FormatInt(startTime+int64(limit)*duration-1, 10))
+	}
+
+	var result struct {
+		List [][]string `json:"list"`
+	}
+	if err := fetchBybit(ctx, "/v5/market/kline", query, &result); err != nil {
+		return nil, err
+	}
+
+	klines := make([]Kline, 0, len(result.List))
+	for i := len(result.List) - 1; i >= 0; i-- {
+		item := result.List[i]
+		if len(item) < 6 {
+			continue
+		}
+		k := Kline{}
+		k.OpenTime, _ = strconv.ParseInt(item[0], 10, 64)
+		k.Open, _ = strconv.ParseFloat(item[1], 64)
+		k.High, _ = strconv.ParseFloat(item[2], 64)
+		k.Low, _ = strconv.ParseFloat(item[3], 64)
+		k.Close, _ = strconv.ParseFloat(item[4], 64)
+		k.Volume, _ = strconv.ParseFloat(item[5], 64)
+		k.CloseTime = k.OpenTime + duration - 1
+		klines = append(klines, k)
+	}
+	return klines, nil
+}
+
+// fetchBybit 请求Bybit公开行情接口并解析result字段（retCode非0时返回错误）
+func fetchBybit(ctx context.Context, path string, query url.Values, result interface{}) error {
+	resp, err := getContext(ctx, bybitClient, "https://"+BybitHost+path+"?"+query.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return DecodeBybitResponse(resp, result)
+}
+
+// DecodeBybitResponse 解析Bybit v5响应：{"retCode":0,"retMsg":"OK","result":{...}}（交易客户端共用）
+func DecodeBybitResponse(resp *http.Response, result interface{}) error {
+	var envelope struct {
+		RetCode int             `json:"retCode"`
+		RetMsg  string          `json:"retMsg"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("解析Bybit响应失败 (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if envelope.RetCode != 0 {
+		return &BybitError{Code: envelope.RetCode, Message: envelope.RetMsg}
+	}
+	if result == nil || len(envelope.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(envelope.Result, result)
+}
+
+// BybitError Bybit接口返回的业务错误
+type BybitError struct {
+	Code    int
+	Message string
+}
+
+func (e *BybitError) Error() string {
+	return fmt.Sprintf("Bybit错误 %d: %s", e.Code, e.Message)
+}
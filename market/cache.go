@@ -0,0 +1,178 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// REST数据缓存类型及有效期（同一周期内多个trader、多个模块重复请求同一数据时直接复用）
+const (
+	CacheKlines       = "klines"
+	CacheSpotKlines   = "spot_klines"
+	CacheOpenInterest = "open_interest"
+	CachePremiumIndex = "premium_index"
+	CacheTicker       = "ticker"
+	CacheTicker24h    = "ticker_24h"
+	CacheDepth        = "depth"
+	CacheLongShort    = "long_short_ratio"
+	CacheMacro        = "macro"
+	CacheFearGreed    = "fear_greed"
+	CacheExchangeInfo = "exchange_info"
+	CacheStablecoin   = "stablecoin"
+)
+
+var cacheTTLs = map[string]time.Duration{
+	CacheKlines:       10 * time.Second,
+	CacheSpotKlines:   10 * time.Second,
+	CacheOpenInterest: time.Minute,
+	CachePremiumIndex: 10 * time.Second, // 标记价格/指数价格/资金费率
+	CacheTicker:       2 * time.Second,
+	CacheTicker24h:    time.Minute, // 全市场24小时统计（权重40）
+	CacheDepth:        5 * time.Second,
+	CacheLongShort:    time.Minute, // 交易所每5分钟更新一次
+	CacheMacro:        5 * time.Minute,
+	CacheFearGreed:    30 * time.Minute, // 每日更新一次
+	CacheExchangeInfo: time.Hour,        // 交易规则很少变化
+	CacheStablecoin:   time.Minute,
+}
+
+// cacheSweepInterval 清理过期缓存的间隔
+const cacheSweepInterval = time.Minute
+
+// CacheStat 单类缓存的命中统计
+type CacheStat struct {
+	Kind       string  `json:"kind"`
+	TTLSeconds float64 `json:"ttl_seconds"`
+	Hits       int64   `json:"hits"`
+	Misses     int64   `json:"misses"`
+	HitRate    float64 `json:"hit_rate"` // 命中率（百分比）
+	Entries    int     `json:"entries"`  // 当前缓存条目数
+}
+
+// cacheEntry 缓存条目
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// inflightFetch 正在进行的请求（并发请求同一key时只发一次）
+type inflightFetch struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// dataCache 进程级REST数据缓存，key为 类型|币种|周期...
+type dataCache struct {
+	mu        sync.Mutex
+	entries   map[string]map[string]cacheEntry // kind -> key -> entry
+	inflight  map[string]*inflightFetch
+	hits      map[string]int64
+	misses    map[string]int64
+	lastSweep time.Time
+}
+
+var marketCache = &dataCache{
+	entries:   make(map[string]map[string]cacheEntry),
+	inflight:  make(map[string]*inflightFetch),
+	hits:      make(map[string]int64),
+	misses:    make(map[string]int64),
+	lastSweep: time.Now(),
+}
+
+// cached 读取缓存，未命中或已过期时调用fetch并缓存结果（错误不缓存）。ctx取消时立即返回，请求随之中止
+func cached[T any](ctx context.Context, kind, key string, fetch func(context.Context) (T, error)) (T, error) {
+	value, err := marketCache.get(ctx, kind, key, func(ctx context.Context) (interface{}, error) { return fetch(ctx) })
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return value.(T), nil
+}
+
+// get 缓存读取（见cached）
+func (c *dataCache) get(ctx context.Context, kind, key string, fetch func(context.Context) (interface{}, error)) (interface{}, error) {
+	now := time.Now()
+	flightKey := kind + "|" + key
+
+	c.mu.Lock()
+	if entry, ok := c.entries[kind][key]; ok && now.Before(entry.expires) {
+		c.hits[kind]++
+		c.mu.Unlock()
+		return entry.value, nil
+	}
+	if flight, ok := c.inflight[flightKey]; ok {
+		c.hits[kind]++
+		c.mu.Unlock()
+		select {
+		case <-flight.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if flight.err != nil && ctx.Err() == nil && (errors.Is(flight.err, context.Canceled) || errors.Is(flight.err, context.DeadlineExceeded)) {
+			// 发起请求的调用方已取消（如其他trader停止），本调用方仍需要数据，重新请求
+			return c.get(ctx, kind, key, fetch)
+		}
+		return flight.value, flight.err
+	}
+	c.misses[kind]++
+	flight := &inflightFetch{done: make(chan struct{})}
+	c.inflight[flightKey] = flight
+	c.mu.Unlock()
+
+	flight.value, flight.err = fetch(ctx)
+
+	c.mu.Lock()
+	delete(c.inflight, flightKey)
+	if flight.err == nil {
+		if c.entries[kind] == nil {
+			c.entries[kind] = make(map[string]cacheEntry)
+		}
+		c.entries[kind][key] = cacheEntry{value: flight.value, expires: time.Now().Add(cacheTTLs[kind])}
+	}
+	if now.Sub(c.lastSweep) > cacheSweepInterval {
+		c.sweep(now)
+	}
+	c.mu.Unlock()
+	close(flight.done)
+
+	return flight.value, flight.err
+}
+
+// sweep 删除过期条目（调用方需持有mu）
+func (c *dataCache) sweep(now time.Time) {
+	for _, entries := range c.entries {
+		for key, entry := range entries {
+			if now.After(entry.expires) {
+				delete(entries, key)
+			}
+		}
+	}
+	c.lastSweep = now
+}
+
+// CacheStats 各类缓存的命中统计（按类型排序）
+func CacheStats() []CacheStat {
+	marketCache.mu.Lock()
+	defer marketCache.mu.Unlock()
+
+	stats := make([]CacheStat, 0, len(cacheTTLs))
+	for kind, ttl := range cacheTTLs {
+		stat := CacheStat{
+			Kind:       kind,
+			TTLSeconds: ttl.Seconds(),
+			Hits:       marketCache.hits[kind],
+			Misses:     marketCache.misses[kind],
+			Entries:    len(marketCache.entries[kind]),
+		}
+		if total := stat.Hits + stat.Misses; total > 0 {
+			stat.HitRate = float64(stat.Hits) / float64(total) * 100
+		}
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Kind < stats[j].Kind })
+	return stats
+}
@@ -0,0 +1,219 @@
+package market
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+)
+
+// K线图尺寸和布局（上：K线+EMA，中：成交量，下：RSI14）
+const (
+	chartWidth        = 800
+	chartHeight       = 480
+	chartPadding      = 8
+	chartPriceBottom  = 300
+	chartVolumeTop    = 308
+	chartVolumeBottom = 380
+	chartRSITop       = 388
+	chartWarmup       = 50 // 额外获取的K线根数（用于计算EMA50和RSI，不画出）
+)
+
+// 图表配色（深色背景，与常见交易终端一致，便于模型识别）
+var (
+	chartBackground = color.RGBA{19, 23, 34, 255}
+	chartGrid       = color.RGBA{42, 46, 57, 255}
+	chartUp         = color.RGBA{38, 166, 154, 255}
+	chartDown       = color.RGBA{239, 83, 80, 255}
+	chartEMA20      = color.RGBA{255, 152, 0, 255}
+	chartEMA50      = color.RGBA{41, 98, 255, 255}
+	chartRSI        = color.RGBA{186, 104, 200, 255}
+	chartRSILevels  = color.RGBA{120, 123, 134, 255}
+)
+
+// ChartImage 一张K线图（PNG）及其说明
+type ChartImage struct {
+	Symbol   string
+	Interval Interval
+	Bars     int     // 图中K线根数
+	High     float64 // 价格轴上沿
+	Low      float64 // 价格轴下沿
+	Last     float64 // 最新价格
+	PNG      []byte
+}
+
+// Caption 图片说明（随prompt发送，图中没有文字标注）
+func (c *ChartImage) Caption() string {
+	return fmt.Sprintf("%s %s, last %d candles, price axis %.6g-%.6g, last %.6g", c.Symbol, c.Interval, c.Bars, c.Low, c.High, c.Last)
+}
+
+// RenderChart 获取K线并绘制K线图：K线（绿涨红跌）、EMA20（橙）、EMA50（蓝），成交量柱，RSI14（紫，灰线为30/70）
+func RenderChart(symbol string, interval Interval, bars int) (*ChartImage, error) {
+	if bars <= 0 {
+		return nil, fmt.Errorf("K线根数必须大于0")
+	}
+	klines, err := GetKlines(symbol, interval, bars+chartWarmup)
+	if err != nil {
+		return nil, err
+	}
+	if len(klines) < 2 {
+		return nil, fmt.Errorf("%s %s K线数据不足", symbol, interval)
+	}
+	chart := &ChartImage{Symbol: Canonical(symbol), Interval: interval}
+	img := chart.draw(klines, bars)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("编码K线图失败: %w", err)
+	}
+	chart.PNG = buf.Bytes()
+	return chart, nil
+}
+
+// draw 绘制最后bars根K线（前面的K线只用于计算指标）
+func (c *ChartImage) draw(klines []Kline, bars int) *image.RGBA {
+	start := len(klines) - bars
+	if start < 0 {
+		start = 0
+	}
+	shown := klines[start:]
+	ema20 := make([]float64, len(shown))
+	ema50 := make([]float64, len(shown))
+	rsi14 := make([]float64, len(shown))
+	for i := range shown {
+		prefix := klines[:start+i+1]
+		ema20[i] = calculateEMA(prefix, 20)
+		ema50[i] = calculateEMA(prefix, 50)
+		rsi14[i] = calculateRSI(prefix, 14)
+	}
+
+	// 价格轴包含所有K线和指标值
+	high, low, maxVolume := math.Inf(-1), math.Inf(1), 0.0
+	for i, k := range shown {
+		high = math.Max(high, k.High)
+		low = math.Min(low, k.Low)
+		for _, v := range []float64{ema20[i], ema50[i]} {
+			if v > 0 {
+				high = math.Max(high, v)
+				low = math.Min(low, v)
+			}
+		}
+		maxVolume = math.Max(maxVolume, k.Volume)
+	}
+	if high <= low {
+		high, low = high*1.001, low*0.999
+	}
+	c.Bars, c.High, c.Low, c.Last = len(shown), high, low, shown[len(shown)-1].Close
+
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, chartHeight))
+	fillRect(img, 0, 0, chartWidth, chartHeight, chartBackground)
+
+	left, right := chartPadding, chartWidth-chartPadding
+	slot := float64(right-left) / float64(len(shown))
+	x := func(i int) int { return left + int((float64(i)+0.5)*slot) }
+	priceY := func(p float64) int {
+		return chartPadding + int((high-p)/(high-low)*float64(chartPriceBottom-chartPadding))
+	}
+	rsiY := func(v float64) int {
+		return chartRSITop + int((100-v)/100*float64(chartHeight-chartPadding-chartRSITop))
+	}
+
+	// 网格：价格轴四等分，面板分隔线，RSI 30/70
+	for i := 0; i <= 4; i++ {
+		hline(img, left, right, chartPadding+i*(chartPriceBottom-chartPadding)/4, chartGrid, 1)
+	}
+	hline(img, left, right, chartVolumeBottom, chartGrid, 1)
+	hline(img, left, right, rsiY(70), chartRSILevels, 4)
+	hline(img, left, right, rsiY(30), chartRSILevels, 4)
+
+	bodyWidth := int(slot * 0.7)
+	if bodyWidth < 1 {
+		bodyWidth = 1
+	}
+	for i, k := range shown {
+		col := chartUp
+		if k.Close < k.Open {
+			col = chartDown
+		}
+		cx := x(i)
+		// 影线、实体
+		fillRect(img, cx, priceY(k.High), cx+1, priceY(k.Low)+1, col)
+		top, bottom := priceY(math.Max(k.Open, k.Close)), priceY(math.Min(k.Open, k.Close))
+		fillRect(img, cx-bodyWidth/2, top, cx-bodyWidth/2+bodyWidth, bottom+1, col)
+		// 成交量
+		if maxVolume > 0 {
+			h := int(k.Volume / maxVolume * float64(chartVolumeBottom-chartVolumeTop))
+			fillRect(img, cx-bodyWidth/2, chartVolumeBottom-h, cx-bodyWidth/2+bodyWidth, chartVolumeBottom, col)
+		}
+	}
+
+	drawSeries(img, ema20, x, priceY, chartEMA20)
+	drawSeries(img, ema50, x, priceY, chartEMA50)
+	drawSeries(img, rsi14, x, rsiY, chartRSI)
+	return img
+}
+
+// drawSeries 画指标折线（值为0表示数据不足，跳过）
+func drawSeries(img *image.RGBA, values []float64, x func(int) int, y func(float64) int, col color.RGBA) {
+	for i := 1; i < len(values); i++ {
+		if values[i-1] <= 0 || values[i] <= 0 {
+			continue
+		}
+		line(img, x(i-1), y(values[i-1]), x(i), y(values[i]), col)
+	}
+}
+
+// fillRect 填充矩形[x0,x1)×[y0,y1)
+func fillRect(img *image.RGBA, x0, y0, x1, y1 int, col color.RGBA) {
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			img.SetRGBA(x, y, col)
+		}
+	}
+}
+
+// hline 水平线（dash>1时为虚线）
+func hline(img *image.RGBA, x0, x1, y int, col color.RGBA, dash int) {
+	for x := x0; x < x1; x++ {
+		if dash <= 1 || (x/dash)%2 == 0 {
+			img.SetRGBA(x, y, col)
+		}
+	}
+}
+
+// line Bresenham直线
+func line(img *image.RGBA, x0, y0, x1, y1 int, col color.RGBA) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		img.SetRGBA(x0, y0, col)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -1,6 +1,7 @@
 package market
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -8,21 +9,65 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Data 市场数据结构
 type Data struct {
-	Symbol            string
-	CurrentPrice      float64
-	PriceChange1h     float64 // 1小时价格变化百分比
-	PriceChange4h     float64 // 4小时价格变化百分比
-	CurrentEMA20      float64
-	CurrentMACD       float64
-	CurrentRSI7       float64
-	OpenInterest      *OIData
-	FundingRate       float64
-	IntradaySeries    *IntradayData
-	LongerTermContext *LongerTermData
+	Symbol             string
+	CurrentPrice       float64
+	PriceChange1h      float64 // 1小时价格变化百分比
+	PriceChange4h      float64 // 4小时价格变化百分比
+	CurrentEMA20       float64
+	CurrentMACD        float64
+	CurrentRSI7        float64
+	OpenInterest       *OIData
+	FundingRate        float64
+	MarkPrice          float64  // 标记价格（用于计算强平和未实现盈亏）
+	IndexPrice         float64  // 指数价格（多家现货交易所的加权价格）
+	BasisPct           float64  // 基差：(标记价格-指数价格)/指数价格 的百分比
+	IntradayInterval   Interval // 日内序列的K线周期（默认3m）
+	IntradaySeries     *IntradayData
+	LongerTermInterval Interval // 长期背景的K线周期（默认4h）
+	LongerTermContext  *LongerTermData
+	ExtraTimeframes    []TimeframeData    // 额外配置的时间周期（如15m、1h、1d）
+	Depth              *DepthData         // 订单簿深度（获取失败时为nil）
+	Liquidations       *LiquidationData   // 近期强平统计（未订阅强平流时为nil）
+	LongShort          *LongShortData     // 多空比（获取失败时为nil）
+	SpotBasis          *SpotBasisData     // 合约相对现货的溢价（没有对应现货交易对时为nil）
+	Levels             *LevelsData        // 长期周期的支撑/阻力位（K线不足时为nil）
+	VolumeProfile      *VolumeProfileData // 日内成交量分布（K线不足时为nil）
+	Regime             *RegimeData        // 市场状态（趋势/震荡/高波动，K线不足时为nil）
+	Returns            []float64          // 日内周期每根K线的收益率（百分比，用于计算币种间相关性，不输出到提示词）
+	Volatility         *VolatilityData    // 实现波动率和ATR百分比
+	DataIssues         []string           // 数据质量问题（K线缺口、数据过期、价格为0）
+	Unreliable         bool               // 数据过期或价格无效（候选币种应排除，持仓币种需标注）
+}
+
+// TimeframeData 额外时间周期的序列数据（指标与3分钟序列相同）
+type TimeframeData struct {
+	Interval string
+	Series   *IntradayData
+}
+
+// SupportedIntervals 可配置的额外K线周期（3m和4h始终获取）
+var SupportedIntervals = map[string]string{
+	"1m":  "1‑minute",
+	"5m":  "5‑minute",
+	"15m": "15‑minute",
+	"30m": "30‑minute",
+	"1h":  "1‑hour",
+	"2h":  "2‑hour",
+	"6h":  "6‑hour",
+	"12h": "12‑hour",
+	"1d":  "1‑day",
+}
+
+// PremiumIndex 标记价格、指数价格和资金费率
+type PremiumIndex struct {
+	MarkPrice   float64
+	IndexPrice  float64
+	FundingRate float64
 }
 
 // OIData Open Interest数据
@@ -33,115 +78,313 @@ type OIData struct {
 
 // IntradayData 日内数据(3分钟间隔)
 type IntradayData struct {
-	MidPrices   []float64
-	EMA20Values []float64
-	MACDValues  []float64
-	RSI7Values  []float64
-	RSI14Values []float64
+	MidPrices       []float64
+	EMA20Values     []float64
+	MACDValues      []float64
+	RSI7Values      []float64
+	RSI14Values     []float64
+	BBWidthValues   []float64 // 布林带宽度（20期，2倍标准差，百分比）
+	ATR14Values     []float64
+	VWAPValues      []float64 // 当日VWAP（UTC 0点起）
+	ADX14Values     []float64
+	TakerBuyValues  []float64        // 主动买入量（数据源不提供时为空）
+	TakerSellValues []float64        // 主动卖出量
+	CVDValues       []float64        // 累计成交量差（主动买入-主动卖出，从获取的第一根K线开始累计）
+	Optional        *IndicatorSeries // 可选指标（未配置时为nil）
 }
 
 // LongerTermData 长期数据(4小时时间框架)
 type LongerTermData struct {
-	EMA20         float64
-	EMA50         float64
-	ATR3          float64
-	ATR14         float64
-	CurrentVolume float64
-	AverageVolume float64
-	MACDValues    []float64
-	RSI14Values   []float64
+	EMA20           float64
+	EMA50           float64
+	ATR3            float64
+	ATR14           float64
+	CurrentVolume   float64
+	AverageVolume   float64
+	MACDValues      []float64
+	RSI14Values     []float64
+	BBWidthValues   []float64 // 布林带宽度（20期，2倍标准差，百分比）
+	ATR14Values     []float64
+	VWAPValues      []float64 // 当日VWAP（UTC 0点起）
+	ADX14Values     []float64
+	TakerBuyValues  []float64        // 主动买入量（数据源不提供时为空）
+	TakerSellValues []float64        // 主动卖出量
+	CVDValues       []float64        // 累计成交量差（主动买入-主动卖出，从获取的第一根K线开始累计）
+	Optional        *IndicatorSeries // 可选指标（未配置时为nil）
 }
 
 // Kline K线数据
 type Kline struct {
-	OpenTime  int64
-	Open      float64
-	High      float64
-	Low       float64
-	Close     float64
-	Volume    float64
-	CloseTime int64
+	OpenTime       int64
+	Open           float64
+	High           float64
+	Low            float64
+	Close          float64
+	Volume         float64
+	TakerBuyVolume float64 // 主动买入成交量
+	CloseTime      int64
 }
 
-// Get 获取指定代币的市场数据
+// 每个周期保留的K线数量（多获取一些用于计算指标）
+const (
+	klineLimitIntraday = 40
+	klineLimitLonger   = 60
+	klineLimitExtra    = 40
+)
+
+// Get 获取指定代币的市场数据（3分钟和4小时周期）
 func Get(symbol string) (*Data, error) {
-	// 标准化symbol
-	symbol = Normalize(symbol)
+	return GetWithTimeframes(symbol, nil)
+}
 
-	// 获取3分钟K线数据 (最近10个)
-	klines3m, err := getKlines(symbol, "3m", 40) // 多获取一些用于计算
-	if err != nil {
-		return nil, fmt.Errorf("获取3分钟K线失败: %v", err)
+// GetContext 同Get，ctx取消时中止进行中的请求
+func GetContext(ctx context.Context, symbol string) (*Data, error) {
+	return GetWithIndicatorsContext(ctx, symbol, nil, nil)
+}
+
+// GetWithTimeframes 获取市场数据，并额外获取指定周期的序列（单个周期失败不影响整体）
+func GetWithTimeframes(symbol string, intervals []string) (*Data, error) {
+	return GetWithIndicators(symbol, intervals, nil)
+}
+
+// GetWithIndicators 获取市场数据、额外周期序列和启用的可选指标（见OptionalIndicators）
+func GetWithIndicators(symbol string, intervals, indicators []string) (*Data, error) {
+	return GetWithIndicatorsContext(context.Background(), symbol, intervals, indicators)
+}
+
+// GetWithIndicatorsContext 同GetWithIndicators，ctx取消时中止进行中的请求
+func GetWithIndicatorsContext(ctx context.Context, symbol string, intervals, indicators []string) (*Data, error) {
+	return getData(ctx, symbol, Interval3m, Interval4h, intervals, indicators)
+}
+
+// getData 获取市场数据：intraday周期用于当前指标和日内序列，longer周期用于长期背景，extras为额外序列
+// 启用WebSocket行情流时直接读取内存数据，行情流不可用时回退到REST
+func getData(ctx context.Context, symbol string, intraday, longer Interval, extras, indicators []string) (*Data, error) {
+	// 映射为交易所实际上线的合约（如PEPE -> 1000PEPEUSDT）
+	symbol = canonical(ctx, symbol)
+	extras = filterExtraIntervals(extras, intraday, longer)
+
+	if stream := activeStream(); stream != nil {
+		if data, ok := stream.get(symbol, intraday, longer, extras, indicators); ok {
+			attachOrderFlow(ctx, data)
+			return data, nil
+		}
 	}
 
-	// 获取4小时K线数据 (最近10个)
-	klines4h, err := getKlines(symbol, "4h", 60) // 多获取用于计算指标
+	set := klineSet{intraday: intraday, longer: longer, extras: extras, extraKlines: make(map[string][]Kline)}
+	var err error
+
+	// 获取日内周期K线数据 (最近10个)
+	set.intradayKlines, err = getKlines(ctx, symbol, string(intraday), klineLimitIntraday) // 多获取一些用于计算
 	if err != nil {
-		return nil, fmt.Errorf("获取4小时K线失败: %v", err)
-	}
-
-	// 计算当前指标 (基于3分钟最新数据)
-	currentPrice := klines3m[len(klines3m)-1].Close
-	currentEMA20 := calculateEMA(klines3m, 20)
-	currentMACD := calculateMACD(klines3m)
-	currentRSI7 := calculateRSI(klines3m, 7)
-
-	// 计算价格变化百分比
-	// 1小时价格变化 = 20个3分钟K线前的价格
-	priceChange1h := 0.0
-	if len(klines3m) >= 21 { // 至少需要21根K线 (当前 + 20根前)
-		price1hAgo := klines3m[len(klines3m)-21].Close
-		if price1hAgo > 0 {
-			priceChange1h = ((currentPrice - price1hAgo) / price1hAgo) * 100
-		}
+		return nil, fmt.Errorf("获取%sK线失败: %v", intraday, err)
+	}
+	if len(set.intradayKlines) == 0 {
+		return nil, fmt.Errorf("%s %sK线为空", symbol, intraday)
 	}
 
-	// 4小时价格变化 = 1个4小时K线前的价格
-	priceChange4h := 0.0
-	if len(klines4h) >= 2 {
-		price4hAgo := klines4h[len(klines4h)-2].Close
-		if price4hAgo > 0 {
-			priceChange4h = ((currentPrice - price4hAgo) / price4hAgo) * 100
-		}
+	// 获取长期周期K线数据 (最近10个)
+	set.longerKlines, err = getKlines(ctx, symbol, string(longer), klineLimitLonger) // 多获取用于计算指标
+	if err != nil {
+		return nil, fmt.Errorf("获取%sK线失败: %v", longer, err)
 	}
 
 	// 获取OI数据
-	oiData, err := getOpenInterestData(symbol)
+	oiData, err := getOpenInterestData(ctx, symbol)
 	if err != nil {
 		// OI失败不影响整体,使用默认值
 		oiData = &OIData{Latest: 0, Average: 0}
 	}
 
 	// 获取Funding Rate
-	fundingRate, _ := getFundingRate(symbol)
+	premium, _ := getPremiumIndex(ctx, symbol)
+
+	// 额外时间周期
+	for _, interval := range extras {
+		klines, err := getKlines(ctx, symbol, interval, klineLimitExtra)
+		if err != nil {
+			continue
+		}
+		set.extraKlines[interval] = klines
+	}
+
+	data := buildData(symbol, set, indicators, oiData, premium)
+	attachOrderFlow(ctx, data)
+	return data, nil
+}
+
+// attachOrderFlow 附加订单簿深度、近期强平统计、多空比和现货溢价（获取失败不影响整体）
+func attachOrderFlow(ctx context.Context, data *Data) {
+	data.Depth, _ = getDepth(ctx, data.Symbol)
+	data.Liquidations = recentLiquidations(data.Symbol)
+	data.LongShort, _ = getLongShortRatio(ctx, data.Symbol)
+	attachSpotBasis(ctx, data)
+}
+
+// klineSet 计算一个币种市场数据所需的各周期K线
+type klineSet struct {
+	intraday       Interval
+	longer         Interval
+	intradayKlines []Kline
+	longerKlines   []Kline
+	extras         []string // 额外周期（按请求顺序）
+	extraKlines    map[string][]Kline
+}
+
+// buildData 根据K线、OI和标记价格/资金费率计算指标（REST和WebSocket行情流共用，premium获取失败时为nil）
+func buildData(symbol string, set klineSet, indicators []string, oiData *OIData, premium *PremiumIndex) *Data {
+	// 计算当前指标 (基于日内周期最新数据)
+	currentPrice := set.intradayKlines[len(set.intradayKlines)-1].Close
+	currentEMA20 := calculateEMA(set.intradayKlines, 20)
+	currentMACD := calculateMACD(set.intradayKlines)
+	currentRSI7 := calculateRSI(set.intradayKlines, 7)
+
+	// 计算价格变化百分比（默认周期下：1小时 = 20根3分钟K线前，4小时 = 1根4小时K线前）
+	priceChange1h := priceChangeOver(currentPrice, time.Hour, set.intraday, set.intradayKlines, set.longer, set.longerKlines)
+	priceChange4h := priceChangeOver(currentPrice, 4*time.Hour, set.longer, set.longerKlines, set.intraday, set.intradayKlines)
 
 	// 计算日内系列数据
-	intradayData := calculateIntradaySeries(klines3m)
+	intradayData := calculateIntradaySeries(set.intradayKlines)
+	intradayData.Optional = calculateOptionalSeries(set.intradayKlines, indicators)
 
 	// 计算长期数据
-	longerTermData := calculateLongerTermData(klines4h)
+	longerTermData := calculateLongerTermData(set.longerKlines)
+	longerTermData.Optional = calculateOptionalSeries(set.longerKlines, indicators)
+
+	// 额外时间周期（按配置顺序）
+	var extraTimeframes []TimeframeData
+	for _, interval := range set.extras {
+		klines := set.extraKlines[interval]
+		if len(klines) == 0 {
+			continue
+		}
+		series := calculateIntradaySeries(klines)
+		series.Optional = calculateOptionalSeries(klines, indicators)
+		extraTimeframes = append(extraTimeframes, TimeframeData{
+			Interval: interval,
+			Series:   series,
+		})
+	}
+
+	issues, unreliable := checkDataQuality(set, currentPrice)
+
+	if premium == nil {
+		premium = &PremiumIndex{}
+	}
+	basisPct := 0.0
+	if premium.IndexPrice > 0 && premium.MarkPrice > 0 {
+		basisPct = (premium.MarkPrice - premium.IndexPrice) / premium.IndexPrice * 100
+	}
 
 	return &Data{
-		Symbol:            symbol,
-		CurrentPrice:      currentPrice,
-		PriceChange1h:     priceChange1h,
-		PriceChange4h:     priceChange4h,
-		CurrentEMA20:      currentEMA20,
-		CurrentMACD:       currentMACD,
-		CurrentRSI7:       currentRSI7,
-		OpenInterest:      oiData,
-		FundingRate:       fundingRate,
-		IntradaySeries:    intradayData,
-		LongerTermContext: longerTermData,
-	}, nil
+		Symbol:             symbol,
+		CurrentPrice:       currentPrice,
+		PriceChange1h:      priceChange1h,
+		PriceChange4h:      priceChange4h,
+		CurrentEMA20:       currentEMA20,
+		CurrentMACD:        currentMACD,
+		CurrentRSI7:        currentRSI7,
+		OpenInterest:       oiData,
+		FundingRate:        premium.FundingRate,
+		MarkPrice:          premium.MarkPrice,
+		IndexPrice:         premium.IndexPrice,
+		BasisPct:           basisPct,
+		IntradayInterval:   set.intraday,
+		IntradaySeries:     intradayData,
+		LongerTermInterval: set.longer,
+		LongerTermContext:  longerTermData,
+		ExtraTimeframes:    extraTimeframes,
+		Levels:             calculateLevels(set.longerKlines, set.longer, currentPrice),
+		VolumeProfile:      calculateVolumeProfile(set.intradayKlines, set.intraday),
+		Regime:             classifyRegime(set.longerKlines, set.longer),
+		Returns:            calculateReturns(set.intradayKlines),
+		Volatility:         calculateVolatility(set, currentPrice, longerTermData.ATR14),
+		DataIssues:         issues,
+		Unreliable:         unreliable,
+	}
+}
+
+// calculateReturns 相邻K线收盘价的收益率序列（百分比）
+func calculateReturns(klines []Kline) []float64 {
+	returns := make([]float64, 0, len(klines))
+	for i := 1; i < len(klines); i++ {
+		if prev := klines[i-1].Close; prev > 0 {
+			returns = append(returns, (klines[i].Close-prev)/prev*100)
+		} else {
+			returns = append(returns, 0)
+		}
+	}
+	return returns
+}
+
+// GetKlines 获取指定币种和周期的最近limit根K线（按时间升序，最后一根为未收盘K线）
+func GetKlines(symbol string, interval Interval, limit int) ([]Kline, error) {
+	return GetKlinesContext(context.Background(), symbol, interval, limit)
+}
+
+// GetKlinesContext 同GetKlines，ctx取消时中止进行中的请求
+func GetKlinesContext(ctx context.Context, symbol string, interval Interval, limit int) ([]Kline, error) {
+	if !interval.Valid() {
+		return nil, fmt.Errorf("不支持的K线周期 %q", interval)
+	}
+	return getKlines(ctx, canonical(ctx, symbol), string(interval), limit)
+}
+
+// GetATR 指定币种和周期的ATR（Wilder平滑，使用带缓存的K线）
+func GetATR(symbol string, interval Interval, period int) (float64, error) {
+	klines, err := GetKlines(symbol, interval, period*5)
+	if err != nil {
+		return 0, err
+	}
+	atr := calculateATR(klines, period)
+	if atr <= 0 {
+		return 0, fmt.Errorf("%s %s K线不足，无法计算ATR%d", symbol, interval, period)
+	}
+	return atr, nil
+}
+
+// GetMinuteVolumeUSD 最近minutes根已收盘1分钟K线的平均成交额（USDT）
+func GetMinuteVolumeUSD(ctx context.Context, symbol string, minutes int) (float64, error) {
+	klines, err := GetKlinesContext(ctx, symbol, Interval1m, minutes+1)
+	if err != nil {
+		return 0, err
+	}
+	if len(klines) < 2 {
+		return 0, fmt.Errorf("%s 1分钟K线不足", symbol)
+	}
+	closed := klines[:len(klines)-1]
+	total := 0.0
+	for _, k := range closed {
+		total += k.Volume * k.Close
+	}
+	return total / float64(len(closed)), nil
+}
+
+// getKlines 从当前数据源获取K线数据（带缓存，缓存过期后通过K线缓冲区只增量请求新K线；返回的切片为共享数据，调用方不可修改）
+func getKlines(ctx context.Context, symbol, interval string, limit int) ([]Kline, error) {
+	source := activeSource()
+	return cached(ctx, CacheKlines, fmt.Sprintf("%s|%s|%s|%d", source.Name(), symbol, interval, limit), func(ctx context.Context) ([]Kline, error) {
+		return klineBuffers.get(ctx, source, symbol, Interval(interval), limit)
+	})
 }
 
-// getKlines 从Binance获取K线数据
-func getKlines(symbol, interval string, limit int) ([]Kline, error) {
+// fetchKlines 从Binance获取K线数据
+func fetchKlines(ctx context.Context, symbol, interval string, limit int) ([]Kline, error) {
 	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/klines?symbol=%s&interval=%s&limit=%d",
 		symbol, interval, limit)
+	return fetchKlinesFrom(ctx, httpClient, url)
+}
+
+// fetchKlinesSince 从Binance获取开盘时间不早于startTime（毫秒）的K线
+func fetchKlinesSince(ctx context.Context, symbol, interval string, startTime int64, limit int) ([]Kline, error) {
+	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/klines?symbol=%s&interval=%s&startTime=%d&limit=%d",
+		symbol, interval, startTime, limit)
+	return fetchKlinesFrom(ctx, httpClient, url)
+}
 
-	resp, err := http.Get(url)
+// fetchKlinesFrom 请求并解析Binance格式的K线数据（合约和现货接口格式相同）
+func fetchKlinesFrom(ctx context.Context, client *http.Client, url string) ([]Kline, error) {
+	resp, err := getContext(ctx, client, url)
 	if err != nil {
 		return nil, err
 	}
@@ -166,15 +409,17 @@ func getKlines(symbol, interval string, limit int) ([]Kline, error) {
 		close, _ := parseFloat(item[4])
 		volume, _ := parseFloat(item[5])
 		closeTime := int64(item[6].(float64))
+		takerBuyVolume, _ := parseFloat(item[9])
 
 		klines[i] = Kline{
-			OpenTime:  openTime,
-			Open:      open,
-			High:      high,
-			Low:       low,
-			Close:     close,
-			Volume:    volume,
-			CloseTime: closeTime,
+			OpenTime:       openTime,
+			Open:           open,
+			High:           high,
+			Low:            low,
+			Close:          close,
+			Volume:         volume,
+			TakerBuyVolume: takerBuyVolume,
+			CloseTime:      closeTime,
 		}
 	}
 
@@ -298,11 +543,15 @@ func calculateATR(klines []Kline, period int) float64 {
 // calculateIntradaySeries 计算日内系列数据
 func calculateIntradaySeries(klines []Kline) *IntradayData {
 	data := &IntradayData{
-		MidPrices:   make([]float64, 0, 10),
-		EMA20Values: make([]float64, 0, 10),
-		MACDValues:  make([]float64, 0, 10),
-		RSI7Values:  make([]float64, 0, 10),
-		RSI14Values: make([]float64, 0, 10),
+		MidPrices:     make([]float64, 0, 10),
+		EMA20Values:   make([]float64, 0, 10),
+		MACDValues:    make([]float64, 0, 10),
+		RSI7Values:    make([]float64, 0, 10),
+		RSI14Values:   make([]float64, 0, 10),
+		BBWidthValues: make([]float64, 0, 10),
+		ATR14Values:   make([]float64, 0, 10),
+		VWAPValues:    make([]float64, 0, 10),
+		ADX14Values:   make([]float64, 0, 10),
 	}
 
 	// 获取最近10个数据点
@@ -335,16 +584,37 @@ func calculateIntradaySeries(klines []Kline) *IntradayData {
 			rsi14 := calculateRSI(klines[:i+1], 14)
 			data.RSI14Values = append(data.RSI14Values, rsi14)
 		}
+
+		appendVolatilitySeries(klines, i, &data.BBWidthValues, &data.ATR14Values, &data.VWAPValues, &data.ADX14Values)
 	}
+	data.TakerBuyValues, data.TakerSellValues, data.CVDValues = calculateTakerFlow(klines, start)
 
 	return data
 }
 
+// appendVolatilitySeries 追加第i根K线的布林带宽度、ATR14、VWAP和ADX14（数据不足的指标跳过）
+func appendVolatilitySeries(klines []Kline, i int, bbWidth, atr14, vwap, adx14 *[]float64) {
+	if i >= 19 {
+		*bbWidth = append(*bbWidth, calculateBollingerWidth(klines[:i+1], 20, 2))
+	}
+	if i >= 14 {
+		*atr14 = append(*atr14, calculateATR(klines[:i+1], 14))
+	}
+	*vwap = append(*vwap, calculateVWAP(klines[:i+1]))
+	if i >= 27 {
+		*adx14 = append(*adx14, calculateADX(klines[:i+1], 14))
+	}
+}
+
 // calculateLongerTermData 计算长期数据
 func calculateLongerTermData(klines []Kline) *LongerTermData {
 	data := &LongerTermData{
-		MACDValues:  make([]float64, 0, 10),
-		RSI14Values: make([]float64, 0, 10),
+		MACDValues:    make([]float64, 0, 10),
+		RSI14Values:   make([]float64, 0, 10),
+		BBWidthValues: make([]float64, 0, 10),
+		ATR14Values:   make([]float64, 0, 10),
+		VWAPValues:    make([]float64, 0, 10),
+		ADX14Values:   make([]float64, 0, 10),
 	}
 
 	// 计算EMA
@@ -381,16 +651,27 @@ func calculateLongerTermData(klines []Kline) *LongerTermData {
 			rsi14 := calculateRSI(klines[:i+1], 14)
 			data.RSI14Values = append(data.RSI14Values, rsi14)
 		}
+
+		appendVolatilitySeries(klines, i, &data.BBWidthValues, &data.ATR14Values, &data.VWAPValues, &data.ADX14Values)
 	}
+	data.TakerBuyValues, data.TakerSellValues, data.CVDValues = calculateTakerFlow(klines, start)
 
 	return data
 }
 
-// getOpenInterestData 获取OI数据
-func getOpenInterestData(symbol string) (*OIData, error) {
+// getOpenInterestData 从当前数据源获取OI数据（带缓存）
+func getOpenInterestData(ctx context.Context, symbol string) (*OIData, error) {
+	source := activeSource()
+	return cached(ctx, CacheOpenInterest, source.Name()+"|"+symbol, func(ctx context.Context) (*OIData, error) {
+		return source.OpenInterest(ctx, symbol)
+	})
+}
+
+// fetchOpenInterestData 从Binance获取OI数据
+func fetchOpenInterestData(ctx context.Context, symbol string) (*OIData, error) {
 	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/openInterest?symbol=%s", symbol)
 
-	resp, err := http.Get(url)
+	resp, err := getContext(ctx, httpClient, url)
 	if err != nil {
 		return nil, err
 	}
@@ -419,19 +700,33 @@ func getOpenInterestData(symbol string) (*OIData, error) {
 	}, nil
 }
 
-// getFundingRate 获取资金费率
-func getFundingRate(symbol string) (float64, error) {
+// getPremiumIndex 从当前数据源获取标记价格、指数价格和资金费率（带缓存）。
+// 优先使用全市场批量数据（扫描多个币种时只需一次请求），批量接口不可用或没有该币种时单独请求
+func getPremiumIndex(ctx context.Context, symbol string) (*PremiumIndex, error) {
+	source := activeSource()
+	if all, err := getAllPremiumIndex(ctx, source); err == nil {
+		if premium, ok := all[symbol]; ok {
+			return premium, nil
+		}
+	}
+	return cached(ctx, CachePremiumIndex, source.Name()+"|"+symbol, func(ctx context.Context) (*PremiumIndex, error) {
+		return source.PremiumIndex(ctx, symbol)
+	})
+}
+
+// fetchPremiumIndex 从Binance获取标记价格、指数价格和资金费率
+func fetchPremiumIndex(ctx context.Context, symbol string) (*PremiumIndex, error) {
 	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/premiumIndex?symbol=%s", symbol)
 
-	resp, err := http.Get(url)
+	resp, err := getContext(ctx, httpClient, url)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	var result struct {
@@ -445,20 +740,41 @@ func getFundingRate(symbol string) (float64, error) {
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	rate, _ := strconv.ParseFloat(result.LastFundingRate, 64)
-	return rate, nil
+	premium := &PremiumIndex{}
+	premium.MarkPrice, _ = strconv.ParseFloat(result.MarkPrice, 64)
+	premium.IndexPrice, _ = strconv.ParseFloat(result.IndexPrice, 64)
+	premium.FundingRate, _ = strconv.ParseFloat(result.LastFundingRate, 64)
+	return premium, nil
+}
+
+// formatBasis 标记价格、指数价格和基差（基差过大说明合约价格偏离现货，可能被操纵或即将回归）
+func formatBasis(data *Data) string {
+	return fmt.Sprintf("Mark Price: %.4f | Index Price: %.4f | Basis (mark vs index): %+.3f%%",
+		data.MarkPrice, data.IndexPrice, data.BasisPct)
 }
 
 // Format 格式化输出市场数据
 func Format(data *Data) string {
 	var sb strings.Builder
 
+	if len(data.DataIssues) > 0 {
+		sb.WriteString(formatDataIssues(data) + "\n\n")
+	}
+
 	sb.WriteString(fmt.Sprintf("current_price = %.2f, current_ema20 = %.3f, current_macd = %.3f, current_rsi (7 period) = %.3f\n\n",
 		data.CurrentPrice, data.CurrentEMA20, data.CurrentMACD, data.CurrentRSI7))
 
+	if data.Regime != nil {
+		sb.WriteString(FormatRegime(data.Regime) + "\n\n")
+	}
+
+	if data.Volatility != nil {
+		sb.WriteString(formatVolatility(data.Volatility, data.IntradayInterval, data.LongerTermInterval) + "\n\n")
+	}
+
 	sb.WriteString(fmt.Sprintf("In addition, here is the latest %s open interest and funding rate for perps:\n\n",
 		data.Symbol))
 
@@ -469,8 +785,36 @@ func Format(data *Data) string {
 
 	sb.WriteString(fmt.Sprintf("Funding Rate: %.2e\n\n", data.FundingRate))
 
+	if data.MarkPrice > 0 && data.IndexPrice > 0 {
+		sb.WriteString(formatBasis(data) + "\n\n")
+	}
+
+	if data.Depth != nil {
+		sb.WriteString(formatDepth(data.Depth) + "\n\n")
+	}
+
+	if data.Liquidations != nil {
+		sb.WriteString(formatLiquidations(data.Liquidations) + "\n\n")
+	}
+
+	if data.LongShort != nil {
+		sb.WriteString(formatLongShort(data.LongShort) + "\n\n")
+	}
+
+	if data.SpotBasis != nil {
+		sb.WriteString(formatSpotBasis(data.SpotBasis) + "\n\n")
+	}
+
+	if data.Levels != nil {
+		sb.WriteString(formatLevels(data.Levels) + "\n\n")
+	}
+
+	if data.VolumeProfile != nil {
+		sb.WriteString(formatVolumeProfile(data.VolumeProfile, data.CurrentPrice) + "\n\n")
+	}
+
 	if data.IntradaySeries != nil {
-		sb.WriteString("Intraday series (3‑minute intervals, oldest → latest):\n\n")
+		sb.WriteString(fmt.Sprintf("Intraday series (%s intervals, oldest → latest):\n\n", intervalLabel(data.IntradayInterval, Interval3m)))
 
 		if len(data.IntradaySeries.MidPrices) > 0 {
 			sb.WriteString(fmt.Sprintf("Mid prices: %s\n\n", formatFloatSlice(data.IntradaySeries.MidPrices)))
@@ -491,10 +835,18 @@ func Format(data *Data) string {
 		if len(data.IntradaySeries.RSI14Values) > 0 {
 			sb.WriteString(fmt.Sprintf("RSI indicators (14‑Period): %s\n\n", formatFloatSlice(data.IntradaySeries.RSI14Values)))
 		}
+
+		writeVolatilitySeries(&sb, data.IntradaySeries.BBWidthValues, data.IntradaySeries.ATR14Values,
+			data.IntradaySeries.VWAPValues, data.IntradaySeries.ADX14Values)
+		writeTakerFlowSeries(&sb, data.IntradaySeries.TakerBuyValues, data.IntradaySeries.TakerSellValues, data.IntradaySeries.CVDValues)
+		if data.SpotBasis != nil && len(data.SpotBasis.PremiumValues) > 0 {
+			sb.WriteString(fmt.Sprintf("Spot–perp premium %%: %s\n\n", formatFloatSlice(data.SpotBasis.PremiumValues)))
+		}
+		writeOptionalSeries(&sb, data.IntradaySeries.Optional)
 	}
 
 	if data.LongerTermContext != nil {
-		sb.WriteString("Longer‑term context (4‑hour timeframe):\n\n")
+		sb.WriteString(fmt.Sprintf("Longer‑term context (%s timeframe):\n\n", intervalLabel(data.LongerTermInterval, Interval4h)))
 
 		sb.WriteString(fmt.Sprintf("20‑Period EMA: %.3f vs. 50‑Period EMA: %.3f\n\n",
 			data.LongerTermContext.EMA20, data.LongerTermContext.EMA50))
@@ -512,11 +864,165 @@ func Format(data *Data) string {
 		if len(data.LongerTermContext.RSI14Values) > 0 {
 			sb.WriteString(fmt.Sprintf("RSI indicators (14‑Period): %s\n\n", formatFloatSlice(data.LongerTermContext.RSI14Values)))
 		}
+
+		writeVolatilitySeries(&sb, data.LongerTermContext.BBWidthValues, data.LongerTermContext.ATR14Values,
+			data.LongerTermContext.VWAPValues, data.LongerTermContext.ADX14Values)
+		writeTakerFlowSeries(&sb, data.LongerTermContext.TakerBuyValues, data.LongerTermContext.TakerSellValues, data.LongerTermContext.CVDValues)
+		writeOptionalSeries(&sb, data.LongerTermContext.Optional)
+	}
+
+	for _, tf := range data.ExtraTimeframes {
+		sb.WriteString(fmt.Sprintf("%s series (%s intervals, oldest → latest):\n\n", tf.Interval, intervalLabel(Interval(tf.Interval), "")))
+		sb.WriteString(fmt.Sprintf("Close prices: %s\n\n", formatFloatSlice(tf.Series.MidPrices)))
+		if len(tf.Series.EMA20Values) > 0 {
+			sb.WriteString(fmt.Sprintf("EMA indicators (20‑period): %s\n\n", formatFloatSlice(tf.Series.EMA20Values)))
+		}
+		if len(tf.Series.MACDValues) > 0 {
+			sb.WriteString(fmt.Sprintf("MACD indicators: %s\n\n", formatFloatSlice(tf.Series.MACDValues)))
+		}
+		if len(tf.Series.RSI14Values) > 0 {
+			sb.WriteString(fmt.Sprintf("RSI indicators (14‑Period): %s\n\n", formatFloatSlice(tf.Series.RSI14Values)))
+		}
+		writeVolatilitySeries(&sb, tf.Series.BBWidthValues, tf.Series.ATR14Values, tf.Series.VWAPValues, tf.Series.ADX14Values)
+		writeTakerFlowSeries(&sb, tf.Series.TakerBuyValues, tf.Series.TakerSellValues, tf.Series.CVDValues)
+		writeOptionalSeries(&sb, tf.Series.Optional)
 	}
 
 	return sb.String()
 }
 
+// writeVolatilitySeries 输出布林带宽度、ATR、VWAP和ADX序列（空序列不输出）
+func writeVolatilitySeries(sb *strings.Builder, bbWidth, atr14, vwap, adx14 []float64) {
+	if len(bbWidth) > 0 {
+		sb.WriteString(fmt.Sprintf("Bollinger Band width %% (20‑period, 2σ): %s\n\n", formatFloatSlice(bbWidth)))
+	}
+	if len(atr14) > 0 {
+		sb.WriteString(fmt.Sprintf("ATR (14‑period): %s\n\n", formatFloatSlice(atr14)))
+	}
+	if len(vwap) > 0 {
+		sb.WriteString(fmt.Sprintf("Session VWAP (since 00:00 UTC): %s\n\n", formatFloatSlice(vwap)))
+	}
+	if len(adx14) > 0 {
+		sb.WriteString(fmt.Sprintf("ADX (14‑period): %s\n\n", formatFloatSlice(adx14)))
+	}
+}
+
+// compactSeriesPoints 紧凑模式下每个序列保留的最新数据点数
+const compactSeriesPoints = 3
+
+// FormatCompact 紧凑格式输出市场数据（序列只保留统计值和最近几个点，用于低优先级币种节省token）
+func FormatCompact(data *Data) string {
+	var sb strings.Builder
+
+	if len(data.DataIssues) > 0 {
+		sb.WriteString(formatDataIssues(data) + "\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("current_price = %.2f, current_ema20 = %.3f, current_macd = %.3f, current_rsi (7 period) = %.3f\n",
+		data.CurrentPrice, data.CurrentEMA20, data.CurrentMACD, data.CurrentRSI7))
+	if data.Regime != nil {
+		sb.WriteString(FormatRegime(data.Regime) + "\n")
+	}
+	if data.Volatility != nil {
+		sb.WriteString(formatVolatility(data.Volatility, data.IntradayInterval, data.LongerTermInterval) + "\n")
+	}
+
+	if data.OpenInterest != nil {
+		sb.WriteString(fmt.Sprintf("Open Interest: Latest: %.2f Average: %.2f | ", data.OpenInterest.Latest, data.OpenInterest.Average))
+	}
+	sb.WriteString(fmt.Sprintf("Funding Rate: %.2e\n", data.FundingRate))
+	if data.MarkPrice > 0 && data.IndexPrice > 0 {
+		sb.WriteString(formatBasis(data) + "\n")
+	}
+	if data.Depth != nil {
+		sb.WriteString(formatDepth(data.Depth) + "\n")
+	}
+	if data.Liquidations != nil {
+		sb.WriteString(formatLiquidations(data.Liquidations) + "\n")
+	}
+	if data.LongShort != nil {
+		sb.WriteString(formatLongShort(data.LongShort) + "\n")
+	}
+	if data.SpotBasis != nil {
+		sb.WriteString(formatSpotBasis(data.SpotBasis) + "\n")
+	}
+	if data.Levels != nil {
+		sb.WriteString(formatLevels(data.Levels) + "\n")
+	}
+	if data.VolumeProfile != nil {
+		sb.WriteString(formatVolumeProfile(data.VolumeProfile, data.CurrentPrice) + "\n")
+	}
+	sb.WriteString("\n")
+
+	if data.IntradaySeries != nil {
+		sb.WriteString(fmt.Sprintf("Intraday series summary (%s intervals):\n", intervalLabel(data.IntradayInterval, Interval3m)))
+		writeSeriesSummary(&sb, "Mid prices", data.IntradaySeries.MidPrices)
+		writeSeriesSummary(&sb, "EMA20", data.IntradaySeries.EMA20Values)
+		writeSeriesSummary(&sb, "MACD", data.IntradaySeries.MACDValues)
+		writeSeriesSummary(&sb, "RSI7", data.IntradaySeries.RSI7Values)
+		writeSeriesSummary(&sb, "RSI14", data.IntradaySeries.RSI14Values)
+		writeSeriesSummary(&sb, "BB width %", data.IntradaySeries.BBWidthValues)
+		writeSeriesSummary(&sb, "ATR14", data.IntradaySeries.ATR14Values)
+		writeSeriesSummary(&sb, "VWAP", data.IntradaySeries.VWAPValues)
+		writeSeriesSummary(&sb, "ADX14", data.IntradaySeries.ADX14Values)
+		writeSeriesSummary(&sb, "CVD", data.IntradaySeries.CVDValues)
+		if data.SpotBasis != nil {
+			writeSeriesSummary(&sb, "Spot–perp premium %", data.SpotBasis.PremiumValues)
+		}
+		writeOptionalSummary(&sb, data.IntradaySeries.Optional)
+		sb.WriteString("\n")
+	}
+
+	if data.LongerTermContext != nil {
+		sb.WriteString(fmt.Sprintf("Longer‑term context (%s): EMA20 %.3f vs EMA50 %.3f | ATR3 %.3f vs ATR14 %.3f | Volume %.3f vs avg %.3f\n",
+			intervalLabel(data.LongerTermInterval, Interval4h),
+			data.LongerTermContext.EMA20, data.LongerTermContext.EMA50,
+			data.LongerTermContext.ATR3, data.LongerTermContext.ATR14,
+			data.LongerTermContext.CurrentVolume, data.LongerTermContext.AverageVolume))
+		writeSeriesSummary(&sb, "MACD", data.LongerTermContext.MACDValues)
+		writeSeriesSummary(&sb, "RSI14", data.LongerTermContext.RSI14Values)
+		writeSeriesSummary(&sb, "BB width %", data.LongerTermContext.BBWidthValues)
+		writeSeriesSummary(&sb, "VWAP", data.LongerTermContext.VWAPValues)
+		writeSeriesSummary(&sb, "ADX14", data.LongerTermContext.ADX14Values)
+		writeSeriesSummary(&sb, "CVD", data.LongerTermContext.CVDValues)
+		writeOptionalSummary(&sb, data.LongerTermContext.Optional)
+		sb.WriteString("\n")
+	}
+
+	for _, tf := range data.ExtraTimeframes {
+		sb.WriteString(fmt.Sprintf("%s series summary (%s intervals):\n", tf.Interval, intervalLabel(Interval(tf.Interval), "")))
+		writeSeriesSummary(&sb, "Close prices", tf.Series.MidPrices)
+		writeSeriesSummary(&sb, "EMA20", tf.Series.EMA20Values)
+		writeSeriesSummary(&sb, "MACD", tf.Series.MACDValues)
+		writeSeriesSummary(&sb, "RSI14", tf.Series.RSI14Values)
+		writeSeriesSummary(&sb, "ADX14", tf.Series.ADX14Values)
+		writeSeriesSummary(&sb, "CVD", tf.Series.CVDValues)
+		writeOptionalSummary(&sb, tf.Series.Optional)
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// writeSeriesSummary 输出序列的最小值、最大值、均值和最近几个点（空序列不输出）
+func writeSeriesSummary(sb *strings.Builder, name string, values []float64) {
+	if len(values) == 0 {
+		return
+	}
+	minV, maxV, sum := values[0], values[0], 0.0
+	for _, v := range values {
+		minV = math.Min(minV, v)
+		maxV = math.Max(maxV, v)
+		sum += v
+	}
+	last := values
+	if len(last) > compactSeriesPoints {
+		last = last[len(last)-compactSeriesPoints:]
+	}
+	sb.WriteString(fmt.Sprintf("- %s (%d pts): min %.3f, max %.3f, mean %.3f, last %s\n",
+		name, len(values), minV, maxV, sum/float64(len(values)), formatFloatSlice(last)))
+}
+
 // formatFloatSlice 格式化float64切片为字符串
 func formatFloatSlice(values []float64) string {
 	strValues := make([]string, len(values))
@@ -526,15 +1032,6 @@ func formatFloatSlice(values []float64) string {
 	return "[" + strings.Join(strValues, ", ") + "]"
 }
 
-// Normalize 标准化symbol,确保是USDT交易对
-func Normalize(symbol string) string {
-	symbol = strings.ToUpper(symbol)
-	if strings.HasSuffix(symbol, "USDT") {
-		return symbol
-	}
-	return symbol + "USDT"
-}
-
 // parseFloat 解析float值
 func parseFloat(v interface{}) (float64, error) {
 	switch val := v.(type) {
@@ -0,0 +1,171 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+)
+
+const (
+	depthLimit   = 1000 // 获取的档位数量（权重20）
+	depthBandPct = 0.5  // 聚合深度的价格范围（中间价±0.5%）
+)
+
+// DepthData 订单簿深度（盘口 + 中间价±0.5%内的聚合深度）
+type DepthData struct {
+	BestBid     float64
+	BestAsk     float64
+	BestBidQty  float64
+	BestAskQty  float64
+	SpreadPct   float64 // 买一卖一价差（相对中间价的百分比）
+	BidDepthUSD float64 // 中间价-0.5%范围内的买单总额（USDT）
+	AskDepthUSD float64 // 中间价+0.5%范围内的卖单总额（USDT）
+	Imbalance   float64 // (买单-卖单)/(买单+卖单)，-1到1，正数表示买盘更厚
+	BidPartial  bool    // 获取的买单档位未覆盖完整价格范围（实际深度大于统计值）
+	AskPartial  bool    // 获取的卖单档位未覆盖完整价格范围
+}
+
+// GetDepth 获取订单簿深度指标（盘口、价差和中间价±0.5%内的聚合深度）
+func GetDepth(symbol string) (*DepthData, error) {
+	return GetDepthContext(context.Background(), symbol)
+}
+
+// GetDepthContext 同GetDepth，ctx取消时中止进行中的请求
+func GetDepthContext(ctx context.Context, symbol string) (*DepthData, error) {
+	return getDepth(ctx, canonical(ctx, symbol))
+}
+
+// getDepth 从当前数据源获取订单簿深度指标（带缓存）
+func getDepth(ctx context.Context, symbol string) (*DepthData, error) {
+	source := activeSource()
+	return cached(ctx, CacheDepth, source.Name()+"|"+symbol, func(ctx context.Context) (*DepthData, error) {
+		return source.Depth(ctx, symbol)
+	})
+}
+
+// fetchDepth 从Binance获取订单簿并计算深度指标
+func fetchDepth(ctx context.Context, symbol string) (*DepthData, error) {
+	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/depth?symbol=%s&limit=%d", symbol, depthLimit)
+
+	resp, err := getContext(ctx, httpClient, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Bids [][]string `json:"bids"`
+		Asks [][]string `json:"asks"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	bids := parseDepthLevels(result.Bids)
+	asks := parseDepthLevels(result.Asks)
+	if len(bids) == 0 || len(asks) == 0 {
+		return nil, fmt.Errorf("%s 订单簿为空", symbol)
+	}
+	return calculateDepth(bids, asks), nil
+}
+
+// parseDepthLevels 解析 [价格, 数量] 档位
+func parseDepthLevels(raw [][]string) [][2]float64 {
+	levels := make([][2]float64, 0, len(raw))
+	for _, level := range raw {
+		if len(level) < 2 {
+			continue
+		}
+		price, err1 := strconv.ParseFloat(level[0], 64)
+		qty, err2 := strconv.ParseFloat(level[1], 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		levels = append(levels, [2]float64{price, qty})
+	}
+	return levels
+}
+
+// calculateDepth 计算盘口、价差和±0.5%范围内的聚合深度（bids价格从高到低，asks从低到高）
+func calculateDepth(bids, asks [][2]float64) *DepthData {
+	depth := &DepthData{
+		BestBid:    bids[0][0],
+		BestBidQty: bids[0][1],
+		BestAsk:    asks[0][0],
+		BestAskQty: asks[0][1],
+	}
+
+	mid := (depth.BestBid + depth.BestAsk) / 2
+	if mid <= 0 {
+		return depth
+	}
+	depth.SpreadPct = (depth.BestAsk - depth.BestBid) / mid * 100
+
+	lower := mid * (1 - depthBandPct/100)
+	upper := mid * (1 + depthBandPct/100)
+
+	// 获取的档位在范围内就已耗尽，说明范围内还有未获取的挂单
+	depth.BidPartial = true
+	for _, level := range bids {
+		if level[0] < lower {
+			depth.BidPartial = false
+			break
+		}
+		depth.BidDepthUSD += level[0] * level[1]
+	}
+	depth.AskPartial = true
+	for _, level := range asks {
+		if level[0] > upper {
+			depth.AskPartial = false
+			break
+		}
+		depth.AskDepthUSD += level[0] * level[1]
+	}
+	if total := depth.BidDepthUSD + depth.AskDepthUSD; total > 0 {
+		depth.Imbalance = (depth.BidDepthUSD - depth.AskDepthUSD) / total
+	}
+	return depth
+}
+
+// formatDepth 订单簿深度描述（供AI判断流动性和滑点）
+func formatDepth(depth *DepthData) string {
+	side := "balanced"
+	if depth.Imbalance > 0.2 {
+		side = "bid-heavy"
+	} else if depth.Imbalance < -0.2 {
+		side = "ask-heavy"
+	}
+	bidPrefix, askPrefix := "", ""
+	if depth.BidPartial {
+		bidPrefix = "≥"
+	}
+	if depth.AskPartial {
+		askPrefix = "≥"
+	}
+
+	return fmt.Sprintf("Order Book: best bid %.4f (%.3f) / best ask %.4f (%.3f), spread %.4f%% | depth within ±%.1f%%: bids %s%s USDT / asks %s%s USDT, imbalance %+.2f (%s)",
+		depth.BestBid, depth.BestBidQty, depth.BestAsk, depth.BestAskQty, depth.SpreadPct,
+		depthBandPct, bidPrefix, formatUSD(depth.BidDepthUSD), askPrefix, formatUSD(depth.AskDepthUSD), depth.Imbalance, side)
+}
+
+// formatUSD 金额简写（K/M/B/T）
+func formatUSD(v float64) string {
+	switch {
+	case v >= 1e12:
+		return fmt.Sprintf("%.2fT", v/1e12)
+	case v >= 1e9:
+		return fmt.Sprintf("%.2fB", v/1e9)
+	case v >= 1e6:
+		return fmt.Sprintf("%.2fM", v/1e6)
+	case v >= 1e3:
+		return fmt.Sprintf("%.1fK", v/1e3)
+	}
+	return fmt.Sprintf("%.0f", v)
+}
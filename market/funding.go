@@ -0,0 +1,42 @@
+package market
+
+import (
+	"context"
+	"fmt"
+)
+
+// FundingRate 币种的当前资金费率
+type FundingRate struct {
+	Symbol         string
+	Rate           float64 // 当前资金费率（小数，如0.0001=0.01%）
+	QuoteVolume24h float64 // 24小时成交额（USDT）
+}
+
+// GetFundingRates 24小时成交额前universe名的USDT合约的当前资金费率（按成交额降序，全市场批量接口）
+func GetFundingRates(universe int) ([]FundingRate, error) {
+	leaders, err := topByQuoteVolume(universe)
+	if err != nil {
+		return nil, err
+	}
+	premiums, err := getAllPremiumIndex(context.Background(), activeSource())
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费率失败: %w", err)
+	}
+
+	rates := make([]FundingRate, 0, len(leaders))
+	for _, leader := range leaders {
+		if premium := premiums[leader.Symbol]; premium != nil {
+			rates = append(rates, FundingRate{
+				Symbol:         leader.Symbol,
+				Rate:           premium.FundingRate,
+				QuoteVolume24h: leader.QuoteVolume24h,
+			})
+		}
+	}
+	return rates, nil
+}
+
+// GetPremiumIndex 获取币种的标记价格、指数价格和当前资金费率（带缓存）
+func GetPremiumIndex(symbol string) (*PremiumIndex, error) {
+	return getPremiumIndex(context.Background(), Canonical(symbol))
+}
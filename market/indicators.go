@@ -0,0 +1,116 @@
+package market
+
+import (
+	"math"
+	"time"
+)
+
+// calculateBollingerWidth 计算布林带宽度（(上轨-下轨)/中轨 的百分比，收窄预示波动率扩张）
+func calculateBollingerWidth(klines []Kline, period int, multiplier float64) float64 {
+	if len(klines) < period {
+		return 0
+	}
+
+	window := klines[len(klines)-period:]
+	sum := 0.0
+	for _, k := range window {
+		sum += k.Close
+	}
+	mean := sum / float64(period)
+	if mean == 0 {
+		return 0
+	}
+
+	variance := 0.0
+	for _, k := range window {
+		variance += (k.Close - mean) * (k.Close - mean)
+	}
+	stdDev := math.Sqrt(variance / float64(period))
+
+	return 2 * multiplier * stdDev / mean * 100
+}
+
+// calculateVWAP 计算当日（UTC 0点起）成交量加权平均价，只使用已获取的K线
+func calculateVWAP(klines []Kline) float64 {
+	if len(klines) == 0 {
+		return 0
+	}
+
+	last := time.UnixMilli(klines[len(klines)-1].OpenTime).UTC()
+	sessionStart := time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, time.UTC).UnixMilli()
+
+	pv, volume := 0.0, 0.0
+	for i := len(klines) - 1; i >= 0 && klines[i].OpenTime >= sessionStart; i-- {
+		typical := (klines[i].High + klines[i].Low + klines[i].Close) / 3
+		pv += typical * klines[i].Volume
+		volume += klines[i].Volume
+	}
+	if volume == 0 {
+		return klines[len(klines)-1].Close
+	}
+	return pv / volume
+}
+
+// calculateADX 计算ADX（Wilder平滑，衡量趋势强度，>25为趋势行情，<20为震荡）
+func calculateADX(klines []Kline, period int) float64 {
+	if len(klines) < 2*period {
+		return 0
+	}
+
+	var trSum, plusDMSum, minusDMSum float64
+	var adx float64
+	dxCount := 0
+	dxSum := 0.0
+
+	for i := 1; i < len(klines); i++ {
+		high, low := klines[i].High, klines[i].Low
+		prevHigh, prevLow, prevClose := klines[i-1].High, klines[i-1].Low, klines[i-1].Close
+
+		tr := math.Max(high-low, math.Max(math.Abs(high-prevClose), math.Abs(low-prevClose)))
+		upMove := high - prevHigh
+		downMove := prevLow - low
+		plusDM, minusDM := 0.0, 0.0
+		if upMove > downMove && upMove > 0 {
+			plusDM = upMove
+		}
+		if downMove > upMove && downMove > 0 {
+			minusDM = downMove
+		}
+
+		// 前period根累加作为初始值，之后Wilder平滑
+		if i <= period {
+			trSum += tr
+			plusDMSum += plusDM
+			minusDMSum += minusDM
+			if i < period {
+				continue
+			}
+		} else {
+			trSum = trSum - trSum/float64(period) + tr
+			plusDMSum = plusDMSum - plusDMSum/float64(period) + plusDM
+			minusDMSum = minusDMSum - minusDMSum/float64(period) + minusDM
+		}
+
+		dx := 0.0
+		if trSum > 0 {
+			plusDI := 100 * plusDMSum / trSum
+			minusDI := 100 * minusDMSum / trSum
+			if plusDI+minusDI > 0 {
+				dx = 100 * math.Abs(plusDI-minusDI) / (plusDI + minusDI)
+			}
+		}
+
+		// 前period个DX的平均值作为初始ADX，之后Wilder平滑
+		if dxCount < period {
+			dxSum += dx
+			dxCount++
+			if dxCount == period {
+				adx = dxSum / float64(period)
+			}
+			continue
+		}
+		adx = (adx*float64(period-1) + dx) / float64(period)
+	}
+
+	return adx
+}
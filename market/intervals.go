@@ -0,0 +1,134 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Interval K线周期（币安格式，如 "3m"、"4h"）
+type Interval string
+
+// 支持的K线周期
+const (
+	Interval1m  Interval = "1m"
+	Interval3m  Interval = "3m"
+	Interval5m  Interval = "5m"
+	Interval15m Interval = "15m"
+	Interval30m Interval = "30m"
+	Interval1h  Interval = "1h"
+	Interval2h  Interval = "2h"
+	Interval4h  Interval = "4h"
+	Interval6h  Interval = "6h"
+	Interval12h Interval = "12h"
+	Interval1d  Interval = "1d"
+)
+
+// intervalInfo 周期时长和显示名称
+var intervalInfo = map[Interval]struct {
+	duration time.Duration
+	label    string
+}{
+	Interval1m:  {time.Minute, "1‑minute"},
+	Interval3m:  {3 * time.Minute, "3‑minute"},
+	Interval5m:  {5 * time.Minute, "5‑minute"},
+	Interval15m: {15 * time.Minute, "15‑minute"},
+	Interval30m: {30 * time.Minute, "30‑minute"},
+	Interval1h:  {time.Hour, "1‑hour"},
+	Interval2h:  {2 * time.Hour, "2‑hour"},
+	Interval4h:  {4 * time.Hour, "4‑hour"},
+	Interval6h:  {6 * time.Hour, "6‑hour"},
+	Interval12h: {12 * time.Hour, "12‑hour"},
+	Interval1d:  {24 * time.Hour, "1‑day"},
+}
+
+// Duration 周期时长（未知周期返回0）
+func (i Interval) Duration() time.Duration {
+	return intervalInfo[i].duration
+}
+
+// Valid 是否为支持的周期
+func (i Interval) Valid() bool {
+	_, ok := intervalInfo[i]
+	return ok
+}
+
+// Intervals 所有支持的K线周期（按时长从短到长）
+func Intervals() []Interval {
+	intervals := make([]Interval, 0, len(intervalInfo))
+	for interval := range intervalInfo {
+		intervals = append(intervals, interval)
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].Duration() < intervals[j].Duration() })
+	return intervals
+}
+
+// GetWithIntervals 按指定周期获取市场数据（剥头皮可用1m+1h，波段可用1h+1d）：
+// intervals[0]用于当前指标和日内序列，intervals[1]用于长期背景，其余作为额外序列
+func GetWithIntervals(symbol string, intervals []Interval) (*Data, error) {
+	if len(intervals) < 2 {
+		return nil, fmt.Errorf("至少需要2个K线周期（日内周期和长期周期），当前: %v", intervals)
+	}
+	for _, interval := range intervals {
+		if !interval.Valid() {
+			return nil, fmt.Errorf("不支持的K线周期 %q", interval)
+		}
+	}
+
+	extras := make([]string, 0, len(intervals)-2)
+	for _, interval := range intervals[2:] {
+		extras = append(extras, string(interval))
+	}
+	return getData(context.Background(), symbol, intervals[0], intervals[1], extras, nil)
+}
+
+// filterExtraIntervals 去掉不支持的周期和与日内/长期周期重复的周期
+func filterExtraIntervals(extras []string, intraday, longer Interval) []string {
+	var filtered []string
+	for _, interval := range extras {
+		i := Interval(interval)
+		if !i.Valid() || i == intraday || i == longer {
+			continue
+		}
+		filtered = append(filtered, interval)
+	}
+	return filtered
+}
+
+// intervalLabel 周期显示名称，如 "3‑minute"（周期为空时使用fallback，兼容旧快照）
+func intervalLabel(interval, fallback Interval) string {
+	if interval == "" {
+		interval = fallback
+	}
+	if info, ok := intervalInfo[interval]; ok {
+		return info.label
+	}
+	return string(interval)
+}
+
+// priceChangeOver 当前价格相对period之前的涨跌幅（百分比）。
+// 按顺序使用第一个能精确回溯period的序列（周期时长整除period且K线数量足够）
+func priceChangeOver(currentPrice float64, period time.Duration, first Interval, firstKlines []Kline, second Interval, secondKlines []Kline) float64 {
+	candidates := []struct {
+		interval Interval
+		klines   []Kline
+	}{{first, firstKlines}, {second, secondKlines}}
+
+	for _, c := range candidates {
+		d := c.interval.Duration()
+		if d == 0 || d > period || period%d != 0 {
+			continue
+		}
+		bars := int(period / d)
+		if len(c.klines) < bars+1 {
+			continue
+		}
+		past := c.klines[len(c.klines)-1-bars].Close
+		if past > 0 {
+			return ((currentPrice - past) / past) * 100
+		}
+		return 0
+	}
+	return 0
+}
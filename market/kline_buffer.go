@@ -0,0 +1,168 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// K线缓冲区设置
+const (
+	klineBufferIdleTTL     = time.Hour // 超过该时长未读取的缓冲区被清理（候选币种会轮换）
+	klineBufferMinFetch    = 2         // 增量请求至少包含已存储的最后一根（可能未收盘）和新的一根
+	klineBufferSweepPeriod = 10 * time.Minute
+)
+
+// KlineBufferStat K线缓冲区统计
+type KlineBufferStat struct {
+	Buffers            int   `json:"buffers"`             // 当前缓冲区数量（币种×周期）
+	FullFetches        int64 `json:"full_fetches"`        // 全量请求次数
+	IncrementalFetches int64 `json:"incremental_fetches"` // 增量请求次数
+	CandlesFetched     int64 `json:"candles_fetched"`     // 实际请求的K线根数
+	CandlesServed      int64 `json:"candles_served"`      // 返回给调用方的K线根数（与请求根数之差即节省的数据量）
+}
+
+// klineBuffer 单个币种单个周期的K线缓冲区（按时间升序，最后一根为未收盘K线）
+type klineBuffer struct {
+	mu       sync.Mutex
+	klines   []Kline
+	capacity int // 保留的最大根数（取各调用方请求limit的最大值）
+	lastRead time.Time
+}
+
+// klineBufferSet 各币种各周期的K线缓冲区，每次只请求最后存储的K线之后的数据
+type klineBufferSet struct {
+	mu        sync.Mutex
+	buffers   map[string]*klineBuffer // 数据源|币种|周期 -> 缓冲区
+	lastSweep time.Time
+
+	fullFetches        atomic.Int64
+	incrementalFetches atomic.Int64
+	candlesFetched     atomic.Int64
+	candlesServed      atomic.Int64
+}
+
+var klineBuffers = &klineBufferSet{
+	buffers:   make(map[string]*klineBuffer),
+	lastSweep: time.Now(),
+}
+
+// get 返回最近limit根K线：缓冲区足够新时只增量请求，否则全量请求并重建缓冲区
+func (s *klineBufferSet) get(ctx context.Context, source Source, symbol string, interval Interval, limit int) ([]Kline, error) {
+	now := time.Now()
+	key := fmt.Sprintf("%s|%s|%s", source.Name(), symbol, interval)
+
+	s.mu.Lock()
+	buf, ok := s.buffers[key]
+	if !ok {
+		buf = &klineBuffer{lastRead: now}
+		s.buffers[key] = buf
+	}
+	if now.Sub(s.lastSweep) > klineBufferSweepPeriod {
+		s.sweep(now)
+	}
+	s.mu.Unlock()
+
+	buf.mu.Lock()
+	defer buf.mu.Unlock()
+	buf.lastRead = now
+	if limit > buf.capacity {
+		buf.capacity = limit
+	}
+
+	fetchLimit, incremental := buf.missing(interval, limit, now)
+	var fetched []Kline
+	var err error
+	if incremental {
+		fetched, err = source.KlinesSince(ctx, symbol, interval, buf.klines[len(buf.klines)-1].OpenTime, fetchLimit)
+		if err == nil && !buf.merge(fetched) {
+			// 增量数据与缓冲区对不上（交易所数据修正或缺口），改为全量请求
+			incremental = false
+		}
+	}
+	if !incremental {
+		fetched, err = source.Klines(ctx, symbol, interval, buf.capacity)
+		if err == nil {
+			buf.klines = append([]Kline(nil), fetched...)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := buf.klines
+	if len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+	result = append([]Kline(nil), result...)
+
+	if incremental {
+		s.incrementalFetches.Add(1)
+	} else {
+		s.fullFetches.Add(1)
+	}
+	s.candlesFetched.Add(int64(len(fetched)))
+	s.candlesServed.Add(int64(len(result)))
+
+	return result, nil
+}
+
+// missing 增量请求需要的根数；缓冲区为空、根数不足或落后太多时返回false（需要全量请求）
+func (b *klineBuffer) missing(interval Interval, limit int, now time.Time) (int, bool) {
+	d := interval.Duration()
+	if d == 0 || len(b.klines) < limit {
+		return 0, false
+	}
+	behind := int(now.Sub(time.UnixMilli(b.klines[len(b.klines)-1].OpenTime)) / d)
+	if behind < 0 || behind >= b.capacity {
+		return 0, false
+	}
+	if n := behind + 1; n > klineBufferMinFetch {
+		return n, true
+	}
+	return klineBufferMinFetch, true
+}
+
+// merge 用增量数据更新缓冲区（第一根必须与已存储的最后一根开盘时间相同），超出容量的旧K线被丢弃
+func (b *klineBuffer) merge(fetched []Kline) bool {
+	last := len(b.klines) - 1
+	if len(fetched) == 0 || fetched[0].OpenTime != b.klines[last].OpenTime {
+		return false
+	}
+	merged := append(b.klines[:last:last], fetched...)
+	if len(merged) > b.capacity {
+		merged = merged[len(merged)-b.capacity:]
+	}
+	b.klines = append([]Kline(nil), merged...)
+	return true
+}
+
+// sweep 清理长时间未读取的缓冲区（调用方需持有mu；读取中的缓冲区持有自身锁时不会再获取mu）
+func (s *klineBufferSet) sweep(now time.Time) {
+	for key, buf := range s.buffers {
+		buf.mu.Lock()
+		idle := now.Sub(buf.lastRead) > klineBufferIdleTTL
+		buf.mu.Unlock()
+		if idle {
+			delete(s.buffers, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// KlineBufferStats K线缓冲区统计（增量请求节省的请求量）
+func KlineBufferStats() KlineBufferStat {
+	klineBuffers.mu.Lock()
+	buffers := len(klineBuffers.buffers)
+	klineBuffers.mu.Unlock()
+
+	return KlineBufferStat{
+		Buffers:            buffers,
+		FullFetches:        klineBuffers.fullFetches.Load(),
+		IncrementalFetches: klineBuffers.incrementalFetches.Load(),
+		CandlesFetched:     klineBuffers.candlesFetched.Load(),
+		CandlesServed:      klineBuffers.candlesServed.Load(),
+	}
+}
@@ -0,0 +1,208 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 支撑/阻力位识别参数
+const (
+	swingPivotBars     = 2    // 摆动高/低点：左右各N根K线的最高/最低点
+	volumeProfileBins  = 24   // 成交量分布的价格分箱数
+	volumeNodeCount    = 3    // 取成交量最大的N个价格区间作为成交量节点
+	levelMergePct      = 0.15 // 相距不超过该百分比的价位合并为一个
+	levelsPerSide      = 3    // 当前价格上下各展示的价位数
+	levelsMinKlineSize = 20   // 识别价位所需的最少K线数
+)
+
+// 价位来源
+const (
+	LevelSwingHigh    = "swing high"
+	LevelSwingLow     = "swing low"
+	LevelPrevDayHigh  = "prev day high"
+	LevelPrevDayLow   = "prev day low"
+	LevelVolumeNode   = "volume node"
+	LevelPointControl = "POC" // 成交量最大的价格区间
+)
+
+// Level 支撑/阻力价位
+type Level struct {
+	Price       float64
+	DistancePct float64  // 相对当前价格的距离（百分比，正值在上方）
+	Sources     []string // 价位来源（多个来源重合的价位更重要）
+}
+
+// LevelsData 当前价格上下最近的支撑/阻力位
+type LevelsData struct {
+	Interval    Interval // 计算使用的K线周期
+	Resistances []Level  // 当前价格上方，由近到远
+	Supports    []Level  // 当前价格下方，由近到远
+}
+
+// calculateLevels 根据长期周期K线识别摆动高低点、前一日高低点和成交量节点（K线不足时返回nil）
+func calculateLevels(klines []Kline, interval Interval, currentPrice float64) *LevelsData {
+	if len(klines) < levelsMinKlineSize || currentPrice <= 0 {
+		return nil
+	}
+
+	var candidates []Level
+	add := func(price float64, source string) {
+		if price > 0 {
+			candidates = append(candidates, Level{Price: price, Sources: []string{source}})
+		}
+	}
+
+	// 摆动高/低点（最后swingPivotBars根K线右侧数据不足，不参与判断）
+	for i := swingPivotBars; i < len(klines)-swingPivotBars; i++ {
+		isHigh, isLow := true, true
+		for j := i - swingPivotBars; j <= i+swingPivotBars; j++ {
+			if j == i {
+				continue
+			}
+			if klines[j].High >= klines[i].High {
+				isHigh = false
+			}
+			if klines[j].Low <= klines[i].Low {
+				isLow = false
+			}
+		}
+		if isHigh {
+			add(klines[i].High, LevelSwingHigh)
+		}
+		if isLow {
+			add(klines[i].Low, LevelSwingLow)
+		}
+	}
+
+	// 前一日（UTC）高低点
+	if high, low, ok := previousDayRange(klines, interval); ok {
+		add(high, LevelPrevDayHigh)
+		add(low, LevelPrevDayLow)
+	}
+
+	// 成交量节点
+	for i, price := range volumeNodes(klines) {
+		if i == 0 {
+			add(price, LevelPointControl)
+		} else {
+			add(price, LevelVolumeNode)
+		}
+	}
+
+	levels := &LevelsData{Interval: interval}
+	for _, level := range mergeLevels(candidates) {
+		level.DistancePct = (level.Price - currentPrice) / currentPrice * 100
+		if level.Price > currentPrice {
+			levels.Resistances = append(levels.Resistances, level)
+		} else if level.Price < currentPrice {
+			levels.Supports = append(levels.Supports, level)
+		}
+	}
+	sort.Slice(levels.Resistances, func(i, j int) bool { return levels.Resistances[i].Price < levels.Resistances[j].Price })
+	sort.Slice(levels.Supports, func(i, j int) bool { return levels.Supports[i].Price > levels.Supports[j].Price })
+	if len(levels.Resistances) > levelsPerSide {
+		levels.Resistances = levels.Resistances[:levelsPerSide]
+	}
+	if len(levels.Supports) > levelsPerSide {
+		levels.Supports = levels.Supports[:levelsPerSide]
+	}
+	return levels
+}
+
+// previousDayRange 前一个UTC自然日的最高/最低价（周期不小于1天或数据不覆盖前一日时返回false）
+func previousDayRange(klines []Kline, interval Interval) (high, low float64, ok bool) {
+	d := interval.Duration()
+	if d == 0 || d >= 24*time.Hour {
+		return 0, 0, false
+	}
+	today := time.UnixMilli(klines[len(klines)-1].OpenTime).UTC().Truncate(24 * time.Hour)
+	yesterday := today.Add(-24 * time.Hour)
+
+	low = math.MaxFloat64
+	for _, k := range klines {
+		t := time.UnixMilli(k.OpenTime).UTC()
+		if t.Before(yesterday) || !t.Before(today) {
+			continue
+		}
+		high = math.Max(high, k.High)
+		low = math.Min(low, k.Low)
+		ok = true
+	}
+	return high, low, ok
+}
+
+// volumeNodes 成交量分布中成交量最大的几个价格区间的中点（按成交量降序，第一个为POC）
+func volumeNodes(klines []Kline) []float64 {
+	minPrice, binSize, volumes := volumeHistogram(klines, volumeProfileBins)
+	if volumes == nil {
+		return nil
+	}
+
+	order := make([]int, len(volumes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return volumes[order[i]] > volumes[order[j]] })
+
+	nodes := make([]float64, 0, volumeNodeCount)
+	for _, b := range order[:volumeNodeCount] {
+		if volumes[b] > 0 {
+			nodes = append(nodes, minPrice+(float64(b)+0.5)*binSize)
+		}
+	}
+	return nodes
+}
+
+// mergeLevels 合并相距不超过levelMergePct的价位（价格取平均，来源合并去重）
+func mergeLevels(levels []Level) []Level {
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Price < levels[j].Price })
+
+	var merged []Level
+	for _, level := range levels {
+		if n := len(merged); n > 0 && (level.Price-merged[n-1].Price)/merged[n-1].Price*100 <= levelMergePct {
+			last := &merged[n-1]
+			count := float64(len(last.Sources))
+			last.Price = (last.Price*count + level.Price) / (count + 1)
+			last.Sources = append(last.Sources, level.Sources...)
+			continue
+		}
+		merged = append(merged, level)
+	}
+
+	for i := range merged {
+		merged[i].Sources = uniqueStrings(merged[i].Sources)
+	}
+	return merged
+}
+
+// uniqueStrings 去重并保持顺序
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := values[:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// formatLevels 支撑/阻力位描述（用于把止损放在结构位之外、止盈放在下一个结构位之前）
+func formatLevels(levels *LevelsData) string {
+	format := func(items []Level) string {
+		if len(items) == 0 {
+			return "none"
+		}
+		parts := make([]string, 0, len(items))
+		for _, l := range items {
+			parts = append(parts, fmt.Sprintf("%.4f (%+.2f%%, %s)", l.Price, l.DistancePct, strings.Join(l.Sources, " + ")))
+		}
+		return strings.Join(parts, ", ")
+	}
+	return fmt.Sprintf("Key levels (%s structure): resistance %s | support %s",
+		intervalLabel(levels.Interval, Interval4h), format(levels.Resistances), format(levels.Supports))
+}
@@ -0,0 +1,177 @@
+package market
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// liquidationWindow 强平记录保留时长（统计最长窗口）
+const liquidationWindow = time.Hour
+
+// LiquidationData 近期强平统计（USDT名义价值）
+type LiquidationData struct {
+	Long5m     float64 // 5分钟内多头被强平金额
+	Short5m    float64 // 5分钟内空头被强平金额
+	Long1h     float64 // 1小时内多头被强平金额
+	Short1h    float64 // 1小时内空头被强平金额
+	Count1h    int     // 1小时内强平笔数
+	LargestUSD float64 // 1小时内单笔最大强平金额
+	CoveredMin int     // 强平流已运行的分钟数（不足60时1小时统计不完整）
+}
+
+// liquidationEvent 单笔强平
+type liquidationEvent struct {
+	Time     time.Time
+	Long     bool // 多头被强平（强平单为卖出）
+	Notional float64
+}
+
+// liquidationFeed 全市场强平流（按币种保存最近1小时的强平记录）
+type liquidationFeed struct {
+	mu      sync.RWMutex
+	events  map[string][]liquidationEvent
+	started time.Time
+}
+
+var (
+	liquidationMu     sync.RWMutex
+	activeLiquidation *liquidationFeed
+)
+
+// EnableLiquidationFeed 订阅全市场强平流（进程内共享，重复调用无效），断线自动重连
+func EnableLiquidationFeed() error {
+	liquidationMu.Lock()
+	defer liquidationMu.Unlock()
+	if activeLiquidation != nil {
+		return nil
+	}
+
+	feed := &liquidationFeed{events: make(map[string][]liquidationEvent), started: time.Now()}
+	setWsProxy()
+	if err := feed.connect(); err != nil {
+		return err
+	}
+	activeLiquidation = feed
+	log.Printf("💥 已订阅全市场强平流")
+	return nil
+}
+
+// recentLiquidations 币种近期强平统计（未订阅强平流时为nil）
+func recentLiquidations(symbol string) *LiquidationData {
+	liquidationMu.RLock()
+	feed := activeLiquidation
+	liquidationMu.RUnlock()
+	if feed == nil {
+		return nil
+	}
+	return feed.summary(symbol, time.Now())
+}
+
+// connect 建立强平流连接
+func (f *liquidationFeed) connect() error {
+	doneC, _, err := futures.WsAllLiquidationOrderServe(f.handle, func(err error) {
+		log.Printf("⚠ 强平WebSocket错误: %v", err)
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-doneC
+		for {
+			log.Printf("🔌 强平WebSocket断开，%v后重连", streamReconnectDelay)
+			time.Sleep(streamReconnectDelay)
+			if err := f.connect(); err == nil {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// handle 记录强平单（卖出强平的是多头，买入强平的是空头）
+func (f *liquidationFeed) handle(event *futures.WsLiquidationOrderEvent) {
+	order := event.LiquidationOrder
+	price, _ := strconv.ParseFloat(order.AvgPrice, 64)
+	quantity, _ := strconv.ParseFloat(order.AccumulatedFilledQty, 64)
+	if price <= 0 || quantity <= 0 {
+		price, _ = strconv.ParseFloat(order.Price, 64)
+		quantity, _ = strconv.ParseFloat(order.OrigQuantity, 64)
+	}
+	if price <= 0 || quantity <= 0 {
+		return
+	}
+
+	f.record(order.Symbol, liquidationEvent{
+		Time:     time.UnixMilli(order.TradeTime),
+		Long:     order.Side == futures.SideTypeSell,
+		Notional: price * quantity,
+	})
+}
+
+// record 保存强平记录并清理超出窗口的旧记录
+func (f *liquidationFeed) record(symbol string, event liquidationEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cutoff := event.Time.Add(-liquidationWindow)
+	events := f.events[symbol]
+	start := 0
+	for start < len(events) && events[start].Time.Before(cutoff) {
+		start++
+	}
+	f.events[symbol] = append(events[start:], event)
+}
+
+// summary 统计5分钟和1小时窗口内的强平金额
+func (f *liquidationFeed) summary(symbol string, now time.Time) *LiquidationData {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	data := &LiquidationData{CoveredMin: int(now.Sub(f.started).Minutes())}
+	if data.CoveredMin > int(liquidationWindow.Minutes()) {
+		data.CoveredMin = int(liquidationWindow.Minutes())
+	}
+	for _, event := range f.events[symbol] {
+		age := now.Sub(event.Time)
+		if age > liquidationWindow {
+			continue
+		}
+		if event.Long {
+			data.Long1h += event.Notional
+		} else {
+			data.Short1h += event.Notional
+		}
+		data.Count1h++
+		if event.Notional > data.LargestUSD {
+			data.LargestUSD = event.Notional
+		}
+		if age <= 5*time.Minute {
+			if event.Long {
+				data.Long5m += event.Notional
+			} else {
+				data.Short5m += event.Notional
+			}
+		}
+	}
+	return data
+}
+
+// formatLiquidations 强平统计描述（多头强平集中往往意味着下跌瀑布，空头强平意味着轧空）
+func formatLiquidations(data *LiquidationData) string {
+	coverage := ""
+	if data.CoveredMin < int(liquidationWindow.Minutes()) {
+		coverage = fmt.Sprintf(" (feed live for %d min only)", data.CoveredMin)
+	}
+	if data.Count1h == 0 {
+		return "Liquidations: none in the last 1h" + coverage
+	}
+	return fmt.Sprintf("Liquidations (longs / shorts liquidated, USDT): 5m %s / %s | 1h %s / %s | %d orders in 1h, largest %s",
+		formatUSD(data.Long5m), formatUSD(data.Short5m), formatUSD(data.Long1h), formatUSD(data.Short1h),
+		data.Count1h, formatUSD(data.LargestUSD)) + coverage
+}
@@ -0,0 +1,121 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+)
+
+// 多空比统计周期：取最近13个5分钟数据点，比较最新值和1小时前的值
+const (
+	longShortPeriod = "5m"
+	longShortLimit  = 13
+)
+
+// LongShortData 多空比（账户/持仓的多空比例，大于1表示偏多）
+type LongShortData struct {
+	TopTraderRatio      float64 // 大户持仓多空比（按持仓量）
+	TopTraderLongPct    float64 // 大户多头持仓占比（百分比）
+	TopTraderRatio1hAgo float64 // 1小时前的大户持仓多空比（数据不足时为0）
+	GlobalRatio         float64 // 全市场账户多空比（按账户数）
+	GlobalLongPct       float64 // 全市场多头账户占比（百分比）
+	GlobalRatio1hAgo    float64 // 1小时前的全市场账户多空比（数据不足时为0）
+}
+
+// getLongShortRatio 从当前数据源获取多空比（带缓存）
+func getLongShortRatio(ctx context.Context, symbol string) (*LongShortData, error) {
+	source := activeSource()
+	return cached(ctx, CacheLongShort, source.Name()+"|"+symbol, func(ctx context.Context) (*LongShortData, error) {
+		return source.LongShortRatio(ctx, symbol)
+	})
+}
+
+// fetchLongShortRatio 从Binance获取大户持仓多空比和全市场账户多空比
+func fetchLongShortRatio(ctx context.Context, symbol string) (*LongShortData, error) {
+	top, err := fetchRatioSeries(ctx, "topLongShortPositionRatio", symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取大户多空比失败: %w", err)
+	}
+	global, err := fetchRatioSeries(ctx, "globalLongShortAccountRatio", symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取全市场多空比失败: %w", err)
+	}
+	if len(top) == 0 || len(global) == 0 {
+		return nil, fmt.Errorf("%s 多空比数据为空", symbol)
+	}
+
+	data := &LongShortData{
+		TopTraderRatio:   top[len(top)-1].ratio,
+		TopTraderLongPct: top[len(top)-1].longPct,
+		GlobalRatio:      global[len(global)-1].ratio,
+		GlobalLongPct:    global[len(global)-1].longPct,
+	}
+	if len(top) == longShortLimit {
+		data.TopTraderRatio1hAgo = top[0].ratio
+	}
+	if len(global) == longShortLimit {
+		data.GlobalRatio1hAgo = global[0].ratio
+	}
+	return data, nil
+}
+
+// ratioPoint 多空比数据点
+type ratioPoint struct {
+	ratio   float64
+	longPct float64
+}
+
+// fetchRatioSeries 获取多空比序列（按时间升序）
+func fetchRatioSeries(ctx context.Context, endpoint, symbol string) ([]ratioPoint, error) {
+	url := fmt.Sprintf("https://fapi.binance.com/futures/data/%s?symbol=%s&period=%s&limit=%d",
+		endpoint, symbol, longShortPeriod, longShortLimit)
+
+	resp, err := getContext(ctx, httpClient, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []struct {
+		LongShortRatio string `json:"longShortRatio"`
+		LongAccount    string `json:"longAccount"`
+		Timestamp      int64  `json:"timestamp"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	points := make([]ratioPoint, 0, len(result))
+	for _, item := range result {
+		ratio, _ := strconv.ParseFloat(item.LongShortRatio, 64)
+		longPct, _ := strconv.ParseFloat(item.LongAccount, 64)
+		points = append(points, ratioPoint{ratio: ratio, longPct: longPct * 100})
+	}
+	return points, nil
+}
+
+// formatLongShort 多空比描述（大户与散户方向背离时往往是反向信号；数据源没有大户多空比时只输出全市场）
+func formatLongShort(data *LongShortData) string {
+	if data.TopTraderRatio == 0 {
+		return fmt.Sprintf("Long/Short Ratio: all accounts %.2f (%.1f%% long)%s",
+			data.GlobalRatio, data.GlobalLongPct, ratioChange(data.GlobalRatio1hAgo))
+	}
+	return fmt.Sprintf("Long/Short Ratio: top traders (by position) %.2f (%.1f%% long)%s | all accounts %.2f (%.1f%% long)%s",
+		data.TopTraderRatio, data.TopTraderLongPct, ratioChange(data.TopTraderRatio1hAgo),
+		data.GlobalRatio, data.GlobalLongPct, ratioChange(data.GlobalRatio1hAgo))
+}
+
+// ratioChange 1小时前的多空比（无数据时为空）
+func ratioChange(hourAgo float64) string {
+	if hourAgo == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", 1h ago %.2f", hourAgo)
+}
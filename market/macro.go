@@ -0,0 +1,129 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 全市场数据来源（CoinGecko公开接口，无需API Key）
+const (
+	macroGlobalURL = "https://api.coingecko.com/api/v3/global"
+	macroTimeout   = 10 * time.Second
+	macroBenchmark = "BTCUSDT"
+)
+
+// macroClient 全市场数据使用的客户端（不占用Binance请求权重额度）
+var macroClient = &http.Client{Timeout: macroTimeout}
+
+// MacroData 全市场宏观数据
+type MacroData struct {
+	BTCDominance     float64 // BTC市值占比（百分比）
+	TotalMarketCap   float64 // 加密货币总市值（USD，TOTAL）
+	TotalChange24h   float64 // 总市值24小时变化（百分比）
+	Total2MarketCap  float64 // 除BTC外的总市值（USD，TOTAL2）
+	Total2Change24h  float64 // TOTAL2的24小时变化（百分比）
+	BTCPrice         float64 // BTC合约最新价
+	BTCPriceChange24 float64 // BTC 24小时涨跌幅（百分比）
+}
+
+// GetMacro 获取全市场宏观数据（带缓存）
+func GetMacro() (*MacroData, error) {
+	return cached(context.Background(), CacheMacro, "global", fetchMacro)
+}
+
+// fetchMacro 获取BTC市值占比、总市值和BTC 24小时涨跌幅
+func fetchMacro(ctx context.Context) (*MacroData, error) {
+	resp, err := getContext(ctx, macroClient, macroGlobalURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("全市场数据请求失败 (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Data struct {
+			TotalMarketCap        map[string]float64 `json:"total_market_cap"`
+			MarketCapPercentage   map[string]float64 `json:"market_cap_percentage"`
+			MarketCapChange24hUSD float64            `json:"market_cap_change_percentage_24h_usd"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	btcPrice, btcChange, err := fetchTicker24h(ctx, macroBenchmark)
+	if err != nil {
+		return nil, fmt.Errorf("获取BTC 24小时行情失败: %w", err)
+	}
+
+	macro := &MacroData{
+		BTCDominance:     result.Data.MarketCapPercentage["btc"],
+		TotalMarketCap:   result.Data.TotalMarketCap["usd"],
+		TotalChange24h:   result.Data.MarketCapChange24hUSD,
+		BTCPrice:         btcPrice,
+		BTCPriceChange24: btcChange,
+	}
+	macro.Total2MarketCap, macro.Total2Change24h = total2(macro)
+	return macro, nil
+}
+
+// total2 除BTC外的总市值及其24小时变化（用BTC价格涨跌幅近似BTC市值涨跌幅）
+func total2(m *MacroData) (float64, float64) {
+	btcCap := m.TotalMarketCap * m.BTCDominance / 100
+	total2 := m.TotalMarketCap - btcCap
+
+	prevTotal := m.TotalMarketCap / (1 + m.TotalChange24h/100)
+	prevBTC := btcCap / (1 + m.BTCPriceChange24/100)
+	prevTotal2 := prevTotal - prevBTC
+	if prevTotal2 <= 0 {
+		return total2, 0
+	}
+	return total2, (total2/prevTotal2 - 1) * 100
+}
+
+// fetchTicker24h 从Binance获取最新价和24小时涨跌幅
+func fetchTicker24h(ctx context.Context, symbol string) (float64, float64, error) {
+	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/ticker/24hr?symbol=%s", symbol)
+
+	resp, err := getContext(ctx, httpClient, url)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var result struct {
+		LastPrice          string `json:"lastPrice"`
+		PriceChangePercent string `json:"priceChangePercent"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, 0, err
+	}
+
+	price, _ := strconv.ParseFloat(result.LastPrice, 64)
+	change, _ := strconv.ParseFloat(result.PriceChangePercent, 64)
+	return price, change, nil
+}
+
+// FormatMacro 全市场宏观数据描述
+func FormatMacro(m *MacroData) string {
+	return fmt.Sprintf("BTC: %.2f (%+.2f%% 24h) | BTC dominance: %.2f%% | TOTAL market cap: $%s (%+.2f%% 24h) | TOTAL2 (ex-BTC): $%s (%+.2f%% 24h)",
+		m.BTCPrice, m.BTCPriceChange24, m.BTCDominance,
+		formatUSD(m.TotalMarketCap), m.TotalChange24h, formatUSD(m.Total2MarketCap), m.Total2Change24h)
+}
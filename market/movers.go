@@ -0,0 +1,48 @@
+package market
+
+import (
+	"context"
+	"fmt"
+)
+
+// 1小时涨跌幅使用5分钟K线计算（最新价相对12根K线前的收盘价）
+const (
+	moverInterval = Interval5m
+	moverBars1h   = 12
+)
+
+// Mover 币种的1小时和24小时涨跌幅
+type Mover struct {
+	Symbol         string
+	QuoteVolume24h float64 // 24小时成交额（USDT）
+	Change1hPct    float64 // 1小时涨跌幅（百分比，K线不足时为0）
+	Change24hPct   float64 // 24小时涨跌幅（百分比）
+}
+
+// GetMovers 24小时成交额前universe名的USDT合约及其1小时/24小时涨跌幅（只统计流动性较好的币种，避免小币种异常波动）
+func GetMovers(universe int) ([]Mover, error) {
+	leaders, err := topByQuoteVolume(universe)
+	if err != nil {
+		return nil, err
+	}
+
+	movers := make([]Mover, 0, len(leaders))
+	for _, leader := range leaders {
+		mover := Mover{
+			Symbol:         leader.Symbol,
+			QuoteVolume24h: leader.QuoteVolume24h,
+			Change24hPct:   leader.PriceChangePct,
+		}
+		klines, err := getKlines(context.Background(), leader.Symbol, string(moverInterval), moverBars1h+1)
+		if err == nil && len(klines) == moverBars1h+1 {
+			if prev := klines[0].Close; prev > 0 {
+				mover.Change1hPct = (klines[len(klines)-1].Close - prev) / prev * 100
+			}
+		}
+		movers = append(movers, mover)
+	}
+	if len(movers) == 0 {
+		return nil, fmt.Errorf("没有可用的24小时行情")
+	}
+	return movers, nil
+}
@@ -0,0 +1,45 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NewListing 新上线的永续合约
+type NewListing struct {
+	Symbol         string
+	ListedAt       time.Time
+	QuoteVolume24h float64 // 24小时成交额（USDT，获取失败时为0）
+}
+
+// GetNewListings 上线时间在maxAge以内、正在交易的USDT永续合约（按上线时间从新到旧）
+func GetNewListings(now time.Time, maxAge time.Duration) ([]NewListing, error) {
+	source := activeSource()
+	all, err := cached(context.Background(), CacheExchangeInfo, source.Name(), source.ExchangeInfo)
+	if err != nil {
+		return nil, fmt.Errorf("获取交易规则失败: %w", err)
+	}
+	tickers, _ := getAllTickers24h(context.Background(), source) // 成交额只用于流动性过滤，获取失败不影响识别
+
+	cutoff := now.Add(-maxAge)
+	var listings []NewListing
+	for symbol, info := range all {
+		if info.OnboardDate == 0 || info.ContractType != "PERPETUAL" || info.Status != "TRADING" || !strings.HasSuffix(symbol, "USDT") {
+			continue
+		}
+		listedAt := time.UnixMilli(info.OnboardDate)
+		if listedAt.Before(cutoff) || listedAt.After(now) {
+			continue
+		}
+		listing := NewListing{Symbol: symbol, ListedAt: listedAt}
+		if ticker := tickers[symbol]; ticker != nil {
+			listing.QuoteVolume24h = ticker.QuoteVolume
+		}
+		listings = append(listings, listing)
+	}
+	sort.Slice(listings, func(i, j int) bool { return listings[i].ListedAt.After(listings[j].ListedAt) })
+	return listings, nil
+}
@@ -0,0 +1,177 @@
+package market
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 可选指标（按trader配置计算并输出）
+const (
+	IndicatorOBV        = "obv"
+	IndicatorStochastic = "stochastic"
+	IndicatorIchimoku   = "ichimoku"
+)
+
+// OptionalIndicators 可配置的可选指标及说明
+var OptionalIndicators = map[string]string{
+	IndicatorOBV:        "On-balance volume",
+	IndicatorStochastic: "Stochastic oscillator (14, 3)",
+	IndicatorIchimoku:   "Ichimoku cloud (9, 26, 52)",
+}
+
+// IndicatorSeries 可选指标序列（未启用或数据不足的指标为空）
+type IndicatorSeries struct {
+	OBVValues     []float64 // 能量潮（从获取的第一根K线开始累计，看趋势而非绝对值）
+	StochKValues  []float64 // 随机指标%K（14期）
+	StochDValues  []float64 // 随机指标%D（%K的3期均值）
+	TenkanValues  []float64 // 转换线（9期）
+	KijunValues   []float64 // 基准线（26期）
+	SenkouAValues []float64 // 先行带A（未平移，对应26期后的云层）
+	SenkouBValues []float64 // 先行带B（52期，未平移）
+}
+
+// hasIndicator 指标是否启用
+func hasIndicator(indicators []string, name string) bool {
+	for _, indicator := range indicators {
+		if indicator == name {
+			return true
+		}
+	}
+	return false
+}
+
+// calculateOptionalSeries 计算启用的可选指标最近10个点（未启用任何指标时返回nil）
+func calculateOptionalSeries(klines []Kline, indicators []string) *IndicatorSeries {
+	if len(indicators) == 0 || len(klines) == 0 {
+		return nil
+	}
+
+	series := &IndicatorSeries{}
+	start := len(klines) - 10
+	if start < 0 {
+		start = 0
+	}
+
+	if hasIndicator(indicators, IndicatorOBV) {
+		obv := 0.0
+		for i := 1; i < len(klines); i++ {
+			switch {
+			case klines[i].Close > klines[i-1].Close:
+				obv += klines[i].Volume
+			case klines[i].Close < klines[i-1].Close:
+				obv -= klines[i].Volume
+			}
+			if i >= start {
+				series.OBVValues = append(series.OBVValues, obv)
+			}
+		}
+	}
+
+	if hasIndicator(indicators, IndicatorStochastic) {
+		for i := start; i < len(klines); i++ {
+			if i >= 13 {
+				series.StochKValues = append(series.StochKValues, calculateStochK(klines[:i+1], 14))
+			}
+			if i >= 15 {
+				d := 0.0
+				for j := i - 2; j <= i; j++ {
+					d += calculateStochK(klines[:j+1], 14)
+				}
+				series.StochDValues = append(series.StochDValues, d/3)
+			}
+		}
+	}
+
+	if hasIndicator(indicators, IndicatorIchimoku) {
+		for i := start; i < len(klines); i++ {
+			window := klines[:i+1]
+			if i >= 8 {
+				series.TenkanValues = append(series.TenkanValues, midpoint(window, 9))
+			}
+			if i >= 25 {
+				tenkan, kijun := midpoint(window, 9), midpoint(window, 26)
+				series.KijunValues = append(series.KijunValues, kijun)
+				series.SenkouAValues = append(series.SenkouAValues, (tenkan+kijun)/2)
+			}
+			if i >= 51 {
+				series.SenkouBValues = append(series.SenkouBValues, midpoint(window, 52))
+			}
+		}
+	}
+
+	return series
+}
+
+// calculateStochK 计算随机指标%K：收盘价在最近period根K线高低区间中的位置（0-100）
+func calculateStochK(klines []Kline, period int) float64 {
+	if len(klines) < period {
+		return 0
+	}
+	high, low := highLow(klines[len(klines)-period:])
+	if high == low {
+		return 50
+	}
+	return (klines[len(klines)-1].Close - low) / (high - low) * 100
+}
+
+// midpoint 最近period根K线最高价和最低价的中点（一目均衡表各线的计算方式）
+func midpoint(klines []Kline, period int) float64 {
+	high, low := highLow(klines[len(klines)-period:])
+	return (high + low) / 2
+}
+
+// highLow K线区间的最高价和最低价
+func highLow(klines []Kline) (float64, float64) {
+	high, low := klines[0].High, klines[0].Low
+	for _, k := range klines[1:] {
+		if k.High > high {
+			high = k.High
+		}
+		if k.Low < low {
+			low = k.Low
+		}
+	}
+	return high, low
+}
+
+// writeOptionalSeries 输出可选指标序列（空序列不输出）
+func writeOptionalSeries(sb *strings.Builder, series *IndicatorSeries) {
+	if series == nil {
+		return
+	}
+	if len(series.OBVValues) > 0 {
+		sb.WriteString(fmt.Sprintf("OBV (cumulative from window start): %s\n\n", formatFloatSlice(series.OBVValues)))
+	}
+	if len(series.StochKValues) > 0 {
+		sb.WriteString(fmt.Sprintf("Stochastic %%K (14): %s\n\n", formatFloatSlice(series.StochKValues)))
+	}
+	if len(series.StochDValues) > 0 {
+		sb.WriteString(fmt.Sprintf("Stochastic %%D (3): %s\n\n", formatFloatSlice(series.StochDValues)))
+	}
+	if len(series.TenkanValues) > 0 {
+		sb.WriteString(fmt.Sprintf("Ichimoku Tenkan‑sen (9): %s\n\n", formatFloatSlice(series.TenkanValues)))
+	}
+	if len(series.KijunValues) > 0 {
+		sb.WriteString(fmt.Sprintf("Ichimoku Kijun‑sen (26): %s\n\n", formatFloatSlice(series.KijunValues)))
+	}
+	if len(series.SenkouAValues) > 0 {
+		sb.WriteString(fmt.Sprintf("Ichimoku Senkou Span A (cloud 26 periods ahead): %s\n\n", formatFloatSlice(series.SenkouAValues)))
+	}
+	if len(series.SenkouBValues) > 0 {
+		sb.WriteString(fmt.Sprintf("Ichimoku Senkou Span B (52, cloud 26 periods ahead): %s\n\n", formatFloatSlice(series.SenkouBValues)))
+	}
+}
+
+// writeOptionalSummary 紧凑格式的可选指标摘要
+func writeOptionalSummary(sb *strings.Builder, series *IndicatorSeries) {
+	if series == nil {
+		return
+	}
+	writeSeriesSummary(sb, "OBV", series.OBVValues)
+	writeSeriesSummary(sb, "Stoch %K", series.StochKValues)
+	writeSeriesSummary(sb, "Stoch %D", series.StochDValues)
+	writeSeriesSummary(sb, "Tenkan", series.TenkanValues)
+	writeSeriesSummary(sb, "Kijun", series.KijunValues)
+	writeSeriesSummary(sb, "Senkou A", series.SenkouAValues)
+	writeSeriesSummary(sb, "Senkou B", series.SenkouBValues)
+}
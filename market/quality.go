@@ -0,0 +1,86 @@
+package market
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// staleAfterIntervals 最新K线开盘时间超过该倍数的周期视为数据过期（最新一根为未收盘K线）
+const staleAfterIntervals = 2
+
+// checkKlines 检查K线是否连续、最新K线是否及时、价格是否有效。
+// 返回问题描述，以及是否严重到数据不可用（过期或价格为0）
+func checkKlines(klines []Kline, interval Interval, now time.Time) (issues []string, unreliable bool) {
+	if len(klines) == 0 {
+		return []string{fmt.Sprintf("%s: no klines", interval)}, true
+	}
+
+	zeroPrices := 0
+	for _, k := range klines {
+		if k.Open <= 0 || k.High <= 0 || k.Low <= 0 || k.Close <= 0 {
+			zeroPrices++
+		}
+	}
+	if zeroPrices > 0 {
+		issues = append(issues, fmt.Sprintf("%s: %d klines with zero price", interval, zeroPrices))
+		unreliable = true
+	}
+
+	d := interval.Duration()
+	if d == 0 {
+		return issues, unreliable
+	}
+
+	gaps, missing := 0, 0
+	for i := 1; i < len(klines); i++ {
+		if diff := time.Duration(klines[i].OpenTime-klines[i-1].OpenTime) * time.Millisecond; diff != d {
+			gaps++
+			if diff > d {
+				missing += int(diff/d) - 1
+			}
+		}
+	}
+	if gaps > 0 {
+		issues = append(issues, fmt.Sprintf("%s: %d gaps in kline sequence (%d candles missing)", interval, gaps, missing))
+	}
+
+	latest := time.UnixMilli(klines[len(klines)-1].OpenTime)
+	if age := now.Sub(latest); age > staleAfterIntervals*d {
+		issues = append(issues, fmt.Sprintf("%s: latest candle opened %s ago (stale)", interval, age.Round(time.Second)))
+		unreliable = true
+	}
+	return issues, unreliable
+}
+
+// checkDataQuality 检查计算市场数据所用的全部K线（额外周期只记录问题，不影响是否可用）
+func checkDataQuality(set klineSet, currentPrice float64) (issues []string, unreliable bool) {
+	now := time.Now()
+	if currentPrice <= 0 {
+		issues = append(issues, "current price is zero")
+		unreliable = true
+	}
+
+	intradayIssues, intradayUnreliable := checkKlines(set.intradayKlines, set.intraday, now)
+	longerIssues, longerUnreliable := checkKlines(set.longerKlines, set.longer, now)
+	issues = append(issues, intradayIssues...)
+	issues = append(issues, longerIssues...)
+	unreliable = unreliable || intradayUnreliable || longerUnreliable
+
+	for _, interval := range set.extras {
+		if klines, ok := set.extraKlines[interval]; ok {
+			extraIssues, _ := checkKlines(klines, Interval(interval), now)
+			issues = append(issues, extraIssues...)
+		}
+	}
+	return issues, unreliable
+}
+
+// formatDataIssues 数据质量提示（数据不可用时提醒AI不要依赖这些序列）
+func formatDataIssues(data *Data) string {
+	prefix := "⚠️ Data quality"
+	if data.Unreliable {
+		prefix = "⚠️ UNRELIABLE DATA (do not open new positions on it)"
+	}
+	return prefix + ": " + strings.Join(data.DataIssues, "; ")
+}
@@ -0,0 +1,231 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Binance合约IP请求权重限制（每分钟，按整分钟窗口重置）
+const (
+	binanceWeightLimit  = 2400
+	rateLimitSoftRatio  = 0.8              // 超过上限的80%后排队等待下一个窗口
+	rateLimitMaxWait    = 15 * time.Second // 需要等待更久时直接拒绝请求（避免拖住整个周期）
+	rateLimitBanDefault = time.Minute      // 429/418没有Retry-After时的退避时长
+)
+
+// RateLimitStat 请求权重限流统计
+type RateLimitStat struct {
+	Limit       int       `json:"limit"`        // 每分钟权重上限
+	SoftLimit   int       `json:"soft_limit"`   // 开始排队的权重
+	UsedWeight  int       `json:"used_weight"`  // 当前分钟已用权重（本地统计与服务器返回取较大值）
+	Requests    int64     `json:"requests"`     // 放行的请求数
+	Queued      int64     `json:"queued"`       // 因接近上限而等待的请求数
+	Shed        int64     `json:"shed"`         // 被拒绝的请求数
+	Throttled   int64     `json:"throttled"`    // 收到429/418的次数
+	BannedUntil time.Time `json:"banned_until"` // 被限流时的恢复时间
+}
+
+// weightLimiter 进程级请求权重限流器（行情数据和交易客户端共用同一IP额度）
+type weightLimiter struct {
+	mu          sync.Mutex
+	window      time.Time // 当前分钟窗口起点
+	used        int
+	bannedUntil time.Time
+	requests    int64
+	queued      int64
+	shed        int64
+	throttled   int64
+}
+
+var binanceLimiter = &weightLimiter{}
+
+// softLimit 开始排队的权重
+func softLimit() int {
+	return int(binanceWeightLimit * rateLimitSoftRatio)
+}
+
+// rollWindow 进入新的分钟窗口时清零（调用方需持有mu）
+func (l *weightLimiter) rollWindow(now time.Time) {
+	if window := now.Truncate(time.Minute); window.After(l.window) {
+		l.window = window
+		l.used = 0
+	}
+}
+
+// acquire 申请请求权重：额度充足直接放行，接近上限时等待下一个窗口（ctx取消时放弃），等待过久或被限流期间拒绝
+func (l *weightLimiter) acquire(ctx context.Context, weight int) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.rollWindow(now)
+
+		if now.Before(l.bannedUntil) {
+			l.shed++
+			until := l.bannedUntil
+			l.mu.Unlock()
+			return fmt.Errorf("Binance请求被限流，%s后恢复", until.Format("15:04:05"))
+		}
+		if l.used+weight <= softLimit() {
+			l.used += weight
+			l.requests++
+			l.mu.Unlock()
+			return nil
+		}
+
+		wait := l.window.Add(time.Minute).Sub(now)
+		if wait > rateLimitMaxWait {
+			l.shed++
+			used := l.used
+			l.mu.Unlock()
+			return fmt.Errorf("Binance请求权重接近上限（%d/%d），请求被拒绝", used, binanceWeightLimit)
+		}
+		l.queued++
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// observe 根据响应更新权重（服务器统计包含同一IP上其他进程的请求）并处理429/418
+func (l *weightLimiter) observe(resp *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if used, err := strconv.Atoi(resp.Header.Get("X-Mbx-Used-Weight-1m")); err == nil && used > l.used {
+		l.used = used
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusTeapot {
+		backoff := rateLimitBanDefault
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			backoff = time.Duration(seconds) * time.Second
+		}
+		l.bannedUntil = time.Now().Add(backoff)
+		l.throttled++
+		log.Printf("🚫 Binance返回%d（请求过于频繁），暂停请求%v", resp.StatusCode, backoff)
+	}
+}
+
+// RateLimitStats 当前限流统计
+func RateLimitStats() RateLimitStat {
+	l := binanceLimiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rollWindow(time.Now())
+
+	return RateLimitStat{
+		Limit:       binanceWeightLimit,
+		SoftLimit:   softLimit(),
+		UsedWeight:  l.used,
+		Requests:    l.requests,
+		Queued:      l.queued,
+		Shed:        l.shed,
+		Throttled:   l.throttled,
+		BannedUntil: l.bannedUntil,
+	}
+}
+
+// rateLimitedTransport 按请求权重限流的RoundTripper
+type rateLimitedTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip 发送请求前申请权重，收到响应后同步服务器统计
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := binanceLimiter.acquire(req.Context(), requestWeight(req.URL)); err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		binanceLimiter.observe(resp)
+	}
+	return resp, err
+}
+
+// NewRateLimitedClient 共享Binance权重额度的HTTP客户端（行情请求和币安交易客户端都应使用）
+func NewRateLimitedClient() *http.Client {
+	return &http.Client{Transport: &rateLimitedTransport{base: http.DefaultTransport}}
+}
+
+// httpClient 行情数据请求使用的客户端
+var httpClient = NewRateLimitedClient()
+
+// getContext 发送GET请求，ctx取消时中止（trader停止、周期超时）
+func getContext(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+// requestWeight Binance合约接口的请求权重（未列出的接口按1计算）
+func requestWeight(u *url.URL) int {
+	query := u.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	switch u.Path {
+	case "/fapi/v1/klines":
+		if limit == 0 {
+			limit = 500
+		}
+		switch {
+		case limit < 100:
+			return 1
+		case limit < 500:
+			return 2
+		case limit <= 1000:
+			return 5
+		}
+		return 10
+	case "/fapi/v1/depth":
+		if limit == 0 {
+			limit = 500
+		}
+		switch {
+		case limit <= 50:
+			return 2
+		case limit <= 100:
+			return 5
+		case limit <= 500:
+			return 10
+		}
+		return 20
+	case "/fapi/v1/premiumIndex":
+		if query.Get("symbol") == "" {
+			return 10
+		}
+		return 1
+	case "/fapi/v1/ticker/price":
+		if query.Get("symbol") == "" {
+			return 2
+		}
+		return 1
+	case "/fapi/v1/ticker/24hr":
+		if query.Get("symbol") == "" {
+			return 40
+		}
+		return 1
+	case "/fapi/v1/openOrders":
+		if query.Get("symbol") == "" {
+			return 40
+		}
+		return 1
+	case "/fapi/v2/account", "/fapi/v3/account", "/fapi/v2/balance", "/fapi/v3/balance",
+		"/fapi/v2/positionRisk", "/fapi/v3/positionRisk":
+		return 5
+	}
+	return 1
+}
@@ -0,0 +1,95 @@
+package market
+
+import (
+	"fmt"
+	"math"
+)
+
+// 市场状态
+const (
+	RegimeTrending = "trending" // 趋势行情（ADX高）
+	RegimeRanging  = "ranging"  // 震荡行情（ADX低）
+	RegimeVolatile = "volatile" // 波动率急剧放大（方向不明，优先于趋势/震荡判断）
+)
+
+// 市场状态判断阈值
+const (
+	regimeTrendADX       = 25.0 // ADX达到该值视为趋势行情
+	regimeATRExpansion   = 1.5  // ATR3/ATR14达到该值视为波动放大
+	regimeVolExpansion   = 1.8  // 近期实现波动率/基准实现波动率达到该值视为波动放大
+	regimeRecentReturns  = 10   // 近期实现波动率使用的收益率个数
+	regimeMinKlineLength = 30   // 计算ADX和基准波动率所需的最少K线数
+)
+
+// RegimeData 市场状态（基于长期周期K线计算）
+type RegimeData struct {
+	Label          string   // trending / ranging / volatile
+	Interval       Interval // 计算使用的K线周期
+	ADX            float64  // ADX14
+	ATRRatio       float64  // ATR3/ATR14（>1表示波动放大）
+	RealizedVolPct float64  // 近期每根K线收益率的标准差（百分比）
+	VolExpansion   float64  // 近期实现波动率/之前的实现波动率
+}
+
+// classifyRegime 根据ADX、ATR和实现波动率判断市场状态（K线不足时返回nil）
+func classifyRegime(klines []Kline, interval Interval) *RegimeData {
+	if len(klines) < regimeMinKlineLength {
+		return nil
+	}
+
+	regime := &RegimeData{
+		Interval: interval,
+		ADX:      calculateADX(klines, 14),
+	}
+	if atr14 := calculateATR(klines, 14); atr14 > 0 {
+		regime.ATRRatio = calculateATR(klines, 3) / atr14
+	}
+
+	returns := make([]float64, 0, len(klines)-1)
+	for i := 1; i < len(klines); i++ {
+		if klines[i-1].Close > 0 {
+			returns = append(returns, (klines[i].Close-klines[i-1].Close)/klines[i-1].Close*100)
+		}
+	}
+	if len(returns) > regimeRecentReturns {
+		recent := stdDev(returns[len(returns)-regimeRecentReturns:])
+		regime.RealizedVolPct = recent
+		if baseline := stdDev(returns[:len(returns)-regimeRecentReturns]); baseline > 0 {
+			regime.VolExpansion = recent / baseline
+		}
+	}
+
+	switch {
+	case regime.ATRRatio >= regimeATRExpansion || regime.VolExpansion >= regimeVolExpansion:
+		regime.Label = RegimeVolatile
+	case regime.ADX >= regimeTrendADX:
+		regime.Label = RegimeTrending
+	default:
+		regime.Label = RegimeRanging
+	}
+	return regime
+}
+
+// stdDev 总体标准差
+func stdDev(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(variance / float64(len(values)))
+}
+
+// FormatRegime 市场状态描述
+func FormatRegime(regime *RegimeData) string {
+	return fmt.Sprintf("Market Regime (%s): %s | ADX14 %.1f, ATR3/ATR14 %.2f, realized vol %.2f%% per bar (%.2fx prior)",
+		intervalLabel(regime.Interval, Interval4h), regime.Label, regime.ADX, regime.ATRRatio, regime.RealizedVolPct, regime.VolExpansion)
+}
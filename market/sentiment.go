@@ -0,0 +1,78 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 恐惧贪婪指数（alternative.me，每日更新）
+const (
+	fearGreedURL  = "https://api.alternative.me/fng/?limit=%d"
+	fearGreedDays = 7
+)
+
+// FearGreedData 加密货币恐惧贪婪指数（0=极度恐惧，100=极度贪婪）
+type FearGreedData struct {
+	Value          int    // 最新指数
+	Classification string // 最新分类（如 "Extreme Fear"）
+	History        []int  // 最近7天指数（旧→新，包含最新值）
+}
+
+// GetFearGreed 获取恐惧贪婪指数（带缓存）
+func GetFearGreed() (*FearGreedData, error) {
+	return cached(context.Background(), CacheFearGreed, "fng", fetchFearGreed)
+}
+
+// fetchFearGreed 获取最近7天的恐惧贪婪指数
+func fetchFearGreed(ctx context.Context) (*FearGreedData, error) {
+	resp, err := getContext(ctx, macroClient, fmt.Sprintf(fearGreedURL, fearGreedDays))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("恐惧贪婪指数请求失败 (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Data []struct {
+			Value          string `json:"value"`
+			Classification string `json:"value_classification"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("恐惧贪婪指数数据为空")
+	}
+
+	// 接口按新→旧返回
+	data := &FearGreedData{Classification: result.Data[0].Classification}
+	for i := len(result.Data) - 1; i >= 0; i-- {
+		value, _ := strconv.Atoi(result.Data[i].Value)
+		data.History = append(data.History, value)
+	}
+	data.Value = data.History[len(data.History)-1]
+	return data, nil
+}
+
+// FormatFearGreed 恐惧贪婪指数描述
+func FormatFearGreed(data *FearGreedData) string {
+	history := make([]string, len(data.History))
+	for i, v := range data.History {
+		history[i] = strconv.Itoa(v)
+	}
+	return fmt.Sprintf("Fear & Greed Index: %d (%s) | last %d days: [%s]",
+		data.Value, data.Classification, len(data.History), strings.Join(history, ", "))
+}
@@ -0,0 +1,195 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"nofx/proxy"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// SourceBinance 默认行情数据源（币安U本位合约）
+const SourceBinance = "binance"
+
+// Source 行情数据源（币种统一使用 BTCUSDT 格式，由数据源自行转换为交易所格式）。
+// 新增交易所数据源时实现该接口并在init中调用RegisterSource，通过配置 market_source 选择。
+// 请求应使用传入的ctx（trader停止或周期超时时取消）
+type Source interface {
+	Name() string
+	Klines(ctx context.Context, symbol string, interval Interval, limit int) ([]Kline, error)                       // 按时间升序，最后一根为未收盘K线
+	KlinesSince(ctx context.Context, symbol string, interval Interval, startTime int64, limit int) ([]Kline, error) // 开盘时间不早于startTime（毫秒）的K线，最多limit根
+	OpenInterest(ctx context.Context, symbol string) (*OIData, error)
+	PremiumIndex(ctx context.Context, symbol string) (*PremiumIndex, error) // 标记价格、指数价格和资金费率
+	Ticker(ctx context.Context, symbol string) (float64, error)             // 最新成交价
+	AllPremiumIndex(ctx context.Context) (map[string]*PremiumIndex, error)  // 全市场批量获取（不支持批量接口时返回错误，调用方回退到单币种请求）
+	AllTickers(ctx context.Context) (map[string]float64, error)             // 全市场最新成交价（同上）
+	AllTickers24h(ctx context.Context) (map[string]*Ticker24h, error)       // 全市场24小时成交额和涨跌幅（同上）
+	Depth(ctx context.Context, symbol string) (*DepthData, error)
+	LongShortRatio(ctx context.Context, symbol string) (*LongShortData, error)
+	SpotKlines(ctx context.Context, symbol string, interval Interval, limit int) ([]Kline, error) // 同名现货交易对的K线（用于计算合约溢价）
+	ExchangeInfo(ctx context.Context) (map[string]*SymbolInfo, error)                             // 所有交易对的价格/数量精度和最小下单量（不含杠杆分层）
+}
+
+var (
+	sourceMu  sync.RWMutex
+	sources   = map[string]Source{SourceBinance: binanceSource{}}
+	activeSrc = Source(binanceSource{})
+)
+
+// RegisterSource 注册行情数据源（同名覆盖）
+func RegisterSource(source Source) {
+	sourceMu.Lock()
+	defer sourceMu.Unlock()
+	sources[source.Name()] = source
+}
+
+// SetSource 选择行情数据源（进程内共享，应在启动时调用）
+func SetSource(name string) error {
+	sourceMu.Lock()
+	defer sourceMu.Unlock()
+	source, ok := sources[name]
+	if !ok {
+		return fmt.Errorf("未知的行情数据源 %q（可选: %v）", name, sourceNamesLocked())
+	}
+	activeSrc = source
+	return nil
+}
+
+// HasSource 数据源是否已注册
+func HasSource(name string) bool {
+	sourceMu.RLock()
+	defer sourceMu.RUnlock()
+	_, ok := sources[name]
+	return ok
+}
+
+// SourceNames 已注册的行情数据源名称
+func SourceNames() []string {
+	sourceMu.RLock()
+	defer sourceMu.RUnlock()
+	return sourceNamesLocked()
+}
+
+func sourceNamesLocked() []string {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SourceName 当前行情数据源名称
+func SourceName() string {
+	return activeSource().Name()
+}
+
+// activeSource 当前行情数据源
+func activeSource() Source {
+	sourceMu.RLock()
+	defer sourceMu.RUnlock()
+	return activeSrc
+}
+
+// GetPrice 获取最新成交价（优先使用全市场批量数据，没有该币种时单独请求）
+func GetPrice(symbol string) (float64, error) {
+	symbol = Canonical(symbol)
+	source := activeSource()
+	if all, err := getAllTickers(context.Background(), source); err == nil {
+		if price, ok := all[symbol]; ok {
+			return price, nil
+		}
+	}
+	return source.Ticker(context.Background(), symbol)
+}
+
+// binanceHosts 币安行情接口（合约REST、现货REST、WebSocket），按交易所类别选择代理
+var binanceHosts = []string{"fapi.binance.com", "api.binance.com", binanceStreamHost}
+
+func init() {
+	for _, host := range binanceHosts {
+		proxy.Register(proxy.KindExchange, host)
+	}
+}
+
+// binanceSource 币安合约REST数据源
+type binanceSource struct{}
+
+func (binanceSource) Name() string { return SourceBinance }
+
+func (binanceSource) Klines(ctx context.Context, symbol string, interval Interval, limit int) ([]Kline, error) {
+	return fetchKlines(ctx, symbol, string(interval), limit)
+}
+
+func (binanceSource) KlinesSince(ctx context.Context, symbol string, interval Interval, startTime int64, limit int) ([]Kline, error) {
+	return fetchKlinesSince(ctx, symbol, string(interval), startTime, limit)
+}
+
+func (binanceSource) OpenInterest(ctx context.Context, symbol string) (*OIData, error) {
+	return fetchOpenInterestData(ctx, symbol)
+}
+
+func (binanceSource) PremiumIndex(ctx context.Context, symbol string) (*PremiumIndex, error) {
+	return fetchPremiumIndex(ctx, symbol)
+}
+
+func (binanceSource) Ticker(ctx context.Context, symbol string) (float64, error) {
+	return fetchTicker(ctx, symbol)
+}
+
+func (binanceSource) AllPremiumIndex(ctx context.Context) (map[string]*PremiumIndex, error) {
+	return fetchAllPremiumIndex(ctx)
+}
+
+func (binanceSource) AllTickers(ctx context.Context) (map[string]float64, error) {
+	return fetchAllTickers(ctx)
+}
+
+func (binanceSource) AllTickers24h(ctx context.Context) (map[string]*Ticker24h, error) {
+	return fetchAllTickers24h(ctx)
+}
+
+func (binanceSource) Depth(ctx context.Context, symbol string) (*DepthData, error) {
+	return fetchDepth(ctx, symbol)
+}
+
+func (binanceSource) LongShortRatio(ctx context.Context, symbol string) (*LongShortData, error) {
+	return fetchLongShortRatio(ctx, symbol)
+}
+
+func (binanceSource) ExchangeInfo(ctx context.Context) (map[string]*SymbolInfo, error) {
+	return fetchExchangeInfo(ctx)
+}
+
+func (binanceSource) SpotKlines(ctx context.Context, symbol string, interval Interval, limit int) ([]Kline, error) {
+	return fetchSpotKlines(ctx, symbol, string(interval), limit)
+}
+
+// fetchTicker 从Binance获取最新成交价
+func fetchTicker(ctx context.Context, symbol string) (float64, error) {
+	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/ticker/price?symbol=%s", symbol)
+
+	resp, err := getContext(ctx, httpClient, url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Symbol string `json:"symbol"`
+		Price  string `json:"price"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseFloat(result.Price, 64)
+}
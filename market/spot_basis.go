@@ -0,0 +1,83 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// spotClient 现货行情请求使用的客户端（现货与合约的请求权重分开计算，不经过合约限流器）
+var spotClient = &http.Client{Timeout: 10 * time.Second}
+
+// SpotBasisData 合约相对现货的溢价/折价（正值表示合约价格高于现货，多头拥挤；负值表示空头拥挤）
+type SpotBasisData struct {
+	SpotPrice     float64   // 现货最新成交价
+	PremiumPct    float64   // 当前溢价：(合约价格-现货价格)/现货价格 的百分比
+	PremiumValues []float64 // 日内周期每根K线收盘时的溢价序列（旧→新）
+}
+
+// getSpotKlines 从当前数据源获取现货K线（带缓存，返回的切片为共享数据，调用方不可修改）
+func getSpotKlines(ctx context.Context, symbol string, interval Interval, limit int) ([]Kline, error) {
+	source := activeSource()
+	return cached(ctx, CacheSpotKlines, fmt.Sprintf("%s|%s|%s|%d", source.Name(), symbol, interval, limit), func(ctx context.Context) ([]Kline, error) {
+		return source.SpotKlines(ctx, symbol, interval, limit)
+	})
+}
+
+// fetchSpotKlines 从Binance现货获取K线数据（格式与合约K线相同）
+func fetchSpotKlines(ctx context.Context, symbol, interval string, limit int) ([]Kline, error) {
+	url := fmt.Sprintf("https://api.binance.com/api/v3/klines?symbol=%s&interval=%s&limit=%d",
+		symbol, interval, limit)
+	return fetchKlinesFrom(ctx, spotClient, url)
+}
+
+// attachSpotBasis 计算合约相对现货的溢价序列（没有对应现货交易对或获取失败时为nil）。
+// 合约序列取日内序列的收盘价，两边都是同一周期最近N根K线，按末尾对齐
+func attachSpotBasis(ctx context.Context, data *Data) {
+	if data.IntradaySeries == nil || len(data.IntradaySeries.MidPrices) == 0 {
+		return
+	}
+	perp := data.IntradaySeries.MidPrices
+	spot, err := getSpotKlines(ctx, data.Symbol, data.IntradayInterval, len(perp))
+	if err != nil || len(spot) == 0 {
+		return
+	}
+	// 现货最新K线与合约不在同一周期时无法对齐
+	if d := data.IntradayInterval.Duration(); d > 0 && time.Since(time.UnixMilli(spot[len(spot)-1].OpenTime)) > staleAfterIntervals*d {
+		return
+	}
+
+	n := len(perp)
+	if len(spot) < n {
+		n = len(spot)
+	}
+	perp, spot = perp[len(perp)-n:], spot[len(spot)-n:]
+
+	basis := &SpotBasisData{
+		SpotPrice:     spot[n-1].Close,
+		PremiumValues: make([]float64, 0, n),
+	}
+	for i := 0; i < n; i++ {
+		basis.PremiumValues = append(basis.PremiumValues, premiumPct(perp[i], spot[i].Close))
+	}
+	basis.PremiumPct = premiumPct(data.CurrentPrice, basis.SpotPrice)
+	data.SpotBasis = basis
+}
+
+// premiumPct 合约相对现货的溢价百分比（现货价格无效时为0）
+func premiumPct(perpPrice, spotPrice float64) float64 {
+	if spotPrice <= 0 {
+		return 0
+	}
+	return (perpPrice - spotPrice) / spotPrice * 100
+}
+
+// formatSpotBasis 现货溢价描述
+func formatSpotBasis(basis *SpotBasisData) string {
+	side := "premium"
+	if basis.PremiumPct < 0 {
+		side = "discount"
+	}
+	return fmt.Sprintf("Spot–perp basis: perp at %+.3f%% %s to spot (spot %.4f)", basis.PremiumPct, side, basis.SpotPrice)
+}
@@ -0,0 +1,108 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// 稳定币美元价格（CoinGecko，保证金和盈亏都以稳定币计价，脱锚直接影响账户价值）
+const stablecoinURL = "https://api.coingecko.com/api/v3/simple/price?ids=%s&vs_currencies=usd"
+
+// stablecoinIDs 监控的稳定币（代码 -> CoinGecko ID）
+var stablecoinIDs = map[string]string{
+	"USDT": "tether",
+	"USDC": "usd-coin",
+}
+
+// StablecoinData 稳定币美元价格
+type StablecoinData struct {
+	Prices map[string]float64 // 代码 -> 美元价格
+}
+
+// Deviation 偏离1美元最多的稳定币及偏离百分比（负值表示低于1美元）
+func (s *StablecoinData) Deviation() (coin string, pct float64) {
+	coins := make([]string, 0, len(s.Prices))
+	for c := range s.Prices {
+		coins = append(coins, c)
+	}
+	sort.Strings(coins)
+	for _, c := range coins {
+		if d := (s.Prices[c] - 1) * 100; math.Abs(d) > math.Abs(pct) {
+			coin, pct = c, d
+		}
+	}
+	return coin, pct
+}
+
+// Depegged 是否有稳定币偏离1美元超过thresholdPct
+func (s *StablecoinData) Depegged(thresholdPct float64) bool {
+	_, pct := s.Deviation()
+	return math.Abs(pct) >= thresholdPct
+}
+
+// GetStablecoins 获取稳定币美元价格（带缓存）
+func GetStablecoins() (*StablecoinData, error) {
+	return cached(context.Background(), CacheStablecoin, "usd", fetchStablecoins)
+}
+
+// fetchStablecoins 从CoinGecko获取稳定币美元价格
+func fetchStablecoins(ctx context.Context) (*StablecoinData, error) {
+	ids := make([]string, 0, len(stablecoinIDs))
+	for _, id := range stablecoinIDs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	resp, err := getContext(ctx, macroClient, fmt.Sprintf(stablecoinURL, strings.Join(ids, ",")))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("稳定币价格请求失败 (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result map[string]struct {
+		USD float64 `json:"usd"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	data := &StablecoinData{Prices: make(map[string]float64, len(stablecoinIDs))}
+	for coin, id := range stablecoinIDs {
+		if price, ok := result[id]; ok && price.USD > 0 {
+			data.Prices[coin] = price.USD
+		}
+	}
+	if len(data.Prices) == 0 {
+		return nil, fmt.Errorf("稳定币价格数据为空")
+	}
+	return data, nil
+}
+
+// FormatStablecoins 稳定币价格描述（按代码排序）
+func FormatStablecoins(s *StablecoinData) string {
+	coins := make([]string, 0, len(s.Prices))
+	for c := range s.Prices {
+		coins = append(coins, c)
+	}
+	sort.Strings(coins)
+
+	parts := make([]string, 0, len(coins))
+	for _, c := range coins {
+		parts = append(parts, fmt.Sprintf("%s $%.4f (%+.2f%%)", c, s.Prices[c], (s.Prices[c]-1)*100))
+	}
+	return "Stablecoins: " + strings.Join(parts, " | ")
+}
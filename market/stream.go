@@ -0,0 +1,404 @@
+package market
+
+import (
+	"context"
+	"log"
+	"nofx/proxy"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+const (
+	streamStaleAfter     = 60 * time.Second // 超过该时间没有收到K线推送视为断流，回退到REST
+	streamReconnectDelay = 5 * time.Second  // 断线重连间隔
+	streamOIRefresh      = time.Minute      // OI没有WebSocket推送，后台定时通过REST刷新
+	streamIdleTimeout    = 30 * time.Minute // 超过该时间未被读取的币种取消订阅
+	streamKlineLimit     = klineLimitLonger // 每个周期在内存中保留的K线数量（同一周期可能用作日内、长期或额外序列）
+)
+
+// Stream WebSocket行情流：在内存中维护K线、标记价格/资金费率和OI，Get直接读取内存
+type Stream struct {
+	mu      sync.Mutex
+	symbols map[string]*symbolStream
+
+	markMu  sync.RWMutex
+	premium map[string]PremiumIndex // 标记价格、指数价格和资金费率（来自全市场标记价格流）
+	markAt  time.Time               // 最近一次标记价格推送时间
+
+	oiMu sync.RWMutex
+	oi   map[string]*OIData
+}
+
+// symbolStream 单个币种的K线订阅（一个连接订阅该币种的所有周期）
+type symbolStream struct {
+	symbol string
+
+	subMu      sync.Mutex // 串行化初始化和重连
+	generation int        // 每次重连递增，用于区分主动关闭和断线
+	stopC      chan struct{}
+
+	mu        sync.RWMutex
+	klines    map[string][]Kline // interval -> K线
+	lastEvent time.Time
+	lastRead  time.Time
+}
+
+var (
+	streamMu      sync.RWMutex
+	defaultStream *Stream
+)
+
+// binanceStreamHost 币安合约WebSocket主机
+const binanceStreamHost = "fstream.binance.com"
+
+// setWsProxy WebSocket连接使用与币安行情接口相同的代理（go-binance只支持全局设置）
+func setWsProxy() {
+	futures.SetWsProxyUrl(proxy.URLForHost(binanceStreamHost))
+}
+
+// EnableStream 启用WebSocket行情流（进程内共享，重复调用无效）
+func EnableStream() error {
+	streamMu.Lock()
+	defer streamMu.Unlock()
+	if defaultStream != nil {
+		return nil
+	}
+
+	s := &Stream{
+		symbols: make(map[string]*symbolStream),
+		premium: make(map[string]PremiumIndex),
+		oi:      make(map[string]*OIData),
+	}
+	setWsProxy()
+	if err := s.connectMarkPrice(); err != nil {
+		return err
+	}
+	go s.maintain()
+
+	defaultStream = s
+	log.Printf("📡 已启用WebSocket行情流（K线、标记价格、资金费率实时推送，OI每%v刷新）", streamOIRefresh)
+	return nil
+}
+
+// activeStream 当前启用的行情流（未启用时为nil）
+func activeStream() *Stream {
+	streamMu.RLock()
+	defer streamMu.RUnlock()
+	return defaultStream
+}
+
+// get 从内存读取市场数据；首次请求的币种/周期会先通过REST初始化再订阅。
+// 行情流断开或初始化失败时返回false，由调用方回退到REST
+func (s *Stream) get(symbol string, intraday, longer Interval, extras, indicators []string) (*Data, bool) {
+	wanted := append([]string{string(intraday), string(longer)}, extras...)
+
+	ss, err := s.subscribe(symbol, wanted)
+	if err != nil {
+		log.Printf("⚠ %s WebSocket订阅失败，使用REST: %v", symbol, err)
+		return nil, false
+	}
+
+	ss.mu.Lock()
+	ss.lastRead = time.Now()
+	if time.Since(ss.lastEvent) > streamStaleAfter {
+		ss.mu.Unlock()
+		return nil, false
+	}
+	// 按用途截取与REST相同数量的K线，保证指标计算结果一致
+	set := klineSet{
+		intraday:       intraday,
+		longer:         longer,
+		intradayKlines: lastKlines(ss.klines[string(intraday)], klineLimitIntraday),
+		longerKlines:   lastKlines(ss.klines[string(longer)], klineLimitLonger),
+		extras:         extras,
+		extraKlines:    make(map[string][]Kline),
+	}
+	for _, interval := range extras {
+		set.extraKlines[interval] = lastKlines(ss.klines[interval], klineLimitExtra)
+	}
+	ss.mu.Unlock()
+
+	if len(set.intradayKlines) == 0 {
+		return nil, false
+	}
+
+	return buildData(symbol, set, indicators, s.openInterest(symbol), s.premiumIndex(symbol)), true
+}
+
+// lastKlines 最近n根K线的副本
+func lastKlines(klines []Kline, n int) []Kline {
+	if len(klines) > n {
+		klines = klines[len(klines)-n:]
+	}
+	return append([]Kline(nil), klines...)
+}
+
+// subscribe 确保币种已订阅所需周期（新增周期时以并集重新订阅）
+func (s *Stream) subscribe(symbol string, intervals []string) (*symbolStream, error) {
+	s.mu.Lock()
+	ss, exists := s.symbols[symbol]
+	if !exists {
+		ss = &symbolStream{symbol: symbol, klines: make(map[string][]Kline)}
+		s.symbols[symbol] = ss
+	}
+	s.mu.Unlock()
+
+	ss.subMu.Lock()
+	defer ss.subMu.Unlock()
+
+	ss.mu.RLock()
+	var missing []string
+	for _, interval := range intervals {
+		if _, ok := ss.klines[interval]; !ok {
+			missing = append(missing, interval)
+		}
+	}
+	ss.mu.RUnlock()
+	if len(missing) == 0 && ss.stopC != nil {
+		return ss, nil
+	}
+
+	if err := ss.seed(missing); err != nil {
+		return nil, err
+	}
+	if err := ss.connect(); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+// seed 通过REST初始化K线（订阅前和断线重连后补齐缺口）
+func (ss *symbolStream) seed(intervals []string) error {
+	for _, interval := range intervals {
+		klines, err := getKlines(context.Background(), ss.symbol, interval, streamKlineLimit)
+		if err != nil {
+			return err
+		}
+		ss.mu.Lock()
+		ss.klines[interval] = append([]Kline(nil), klines...) // 缓存中的切片是共享的，推送更新前先复制
+		ss.lastEvent = time.Now()
+		ss.mu.Unlock()
+	}
+	return nil
+}
+
+// connect 建立（或以当前所有周期重建）该币种的K线连接，调用方需持有subMu
+func (ss *symbolStream) connect() error {
+	ss.mu.RLock()
+	intervals := make([]string, 0, len(ss.klines))
+	for interval := range ss.klines {
+		intervals = append(intervals, interval)
+	}
+	ss.mu.RUnlock()
+	sort.Strings(intervals)
+
+	if ss.stopC != nil {
+		close(ss.stopC)
+		ss.stopC = nil
+	}
+	ss.generation++
+	generation := ss.generation
+
+	doneC, stopC, err := futures.WsCombinedKlineServeMultiInterval(
+		map[string][]string{ss.symbol: intervals},
+		ss.handleKline,
+		func(err error) { log.Printf("⚠ %s K线WebSocket错误: %v", ss.symbol, err) },
+	)
+	if err != nil {
+		return err
+	}
+	ss.stopC = stopC
+
+	go func() {
+		<-doneC
+		ss.reconnect(generation)
+	}()
+	return nil
+}
+
+// reconnect 连接意外断开后重新初始化并重连（主动关闭或已被新连接替换时忽略）
+func (ss *symbolStream) reconnect(generation int) {
+	for {
+		ss.subMu.Lock()
+		if ss.generation != generation || ss.stopC == nil {
+			ss.subMu.Unlock()
+			return
+		}
+		log.Printf("🔌 %s K线WebSocket断开，%v后重连", ss.symbol, streamReconnectDelay)
+		ss.subMu.Unlock()
+
+		time.Sleep(streamReconnectDelay)
+
+		ss.subMu.Lock()
+		if ss.generation != generation || ss.stopC == nil {
+			ss.subMu.Unlock()
+			return
+		}
+		ss.mu.RLock()
+		intervals := make([]string, 0, len(ss.klines))
+		for interval := range ss.klines {
+			intervals = append(intervals, interval)
+		}
+		ss.mu.RUnlock()
+
+		err := ss.seed(intervals)
+		if err == nil {
+			ss.stopC = nil // 旧连接已结束，无需关闭
+			err = ss.connect()
+		}
+		ss.subMu.Unlock()
+		if err == nil {
+			return
+		}
+		log.Printf("⚠ %s K线WebSocket重连失败: %v", ss.symbol, err)
+	}
+}
+
+// close 取消订阅
+func (ss *symbolStream) close() {
+	ss.subMu.Lock()
+	defer ss.subMu.Unlock()
+	if ss.stopC != nil {
+		close(ss.stopC)
+		ss.stopC = nil
+	}
+}
+
+// handleKline 用推送的K线更新（同一根K线替换，新K线追加并裁剪到固定长度）
+func (ss *symbolStream) handleKline(event *futures.WsKlineEvent) {
+	k := event.Kline
+	kline := Kline{OpenTime: k.StartTime, CloseTime: k.EndTime}
+	kline.Open, _ = strconv.ParseFloat(k.Open, 64)
+	kline.High, _ = strconv.ParseFloat(k.High, 64)
+	kline.Low, _ = strconv.ParseFloat(k.Low, 64)
+	kline.Close, _ = strconv.ParseFloat(k.Close, 64)
+	kline.Volume, _ = strconv.ParseFloat(k.Volume, 64)
+	kline.TakerBuyVolume, _ = strconv.ParseFloat(k.ActiveBuyVolume, 64)
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.lastEvent = time.Now()
+
+	klines := ss.klines[k.Interval]
+	n := len(klines)
+	switch {
+	case n > 0 && klines[n-1].OpenTime == kline.OpenTime:
+		klines[n-1] = kline
+	case n == 0 || kline.OpenTime > klines[n-1].OpenTime:
+		klines = append(klines, kline)
+		if len(klines) > streamKlineLimit {
+			klines = append([]Kline(nil), klines[len(klines)-streamKlineLimit:]...)
+		}
+		ss.klines[k.Interval] = klines
+	}
+}
+
+// connectMarkPrice 订阅全市场标记价格流（每秒推送，包含资金费率），断线自动重连
+func (s *Stream) connectMarkPrice() error {
+	doneC, _, err := futures.WsAllMarkPriceServeWithRate(time.Second, s.handleMarkPrice, func(err error) {
+		log.Printf("⚠ 标记价格WebSocket错误: %v", err)
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-doneC
+		for {
+			log.Printf("🔌 标记价格WebSocket断开，%v后重连", streamReconnectDelay)
+			time.Sleep(streamReconnectDelay)
+			if err := s.connectMarkPrice(); err == nil {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// handleMarkPrice 更新标记价格、指数价格和资金费率
+func (s *Stream) handleMarkPrice(events futures.WsAllMarkPriceEvent) {
+	s.markMu.Lock()
+	defer s.markMu.Unlock()
+	for _, event := range events {
+		var premium PremiumIndex
+		premium.MarkPrice, _ = strconv.ParseFloat(event.MarkPrice, 64)
+		premium.IndexPrice, _ = strconv.ParseFloat(event.IndexPrice, 64)
+		premium.FundingRate, _ = strconv.ParseFloat(event.FundingRate, 64)
+		s.premium[event.Symbol] = premium
+	}
+	s.markAt = time.Now()
+}
+
+// premiumIndex 标记价格、指数价格和资金费率（标记价格流断开时回退到REST）
+func (s *Stream) premiumIndex(symbol string) *PremiumIndex {
+	s.markMu.RLock()
+	premium, ok := s.premium[symbol]
+	fresh := time.Since(s.markAt) <= streamStaleAfter
+	s.markMu.RUnlock()
+	if ok && fresh {
+		return &premium
+	}
+	fetched, _ := getPremiumIndex(context.Background(), symbol)
+	return fetched
+}
+
+// openInterest OI（后台定时刷新，首次读取时通过REST获取）
+func (s *Stream) openInterest(symbol string) *OIData {
+	s.oiMu.RLock()
+	oi, ok := s.oi[symbol]
+	s.oiMu.RUnlock()
+	if ok {
+		return oi
+	}
+
+	oi, err := getOpenInterestData(context.Background(), symbol)
+	if err != nil {
+		// OI失败不影响整体,使用默认值
+		return &OIData{Latest: 0, Average: 0}
+	}
+	s.oiMu.Lock()
+	s.oi[symbol] = oi
+	s.oiMu.Unlock()
+	return oi
+}
+
+// maintain 定时刷新已订阅币种的OI，并取消长时间未被读取的币种订阅
+func (s *Stream) maintain() {
+	ticker := time.NewTicker(streamOIRefresh)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		active := make([]string, 0, len(s.symbols))
+		for symbol, ss := range s.symbols {
+			ss.mu.RLock()
+			idle := time.Since(ss.lastRead) > streamIdleTimeout
+			ss.mu.RUnlock()
+			if idle {
+				ss.close()
+				delete(s.symbols, symbol)
+				s.oiMu.Lock()
+				delete(s.oi, symbol)
+				s.oiMu.Unlock()
+				log.Printf("📡 %s 超过%v未使用，已取消WebSocket订阅", symbol, streamIdleTimeout)
+				continue
+			}
+			active = append(active, symbol)
+		}
+		s.mu.Unlock()
+
+		for _, symbol := range active {
+			oi, err := fetchOpenInterestData(context.Background(), symbol) // 绕过缓存，保证定时刷新拿到最新值
+			if err != nil {
+				continue
+			}
+			s.oiMu.Lock()
+			s.oi[symbol] = oi
+			s.oiMu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package market
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// quoteAssets 识别为计价币种的后缀（统一映射到USDT永续合约）
+var quoteAssets = []string{"USDT", "USDC", "FDUSD", "BUSD"}
+
+// multiplierPrefixes 低价币倍数合约前缀（如1000PEPEUSDT、1MBABYDOGEUSDT），按长度优先匹配
+var multiplierPrefixes = []string{"1000000", "1000", "1M"}
+
+// Normalize 标准化交易对格式（不检查交易所是否上线）
+// 去掉分隔符和结算/PERP后缀（PEPE/USDT:USDT、BTC-PERP），其他计价币种统一为USDT，Hyperliquid的kPEPE记为1000PEPE
+func Normalize(symbol string) string {
+	symbol = strings.TrimSpace(symbol)
+	// Hyperliquid用小写k表示1000倍（kPEPE、kBONK）
+	if len(symbol) > 1 && symbol[0] == 'k' && symbol[1] >= 'A' && symbol[1] <= 'Z' {
+		symbol = "1000" + symbol[1:]
+	}
+	symbol = strings.ToUpper(symbol)
+
+	// 带分隔符的写法只取基础币种部分（BTC/USDT、BTC-PERP、BTC_USDT）
+	if i := strings.IndexAny(symbol, "/-_:"); i > 0 {
+		symbol = symbol[:i]
+	}
+	return baseWithoutQuote(symbol) + "USDT"
+}
+
+// BaseAsset 交易对的基础币种（去掉计价币种和倍数合约前缀，如1000PEPEUSDT、kPEPE、PEPE/USDC -> PEPE）
+func BaseAsset(symbol string) string {
+	coin := strings.TrimSuffix(Normalize(symbol), "USDT")
+	for _, prefix := range multiplierPrefixes {
+		if rest := strings.TrimPrefix(coin, prefix); rest != coin && rest != "" {
+			return rest
+		}
+	}
+	return coin
+}
+
+// Multiplier 倍数合约的倍数（1000PEPEUSDT -> 1000，1MBABYDOGEUSDT -> 1000000，普通合约为1），
+// 用于在按基础币种计价的交易所之间换算数量和价格
+func Multiplier(symbol string) float64 {
+	coin := strings.TrimSuffix(Normalize(symbol), "USDT")
+	for _, prefix := range multiplierPrefixes {
+		if rest := strings.TrimPrefix(coin, prefix); rest != coin && rest != "" {
+			if prefix == "1M" {
+				return 1000000
+			}
+			multiplier, _ := strconv.ParseFloat(prefix, 64)
+			return multiplier
+		}
+	}
+	return 1
+}
+
+// Canonical 把任意写法的交易对映射为交易所实际上线的USDT永续合约（PEPE -> 1000PEPEUSDT，kBONK -> 1000BONKUSDT）
+// 交易规则不可用或找不到对应合约时返回Normalize的结果
+func Canonical(symbol string) string {
+	return canonical(context.Background(), symbol)
+}
+
+// canonical 同Canonical，ctx取消时中止交易规则请求
+func canonical(ctx context.Context, symbol string) string {
+	normalized := Normalize(symbol)
+	source := activeSource()
+	all, err := cached(ctx, CacheExchangeInfo, source.Name(), source.ExchangeInfo)
+	if err != nil {
+		return normalized
+	}
+	if _, ok := all[normalized]; ok {
+		return normalized
+	}
+
+	// 同一基础币种可能以不同倍数上线，优先可交易的合约
+	coin := BaseAsset(normalized)
+	var fallback string
+	for _, prefix := range append([]string{""}, multiplierPrefixes...) {
+		candidate := prefix + coin + "USDT"
+		info, ok := all[candidate]
+		if !ok {
+			continue
+		}
+		if info.Status == "" || info.Status == "TRADING" {
+			return candidate
+		}
+		if fallback == "" {
+			fallback = candidate
+		}
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return normalized
+}
+
+// baseWithoutQuote 去掉计价币种后缀（币种本身就是计价币种时保留，如USDC）
+func baseWithoutQuote(symbol string) string {
+	for _, quote := range quoteAssets {
+		if base := strings.TrimSuffix(symbol, quote); base != symbol && base != "" {
+			return base
+		}
+	}
+	return symbol
+}
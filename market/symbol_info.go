@@ -0,0 +1,185 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LeverageBracket 杠杆分层：名义价值不超过NotionalCap时最高可用MaxLeverage倍
+type LeverageBracket struct {
+	NotionalCap      float64 `json:"notional_cap"`
+	MaxLeverage      int     `json:"max_leverage"`
+	MaintMarginRatio float64 `json:"maint_margin_ratio"`
+}
+
+// SymbolInfo 交易对规则（价格/数量步进、最小下单量和杠杆分层）
+type SymbolInfo struct {
+	Symbol            string            `json:"symbol"`
+	TickSize          float64           `json:"tick_size"`    // 价格步进值
+	StepSize          float64           `json:"step_size"`    // 数量步进值
+	MinQty            float64           `json:"min_qty"`      // 最小下单数量
+	MinNotional       float64           `json:"min_notional"` // 最小下单名义价值（USDT）
+	PricePrecision    int               `json:"price_precision"`
+	QuantityPrecision int               `json:"quantity_precision"`
+	ContractType      string            `json:"contract_type,omitempty"` // 合约类型（PERPETUAL为永续合约）
+	Status            string            `json:"status,omitempty"`        // 交易状态（TRADING为可交易）
+	OnboardDate       int64             `json:"onboard_date,omitempty"`  // 上线时间（Unix毫秒）
+	Brackets          []LeverageBracket `json:"brackets,omitempty"`      // 按名义价值升序（需要交易账户API Key获取，未加载时为空）
+}
+
+// RoundPrice 价格四舍五入到tickSize的整数倍
+func (s *SymbolInfo) RoundPrice(price float64) float64 {
+	if s.TickSize <= 0 {
+		return price
+	}
+	return math.Round(price/s.TickSize) * s.TickSize
+}
+
+// FloorQuantity 数量向下取整到stepSize的整数倍（向上取整可能超出可用保证金）
+func (s *SymbolInfo) FloorQuantity(quantity float64) float64 {
+	if s.StepSize <= 0 {
+		return quantity
+	}
+	// 加一个极小值，避免 0.3/0.1 = 2.9999999 这类浮点误差被向下取整
+	return math.Floor(quantity/s.StepSize+1e-9) * s.StepSize
+}
+
+// FormatPrice 按tickSize格式化价格
+func (s *SymbolInfo) FormatPrice(price float64) string {
+	return strconv.FormatFloat(s.RoundPrice(price), 'f', stepPrecision(s.TickSize, s.PricePrecision), 64)
+}
+
+// FormatQuantity 按stepSize格式化数量（向下取整）
+func (s *SymbolInfo) FormatQuantity(quantity float64) string {
+	return strconv.FormatFloat(s.FloorQuantity(quantity), 'f', stepPrecision(s.StepSize, s.QuantityPrecision), 64)
+}
+
+// MaxLeverage 该名义价值可用的最高杠杆（未加载杠杆分层时返回0）
+func (s *SymbolInfo) MaxLeverage(notional float64) int {
+	for _, bracket := range s.Brackets {
+		if notional <= bracket.NotionalCap {
+			return bracket.MaxLeverage
+		}
+	}
+	return 0
+}
+
+// stepPrecision 步进值的小数位数（步进值无效时使用交易所给出的精度）
+func stepPrecision(step float64, fallback int) int {
+	if step <= 0 {
+		return fallback
+	}
+	s := strconv.FormatFloat(step, 'f', -1, 64)
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		return len(s) - dot - 1
+	}
+	return 0
+}
+
+var (
+	bracketsMu sync.RWMutex
+	brackets   = make(map[string][]LeverageBracket)
+)
+
+// SetLeverageBrackets 设置杠杆分层（由持有API Key的交易器加载后调用，同名币种覆盖）
+func SetLeverageBrackets(symbolBrackets map[string][]LeverageBracket) {
+	bracketsMu.Lock()
+	defer bracketsMu.Unlock()
+	for symbol, b := range symbolBrackets {
+		brackets[symbol] = b
+	}
+}
+
+// GetSymbolInfo 获取交易对规则（交易所规则带缓存，附加已加载的杠杆分层）
+func GetSymbolInfo(symbol string) (*SymbolInfo, error) {
+	symbol = Canonical(symbol)
+	source := activeSource()
+	all, err := cached(context.Background(), CacheExchangeInfo, source.Name(), source.ExchangeInfo)
+	if err != nil {
+		return nil, fmt.Errorf("获取交易规则失败: %w", err)
+	}
+	info, ok := all[symbol]
+	if !ok {
+		return nil, fmt.Errorf("未找到交易对 %s 的交易规则", symbol)
+	}
+
+	// 返回副本，缓存中的规则保持不变
+	result := *info
+	bracketsMu.RLock()
+	result.Brackets = brackets[symbol]
+	bracketsMu.RUnlock()
+	return &result, nil
+}
+
+// GetSymbolInfoFrom 从指定数据源获取交易对规则（交易所与行情数据源不同时，下单精度以交易所为准；不含杠杆分层）
+func GetSymbolInfoFrom(sourceName, symbol string) (*SymbolInfo, error) {
+	sourceMu.RLock()
+	source, ok := sources[sourceName]
+	sourceMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未知的行情数据源 %q", sourceName)
+	}
+	all, err := cached(context.Background(), CacheExchangeInfo, source.Name(), source.ExchangeInfo)
+	if err != nil {
+		return nil, fmt.Errorf("获取交易规则失败: %w", err)
+	}
+	info, ok := all[Normalize(symbol)]
+	if !ok {
+		return nil, fmt.Errorf("未找到交易对 %s 的交易规则", symbol)
+	}
+	result := *info
+	return &result, nil
+}
+
+// fetchExchangeInfo 从Binance获取所有合约的交易规则
+func fetchExchangeInfo(ctx context.Context) (map[string]*SymbolInfo, error) {
+	body, err := fetchBody(ctx, "https://fapi.binance.com/fapi/v1/exchangeInfo")
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Symbols []struct {
+			Symbol            string                   `json:"symbol"`
+			PricePrecision    int                      `json:"pricePrecision"`
+			QuantityPrecision int                      `json:"quantityPrecision"`
+			ContractType      string                   `json:"contractType"`
+			Status            string                   `json:"status"`
+			OnboardDate       int64                    `json:"onboardDate"`
+			Filters           []map[string]interface{} `json:"filters"`
+		} `json:"symbols"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	infos := make(map[string]*SymbolInfo, len(result.Symbols))
+	for _, s := range result.Symbols {
+		info := &SymbolInfo{
+			Symbol:            s.Symbol,
+			PricePrecision:    s.PricePrecision,
+			QuantityPrecision: s.QuantityPrecision,
+			ContractType:      s.ContractType,
+			Status:            s.Status,
+			OnboardDate:       s.OnboardDate,
+		}
+		for _, filter := range s.Filters {
+			switch filter["filterType"] {
+			case "PRICE_FILTER":
+				info.TickSize, _ = parseFloat(filter["tickSize"])
+			case "LOT_SIZE":
+				info.StepSize, _ = parseFloat(filter["stepSize"])
+				info.MinQty, _ = parseFloat(filter["minQty"])
+			case "MIN_NOTIONAL":
+				info.MinNotional, _ = parseFloat(filter["notional"])
+			}
+		}
+		infos[s.Symbol] = info
+	}
+	return infos, nil
+}
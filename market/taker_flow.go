@@ -0,0 +1,43 @@
+package market
+
+import (
+	"fmt"
+	"strings"
+)
+
+// calculateTakerFlow 计算从start开始每根K线的主动买入量、主动卖出量和累计成交量差（CVD）。
+// CVD从获取的第一根K线开始累计（看趋势和与价格的背离，而非绝对值）；数据源不提供主动买入量时返回nil
+func calculateTakerFlow(klines []Kline, start int) (buy, sell, cvd []float64) {
+	hasTakerData := false
+	for _, k := range klines {
+		if k.TakerBuyVolume > 0 {
+			hasTakerData = true
+			break
+		}
+	}
+	if !hasTakerData {
+		return nil, nil, nil
+	}
+
+	delta := 0.0
+	for i, k := range klines {
+		takerSell := k.Volume - k.TakerBuyVolume
+		delta += k.TakerBuyVolume - takerSell
+		if i >= start {
+			buy = append(buy, k.TakerBuyVolume)
+			sell = append(sell, takerSell)
+			cvd = append(cvd, delta)
+		}
+	}
+	return buy, sell, cvd
+}
+
+// writeTakerFlowSeries 输出主动买卖量和CVD序列（空序列不输出）
+func writeTakerFlowSeries(sb *strings.Builder, buy, sell, cvd []float64) {
+	if len(buy) == 0 {
+		return
+	}
+	sb.WriteString(fmt.Sprintf("Taker buy volume: %s\n\n", formatFloatSlice(buy)))
+	sb.WriteString(fmt.Sprintf("Taker sell volume: %s\n\n", formatFloatSlice(sell)))
+	sb.WriteString(fmt.Sprintf("Cumulative volume delta (from window start): %s\n\n", formatFloatSlice(cvd)))
+}
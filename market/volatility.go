@@ -0,0 +1,78 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// hoursPerYear 年化使用的小时数（加密货币全年交易）
+const hoursPerYear = 365 * 24
+
+// VolatilityData 波动率指标（百分比）
+type VolatilityData struct {
+	RealizedVol1h  float64 // 最近1小时实现波动率（年化，数据不足时为0）
+	RealizedVol24h float64 // 最近24小时实现波动率（年化，数据不足时为0）
+	ATRPct         float64 // 日内周期ATR14占当前价格的百分比
+	LongerATRPct   float64 // 长期周期ATR14占当前价格的百分比
+}
+
+// calculateVolatility 计算实现波动率和ATR百分比
+func calculateVolatility(set klineSet, currentPrice, longerATR14 float64) *VolatilityData {
+	vol := &VolatilityData{
+		RealizedVol1h:  realizedVolOver(time.Hour, set.intraday, set.intradayKlines, set.longer, set.longerKlines),
+		RealizedVol24h: realizedVolOver(24*time.Hour, set.intraday, set.intradayKlines, set.longer, set.longerKlines),
+	}
+	if currentPrice > 0 {
+		vol.ATRPct = calculateATR(set.intradayKlines, 14) / currentPrice * 100
+		vol.LongerATRPct = longerATR14 / currentPrice * 100
+	}
+	return vol
+}
+
+// realizedVolOver period内对数收益率的年化标准差（百分比）。
+// 按顺序使用第一个能覆盖period且至少有2个收益率的序列
+func realizedVolOver(period time.Duration, first Interval, firstKlines []Kline, second Interval, secondKlines []Kline) float64 {
+	candidates := []struct {
+		interval Interval
+		klines   []Kline
+	}{{first, firstKlines}, {second, secondKlines}}
+
+	for _, c := range candidates {
+		d := c.interval.Duration()
+		if d == 0 || d > period || period%d != 0 {
+			continue
+		}
+		bars := int(period / d)
+		if bars < 2 || len(c.klines) < bars+1 {
+			continue
+		}
+
+		window := c.klines[len(c.klines)-bars-1:]
+		returns := make([]float64, 0, bars)
+		for i := 1; i < len(window); i++ {
+			if window[i-1].Close > 0 && window[i].Close > 0 {
+				returns = append(returns, math.Log(window[i].Close/window[i-1].Close))
+			}
+		}
+		if len(returns) < 2 {
+			continue
+		}
+		barsPerYear := float64(hoursPerYear) * float64(time.Hour) / float64(d)
+		return stdDev(returns) * math.Sqrt(barsPerYear) * 100
+	}
+	return 0
+}
+
+// formatVolatility 波动率描述（供AI按波动率调整止损距离和仓位）
+func formatVolatility(vol *VolatilityData, intraday, longer Interval) string {
+	realized := func(v float64) string {
+		if v == 0 {
+			return "n/a"
+		}
+		return fmt.Sprintf("%.1f%%", v)
+	}
+	return fmt.Sprintf("Volatility: realized (annualized) 1h %s / 24h %s | ATR14 %.3f%% of price (%s) / %.3f%% (%s)",
+		realized(vol.RealizedVol1h), realized(vol.RealizedVol24h),
+		vol.ATRPct, intervalLabel(intraday, Interval3m), vol.LongerATRPct, intervalLabel(longer, Interval4h))
+}
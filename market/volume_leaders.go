@@ -0,0 +1,96 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 成交量加速计算参数（1小时K线：最近4根已收盘K线的平均成交量 / 之前20根的平均成交量）
+const (
+	volumeAccelInterval = Interval1h
+	volumeAccelRecent   = 4
+	volumeAccelBaseline = 20
+)
+
+// Ticker24h 24小时统计
+type Ticker24h struct {
+	QuoteVolume    float64 // 24小时成交额（USDT）
+	PriceChangePct float64 // 24小时涨跌幅（百分比）
+}
+
+// VolumeLeader 成交额排名靠前的币种
+type VolumeLeader struct {
+	Symbol         string
+	QuoteVolume24h float64 // 24小时成交额（USDT）
+	PriceChangePct float64 // 24小时涨跌幅（百分比）
+	Acceleration   float64 // 成交量加速倍数（最近4小时平均 / 之前20小时平均，K线不足时为0）
+}
+
+// GetVolumeLeaders 24小时成交额前shortlist名的USDT合约，按成交量加速倍数降序排列
+func GetVolumeLeaders(shortlist int) ([]VolumeLeader, error) {
+	leaders, err := topByQuoteVolume(shortlist)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range leaders {
+		klines, err := getKlines(context.Background(), leaders[i].Symbol, string(volumeAccelInterval), volumeAccelRecent+volumeAccelBaseline+1)
+		if err != nil {
+			continue
+		}
+		leaders[i].Acceleration = volumeAcceleration(klines)
+	}
+
+	// 成交量加速相同时按成交额排序
+	sort.SliceStable(leaders, func(i, j int) bool { return leaders[i].Acceleration > leaders[j].Acceleration })
+	return leaders, nil
+}
+
+// topByQuoteVolume 24小时成交额前n名的USDT合约（按成交额降序）
+func topByQuoteVolume(n int) ([]VolumeLeader, error) {
+	tickers, err := getAllTickers24h(context.Background(), activeSource())
+	if err != nil {
+		return nil, fmt.Errorf("获取24小时行情失败: %w", err)
+	}
+
+	leaders := make([]VolumeLeader, 0, len(tickers))
+	for symbol, ticker := range tickers {
+		if strings.HasSuffix(symbol, "USDT") && ticker.QuoteVolume > 0 {
+			leaders = append(leaders, VolumeLeader{
+				Symbol:         symbol,
+				QuoteVolume24h: ticker.QuoteVolume,
+				PriceChangePct: ticker.PriceChangePct,
+			})
+		}
+	}
+	sort.Slice(leaders, func(i, j int) bool { return leaders[i].QuoteVolume24h > leaders[j].QuoteVolume24h })
+	if len(leaders) > n {
+		leaders = leaders[:n]
+	}
+	return leaders, nil
+}
+
+// volumeAcceleration 最近几根已收盘K线的平均成交量相对之前的倍数（最后一根为未收盘K线，不参与计算）
+func volumeAcceleration(klines []Kline) float64 {
+	if len(klines) < volumeAccelRecent+volumeAccelBaseline+1 {
+		return 0
+	}
+	closed := klines[len(klines)-1-volumeAccelRecent-volumeAccelBaseline : len(klines)-1]
+
+	var baseline, recent float64
+	for i, k := range closed {
+		if i < volumeAccelBaseline {
+			baseline += k.Volume
+		} else {
+			recent += k.Volume
+		}
+	}
+	baseline /= volumeAccelBaseline
+	recent /= volumeAccelRecent
+	if baseline <= 0 {
+		return 0
+	}
+	return recent / baseline
+}
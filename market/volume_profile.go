@@ -0,0 +1,112 @@
+package market
+
+import (
+	"fmt"
+	"math"
+)
+
+// 成交量分布参数
+const (
+	valueAreaPct          = 0.70 // 价值区域覆盖的成交量比例
+	intradayProfileBins   = 20   // 日内成交量分布的价格分箱数
+	volumeProfileMinKline = 10   // 计算成交量分布所需的最少K线数
+)
+
+// VolumeProfileData 日内成交量分布
+type VolumeProfileData struct {
+	Interval      Interval // K线周期
+	Candles       int      // 参与统计的K线数
+	POC           float64  // 成交量最大的价格（Point of Control）
+	ValueAreaHigh float64  // 价值区域上沿（POC附近覆盖70%成交量的价格区间）
+	ValueAreaLow  float64  // 价值区域下沿
+}
+
+// volumeHistogram 按价格分箱统计成交量（每根K线的成交量平均分配到其最高/最低价覆盖的区间）。
+// 返回最低价、分箱宽度和各分箱成交量，价格区间为0时返回nil
+func volumeHistogram(klines []Kline, bins int) (minPrice, binSize float64, volumes []float64) {
+	minPrice, maxPrice := math.MaxFloat64, 0.0
+	for _, k := range klines {
+		minPrice = math.Min(minPrice, k.Low)
+		maxPrice = math.Max(maxPrice, k.High)
+	}
+	if maxPrice <= minPrice {
+		return 0, 0, nil
+	}
+
+	binSize = (maxPrice - minPrice) / float64(bins)
+	bin := func(price float64) int {
+		return int(math.Min(float64(bins-1), math.Floor((price-minPrice)/binSize)))
+	}
+	volumes = make([]float64, bins)
+	for _, k := range klines {
+		lo, hi := bin(k.Low), bin(k.High)
+		share := k.Volume / float64(hi-lo+1)
+		for b := lo; b <= hi; b++ {
+			volumes[b] += share
+		}
+	}
+	return minPrice, binSize, volumes
+}
+
+// calculateVolumeProfile 根据日内K线计算POC和价值区域（K线不足或没有成交量时返回nil）
+func calculateVolumeProfile(klines []Kline, interval Interval) *VolumeProfileData {
+	if len(klines) < volumeProfileMinKline {
+		return nil
+	}
+	minPrice, binSize, volumes := volumeHistogram(klines, intradayProfileBins)
+	if volumes == nil {
+		return nil
+	}
+
+	poc, total := 0, 0.0
+	for i, v := range volumes {
+		total += v
+		if v > volumes[poc] {
+			poc = i
+		}
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	// 从POC开始，每次向成交量较大的一侧扩展，直到覆盖70%的成交量
+	lo, hi := poc, poc
+	covered := volumes[poc]
+	for covered < total*valueAreaPct && (lo > 0 || hi < len(volumes)-1) {
+		below, above := -1.0, -1.0
+		if lo > 0 {
+			below = volumes[lo-1]
+		}
+		if hi < len(volumes)-1 {
+			above = volumes[hi+1]
+		}
+		if above >= below {
+			hi++
+			covered += above
+		} else {
+			lo--
+			covered += below
+		}
+	}
+
+	return &VolumeProfileData{
+		Interval:      interval,
+		Candles:       len(klines),
+		POC:           minPrice + (float64(poc)+0.5)*binSize,
+		ValueAreaHigh: minPrice + float64(hi+1)*binSize,
+		ValueAreaLow:  minPrice + float64(lo)*binSize,
+	}
+}
+
+// formatVolumeProfile 成交量分布描述（价格相对价值区域的位置）
+func formatVolumeProfile(profile *VolumeProfileData, currentPrice float64) string {
+	position := "inside value area"
+	switch {
+	case currentPrice > profile.ValueAreaHigh:
+		position = "above value area"
+	case currentPrice < profile.ValueAreaLow:
+		position = "below value area"
+	}
+	return fmt.Sprintf("Volume profile (last %d × %s): POC %.4f | value area %.4f – %.4f | price %s",
+		profile.Candles, intervalLabel(profile.Interval, Interval3m), profile.POC, profile.ValueAreaLow, profile.ValueAreaHigh, position)
+}
@@ -0,0 +1,266 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// anthropicVersion Anthropic Messages API版本
+const anthropicVersion = "2023-06-01"
+
+// anthropicProvider Anthropic Messages API（system prompt单独传入，回复为content块列表）
+type anthropicProvider struct{}
+
+func (anthropicProvider) Name() string { return ProviderAnthropic }
+
+func (anthropicProvider) SupportsSchema() bool { return false }
+
+func (anthropicProvider) SupportsImages() bool { return true }
+
+func (p anthropicProvider) NewRequest(cfg *Client, messages []Message, schema *ResponseSchema) (*http.Request, error) {
+	return p.newRequest(cfg, messages, false)
+}
+
+func (p anthropicProvider) NewStreamRequest(cfg *Client, messages []Message, schema *ResponseSchema) (*http.Request, error) {
+	return p.newRequest(cfg, messages, true)
+}
+
+// newRequest 构建Messages API请求（system消息合并后单独传入）
+func (p anthropicProvider) newRequest(cfg *Client, messages []Message, stream bool) (*http.Request, error) {
+	var system []string
+	var conversation []map[string]interface{}
+	for _, m := range messages {
+		if m.Role == "system" {
+			system = append(system, m.Content)
+			continue
+		}
+		conversation = append(conversation, map[string]interface{}{"role": m.Role, "content": anthropicContent(m)})
+	}
+
+	requestBody := map[string]interface{}{
+		"model":    cfg.Model,
+		"messages": conversation,
+	}
+	setAnthropicParams(requestBody, cfg.Params, true)
+	setAnthropicSystem(requestBody, system)
+	if stream {
+		requestBody["stream"] = true
+	}
+
+	return p.post(cfg, requestBody)
+}
+
+// post 发送到/messages并设置认证和API版本
+func (anthropicProvider) post(cfg *Client, requestBody map[string]interface{}) (*http.Request, error) {
+	req, err := newJSONRequest(cfg.endpoint("/messages"), requestBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-api-key", cfg.APIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	return req, nil
+}
+
+// setAnthropicSystem 设置system prompt并在末尾加缓存断点：system prompt只包含固定规则，
+// 同一策略的后续请求按缓存价格（1折）计费，写入缓存按1.25倍计费（不足最小长度时API自动忽略断点）
+func setAnthropicSystem(requestBody map[string]interface{}, system []string) {
+	if len(system) == 0 {
+		return
+	}
+	requestBody["system"] = []map[string]interface{}{{
+		"type":          "text",
+		"text":          strings.Join(system, "\n\n"),
+		"cache_control": map[string]string{"type": "ephemeral"},
+	}}
+}
+
+// anthropicUsage Messages API的token用量（input_tokens不含缓存命中和写入缓存的部分）
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+func (u anthropicUsage) usage() Usage {
+	return Usage{
+		PromptTokens:     u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens,
+		CompletionTokens: u.OutputTokens,
+		CacheReadTokens:  u.CacheReadInputTokens,
+		CacheWriteTokens: u.CacheCreationInputTokens,
+	}
+}
+
+// setAnthropicParams 设置生成参数：开启思考时max_tokens需包含思考预算，且不能设置temperature和top_p
+func setAnthropicParams(requestBody map[string]interface{}, params GenerationParams, thinking bool) {
+	if thinking && params.ThinkingBudget > 0 {
+		requestBody["thinking"] = map[string]interface{}{"type": "enabled", "budget_tokens": params.ThinkingBudget}
+		requestBody["max_tokens"] = params.maxTokens() + params.ThinkingBudget
+	} else {
+		requestBody["temperature"] = params.temperature()
+		requestBody["max_tokens"] = params.maxTokens()
+		if params.TopP > 0 {
+			requestBody["top_p"] = params.TopP
+		}
+	}
+	if len(params.Stop) > 0 {
+		requestBody["stop_sequences"] = params.Stop
+	}
+}
+
+func (anthropicProvider) ParseResponse(body []byte) (string, Usage, error) {
+	var result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage anthropicUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", Usage{}, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	var sb strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			sb.WriteString(block.Text)
+		}
+	}
+	if sb.Len() == 0 {
+		return "", Usage{}, fmt.Errorf("API返回空响应")
+	}
+	return sb.String(), result.Usage.usage(), nil
+}
+
+// ParseStreamEvent 输入token（含缓存命中和写入）在message_start中给出，输出token在message_delta中给出（累计值）
+func (anthropicProvider) ParseStreamEvent(data []byte) (string, Usage, error) {
+	var event struct {
+		Type    string `json:"type"`
+		Message struct {
+			Usage anthropicUsage `json:"usage"`
+		} `json:"message"`
+		Delta struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"delta"`
+		Usage struct {
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return "", Usage{}, fmt.Errorf("解析流式响应失败: %w", err)
+	}
+
+	switch event.Type {
+	case "message_start":
+		usage := event.Message.Usage.usage()
+		usage.CompletionTokens = 0 // message_start中的output_tokens为占位值，以message_delta为准
+		return "", usage, nil
+	case "content_block_delta":
+		if event.Delta.Type == "text_delta" {
+			return event.Delta.Text, Usage{}, nil
+		}
+	case "message_delta":
+		return "", Usage{CompletionTokens: event.Usage.OutputTokens}, nil
+	case "error":
+		// 流中途过载时按529处理，交给重试和故障切换
+		if event.Error.Type == "overloaded_error" {
+			return "", Usage{}, &StatusError{StatusCode: 529, Body: event.Error.Message}
+		}
+		return "", Usage{}, fmt.Errorf("API返回错误: %s", event.Error.Message)
+	}
+	return "", Usage{}, nil
+}
+
+// NewToolRequest 工具调用为assistant的tool_use块，结果为user消息中的tool_result块（连续的结果合并为一条消息）
+func (p anthropicProvider) NewToolRequest(cfg *Client, messages []Message, tools []Tool, allowCalls bool) (*http.Request, error) {
+	var system []string
+	var conversation []map[string]interface{}
+	for _, m := range messages {
+		switch {
+		case m.Role == "system":
+			system = append(system, m.Content)
+		case m.Role == "tool":
+			block := map[string]interface{}{"type": "tool_result", "tool_use_id": m.ToolCallID, "content": m.Content}
+			if n := len(conversation); n > 0 && conversation[n-1]["role"] == "user" {
+				if blocks, ok := conversation[n-1]["content"].([]map[string]interface{}); ok {
+					conversation[n-1]["content"] = append(blocks, block)
+					continue
+				}
+			}
+			conversation = append(conversation, map[string]interface{}{"role": "user", "content": []map[string]interface{}{block}})
+		case len(m.ToolCalls) > 0:
+			var blocks []map[string]interface{}
+			if m.Content != "" {
+				blocks = append(blocks, map[string]interface{}{"type": "text", "text": m.Content})
+			}
+			for _, call := range m.ToolCalls {
+				blocks = append(blocks, map[string]interface{}{"type": "tool_use", "id": call.ID, "name": call.Name, "input": json.RawMessage(toolArguments(call))})
+			}
+			conversation = append(conversation, map[string]interface{}{"role": "assistant", "content": blocks})
+		default:
+			conversation = append(conversation, map[string]interface{}{"role": m.Role, "content": anthropicContent(m)})
+		}
+	}
+
+	definitions := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		definitions = append(definitions, map[string]interface{}{
+			"name":         tool.Name,
+			"description":  tool.Description,
+			"input_schema": tool.Parameters,
+		})
+	}
+
+	toolChoice := "auto"
+	if !allowCalls {
+		toolChoice = "none"
+	}
+	requestBody := map[string]interface{}{
+		"model":       cfg.Model,
+		"messages":    conversation,
+		"tools":       definitions,
+		"tool_choice": map[string]interface{}{"type": toolChoice},
+	}
+	// 开启思考时tool_use轮次必须原样带回thinking块，工具调用对话不开启思考
+	setAnthropicParams(requestBody, cfg.Params, false)
+	setAnthropicSystem(requestBody, system)
+	return p.post(cfg, requestBody)
+}
+
+func (anthropicProvider) ParseToolResponse(body []byte) (string, []ToolCall, Usage, error) {
+	var result struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+		Usage anthropicUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", nil, Usage{}, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	var sb strings.Builder
+	var calls []ToolCall
+	for _, block := range result.Content {
+		switch block.Type {
+		case "text":
+			sb.WriteString(block.Text)
+		case "tool_use":
+			calls = append(calls, ToolCall{ID: block.ID, Name: block.Name, Arguments: string(block.Input)})
+		}
+	}
+	if sb.Len() == 0 && len(calls) == 0 {
+		return "", nil, Usage{}, fmt.Errorf("API返回空响应")
+	}
+	return sb.String(), calls, result.Usage.usage(), nil
+}
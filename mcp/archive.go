@@ -0,0 +1,186 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArchiveEntry 一次AI调用的完整记录（用于事后复盘亏损交易）
+type ArchiveEntry struct {
+	CycleID          string          `json:"cycle_id"`
+	Model            string          `json:"model"` // 实际调用的模型（provider/model，故障切换时为备用模型）
+	StartedAt        time.Time       `json:"started_at"`
+	LatencyMs        int64           `json:"latency_ms"`
+	Request          json.RawMessage `json:"request,omitempty"`  // 发送的请求体
+	Response         string          `json:"response,omitempty"` // 原始响应（流式调用为拼接后的回复文本，错误时为错误响应体）
+	PromptTokens     int             `json:"prompt_tokens"`
+	CompletionTokens int             `json:"completion_tokens"`
+	CacheReadTokens  int             `json:"cache_read_tokens,omitempty"`
+	Error            string          `json:"error,omitempty"`
+}
+
+// Archive AI请求/响应归档：每个周期一个子目录，每次调用（含重试和故障切换）一个文件
+type Archive struct {
+	mu        sync.Mutex
+	dir       string
+	retention time.Duration // 保留时长（0表示不清理）
+	seq       map[string]int
+}
+
+// NewArchive 创建归档（retention<=0时永久保留）
+func NewArchive(dir string, retention time.Duration) *Archive {
+	return &Archive{dir: dir, retention: retention, seq: make(map[string]int)}
+}
+
+// Dir 归档目录
+func (a *Archive) Dir() string {
+	return a.dir
+}
+
+// record 写入一次调用记录（失败只打印日志，不影响本次调用）
+func (a *Archive) record(entry ArchiveEntry) {
+	if entry.CycleID == "" {
+		entry.CycleID = "unassigned"
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cycleDir := filepath.Join(a.dir, entry.CycleID)
+	if _, ok := a.seq[entry.CycleID]; !ok {
+		// 新周期：顺便清理过期归档，只保留当前周期的序号
+		a.prune()
+		a.seq = map[string]int{}
+	}
+	if err := os.MkdirAll(cycleDir, 0755); err != nil {
+		fmt.Printf("⚠ 创建AI归档目录失败: %v\n", err)
+		return
+	}
+	a.seq[entry.CycleID]++
+	name := fmt.Sprintf("%03d_%s.json", a.seq[entry.CycleID], strings.NewReplacer("/", "_", ":", "_").Replace(entry.Model))
+	if err := os.WriteFile(filepath.Join(cycleDir, name), data, 0644); err != nil {
+		fmt.Printf("⚠ 写入AI归档失败: %v\n", err)
+	}
+}
+
+// prune 删除超过保留时长的周期目录
+func (a *Archive) prune() {
+	if a.retention <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(a.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err == nil && entry.IsDir() && time.Since(info.ModTime()) > a.retention {
+			os.RemoveAll(filepath.Join(a.dir, entry.Name()))
+		}
+	}
+}
+
+// Load 读取指定周期的所有调用记录（按调用顺序）
+func (a *Archive) Load(cycleID string) ([]ArchiveEntry, error) {
+	if cycleID == "" || strings.ContainsAny(cycleID, `/\`) || strings.Contains(cycleID, "..") {
+		return nil, fmt.Errorf("无效的周期ID: %q", cycleID)
+	}
+	files, err := os.ReadDir(filepath.Join(a.dir, cycleID))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("周期 %s 没有AI归档记录", cycleID)
+		}
+		return nil, fmt.Errorf("读取AI归档失败: %w", err)
+	}
+
+	var names []string
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".json") {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+
+	entries := make([]ArchiveEntry, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(a.dir, cycleID, name))
+		if err != nil {
+			continue
+		}
+		var entry ArchiveEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// SetArchive 启用请求/响应归档（nil关闭），故障切换链中的模型需要分别设置
+func (cfg *Client) SetArchive(archive *Archive) {
+	cfg.archive = archive
+}
+
+// SetCycleID 设置后续调用归档时使用的周期ID
+func (cfg *Client) SetCycleID(cycleID string) {
+	cfg.cycleID.Store(cycleID)
+}
+
+// archiveCall 归档一次HTTP调用（response为原始响应，err不为nil时记录错误和错误响应体）
+func (cfg *Client) archiveCall(req *http.Request, started time.Time, response string, usage Usage, err error) {
+	if cfg.archive == nil {
+		return
+	}
+	cycleID, _ := cfg.cycleID.Load().(string)
+	entry := ArchiveEntry{
+		CycleID:          cycleID,
+		Model:            cfg.Name(),
+		StartedAt:        started,
+		LatencyMs:        time.Since(started).Milliseconds(),
+		Request:          requestBody(req),
+		Response:         response,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CacheReadTokens:  usage.CacheReadTokens,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		var statusErr *StatusError
+		if entry.Response == "" && errors.As(err, &statusErr) {
+			entry.Response = statusErr.Body
+		}
+	}
+	cfg.archive.record(entry)
+}
+
+// requestBody 读取请求体副本（非JSON时按字符串保存）
+func requestBody(req *http.Request) json.RawMessage {
+	if req == nil || req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	if !json.Valid(data) {
+		data, _ = json.Marshal(string(data))
+	}
+	return data
+}
@@ -0,0 +1,140 @@
+package mcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 响应缓存配置（ttl为0表示不缓存）
+var (
+	cacheMu  sync.RWMutex
+	cacheDir = "ai_cache"
+	cacheTTL time.Duration
+
+	// cacheNormalizer 计算缓存键前处理消息内容（去掉时间、调用次数等每次都不同的部分），nil表示使用原文
+	cacheNormalizer func(content string) string
+)
+
+// cachedResponse 缓存文件内容
+type cachedResponse struct {
+	CreatedAt time.Time `json:"created_at"`
+	Model     string    `json:"model"` // 实际响应的模型（provider/model）
+	Content   string    `json:"content"`
+}
+
+// SetResponseCache 启用AI响应缓存：相同模型和prompt在ttl内直接复用上次的响应（ttl<=0关闭）
+// 缓存写入磁盘，崩溃重启或同一周期内重复试运行都不会重复付费
+func SetResponseCache(dir string, ttl time.Duration) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if dir != "" {
+		cacheDir = dir
+	}
+	cacheTTL = ttl
+	if ttl > 0 {
+		pruneCache(cacheDir, ttl)
+	}
+}
+
+// SetCacheNormalizer 设置计算缓存键前的消息内容处理函数（用于去掉prompt中每次调用都会变化的内容，否则永远无法命中缓存）
+func SetCacheNormalizer(normalize func(content string) string) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cacheNormalizer = normalize
+}
+
+// cacheKey 缓存键：模型、生成参数、schema和对话（经过normalizer处理）的哈希
+func (cfg *Client) cacheKey(messages []Message, schema *ResponseSchema) string {
+	cacheMu.RLock()
+	normalize := cacheNormalizer
+	cacheMu.RUnlock()
+
+	h := sha256.New()
+	h.Write([]byte(cfg.Name()))
+	if params, err := json.Marshal(cfg.Params); err == nil {
+		h.Write(params)
+	}
+	if schema != nil {
+		h.Write([]byte("\x00" + schema.Name))
+	}
+	for _, msg := range messages {
+		content := msg.Content
+		if normalize != nil {
+			content = normalize(content)
+		}
+		h.Write([]byte("\x00" + msg.Role + "\x00" + content))
+		for _, img := range msg.Images {
+			sum := sha256.Sum256(img.Data)
+			h.Write(sum[:])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadCached 读取未过期的缓存响应
+func loadCached(key string) (cachedResponse, bool) {
+	cacheMu.RLock()
+	dir, ttl := cacheDir, cacheTTL
+	cacheMu.RUnlock()
+	if ttl <= 0 {
+		return cachedResponse{}, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, key+".json"))
+	if err != nil {
+		return cachedResponse{}, false
+	}
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil || time.Since(cached.CreatedAt) > ttl {
+		return cachedResponse{}, false
+	}
+	return cached, true
+}
+
+// storeCached 写入缓存（失败只影响缓存，不影响本次调用）
+func storeCached(key, model, content string) {
+	cacheMu.RLock()
+	dir, ttl := cacheDir, cacheTTL
+	cacheMu.RUnlock()
+	if ttl <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(cachedResponse{CreatedAt: time.Now(), Model: model, Content: content})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	// 先写临时文件再重命名，避免崩溃时留下半截缓存
+	path := filepath.Join(dir, key+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return
+	}
+	os.Rename(tmp, path)
+}
+
+// pruneCache 删除过期的缓存文件
+func pruneCache(dir string, ttl time.Duration) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err == nil && time.Since(info.ModTime()) > ttl {
+			os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
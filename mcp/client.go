@@ -1,49 +1,87 @@
 package mcp
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
-// Provider AI提供商类型
-type Provider string
-
-const (
-	ProviderDeepSeek Provider = "deepseek"
-	ProviderQwen     Provider = "qwen"
-	ProviderCustom   Provider = "custom"
-)
-
 // Client AI API配置
 type Client struct {
-	Provider   Provider
+	Provider   Provider // API格式（决定请求/响应结构和认证方式）
 	APIKey     string
 	SecretKey  string // 阿里云需要
 	BaseURL    string
 	Model      string
 	Timeout    time.Duration
-	UseFullURL bool // 是否使用完整URL（不添加/chat/completions）
+	UseFullURL bool   // 是否使用完整URL（不添加/chat/completions）
+	APIVersion string // API版本（Azure OpenAI的api-version参数）
+
+	// Params 生成参数（temperature、top_p、max_tokens、推理强度/思考预算、停止序列）
+	Params GenerationParams
+
+	// StructuredOutput 是否启用json_schema结构化输出（仅OpenAI兼容的自定义API支持）
+	StructuredOutput bool
+
+	// Stream 是否使用流式输出（提供商支持SSE时边生成边解析，可提前结束）
+	Stream bool
+
+	// Fallbacks 故障切换链：超时、429或5xx时按顺序改用下一个模型（可选）
+	Fallbacks []*Client
+
+	lastServed atomic.Value       // 最近一次成功响应的模型（provider/model）
+	meter      *usageMeter        // 累计token用量和费用
+	limiter    *rateLimiter       // 客户端限流（nil表示不限制）
+	archive    *Archive           // 请求/响应归档（nil表示不归档）
+	cycleID    atomic.Value       // 当前周期ID（归档时使用）
+	openRouter *OpenRouterOptions // OpenRouter模型路由选项（仅OpenRouter）
+	httpClient *http.Client       // 发送请求使用的HTTP客户端（nil使用http.DefaultClient，模拟客户端替换Transport）
+	policy     RetryPolicy        // 重试和熔断策略（0值字段使用默认值）
+	breaker    *circuitBreaker    // 熔断器（连续失败后暂停调用该模型）
+}
+
+// Message 对话消息
+type Message struct {
+	Role    string `json:"role"` // "system", "user"、"assistant" 或 "tool"（工具调用结果）
+	Content string `json:"content"`
+
+	// 工具调用（只用于工具调用对话，由各提供商转换为自己的格式）
+	ToolCalls  []ToolCall `json:"-"` // assistant请求的工具调用
+	ToolCallID string     `json:"-"` // tool消息对应的调用ID
+	ToolName   string     `json:"-"` // tool消息对应的工具名称
+
+	// Images 随user消息发送的图片（只发送给支持视觉输入的模型，其他模型只收到文本）
+	Images []Image `json:"-"`
+}
+
+// ResponseSchema 结构化输出使用的JSON Schema
+type ResponseSchema struct {
+	Name   string                 // schema名称
+	Schema map[string]interface{} // JSON Schema定义
 }
 
 func New() *Client {
 	// 默认配置
 	var defaultClient = Client{
-		Provider: ProviderDeepSeek,
+		Provider: openAIProvider{name: ProviderDeepSeek},
 		BaseURL:  "https://api.deepseek.com/v1",
 		Model:    "deepseek-chat",
 		Timeout:  120 * time.Second, // 增加到120秒，因为AI需要分析大量数据
+		meter:    &usageMeter{},
+		breaker:  &circuitBreaker{},
 	}
 	return &defaultClient
 }
 
 // SetDeepSeekAPIKey 设置DeepSeek API密钥
 func (cfg *Client) SetDeepSeekAPIKey(apiKey string) {
-	cfg.Provider = ProviderDeepSeek
+	cfg.Provider = openAIProvider{name: ProviderDeepSeek}
 	cfg.APIKey = apiKey
 	cfg.BaseURL = "https://api.deepseek.com/v1"
 	cfg.Model = "deepseek-chat"
@@ -51,16 +89,67 @@ func (cfg *Client) SetDeepSeekAPIKey(apiKey string) {
 
 // SetQwenAPIKey 设置阿里云Qwen API密钥
 func (cfg *Client) SetQwenAPIKey(apiKey, secretKey string) {
-	cfg.Provider = ProviderQwen
+	cfg.Provider = openAIProvider{name: ProviderQwen}
 	cfg.APIKey = apiKey
 	cfg.SecretKey = secretKey
 	cfg.BaseURL = "https://dashscope.aliyuncs.com/compatible-mode/v1"
 	cfg.Model = "qwen-plus" // 可选: qwen-turbo, qwen-plus, qwen-max
 }
 
+// SetOpenAIAPIKey 设置OpenAI API密钥
+func (cfg *Client) SetOpenAIAPIKey(apiKey string) {
+	cfg.Provider = openAIProvider{name: ProviderOpenAI, schema: true}
+	cfg.APIKey = apiKey
+	cfg.BaseURL = "https://api.openai.com/v1"
+	cfg.Model = "gpt-4o"
+}
+
+// SetAnthropicAPIKey 设置Anthropic API密钥（Messages API）
+func (cfg *Client) SetAnthropicAPIKey(apiKey string) {
+	cfg.Provider = anthropicProvider{}
+	cfg.APIKey = apiKey
+	cfg.BaseURL = "https://api.anthropic.com/v1"
+	cfg.Model = "claude-sonnet-4-5"
+}
+
+// SetGeminiAPIKey 设置Google Gemini API密钥（generateContent API）
+func (cfg *Client) SetGeminiAPIKey(apiKey string) {
+	cfg.Provider = geminiProvider{}
+	cfg.APIKey = apiKey
+	cfg.BaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	cfg.Model = "gemini-2.5-flash"
+}
+
+// SetAzureAPI 设置Azure OpenAI（endpoint为资源地址如https://xxx.openai.azure.com，deployment为部署名称，apiVersion为空时使用默认版本）
+// 费用按部署名称匹配模型价格，部署名称与模型不同时可用SetModelPrice补充
+func (cfg *Client) SetAzureAPI(endpoint, apiKey, deployment, apiVersion string) {
+	cfg.Provider = openAIProvider{name: ProviderAzure, schema: true}
+	cfg.APIKey = apiKey
+	cfg.BaseURL = strings.TrimSuffix(endpoint, "/") + "/openai/deployments/" + deployment
+	cfg.UseFullURL = false
+	cfg.Model = deployment
+	cfg.APIVersion = apiVersion
+	if cfg.APIVersion == "" {
+		cfg.APIVersion = defaultAzureAPIVersion
+	}
+}
+
+// SetLocalAPI 设置本地模型（Ollama或vLLM、LM Studio等OpenAI兼容服务，apiURL为空时使用Ollama默认地址）
+func (cfg *Client) SetLocalAPI(apiURL, modelName string) {
+	cfg.Provider = openAIProvider{name: ProviderLocal, schema: true}
+	cfg.APIKey = "" // 本地服务通常不需要密钥，需要时由调用方设置
+	cfg.BaseURL = "http://localhost:11434/v1"
+	cfg.UseFullURL = false
+	if apiURL != "" {
+		cfg.BaseURL = strings.TrimSuffix(apiURL, "/")
+	}
+	cfg.Model = modelName
+	cfg.Timeout = 300 * time.Second // 本地推理通常比云端API慢
+}
+
 // SetCustomAPI 设置自定义OpenAI兼容API
 func (cfg *Client) SetCustomAPI(apiURL, apiKey, modelName string) {
-	cfg.Provider = ProviderCustom
+	cfg.Provider = openAIProvider{name: ProviderCustom, schema: true}
 	cfg.APIKey = apiKey
 
 	// 检查URL是否以#结尾，如果是则使用完整URL（不添加/chat/completions）
@@ -84,149 +173,262 @@ func (cfg *Client) SetClient(Client Client) {
 	cfg = &Client
 }
 
+// Name 模型标识（provider/model）
+func (cfg *Client) Name() string {
+	return fmt.Sprintf("%s/%s", cfg.Provider.Name(), cfg.Model)
+}
+
+// LastServed 最近一次成功响应的模型（发生故障切换时为备用模型，尚未成功调用时为空）
+func (cfg *Client) LastServed() string {
+	served, _ := cfg.lastServed.Load().(string)
+	return served
+}
+
+// SupportsStructuredOutput 当前配置是否支持json_schema结构化输出
+// 只有显式开启且提供商支持json_schema时才使用（DeepSeek/Qwen/Anthropic/Gemini不支持）
+func (cfg *Client) SupportsStructuredOutput() bool {
+	return cfg.StructuredOutput && cfg.Provider.SupportsSchema()
+}
+
 // CallWithMessages 使用 system + user prompt 调用AI API（推荐）
-func (cfg *Client) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
-	if cfg.APIKey == "" {
-		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetDeepSeekAPIKey() 或 SetQwenAPIKey()")
+// ctx的截止时间和取消会中止请求、重试等待、限流等待和故障切换
+func (cfg *Client) CallWithMessages(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	return cfg.callChain(ctx, BuildMessages(systemPrompt, userPrompt), nil, nil)
+}
+
+// CallWithSchema 使用json_schema结构化输出调用AI API，返回符合schema的JSON字符串
+func (cfg *Client) CallWithSchema(ctx context.Context, systemPrompt, userPrompt string, schema *ResponseSchema) (string, error) {
+	return cfg.CallConversation(ctx, BuildMessages(systemPrompt, userPrompt), schema)
+}
+
+// CallConversation 使用完整对话历史调用AI API（用于多轮修正），schema为nil时使用普通文本输出
+func (cfg *Client) CallConversation(ctx context.Context, messages []Message, schema *ResponseSchema) (string, error) {
+	if schema != nil && !cfg.SupportsStructuredOutput() {
+		return "", fmt.Errorf("当前AI提供商(%s)不支持结构化输出", cfg.Provider.Name())
 	}
+	return cfg.callChain(ctx, messages, schema, nil)
+}
 
-	// 重试配置
-	maxRetries := 3
-	var lastErr error
+// BuildMessages 构建 system + user 的初始对话（system prompt为空时省略）
+func BuildMessages(systemPrompt, userPrompt string) []Message {
+	messages := []Message{}
+	if systemPrompt != "" {
+		messages = append(messages, Message{Role: "system", Content: systemPrompt})
+	}
+	return append(messages, Message{Role: "user", Content: userPrompt})
+}
 
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		if attempt > 1 {
-			fmt.Printf("⚠️  AI API调用失败，正在重试 (%d/%d)...\n", attempt, maxRetries)
+// callChain 依次调用主模型和故障切换链，超时、429或5xx时切换到下一个
+// 需要结构化输出时跳过不支持的备用模型，启用响应缓存时先查缓存，handler不为nil时对支持的模型使用流式输出
+func (cfg *Client) callChain(ctx context.Context, messages []Message, schema *ResponseSchema, handler StreamHandler) (string, error) {
+	// 相同prompt在缓存有效期内直接复用上次响应（不计费）
+	key := cfg.cacheKey(messages, schema)
+	if cached, ok := loadCached(key); ok {
+		fmt.Printf("♻️  使用缓存的AI响应（%s，%s前）\n", cached.Model, time.Since(cached.CreatedAt).Round(time.Second))
+		cfg.lastServed.Store(cached.Model)
+		return cached.Content, nil
+	}
+
+	chain := append([]*Client{cfg}, cfg.Fallbacks...)
+	var lastErr error
+	for i, client := range chain {
+		if schema != nil && !client.SupportsStructuredOutput() {
+			continue
+		}
+		if i > 0 && lastErr != nil {
+			fmt.Printf("🔁 AI故障切换: %v，改用 %s\n", lastErr, client.Name())
 		}
 
-		result, err := cfg.callOnce(systemPrompt, userPrompt)
+		sent := messages
+		if !client.SupportsImages() {
+			sent = withoutImages(messages)
+		}
+		var result string
+		var usage Usage
+		err := client.guard(func() error {
+			var err error
+			result, usage, err = client.callWithRetry(ctx, sent, schema, handler)
+			return err
+		})
 		if err == nil {
-			if attempt > 1 {
-				fmt.Printf("✓ AI API重试成功\n")
-			}
+			cfg.lastServed.Store(client.Name())
+			cfg.record(client, usage)
+			storeCached(key, client.Name(), result)
 			return result, nil
 		}
-
 		lastErr = err
-		// 如果不是网络错误，不重试
-		if !isRetryableError(err) {
+		if !shouldFailover(err) {
 			return "", err
 		}
+	}
+	return "", lastErr
+}
 
-		// 重试前等待
-		if attempt < maxRetries {
-			waitTime := time.Duration(attempt) * 2 * time.Second
-			fmt.Printf("⏳ 等待%v后重试...\n", waitTime)
-			time.Sleep(waitTime)
+// callWithRetry 带重试的AI API调用（schema为nil时使用普通文本输出）
+func (cfg *Client) callWithRetry(ctx context.Context, messages []Message, schema *ResponseSchema, handler StreamHandler) (string, Usage, error) {
+	var result string
+	var usage Usage
+	err := cfg.retry(ctx, func() error {
+		var err error
+		if handler != nil && cfg.SupportsStreaming() {
+			result, usage, err = cfg.callStream(ctx, messages, schema, handler)
+		} else {
+			result, usage, err = cfg.callOnce(ctx, messages, schema)
 		}
+		return err
+	})
+	return result, usage, err
+}
+
+// retry 执行一次API调用，网络错误、限流和服务过载时指数退避重试，不会超过ctx的截止时间，ctx取消时立即返回
+func (cfg *Client) retry(ctx context.Context, call func() error) error {
+	if cfg.APIKey == "" && cfg.Provider.Name() != ProviderLocal {
+		return fmt.Errorf("AI API密钥未设置，请先调用 SetDeepSeekAPIKey() 或 SetQwenAPIKey()")
 	}
 
-	return "", fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
-}
+	policy := cfg.RetryPolicy()
+	var lastErr error
 
-// callOnce 单次调用AI API（内部使用）
-func (cfg *Client) callOnce(systemPrompt, userPrompt string) (string, error) {
-	// 构建 messages 数组
-	messages := []map[string]string{}
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			fmt.Printf("⚠️  AI API调用失败，正在重试 (%d/%d)...\n", attempt, policy.MaxAttempts)
+		}
+		if cfg.limiter != nil {
+			if err := cfg.limiter.wait(ctx); err != nil {
+				return err
+			}
+		}
 
-	// 如果有 system prompt，添加 system message
-	if systemPrompt != "" {
-		messages = append(messages, map[string]string{
-			"role":    "system",
-			"content": systemPrompt,
-		})
-	}
+		err := call()
+		if err == nil {
+			if attempt > 1 {
+				fmt.Printf("✓ AI API重试成功\n")
+			}
+			return nil
+		}
 
-	// 添加 user message
-	messages = append(messages, map[string]string{
-		"role":    "user",
-		"content": userPrompt,
-	})
+		lastErr = err
+		if ctx.Err() != nil {
+			return contextError(ctx, err)
+		}
+		// 只重试网络错误、限流和服务过载
+		if !isRetryableError(err) && !isOverloaded(err) {
+			return err
+		}
 
-	// 构建请求体
-	requestBody := map[string]interface{}{
-		"model":       cfg.Model,
-		"messages":    messages,
-		"temperature": 0.5, // 降低temperature以提高JSON格式稳定性
-		"max_tokens":  2000,
+		// 重试前等待（指数退避），等待会超过截止时间时放弃
+		if attempt < policy.MaxAttempts {
+			waitTime := policy.backoff(attempt, err)
+			if deadline, ok := ctx.Deadline(); ok && time.Now().Add(waitTime).After(deadline) {
+				return fmt.Errorf("%w: %v", ErrDeadline, err)
+			}
+			fmt.Printf("⏳ 等待%v后重试...\n", waitTime.Round(100*time.Millisecond))
+			if err := sleepContext(ctx, waitTime); err != nil {
+				return contextError(ctx, lastErr)
+			}
+		}
 	}
 
-	// 注意：response_format 参数仅 OpenAI 支持，DeepSeek/Qwen 不支持
-	// 我们通过强化 prompt 和后处理来确保 JSON 格式正确
+	return fmt.Errorf("重试%d次后仍然失败: %w", policy.MaxAttempts, lastErr)
+}
 
-	jsonData, err := json.Marshal(requestBody)
+// callOnce 单次调用AI API（内部使用）
+func (cfg *Client) callOnce(ctx context.Context, messages []Message, schema *ResponseSchema) (string, Usage, error) {
+	// 按提供商的API格式构建请求
+	req, err := cfg.Provider.NewRequest(cfg, messages, schema)
 	if err != nil {
-		return "", fmt.Errorf("序列化请求失败: %w", err)
+		return "", Usage{}, err
 	}
-
-	// 创建HTTP请求
-	var url string
-	if cfg.UseFullURL {
-		// 使用完整URL，不添加/chat/completions
-		url = cfg.BaseURL
-	} else {
-		// 默认行为：添加/chat/completions
-		url = fmt.Sprintf("%s/chat/completions", cfg.BaseURL)
-	}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	started := time.Now()
+	body, err := cfg.send(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
+		cfg.archiveCall(req, started, "", Usage{}, err)
+		return "", Usage{}, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	// 解析响应（回复文本和token用量）
+	content, usage, err := cfg.Provider.ParseResponse(body)
+	cfg.archiveCall(req, started, string(body), usage, err)
+	return content, usage, err
+}
 
-	// 根据不同的Provider设置认证方式
-	switch cfg.Provider {
-	case ProviderDeepSeek:
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", cfg.APIKey))
-	case ProviderQwen:
-		// 阿里云Qwen使用API-Key认证
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", cfg.APIKey))
-		// 注意：如果使用的不是兼容模式，可能需要不同的认证方式
-	default:
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", cfg.APIKey))
-	}
+// send 发送请求并读取响应（非200响应返回StatusError），单次请求超时为cfg.Timeout，ctx结束时中止
+func (cfg *Client) send(ctx context.Context, req *http.Request) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	req = req.WithContext(ctx)
 
 	// 发送请求
-	client := &http.Client{Timeout: cfg.Timeout}
-	resp, err := client.Do(req)
+	resp, err := cfg.doer().Do(req)
 	if err != nil {
-		return "", fmt.Errorf("发送请求失败: %w", err)
+		return nil, fmt.Errorf("发送请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取响应失败: %w", err)
+		return nil, fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(body), RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
 	}
+	return body, nil
+}
 
-	// 解析响应
-	var result struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
+// doer 发送请求使用的HTTP客户端
+func (cfg *Client) doer() *http.Client {
+	if cfg.httpClient != nil {
+		return cfg.httpClient
 	}
+	return http.DefaultClient
+}
 
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("解析响应失败: %w", err)
+// endpoint 请求地址（UseFullURL时直接使用BaseURL，否则拼接path）
+func (cfg *Client) endpoint(path string) string {
+	if cfg.UseFullURL {
+		return cfg.BaseURL
 	}
+	return cfg.BaseURL + path
+}
 
-	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("API返回空响应")
-	}
+// StatusError API返回的非200响应
+type StatusError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // 服务端要求的等待时间（Retry-After响应头，未提供时为0）
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API返回错误 (status %d): %s", e.StatusCode, e.Body)
+}
 
-	return result.Choices[0].Message.Content, nil
+// shouldFailover 是否切换到下一个模型（超时、网络错误、429限流、5xx服务端错误或已熔断；本周期截止时间已到或调用被取消时不切换）
+func shouldFailover(err error) bool {
+	if errors.Is(err, ErrDeadline) || errors.Is(err, ErrCanceled) {
+		return false
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return isRetryableError(err)
 }
 
 // isRetryableError 判断错误是否可重试
 func isRetryableError(err error) bool {
+	// 单次请求超时（cfg.Timeout）可以重试，周期截止时间已到的情况在retry中先行处理
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
 	errStr := err.Error()
 	// 网络错误、超时、EOF等可以重试
 	retryableErrors := []string{
@@ -0,0 +1,233 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// geminiProvider Google Gemini generateContent API（assistant角色称为model，内容为parts列表）
+type geminiProvider struct{}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"` // base64
+}
+
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	ID       string                 `json:"id,omitempty"`
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+func (geminiProvider) Name() string { return ProviderGemini }
+
+func (geminiProvider) SupportsSchema() bool { return false }
+
+func (geminiProvider) SupportsImages() bool { return true }
+
+func (p geminiProvider) NewRequest(cfg *Client, messages []Message, schema *ResponseSchema) (*http.Request, error) {
+	return p.newRequest(cfg, messages, fmt.Sprintf("/models/%s:generateContent", cfg.Model))
+}
+
+func (p geminiProvider) NewStreamRequest(cfg *Client, messages []Message, schema *ResponseSchema) (*http.Request, error) {
+	return p.newRequest(cfg, messages, fmt.Sprintf("/models/%s:streamGenerateContent?alt=sse", cfg.Model))
+}
+
+// newRequest 构建generateContent请求（普通和流式只有接口路径不同）
+func (p geminiProvider) newRequest(cfg *Client, messages []Message, path string) (*http.Request, error) {
+	return p.post(cfg, path, p.requestBody(messages, cfg.Params))
+}
+
+// requestBody 请求体：assistant角色改为model，工具调用为functionCall，结果为functionResponse（连续的结果合并为一条消息）
+func (geminiProvider) requestBody(messages []Message, params GenerationParams) map[string]interface{} {
+	var system []geminiPart
+	var contents []geminiContent
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+			system = append(system, geminiPart{Text: m.Content})
+		case "assistant":
+			var parts []geminiPart
+			if m.Content != "" || len(m.ToolCalls) == 0 {
+				parts = append(parts, geminiPart{Text: m.Content})
+			}
+			for _, call := range m.ToolCalls {
+				parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{ID: geminiCallID(call.ID, call.Name), Name: call.Name, Args: json.RawMessage(toolArguments(call))}})
+			}
+			contents = append(contents, geminiContent{Role: "model", Parts: parts})
+		case "tool":
+			part := geminiPart{FunctionResponse: &geminiFunctionResponse{ID: geminiCallID(m.ToolCallID, m.ToolName), Name: m.ToolName, Response: map[string]interface{}{"result": m.Content}}}
+			if n := len(contents); n > 0 && contents[n-1].Role == "user" && contents[n-1].Parts[0].FunctionResponse != nil {
+				contents[n-1].Parts = append(contents[n-1].Parts, part)
+				continue
+			}
+			contents = append(contents, geminiContent{Role: "user", Parts: []geminiPart{part}})
+		default:
+			parts := []geminiPart{{Text: m.Content}}
+			for _, img := range m.Images {
+				parts = append(parts, geminiPart{InlineData: &geminiBlob{MimeType: img.MIMEType, Data: img.base64()}})
+			}
+			contents = append(contents, geminiContent{Role: "user", Parts: parts})
+		}
+	}
+
+	generationConfig := map[string]interface{}{
+		"temperature": params.temperature(),
+		// 思考token也计入maxOutputTokens，加上思考预算以免回复被截断（默认关闭思考，推理过程由prompt要求写在输出中）
+		"maxOutputTokens": params.maxTokens() + params.ThinkingBudget,
+		"thinkingConfig":  map[string]interface{}{"thinkingBudget": params.ThinkingBudget},
+	}
+	if params.TopP > 0 {
+		generationConfig["topP"] = params.TopP
+	}
+	if len(params.Stop) > 0 {
+		generationConfig["stopSequences"] = params.Stop
+	}
+
+	requestBody := map[string]interface{}{
+		"contents":         contents,
+		"generationConfig": generationConfig,
+	}
+	if len(system) > 0 {
+		requestBody["systemInstruction"] = geminiContent{Parts: system}
+	}
+	return requestBody
+}
+
+// post 发送到指定接口路径并设置认证
+func (geminiProvider) post(cfg *Client, path string, requestBody map[string]interface{}) (*http.Request, error) {
+	req, err := newJSONRequest(cfg.endpoint(path), requestBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-goog-api-key", cfg.APIKey)
+	return req, nil
+}
+
+// geminiResponse generateContent响应（流式输出时每个事件都是一个片段）
+type geminiResponse struct {
+	Candidates []struct {
+		Content geminiContent `json:"content"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"` // 隐式缓存命中的token数（包含在promptTokenCount中）
+	} `json:"usageMetadata"`
+}
+
+// text 第一个候选回复的文本
+func (r geminiResponse) text() string {
+	if len(r.Candidates) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	for _, part := range r.Candidates[0].Content.Parts {
+		sb.WriteString(part.Text)
+	}
+	return sb.String()
+}
+
+func (r geminiResponse) usage() Usage {
+	return Usage{
+		PromptTokens:     r.UsageMetadata.PromptTokenCount,
+		CompletionTokens: r.UsageMetadata.CandidatesTokenCount,
+		CacheReadTokens:  r.UsageMetadata.CachedContentTokenCount,
+	}
+}
+
+func (geminiProvider) ParseResponse(body []byte) (string, Usage, error) {
+	var result geminiResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", Usage{}, fmt.Errorf("解析响应失败: %w", err)
+	}
+	text := result.text()
+	if text == "" {
+		return "", Usage{}, fmt.Errorf("API返回空响应")
+	}
+	return text, result.usage(), nil
+}
+
+func (geminiProvider) ParseStreamEvent(data []byte) (string, Usage, error) {
+	var result geminiResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", Usage{}, fmt.Errorf("解析流式响应失败: %w", err)
+	}
+	return result.text(), result.usage(), nil
+}
+
+func (p geminiProvider) NewToolRequest(cfg *Client, messages []Message, tools []Tool, allowCalls bool) (*http.Request, error) {
+	declarations := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		declarations = append(declarations, map[string]interface{}{
+			"name":        tool.Name,
+			"description": tool.Description,
+			"parameters":  tool.Parameters,
+		})
+	}
+
+	mode := "AUTO"
+	if !allowCalls {
+		mode = "NONE"
+	}
+	requestBody := p.requestBody(messages, cfg.Params)
+	requestBody["tools"] = []map[string]interface{}{{"functionDeclarations": declarations}}
+	requestBody["toolConfig"] = map[string]interface{}{"functionCallingConfig": map[string]interface{}{"mode": mode}}
+	return p.post(cfg, fmt.Sprintf("/models/%s:generateContent", cfg.Model), requestBody)
+}
+
+// ParseToolResponse Gemini的functionCall可能没有ID，此时以工具名称作为调用ID
+func (geminiProvider) ParseToolResponse(body []byte) (string, []ToolCall, Usage, error) {
+	var result geminiResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", nil, Usage{}, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if len(result.Candidates) == 0 {
+		return "", nil, Usage{}, fmt.Errorf("API返回空响应")
+	}
+
+	var calls []ToolCall
+	for _, part := range result.Candidates[0].Content.Parts {
+		if part.FunctionCall == nil {
+			continue
+		}
+		id := part.FunctionCall.ID
+		if id == "" {
+			id = part.FunctionCall.Name
+		}
+		calls = append(calls, ToolCall{ID: id, Name: part.FunctionCall.Name, Arguments: string(part.FunctionCall.Args)})
+	}
+	text := result.text()
+	if text == "" && len(calls) == 0 {
+		return "", nil, Usage{}, fmt.Errorf("API返回空响应")
+	}
+	return text, calls, result.usage(), nil
+}
+
+// geminiCallID 原始的functionCall ID（以工具名称代替的ID不回传）
+func geminiCallID(id, name string) string {
+	if id == name {
+		return ""
+	}
+	return id
+}
@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// Image 随user消息发送的图片（只发送给支持视觉输入的模型）
+type Image struct {
+	MIMEType string // 图片类型，如 "image/png"
+	Data     []byte // 图片内容
+}
+
+// base64 图片内容的base64编码
+func (img Image) base64() string {
+	return base64.StdEncoding.EncodeToString(img.Data)
+}
+
+// dataURL data URL格式（OpenAI的image_url）
+func (img Image) dataURL() string {
+	return fmt.Sprintf("data:%s;base64,%s", img.MIMEType, img.base64())
+}
+
+// ImageProvider 支持视觉输入的API格式（部分提供商只有特定模型支持）
+type ImageProvider interface {
+	SupportsImages() bool
+}
+
+// SupportsImages 当前提供商是否支持图片输入
+func (cfg *Client) SupportsImages() bool {
+	p, ok := cfg.Provider.(ImageProvider)
+	return ok && p.SupportsImages()
+}
+
+// withoutImages 去掉消息中的图片（故障切换到不支持视觉输入的模型时只发送文本）
+func withoutImages(messages []Message) []Message {
+	if !hasImages(messages) {
+		return messages
+	}
+	stripped := make([]Message, len(messages))
+	for i, m := range messages {
+		m.Images = nil
+		stripped[i] = m
+	}
+	return stripped
+}
+
+// hasImages 消息中是否包含图片
+func hasImages(messages []Message) bool {
+	for _, m := range messages {
+		if len(m.Images) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// openAIContent OpenAI格式的消息内容：没有图片时为字符串，有图片时为文本和image_url块的列表
+func openAIContent(m Message) interface{} {
+	if len(m.Images) == 0 {
+		return m.Content
+	}
+	parts := []map[string]interface{}{{"type": "text", "text": m.Content}}
+	for _, img := range m.Images {
+		parts = append(parts, map[string]interface{}{
+			"type":      "image_url",
+			"image_url": map[string]interface{}{"url": img.dataURL()},
+		})
+	}
+	return parts
+}
+
+// anthropicContent Anthropic格式的消息内容：有图片时图片块在前、文本在后（官方建议的顺序）
+func anthropicContent(m Message) interface{} {
+	if len(m.Images) == 0 {
+		return m.Content
+	}
+	blocks := make([]map[string]interface{}, 0, len(m.Images)+1)
+	for _, img := range m.Images {
+		blocks = append(blocks, map[string]interface{}{
+			"type":   "image",
+			"source": map[string]interface{}{"type": "base64", "media_type": img.MIMEType, "data": img.base64()},
+		})
+	}
+	return append(blocks, map[string]interface{}{"type": "text", "text": m.Content})
+}
@@ -0,0 +1,186 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProviderMock 模拟提供商（按脚本返回预设响应，不发送网络请求、不计费）
+const ProviderMock = "mock"
+
+// defaultMockContent 未提供脚本时的响应（不开仓）
+const defaultMockContent = "Mock AI: no trade this cycle.\n\n[]"
+
+// MockResponse 模拟客户端的一次响应
+type MockResponse struct {
+	Content    string `json:"content"`               // 回复文本（可以是格式错误的内容，用于测试解析和修正流程）
+	StatusCode int    `json:"status_code,omitempty"` // 非0且非200时返回该状态码和Content作为错误响应体（用于测试重试和故障切换）
+	DelayMs    int    `json:"delay_ms,omitempty"`    // 响应前等待的毫秒数（用于测试截止时间和取消）
+}
+
+// Mock 脚本化的模拟AI服务：按顺序返回响应，脚本用完后重复最后一个（Loop时从头循环）
+// 作为HTTP Transport接入Client，请求仍经过重试、缓存、归档和响应解析，结果完全确定
+type Mock struct {
+	Loop bool // 脚本用完后从头循环
+
+	mu        sync.Mutex
+	responses []MockResponse
+	next      int
+	requests  []string // 收到的请求体（按顺序）
+}
+
+// NewMock 创建模拟AI服务（没有响应时始终返回空决策）
+func NewMock(responses ...MockResponse) *Mock {
+	if len(responses) == 0 {
+		responses = []MockResponse{{Content: defaultMockContent}}
+	}
+	return &Mock{responses: responses}
+}
+
+// LoadMock 从JSON文件加载响应脚本（MockResponse数组）
+func LoadMock(path string) (*Mock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取模拟响应文件失败: %w", err)
+	}
+	var responses []MockResponse
+	if err := json.Unmarshal(data, &responses); err != nil {
+		return nil, fmt.Errorf("解析模拟响应文件失败: %w", err)
+	}
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("模拟响应文件 %s 为空", path)
+	}
+	return NewMock(responses...), nil
+}
+
+// Requests 已收到的请求体（用于检查发送给AI的prompt）
+func (m *Mock) Requests() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.requests...)
+}
+
+// Calls 已收到的请求数
+func (m *Mock) Calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.requests)
+}
+
+// Reset 从脚本开头重新开始，并清空请求记录
+func (m *Mock) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next = 0
+	m.requests = nil
+}
+
+// take 记录请求并取出下一个响应
+func (m *Mock) take(body string) MockResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, body)
+	i := m.next
+	if i >= len(m.responses) {
+		if m.Loop {
+			i = 0
+		} else {
+			i = len(m.responses) - 1
+		}
+	}
+	m.next = i + 1
+	return m.responses[i]
+}
+
+// RoundTrip 按脚本返回OpenAI Chat Completions格式的响应（请求要求stream时返回SSE）
+func (m *Mock) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = data
+	}
+	response := m.take(string(body))
+
+	if response.DelayMs > 0 {
+		timer := time.NewTimer(time.Duration(response.DelayMs) * time.Millisecond)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	if response.StatusCode != 0 && response.StatusCode != http.StatusOK {
+		return mockHTTPResponse(req, response.StatusCode, "application/json", response.Content), nil
+	}
+
+	var request struct {
+		Messages []struct {
+			Content json.RawMessage `json:"content"` // 文本，或带图片时的内容块列表
+		} `json:"messages"`
+		Stream bool `json:"stream"`
+	}
+	json.Unmarshal(body, &request)
+	promptTokens := 0
+	for _, msg := range request.Messages {
+		promptTokens += estimateTokens(string(msg.Content))
+	}
+	usage := map[string]int{"prompt_tokens": promptTokens, "completion_tokens": estimateTokens(response.Content)}
+
+	if request.Stream {
+		var sse strings.Builder
+		for _, event := range []interface{}{
+			map[string]interface{}{"choices": []interface{}{map[string]interface{}{"delta": map[string]string{"content": response.Content}}}},
+			map[string]interface{}{"choices": []interface{}{}, "usage": usage},
+		} {
+			data, _ := json.Marshal(event)
+			sse.WriteString("data: " + string(data) + "\n\n")
+		}
+		sse.WriteString("data: [DONE]\n\n")
+		return mockHTTPResponse(req, http.StatusOK, "text/event-stream", sse.String()), nil
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"choices": []interface{}{map[string]interface{}{"message": map[string]string{"role": "assistant", "content": response.Content}}},
+		"usage":   usage,
+	})
+	return mockHTTPResponse(req, http.StatusOK, "application/json", string(data)), nil
+}
+
+// mockHTTPResponse 构建模拟的HTTP响应
+func mockHTTPResponse(req *http.Request, status int, contentType, body string) *http.Response {
+	return &http.Response{
+		StatusCode:    status,
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{contentType}},
+		Body:          io.NopCloser(bytes.NewBufferString(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// SetMock 使用模拟AI服务（不发送网络请求；支持结构化输出、流式输出和工具调用请求，工具调用请求只返回文本）
+func (cfg *Client) SetMock(mock *Mock) {
+	cfg.Provider = openAIProvider{name: ProviderMock, schema: true}
+	cfg.APIKey = "mock"
+	cfg.BaseURL = "http://mock.invalid/v1"
+	cfg.UseFullURL = false
+	cfg.Model = "mock"
+	cfg.Timeout = 30 * time.Second
+	cfg.httpClient = &http.Client{Transport: mock}
+}
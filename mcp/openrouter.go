@@ -0,0 +1,104 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// OpenRouter接口地址和应用标识（OpenRouter按HTTP-Referer / X-Title统计来源应用）
+const (
+	openRouterBaseURL = "https://openrouter.ai/api/v1"
+	openRouterReferer = "https://github.com/tinkle-community/nofx"
+	openRouterTitle   = "NOFX"
+)
+
+// OpenRouterOptions OpenRouter模型路由选项
+type OpenRouterOptions struct {
+	Models        []string // 备用模型：主模型不可用、限流或拒绝请求时由OpenRouter依次改用（请求中的models）
+	ProviderOrder []string // 优先使用的底层提供商（如 "Anthropic"、"Google"），按顺序尝试
+	Sort          string   // 底层提供商排序："price"、"throughput" 或 "latency"（空表示OpenRouter默认负载均衡）
+}
+
+// SetOpenRouterAPI 设置OpenRouter（一个密钥访问多家模型，model为OpenRouter模型ID，如 "anthropic/claude-sonnet-4"）
+func (cfg *Client) SetOpenRouterAPI(apiKey, model string, options OpenRouterOptions) {
+	cfg.Provider = openAIProvider{name: ProviderOpenRouter, schema: true}
+	cfg.APIKey = apiKey
+	cfg.BaseURL = openRouterBaseURL
+	cfg.UseFullURL = false
+	cfg.Model = model
+	cfg.openRouter = &options
+}
+
+// setOpenRouterFields 添加OpenRouter的模型路由参数和来源请求头
+func setOpenRouterFields(cfg *Client, requestBody map[string]interface{}, headers http.Header) {
+	headers.Set("HTTP-Referer", openRouterReferer)
+	headers.Set("X-Title", openRouterTitle)
+	if cfg.openRouter == nil {
+		return
+	}
+	if len(cfg.openRouter.Models) > 0 {
+		requestBody["models"] = append([]string{cfg.Model}, cfg.openRouter.Models...)
+	}
+	provider := map[string]interface{}{}
+	if len(cfg.openRouter.ProviderOrder) > 0 {
+		provider["order"] = cfg.openRouter.ProviderOrder
+	}
+	if cfg.openRouter.Sort != "" {
+		provider["sort"] = cfg.openRouter.Sort
+	}
+	if len(provider) > 0 {
+		requestBody["provider"] = provider
+	}
+}
+
+// LoadOpenRouterPrices 从OpenRouter获取所有模型的价格并写入价格表（按模型ID计费，返回加载的模型数）
+func LoadOpenRouterPrices(ctx context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", openRouterBaseURL+"/models", nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("获取OpenRouter模型价格失败: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("读取OpenRouter模型价格失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var result struct {
+		Data []struct {
+			ID      string `json:"id"`
+			Pricing struct {
+				Prompt     string `json:"prompt"`     // USD / token
+				Completion string `json:"completion"` // USD / token
+			} `json:"pricing"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, fmt.Errorf("解析OpenRouter模型价格失败: %w", err)
+	}
+
+	loaded := 0
+	for _, model := range result.Data {
+		input, err1 := strconv.ParseFloat(model.Pricing.Prompt, 64)
+		output, err2 := strconv.ParseFloat(model.Pricing.Completion, 64)
+		if model.ID == "" || err1 != nil || err2 != nil || input < 0 || output < 0 {
+			continue // 价格为-1表示按路由动态计费，无法预估
+		}
+		SetModelPrice(model.ID, Price{Input: input * 1e6, Output: output * 1e6})
+		loaded++
+	}
+	return loaded, nil
+}
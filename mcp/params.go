@@ -0,0 +1,33 @@
+package mcp
+
+// 生成参数默认值
+const (
+	defaultTemperature = 0.5 // 降低temperature以提高JSON格式稳定性
+	defaultMaxTokens   = 2000
+)
+
+// GenerationParams 生成参数（零值字段使用默认值，提供商不支持的参数忽略）
+type GenerationParams struct {
+	Temperature     *float64 // 默认0.5（0也是有效值，所以用指针区分未设置）
+	TopP            float64  // 0表示使用提供商默认值
+	MaxTokens       int      // 回复最大token数（默认2000，不含思考预算）
+	ReasoningEffort string   // 推理模型的推理强度（OpenAI兼容API: "minimal"、"low"、"medium"、"high"），空表示不设置
+	ThinkingBudget  int      // 思考token预算（Anthropic extended thinking至少1024，Gemini thinkingBudget），0表示关闭思考
+	Stop            []string // 停止序列
+}
+
+// temperature 采样温度
+func (p GenerationParams) temperature() float64 {
+	if p.Temperature != nil {
+		return *p.Temperature
+	}
+	return defaultTemperature
+}
+
+// maxTokens 回复最大token数
+func (p GenerationParams) maxTokens() int {
+	if p.MaxTokens > 0 {
+		return p.MaxTokens
+	}
+	return defaultMaxTokens
+}
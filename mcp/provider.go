@@ -0,0 +1,327 @@
+package mcp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// 提供商名称（与配置中的ai_model一致）
+const (
+	ProviderDeepSeek   = "deepseek"
+	ProviderQwen       = "qwen"
+	ProviderOpenAI     = "openai"
+	ProviderAnthropic  = "anthropic"
+	ProviderGemini     = "gemini"
+	ProviderLocal      = "local" // 本地Ollama或其他OpenAI兼容服务（vLLM、LM Studio），API密钥可选
+	ProviderCustom     = "custom"
+	ProviderAzure      = "azure"      // Azure OpenAI（按部署名称调用，api-key认证）
+	ProviderOpenRouter = "openrouter" // OpenRouter（一个密钥访问多家模型，支持模型路由）
+)
+
+// defaultAzureAPIVersion Azure OpenAI默认API版本
+const defaultAzureAPIVersion = "2024-10-21"
+
+// Provider AI API格式：负责构建请求和解析响应，Client只处理重试和HTTP传输
+type Provider interface {
+	Name() string
+	NewRequest(cfg *Client, messages []Message, schema *ResponseSchema) (*http.Request, error) // 构建HTTP请求（schema为nil时使用普通文本输出）
+	ParseResponse(body []byte) (string, Usage, error)                                          // 从响应中提取回复文本和token用量
+	SupportsSchema() bool                                                                      // 是否支持json_schema结构化输出
+}
+
+// newJSONRequest 创建JSON格式的POST请求
+func newJSONRequest(url string, body interface{}) (*http.Request, error) {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// openAIProvider OpenAI Chat Completions格式（OpenAI、DeepSeek、Qwen兼容模式、本地模型和自定义API）
+type openAIProvider struct {
+	name   string
+	schema bool // 是否支持response_format的json_schema（DeepSeek/Qwen不支持）
+}
+
+func (p openAIProvider) Name() string { return p.name }
+
+func (p openAIProvider) SupportsSchema() bool { return p.schema }
+
+// SupportsImages DeepSeek和Qwen兼容模式的默认模型不支持图片输入
+func (p openAIProvider) SupportsImages() bool {
+	return p.name != ProviderDeepSeek && p.name != ProviderQwen
+}
+
+func (p openAIProvider) NewRequest(cfg *Client, messages []Message, schema *ResponseSchema) (*http.Request, error) {
+	return p.newRequest(cfg, messages, schema, false)
+}
+
+func (p openAIProvider) NewStreamRequest(cfg *Client, messages []Message, schema *ResponseSchema) (*http.Request, error) {
+	return p.newRequest(cfg, messages, schema, true)
+}
+
+// newRequest 构建Chat Completions请求（stream时要求在最后一个事件中返回token用量）
+func (p openAIProvider) newRequest(cfg *Client, messages []Message, schema *ResponseSchema, stream bool) (*http.Request, error) {
+	requestBody := map[string]interface{}{
+		"model":    cfg.Model,
+		"messages": openAIMessages(messages),
+	}
+	setOpenAIParams(requestBody, cfg.Params)
+	p.setPromptCacheKey(requestBody, messages)
+
+	// 注意：response_format 参数仅 OpenAI 支持，DeepSeek/Qwen 不支持
+	// 我们通过强化 prompt 和后处理来确保 JSON 格式正确
+	if schema != nil {
+		requestBody["response_format"] = map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   schema.Name,
+				"strict": true,
+				"schema": schema.Schema,
+			},
+		}
+	}
+
+	if stream {
+		requestBody["stream"] = true
+		requestBody["stream_options"] = map[string]interface{}{"include_usage": true}
+	}
+
+	return p.post(cfg, requestBody)
+}
+
+// post 发送到/chat/completions（UseFullURL时使用完整URL），设置了密钥时带Bearer认证
+// Azure的地址为部署地址加api-version参数，使用api-key请求头认证；OpenRouter附加模型路由参数
+func (p openAIProvider) post(cfg *Client, requestBody map[string]interface{}) (*http.Request, error) {
+	url := cfg.endpoint("/chat/completions")
+	if p.name == ProviderAzure {
+		url += "?api-version=" + cfg.APIVersion
+	}
+	headers := http.Header{}
+	if p.name == ProviderOpenRouter {
+		setOpenRouterFields(cfg, requestBody, headers)
+	}
+	req, err := newJSONRequest(url, requestBody)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+	if cfg.APIKey == "" {
+		return req, nil
+	}
+	if p.name == ProviderAzure {
+		req.Header.Set("api-key", cfg.APIKey)
+	} else {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", cfg.APIKey))
+	}
+	return req, nil
+}
+
+// setOpenAIParams 设置生成参数：推理模型（设置了reasoning_effort）使用max_completion_tokens，且只在显式配置时发送temperature
+func setOpenAIParams(requestBody map[string]interface{}, params GenerationParams) {
+	if params.ReasoningEffort != "" {
+		requestBody["reasoning_effort"] = params.ReasoningEffort
+		requestBody["max_completion_tokens"] = params.maxTokens()
+		if params.Temperature != nil {
+			requestBody["temperature"] = *params.Temperature
+		}
+	} else {
+		requestBody["temperature"] = params.temperature()
+		requestBody["max_tokens"] = params.maxTokens()
+	}
+	if params.TopP > 0 {
+		requestBody["top_p"] = params.TopP
+	}
+	if len(params.Stop) > 0 {
+		requestBody["stop"] = params.Stop
+	}
+}
+
+// openAIMessages 请求中的消息列表（有图片时内容改为文本和图片块的列表）
+func openAIMessages(messages []Message) interface{} {
+	if !hasImages(messages) {
+		return messages
+	}
+	converted := make([]map[string]interface{}, 0, len(messages))
+	for _, m := range messages {
+		converted = append(converted, map[string]interface{}{"role": m.Role, "content": openAIContent(m)})
+	}
+	return converted
+}
+
+// setPromptCacheKey OpenAI对1024 token以上的相同前缀自动缓存；按system prompt设置prompt_cache_key，
+// 使同一策略的请求路由到同一缓存分片，提高命中率（其他兼容API不识别该参数，不发送）
+func (p openAIProvider) setPromptCacheKey(requestBody map[string]interface{}, messages []Message) {
+	if p.name != ProviderOpenAI {
+		return
+	}
+	for _, m := range messages {
+		if m.Role == "system" && m.Content != "" {
+			sum := sha256.Sum256([]byte(m.Content))
+			requestBody["prompt_cache_key"] = "nofx-" + hex.EncodeToString(sum[:8])
+			return
+		}
+	}
+}
+
+// openAIUsage Chat Completions的token用量（缓存命中：OpenAI为prompt_tokens_details.cached_tokens，DeepSeek为prompt_cache_hit_tokens）
+type openAIUsage struct {
+	PromptTokens         int `json:"prompt_tokens"`
+	CompletionTokens     int `json:"completion_tokens"`
+	PromptCacheHitTokens int `json:"prompt_cache_hit_tokens"`
+	PromptTokensDetails  struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+func (u openAIUsage) usage() Usage {
+	cached := u.PromptTokensDetails.CachedTokens
+	if u.PromptCacheHitTokens > cached {
+		cached = u.PromptCacheHitTokens
+	}
+	return Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, CacheReadTokens: cached}
+}
+
+func (p openAIProvider) ParseResponse(body []byte) (string, Usage, error) {
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage openAIUsage `json:"usage"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", Usage{}, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	if len(result.Choices) == 0 {
+		return "", Usage{}, fmt.Errorf("API返回空响应")
+	}
+
+	usage := result.Usage.usage()
+	return result.Choices[0].Message.Content, usage, nil
+}
+
+func (p openAIProvider) ParseStreamEvent(data []byte) (string, Usage, error) {
+	var event struct {
+		Choices []struct {
+			Delta struct {
+				Content string `json:"content"`
+			} `json:"delta"`
+		} `json:"choices"`
+		Usage *openAIUsage `json:"usage"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return "", Usage{}, fmt.Errorf("解析流式响应失败: %w", err)
+	}
+	if event.Error != nil {
+		return "", Usage{}, fmt.Errorf("API返回错误: %s", event.Error.Message)
+	}
+
+	var usage Usage
+	if event.Usage != nil {
+		usage = event.Usage.usage()
+	}
+	if len(event.Choices) == 0 {
+		return "", usage, nil
+	}
+	return event.Choices[0].Delta.Content, usage, nil
+}
+
+func (p openAIProvider) NewToolRequest(cfg *Client, messages []Message, tools []Tool, allowCalls bool) (*http.Request, error) {
+	// 工具调用消息使用OpenAI的tool_calls / tool格式
+	converted := make([]map[string]interface{}, 0, len(messages))
+	for _, m := range messages {
+		msg := map[string]interface{}{"role": m.Role, "content": openAIContent(m)}
+		if len(m.ToolCalls) > 0 {
+			calls := make([]map[string]interface{}, 0, len(m.ToolCalls))
+			for _, call := range m.ToolCalls {
+				calls = append(calls, map[string]interface{}{
+					"id":       call.ID,
+					"type":     "function",
+					"function": map[string]interface{}{"name": call.Name, "arguments": call.Arguments},
+				})
+			}
+			msg["tool_calls"] = calls
+		}
+		if m.Role == "tool" {
+			msg["tool_call_id"] = m.ToolCallID
+		}
+		converted = append(converted, msg)
+	}
+
+	functions := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		functions = append(functions, map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"parameters":  tool.Parameters,
+			},
+		})
+	}
+
+	toolChoice := "auto"
+	if !allowCalls {
+		toolChoice = "none"
+	}
+	requestBody := map[string]interface{}{
+		"model":       cfg.Model,
+		"messages":    converted,
+		"tools":       functions,
+		"tool_choice": toolChoice,
+	}
+	setOpenAIParams(requestBody, cfg.Params)
+	p.setPromptCacheKey(requestBody, messages)
+	return p.post(cfg, requestBody)
+}
+
+func (p openAIProvider) ParseToolResponse(body []byte) (string, []ToolCall, Usage, error) {
+	var result struct {
+		Choices []struct {
+			Message struct {
+				Content   string `json:"content"`
+				ToolCalls []struct {
+					ID       string `json:"id"`
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage openAIUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", nil, Usage{}, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return "", nil, Usage{}, fmt.Errorf("API返回空响应")
+	}
+
+	message := result.Choices[0].Message
+	var calls []ToolCall
+	for _, call := range message.ToolCalls {
+		calls = append(calls, ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: call.Function.Arguments})
+	}
+	usage := result.Usage.usage()
+	return message.Content, calls, usage, nil
+}
@@ -0,0 +1,98 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrDeadline 本周期的AI调用截止时间已到（不再重试，也不切换备用模型）
+var ErrDeadline = errors.New("AI调用超出本周期截止时间")
+
+// ErrCanceled AI调用被取消（如收到退出信号），不再重试，也不切换备用模型
+var ErrCanceled = errors.New("AI调用已取消")
+
+// rateLimiter 客户端限流（相邻请求之间的最小间隔）
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait 等待到允许发送下一个请求（等待会超过ctx截止时间时返回ErrDeadline，ctx取消时返回ErrCanceled）
+func (r *rateLimiter) wait(ctx context.Context) error {
+	r.mu.Lock()
+	at := time.Now()
+	if r.next.After(at) {
+		at = r.next
+	}
+	if deadline, ok := ctx.Deadline(); ok && at.After(deadline) {
+		r.mu.Unlock()
+		return ErrDeadline
+	}
+	r.next = at.Add(r.interval)
+	r.mu.Unlock()
+
+	if err := sleepContext(ctx, time.Until(at)); err != nil {
+		return contextError(ctx, err)
+	}
+	return nil
+}
+
+// sleepContext 等待d，ctx结束时提前返回ctx.Err()
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// contextError ctx结束后的错误：截止时间已到包装为ErrDeadline，被取消包装为ErrCanceled
+func contextError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", ErrDeadline, err)
+	}
+	return fmt.Errorf("%w: %v", ErrCanceled, err)
+}
+
+// SetRateLimit 设置每分钟最多请求数（0表示不限制，主模型和故障切换链各自限流）
+func (cfg *Client) SetRateLimit(requestsPerMinute int) {
+	if requestsPerMinute <= 0 {
+		cfg.limiter = nil
+		return
+	}
+	cfg.limiter = &rateLimiter{interval: time.Minute / time.Duration(requestsPerMinute)}
+}
+
+// isOverloaded 是否为限流或服务过载（429、503、Anthropic的529）
+func isOverloaded(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	switch statusErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable, 529:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter 解析Retry-After响应头（秒数）
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
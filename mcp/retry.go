@@ -0,0 +1,173 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// 默认重试策略
+const (
+	defaultMaxAttempts      = 4
+	defaultBaseBackoff      = 2 * time.Second
+	defaultMaxBackoff       = 30 * time.Second
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 5 * time.Minute
+)
+
+// ErrCircuitOpen AI接口连续失败后熔断：冷却期内直接失败（不发送请求），可切换备用模型，本周期跳过
+var ErrCircuitOpen = errors.New("AI接口已熔断")
+
+// RetryPolicy AI调用的重试和熔断策略（0值字段使用默认值）
+type RetryPolicy struct {
+	MaxAttempts      int           // 每次调用最多尝试次数（含首次，默认4）
+	BaseBackoff      time.Duration // 首次重试前的等待时间，之后指数增长并加随机抖动（默认2秒）
+	MaxBackoff       time.Duration // 单次等待上限（默认30秒，Retry-After也不超过该值）
+	BreakerThreshold int           // 连续N次调用失败（重试用尽后）熔断（默认5，-1关闭熔断）
+	BreakerCooldown  time.Duration // 熔断冷却时间，到期后放行一次试探调用，成功则恢复（默认5分钟）
+}
+
+// DefaultRetryPolicy 默认重试策略
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:      defaultMaxAttempts,
+		BaseBackoff:      defaultBaseBackoff,
+		MaxBackoff:       defaultMaxBackoff,
+		BreakerThreshold: defaultBreakerThreshold,
+		BreakerCooldown:  defaultBreakerCooldown,
+	}
+}
+
+// withDefaults 用默认值补全未设置的字段
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	defaults := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaults.MaxAttempts
+	}
+	if p.BaseBackoff <= 0 {
+		p.BaseBackoff = defaults.BaseBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = defaults.MaxBackoff
+	}
+	if p.BreakerThreshold == 0 {
+		p.BreakerThreshold = defaults.BreakerThreshold
+	}
+	if p.BreakerCooldown <= 0 {
+		p.BreakerCooldown = defaults.BreakerCooldown
+	}
+	return p
+}
+
+// backoff 第attempt次失败后的等待时间（指数退避加随机抖动，服务端给出Retry-After时优先使用）
+func (p RetryPolicy) backoff(attempt int, err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		if statusErr.RetryAfter > p.MaxBackoff {
+			return p.MaxBackoff
+		}
+		return statusErr.RetryAfter
+	}
+	wait := p.BaseBackoff << (attempt - 1)
+	if wait > p.MaxBackoff || wait <= 0 {
+		wait = p.MaxBackoff
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// circuitBreaker 熔断器：连续失败达到阈值后打开，冷却期后半开放行一次试探调用
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int       // 连续失败次数
+	openUntil time.Time // 熔断截止时间（零值表示未熔断）
+	probing   bool      // 半开状态下已有试探调用在进行
+}
+
+// allow 是否允许发送调用（熔断中返回ErrCircuitOpen）
+func (b *circuitBreaker) allow(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return nil
+	}
+	if now.Before(b.openUntil) {
+		return fmt.Errorf("%w（%s后恢复试探）", ErrCircuitOpen, b.openUntil.Sub(now).Round(time.Second))
+	}
+	if b.probing {
+		return fmt.Errorf("%w（试探调用进行中）", ErrCircuitOpen)
+	}
+	b.probing = true
+	return nil
+}
+
+// success 调用成功：清零失败计数并关闭熔断，返回是否从熔断中恢复
+func (b *circuitBreaker) success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	recovered := !b.openUntil.IsZero()
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.probing = false
+	return recovered
+}
+
+// failure 调用失败：连续失败达到阈值（或半开试探失败）时熔断，返回是否本次进入熔断
+func (b *circuitBreaker) failure(now time.Time, policy RetryPolicy) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if policy.BreakerThreshold < 0 {
+		return false
+	}
+	if b.probing || b.failures >= policy.BreakerThreshold {
+		b.openUntil = now.Add(policy.BreakerCooldown)
+		b.probing = false
+		return true
+	}
+	return false
+}
+
+// release 调用因截止时间或取消中止（不代表接口故障）：不计入失败，释放试探名额
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// SetRetryPolicy 设置重试和熔断策略（主模型和故障切换链各自熔断，需要分别设置）
+func (cfg *Client) SetRetryPolicy(policy RetryPolicy) {
+	cfg.policy = policy.withDefaults()
+}
+
+// RetryPolicy 当前重试策略（未设置的字段为默认值）
+func (cfg *Client) RetryPolicy() RetryPolicy {
+	return cfg.policy.withDefaults()
+}
+
+// guard 经熔断器执行一次调用（含重试）：熔断中不发送请求；接口故障类错误计入连续失败
+func (cfg *Client) guard(call func() error) error {
+	if cfg.breaker == nil {
+		return call()
+	}
+	if err := cfg.breaker.allow(time.Now()); err != nil {
+		return err
+	}
+	err := call()
+	switch {
+	case err == nil:
+		if cfg.breaker.success() {
+			fmt.Printf("✅ AI接口 %s 已恢复，解除熔断\n", cfg.Name())
+		}
+	case errors.Is(err, ErrDeadline) || errors.Is(err, ErrCanceled) || !shouldFailover(err):
+		// 截止时间、取消和请求本身的错误（如400）不代表接口不可用
+		cfg.breaker.release()
+	default:
+		policy := cfg.RetryPolicy()
+		if cfg.breaker.failure(time.Now(), policy) {
+			fmt.Printf("🔌 AI接口 %s 连续失败，熔断%v（期间跳过调用）\n", cfg.Name(), policy.BreakerCooldown)
+		}
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// StreamingProvider 支持流式输出（SSE）的API格式
+type StreamingProvider interface {
+	NewStreamRequest(cfg *Client, messages []Message, schema *ResponseSchema) (*http.Request, error) // 构建流式请求
+	ParseStreamEvent(data []byte) (string, Usage, error)                                             // 解析一条SSE数据，返回新增文本和token用量（未包含用量时为0）
+}
+
+// StreamHandler 流式响应回调：每收到新文本时传入目前为止的完整回复，返回true表示已得到所需内容，提前结束接收
+type StreamHandler func(text string) bool
+
+// SupportsStreaming 当前配置是否使用流式输出（显式开启且提供商支持SSE）
+func (cfg *Client) SupportsStreaming() bool {
+	_, ok := cfg.Provider.(StreamingProvider)
+	return cfg.Stream && ok
+}
+
+// CallConversationStream 流式调用AI API，边生成边回调handler（handler返回true时提前结束）
+// 主模型和备用模型不支持流式输出时退化为普通调用，返回完整回复
+func (cfg *Client) CallConversationStream(ctx context.Context, messages []Message, schema *ResponseSchema, handler StreamHandler) (string, error) {
+	if schema != nil && !cfg.SupportsStructuredOutput() {
+		return "", fmt.Errorf("当前AI提供商(%s)不支持结构化输出", cfg.Provider.Name())
+	}
+	return cfg.callChain(ctx, messages, schema, handler)
+}
+
+// callStream 单次流式调用：逐条读取SSE事件并累加回复文本，handler要求结束时关闭连接
+func (cfg *Client) callStream(ctx context.Context, messages []Message, schema *ResponseSchema, handler StreamHandler) (reply string, replyUsage Usage, err error) {
+	req, err := cfg.Provider.(StreamingProvider).NewStreamRequest(cfg, messages, schema)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	started := time.Now()
+	defer func() { cfg.archiveCall(req, started, reply, replyUsage, err) }()
+
+	// 流式响应持续时间较长，整体超时改用context控制，响应关闭后取消
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	resp, err := cfg.doer().Do(req)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body bytes.Buffer
+		body.ReadFrom(resp.Body)
+		return "", Usage{}, &StatusError{StatusCode: resp.StatusCode, Body: body.String(), RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+
+	var text strings.Builder
+	var usage Usage
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue // 忽略event:行、注释和空行
+		}
+		data := bytes.TrimSpace(line[len("data:"):])
+		if len(data) == 0 || string(data) == "[DONE]" {
+			continue
+		}
+
+		delta, eventUsage, err := cfg.Provider.(StreamingProvider).ParseStreamEvent(data)
+		if err != nil {
+			return "", Usage{}, err
+		}
+		// 各提供商在不同事件中给出累计用量，取最新的非零值
+		if eventUsage.PromptTokens > 0 {
+			usage.PromptTokens = eventUsage.PromptTokens
+		}
+		if eventUsage.CompletionTokens > 0 {
+			usage.CompletionTokens = eventUsage.CompletionTokens
+		}
+		if eventUsage.CacheReadTokens > 0 {
+			usage.CacheReadTokens = eventUsage.CacheReadTokens
+		}
+		if eventUsage.CacheWriteTokens > 0 {
+			usage.CacheWriteTokens = eventUsage.CacheWriteTokens
+		}
+		if delta == "" {
+			continue
+		}
+		text.WriteString(delta)
+		if handler != nil && handler(text.String()) {
+			// 提前结束时服务端不再返回用量（OpenAI格式只在最后一个事件中给出），按文本估算
+			if usage.PromptTokens == 0 {
+				for _, msg := range messages {
+					usage.PromptTokens += estimateTokens(msg.Content)
+				}
+			}
+			if usage.CompletionTokens == 0 {
+				usage.CompletionTokens = estimateTokens(text.String())
+			}
+			return text.String(), usage, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", Usage{}, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if text.Len() == 0 {
+		return "", Usage{}, fmt.Errorf("API返回空响应")
+	}
+	return text.String(), usage, nil
+}
+
+// estimateTokens 粗略估算token数（ASCII约4个字符一个token，其他字符各算一个）
+func estimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
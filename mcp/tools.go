@@ -0,0 +1,122 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Tool 可供AI在推理过程中调用的工具（函数调用）
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{} // 参数的JSON Schema（type为object）
+}
+
+// ToolCall AI请求的一次工具调用
+type ToolCall struct {
+	ID        string // 调用ID（工具结果需要带回，Gemini不提供时使用工具名称）
+	Name      string
+	Arguments string // JSON格式的参数
+}
+
+// ToolExecutor 执行工具调用，返回交给AI的结果文本（执行失败时也返回说明文字，由AI自行处理）
+type ToolExecutor func(call ToolCall) string
+
+// ToolProvider 支持原生工具调用的API格式
+type ToolProvider interface {
+	NewToolRequest(cfg *Client, messages []Message, tools []Tool, allowCalls bool) (*http.Request, error) // allowCalls为false时禁止调用工具，要求直接回答
+	ParseToolResponse(body []byte) (string, []ToolCall, Usage, error)                                     // 回复文本、请求的工具调用和token用量
+}
+
+// SupportsTools 当前提供商是否支持原生工具调用
+func (cfg *Client) SupportsTools() bool {
+	_, ok := cfg.Provider.(ToolProvider)
+	return ok
+}
+
+// CallWithTools 带工具调用的对话：AI请求工具时执行并把结果返回给AI，直到AI给出最终回复
+// 最多maxRounds轮工具调用，之后禁止调用工具，要求AI根据已有信息回答
+func (cfg *Client) CallWithTools(ctx context.Context, messages []Message, tools []Tool, execute ToolExecutor, maxRounds int) (string, error) {
+	if !cfg.SupportsTools() {
+		return "", fmt.Errorf("当前AI提供商(%s)不支持工具调用", cfg.Provider.Name())
+	}
+
+	messages = append([]Message(nil), messages...)
+	for round := 0; ; round++ {
+		allowCalls := round < maxRounds
+		content, calls, err := cfg.callToolRound(ctx, messages, tools, allowCalls)
+		if err != nil {
+			return "", err
+		}
+		if len(calls) == 0 || !allowCalls {
+			return content, nil
+		}
+
+		messages = append(messages, Message{Role: "assistant", Content: content, ToolCalls: calls})
+		for _, call := range calls {
+			fmt.Printf("🔧 AI调用工具: %s(%s)\n", call.Name, call.Arguments)
+			messages = append(messages, Message{Role: "tool", Content: execute(call), ToolCallID: call.ID, ToolName: call.Name})
+		}
+	}
+}
+
+// callToolRound 一轮工具调用对话，依次尝试主模型和支持工具调用的备用模型
+func (cfg *Client) callToolRound(ctx context.Context, messages []Message, tools []Tool, allowCalls bool) (string, []ToolCall, error) {
+	chain := append([]*Client{cfg}, cfg.Fallbacks...)
+	var lastErr error
+	for i, client := range chain {
+		if !client.SupportsTools() {
+			continue
+		}
+		if i > 0 && lastErr != nil {
+			fmt.Printf("🔁 AI故障切换: %v，改用 %s\n", lastErr, client.Name())
+		}
+
+		sent := messages
+		if !client.SupportsImages() {
+			sent = withoutImages(messages)
+		}
+		var content string
+		var calls []ToolCall
+		var usage Usage
+		err := client.guard(func() error {
+			return client.retry(ctx, func() error {
+				provider := client.Provider.(ToolProvider)
+				req, err := provider.NewToolRequest(client, sent, tools, allowCalls)
+				if err != nil {
+					return err
+				}
+				started := time.Now()
+				body, err := client.send(ctx, req)
+				if err != nil {
+					client.archiveCall(req, started, "", Usage{}, err)
+					return err
+				}
+				content, calls, usage, err = provider.ParseToolResponse(body)
+				client.archiveCall(req, started, string(body), usage, err)
+				return err
+			})
+		})
+		if err == nil {
+			cfg.lastServed.Store(client.Name())
+			cfg.record(client, usage)
+			return content, calls, nil
+		}
+		lastErr = err
+		if !shouldFailover(err) {
+			return "", nil, err
+		}
+	}
+	return "", nil, lastErr
+}
+
+// toolArguments 调用参数的JSON（参数为空或不是合法JSON时返回空对象）
+func toolArguments(call ToolCall) string {
+	if call.Arguments == "" || !json.Valid([]byte(call.Arguments)) {
+		return "{}"
+	}
+	return call.Arguments
+}
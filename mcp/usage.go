@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"strings"
+	"sync"
+)
+
+// Usage token用量和费用
+type Usage struct {
+	PromptTokens     int     // 输入token数（含缓存命中和写入缓存的部分）
+	CompletionTokens int     // 输出token数
+	CacheReadTokens  int     // 命中提示缓存的输入token数（按折扣价计费）
+	CacheWriteTokens int     // 写入提示缓存的输入token数（Anthropic按溢价计费）
+	CostUSD          float64 // 按模型价格计算的费用（未知模型和本地模型为0）
+	CacheSavingsUSD  float64 // 提示缓存节省的费用（命中节省减去写入溢价）
+}
+
+// Add 累加用量
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.CacheReadTokens += other.CacheReadTokens
+	u.CacheWriteTokens += other.CacheWriteTokens
+	u.CostUSD += other.CostUSD
+	u.CacheSavingsUSD += other.CacheSavingsUSD
+}
+
+// Price 模型价格（USD / 百万token）
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+var (
+	priceMu sync.RWMutex
+	// modelPrices 官方标价（按模型名称前缀匹配，可通过SetModelPrice覆盖或补充）
+	modelPrices = map[string]Price{
+		"deepseek-chat":     {Input: 0.27, Output: 1.10},
+		"deepseek-reasoner": {Input: 0.55, Output: 2.19},
+		"qwen-turbo":        {Input: 0.05, Output: 0.20},
+		"qwen-plus":         {Input: 0.40, Output: 1.20},
+		"qwen-max":          {Input: 1.60, Output: 6.40},
+		"gpt-4o-mini":       {Input: 0.15, Output: 0.60},
+		"gpt-4o":            {Input: 2.50, Output: 10.00},
+		"gpt-4.1-mini":      {Input: 0.40, Output: 1.60},
+		"gpt-4.1":           {Input: 2.00, Output: 8.00},
+		"claude-haiku-4":    {Input: 1.00, Output: 5.00},
+		"claude-sonnet-4":   {Input: 3.00, Output: 15.00},
+		"claude-opus-4":     {Input: 15.00, Output: 75.00},
+		"gemini-2.5-flash":  {Input: 0.30, Output: 2.50},
+		"gemini-2.5-pro":    {Input: 1.25, Output: 10.00},
+	}
+)
+
+// SetModelPrice 设置或覆盖模型价格（自定义API或价格变动时使用）
+func SetModelPrice(model string, price Price) {
+	priceMu.Lock()
+	defer priceMu.Unlock()
+	modelPrices[model] = price
+}
+
+// modelPrice 查找模型价格（最长前缀匹配，如gpt-4o-2024-08-06使用gpt-4o的价格）
+func modelPrice(model string) (Price, bool) {
+	priceMu.RLock()
+	defer priceMu.RUnlock()
+	if price, ok := modelPrices[model]; ok {
+		return price, true
+	}
+	var best string
+	for name := range modelPrices {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	price, ok := modelPrices[best]
+	return price, ok && best != ""
+}
+
+// cacheRates 提示缓存相对于普通输入价格的倍率（命中、写入）
+func cacheRates(provider string) (read, write float64) {
+	switch provider {
+	case ProviderAnthropic:
+		return 0.1, 1.25 // 命中1折，写入（5分钟缓存）加价25%
+	case ProviderGemini:
+		return 0.25, 1
+	case ProviderDeepSeek:
+		return 0.26, 1 // 缓存命中 $0.07 vs 未命中 $0.27
+	default:
+		return 0.5, 1 // OpenAI及兼容API的自动缓存：命中半价，写入不加价
+	}
+}
+
+// usageMeter 客户端累计用量（主模型和故障切换链共用）
+type usageMeter struct {
+	mu    sync.Mutex
+	total Usage
+}
+
+// record 按响应模型的价格计算费用并累加
+func (cfg *Client) record(client *Client, usage Usage) {
+	if client.Provider.Name() != ProviderLocal {
+		if price, ok := modelPrice(client.Model); ok {
+			read, write := cacheRates(client.Provider.Name())
+			uncached := usage.PromptTokens - usage.CacheReadTokens - usage.CacheWriteTokens
+			input := float64(uncached) + float64(usage.CacheReadTokens)*read + float64(usage.CacheWriteTokens)*write
+			usage.CostUSD = (input*price.Input + float64(usage.CompletionTokens)*price.Output) / 1e6
+			usage.CacheSavingsUSD = (float64(usage.CacheReadTokens)*(1-read) - float64(usage.CacheWriteTokens)*(write-1)) * price.Input / 1e6
+		}
+	}
+	if cfg.meter == nil {
+		return
+	}
+	cfg.meter.mu.Lock()
+	defer cfg.meter.mu.Unlock()
+	cfg.meter.total.Add(usage)
+}
+
+// TakeUsage 返回自上次调用以来累计的token用量和费用并清零（包括故障切换到备用模型的调用）
+func (cfg *Client) TakeUsage() Usage {
+	if cfg.meter == nil {
+		return Usage{}
+	}
+	cfg.meter.mu.Lock()
+	defer cfg.meter.mu.Unlock()
+	usage := cfg.meter.total
+	cfg.meter.total = Usage{}
+	return usage
+}
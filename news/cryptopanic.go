@@ -0,0 +1,115 @@
+package news
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CryptoPanic API
+const (
+	cryptoPanicURL     = "https://cryptopanic.com/api/v1/posts/"
+	cryptoPanicTimeout = 10 * time.Second
+)
+
+// CryptoPanic CryptoPanic新闻数据源（需要API Key，免费账户即可）
+type CryptoPanic struct {
+	apiKey string
+	client *http.Client
+}
+
+// NewCryptoPanic 创建CryptoPanic数据源
+func NewCryptoPanic(apiKey string) *CryptoPanic {
+	return &CryptoPanic{
+		apiKey: apiKey,
+		client: &http.Client{Timeout: cryptoPanicTimeout},
+	}
+}
+
+// Name 数据源名称
+func (c *CryptoPanic) Name() string {
+	return "cryptopanic"
+}
+
+// Headlines 一次请求获取所有币种的新闻，按标注的币种分组
+func (c *CryptoPanic) Headlines(symbols []string) (map[string][]Headline, error) {
+	codeToSymbol := make(map[string]string, len(symbols))
+	codes := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		code := strings.TrimSuffix(strings.ToUpper(symbol), "USDT")
+		codeToSymbol[code] = symbol
+		codes = append(codes, code)
+	}
+
+	params := url.Values{}
+	params.Set("auth_token", c.apiKey)
+	params.Set("currencies", strings.Join(codes, ","))
+	params.Set("kind", "news")
+	params.Set("public", "true")
+
+	resp, err := c.client.Get(cryptoPanicURL + "?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("请求失败 (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Results []struct {
+			Title       string    `json:"title"`
+			PublishedAt time.Time `json:"published_at"`
+			Source      struct {
+				Title string `json:"title"`
+			} `json:"source"`
+			Currencies []struct {
+				Code string `json:"code"`
+			} `json:"currencies"`
+			Votes struct {
+				Positive  int `json:"positive"`
+				Negative  int `json:"negative"`
+				Important int `json:"important"`
+				Liked     int `json:"liked"`
+				Disliked  int `json:"disliked"`
+				Toxic     int `json:"toxic"`
+			} `json:"votes"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	headlines := make(map[string][]Headline)
+	for _, post := range result.Results {
+		bullish := post.Votes.Positive + post.Votes.Liked
+		bearish := post.Votes.Negative + post.Votes.Disliked + post.Votes.Toxic
+		h := Headline{
+			Title:       post.Title,
+			Source:      post.Source.Title,
+			PublishedAt: post.PublishedAt,
+			Important:   post.Votes.Important > 0,
+		}
+		switch {
+		case bullish > bearish:
+			h.Sentiment = 1
+		case bearish > bullish:
+			h.Sentiment = -1
+		}
+		for _, currency := range post.Currencies {
+			if symbol, ok := codeToSymbol[currency.Code]; ok {
+				headlines[symbol] = append(headlines[symbol], h)
+			}
+		}
+	}
+	return headlines, nil
+}
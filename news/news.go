@@ -0,0 +1,175 @@
+package news
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认设置
+const (
+	defaultMaxHeadlines = 3               // 每个币种展示的标题数
+	defaultMaxAge       = 24 * time.Hour  // 只展示该时间内的新闻
+	cacheTTL            = 2 * time.Minute // 同一币种的新闻缓存时长（免费API额度有限）
+)
+
+// Headline 新闻标题
+type Headline struct {
+	Title       string    `json:"title"`
+	Source      string    `json:"source"`
+	PublishedAt time.Time `json:"published_at"`
+	Sentiment   int       `json:"sentiment"` // 1=利好，-1=利空，0=中性或未知（来自数据源的投票或标注）
+	Important   bool      `json:"important"` // 数据源标记为重要新闻
+}
+
+// SymbolNews 单个币种的近期新闻
+type SymbolNews struct {
+	Symbol    string     `json:"symbol"`
+	Headlines []Headline `json:"headlines"`  // 新→旧
+	Sentiment float64    `json:"sentiment"`  // 粗略情绪分（-1到1，标题情绪的均值）
+	FetchedAt time.Time  `json:"fetched_at"` // 获取时间（计算新闻发布距今时长，回放时保持一致）
+}
+
+// Provider 新闻数据源（币种使用 BTCUSDT 格式），返回每个币种的近期新闻（新→旧）
+type Provider interface {
+	Name() string
+	Headlines(symbols []string) (map[string][]Headline, error)
+}
+
+// newsCacheEntry 单个币种的新闻缓存
+type newsCacheEntry struct {
+	headlines []Headline
+	fetchedAt time.Time
+}
+
+var (
+	mu           sync.Mutex
+	provider     Provider
+	maxHeadlines = defaultMaxHeadlines
+	maxAge       = defaultMaxAge
+	cache        = make(map[string]newsCacheEntry)
+)
+
+// SetProvider 设置新闻数据源（nil表示禁用），maxHeadlines/maxAgeHours为0时使用默认值
+func SetProvider(p Provider, headlines int, maxAgeHours int) {
+	mu.Lock()
+	defer mu.Unlock()
+	provider = p
+	maxHeadlines = defaultMaxHeadlines
+	if headlines > 0 {
+		maxHeadlines = headlines
+	}
+	maxAge = defaultMaxAge
+	if maxAgeHours > 0 {
+		maxAge = time.Duration(maxAgeHours) * time.Hour
+	}
+	cache = make(map[string]newsCacheEntry)
+}
+
+// Enabled 是否配置了新闻数据源
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return provider != nil
+}
+
+// Get 获取币种的近期新闻（未配置数据源时返回nil，没有近期新闻的币种不在结果中）
+func Get(symbols []string) (map[string]*SymbolNews, error) {
+	mu.Lock()
+	p := provider
+	limit, age := maxHeadlines, maxAge
+	now := time.Now()
+	headlines := make(map[string][]Headline, len(symbols))
+	var missing []string
+	for _, symbol := range symbols {
+		if entry, ok := cache[symbol]; ok && now.Sub(entry.fetchedAt) < cacheTTL {
+			headlines[symbol] = entry.headlines
+		} else {
+			missing = append(missing, symbol)
+		}
+	}
+	mu.Unlock()
+
+	if p == nil {
+		return nil, nil
+	}
+
+	if len(missing) > 0 {
+		fetched, err := p.Headlines(missing)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Name(), err)
+		}
+		mu.Lock()
+		for _, symbol := range missing {
+			cache[symbol] = newsCacheEntry{headlines: fetched[symbol], fetchedAt: now}
+			headlines[symbol] = fetched[symbol]
+		}
+		mu.Unlock()
+	}
+
+	result := make(map[string]*SymbolNews)
+	for symbol, items := range headlines {
+		if summary := summarize(symbol, items, limit, now, age); summary != nil {
+			result[symbol] = summary
+		}
+	}
+	return result, nil
+}
+
+// summarize 保留maxAge内最新的limit条新闻并计算情绪分（没有近期新闻时返回nil）
+func summarize(symbol string, items []Headline, limit int, now time.Time, maxAge time.Duration) *SymbolNews {
+	var recent []Headline
+	for _, h := range items {
+		if now.Sub(h.PublishedAt) < maxAge {
+			recent = append(recent, h)
+		}
+	}
+	if len(recent) == 0 {
+		return nil
+	}
+	sort.Slice(recent, func(i, j int) bool { return recent[i].PublishedAt.After(recent[j].PublishedAt) })
+	if len(recent) > limit {
+		recent = recent[:limit]
+	}
+
+	sum := 0
+	for _, h := range recent {
+		sum += h.Sentiment
+	}
+	return &SymbolNews{
+		Symbol:    symbol,
+		Headlines: recent,
+		Sentiment: float64(sum) / float64(len(recent)),
+		FetchedAt: now,
+	}
+}
+
+// Format 新闻描述（标题按新→旧，带发布时间距获取时的时长）
+func Format(n *SymbolNews) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Recent news (sentiment %+.2f):\n", n.Sentiment))
+	for _, h := range n.Headlines {
+		tags := ""
+		if h.Important {
+			tags += " [important]"
+		}
+		switch h.Sentiment {
+		case 1:
+			tags += " [bullish]"
+		case -1:
+			tags += " [bearish]"
+		}
+		sb.WriteString(fmt.Sprintf("- %s ago: %s (%s)%s\n", formatAge(n.FetchedAt.Sub(h.PublishedAt)), h.Title, h.Source, tags))
+	}
+	return sb.String()
+}
+
+// formatAge 时长简写（分钟/小时）
+func formatAge(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%.1fh", d.Hours())
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"nofx/market"
 	"os"
 	"path/filepath"
 	"strings"
@@ -316,50 +317,9 @@ func GetTopRatedCoins(limit int) ([]string, error) {
 	return symbols, nil
 }
 
-// normalizeSymbol 标准化币种符号
+// normalizeSymbol 标准化币种符号（映射为交易所实际上线的USDT永续合约，如PEPE -> 1000PEPEUSDT）
 func normalizeSymbol(symbol string) string {
-	// 移除空格
-	symbol = trimSpaces(symbol)
-
-	// 转为大写
-	symbol = toUpper(symbol)
-
-	// 确保以USDT结尾
-	if !endsWith(symbol, "USDT") {
-		symbol = symbol + "USDT"
-	}
-
-	return symbol
-}
-
-// 辅助函数
-func trimSpaces(s string) string {
-	result := ""
-	for i := 0; i < len(s); i++ {
-		if s[i] != ' ' {
-			result += string(s[i])
-		}
-	}
-	return result
-}
-
-func toUpper(s string) string {
-	result := ""
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		if c >= 'a' && c <= 'z' {
-			c = c - 'a' + 'A'
-		}
-		result += string(c)
-	}
-	return result
-}
-
-func endsWith(s, suffix string) bool {
-	if len(s) < len(suffix) {
-		return false
-	}
-	return s[len(s)-len(suffix):] == suffix
+	return market.Canonical(symbol)
 }
 
 // convertSymbolsToCoins 将币种符号列表转换为CoinInfo列表
@@ -418,8 +378,13 @@ var oiTopConfig = struct {
 	CacheDir: "coin_pool_cache",
 }
 
-// GetOITopPositions 获取持仓量增长Top20数据（带重试和缓存）
+// GetOITopPositions 获取持仓量增长Top20数据（带重试和缓存；配置了多个时间窗口时使用第一个）
 func GetOITopPositions() ([]OIPosition, error) {
+	return getOITopPositions(primaryOITopWindow())
+}
+
+// getOITopPositions 获取指定时间窗口的持仓量增长排名（零值窗口使用API URL自带的参数）
+func getOITopPositions(window OITopWindow) ([]OIPosition, error) {
 	// 检查API URL是否配置
 	if strings.TrimSpace(oiTopConfig.APIURL) == "" {
 		log.Printf("⚠️  未配置OI Top API URL，跳过OI Top数据获取")
@@ -436,13 +401,13 @@ func GetOITopPositions() ([]OIPosition, error) {
 			time.Sleep(2 * time.Second)
 		}
 
-		positions, err := fetchOITop()
+		positions, err := fetchOITop(window)
 		if err == nil {
 			if attempt > 1 {
 				log.Printf("✓ 第%d次重试成功", attempt)
 			}
 			// 成功获取后保存到缓存
-			if err := saveOITopCache(positions); err != nil {
+			if err := saveOITopCache(window, positions); err != nil {
 				log.Printf("⚠️  保存OI Top缓存失败: %v", err)
 			}
 			return positions, nil
@@ -454,7 +419,7 @@ func GetOITopPositions() ([]OIPosition, error) {
 
 	// API获取失败，尝试使用缓存
 	log.Printf("⚠️  OI Top API请求全部失败，尝试使用历史缓存数据...")
-	cachedPositions, err := loadOITopCache()
+	cachedPositions, err := loadOITopCache(window)
 	if err == nil {
 		log.Printf("✓ 使用历史OI Top缓存数据（共%d个币种）", len(cachedPositions))
 		return cachedPositions, nil
@@ -466,14 +431,19 @@ func GetOITopPositions() ([]OIPosition, error) {
 }
 
 // fetchOITop 实际执行OI Top请求
-func fetchOITop() ([]OIPosition, error) {
+func fetchOITop(window OITopWindow) ([]OIPosition, error) {
 	log.Printf("🔄 正在请求OI Top数据...")
 
+	apiURL, err := window.apiURL(oiTopConfig.APIURL)
+	if err != nil {
+		return nil, fmt.Errorf("OI Top API URL无效: %w", err)
+	}
+
 	client := &http.Client{
 		Timeout: oiTopConfig.Timeout,
 	}
 
-	resp, err := client.Get(oiTopConfig.APIURL)
+	resp, err := client.Get(apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("请求OI Top API失败: %w", err)
 	}
@@ -508,7 +478,7 @@ func fetchOITop() ([]OIPosition, error) {
 }
 
 // saveOITopCache 保存OI Top数据到缓存
-func saveOITopCache(positions []OIPosition) error {
+func saveOITopCache(window OITopWindow, positions []OIPosition) error {
 	if err := os.MkdirAll(oiTopConfig.CacheDir, 0755); err != nil {
 		return fmt.Errorf("创建缓存目录失败: %w", err)
 	}
@@ -524,7 +494,7 @@ func saveOITopCache(positions []OIPosition) error {
 		return fmt.Errorf("序列化OI Top缓存数据失败: %w", err)
 	}
 
-	cachePath := filepath.Join(oiTopConfig.CacheDir, "oi_top_latest.json")
+	cachePath := filepath.Join(oiTopConfig.CacheDir, window.cacheFile())
 	if err := ioutil.WriteFile(cachePath, data, 0644); err != nil {
 		return fmt.Errorf("写入OI Top缓存文件失败: %w", err)
 	}
@@ -534,8 +504,8 @@ func saveOITopCache(positions []OIPosition) error {
 }
 
 // loadOITopCache 从缓存加载OI Top数据
-func loadOITopCache() ([]OIPosition, error) {
-	cachePath := filepath.Join(oiTopConfig.CacheDir, "oi_top_latest.json")
+func loadOITopCache(window OITopWindow) ([]OIPosition, error) {
+	cachePath := filepath.Join(oiTopConfig.CacheDir, window.cacheFile())
 
 	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("OI Top缓存文件不存在")
@@ -579,67 +549,89 @@ func GetOITopSymbols() ([]string, error) {
 	return symbols, nil
 }
 
-// MergedCoinPool 合并的币种池（AI500 + OI Top）
+// MergedCoinPool 合并的币种池（所有启用的来源，默认AI500 + OI Top）
 type MergedCoinPool struct {
-	AI500Coins    []CoinInfo          // AI500评分币种
-	OITopCoins    []OIPosition        // 持仓量增长Top20
-	AllSymbols    []string            // 所有不重复的币种符号
-	SymbolSources map[string][]string // 每个币种的来源（"ai500"/"oi_top"）
+	AllSymbols    []string                // 所有不重复的币种符号（按来源顺序）
+	SymbolSources map[string][]string     // 每个币种的来源（例如"ai500"/"oi_top"）
+	Scores        map[string]float64      // 每个币种在各来源中的最高评分
+	Tags          map[string][]string     // 每个币种在各来源中的标签（去重）
+	Ranks         map[string][]SourceRank // 每个币种在各来源中的排名和评分（自选币种不排名）
 }
 
-// GetMergedCoinPool 获取合并后的币种池（AI500 + OI Top，去重）
-func GetMergedCoinPool(ai500Limit int) (*MergedCoinPool, error) {
-	// 1. 获取AI500数据
-	ai500TopSymbols, err := GetTopRatedCoins(ai500Limit)
-	if err != nil {
-		log.Printf("⚠️  获取AI500数据失败: %v", err)
-		ai500TopSymbols = []string{} // 失败时用空列表
+// GetMergedCoinPool 获取合并后的币种池（自选币种在前，再依次获取启用的来源，去重并排除黑名单；单个来源失败不影响其他来源）
+// 设置了刷新间隔时，间隔内返回上次的合并结果（多个trader共享，调用方不应修改）
+func GetMergedCoinPool() (*MergedCoinPool, error) {
+	if cached := cachedMergedPool(); cached != nil {
+		return cached, nil
 	}
 
-	// 2. 获取OI Top数据
-	oiTopSymbols, err := GetOITopSymbols()
-	if err != nil {
-		log.Printf("⚠️  获取OI Top数据失败: %v", err)
-		oiTopSymbols = []string{} // 失败时用空列表
+	merged := &MergedCoinPool{
+		SymbolSources: make(map[string][]string),
+		Scores:        make(map[string]float64),
+		Tags:          make(map[string][]string),
+		Ranks:         make(map[string][]SourceRank),
+	}
+
+	excluded := excludedSymbols()
+	add := func(sourceName string, candidates []Candidate) int {
+		count := 0
+		ranks := sourceRanks(candidates)
+		for i, c := range candidates {
+			if excluded[c.Symbol] {
+				continue
+			}
+			if sourceName != SourceWatchlist {
+				merged.Ranks[c.Symbol] = append(merged.Ranks[c.Symbol], SourceRank{
+					Source: sourceName,
+					Rank:   ranks[i],
+					Total:  len(candidates),
+					Score:  c.Score,
+				})
+			}
+			count++
+			if _, seen := merged.SymbolSources[c.Symbol]; !seen {
+				merged.AllSymbols = append(merged.AllSymbols, c.Symbol)
+			}
+			merged.SymbolSources[c.Symbol] = append(merged.SymbolSources[c.Symbol], sourceName)
+			if c.Score > merged.Scores[c.Symbol] {
+				merged.Scores[c.Symbol] = c.Score
+			}
+			for _, tag := range c.Tags {
+				if !containsString(merged.Tags[c.Symbol], tag) {
+					merged.Tags[c.Symbol] = append(merged.Tags[c.Symbol], tag)
+				}
+			}
+		}
+		return count
 	}
 
-	// 3. 合并并去重
-	symbolSet := make(map[string]bool)
-	symbolSources := make(map[string][]string)
+	add(SourceWatchlist, watchlistCandidates())
 
-	// 添加AI500币种
-	for _, symbol := range ai500TopSymbols {
-		symbolSet[symbol] = true
-		symbolSources[symbol] = append(symbolSources[symbol], "ai500")
-	}
-
-	// 添加OI Top币种
-	for _, symbol := range oiTopSymbols {
-		if !symbolSet[symbol] {
-			symbolSet[symbol] = true
+	list, settings := activeSourceList()
+	counts := make([]int, len(list))
+	for i, source := range list {
+		candidates, err := fetchSource(source, settings[i].Limit)
+		if err != nil {
+			log.Printf("⚠️  获取%s数据失败: %v", source.Name(), err)
+			continue
 		}
-		symbolSources[symbol] = append(symbolSources[symbol], "oi_top")
+		counts[i] = add(source.Name(), candidates)
 	}
 
-	// 转换为数组
-	var allSymbols []string
-	for symbol := range symbolSet {
-		allSymbols = append(allSymbols, symbol)
+	if len(excluded) > 0 {
+		log.Printf("🚫 黑名单币种（含新上线合约）: %d个", len(excluded))
 	}
+	logSourceCounts(settings, counts, len(merged.AllSymbols))
+	storeMergedPool(merged)
+	return merged, nil
+}
 
-	// 获取完整数据
-	ai500Coins, _ := GetCoinPool()
-	oiTopPositions, _ := GetOITopPositions()
-
-	merged := &MergedCoinPool{
-		AI500Coins:    ai500Coins,
-		OITopCoins:    oiTopPositions,
-		AllSymbols:    allSymbols,
-		SymbolSources: symbolSources,
+// containsString 切片是否包含指定字符串
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
 	}
-
-	log.Printf("📊 币种池合并完成: AI500=%d, OI_Top=%d, 总计(去重)=%d",
-		len(ai500TopSymbols), len(oiTopSymbols), len(allSymbols))
-
-	return merged, nil
+	return false
 }
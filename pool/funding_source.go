@@ -0,0 +1,100 @@
+package pool
+
+import (
+	"math"
+	"nofx/market"
+	"sort"
+	"sync"
+)
+
+// SourceFunding 资金费率极端值和翻转
+const SourceFunding = "funding"
+
+// 资金费率来源参数
+const (
+	fundingUniverse    = 100    // 只在24小时成交额前N名中统计
+	fundingPerSide     = 5      // 正/负资金费率各取前N个
+	fundingExtremeRate = 0.0005 // 极端资金费率阈值（0.05%/8h，约为基准费率的5倍）
+	fundingFlipMinRate = 0.0001 // 翻转前后的资金费率绝对值都需达到该值（过滤0附近的抖动）
+	fundingTagHigh     = "funding_high"
+	fundingTagLow      = "funding_low"
+	fundingTagFlip     = "funding_flip"
+	fundingTagLongSqz  = "long_squeeze"  // 多头拥挤，容易多杀多
+	fundingTagShortSqz = "short_squeeze" // 空头拥挤，容易轧空
+)
+
+// fundingSource 资金费率极端或刚翻转的币种（挤压行情候选）
+type fundingSource struct {
+	mu       sync.Mutex
+	previous map[string]float64 // 上一次获取时的资金费率（用于识别翻转）
+}
+
+func init() {
+	RegisterSource(&fundingSource{})
+}
+
+func (*fundingSource) Name() string { return SourceFunding }
+
+func (s *fundingSource) Fetch() ([]Candidate, error) {
+	rates, err := market.GetFundingRates(fundingUniverse)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	previous := s.previous
+	s.previous = make(map[string]float64, len(rates))
+	for _, r := range rates {
+		s.previous[r.Symbol] = r.Rate
+	}
+	s.mu.Unlock()
+
+	tags := make(map[string][]string)
+	var order []string
+	tag := func(symbol string, values ...string) {
+		if _, ok := tags[symbol]; !ok {
+			order = append(order, symbol)
+		}
+		tags[symbol] = append(tags[symbol], values...)
+	}
+
+	sorted := append([]market.FundingRate(nil), rates...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Rate > sorted[j].Rate })
+	for i := 0; i < fundingPerSide && i < len(sorted) && sorted[i].Rate >= fundingExtremeRate; i++ {
+		tag(sorted[i].Symbol, fundingTagHigh, fundingTagLongSqz)
+	}
+	for i := len(sorted) - 1; i >= len(sorted)-fundingPerSide && i >= 0 && sorted[i].Rate <= -fundingExtremeRate; i-- {
+		tag(sorted[i].Symbol, fundingTagLow, fundingTagShortSqz)
+	}
+
+	// 资金费率翻转（与上一次获取相比正负号改变）
+	for _, r := range rates {
+		prev, ok := previous[r.Symbol]
+		if !ok || math.Abs(prev) < fundingFlipMinRate || math.Abs(r.Rate) < fundingFlipMinRate || (prev > 0) == (r.Rate > 0) {
+			continue
+		}
+		squeeze := fundingTagShortSqz
+		if r.Rate > 0 {
+			squeeze = fundingTagLongSqz
+		}
+		if !containsString(tags[r.Symbol], squeeze) {
+			tag(r.Symbol, fundingTagFlip, squeeze)
+		} else {
+			tag(r.Symbol, fundingTagFlip)
+		}
+	}
+
+	bySymbol := make(map[string]float64, len(rates))
+	for _, r := range rates {
+		bySymbol[r.Symbol] = r.Rate
+	}
+	candidates := make([]Candidate, 0, len(order))
+	for _, symbol := range order {
+		candidates = append(candidates, Candidate{
+			Symbol: symbol,
+			Score:  math.Round(math.Abs(bySymbol[symbol])*1e6) / 100, // 资金费率绝对值（基点）
+			Tags:   tags[symbol],
+		})
+	}
+	return candidates, nil
+}
@@ -0,0 +1,72 @@
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// httpSource 自定义筛选器（通过HTTP接口返回候选币种，在配置中注册）
+type httpSource struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+// httpSourceResponse 自定义筛选器接口返回的数据结构
+type httpSourceResponse struct {
+	Success bool `json:"success"`
+	Data    struct {
+		Candidates []struct {
+			Symbol string   `json:"symbol"`
+			Score  float64  `json:"score"`
+			Tags   []string `json:"tags"`
+		} `json:"candidates"`
+	} `json:"data"`
+}
+
+// NewHTTPSource 创建HTTP筛选器来源
+func NewHTTPSource(name, url string) Source {
+	return &httpSource{
+		name:   name,
+		url:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *httpSource) Name() string { return s.name }
+
+func (s *httpSource) Fetch() ([]Candidate, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, fmt.Errorf("请求%s失败: %w", s.name, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var response httpSourceResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("JSON解析失败: %w", err)
+	}
+	if !response.Success {
+		return nil, fmt.Errorf("API返回失败状态")
+	}
+
+	candidates := make([]Candidate, 0, len(response.Data.Candidates))
+	for _, c := range response.Data.Candidates {
+		if c.Symbol == "" {
+			continue
+		}
+		candidates = append(candidates, Candidate{Symbol: c.Symbol, Score: c.Score, Tags: c.Tags})
+	}
+	return candidates, nil
+}
@@ -0,0 +1,76 @@
+package pool
+
+import (
+	"math"
+	"nofx/market"
+	"sort"
+)
+
+// SourceMovers 1小时/24小时涨跌幅榜
+const SourceMovers = "movers"
+
+// 涨跌幅榜参数
+const (
+	moversUniverse = 60 // 只在24小时成交额前N名中统计（过滤流动性差的币种）
+	moversPerList  = 5  // 每个榜单（1h涨幅、1h跌幅、24h涨幅、24h跌幅）取前N个
+)
+
+// moversSource 涨跌幅最大的币种（动量突破是做多候选，冲顶回落是做空候选）
+type moversSource struct{}
+
+func init() {
+	RegisterSource(moversSource{})
+}
+
+func (moversSource) Name() string { return SourceMovers }
+
+func (moversSource) Fetch() ([]Candidate, error) {
+	movers, err := market.GetMovers(moversUniverse)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string][]string)
+	var order []string
+	addList := func(tag string, change func(m market.Mover) float64, descending bool) {
+		sorted := append([]market.Mover(nil), movers...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			if descending {
+				return change(sorted[i]) > change(sorted[j])
+			}
+			return change(sorted[i]) < change(sorted[j])
+		})
+		for i := 0; i < moversPerList && i < len(sorted); i++ {
+			// 只收录方向一致的币种（例如涨幅榜不收录下跌的币种）
+			if c := change(sorted[i]); c == 0 || (c > 0) != descending {
+				break
+			}
+			symbol := sorted[i].Symbol
+			if _, ok := tags[symbol]; !ok {
+				order = append(order, symbol)
+			}
+			tags[symbol] = append(tags[symbol], tag)
+		}
+	}
+	change1h := func(m market.Mover) float64 { return m.Change1hPct }
+	change24h := func(m market.Mover) float64 { return m.Change24hPct }
+	addList("gainer_1h", change1h, true)
+	addList("loser_1h", change1h, false)
+	addList("gainer_24h", change24h, true)
+	addList("loser_24h", change24h, false)
+
+	bySymbol := make(map[string]market.Mover, len(movers))
+	for _, m := range movers {
+		bySymbol[m.Symbol] = m
+	}
+	candidates := make([]Candidate, 0, len(order))
+	for _, symbol := range order {
+		m := bySymbol[symbol]
+		candidates = append(candidates, Candidate{
+			Symbol: symbol,
+			Score:  math.Round(math.Max(math.Abs(m.Change1hPct), math.Abs(m.Change24hPct))*100) / 100, // 最大涨跌幅（百分比）
+			Tags:   tags[symbol],
+		})
+	}
+	return candidates, nil
+}
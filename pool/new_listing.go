@@ -0,0 +1,94 @@
+package pool
+
+import (
+	"log"
+	"nofx/market"
+	"sync"
+	"time"
+)
+
+// SourceNewListing 新上线永续合约
+const SourceNewListing = "new_listing"
+
+// 新上线合约处理方式
+const (
+	NewListingCandidate = "candidate" // 加入候选币种池（带new_listing标签，需满足更严格的成交额要求）
+	NewListingBlacklist = "blacklist" // 从候选币种池中排除（上线初期波动大、深度浅）
+)
+
+var (
+	newListingMu     sync.RWMutex
+	newListingConfig = struct {
+		Mode           string        // 处理方式（空=不处理）
+		MaxAge         time.Duration // 上线时间在该范围内视为新上线
+		MinQuoteVolume float64       // 加入候选池所需的最低24小时成交额（USDT）
+	}{
+		MaxAge:         72 * time.Hour,
+		MinQuoteVolume: 50_000_000,
+	}
+)
+
+func init() {
+	RegisterSource(newListingSource{})
+}
+
+// SetNewListings 设置新上线合约的处理方式（maxAgeHours、minQuoteVolume为0时使用默认值72小时、5000万USDT）
+func SetNewListings(mode string, maxAgeHours int, minQuoteVolume float64) {
+	newListingMu.Lock()
+	defer newListingMu.Unlock()
+	newListingConfig.Mode = mode
+	if maxAgeHours > 0 {
+		newListingConfig.MaxAge = time.Duration(maxAgeHours) * time.Hour
+	}
+	if minQuoteVolume > 0 {
+		newListingConfig.MinQuoteVolume = minQuoteVolume
+	}
+	if mode == NewListingCandidate {
+		EnableSource(SourceNewListing)
+	}
+}
+
+// newListingSource 新上线且成交额达标的永续合约
+type newListingSource struct{}
+
+func (newListingSource) Name() string { return SourceNewListing }
+
+func (newListingSource) Fetch() ([]Candidate, error) {
+	newListingMu.RLock()
+	maxAge, minQuoteVolume := newListingConfig.MaxAge, newListingConfig.MinQuoteVolume
+	newListingMu.RUnlock()
+
+	listings, err := market.GetNewListings(time.Now(), maxAge)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []Candidate
+	for _, listing := range listings {
+		if listing.QuoteVolume24h < minQuoteVolume {
+			continue
+		}
+		candidates = append(candidates, Candidate{Symbol: listing.Symbol, Tags: []string{SourceNewListing}})
+	}
+	return candidates, nil
+}
+
+// newListingBlacklist 需要从候选池排除的新上线合约（未启用黑名单或获取失败时返回nil）
+func newListingBlacklist() map[string]bool {
+	newListingMu.RLock()
+	mode, maxAge := newListingConfig.Mode, newListingConfig.MaxAge
+	newListingMu.RUnlock()
+	if mode != NewListingBlacklist {
+		return nil
+	}
+
+	listings, err := market.GetNewListings(time.Now(), maxAge)
+	if err != nil {
+		log.Printf("⚠️  获取新上线合约失败，本周期不排除: %v", err)
+		return nil
+	}
+	blacklist := make(map[string]bool, len(listings))
+	for _, listing := range listings {
+		blacklist[listing.Symbol] = true
+	}
+	return blacklist
+}
@@ -0,0 +1,84 @@
+package pool
+
+import (
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// OITopWindows 支持的持仓量增长统计窗口
+var OITopWindows = []string{"15m", "1h", "4h"}
+
+// ValidOITopWindow 是否是支持的统计窗口
+func ValidOITopWindow(window string) bool {
+	return containsString(OITopWindows, window)
+}
+
+// OITopWindow 持仓量增长排名的统计窗口和排名深度
+type OITopWindow struct {
+	Window string // 统计窗口（15m/1h/4h，空=使用API URL自带的参数）
+	Limit  int    // 取排名前N个（0=使用API默认值）
+}
+
+var (
+	oiTopWindowMu sync.RWMutex
+	oiTopWindows  []OITopWindow
+)
+
+// SetOITopWindows 设置持仓量增长排名的统计窗口（可同时配置多个，候选币种带 oi_top_<窗口> 标签）
+func SetOITopWindows(windows []OITopWindow) {
+	oiTopWindowMu.Lock()
+	defer oiTopWindowMu.Unlock()
+	oiTopWindows = append([]OITopWindow(nil), windows...)
+}
+
+// configuredOITopWindows 已配置的统计窗口（未配置时为一个零值窗口）
+func configuredOITopWindows() []OITopWindow {
+	oiTopWindowMu.RLock()
+	defer oiTopWindowMu.RUnlock()
+	if len(oiTopWindows) == 0 {
+		return []OITopWindow{{}}
+	}
+	return append([]OITopWindow(nil), oiTopWindows...)
+}
+
+// primaryOITopWindow 第一个统计窗口（用于提示词中的OI Top数据）
+func primaryOITopWindow() OITopWindow {
+	return configuredOITopWindows()[0]
+}
+
+// apiURL 在API URL上设置统计窗口（duration）和排名深度（limit）参数
+func (w OITopWindow) apiURL(base string) (string, error) {
+	if w.Window == "" && w.Limit == 0 {
+		return base, nil
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	if w.Window != "" {
+		query.Set("duration", w.Window)
+	}
+	if w.Limit > 0 {
+		query.Set("limit", strconv.Itoa(w.Limit))
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// cacheFile 缓存文件名（每个窗口单独缓存）
+func (w OITopWindow) cacheFile() string {
+	if w.Window == "" {
+		return "oi_top_latest.json"
+	}
+	return "oi_top_" + w.Window + "_latest.json"
+}
+
+// tag 候选币种标签（未配置窗口时不加标签）
+func (w OITopWindow) tag() string {
+	if w.Window == "" {
+		return ""
+	}
+	return SourceOITop + "_" + w.Window
+}
@@ -0,0 +1,48 @@
+package pool
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	refreshMu       sync.Mutex
+	refreshInterval time.Duration // 币种池刷新间隔（0=每次都重新获取）
+	lastPool        *MergedCoinPool
+	lastPoolAt      time.Time
+)
+
+// SetRefreshInterval 设置币种池刷新间隔（与决策周期无关，间隔内直接返回上次合并结果）
+func SetRefreshInterval(interval time.Duration) {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	refreshInterval = interval
+	lastPool = nil
+}
+
+// cachedMergedPool 返回仍在刷新间隔内的上次合并结果（没有则返回nil）
+func cachedMergedPool() *MergedCoinPool {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	if refreshInterval <= 0 || lastPool == nil {
+		return nil
+	}
+	age := time.Since(lastPoolAt)
+	if age >= refreshInterval {
+		return nil
+	}
+	log.Printf("✓ 使用缓存的合并币种池（%d个币种，%.0f分钟前刷新，下次刷新约%.0f分钟后）",
+		len(lastPool.AllSymbols), age.Minutes(), (refreshInterval - age).Minutes())
+	return lastPool
+}
+
+// storeMergedPool 保存本次合并结果
+func storeMergedPool(merged *MergedCoinPool) {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	if refreshInterval > 0 {
+		lastPool = merged
+		lastPoolAt = time.Now()
+	}
+}
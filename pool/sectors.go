@@ -0,0 +1,72 @@
+package pool
+
+import (
+	"nofx/market"
+	"strings"
+	"sync"
+)
+
+// 板块名称
+const (
+	SectorL1   = "L1"
+	SectorL2   = "L2"
+	SectorMeme = "meme"
+	SectorAI   = "AI"
+	SectorDeFi = "DeFi"
+)
+
+// defaultSectors 内置板块映射（基础币种 -> 板块，可通过配置 sectors 补充或覆盖）
+var defaultSectors = map[string][]string{
+	SectorL1: {
+		"BTC", "ETH", "SOL", "BNB", "XRP", "ADA", "AVAX", "DOT", "TRX", "TON", "NEAR", "APT", "SUI", "SEI",
+		"ATOM", "ALGO", "LTC", "BCH", "ETC", "HBAR", "ICP", "KAS", "XLM", "TIA", "FTM", "S", "BERA",
+	},
+	SectorL2: {
+		"ARB", "OP", "POL", "MATIC", "STRK", "MNT", "IMX", "ZK", "METIS", "MANTA", "BLAST", "SCR", "STX",
+	},
+	SectorMeme: {
+		"DOGE", "SHIB", "PEPE", "WIF", "BONK", "FLOKI", "BOME", "MEW", "POPCAT", "PNUT", "TRUMP", "FARTCOIN",
+		"MOODENG", "NEIRO", "BRETT", "TURBO", "GOAT", "PENGU", "SPX", "MEME", "PEOPLE",
+	},
+	SectorAI: {
+		"FET", "RENDER", "TAO", "WLD", "ARKM", "AI16Z", "VIRTUAL", "GRT", "IO", "AIXBT", "GRIFFAIN", "ZEREBRO", "NFP",
+	},
+	SectorDeFi: {
+		"UNI", "AAVE", "MKR", "LDO", "CRV", "COMP", "SNX", "DYDX", "JUP", "PENDLE", "ENA", "ONDO", "HYPE", "INJ",
+		"RUNE", "CAKE", "SUSHI", "1INCH", "GMX", "ETHFI", "JTO", "RAY", "MORPHO", "EIGEN",
+	},
+}
+
+var (
+	sectorMu  sync.RWMutex
+	coinToSec = buildSectorIndex(defaultSectors)
+)
+
+// buildSectorIndex 基础币种 -> 板块
+func buildSectorIndex(sectors map[string][]string) map[string]string {
+	index := make(map[string]string)
+	for sector, coins := range sectors {
+		for _, coin := range coins {
+			index[strings.ToUpper(coin)] = sector
+		}
+	}
+	return index
+}
+
+// SetSectors 补充或覆盖板块映射（板块名称 -> 基础币种或交易对列表，同一币种以配置为准）
+func SetSectors(sectors map[string][]string) {
+	sectorMu.Lock()
+	defer sectorMu.Unlock()
+	for sector, coins := range sectors {
+		for _, coin := range coins {
+			coinToSec[market.BaseAsset(coin)] = sector
+		}
+	}
+}
+
+// Sector 交易对所属板块（未收录时返回空字符串）
+func Sector(symbol string) string {
+	sectorMu.RLock()
+	defer sectorMu.RUnlock()
+	return coinToSec[market.BaseAsset(symbol)]
+}
@@ -0,0 +1,188 @@
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"nofx/market"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SourceSocial 社交热度币种
+const SourceSocial = "social"
+
+// 社交热度数据源
+const (
+	SocialCoinGecko  = "coingecko"  // CoinGecko热搜（无需API Key）
+	SocialLunarCrush = "lunarcrush" // LunarCrush Galaxy Score排名（需要API Key）
+)
+
+// 社交热度来源参数
+const (
+	socialRefreshInterval = 10 * time.Minute // 热度榜刷新间隔（免费接口有频率限制）
+	socialDefaultLimit    = 10               // 默认取热度榜前N个
+	socialTimeout         = 10 * time.Second
+)
+
+// socialSource 社交媒体热度榜上的币种（映射为交易所合约，叙事驱动的候选）
+type socialSource struct {
+	provider string
+	apiKey   string
+	limit    int
+	client   *http.Client
+
+	mu        sync.Mutex
+	fetchedAt time.Time
+	coins     []string // 上次获取的热度榜币种（基础币种，如PEPE）
+}
+
+// NewSocialSource 创建社交热度来源（limit为0时取前10个）
+func NewSocialSource(provider, apiKey string, limit int) Source {
+	if limit <= 0 {
+		limit = socialDefaultLimit
+	}
+	return &socialSource{
+		provider: provider,
+		apiKey:   apiKey,
+		limit:    limit,
+		client:   &http.Client{Timeout: socialTimeout},
+	}
+}
+
+func (s *socialSource) Name() string { return SourceSocial }
+
+func (s *socialSource) Fetch() ([]Candidate, error) {
+	coins, err := s.trending()
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []Candidate
+	for i, coin := range coins {
+		symbol, ok := exchangeSymbol(coin)
+		if !ok {
+			continue
+		}
+		candidates = append(candidates, Candidate{
+			Symbol: symbol,
+			Score:  float64(len(coins) - i), // 热度排名越靠前评分越高
+			Tags:   []string{SourceSocial},
+		})
+	}
+	return candidates, nil
+}
+
+// trending 热度榜币种（带缓存，刷新失败时沿用上次结果）
+func (s *socialSource) trending() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.coins != nil && time.Since(s.fetchedAt) < socialRefreshInterval {
+		return s.coins, nil
+	}
+
+	var coins []string
+	var err error
+	switch s.provider {
+	case SocialLunarCrush:
+		coins, err = s.fetchLunarCrush()
+	default:
+		coins, err = s.fetchCoinGecko()
+	}
+	if err != nil {
+		if s.coins != nil {
+			return s.coins, nil
+		}
+		return nil, fmt.Errorf("获取%s热度榜失败: %w", s.provider, err)
+	}
+	if len(coins) > s.limit {
+		coins = coins[:s.limit]
+	}
+	s.coins, s.fetchedAt = coins, time.Now()
+	return coins, nil
+}
+
+// fetchCoinGecko CoinGecko热搜币种（按热度排序）
+func (s *socialSource) fetchCoinGecko() ([]string, error) {
+	body, err := s.get("https://api.coingecko.com/api/v3/search/trending", nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Coins []struct {
+			Item struct {
+				Symbol string `json:"symbol"`
+			} `json:"item"`
+		} `json:"coins"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("JSON解析失败: %w", err)
+	}
+	coins := make([]string, 0, len(result.Coins))
+	for _, c := range result.Coins {
+		coins = append(coins, c.Item.Symbol)
+	}
+	return coins, nil
+}
+
+// fetchLunarCrush LunarCrush按Galaxy Score（社交活跃度综合评分）排序的币种
+func (s *socialSource) fetchLunarCrush() ([]string, error) {
+	url := fmt.Sprintf("https://lunarcrush.com/api4/public/coins/list/v2?sort=galaxy_score&limit=%d", s.limit*3)
+	body, err := s.get(url, map[string]string{"Authorization": "Bearer " + s.apiKey})
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Data []struct {
+			Symbol string `json:"symbol"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("JSON解析失败: %w", err)
+	}
+	coins := make([]string, 0, len(result.Data))
+	for _, c := range result.Data {
+		coins = append(coins, c.Symbol)
+	}
+	return coins, nil
+}
+
+// get 请求热度榜接口
+func (s *socialSource) get(url string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// exchangeSymbol 把基础币种映射为交易所的USDT永续合约（低价币可能以1000倍合约上线，如1000PEPEUSDT）
+func exchangeSymbol(coin string) (string, bool) {
+	coin = strings.ToUpper(strings.TrimSpace(coin))
+	if coin == "" || coin == "USDT" || coin == "USDC" {
+		return "", false
+	}
+	symbol := market.Canonical(coin)
+	if info, err := market.GetSymbolInfo(symbol); err == nil && info.Status == "TRADING" {
+		return symbol, true
+	}
+	return "", false
+}
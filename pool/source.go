@@ -0,0 +1,209 @@
+package pool
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+)
+
+// 内置候选币种来源名称
+const (
+	SourceAI500 = "ai500"
+	SourceOITop = "oi_top"
+)
+
+// Candidate 候选币种
+type Candidate struct {
+	Symbol string   // 交易对符号（例如：BTCUSDT）
+	Score  float64  // 评分（来源不提供评分时为0）
+	Tags   []string // 来源附加的标签（例如："breakout"）
+}
+
+// SourceRank 币种在某个来源中的排名
+type SourceRank struct {
+	Source string  // 来源名称
+	Rank   int     // 来源内排名（从1开始）
+	Total  int     // 来源返回的币种数量
+	Score  float64 // 来源评分（不提供评分时为0）
+}
+
+// Source 候选币种来源（AI500、OI Top或自定义筛选器）
+type Source interface {
+	Name() string
+	Fetch() ([]Candidate, error)
+}
+
+// SourceSetting 启用的候选币种来源
+type SourceSetting struct {
+	Name  string // 来源名称
+	Limit int    // 按评分取前N个（0=不限制）
+}
+
+var (
+	sourceMu      sync.RWMutex
+	sources       = map[string]Source{}
+	activeSources = []SourceSetting{
+		{Name: SourceAI500, Limit: 20}, // AI500取前20个评分最高的币种
+		{Name: SourceOITop},
+	}
+	extraSources []string // 由对应功能配置启用的来源（如新上线合约、社交热度）
+)
+
+func init() {
+	RegisterSource(ai500Source{})
+	RegisterSource(oiTopSource{})
+}
+
+// RegisterSource 注册候选币种来源（同名来源会被覆盖）
+func RegisterSource(source Source) {
+	sourceMu.Lock()
+	defer sourceMu.Unlock()
+	sources[source.Name()] = source
+}
+
+// HasSource 来源是否已注册
+func HasSource(name string) bool {
+	sourceMu.RLock()
+	defer sourceMu.RUnlock()
+	_, ok := sources[name]
+	return ok
+}
+
+// SetSources 设置启用的候选币种来源（按顺序合并，应在启动时调用）
+func SetSources(settings []SourceSetting) error {
+	sourceMu.Lock()
+	defer sourceMu.Unlock()
+	for _, s := range settings {
+		if _, ok := sources[s.Name]; !ok {
+			return fmt.Errorf("未知的候选币种来源 %q", s.Name)
+		}
+	}
+	activeSources = append([]SourceSetting(nil), settings...)
+	return nil
+}
+
+// EnableSource 额外启用一个已注册的来源（不受candidate_sources配置影响，追加到来源列表最后）
+func EnableSource(name string) {
+	sourceMu.Lock()
+	defer sourceMu.Unlock()
+	if !containsString(extraSources, name) {
+		extraSources = append(extraSources, name)
+	}
+}
+
+// fetchSource 获取一个来源的候选币种（按评分降序截取前limit个，评分相同保持来源顺序）
+func fetchSource(source Source, limit int) ([]Candidate, error) {
+	candidates, err := source.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	for i := range candidates {
+		candidates[i].Symbol = normalizeSymbol(candidates[i].Symbol)
+	}
+	if limit > 0 && len(candidates) > limit {
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+// sourceRanks 来源内排名（有评分时按评分降序，评分相同或不提供评分时按来源返回的顺序），与candidates一一对应
+func sourceRanks(candidates []Candidate) []int {
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return candidates[order[a]].Score > candidates[order[b]].Score })
+	ranks := make([]int, len(candidates))
+	for rank, i := range order {
+		ranks[i] = rank + 1
+	}
+	return ranks
+}
+
+// ai500Source AI500评分币种池
+type ai500Source struct{}
+
+func (ai500Source) Name() string { return SourceAI500 }
+
+func (ai500Source) Fetch() ([]Candidate, error) {
+	coins, err := GetCoinPool()
+	if err != nil {
+		return nil, err
+	}
+	var candidates []Candidate
+	for _, coin := range coins {
+		if coin.IsAvailable {
+			candidates = append(candidates, Candidate{Symbol: coin.Pair, Score: coin.Score})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("没有可用的币种")
+	}
+	return candidates, nil
+}
+
+// oiTopSource 持仓量增长Top20
+type oiTopSource struct{}
+
+func (oiTopSource) Name() string { return SourceOITop }
+
+func (oiTopSource) Fetch() ([]Candidate, error) {
+	// 多个统计窗口的排名合并，同一币种只出现一次，带上所有命中窗口的标签
+	var candidates []Candidate
+	index := make(map[string]int)
+	for _, window := range configuredOITopWindows() {
+		positions, err := getOITopPositions(window)
+		if err != nil {
+			return nil, err
+		}
+		for _, pos := range positions {
+			i, ok := index[pos.Symbol]
+			if !ok {
+				i = len(candidates)
+				index[pos.Symbol] = i
+				candidates = append(candidates, Candidate{Symbol: pos.Symbol})
+			}
+			if tag := window.tag(); tag != "" {
+				candidates[i].Tags = append(candidates[i].Tags, tag)
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// activeSourceList 当前启用的来源及其设置
+func activeSourceList() ([]Source, []SourceSetting) {
+	sourceMu.RLock()
+	defer sourceMu.RUnlock()
+	settings := append([]SourceSetting(nil), activeSources...)
+
+	// 额外启用的来源追加到最后（已在来源列表中配置的除外）
+	for _, name := range extraSources {
+		listed := false
+		for _, s := range settings {
+			listed = listed || s.Name == name
+		}
+		if !listed {
+			settings = append(settings, SourceSetting{Name: name})
+		}
+	}
+	list := make([]Source, len(settings))
+	for i, s := range settings {
+		list[i] = sources[s.Name]
+	}
+	return list, settings
+}
+
+// logSourceCounts 打印各来源的候选币种数量
+func logSourceCounts(settings []SourceSetting, counts []int, total int) {
+	msg := ""
+	for i, s := range settings {
+		if i > 0 {
+			msg += ", "
+		}
+		msg += fmt.Sprintf("%s=%d", s.Name, counts[i])
+	}
+	log.Printf("📊 币种池合并完成: %s, 总计(去重)=%d", msg, total)
+}
@@ -0,0 +1,78 @@
+package pool
+
+import (
+	"nofx/market"
+	"sort"
+	"sync"
+)
+
+// SourceWatchlist 用户自选币种（始终加入候选池）
+const SourceWatchlist = "watchlist"
+
+var (
+	userListMu sync.RWMutex
+	watchlist  []string // 按配置写法标准化，合并时再映射为实际合约
+	blacklist  = make(map[string]bool)
+)
+
+// SetWatchlist 设置始终加入候选池的币种（排在所有来源之前）
+func SetWatchlist(symbols []string) {
+	userListMu.Lock()
+	defer userListMu.Unlock()
+	watchlist = watchlist[:0]
+	for _, symbol := range symbols {
+		if symbol = market.Normalize(symbol); !containsString(watchlist, symbol) {
+			watchlist = append(watchlist, symbol)
+		}
+	}
+}
+
+// SetBlacklist 设置禁止交易的币种（从所有来源的候选中排除）
+func SetBlacklist(symbols []string) {
+	userListMu.Lock()
+	defer userListMu.Unlock()
+	blacklist = make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		blacklist[market.Normalize(symbol)] = true
+	}
+}
+
+// Blacklist 禁止交易的币种（按字母排序）
+func Blacklist() []string {
+	userListMu.RLock()
+	defer userListMu.RUnlock()
+	symbols := make([]string, 0, len(blacklist))
+	for symbol := range blacklist {
+		symbols = append(symbols, normalizeSymbol(symbol))
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// watchlistCandidates 自选币种作为候选
+func watchlistCandidates() []Candidate {
+	userListMu.RLock()
+	defer userListMu.RUnlock()
+	candidates := make([]Candidate, 0, len(watchlist))
+	for _, symbol := range watchlist {
+		candidates = append(candidates, Candidate{Symbol: normalizeSymbol(symbol)})
+	}
+	return candidates
+}
+
+// excludedSymbols 本次合并需要排除的币种（用户黑名单 + 新上线合约黑名单）
+func excludedSymbols() map[string]bool {
+	excluded := newListingBlacklist()
+	userListMu.RLock()
+	defer userListMu.RUnlock()
+	if len(blacklist) == 0 {
+		return excluded
+	}
+	if excluded == nil {
+		excluded = make(map[string]bool, len(blacklist))
+	}
+	for symbol := range blacklist {
+		excluded[normalizeSymbol(symbol)] = true
+	}
+	return excluded
+}
@@ -0,0 +1,37 @@
+package pool
+
+import (
+	"math"
+	"nofx/market"
+)
+
+// SourceVolumeTop 24小时成交额和成交量加速排名
+const SourceVolumeTop = "vol_top"
+
+// volumeTopShortlist 先按24小时成交额取前N个，再按成交量加速排序
+const volumeTopShortlist = 30
+
+// volumeTopSource 成交额领先且成交量正在放大的币种（补充AI500和OI Top都没有覆盖的放量币种）
+type volumeTopSource struct{}
+
+func init() {
+	RegisterSource(volumeTopSource{})
+}
+
+func (volumeTopSource) Name() string { return SourceVolumeTop }
+
+func (volumeTopSource) Fetch() ([]Candidate, error) {
+	leaders, err := market.GetVolumeLeaders(volumeTopShortlist)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]Candidate, 0, len(leaders))
+	for _, leader := range leaders {
+		candidates = append(candidates, Candidate{
+			Symbol: leader.Symbol,
+			Score:  math.Round(leader.Acceleration*100) / 100, // 成交量加速倍数
+			Tags:   []string{SourceVolumeTop},
+		})
+	}
+	return candidates, nil
+}
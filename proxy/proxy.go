@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// 端点类别（AI接口和交易所接口可分别使用不同代理）
+const (
+	KindAI       = "ai"       // AI模型API
+	KindExchange = "exchange" // 交易所交易和行情接口
+)
+
+// Direct 配置为该值时直连（不使用代理，也不读取环境变量）
+const Direct = "direct"
+
+var (
+	mu           sync.RWMutex
+	defaultProxy *url.URL                // 所有请求的默认代理（nil表示使用环境变量HTTP_PROXY/HTTPS_PROXY）
+	kindProxies  = map[string]*url.URL{} // 类别 -> 代理
+	hostProxies  = map[string]*url.URL{} // 主机 -> 代理（优先级最高）
+	hostKinds    = map[string]string{}   // 已登记的端点主机 -> 类别
+	direct       = &url.URL{}            // 直连标记
+)
+
+func init() {
+	// 所有使用默认Transport的客户端（包括交易所SDK）都按目标主机选择代理
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport.Proxy = ForRequest
+	}
+}
+
+// Validate 检查代理地址（支持http、https、socks5、socks5h，或"direct"表示直连）
+func Validate(proxyURL string) error {
+	_, err := parse(proxyURL)
+	return err
+}
+
+// parse 解析代理地址（空字符串返回nil）
+func parse(proxyURL string) (*url.URL, error) {
+	if proxyURL == "" {
+		return nil, nil
+	}
+	if proxyURL == Direct {
+		return direct, nil
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("代理地址 %q 格式错误: %w", proxyURL, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("代理地址 %q 的协议必须是 http、https、socks5 或 socks5h", proxyURL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("代理地址 %q 缺少主机和端口", proxyURL)
+	}
+	return u, nil
+}
+
+// SetDefault 设置所有请求的默认代理（空字符串使用环境变量）
+func SetDefault(proxyURL string) error {
+	u, err := parse(proxyURL)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	defaultProxy = u
+	return nil
+}
+
+// SetKind 设置一类端点的代理（覆盖默认代理，空字符串取消）
+func SetKind(kind, proxyURL string) error {
+	u, err := parse(proxyURL)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if u == nil {
+		delete(kindProxies, kind)
+	} else {
+		kindProxies[kind] = u
+	}
+	return nil
+}
+
+// SetHost 设置单个主机的代理（优先级最高，空字符串取消）
+func SetHost(host, proxyURL string) error {
+	u, err := parse(proxyURL)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	host = strings.ToLower(host)
+	if u == nil {
+		delete(hostProxies, host)
+	} else {
+		hostProxies[host] = u
+	}
+	return nil
+}
+
+// Register 登记端点所属的类别（endpoint可以是完整URL或主机名）
+func Register(kind, endpoint string) {
+	host := endpoint
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		host = u.Hostname()
+	}
+	if host == "" {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	hostKinds[strings.ToLower(host)] = kind
+}
+
+// ForRequest 按请求的目标主机选择代理（主机 > 类别 > 默认 > 环境变量），可直接用作http.Transport.Proxy
+func ForRequest(req *http.Request) (*url.URL, error) {
+	if u := ForHost(req.URL.Hostname()); u != nil {
+		if u == direct {
+			return nil, nil
+		}
+		return u, nil
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// ForHost 主机使用的代理（未配置时返回nil，直连时返回空URL）
+func ForHost(host string) *url.URL {
+	mu.RLock()
+	defer mu.RUnlock()
+	host = strings.ToLower(host)
+	if u, ok := hostProxies[host]; ok {
+		return u
+	}
+	if u, ok := kindProxies[hostKinds[host]]; ok {
+		return u
+	}
+	return defaultProxy
+}
+
+// URLForHost 主机使用的代理地址字符串（直连或未配置时为空，用于只接受字符串的第三方库）
+func URLForHost(host string) string {
+	u := ForHost(host)
+	if u == nil || u == direct {
+		return ""
+	}
+	return u.String()
+}
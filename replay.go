@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"os"
+	"strings"
+)
+
+// runReplay 离线回放：用保存的上下文快照重新解析、校验AI响应（不请求行情、不调用AI、不下单）
+// 用法: nofx replay <context_snapshot.json> <AI响应文件 | 决策日志.json>
+func runReplay(args []string) {
+	if len(args) != 2 {
+		log.Fatalf("用法: %s replay <context_snapshot.json> <AI响应文件 | 决策日志.json>", os.Args[0])
+	}
+
+	ctx := &decision.Context{}
+	if err := ctx.Load(args[0]); err != nil {
+		log.Fatalf("❌ 加载上下文快照失败: %v", err)
+	}
+
+	response, err := loadReplayResponse(args[1])
+	if err != nil {
+		log.Fatalf("❌ 读取AI响应失败: %v", err)
+	}
+
+	result, err := decision.Replay(ctx, response)
+
+	fmt.Println("💭 思维链:")
+	fmt.Println(result.CoTTrace)
+	fmt.Println(strings.Repeat("-", 60))
+	decisionsJSON, _ := json.MarshalIndent(result.Decisions, "", "  ")
+	fmt.Printf("📋 决策 (%d):\n%s\n", len(result.Decisions), decisionsJSON)
+	for _, rejection := range result.HookRejections {
+		fmt.Printf("⛔ 被hook拒绝: %s\n", rejection)
+	}
+
+	if err != nil {
+		fmt.Printf("❌ 校验失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("✓ 校验通过")
+}
+
+// loadReplayResponse 读取AI响应：决策日志JSON取raw_response字段，否则将整个文件视为原始响应
+func loadReplayResponse(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var record logger.DecisionRecord
+	if err := json.Unmarshal(data, &record); err == nil && record.CycleNumber > 0 {
+		if record.RawResponse == "" {
+			return "", fmt.Errorf("决策日志中没有raw_response（该记录早于回放功能）")
+		}
+		return record.RawResponse, nil
+	}
+	return string(data), nil
+}
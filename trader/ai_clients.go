@@ -0,0 +1,115 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/mcp"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// cycleDeadlineFraction 行情获取和AI调用最多占用扫描间隔的比例（留出执行时间，避免拖到下一个周期）
+const cycleDeadlineFraction = 0.9
+
+// aiClients 本trader使用的所有AI客户端（主模型、集成投票、风控审核、便宜模型）
+func (at *AutoTrader) aiClients() []*mcp.Client {
+	clients := append([]*mcp.Client{at.mcpClient}, at.ensembleClients...)
+	if at.riskOfficerClient != nil {
+		clients = append(clients, at.riskOfficerClient)
+	}
+	if at.cheapClient != nil {
+		clients = append(clients, at.cheapClient)
+	}
+	return clients
+}
+
+// lowStakes 本周期是否可以使用便宜模型：没有持仓、没有未成交的挂单，且没有候选币种评分达到升级阈值
+// 返回说明（用于日志）；未配置便宜模型时始终返回false
+func (at *AutoTrader) lowStakes(ctx *decision.Context) (string, bool) {
+	if at.cheapClient == nil || len(ctx.Positions) > 0 || len(ctx.PendingEntries) > 0 {
+		return "", false
+	}
+	best := 0.0
+	for _, coin := range ctx.CandidateCoins {
+		if coin.Score > best {
+			best = coin.Score
+		}
+	}
+	if at.config.EscalationScore > 0 && best >= at.config.EscalationScore {
+		return "", false
+	}
+	if at.config.EscalationScore > 0 {
+		return fmt.Sprintf("无持仓和挂单，候选最高评分%.1f<%.1f", best, at.config.EscalationScore), true
+	}
+	return "无持仓和挂单", true
+}
+
+// cycleContext 本周期行情获取和AI调用使用的context（慢的提供商不会拖过下一个周期，trader停止时随runCtx取消）
+func (at *AutoTrader) cycleContext(runCtx context.Context, cycleStart time.Time) (context.Context, context.CancelFunc) {
+	deadline := cycleStart.Add(time.Duration(float64(at.config.ScanInterval) * cycleDeadlineFraction))
+	return context.WithDeadline(runCtx, deadline)
+}
+
+// openRouterPricesOnce OpenRouter模型价格只在首次创建OpenRouter客户端时加载
+var openRouterPricesOnce sync.Once
+
+// loadOpenRouterPrices 加载OpenRouter的模型价格用于费用统计（失败时只打印日志，费用按内置价格表计算）
+func loadOpenRouterPrices() {
+	openRouterPricesOnce.Do(func() {
+		count, err := mcp.LoadOpenRouterPrices(context.Background())
+		if err != nil {
+			log.Printf("⚠️  %v，OpenRouter费用按内置价格表计算", err)
+			return
+		}
+		log.Printf("💲 已加载%d个OpenRouter模型的价格", count)
+	})
+}
+
+// aiArchiveDir AI请求/响应归档目录（放在子目录中，避免被决策日志读取）
+func aiArchiveDir(logDir string) string {
+	return filepath.Join(logDir, "ai_archive")
+}
+
+// setAICycle 生成本周期ID并设置到所有AI客户端（含故障切换链），归档按周期ID分组
+func (at *AutoTrader) setAICycle(cycleStart time.Time) {
+	at.cycleID = fmt.Sprintf("%s_%d", cycleStart.Format("20060102_150405"), at.callCount)
+	for _, client := range append(at.aiClients(), at.mcpClient.Fallbacks...) {
+		client.SetCycleID(at.cycleID)
+	}
+}
+
+// GetAIArchive 获取指定周期的AI请求/响应归档
+func (at *AutoTrader) GetAIArchive(cycleID string) ([]mcp.ArchiveEntry, error) {
+	if at.aiArchive == nil {
+		return nil, fmt.Errorf("trader %s 未启用AI归档（ai_archive_days）", at.id)
+	}
+	return at.aiArchive.Load(cycleID)
+}
+
+// takeAIUsage 汇总本周期所有AI客户端（主模型含故障切换、集成投票、风控审核）的token用量和费用
+func (at *AutoTrader) takeAIUsage() *logger.AIUsage {
+	var total mcp.Usage
+	for _, client := range at.aiClients() {
+		total.Add(client.TakeUsage())
+	}
+	if total.PromptTokens == 0 && total.CompletionTokens == 0 {
+		return nil
+	}
+
+	log.Printf("💸 AI用量: 输入%d / 输出%d tokens, 费用 $%.4f", total.PromptTokens, total.CompletionTokens, total.CostUSD)
+	if total.CacheReadTokens > 0 || total.CacheWriteTokens > 0 {
+		log.Printf("🗃 提示缓存: 命中%d / 写入%d tokens, 节省 $%.4f", total.CacheReadTokens, total.CacheWriteTokens, total.CacheSavingsUSD)
+	}
+	return &logger.AIUsage{
+		PromptTokens:     total.PromptTokens,
+		CompletionTokens: total.CompletionTokens,
+		CacheReadTokens:  total.CacheReadTokens,
+		CacheWriteTokens: total.CacheWriteTokens,
+		CostUSD:          total.CostUSD,
+		CacheSavingsUSD:  total.CacheSavingsUSD,
+	}
+}
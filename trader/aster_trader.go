@@ -13,6 +13,7 @@ import (
 	"math/big"
 	"net/http"
 	"net/url"
+	"nofx/proxy"
 	"sort"
 	"strconv"
 	"strings"
@@ -57,7 +58,7 @@ func NewAsterTrader(user, signer, privateKeyHex string) (*AsterTrader, error) {
 		return nil, fmt.Errorf("解析私钥失败: %w", err)
 	}
 
-	return &AsterTrader{
+	trader := &AsterTrader{
 		ctx:             context.Background(),
 		user:            user,
 		signer:          signer,
@@ -66,13 +67,16 @@ func NewAsterTrader(user, signer, privateKeyHex string) (*AsterTrader, error) {
 		client: &http.Client{
 			Timeout: 30 * time.Second, // 增加到30秒
 			Transport: &http.Transport{
+				Proxy:                 proxy.ForRequest,
 				TLSHandshakeTimeout:   10 * time.Second,
 				ResponseHeaderTimeout: 10 * time.Second,
 				IdleConnTimeout:       90 * time.Second,
 			},
 		},
 		baseURL: "https://fapi.asterdex.com",
-	}, nil
+	}
+	proxy.Register(proxy.KindExchange, trader.baseURL)
+	return trader, nil
 }
 
 // genNonce 生成微秒时间戳
@@ -90,7 +94,7 @@ func (t *AsterTrader) getPrecision(symbol string) (SymbolPrecision, error) {
 	t.mu.RUnlock()
 
 	// 获取交易所信息
-	resp, err := t.client.Get(t.baseURL + "/fapi/v3/exchangeInfo")
+	resp, err := t.get(t.baseURL + "/fapi/v3/exchangeInfo")
 	if err != nil {
 		return SymbolPrecision{}, err
 	}
@@ -352,7 +356,9 @@ func (t *AsterTrader) request(method, endpoint string, params map[string]interfa
 			strings.Contains(err.Error(), "EOF") {
 			if attempt < maxRetries {
 				waitTime := time.Duration(attempt) * time.Second
-				time.Sleep(waitTime)
+				if !sleepContext(t.ctx, waitTime) {
+					return nil, t.ctx.Err()
+				}
 				continue
 			}
 		}
@@ -364,6 +370,20 @@ func (t *AsterTrader) request(method, endpoint string, params map[string]interfa
 	return nil, fmt.Errorf("请求失败（已重试%d次）: %w", maxRetries, lastErr)
 }
 
+// SetContext 设置请求使用的context，取消后进行中的请求立即返回
+func (t *AsterTrader) SetContext(ctx context.Context) {
+	t.ctx = ctx
+}
+
+// get 发送不需要签名的GET请求
+func (t *AsterTrader) get(rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return t.client.Do(req)
+}
+
 // doRequest 执行实际的HTTP请求
 func (t *AsterTrader) doRequest(method, endpoint string, params map[string]interface{}) ([]byte, error) {
 	fullURL := t.baseURL + endpoint
@@ -376,7 +396,7 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 		for k, v := range params {
 			form.Set(k, fmt.Sprintf("%v", v))
 		}
-		req, err := http.NewRequest("POST", fullURL, strings.NewReader(form.Encode()))
+		req, err := http.NewRequestWithContext(t.ctx, "POST", fullURL, strings.NewReader(form.Encode()))
 		if err != nil {
 			return nil, err
 		}
@@ -403,7 +423,7 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 		u, _ := url.Parse(fullURL)
 		u.RawQuery = q.Encode()
 
-		req, err := http.NewRequest(method, u.String(), nil)
+		req, err := http.NewRequestWithContext(t.ctx, method, u.String(), nil)
 		if err != nil {
 			return nil, err
 		}
@@ -654,6 +674,64 @@ func (t *AsterTrader) OpenShort(symbol string, quantity float64, leverage int) (
 	return result, nil
 }
 
+// PlaceLimitEntry 挂限价开仓单（GTC，成交后由调用方设置止损止盈）
+func (t *AsterTrader) PlaceLimitEntry(symbol string, positionSide string, quantity float64, leverage int, price float64) (map[string]interface{}, error) {
+	// 不取消已有委托：已有持仓的止损止盈单需要保留
+
+	// 先设置杠杆
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, fmt.Errorf("设置杠杆失败: %w", err)
+	}
+
+	side := "BUY"
+	if positionSide == "SHORT" {
+		side = "SELL"
+	}
+
+	// 格式化价格和数量到正确精度
+	formattedPrice, err := t.formatPrice(symbol, price)
+	if err != nil {
+		return nil, err
+	}
+	formattedQty, err := t.formatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	// 获取精度信息
+	prec, err := t.getPrecision(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	// 转换为字符串，使用正确的精度格式
+	priceStr := t.formatFloatWithPrecision(formattedPrice, prec.PricePrecision)
+	qtyStr := t.formatFloatWithPrecision(formattedQty, prec.QuantityPrecision)
+
+	params := map[string]interface{}{
+		"symbol":       symbol,
+		"positionSide": "BOTH",
+		"type":         "LIMIT",
+		"side":         side,
+		"timeInForce":  "GTC",
+		"quantity":     qtyStr,
+		"price":        priceStr,
+	}
+
+	body, err := t.request("POST", "/fapi/v3/order", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	log.Printf("✓ 限价开仓单已挂出: %s %s 数量: %s 价格: %s", symbol, positionSide, qtyStr, priceStr)
+	return result, nil
+}
+
 // CloseLong 平多单
 func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
 	// 如果数量为0，获取当前持仓数量
@@ -833,7 +911,7 @@ func (t *AsterTrader) SetLeverage(symbol string, leverage int) error {
 // GetMarketPrice 获取市场价格
 func (t *AsterTrader) GetMarketPrice(symbol string) (float64, error) {
 	// 使用ticker接口获取当前价格
-	resp, err := t.client.Get(fmt.Sprintf("%s/fapi/v3/ticker/price?symbol=%s", t.baseURL, symbol))
+	resp, err := t.get(fmt.Sprintf("%s/fapi/v3/ticker/price?symbol=%s", t.baseURL, symbol))
 	if err != nil {
 		return 0, err
 	}
@@ -949,6 +1027,17 @@ func (t *AsterTrader) CancelAllOrders(symbol string) error {
 	return err
 }
 
+// CancelOrder 取消指定订单
+func (t *AsterTrader) CancelOrder(symbol string, orderID int64) error {
+	params := map[string]interface{}{
+		"symbol":  symbol,
+		"orderId": orderID,
+	}
+
+	_, err := t.request("DELETE", "/fapi/v3/order", params)
+	return err
+}
+
 // FormatQuantity 格式化数量（实现Trader接口）
 func (t *AsterTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
 	formatted, err := t.formatQuantity(symbol, quantity)
@@ -1,7 +1,9 @@
 package trader
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"nofx/decision"
@@ -9,7 +11,9 @@ import (
 	"nofx/market"
 	"nofx/mcp"
 	"nofx/pool"
+	"nofx/proxy"
 	"strings"
+	"text/template"
 	"time"
 )
 
@@ -18,20 +22,41 @@ type AutoTraderConfig struct {
 	// Trader标识
 	ID      string // Trader唯一标识（用于日志目录等）
 	Name    string // Trader显示名称
-	AIModel string // AI模型: "qwen" 或 "deepseek"
+	AIModel string // AI模型: "qwen"、"deepseek"、"openai"、"anthropic"、"gemini"、"local" 或 "custom"
 
 	// 交易平台选择
-	Exchange string // "binance", "hyperliquid" 或 "aster"
+	Exchange string // "binance", "bybit", "okx", "hyperliquid", "aster" 或 "paper"
+
+	// 使用交易所测试网（币安、Bybit、Hyperliquid测试网，OKX模拟盘）
+	Testnet bool
 
 	// 币安API配置
 	BinanceAPIKey    string
 	BinanceSecretKey string
 
+	// Bybit API配置
+	BybitAPIKey    string
+	BybitSecretKey string
+
+	// OKX API配置
+	OKXAPIKey     string
+	OKXSecretKey  string
+	OKXPassphrase string
+
 	// Hyperliquid配置
 	HyperliquidPrivateKey string
 	HyperliquidWalletAddr string
 	HyperliquidTestnet    bool
 
+	// 模拟交易配置（exchange为paper时使用）
+	Paper PaperOptions
+
+	// 移动止损管理
+	TrailingStop TrailingStopOptions
+
+	// 开仓执行方式
+	EntryExecution EntryExecutionOptions
+
 	// Aster配置
 	AsterUser       string // Aster主钱包地址
 	AsterSigner     string // Aster API钱包地址
@@ -40,14 +65,33 @@ type AutoTraderConfig struct {
 	CoinPoolAPIURL string
 
 	// AI配置
-	UseQwen     bool
-	DeepSeekKey string
-	QwenKey     string
+	UseQwen      bool
+	DeepSeekKey  string
+	QwenKey      string
+	OpenAIKey    string
+	AnthropicKey string
+	GeminiKey    string
+	ModelName    string // 覆盖内置提供商的默认模型（可选，local必填）
+	LocalAPIURL  string // 本地模型服务地址（默认Ollama）
+	LocalAPIKey  string // 本地模型服务密钥（可选）
+
+	MockResponsesFile string // 模拟AI的响应脚本（ai_model为mock时使用，可选）
+
+	// Azure OpenAI配置
+	AzureEndpoint   string // 资源地址
+	AzureAPIKey     string
+	AzureDeployment string // 部署名称
+	AzureAPIVersion string // API版本（可选）
+
+	// OpenRouter配置（ModelName为OpenRouter模型ID）
+	OpenRouterKey string
+	OpenRouter    *mcp.OpenRouterOptions // 模型路由选项（可选）
 
 	// 自定义AI API配置
-	CustomAPIURL    string
-	CustomAPIKey    string
-	CustomModelName string
+	CustomAPIURL     string
+	CustomAPIKey     string
+	CustomModelName  string
+	StructuredOutput bool // 使用json_schema结构化输出（自定义API或OpenAI）
 
 	// 扫描配置
 	ScanInterval time.Duration // 扫描间隔（建议3分钟）
@@ -63,6 +107,82 @@ type AutoTraderConfig struct {
 	MaxDailyLoss    float64       // 最大日亏损百分比（提示）
 	MaxDrawdown     float64       // 最大回撤百分比（提示）
 	StopTradingTime time.Duration // 触发风控后暂停时长
+
+	// 策略配置
+	PromptTemplateFile string                          // 自定义system prompt模板文件（留空使用内置规则）
+	ValidationRetries  int                             // 决策校验失败后让AI修正的最大轮数（0使用默认值，负数禁用）
+	MinRiskReward      float64                         // 开仓最小风险回报比（0使用默认值3.0）
+	MaxPositions       int                             // 最大同时持仓数量（0使用默认值3）
+	DecisionMemory     int                             // 提示词中展示的最近决策数量（0使用默认值10，负数禁用）
+	InvalidationAction string                          // invalidation_rule触发时的处理："close"（默认，自动平仓）或 "flag"（仅提示AI）
+	TradeCooldown      int                             // 平仓后同一币种重新开仓的冷却时间（分钟，0使用默认值15，负数禁用）
+	PromptTokenBudget  int                             // 用户prompt的token预算（0表示不限制）
+	HedgeMode          bool                            // 双向持仓模式：允许同一币种同时持有多仓和空仓
+	FearGreed          bool                            // 在提示词中加入恐惧贪婪指数
+	DepegThreshold     float64                         // 稳定币脱锚阈值（偏离1美元的百分比，0使用默认值0.5）
+	PauseOnDepeg       bool                            // 稳定币脱锚期间禁止开新仓
+	MaxSectorPositions int                             // 同一板块最大同时持仓数量（0表示不限制）
+	MaxSpreadPct       float64                         // 候选币种最大买卖价差（百分比，0使用默认值0.1，负数禁用）
+	MinDepthMultiple   float64                         // 候选币种±0.5%深度至少为最大仓位价值的N倍（0使用默认值1，负数禁用）
+	MarketDataFormat   decision.MarketDataFormat       // 各层级币种的行情数据格式（完整或紧凑）
+	Timeframes         map[string][]string             // 额外K线周期（"default"适用于所有币种）
+	Indicators         []string                        // 可选指标（obv、stochastic、ichimoku）
+	CostModel          decision.CostModel              // 开仓期望收益检查使用的手续费/滑点/持仓时间
+	SymbolLimits       map[string]decision.SymbolLimit // 单币种杠杆/仓位/风险限制
+
+	// 多模型集成投票（EnsembleModels为空表示不启用）
+	EnsembleModels   []AIModelSpec // 额外参与投票的模型（主模型自动参与）
+	EnsembleMode     string        // "majority" 或 "confidence"
+	EnsembleMinAgree int           // 至少N个模型同意才执行
+
+	EnsembleLatencyBudget time.Duration // 并行调用的等待上限，超时的模型不参与投票（0表示等待全部）
+
+	// Kelly仓位计算（Mode为空表示不启用）
+	PositionSizing decision.PositionSizing
+
+	// 风控审核模型（nil表示不启用）
+	RiskOfficer *AIModelSpec
+
+	// 主模型故障切换链（超时、429或5xx时按顺序切换）
+	AIFallbacks []AIModelSpec
+
+	// 低风险周期使用的便宜模型（nil表示始终使用主模型）
+	CheapModel *AIModelSpec
+	// 任一候选币种评分达到该值时使用主模型（0表示只按持仓和挂单判断）
+	EscalationScore float64
+
+	// 每个AI客户端每分钟最多请求数（0表示不限制）
+	AIRequestsPerMinute int
+	AIRetry             *mcp.RetryPolicy // AI重试和熔断策略（nil使用默认值）
+
+	// 主模型流式输出（思维链实时输出，决策数组完整后提前结束）
+	AIStream bool
+
+	// AI工具调用最大轮数（0表示不启用）
+	AIToolRounds int
+
+	// K线图（MaxSymbols为0表示不启用，只发送给支持视觉输入的模型）
+	Charts decision.ChartConfig
+
+	// AI请求/响应归档保留天数（0表示不归档）
+	AIArchiveDays int
+
+	// 主模型生成参数（nil使用默认值）
+	AIParams *mcp.GenerationParams
+}
+
+// AIModelSpec 额外AI模型配置
+type AIModelSpec struct {
+	AIModel   string // "qwen", "deepseek", "openai", "anthropic", "gemini", "azure", "local", "custom" 或 "mock"
+	APIKey    string // local和mock可选
+	APIURL    string // custom必填，local可选，azure为资源地址
+	ModelName string // custom必填，其他可选覆盖默认模型，azure为部署名称
+
+	APIVersion        string                 // azure的API版本（可选）
+	OpenRouter        *mcp.OpenRouterOptions // openrouter的模型路由选项（可选）
+	MockResponsesFile string                 // mock的响应脚本（可选）
+
+	Params *mcp.GenerationParams // 生成参数（nil使用默认值）
 }
 
 // AutoTrader 自动交易器
@@ -80,10 +200,25 @@ type AutoTrader struct {
 	lastResetTime         time.Time
 	stopUntil             time.Time
 	isRunning             bool
-	startTime             time.Time        // 系统启动时间
-	callCount             int              // AI调用次数
-	positionFirstSeenTime map[string]int64 // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
+	startTime             time.Time                         // 系统启动时间
+	callCount             int                               // AI调用次数
+	positionFirstSeenTime map[string]int64                  // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
 	positionExitPlans     map[string]*decision.PositionInfo // 持仓退出计划信息 (symbol_side -> PositionInfo)
+	promptTemplate        *template.Template                // 自定义system prompt模板（nil表示使用内置规则）
+	ensembleClients       []*mcp.Client                     // 集成投票的额外AI客户端
+	riskOfficerClient     *mcp.Client                       // 风控审核模型客户端（nil表示不启用）
+	cheapClient           *mcp.Client                       // 低风险周期使用的便宜模型客户端（nil表示不启用）
+	trailingPeaks         map[string]float64                // 已激活移动止损的最优价格 (symbol_side -> price)
+	lastCloseTimes        map[string]int64                  // 最近平仓时间 (symbol -> timestamp毫秒)，用于重新开仓冷却
+	pendingEntries        map[string]*pendingEntry          // 未成交的限价开仓单 (symbol_side -> entry)
+	stateFile             string                            // 持仓状态文件（退出计划等，重启后恢复）
+	snapshotDir           string                            // 每个周期的完整决策上下文快照目录
+	aiArchive             *mcp.Archive                      // AI请求/响应归档（nil表示不归档）
+	cycleID               string                            // 当前周期ID（关联决策日志和AI归档）
+	runCtx                context.Context                   // 运行context，Stop时取消（中止进行中的行情获取和AI调用）
+	stopRun               context.CancelFunc
+	stopExchange          context.CancelFunc // 中止交易所请求（Stop后延迟exchangeStopGrace调用）
+	executionDeadline     time.Time          // 本周期开仓执行（追价、拆单）等待的截止时间
 }
 
 // NewAutoTrader 创建自动交易器
@@ -109,17 +244,64 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 	if config.AIModel == "custom" {
 		// 使用自定义API
 		mcpClient.SetCustomAPI(config.CustomAPIURL, config.CustomAPIKey, config.CustomModelName)
+		mcpClient.StructuredOutput = config.StructuredOutput
 		log.Printf("🤖 [%s] 使用自定义AI API: %s (模型: %s)", config.Name, config.CustomAPIURL, config.CustomModelName)
 	} else if config.UseQwen || config.AIModel == "qwen" {
 		// 使用Qwen
 		mcpClient.SetQwenAPIKey(config.QwenKey, "")
 		log.Printf("🤖 [%s] 使用阿里云Qwen AI", config.Name)
+	} else if config.AIModel == "local" {
+		// 使用本地模型（Ollama / vLLM / LM Studio）
+		mcpClient.SetLocalAPI(config.LocalAPIURL, config.ModelName)
+		mcpClient.APIKey = config.LocalAPIKey
+		mcpClient.StructuredOutput = config.StructuredOutput
+		log.Printf("🤖 [%s] 使用本地模型: %s (模型: %s)", config.Name, mcpClient.BaseURL, mcpClient.Model)
+	} else if config.AIModel == "azure" {
+		// 使用Azure OpenAI（部署地址 + api-version，api-key认证）
+		client, err := newAIClient(AIModelSpec{AIModel: "azure", APIKey: config.AzureAPIKey, APIURL: config.AzureEndpoint, ModelName: config.AzureDeployment, APIVersion: config.AzureAPIVersion})
+		if err != nil {
+			return nil, err
+		}
+		mcpClient = client
+		mcpClient.StructuredOutput = config.StructuredOutput
+		log.Printf("🤖 [%s] 使用Azure OpenAI: %s (部署: %s, api-version: %s)", config.Name, config.AzureEndpoint, mcpClient.Model, mcpClient.APIVersion)
+	} else if config.AIModel == "openrouter" {
+		// 使用OpenRouter（一个密钥访问多家模型，可配置模型和底层提供商路由）
+		client, err := newAIClient(AIModelSpec{AIModel: "openrouter", APIKey: config.OpenRouterKey, ModelName: config.ModelName, OpenRouter: config.OpenRouter})
+		if err != nil {
+			return nil, err
+		}
+		mcpClient = client
+		mcpClient.StructuredOutput = config.StructuredOutput
+		log.Printf("🤖 [%s] 使用OpenRouter (模型: %s)", config.Name, mcpClient.Model)
+	} else if config.AIModel == "mock" {
+		// 模拟AI（按脚本返回响应，用于模拟运行和验证执行流程，不产生API费用）
+		client, err := newAIClient(AIModelSpec{AIModel: "mock", MockResponsesFile: config.MockResponsesFile})
+		if err != nil {
+			return nil, err
+		}
+		mcpClient = client
+		mcpClient.StructuredOutput = config.StructuredOutput
+		log.Printf("🤖 [%s] 使用模拟AI (响应脚本: %s)", config.Name, mockScriptName(config.MockResponsesFile))
+	} else if keys := map[string]string{"openai": config.OpenAIKey, "anthropic": config.AnthropicKey, "gemini": config.GeminiKey}; keys[config.AIModel] != "" {
+		// 使用OpenAI / Anthropic / Gemini（各自的API格式）
+		client, err := newAIClient(AIModelSpec{AIModel: config.AIModel, APIKey: keys[config.AIModel], ModelName: config.ModelName})
+		if err != nil {
+			return nil, err
+		}
+		mcpClient = client
+		mcpClient.StructuredOutput = config.StructuredOutput
+		log.Printf("🤖 [%s] 使用%s AI (模型: %s)", config.Name, mcpClient.Provider.Name(), mcpClient.Model)
 	} else {
 		// 默认使用DeepSeek
 		mcpClient.SetDeepSeekAPIKey(config.DeepSeekKey)
 		log.Printf("🤖 [%s] 使用DeepSeek AI", config.Name)
 	}
 
+	if config.AIParams != nil {
+		mcpClient.Params = *config.AIParams
+	}
+
 	// 初始化币种池API
 	if config.CoinPoolAPIURL != "" {
 		pool.SetCoinPoolAPI(config.CoinPoolAPIURL)
@@ -137,10 +319,19 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 	switch config.Exchange {
 	case "binance":
 		log.Printf("🏦 [%s] 使用币安合约交易", config.Name)
-		trader = NewFuturesTrader(config.BinanceAPIKey, config.BinanceSecretKey)
+		trader = NewFuturesTrader(config.BinanceAPIKey, config.BinanceSecretKey, config.Testnet)
+	case "bybit":
+		log.Printf("🏦 [%s] 使用Bybit合约交易", config.Name)
+		trader = NewBybitTrader(config.BybitAPIKey, config.BybitSecretKey, config.Testnet)
+	case "okx":
+		log.Printf("🏦 [%s] 使用OKX合约交易", config.Name)
+		trader, err = NewOKXTrader(config.OKXAPIKey, config.OKXSecretKey, config.OKXPassphrase, config.Testnet)
+		if err != nil {
+			return nil, fmt.Errorf("初始化OKX交易器失败: %w", err)
+		}
 	case "hyperliquid":
 		log.Printf("🏦 [%s] 使用Hyperliquid交易", config.Name)
-		trader, err = NewHyperliquidTrader(config.HyperliquidPrivateKey, config.HyperliquidWalletAddr, config.HyperliquidTestnet)
+		trader, err = NewHyperliquidTrader(config.HyperliquidPrivateKey, config.HyperliquidWalletAddr, config.Testnet || config.HyperliquidTestnet)
 		if err != nil {
 			return nil, fmt.Errorf("初始化Hyperliquid交易器失败: %w", err)
 		}
@@ -150,20 +341,106 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 		if err != nil {
 			return nil, fmt.Errorf("初始化Aster交易器失败: %w", err)
 		}
+	case "paper":
+		log.Printf("🏦 [%s] 使用模拟交易（实时行情撮合，不动用真实资金）", config.Name)
+		trader, err = NewPaperTrader(config.InitialBalance, paperStateFile(fmt.Sprintf("decision_logs/%s", config.ID)), config.Paper)
+		if err != nil {
+			return nil, fmt.Errorf("初始化模拟交易器失败: %w", err)
+		}
 	default:
 		return nil, fmt.Errorf("不支持的交易平台: %s", config.Exchange)
 	}
+	if config.Testnet {
+		log.Printf("🧪 [%s] 已连接%s测试网（行情数据仍来自主网）", config.Name, config.Exchange)
+	}
+
+	// 双向持仓模式
+	if config.HedgeMode {
+		ht, ok := trader.(hedgeModeTrader)
+		if !ok {
+			return nil, fmt.Errorf("交易平台 %s 不支持双向持仓模式", config.Exchange)
+		}
+		if err := ht.SetHedgeMode(true); err != nil {
+			return nil, fmt.Errorf("[%s] 启用双向持仓模式失败: %w", config.Name, err)
+		}
+	}
+
+	// 杠杆分层（决策验证按名义价值检查杠杆上限，加载失败不影响运行）
+	if bl, ok := trader.(leverageBracketLoader); ok {
+		if err := bl.LoadLeverageBrackets(); err != nil {
+			log.Printf("⚠️ [%s] %v", config.Name, err)
+		}
+	}
 
 	// 验证初始金额配置
 	if config.InitialBalance <= 0 {
 		return nil, fmt.Errorf("初始金额必须大于0，请在配置中设置InitialBalance")
 	}
 
+	// 加载自定义prompt模板
+	var promptTemplate *template.Template
+	if config.PromptTemplateFile != "" {
+		promptTemplate, err = decision.LoadPromptTemplate(config.PromptTemplateFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载prompt模板失败: %w", err)
+		}
+		log.Printf("📝 [%s] 使用自定义prompt模板: %s", config.Name, config.PromptTemplateFile)
+	}
+
+	// 初始化故障切换链
+	for _, spec := range config.AIFallbacks {
+		client, err := newAIClient(spec)
+		if err != nil {
+			return nil, fmt.Errorf("初始化备用AI模型失败: %w", err)
+		}
+		mcpClient.Fallbacks = append(mcpClient.Fallbacks, client)
+	}
+	if len(mcpClient.Fallbacks) > 0 {
+		log.Printf("🔁 [%s] AI故障切换链: %d个备用模型", config.Name, len(mcpClient.Fallbacks))
+	}
+
+	// 初始化集成投票的额外模型
+	var ensembleClients []*mcp.Client
+	for _, spec := range config.EnsembleModels {
+		client, err := newAIClient(spec)
+		if err != nil {
+			return nil, fmt.Errorf("初始化集成投票模型失败: %w", err)
+		}
+		ensembleClients = append(ensembleClients, client)
+	}
+	if len(ensembleClients) > 0 {
+		log.Printf("🗳️  [%s] 启用多模型集成投票: %d个模型, 模式=%s, 至少%d个同意",
+			config.Name, len(ensembleClients)+1, config.EnsembleMode, config.EnsembleMinAgree)
+		if config.EnsembleLatencyBudget > 0 {
+			log.Printf("⏱️  [%s] 集成投票等待上限: %v（超时的模型不参与投票）", config.Name, config.EnsembleLatencyBudget)
+		}
+	}
+
+	// 初始化风控审核模型
+	var riskOfficerClient *mcp.Client
+	if config.RiskOfficer != nil {
+		riskOfficerClient, err = newAIClient(*config.RiskOfficer)
+		if err != nil {
+			return nil, fmt.Errorf("初始化风控审核模型失败: %w", err)
+		}
+		log.Printf("👮 [%s] 启用风控审核模型: %s/%s", config.Name, riskOfficerClient.Provider.Name(), riskOfficerClient.Model)
+	}
+
+	// 初始化按风险路由的便宜模型
+	var cheapClient *mcp.Client
+	if config.CheapModel != nil {
+		cheapClient, err = newAIClient(*config.CheapModel)
+		if err != nil {
+			return nil, fmt.Errorf("初始化便宜模型失败: %w", err)
+		}
+		log.Printf("🪙 [%s] 按风险路由模型: 没有持仓、挂单和高分候选币种（评分<%.0f）的周期使用 %s", config.Name, config.EscalationScore, cheapClient.Name())
+	}
+
 	// 初始化决策日志记录器（使用trader ID创建独立目录）
 	logDir := fmt.Sprintf("decision_logs/%s", config.ID)
 	decisionLogger := logger.NewDecisionLogger(logDir)
 
-	return &AutoTrader{
+	at := &AutoTrader{
 		id:                    config.ID,
 		name:                  config.Name,
 		aiModel:               config.AIModel,
@@ -179,7 +456,129 @@ func NewAutoTrader(config AutoTraderConfig) (*AutoTrader, error) {
 		isRunning:             false,
 		positionFirstSeenTime: make(map[string]int64),
 		positionExitPlans:     make(map[string]*decision.PositionInfo),
-	}, nil
+		promptTemplate:        promptTemplate,
+		ensembleClients:       ensembleClients,
+		riskOfficerClient:     riskOfficerClient,
+		cheapClient:           cheapClient,
+		trailingPeaks:         make(map[string]float64),
+		lastCloseTimes:        make(map[string]int64),
+		pendingEntries:        make(map[string]*pendingEntry),
+		stateFile:             positionStateFile(logDir),
+		snapshotDir:           contextSnapshotDir(logDir),
+	}
+	at.runCtx, at.stopRun = context.WithCancel(context.Background())
+	exchangeCtx, stopExchange := context.WithCancel(context.Background())
+	at.stopExchange = stopExchange
+	if cb, ok := trader.(contextBinder); ok {
+		cb.SetContext(exchangeCtx)
+	}
+	at.loadPositionState()
+
+	// AI接口按类别选择代理
+	for _, client := range append(at.aiClients(), mcpClient.Fallbacks...) {
+		proxy.Register(proxy.KindAI, client.BaseURL)
+	}
+
+	// AI客户端限流
+	if config.AIRequestsPerMinute > 0 {
+		for _, client := range append(at.aiClients(), mcpClient.Fallbacks...) {
+			client.SetRateLimit(config.AIRequestsPerMinute)
+		}
+		log.Printf("🚦 [%s] AI请求限流: 每个模型每分钟最多%d次", config.Name, config.AIRequestsPerMinute)
+	}
+
+	// K线图（不支持视觉输入的模型只收到文本）
+	if config.Charts.MaxSymbols > 0 {
+		log.Printf("🖼️  [%s] K线图: 每周期最多%d个币种", config.Name, config.Charts.MaxSymbols)
+		if !mcpClient.SupportsImages() {
+			log.Printf("⚠️  [%s] 主模型 %s 不支持图片输入，K线图只会发送给支持视觉输入的模型", config.Name, mcpClient.Name())
+		}
+	}
+
+	// AI重试和熔断策略（故障切换链中的模型各自熔断）
+	if config.AIRetry != nil {
+		for _, client := range append(at.aiClients(), mcpClient.Fallbacks...) {
+			client.SetRetryPolicy(*config.AIRetry)
+		}
+		policy := mcpClient.RetryPolicy()
+		log.Printf("🔌 [%s] AI重试策略: 最多%d次尝试, 退避%v-%v, 连续失败%d次熔断%v", config.Name, policy.MaxAttempts, policy.BaseBackoff, policy.MaxBackoff, policy.BreakerThreshold, policy.BreakerCooldown)
+	}
+
+	// 主模型流式输出（集成投票的模型并发调用，不流式输出以免日志交错）
+	if config.AIStream {
+		for _, client := range append([]*mcp.Client{mcpClient}, mcpClient.Fallbacks...) {
+			client.Stream = true
+		}
+		log.Printf("🌊 [%s] 已启用AI流式输出", config.Name)
+	}
+
+	// AI请求/响应归档（故障切换链中的模型也归档）
+	if config.AIArchiveDays > 0 {
+		at.aiArchive = mcp.NewArchive(aiArchiveDir(logDir), time.Duration(config.AIArchiveDays)*24*time.Hour)
+		for _, client := range append(at.aiClients(), mcpClient.Fallbacks...) {
+			client.SetArchive(at.aiArchive)
+		}
+		log.Printf("🗄️  [%s] AI请求/响应归档: %s（保留%d天）", config.Name, at.aiArchive.Dir(), config.AIArchiveDays)
+	}
+
+	return at, nil
+}
+
+// newAIClient 根据模型配置创建AI客户端
+func newAIClient(spec AIModelSpec) (*mcp.Client, error) {
+	client := mcp.New()
+	switch spec.AIModel {
+	case "deepseek":
+		client.SetDeepSeekAPIKey(spec.APIKey)
+	case "qwen":
+		client.SetQwenAPIKey(spec.APIKey, "")
+	case "openai":
+		client.SetOpenAIAPIKey(spec.APIKey)
+	case "anthropic":
+		client.SetAnthropicAPIKey(spec.APIKey)
+	case "gemini":
+		client.SetGeminiAPIKey(spec.APIKey)
+	case "local":
+		client.SetLocalAPI(spec.APIURL, spec.ModelName)
+		client.APIKey = spec.APIKey
+	case "custom":
+		client.SetCustomAPI(spec.APIURL, spec.APIKey, spec.ModelName)
+	case "azure":
+		client.SetAzureAPI(spec.APIURL, spec.APIKey, spec.ModelName, spec.APIVersion)
+	case "openrouter":
+		var options mcp.OpenRouterOptions
+		if spec.OpenRouter != nil {
+			options = *spec.OpenRouter
+		}
+		client.SetOpenRouterAPI(spec.APIKey, spec.ModelName, options)
+		loadOpenRouterPrices()
+	case "mock":
+		mock := mcp.NewMock()
+		if spec.MockResponsesFile != "" {
+			var err error
+			if mock, err = mcp.LoadMock(spec.MockResponsesFile); err != nil {
+				return nil, err
+			}
+		}
+		client.SetMock(mock)
+	default:
+		return nil, fmt.Errorf("不支持的AI模型: %s", spec.AIModel)
+	}
+	if spec.ModelName != "" {
+		client.Model = spec.ModelName
+	}
+	if spec.Params != nil {
+		client.Params = *spec.Params
+	}
+	return client, nil
+}
+
+// mockScriptName 日志中显示的模拟响应脚本
+func mockScriptName(path string) string {
+	if path == "" {
+		return "内置空决策"
+	}
+	return path
 }
 
 // Run 运行自动交易主循环
@@ -193,32 +592,57 @@ func (at *AutoTrader) Run() error {
 	ticker := time.NewTicker(at.config.ScanInterval)
 	defer ticker.Stop()
 
+	// 移动止损和限价开仓单在两次AI决策之间由执行器维护
+	trailingTicker := time.NewTicker(trailingStopCheckInterval)
+	defer trailingTicker.Stop()
+
+	// 上次运行可能在开仓后、挂止损止盈前中断，先为已有持仓恢复保护
+	at.restoreProtection()
+
 	// 首次立即执行
-	if err := at.runCycle(); err != nil {
+	if err := at.runCycle(at.runCtx); err != nil {
 		log.Printf("❌ 执行失败: %v", err)
 	}
 
 	for at.isRunning {
 		select {
+		case <-at.runCtx.Done():
+			return nil
 		case <-ticker.C:
-			if err := at.runCycle(); err != nil {
+			if err := at.runCycle(at.runCtx); err != nil {
 				log.Printf("❌ 执行失败: %v", err)
 			}
+		case <-trailingTicker.C:
+			at.maintainPositions()
 		}
 	}
 
 	return nil
 }
 
-// Stop 停止自动交易
+// maintainPositions 两次AI决策之间的持仓维护：移动止损、限价开仓单成交检查、止损止盈联动
+func (at *AutoTrader) maintainPositions() {
+	at.updateTrailingStops()
+	at.checkPendingEntries()
+	at.linkProtectiveOrders()
+}
+
+// Stop 停止自动交易（进行中的行情获取、AI调用和开仓等待立即中止，本周期不再执行新的决策）。
+// 交易所请求在exchangeStopGrace后中止，正在执行的开仓仍能挂上止损止盈
 func (at *AutoTrader) Stop() {
 	at.isRunning = false
+	at.stopRun()
+	time.AfterFunc(exchangeStopGrace, at.stopExchange)
 	log.Println("⏹ 自动交易系统停止")
 }
 
-// runCycle 运行一个交易周期（使用AI全权决策）
-func (at *AutoTrader) runCycle() error {
+// runCycle 运行一个交易周期（使用AI全权决策），runCtx取消时中止
+func (at *AutoTrader) runCycle(runCtx context.Context) error {
 	at.callCount++
+	cycleCtx, cancel := at.cycleContext(runCtx, time.Now())
+	defer cancel()
+	at.executionDeadline = time.Now().Add(time.Duration(float64(at.config.ScanInterval) * executionDeadlineFraction))
+	at.setAICycle(time.Now())
 
 	log.Printf("\n" + strings.Repeat("=", 70))
 	log.Printf("⏰ %s - AI Decision Cycle #%d", time.Now().Format("2006-01-02 15:04:05"), at.callCount)
@@ -226,6 +650,7 @@ func (at *AutoTrader) runCycle() error {
 
 	// 创建决策记录
 	record := &logger.DecisionRecord{
+		CycleID:      at.cycleID,
 		ExecutionLog: []string{},
 		Success:      true,
 	}
@@ -247,7 +672,13 @@ func (at *AutoTrader) runCycle() error {
 		log.Println("📅 Daily P&L reset")
 	}
 
-	// 3. Collect trading context
+	// 3. Enforce machine-checkable invalidation rules before the AI sees the positions
+	at.enforceInvalidationRules(record)
+
+	// Protect filled limit entries and cancel expired ones
+	record.ExecutionLog = append(record.ExecutionLog, at.checkPendingEntries()...)
+
+	// 4. Collect trading context
 	ctx, err := at.buildTradingContext()
 	if err != nil {
 		record.Success = false
@@ -282,25 +713,67 @@ func (at *AutoTrader) runCycle() error {
 	// 保存候选币种列表
 	for _, coin := range ctx.CandidateCoins {
 		record.CandidateCoins = append(record.CandidateCoins, coin.Symbol)
+		record.CandidatePool = append(record.CandidatePool, logger.CandidateSnapshot{
+			Symbol:  coin.Symbol,
+			Sources: coin.Sources,
+			Score:   coin.Score,
+			Tags:    coin.Tags,
+		})
 	}
 
 	log.Printf("📊 Account equity: %.2f USDT | Available: %.2f USDT | Positions: %d",
 		ctx.Account.TotalEquity, ctx.Account.AvailableBalance, ctx.Account.PositionCount)
 
-	// 4. Call AI to get complete decision
+	// 5. Call AI to get complete decision
 	log.Println("🤖 Requesting AI analysis and decision...")
-	decision, err := decision.GetFullDecision(ctx, at.mcpClient)
+	decision, err := at.getDecision(cycleCtx, ctx)
+	record.AIUsage = at.takeAIUsage()
+
+	// Save the full context (with market data) so the cycle can be inspected and replayed offline
+	if path := at.saveContextSnapshot(ctx); path != "" {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("📸 Context snapshot: %s", path))
+	}
 
 	// Even if there's an error, save chain of thought, decision and input prompt (for debugging)
 	if decision != nil {
 		record.InputPrompt = decision.UserPrompt
+		record.RawResponse = decision.RawResponse
+		record.AIProvider = decision.Provider
 		record.CoTTrace = decision.CoTTrace
+		if decision.CoTSections != nil {
+			sectionsJSON, _ := json.MarshalIndent(decision.CoTSections, "", "  ")
+			record.CoTSectionsJSON = string(sectionsJSON)
+		}
 		if len(decision.Decisions) > 0 {
 			decisionJSON, _ := json.MarshalIndent(decision.Decisions, "", "  ")
 			record.DecisionJSON = string(decisionJSON)
 		}
+		if len(decision.ModelTraces) > 0 {
+			tracesJSON, _ := json.MarshalIndent(decision.ModelTraces, "", "  ")
+			record.ModelTracesJSON = string(tracesJSON)
+		}
+		for _, rejection := range decision.HookRejections {
+			log.Printf("⛔ Rejected by hook: %s", rejection)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⛔ %s (rejected by hook)", rejection))
+		}
+		if decision.PromptBudget != nil {
+			log.Printf("✂️  User prompt trimmed to fit token budget: %s", decision.PromptBudget)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✂️ Prompt trimmed: %s", decision.PromptBudget))
+		}
+		for _, veto := range decision.Vetoes {
+			log.Printf("👮 Vetoed by risk officer: %s", veto)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("👮 %s (vetoed by risk officer)", veto))
+		}
 	}
 
+	if errors.Is(err, mcp.ErrCircuitOpen) {
+		// AI接口熔断中：本周期跳过（不开新仓，持仓由止损止盈和移动止损保护），等待冷却后自动恢复
+		log.Printf("⏭️  AI接口熔断中，跳过本周期: %v", err)
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("Cycle skipped: %v", err)
+		at.decisionLogger.LogDecision(record)
+		return nil
+	}
 	if err != nil {
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("Failed to get AI decision: %v", err)
@@ -318,25 +791,31 @@ func (at *AutoTrader) runCycle() error {
 		return fmt.Errorf("failed to get AI decision: %w", err)
 	}
 
-	// 5. Print AI chain of thought
+	// 6. Print AI chain of thought
 	log.Printf("\n" + strings.Repeat("-", 70))
 	log.Println("💭 AI Chain of Thought Analysis:")
 	log.Println(strings.Repeat("-", 70))
 	log.Println(decision.CoTTrace)
 	log.Printf(strings.Repeat("-", 70) + "\n")
 
-	// 6. Print AI decisions
+	// 7. Print AI decisions
 	log.Printf("📋 AI Decision List (%d items):\n", len(decision.Decisions))
 	for i, d := range decision.Decisions {
 		log.Printf("  [%d] %s: %s - %s", i+1, d.Symbol, d.Action, d.Reasoning)
 		if d.Action == "open_long" || d.Action == "open_short" {
 			log.Printf("      Leverage: %dx | Position: %.2f USDT | Stop Loss: %.4f | Take Profit: %.4f",
 				d.Leverage, d.PositionSizeUSD, d.StopLoss, d.TakeProfit)
+			if d.TrailingCallbackRate > 0 {
+				log.Printf("      Trailing Stop: activate at %.4f, callback %.2f%%", d.TrailingActivation, d.TrailingCallbackRate)
+			}
+		}
+		if d.Action == "reduce_long" || d.Action == "reduce_short" {
+			log.Printf("      Close: %.1f%% of position", d.ClosePercentage)
 		}
 	}
 	log.Println()
 
-	// 7. Sort decisions: ensure close positions before opening (prevent position stacking overflow)
+	// 8. Sort decisions: ensure close positions before opening (prevent position stacking overflow)
 	sortedDecisions := sortDecisionsByPriority(decision.Decisions)
 
 	log.Println("🔄 Execution order (optimized): Close first → Open later")
@@ -347,14 +826,21 @@ func (at *AutoTrader) runCycle() error {
 
 	// Execute decisions and record results
 	for _, d := range sortedDecisions {
+		if runCtx.Err() != nil {
+			log.Printf("⏹ Trader stopping, skipped remaining decisions from %s %s", d.Symbol, d.Action)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⏹ Trader stopped, skipped remaining decisions from %s %s", d.Symbol, d.Action))
+			break
+		}
 		actionRecord := logger.DecisionAction{
-			Action:    d.Action,
-			Symbol:    d.Symbol,
-			Quantity:  0,
-			Leverage:  d.Leverage,
-			Price:     0,
-			Timestamp: time.Now(),
-			Success:   false,
+			Action:     d.Action,
+			Symbol:     d.Symbol,
+			Quantity:   0,
+			Leverage:   d.Leverage,
+			Price:      0,
+			Timestamp:  time.Now(),
+			Success:    false,
+			Reasoning:  d.Reasoning,
+			Confidence: d.Confidence,
 		}
 
 		if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
@@ -365,13 +851,13 @@ func (at *AutoTrader) runCycle() error {
 			actionRecord.Success = true
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s success", d.Symbol, d.Action))
 			// Brief delay after successful execution
-			time.Sleep(1 * time.Second)
+			sleepContext(at.runCtx, time.Second)
 		}
 
 		record.Decisions = append(record.Decisions, actionRecord)
 	}
 
-	// 8. Save decision record
+	// 9. Save decision record
 	if err := at.decisionLogger.LogDecision(record); err != nil {
 		log.Printf("⚠ Failed to save decision record: %v", err)
 	}
@@ -379,6 +865,38 @@ func (at *AutoTrader) runCycle() error {
 	return nil
 }
 
+// getDecision 获取AI决策（低风险周期使用便宜模型；启用集成投票时并行调用所有模型，启用风控审核时再由审核模型逐条审核）
+func (at *AutoTrader) getDecision(cycleCtx context.Context, ctx *decision.Context) (*decision.FullDecision, error) {
+	var fullDecision *decision.FullDecision
+	var err error
+	if reason, lowStakes := at.lowStakes(ctx); lowStakes {
+		log.Printf("🪙 低风险周期（%s），使用便宜模型 %s", reason, at.cheapClient.Name())
+		fullDecision, err = decision.GetFullDecision(cycleCtx, ctx, at.cheapClient)
+	} else if len(at.ensembleClients) == 0 {
+		fullDecision, err = decision.GetFullDecision(cycleCtx, ctx, at.mcpClient)
+	} else {
+		clients := append([]*mcp.Client{at.mcpClient}, at.ensembleClients...)
+		fullDecision, err = decision.GetEnsembleDecision(cycleCtx, ctx, clients, decision.EnsembleConfig{
+			Mode:          at.config.EnsembleMode,
+			MinAgree:      at.config.EnsembleMinAgree,
+			LatencyBudget: at.config.EnsembleLatencyBudget,
+		})
+	}
+	if err != nil || at.riskOfficerClient == nil {
+		return fullDecision, err
+	}
+
+	// 审核失败时不阻塞交易（硬性约束已由校验保证），仅记录日志
+	decisions, vetoes, reviewErr := decision.ReviewDecisions(cycleCtx, ctx, fullDecision.Decisions, at.riskOfficerClient)
+	if reviewErr != nil {
+		log.Printf("⚠️  风控审核失败，按原决策执行: %v", reviewErr)
+		return fullDecision, nil
+	}
+	fullDecision.Decisions = decisions
+	fullDecision.Vetoes = vetoes
+	return fullDecision, nil
+}
+
 // buildTradingContext 构建交易上下文
 func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	// 1. 获取账户信息
@@ -416,6 +934,7 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 
 	// 当前持仓的key集合（用于清理已平仓的记录）
 	currentPositionKeys := make(map[string]bool)
+	stateChanged := false
 
 	for _, pos := range positions {
 		symbol := pos["symbol"].(string)
@@ -451,6 +970,7 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		if _, exists := at.positionFirstSeenTime[posKey]; !exists {
 			// 新持仓，记录当前时间
 			at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+			stateChanged = true
 		}
 		updateTime := at.positionFirstSeenTime[posKey]
 
@@ -481,42 +1001,50 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 			posInfo.InvalidationCondition = exitPlanInfo.InvalidationCondition
 			posInfo.Confidence = exitPlanInfo.Confidence
 			posInfo.RiskUSD = exitPlanInfo.RiskUSD
+			posInfo.TrailingActivation = exitPlanInfo.TrailingActivation
+			posInfo.TrailingCallbackRate = exitPlanInfo.TrailingCallbackRate
+			posInfo.TrailingATRMultiple = exitPlanInfo.TrailingATRMultiple
+			posInfo.TrailingOnExchange = exitPlanInfo.TrailingOnExchange
+			posInfo.InvalidationRule = exitPlanInfo.InvalidationRule
+			posInfo.InvalidationTriggered = exitPlanInfo.InvalidationTriggered
 		}
 
 		positionInfos = append(positionInfos, posInfo)
 	}
 
-	// 清理已平仓的持仓记录
-	for key := range at.positionFirstSeenTime {
-		if !currentPositionKeys[key] {
-			delete(at.positionFirstSeenTime, key)
-			delete(at.positionExitPlans, key)
-		}
+	// 清理已平仓的持仓记录（同时撤销残留的止损止盈单）
+	if at.releaseClosedPositions(currentPositionKeys) {
+		stateChanged = true
+	}
+	if stateChanged {
+		at.savePositionState()
 	}
 
-	// 3. 获取合并的候选币种池（AI500 + OI Top，去重）
+	// 3. 获取合并的候选币种池（所有启用的来源，默认AI500 + OI Top，去重）
 	// 无论有没有持仓，都分析相同数量的币种（让AI看到所有好机会）
 	// AI会根据保证金使用率和现有持仓情况，自己决定是否要换仓
-	const ai500Limit = 20 // AI500取前20个评分最高的币种
-
-	// 获取合并后的币种池（AI500 + OI Top）
-	mergedPool, err := pool.GetMergedCoinPool(ai500Limit)
+	mergedPool, err := pool.GetMergedCoinPool()
 	if err != nil {
 		return nil, fmt.Errorf("获取合并币种池失败: %w", err)
 	}
 
-	// 构建候选币种列表（包含来源信息）
+	// 构建候选币种列表（包含来源、评分、排名和标签，评分用于token预算不足时裁剪候选币种）
 	var candidateCoins []decision.CandidateCoin
 	for _, symbol := range mergedPool.AllSymbols {
-		sources := mergedPool.SymbolSources[symbol]
+		var ranks []decision.SourceRank
+		for _, r := range mergedPool.Ranks[symbol] {
+			ranks = append(ranks, decision.SourceRank{Source: r.Source, Rank: r.Rank, Total: r.Total, Score: r.Score})
+		}
 		candidateCoins = append(candidateCoins, decision.CandidateCoin{
 			Symbol:  symbol,
-			Sources: sources, // "ai500" 和/或 "oi_top"
+			Sources: mergedPool.SymbolSources[symbol], // 例如 "ai500" 和/或 "oi_top"
+			Score:   mergedPool.Scores[symbol],
+			Tags:    mergedPool.Tags[symbol],
+			Ranks:   ranks,
 		})
 	}
 
-	log.Printf("📋 合并币种池: AI500前%d + OI_Top20 = 总计%d个候选币种",
-		ai500Limit, len(candidateCoins))
+	log.Printf("📋 合并币种池: 总计%d个候选币种", len(candidateCoins))
 
 	// 4. 计算总盈亏
 	totalPnL := totalEquity - at.initialBalance
@@ -555,10 +1083,37 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 			MarginUsedPct:    marginUsedPct,
 			PositionCount:    len(positionInfos),
 		},
-		Positions:      positionInfos,
-		CandidateCoins: candidateCoins,
-		Performance:    performance, // 添加历史表现分析
-	}
+		Positions:            positionInfos,
+		CandidateCoins:       candidateCoins,
+		Performance:          performance, // 添加历史表现分析
+		PromptTemplate:       at.promptTemplate,
+		MaxCorrections:       at.config.ValidationRetries,
+		MinRiskReward:        at.config.MinRiskReward,
+		MaxPositions:         at.config.MaxPositions,
+		SymbolLimits:         at.config.SymbolLimits,
+		PositionSizing:       at.config.PositionSizing,
+		TradeCooldownMinutes: at.config.TradeCooldown,
+		RecentCloses:         at.recentCloses(),
+		PromptTokenBudget:    at.config.PromptTokenBudget,
+		MarketDataFormat:     at.config.MarketDataFormat,
+		Timeframes:           at.config.Timeframes,
+		Indicators:           at.config.Indicators,
+		CostModel:            at.config.CostModel,
+		PendingEntries:       at.pendingEntryList(),
+		HedgeMode:            at.config.HedgeMode,
+		FearGreed:            at.config.FearGreed,
+		DepegThresholdPct:    at.config.DepegThreshold,
+		PauseOnDepeg:         at.config.PauseOnDepeg,
+		Blacklist:            pool.Blacklist(),
+		Sectors:              symbolSectors(positionInfos, candidateCoins),
+		MaxSectorPositions:   at.config.MaxSectorPositions,
+		MaxSpreadPct:         at.config.MaxSpreadPct,
+		MinDepthMultiple:     at.config.MinDepthMultiple,
+		MaxToolRounds:        at.config.AIToolRounds,
+		Charts:               at.config.Charts,
+		ExchangeRules:        at.config.Exchange == market.SourceName() || at.config.Exchange == "paper", // 交易规则来自行情数据源，只在与交易所一致时检查（模拟交易按行情数据源撮合）
+	}
+	ctx.RecentDecisions = at.buildDecisionMemory(performance)
 
 	return ctx, nil
 }
@@ -574,6 +1129,10 @@ func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, act
 		return at.executeCloseLongWithRecord(decision, actionRecord)
 	case "close_short":
 		return at.executeCloseShortWithRecord(decision, actionRecord)
+	case "reduce_long":
+		return at.executeReduceWithRecord(decision, actionRecord, "long")
+	case "reduce_short":
+		return at.executeReduceWithRecord(decision, actionRecord, "short")
 	case "hold", "wait":
 		// No execution needed, just record
 		return nil
@@ -595,9 +1154,17 @@ func (at *AutoTrader) executeOpenLongWithRecord(dec *decision.Decision, actionRe
 			}
 		}
 	}
+	if err := at.checkNoPendingEntry(dec.Symbol, "long"); err != nil {
+		return err
+	}
+
+	// Limit entry: rest the order, stop loss and take profit are set once it fills
+	if dec.IsLimitEntry() {
+		return at.placeLimitEntry(dec, actionRecord, "long")
+	}
 
 	// Get current price
-	marketData, err := market.Get(dec.Symbol)
+	marketData, err := market.GetContext(at.runCtx, dec.Symbol)
 	if err != nil {
 		return err
 	}
@@ -607,41 +1174,29 @@ func (at *AutoTrader) executeOpenLongWithRecord(dec *decision.Decision, actionRe
 	actionRecord.Quantity = quantity
 	actionRecord.Price = marketData.CurrentPrice
 
-	// Open position
-	order, err := at.trader.OpenLong(dec.Symbol, quantity, dec.Leverage)
-	if err != nil {
-		return err
-	}
-
-	// Record order ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
-
-	log.Printf("  ✓ Position opened successfully, Order ID: %v, Quantity: %.4f", order["orderId"], quantity)
-
-	// Record position open time
+	// Store open time and exit plan before the order is sent, so a restart after a crash can still protect the position
 	posKey := dec.Symbol + "_long"
+	plan := exitPlanFromDecision(dec)
 	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	at.positionExitPlans[posKey] = plan
+	at.savePositionState()
 
-	// Store exit plan info
-	at.positionExitPlans[posKey] = &decision.PositionInfo{
-		StopLoss:              dec.StopLoss,
-		TakeProfit:            dec.TakeProfit,
-		InvalidationCondition: dec.InvalidationCondition,
-		Confidence:            dec.Confidence,
-		RiskUSD:               dec.RiskUSD,
+	// Large positions are entered in timed slices so the bot's own order doesn't move the price against it
+	if slices := at.entrySlices(dec.Symbol, "long", dec.PositionSizeUSD); slices > 1 {
+		return at.splitEntry(dec.Symbol, "long", quantity, dec.Leverage, slices, plan, actionRecord)
 	}
 
-	// Set stop loss and take profit
-	if err := at.trader.SetStopLoss(dec.Symbol, "LONG", quantity, dec.StopLoss); err != nil {
-		log.Printf("  ⚠ Failed to set stop loss: %v", err)
-	}
-	if err := at.trader.SetTakeProfit(dec.Symbol, "LONG", quantity, dec.TakeProfit); err != nil {
-		log.Printf("  ⚠ Failed to set take profit: %v", err)
+	// Open position
+	order, err := at.openPosition(dec.Symbol, "long", quantity, dec.Leverage, 0)
+	if err != nil {
+		delete(at.positionFirstSeenTime, posKey)
+		delete(at.positionExitPlans, posKey)
+		at.savePositionState()
+		return err
 	}
+	at.forgetCancelledEntries(dec.Symbol)
 
-	return nil
+	return at.confirmEntry(dec.Symbol, "long", quantity, order, plan, actionRecord)
 }
 
 // executeOpenShortWithRecord Execute open short position and record detailed information
@@ -657,9 +1212,17 @@ func (at *AutoTrader) executeOpenShortWithRecord(dec *decision.Decision, actionR
 			}
 		}
 	}
+	if err := at.checkNoPendingEntry(dec.Symbol, "short"); err != nil {
+		return err
+	}
+
+	// Limit entry: rest the order, stop loss and take profit are set once it fills
+	if dec.IsLimitEntry() {
+		return at.placeLimitEntry(dec, actionRecord, "short")
+	}
 
 	// Get current price
-	marketData, err := market.Get(dec.Symbol)
+	marketData, err := market.GetContext(at.runCtx, dec.Symbol)
 	if err != nil {
 		return err
 	}
@@ -669,41 +1232,83 @@ func (at *AutoTrader) executeOpenShortWithRecord(dec *decision.Decision, actionR
 	actionRecord.Quantity = quantity
 	actionRecord.Price = marketData.CurrentPrice
 
+	// Store open time and exit plan before the order is sent, so a restart after a crash can still protect the position
+	posKey := dec.Symbol + "_short"
+	plan := exitPlanFromDecision(dec)
+	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	at.positionExitPlans[posKey] = plan
+	at.savePositionState()
+
+	// Large positions are entered in timed slices so the bot's own order doesn't move the price against it
+	if slices := at.entrySlices(dec.Symbol, "short", dec.PositionSizeUSD); slices > 1 {
+		return at.splitEntry(dec.Symbol, "short", quantity, dec.Leverage, slices, plan, actionRecord)
+	}
+
 	// Open position
-	order, err := at.trader.OpenShort(dec.Symbol, quantity, dec.Leverage)
+	order, err := at.openPosition(dec.Symbol, "short", quantity, dec.Leverage, 0)
 	if err != nil {
+		delete(at.positionFirstSeenTime, posKey)
+		delete(at.positionExitPlans, posKey)
+		at.savePositionState()
 		return err
 	}
+	at.forgetCancelledEntries(dec.Symbol)
 
-	// Record order ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
-	}
-
-	log.Printf("  ✓ Position opened successfully, Order ID: %v, Quantity: %.4f", order["orderId"], quantity)
-
-	// Record position open time
-	posKey := dec.Symbol + "_short"
-	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+	return at.confirmEntry(dec.Symbol, "short", quantity, order, plan, actionRecord)
+}
 
-	// Store exit plan info
-	at.positionExitPlans[posKey] = &decision.PositionInfo{
-		StopLoss:              dec.StopLoss,
-		TakeProfit:            dec.TakeProfit,
-		InvalidationCondition: dec.InvalidationCondition,
-		Confidence:            dec.Confidence,
-		RiskUSD:               dec.RiskUSD,
+// confirmEntry Track a market entry until it fills, record the actual fill and protect the filled quantity
+func (at *AutoTrader) confirmEntry(symbol, side string, quantity float64, order map[string]interface{}, plan *decision.PositionInfo, actionRecord *logger.DecisionAction) error {
+	entry := newTrackedOrder(symbol, side, quantity, order)
+	at.awaitMarketFill(entry, 0)
+	actionRecord.OrderID = entry.OrderID
+	if entry.Filled == 0 {
+		posKey := symbol + "_" + side
+		delete(at.positionFirstSeenTime, posKey)
+		delete(at.positionExitPlans, posKey)
+		at.savePositionState()
+		return fmt.Errorf("open order %d %s, nothing filled", entry.OrderID, entry.State)
 	}
 
-	// Set stop loss and take profit
-	if err := at.trader.SetStopLoss(dec.Symbol, "SHORT", quantity, dec.StopLoss); err != nil {
-		log.Printf("  ⚠ Failed to set stop loss: %v", err)
-	}
-	if err := at.trader.SetTakeProfit(dec.Symbol, "SHORT", quantity, dec.TakeProfit); err != nil {
-		log.Printf("  ⚠ Failed to set take profit: %v", err)
+	actionRecord.Quantity = entry.Filled
+	if entry.AvgPrice > 0 {
+		actionRecord.Price = entry.AvgPrice
 	}
+	plan.Quantity = entry.Filled
+	log.Printf("  ✓ Position opened, Order ID: %d, %s", entry.OrderID, entry.fillSummary())
 
-	return nil
+	// Place reduce-only stop loss and take profit right away for the filled quantity (closed again if no stop loss can be placed)
+	return at.protectNewPosition(symbol, side, entry.Filled, plan)
+}
+
+// confirmExit Track a closing order until it fills; a partially filled close keeps the rest of the position
+// and re-places its stop loss / take profit. Returns the remaining quantity
+func (at *AutoTrader) confirmExit(symbol, side string, positionBefore, quantity float64, order map[string]interface{}, actionRecord *logger.DecisionAction) float64 {
+	exit := newTrackedOrder(symbol, side, quantity, order)
+	at.awaitMarketFill(exit, positionBefore)
+	actionRecord.OrderID = exit.OrderID
+	actionRecord.Quantity = exit.Filled
+	if exit.AvgPrice > 0 {
+		actionRecord.Price = exit.AvgPrice
+	}
+	log.Printf("  ✓ Close order %d: %s", exit.OrderID, exit.fillSummary())
+
+	remaining := positionBefore - exit.Filled
+	if remaining <= positionBefore*(1-orderFillTolerance) {
+		return 0
+	}
+	if plan, exists := at.positionExitPlans[symbol+"_"+side]; exists {
+		plan.Quantity = remaining
+		if err := at.resizeProtection(symbol, side, remaining, plan); err != nil {
+			log.Printf("  ❌ %v", err)
+			if _, open := at.positionExitPlans[symbol+"_"+side]; !open {
+				return 0 // Rest of the position was closed at market because no stop loss could be placed
+			}
+		}
+	} else {
+		log.Printf("  ⚠ No exit plan stored for %s %s, stop loss/take profit not restored", symbol, side)
+	}
+	return remaining
 }
 
 // executeCloseLongWithRecord Execute close long position and record detailed information
@@ -711,24 +1316,31 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 	log.Printf("  🔄 Close long: %s", decision.Symbol)
 
 	// Get current price
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := market.GetContext(at.runCtx, decision.Symbol)
 	if err != nil {
 		return err
 	}
 	actionRecord.Price = marketData.CurrentPrice
+	positionQty, err := at.positionQuantity(decision.Symbol, "long")
+	if err != nil {
+		return err
+	}
 
 	// Close position
 	order, err := at.trader.CloseLong(decision.Symbol, 0) // 0 = close all
 	if err != nil {
 		return err
 	}
+	at.forgetCancelledEntries(decision.Symbol)
 
-	// Record order ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
+	if remaining := at.confirmExit(decision.Symbol, "long", positionQty, positionQty, order, actionRecord); remaining > 0 {
+		return fmt.Errorf("close only partially filled, %.4f of %.4f still open with stop loss/take profit", remaining, positionQty)
 	}
-
 	log.Printf("  ✓ Position closed successfully")
+	// Forget the closed position now, otherwise the protection watcher later cancels orders by symbol
+	// (in one-way mode that would hit a position opened on the other side in the same cycle)
+	at.forgetClosedPosition(decision.Symbol + "_long")
+	at.recordClose(decision.Symbol)
 	return nil
 }
 
@@ -737,24 +1349,72 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 	log.Printf("  🔄 Close short: %s", decision.Symbol)
 
 	// Get current price
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := market.GetContext(at.runCtx, decision.Symbol)
 	if err != nil {
 		return err
 	}
 	actionRecord.Price = marketData.CurrentPrice
+	positionQty, err := at.positionQuantity(decision.Symbol, "short")
+	if err != nil {
+		return err
+	}
 
 	// Close position
 	order, err := at.trader.CloseShort(decision.Symbol, 0) // 0 = close all
 	if err != nil {
 		return err
 	}
+	at.forgetCancelledEntries(decision.Symbol)
 
-	// Record order ID
-	if orderID, ok := order["orderId"].(int64); ok {
-		actionRecord.OrderID = orderID
+	if remaining := at.confirmExit(decision.Symbol, "short", positionQty, positionQty, order, actionRecord); remaining > 0 {
+		return fmt.Errorf("close only partially filled, %.4f of %.4f still open with stop loss/take profit", remaining, positionQty)
 	}
-
 	log.Printf("  ✓ Position closed successfully")
+	// Forget the closed position now, otherwise the protection watcher later cancels orders by symbol
+	// (in one-way mode that would hit a position opened on the other side in the same cycle)
+	at.forgetClosedPosition(decision.Symbol + "_short")
+	at.recordClose(decision.Symbol)
+	return nil
+}
+
+// executeReduceWithRecord Execute partial close and restore stop loss / take profit for the remaining quantity
+func (at *AutoTrader) executeReduceWithRecord(dec *decision.Decision, actionRecord *logger.DecisionAction, side string) error {
+	log.Printf("  ✂️ Reduce %s: %s (%.1f%%)", side, dec.Symbol, dec.ClosePercentage)
+
+	// Find current position quantity
+	positionQty, err := at.positionQuantity(dec.Symbol, side)
+	if err != nil {
+		return err
+	}
+	if positionQty == 0 {
+		return fmt.Errorf("no %s position found for %s", side, dec.Symbol)
+	}
+
+	// Get current price
+	marketData, err := market.GetContext(at.runCtx, dec.Symbol)
+	if err != nil {
+		return err
+	}
+
+	quantity := positionQty * dec.ClosePercentage / 100
+	actionRecord.Quantity = quantity
+	actionRecord.Price = marketData.CurrentPrice
+
+	// Close part of the position
+	var order map[string]interface{}
+	if side == "long" {
+		order, err = at.trader.CloseLong(dec.Symbol, quantity)
+	} else {
+		order, err = at.trader.CloseShort(dec.Symbol, quantity)
+	}
+	if err != nil {
+		return err
+	}
+	at.forgetCancelledEntries(dec.Symbol)
+
+	// Closing cancels the symbol's protective orders, confirmExit re-places them for the actual remaining quantity
+	remaining := at.confirmExit(dec.Symbol, side, positionQty, quantity, order, actionRecord)
+	log.Printf("  ✓ Position reduced, Remaining: %.4f / %.4f", remaining, positionQty)
 	return nil
 }
 
@@ -945,8 +1605,8 @@ func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision
 	// 定义优先级
 	getActionPriority := func(action string) int {
 		switch action {
-		case "close_long", "close_short":
-			return 1 // 最高优先级：先平仓
+		case "close_long", "close_short", "reduce_long", "reduce_short":
+			return 1 // 最高优先级：先平仓（含部分平仓）
 		case "open_long", "open_short":
 			return 2 // 次优先级：后开仓
 		case "hold", "wait":
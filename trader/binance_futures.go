@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"nofx/market"
+	"nofx/proxy"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -14,6 +17,7 @@ import (
 // FuturesTrader 币安合约交易器
 type FuturesTrader struct {
 	client *futures.Client
+	ctx    context.Context // 请求使用的context（见SetContext）
 
 	// 余额缓存
 	cachedBalance     map[string]interface{}
@@ -27,17 +31,38 @@ type FuturesTrader struct {
 
 	// 缓存有效期（15秒）
 	cacheDuration time.Duration
+
+	// 对冲模式（双向持仓）：撤单时只撤对应方向，保留另一方向的止损止盈单
+	hedgeMode bool
 }
 
-// NewFuturesTrader 创建合约交易器
-func NewFuturesTrader(apiKey, secretKey string) *FuturesTrader {
+// binanceFuturesTestnetURL 币安合约测试网（API Key需在testnet.binancefuture.com单独申请）
+const binanceFuturesTestnetURL = "https://testnet.binancefuture.com"
+
+func init() {
+	proxy.Register(proxy.KindExchange, binanceFuturesTestnetURL)
+}
+
+// NewFuturesTrader 创建合约交易器（testnet为true时连接合约测试网）
+func NewFuturesTrader(apiKey, secretKey string, testnet bool) *FuturesTrader {
 	client := futures.NewClient(apiKey, secretKey)
+	client.HTTPClient = market.NewRateLimitedClient() // 与行情数据共享IP请求权重额度
+	if testnet {
+		// 只修改该客户端的地址，不使用futures.UseTestnet（全局变量会影响同进程中的其他交易员）
+		client.BaseURL = binanceFuturesTestnetURL
+	}
 	return &FuturesTrader{
 		client:        client,
+		ctx:           context.Background(),
 		cacheDuration: 15 * time.Second, // 15秒缓存
 	}
 }
 
+// SetContext 设置请求使用的context，取消后进行中的请求立即返回
+func (t *FuturesTrader) SetContext(ctx context.Context) {
+	t.ctx = ctx
+}
+
 // GetBalance 获取账户余额（带缓存）
 func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
 	// 先检查缓存是否有效
@@ -52,7 +77,7 @@ func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
 
 	// 缓存过期或不存在，调用API
 	log.Printf("🔄 缓存过期，正在调用币安API获取账户余额...")
-	account, err := t.client.NewGetAccountService().Do(context.Background())
+	account, err := t.client.NewGetAccountService().Do(t.ctx)
 	if err != nil {
 		log.Printf("❌ 币安API调用失败: %v", err)
 		return nil, fmt.Errorf("获取账户信息失败: %w", err)
@@ -91,7 +116,7 @@ func (t *FuturesTrader) GetPositions() ([]map[string]interface{}, error) {
 
 	// 缓存过期或不存在，调用API
 	log.Printf("🔄 缓存过期，正在调用币安API获取持仓信息...")
-	positions, err := t.client.NewGetPositionRiskService().Do(context.Background())
+	positions, err := t.client.NewGetPositionRiskService().Do(t.ctx)
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
@@ -112,8 +137,12 @@ func (t *FuturesTrader) GetPositions() ([]map[string]interface{}, error) {
 		posMap["leverage"], _ = strconv.ParseFloat(pos.Leverage, 64)
 		posMap["liquidationPrice"], _ = strconv.ParseFloat(pos.LiquidationPrice, 64)
 
-		// 判断方向
-		if posAmt > 0 {
+		// 判断方向（对冲模式下以positionSide为准，单向模式为BOTH，按数量正负判断）
+		if pos.PositionSide == string(futures.PositionSideTypeLong) {
+			posMap["side"] = "long"
+		} else if pos.PositionSide == string(futures.PositionSideTypeShort) {
+			posMap["side"] = "short"
+		} else if posAmt > 0 {
 			posMap["side"] = "long"
 		} else {
 			posMap["side"] = "short"
@@ -157,7 +186,7 @@ func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
 	_, err = t.client.NewChangeLeverageService().
 		Symbol(symbol).
 		Leverage(leverage).
-		Do(context.Background())
+		Do(t.ctx)
 
 	if err != nil {
 		// 如果错误信息包含"No need to change"，说明杠杆已经是目标值
@@ -172,7 +201,7 @@ func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
 
 	// 切换杠杆后等待5秒（避免冷却期错误）
 	log.Printf("  ⏱ 等待5秒冷却期...")
-	time.Sleep(5 * time.Second)
+	sleepContext(t.ctx, 5*time.Second)
 
 	return nil
 }
@@ -182,7 +211,7 @@ func (t *FuturesTrader) SetMarginType(symbol string, marginType futures.MarginTy
 	err := t.client.NewChangeMarginTypeService().
 		Symbol(symbol).
 		MarginType(marginType).
-		Do(context.Background())
+		Do(t.ctx)
 
 	if err != nil {
 		// 如果已经是该模式，不算错误
@@ -197,7 +226,7 @@ func (t *FuturesTrader) SetMarginType(symbol string, marginType futures.MarginTy
 
 	// 切换保证金模式后等待3秒（避免冷却期错误）
 	log.Printf("  ⏱ 等待3秒冷却期...")
-	time.Sleep(3 * time.Second)
+	sleepContext(t.ctx, 3*time.Second)
 
 	return nil
 }
@@ -205,7 +234,7 @@ func (t *FuturesTrader) SetMarginType(symbol string, marginType futures.MarginTy
 // OpenLong 开多仓
 func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.cancelOrders(symbol, futures.PositionSideTypeLong); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
 	}
 
@@ -232,12 +261,13 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 		PositionSide(futures.PositionSideTypeLong).
 		Type(futures.OrderTypeMarket).
 		Quantity(quantityStr).
-		Do(context.Background())
+		Do(t.ctx)
 
 	if err != nil {
 		return nil, fmt.Errorf("开多仓失败: %w", err)
 	}
 
+	t.invalidateCache()
 	log.Printf("✓ 开多仓成功: %s 数量: %s", symbol, quantityStr)
 	log.Printf("  订单ID: %d", order.OrderID)
 
@@ -251,7 +281,7 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 // OpenShort 开空仓
 func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
 	// 先取消该币种的所有委托单（清理旧的止损止盈单）
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.cancelOrders(symbol, futures.PositionSideTypeShort); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
 	}
 
@@ -278,12 +308,13 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 		PositionSide(futures.PositionSideTypeShort).
 		Type(futures.OrderTypeMarket).
 		Quantity(quantityStr).
-		Do(context.Background())
+		Do(t.ctx)
 
 	if err != nil {
 		return nil, fmt.Errorf("开空仓失败: %w", err)
 	}
 
+	t.invalidateCache()
 	log.Printf("✓ 开空仓成功: %s 数量: %s", symbol, quantityStr)
 	log.Printf("  订单ID: %d", order.OrderID)
 
@@ -294,6 +325,71 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 	return result, nil
 }
 
+// PlaceLimitEntry 挂限价开仓单（GTC，成交后由调用方设置止损止盈）
+func (t *FuturesTrader) PlaceLimitEntry(symbol string, positionSide string, quantity float64, leverage int, price float64) (map[string]interface{}, error) {
+	return t.placeLimitOrder(symbol, positionSide, quantity, leverage, price, futures.TimeInForceTypeGTC)
+}
+
+// PlacePostOnlyEntry 挂只做maker的限价开仓单（GTX，会立即成交时交易所直接使订单失效）
+func (t *FuturesTrader) PlacePostOnlyEntry(symbol string, positionSide string, quantity float64, leverage int, price float64) (map[string]interface{}, error) {
+	return t.placeLimitOrder(symbol, positionSide, quantity, leverage, price, futures.TimeInForceTypeGTX)
+}
+
+// placeLimitOrder 挂限价开仓单
+func (t *FuturesTrader) placeLimitOrder(symbol string, positionSide string, quantity float64, leverage int, price float64, timeInForce futures.TimeInForceType) (map[string]interface{}, error) {
+	// 不取消已有委托：同币种反方向持仓的止损止盈单需要保留
+
+	// 设置杠杆
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	// 设置逐仓模式
+	if err := t.SetMarginType(symbol, futures.MarginTypeIsolated); err != nil {
+		return nil, err
+	}
+
+	side := futures.SideTypeBuy
+	posSide := futures.PositionSideTypeLong
+	if positionSide == "SHORT" {
+		side = futures.SideTypeSell
+		posSide = futures.PositionSideTypeShort
+	}
+
+	// 格式化数量和价格到正确精度
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	priceStr, err := t.formatPrice(symbol, price)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(side).
+		PositionSide(posSide).
+		Type(futures.OrderTypeLimit).
+		TimeInForce(timeInForce).
+		Price(priceStr).
+		Quantity(quantityStr).
+		Do(t.ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("挂限价开仓单失败: %w", err)
+	}
+
+	log.Printf("✓ 限价开仓单已挂出: %s %s 数量: %s 价格: %s", symbol, positionSide, quantityStr, priceStr)
+	log.Printf("  订单ID: %d", order.OrderID)
+
+	result := make(map[string]interface{})
+	result["orderId"] = order.OrderID
+	result["symbol"] = order.Symbol
+	result["status"] = order.Status
+	return result, nil
+}
+
 // CloseLong 平多仓
 func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
 	// 如果数量为0，获取当前持仓数量
@@ -328,16 +424,17 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 		PositionSide(futures.PositionSideTypeLong).
 		Type(futures.OrderTypeMarket).
 		Quantity(quantityStr).
-		Do(context.Background())
+		Do(t.ctx)
 
 	if err != nil {
 		return nil, fmt.Errorf("平多仓失败: %w", err)
 	}
 
+	t.invalidateCache()
 	log.Printf("✓ 平多仓成功: %s 数量: %s", symbol, quantityStr)
 
 	// 平仓后取消该币种的所有挂单（止损止盈单）
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.cancelOrders(symbol, futures.PositionSideTypeLong); err != nil {
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
@@ -382,16 +479,17 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 		PositionSide(futures.PositionSideTypeShort).
 		Type(futures.OrderTypeMarket).
 		Quantity(quantityStr).
-		Do(context.Background())
+		Do(t.ctx)
 
 	if err != nil {
 		return nil, fmt.Errorf("平空仓失败: %w", err)
 	}
 
+	t.invalidateCache()
 	log.Printf("✓ 平空仓成功: %s 数量: %s", symbol, quantityStr)
 
 	// 平仓后取消该币种的所有挂单（止损止盈单）
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.cancelOrders(symbol, futures.PositionSideTypeShort); err != nil {
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
@@ -406,7 +504,7 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 func (t *FuturesTrader) CancelAllOrders(symbol string) error {
 	err := t.client.NewCancelAllOpenOrdersService().
 		Symbol(symbol).
-		Do(context.Background())
+		Do(t.ctx)
 
 	if err != nil {
 		return fmt.Errorf("取消挂单失败: %w", err)
@@ -416,9 +514,109 @@ func (t *FuturesTrader) CancelAllOrders(symbol string) error {
 	return nil
 }
 
+// GetOrderStatus 查询订单状态（status为币安订单状态，executedQty/avgPrice为已成交数量和均价）
+func (t *FuturesTrader) GetOrderStatus(symbol string, orderID int64) (map[string]interface{}, error) {
+	order, err := t.client.NewGetOrderService().
+		Symbol(symbol).
+		OrderID(orderID).
+		Do(t.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	result := make(map[string]interface{})
+	result["orderId"] = order.OrderID
+	result["symbol"] = order.Symbol
+	result["status"] = string(order.Status)
+	result["executedQty"], _ = strconv.ParseFloat(order.ExecutedQuantity, 64)
+	result["avgPrice"], _ = strconv.ParseFloat(order.AvgPrice, 64)
+	return result, nil
+}
+
+// invalidateCache 下单后清除余额和持仓缓存（之后的查询需要看到成交结果）
+func (t *FuturesTrader) invalidateCache() {
+	t.balanceCacheMutex.Lock()
+	t.cachedBalance = nil
+	t.balanceCacheMutex.Unlock()
+	t.positionsCacheMutex.Lock()
+	t.cachedPositions = nil
+	t.positionsCacheMutex.Unlock()
+}
+
+// CancelProtectiveOrders 只取消该币种的止损/止盈/移动止损单（positionSide为空时不限方向），保留未成交的限价开仓单
+func (t *FuturesTrader) CancelProtectiveOrders(symbol string, positionSide string) error {
+	orders, err := t.client.NewListOpenOrdersService().
+		Symbol(symbol).
+		Do(t.ctx)
+	if err != nil {
+		return fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	cancelled := 0
+	for _, order := range orders {
+		switch order.Type {
+		case futures.OrderTypeStopMarket, futures.OrderTypeTakeProfitMarket, futures.OrderTypeTrailingStopMarket:
+		default:
+			continue
+		}
+		if positionSide != "" && string(order.PositionSide) != positionSide {
+			continue
+		}
+		if err := t.CancelOrder(symbol, order.OrderID); err != nil {
+			return err
+		}
+		cancelled++
+	}
+
+	log.Printf("  ✓ 已取消 %s 的 %d 个止损止盈单", symbol, cancelled)
+	return nil
+}
+
+// cancelOrders 开平仓时清理止损止盈单：对冲模式只撤该方向，限价开仓单始终保留
+func (t *FuturesTrader) cancelOrders(symbol string, positionSide futures.PositionSideType) error {
+	if t.hedgeMode {
+		return t.CancelProtectiveOrders(symbol, string(positionSide))
+	}
+	return t.CancelProtectiveOrders(symbol, "")
+}
+
+// SetHedgeMode 切换账户持仓模式（true=双向持仓，允许同一币种同时持有多空仓）
+func (t *FuturesTrader) SetHedgeMode(enabled bool) error {
+	err := t.client.NewChangePositionModeService().
+		DualSide(enabled).
+		Do(t.ctx)
+
+	if err != nil && !contains(err.Error(), "No need to change") {
+		return fmt.Errorf("切换持仓模式失败（有持仓或挂单时无法切换）: %w", err)
+	}
+
+	t.hedgeMode = enabled
+	if enabled {
+		log.Printf("  ✓ 已启用双向持仓模式（对冲模式）")
+	} else {
+		log.Printf("  ✓ 已启用单向持仓模式")
+	}
+	return nil
+}
+
+// CancelOrder 取消指定挂单
+func (t *FuturesTrader) CancelOrder(symbol string, orderID int64) error {
+	_, err := t.client.NewCancelOrderService().
+		Symbol(symbol).
+		OrderID(orderID).
+		Do(t.ctx)
+
+	if err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+
+	log.Printf("  ✓ 已取消 %s 订单 %d", symbol, orderID)
+	return nil
+}
+
 // GetMarketPrice 获取市场价格
 func (t *FuturesTrader) GetMarketPrice(symbol string) (float64, error) {
-	prices, err := t.client.NewListPricesService().Symbol(symbol).Do(context.Background())
+	prices, err := t.client.NewListPricesService().Symbol(symbol).Do(t.ctx)
 	if err != nil {
 		return 0, fmt.Errorf("获取价格失败: %w", err)
 	}
@@ -456,22 +654,26 @@ func (t *FuturesTrader) SetStopLoss(symbol string, positionSide string, quantity
 		posSide = futures.PositionSideTypeShort
 	}
 
-	// 格式化数量
+	// 格式化数量和触发价格（按交易规则的步进值）
 	quantityStr, err := t.FormatQuantity(symbol, quantity)
 	if err != nil {
 		return err
 	}
+	priceStr, err := t.formatPrice(symbol, stopPrice)
+	if err != nil {
+		return err
+	}
 
 	_, err = t.client.NewCreateOrderService().
 		Symbol(symbol).
 		Side(side).
 		PositionSide(posSide).
 		Type(futures.OrderTypeStopMarket).
-		StopPrice(fmt.Sprintf("%.8f", stopPrice)).
+		StopPrice(priceStr).
 		Quantity(quantityStr).
 		WorkingType(futures.WorkingTypeContractPrice).
 		ClosePosition(true).
-		Do(context.Background())
+		Do(t.ctx)
 
 	if err != nil {
 		return fmt.Errorf("设置止损失败: %w", err)
@@ -494,22 +696,26 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 		posSide = futures.PositionSideTypeShort
 	}
 
-	// 格式化数量
+	// 格式化数量和触发价格（按交易规则的步进值）
 	quantityStr, err := t.FormatQuantity(symbol, quantity)
 	if err != nil {
 		return err
 	}
+	priceStr, err := t.formatPrice(symbol, takeProfitPrice)
+	if err != nil {
+		return err
+	}
 
 	_, err = t.client.NewCreateOrderService().
 		Symbol(symbol).
 		Side(side).
 		PositionSide(posSide).
 		Type(futures.OrderTypeTakeProfitMarket).
-		StopPrice(fmt.Sprintf("%.8f", takeProfitPrice)).
+		StopPrice(priceStr).
 		Quantity(quantityStr).
 		WorkingType(futures.WorkingTypeContractPrice).
 		ClosePosition(true).
-		Do(context.Background())
+		Do(t.ctx)
 
 	if err != nil {
 		return fmt.Errorf("设置止盈失败: %w", err)
@@ -519,84 +725,97 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 	return nil
 }
 
-// GetSymbolPrecision 获取交易对的数量精度
-func (t *FuturesTrader) GetSymbolPrecision(symbol string) (int, error) {
-	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
-	if err != nil {
-		return 0, fmt.Errorf("获取交易规则失败: %w", err)
+// SetTrailingStop 设置交易所原生移动止损单（价格到达activationPrice后按callbackRate%回调触发，市价平仓）
+func (t *FuturesTrader) SetTrailingStop(symbol string, positionSide string, quantity, activationPrice, callbackRate float64) error {
+	side := futures.SideTypeSell
+	posSide := futures.PositionSideTypeLong
+	if positionSide == "SHORT" {
+		side = futures.SideTypeBuy
+		posSide = futures.PositionSideTypeShort
 	}
 
-	for _, s := range exchangeInfo.Symbols {
-		if s.Symbol == symbol {
-			// 从LOT_SIZE filter获取精度
-			for _, filter := range s.Filters {
-				if filter["filterType"] == "LOT_SIZE" {
-					stepSize := filter["stepSize"].(string)
-					precision := calculatePrecision(stepSize)
-					log.Printf("  %s 数量精度: %d (stepSize: %s)", symbol, precision, stepSize)
-					return precision, nil
-				}
-			}
-		}
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return err
 	}
-
-	log.Printf("  ⚠ %s 未找到精度信息，使用默认精度3", symbol)
-	return 3, nil // 默认精度为3
-}
-
-// calculatePrecision 从stepSize计算精度
-func calculatePrecision(stepSize string) int {
-	// 去除尾部的0
-	stepSize = trimTrailingZeros(stepSize)
-
-	// 查找小数点
-	dotIndex := -1
-	for i := 0; i < len(stepSize); i++ {
-		if stepSize[i] == '.' {
-			dotIndex = i
-			break
+	service := t.client.NewCreateOrderService().
+		Symbol(symbol).
+		Side(side).
+		PositionSide(posSide).
+		Type(futures.OrderTypeTrailingStopMarket).
+		Quantity(quantityStr).
+		CallbackRate(strconv.FormatFloat(callbackRate, 'f', 1, 64)). // 币安回调比例步进为0.1%
+		WorkingType(futures.WorkingTypeContractPrice)
+	if activationPrice > 0 {
+		priceStr, err := t.formatPrice(symbol, activationPrice)
+		if err != nil {
+			return err
 		}
+		service = service.ActivationPrice(priceStr)
 	}
-
-	// 如果没有小数点或小数点在最后，精度为0
-	if dotIndex == -1 || dotIndex == len(stepSize)-1 {
-		return 0
+	if !t.hedgeMode {
+		service = service.ReduceOnly(true) // 对冲模式下由positionSide保证只减仓，不能传reduceOnly
 	}
-
-	// 返回小数点后的位数
-	return len(stepSize) - dotIndex - 1
-}
-
-// trimTrailingZeros 去除尾部的0
-func trimTrailingZeros(s string) string {
-	// 如果没有小数点，直接返回
-	if !stringContains(s, ".") {
-		return s
+	if _, err := service.Do(t.ctx); err != nil {
+		return fmt.Errorf("设置移动止损失败: %w", err)
 	}
 
-	// 从后向前遍历，去除尾部的0
-	for len(s) > 0 && s[len(s)-1] == '0' {
-		s = s[:len(s)-1]
-	}
+	log.Printf("  移动止损设置: 激活价 %.4f, 回调 %.1f%%", activationPrice, callbackRate)
+	return nil
+}
 
-	// 如果最后一位是小数点，也去掉
-	if len(s) > 0 && s[len(s)-1] == '.' {
-		s = s[:len(s)-1]
+// GetSymbolPrecision 获取交易对的数量精度
+func (t *FuturesTrader) GetSymbolPrecision(symbol string) (int, error) {
+	info, err := market.GetSymbolInfo(symbol)
+	if err != nil {
+		log.Printf("  ⚠ %s 未找到精度信息，使用默认精度3: %v", symbol, err)
+		return 3, nil // 默认精度为3
 	}
-
-	return s
+	return info.QuantityPrecision, nil
 }
 
-// FormatQuantity 格式化数量到正确的精度
+// FormatQuantity 按LOT_SIZE的stepSize格式化数量（向下取整，交易规则带缓存）
 func (t *FuturesTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
-	precision, err := t.GetSymbolPrecision(symbol)
+	info, err := market.GetSymbolInfo(symbol)
 	if err != nil {
 		// 如果获取失败，使用默认格式
 		return fmt.Sprintf("%.3f", quantity), nil
 	}
+	return info.FormatQuantity(quantity), nil
+}
+
+// formatPrice 按PRICE_FILTER的tickSize格式化价格
+func (t *FuturesTrader) formatPrice(symbol string, price float64) (string, error) {
+	info, err := market.GetSymbolInfo(symbol)
+	if err != nil {
+		return fmt.Sprintf("%.8f", price), nil
+	}
+	return info.FormatPrice(price), nil
+}
 
-	format := fmt.Sprintf("%%.%df", precision)
-	return fmt.Sprintf(format, quantity), nil
+// LoadLeverageBrackets 加载所有交易对的杠杆分层（需要API Key），供决策验证按名义价值检查杠杆上限
+func (t *FuturesTrader) LoadLeverageBrackets() error {
+	result, err := t.client.NewGetLeverageBracketService().Do(t.ctx)
+	if err != nil {
+		return fmt.Errorf("获取杠杆分层失败: %w", err)
+	}
+
+	symbolBrackets := make(map[string][]market.LeverageBracket, len(result))
+	for _, item := range result {
+		brackets := make([]market.LeverageBracket, 0, len(item.Brackets))
+		for _, b := range item.Brackets {
+			brackets = append(brackets, market.LeverageBracket{
+				NotionalCap:      b.NotionalCap,
+				MaxLeverage:      b.InitialLeverage,
+				MaintMarginRatio: b.MaintMarginRatio,
+			})
+		}
+		sort.Slice(brackets, func(i, j int) bool { return brackets[i].NotionalCap < brackets[j].NotionalCap })
+		symbolBrackets[item.Symbol] = brackets
+	}
+	market.SetLeverageBrackets(symbolBrackets)
+	log.Printf("  已加载 %d 个交易对的杠杆分层", len(symbolBrackets))
+	return nil
 }
 
 // 辅助函数
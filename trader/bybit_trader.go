@@ -0,0 +1,644 @@
+package trader
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"nofx/market"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Bybit接口参数
+const (
+	bybitRecvWindow = "5000" // 请求有效时间窗口（毫秒）
+
+	bybitCodeLeverageNotModified = 110043 // 杠杆未改变
+	bybitCodeModeNotModified     = 110025 // 持仓模式未改变
+)
+
+// BybitTrader Bybit USDT永续合约交易器（v5统一账户接口，HMAC签名）
+type BybitTrader struct {
+	apiKey    string
+	secretKey string
+	baseURL   string
+	client    *http.Client
+	ctx       context.Context // 请求使用的context（见SetContext）
+
+	// 双向持仓模式：下单时按方向指定positionIdx（1=多，2=空），单向模式为0
+	hedgeMode bool
+
+	// 订单ID：Bybit的orderId为UUID，下单时生成数字型orderLinkId作为统一接口的int64订单ID
+	idMu       sync.Mutex
+	lastLinkID int64
+}
+
+// NewBybitTrader 创建Bybit交易器（testnet为true时连接测试网，API Key需在testnet.bybit.com单独申请）
+func NewBybitTrader(apiKey, secretKey string, testnet bool) *BybitTrader {
+	host := market.BybitHost
+	if testnet {
+		host = market.BybitTestnetHost
+	}
+	return &BybitTrader{
+		apiKey:    apiKey,
+		secretKey: secretKey,
+		baseURL:   "https://" + host,
+		client:    &http.Client{Timeout: 15 * time.Second},
+		ctx:       context.Background(),
+	}
+}
+
+// SetContext 设置请求使用的context，取消后进行中的请求立即返回
+func (t *BybitTrader) SetContext(ctx context.Context) {
+	t.ctx = ctx
+}
+
+// request 发送签名请求并解析result字段：
+// 签名 = HMAC_SHA256(timestamp + apiKey + recvWindow + (GET为querystring，POST为JSON body))
+func (t *BybitTrader) request(method, path string, params map[string]interface{}, result interface{}) error {
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	var payload string
+	var body []byte
+	fullURL := t.baseURL + path
+	if method == http.MethodGet {
+		query := url.Values{}
+		for k, v := range params {
+			query.Set(k, fmt.Sprintf("%v", v))
+		}
+		payload = query.Encode()
+		if payload != "" {
+			fullURL += "?" + payload
+		}
+	} else {
+		var err error
+		body, err = json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		payload = string(body)
+	}
+
+	mac := hmac.New(sha256.New, []byte(t.secretKey))
+	mac.Write([]byte(timestamp + t.apiKey + bybitRecvWindow + payload))
+
+	req, err := http.NewRequestWithContext(t.ctx, method, fullURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-BAPI-API-KEY", t.apiKey)
+	req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
+	req.Header.Set("X-BAPI-RECV-WINDOW", bybitRecvWindow)
+	req.Header.Set("X-BAPI-SIGN", hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return market.DecodeBybitResponse(resp, result)
+}
+
+// bybitErrorCode Bybit业务错误码（非Bybit错误返回0）
+func bybitErrorCode(err error) int {
+	var bybitErr *market.BybitError
+	if errors.As(err, &bybitErr) {
+		return bybitErr.Code
+	}
+	return 0
+}
+
+// nextOrderID 生成递增的数字订单ID（毫秒时间戳×1000，重启后也不会重复）
+func (t *BybitTrader) nextOrderID() int64 {
+	t.idMu.Lock()
+	defer t.idMu.Unlock()
+	id := time.Now().UnixMilli() * 1000
+	if id <= t.lastLinkID {
+		id = t.lastLinkID + 1
+	}
+	t.lastLinkID = id
+	return id
+}
+
+// positionIdx 下单的持仓索引（单向持仓为0，双向持仓多仓1、空仓2）
+func (t *BybitTrader) positionIdx(positionSide string) int {
+	if !t.hedgeMode {
+		return 0
+	}
+	if positionSide == "SHORT" {
+		return 2
+	}
+	return 1
+}
+
+// GetBalance 获取统一账户余额（字段名与币安一致）
+func (t *BybitTrader) GetBalance() (map[string]interface{}, error) {
+	var result struct {
+		List []struct {
+			TotalWalletBalance    string `json:"totalWalletBalance"`
+			TotalAvailableBalance string `json:"totalAvailableBalance"`
+			TotalPerpUPL          string `json:"totalPerpUPL"`
+		} `json:"list"`
+	}
+	params := map[string]interface{}{"accountType": "UNIFIED"}
+	if err := t.request(http.MethodGet, "/v5/account/wallet-balance", params, &result); err != nil {
+		return nil, fmt.Errorf("获取账户信息失败: %w", err)
+	}
+	if len(result.List) == 0 {
+		return nil, fmt.Errorf("获取账户信息失败: 没有统一账户数据")
+	}
+	account := result.List[0]
+
+	balance := make(map[string]interface{})
+	balance["totalWalletBalance"], _ = strconv.ParseFloat(account.TotalWalletBalance, 64)
+	balance["availableBalance"], _ = strconv.ParseFloat(account.TotalAvailableBalance, 64)
+	balance["totalUnrealizedProfit"], _ = strconv.ParseFloat(account.TotalPerpUPL, 64)
+
+	log.Printf("✓ Bybit API返回: 总余额=%s, 可用=%s, 未实现盈亏=%s",
+		account.TotalWalletBalance, account.TotalAvailableBalance, account.TotalPerpUPL)
+	return balance, nil
+}
+
+// GetPositions 获取所有USDT永续合约持仓（空仓的positionAmt为负数，与币安一致）
+func (t *BybitTrader) GetPositions() ([]map[string]interface{}, error) {
+	var result struct {
+		List []struct {
+			Symbol        string `json:"symbol"`
+			Side          string `json:"side"`
+			Size          string `json:"size"`
+			AvgPrice      string `json:"avgPrice"`
+			MarkPrice     string `json:"markPrice"`
+			UnrealisedPnl string `json:"unrealisedPnl"`
+			Leverage      string `json:"leverage"`
+			LiqPrice      string `json:"liqPrice"`
+		} `json:"list"`
+	}
+	params := map[string]interface{}{"category": "linear", "settleCoin": "USDT", "limit": 200}
+	if err := t.request(http.MethodGet, "/v5/position/list", params, &result); err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	var positions []map[string]interface{}
+	for _, pos := range result.List {
+		size, _ := strconv.ParseFloat(pos.Size, 64)
+		if size == 0 {
+			continue // 跳过无持仓的
+		}
+
+		posMap := make(map[string]interface{})
+		posMap["symbol"] = pos.Symbol
+		if pos.Side == "Sell" {
+			posMap["side"] = "short"
+			posMap["positionAmt"] = -size
+		} else {
+			posMap["side"] = "long"
+			posMap["positionAmt"] = size
+		}
+		posMap["entryPrice"], _ = strconv.ParseFloat(pos.AvgPrice, 64)
+		posMap["markPrice"], _ = strconv.ParseFloat(pos.MarkPrice, 64)
+		posMap["unRealizedProfit"], _ = strconv.ParseFloat(pos.UnrealisedPnl, 64)
+		posMap["leverage"], _ = strconv.ParseFloat(pos.Leverage, 64)
+		posMap["liquidationPrice"], _ = strconv.ParseFloat(pos.LiqPrice, 64)
+		positions = append(positions, posMap)
+	}
+	return positions, nil
+}
+
+// SetLeverage 设置杠杆（多空相同；统一账户的保证金模式是账户级设置，不在下单时切换）
+func (t *BybitTrader) SetLeverage(symbol string, leverage int) error {
+	params := map[string]interface{}{
+		"category":     "linear",
+		"symbol":       symbol,
+		"buyLeverage":  strconv.Itoa(leverage),
+		"sellLeverage": strconv.Itoa(leverage),
+	}
+	err := t.request(http.MethodPost, "/v5/position/set-leverage", params, nil)
+	if bybitErrorCode(err) == bybitCodeLeverageNotModified {
+		log.Printf("  ✓ %s 杠杆已是 %dx", symbol, leverage)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("设置杠杆失败: %w", err)
+	}
+	log.Printf("  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
+	return nil
+}
+
+// SetHedgeMode 切换USDT永续合约的持仓模式（true=双向持仓）
+func (t *BybitTrader) SetHedgeMode(enabled bool) error {
+	mode := 0
+	if enabled {
+		mode = 3
+	}
+	params := map[string]interface{}{"category": "linear", "coin": "USDT", "mode": mode}
+	err := t.request(http.MethodPost, "/v5/position/switch-mode", params, nil)
+	if err != nil && bybitErrorCode(err) != bybitCodeModeNotModified {
+		return fmt.Errorf("切换持仓模式失败（有持仓或挂单时无法切换）: %w", err)
+	}
+
+	t.hedgeMode = enabled
+	if enabled {
+		log.Printf("  ✓ 已启用双向持仓模式（对冲模式）")
+	} else {
+		log.Printf("  ✓ 已启用单向持仓模式")
+	}
+	return nil
+}
+
+// placeOrder 下单（orderLinkId为生成的数字ID，作为统一接口返回的orderId）
+func (t *BybitTrader) placeOrder(params map[string]interface{}) (map[string]interface{}, error) {
+	orderID := t.nextOrderID()
+	params["category"] = "linear"
+	params["orderLinkId"] = strconv.FormatInt(orderID, 10)
+
+	var result struct {
+		OrderID string `json:"orderId"`
+	}
+	if err := t.request(http.MethodPost, "/v5/order/create", params, &result); err != nil {
+		return nil, err
+	}
+
+	order := make(map[string]interface{})
+	order["orderId"] = orderID
+	order["exchangeOrderId"] = result.OrderID
+	order["symbol"] = params["symbol"]
+	order["status"] = "NEW"
+	return order, nil
+}
+
+// OpenLong 开多仓
+func (t *BybitTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openMarket(symbol, "LONG", quantity, leverage)
+}
+
+// OpenShort 开空仓
+func (t *BybitTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openMarket(symbol, "SHORT", quantity, leverage)
+}
+
+// openMarket 市价开仓（先清理该方向旧的止损止盈单）
+func (t *BybitTrader) openMarket(symbol, positionSide string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if err := t.cancelOrders(symbol, positionSide); err != nil {
+		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	side := "Buy"
+	if positionSide == "SHORT" {
+		side = "Sell"
+	}
+	order, err := t.placeOrder(map[string]interface{}{
+		"symbol":      symbol,
+		"side":        side,
+		"orderType":   "Market",
+		"qty":         quantityStr,
+		"positionIdx": t.positionIdx(positionSide),
+	})
+	if err != nil {
+		if positionSide == "SHORT" {
+			return nil, fmt.Errorf("开空仓失败: %w", err)
+		}
+		return nil, fmt.Errorf("开多仓失败: %w", err)
+	}
+
+	if positionSide == "SHORT" {
+		log.Printf("✓ 开空仓成功: %s 数量: %s", symbol, quantityStr)
+	} else {
+		log.Printf("✓ 开多仓成功: %s 数量: %s", symbol, quantityStr)
+	}
+	log.Printf("  订单ID: %d", order["orderId"])
+	return order, nil
+}
+
+// PlaceLimitEntry 挂限价开仓单（GTC，成交后由调用方设置止损止盈）
+func (t *BybitTrader) PlaceLimitEntry(symbol string, positionSide string, quantity float64, leverage int, price float64) (map[string]interface{}, error) {
+	return t.placeLimitOrder(symbol, positionSide, quantity, leverage, price, "GTC")
+}
+
+// PlacePostOnlyEntry 挂只做maker的限价开仓单（PostOnly，会立即成交时交易所撤销订单）
+func (t *BybitTrader) PlacePostOnlyEntry(symbol string, positionSide string, quantity float64, leverage int, price float64) (map[string]interface{}, error) {
+	return t.placeLimitOrder(symbol, positionSide, quantity, leverage, price, "PostOnly")
+}
+
+// placeLimitOrder 挂限价开仓单
+func (t *BybitTrader) placeLimitOrder(symbol string, positionSide string, quantity float64, leverage int, price float64, timeInForce string) (map[string]interface{}, error) {
+	// 不取消已有委托：同币种反方向持仓的止损止盈单需要保留
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	priceStr, err := t.formatPrice(symbol, price)
+	if err != nil {
+		return nil, err
+	}
+
+	side := "Buy"
+	if positionSide == "SHORT" {
+		side = "Sell"
+	}
+	order, err := t.placeOrder(map[string]interface{}{
+		"symbol":      symbol,
+		"side":        side,
+		"orderType":   "Limit",
+		"qty":         quantityStr,
+		"price":       priceStr,
+		"timeInForce": timeInForce,
+		"positionIdx": t.positionIdx(positionSide),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("挂限价开仓单失败: %w", err)
+	}
+
+	log.Printf("✓ 限价开仓单已挂出: %s %s 数量: %s 价格: %s", symbol, positionSide, quantityStr, priceStr)
+	log.Printf("  订单ID: %d", order["orderId"])
+	return order, nil
+}
+
+// CloseLong 平多仓（quantity=0表示全部平仓）
+func (t *BybitTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closeMarket(symbol, "LONG", quantity)
+}
+
+// CloseShort 平空仓（quantity=0表示全部平仓）
+func (t *BybitTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closeMarket(symbol, "SHORT", quantity)
+}
+
+// closeMarket 市价只减仓平仓，平仓后取消该方向的止损止盈单
+func (t *BybitTrader) closeMarket(symbol, positionSide string, quantity float64) (map[string]interface{}, error) {
+	side, posSide := "Sell", "long"
+	if positionSide == "SHORT" {
+		side, posSide = "Buy", "short"
+	}
+
+	if quantity == 0 {
+		positions, err := t.GetPositions()
+		if err != nil {
+			return nil, err
+		}
+		for _, pos := range positions {
+			if pos["symbol"] == symbol && pos["side"] == posSide {
+				quantity = pos["positionAmt"].(float64)
+				if quantity < 0 {
+					quantity = -quantity
+				}
+				break
+			}
+		}
+		if quantity == 0 {
+			if posSide == "short" {
+				return nil, fmt.Errorf("没有找到 %s 的空仓", symbol)
+			}
+			return nil, fmt.Errorf("没有找到 %s 的多仓", symbol)
+		}
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	order, err := t.placeOrder(map[string]interface{}{
+		"symbol":      symbol,
+		"side":        side,
+		"orderType":   "Market",
+		"qty":         quantityStr,
+		"reduceOnly":  true,
+		"positionIdx": t.positionIdx(positionSide),
+	})
+	if err != nil {
+		if posSide == "short" {
+			return nil, fmt.Errorf("平空仓失败: %w", err)
+		}
+		return nil, fmt.Errorf("平多仓失败: %w", err)
+	}
+
+	if posSide == "short" {
+		log.Printf("✓ 平空仓成功: %s 数量: %s", symbol, quantityStr)
+	} else {
+		log.Printf("✓ 平多仓成功: %s 数量: %s", symbol, quantityStr)
+	}
+
+	if err := t.cancelOrders(symbol, positionSide); err != nil {
+		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	}
+	return order, nil
+}
+
+// GetMarketPrice 获取最新成交价
+func (t *BybitTrader) GetMarketPrice(symbol string) (float64, error) {
+	var result struct {
+		List []struct {
+			LastPrice string `json:"lastPrice"`
+		} `json:"list"`
+	}
+	params := map[string]interface{}{"category": "linear", "symbol": symbol}
+	if err := t.request(http.MethodGet, "/v5/market/tickers", params, &result); err != nil {
+		return 0, fmt.Errorf("获取价格失败: %w", err)
+	}
+	if len(result.List) == 0 {
+		return 0, fmt.Errorf("未找到价格")
+	}
+	return strconv.ParseFloat(result.List[0].LastPrice, 64)
+}
+
+// SetStopLoss 设置止损单（条件市价单，只减仓，按最新成交价触发）
+func (t *BybitTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	if err := t.placeTrigger(symbol, positionSide, quantity, stopPrice, true); err != nil {
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
+	log.Printf("  止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// SetTakeProfit 设置止盈单（条件市价单，只减仓，按最新成交价触发）
+func (t *BybitTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	if err := t.placeTrigger(symbol, positionSide, quantity, takeProfitPrice, false); err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
+	}
+	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
+
+// placeTrigger 下条件平仓单：多仓止损/空仓止盈在价格下跌时触发（triggerDirection=2），反之为上涨触发（1）
+func (t *BybitTrader) placeTrigger(symbol, positionSide string, quantity, triggerPrice float64, stopLoss bool) error {
+	side := "Sell"
+	if positionSide == "SHORT" {
+		side = "Buy"
+	}
+	direction := 1
+	if (positionSide == "LONG") == stopLoss {
+		direction = 2
+	}
+
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return err
+	}
+	priceStr, err := t.formatPrice(symbol, triggerPrice)
+	if err != nil {
+		return err
+	}
+	_, err = t.placeOrder(map[string]interface{}{
+		"symbol":           symbol,
+		"side":             side,
+		"orderType":        "Market",
+		"qty":              quantityStr,
+		"triggerPrice":     priceStr,
+		"triggerDirection": direction,
+		"triggerBy":        "LastPrice",
+		"reduceOnly":       true,
+		"closeOnTrigger":   true,
+		"positionIdx":      t.positionIdx(positionSide),
+	})
+	return err
+}
+
+// CancelAllOrders 取消该币种的所有挂单（含条件单）
+func (t *BybitTrader) CancelAllOrders(symbol string) error {
+	params := map[string]interface{}{"category": "linear", "symbol": symbol}
+	if err := t.request(http.MethodPost, "/v5/order/cancel-all", params, nil); err != nil {
+		return fmt.Errorf("取消挂单失败: %w", err)
+	}
+	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	return nil
+}
+
+// CancelOrder 取消指定挂单（orderID为下单时生成的orderLinkId）
+func (t *BybitTrader) CancelOrder(symbol string, orderID int64) error {
+	params := map[string]interface{}{
+		"category":    "linear",
+		"symbol":      symbol,
+		"orderLinkId": strconv.FormatInt(orderID, 10),
+	}
+	if err := t.request(http.MethodPost, "/v5/order/cancel", params, nil); err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+	log.Printf("  ✓ 已取消 %s 订单 %d", symbol, orderID)
+	return nil
+}
+
+// GetOrderStatus 查询订单状态（orderID为下单时生成的orderLinkId，状态换算为币安的写法）。
+// 实时订单接口查不到时（已结束较久的订单）查询历史订单
+func (t *BybitTrader) GetOrderStatus(symbol string, orderID int64) (map[string]interface{}, error) {
+	var result struct {
+		List []struct {
+			OrderStatus string `json:"orderStatus"`
+			CumExecQty  string `json:"cumExecQty"`
+			AvgPrice    string `json:"avgPrice"`
+		} `json:"list"`
+	}
+	params := map[string]interface{}{"category": "linear", "symbol": symbol, "orderLinkId": strconv.FormatInt(orderID, 10)}
+	for _, path := range []string{"/v5/order/realtime", "/v5/order/history"} {
+		if err := t.request(http.MethodGet, path, params, &result); err != nil {
+			return nil, fmt.Errorf("查询订单失败: %w", err)
+		}
+		if len(result.List) > 0 {
+			break
+		}
+	}
+	if len(result.List) == 0 {
+		return nil, fmt.Errorf("未找到订单 %d", orderID)
+	}
+
+	order := result.List[0]
+	status := "NEW"
+	switch order.OrderStatus {
+	case "PartiallyFilled":
+		status = "PARTIALLY_FILLED"
+	case "Filled":
+		status = "FILLED"
+	case "Cancelled", "PartiallyFilledCanceled", "Deactivated":
+		status = "CANCELED"
+	case "Rejected":
+		status = "REJECTED"
+	}
+	filled, _ := strconv.ParseFloat(order.CumExecQty, 64)
+	avgPrice, _ := strconv.ParseFloat(order.AvgPrice, 64)
+	return map[string]interface{}{
+		"orderId":     orderID,
+		"symbol":      symbol,
+		"status":      status,
+		"executedQty": filled,
+		"avgPrice":    avgPrice,
+	}, nil
+}
+
+// CancelProtectiveOrders 只取消该币种的止损止盈单（只减仓的条件单，positionSide为空时不限方向），保留限价开仓单
+func (t *BybitTrader) CancelProtectiveOrders(symbol string, positionSide string) error {
+	var result struct {
+		List []struct {
+			OrderID      string `json:"orderId"`
+			TriggerPrice string `json:"triggerPrice"`
+			ReduceOnly   bool   `json:"reduceOnly"`
+			PositionIdx  int    `json:"positionIdx"`
+		} `json:"list"`
+	}
+	params := map[string]interface{}{"category": "linear", "symbol": symbol, "limit": 50}
+	if err := t.request(http.MethodGet, "/v5/order/realtime", params, &result); err != nil {
+		return fmt.Errorf("获取挂单失败: %w", err)
+	}
+
+	cancelled := 0
+	for _, order := range result.List {
+		trigger, _ := strconv.ParseFloat(order.TriggerPrice, 64)
+		if trigger == 0 || !order.ReduceOnly {
+			continue
+		}
+		if positionSide != "" && t.hedgeMode && order.PositionIdx != t.positionIdx(positionSide) {
+			continue
+		}
+		params := map[string]interface{}{"category": "linear", "symbol": symbol, "orderId": order.OrderID}
+		if err := t.request(http.MethodPost, "/v5/order/cancel", params, nil); err != nil {
+			return fmt.Errorf("取消订单失败: %w", err)
+		}
+		cancelled++
+	}
+
+	log.Printf("  ✓ 已取消 %s 的 %d 个止损止盈单", symbol, cancelled)
+	return nil
+}
+
+// cancelOrders 开平仓时清理止损止盈单：对冲模式只撤该方向，限价开仓单始终保留
+func (t *BybitTrader) cancelOrders(symbol, positionSide string) error {
+	if t.hedgeMode {
+		return t.CancelProtectiveOrders(symbol, positionSide)
+	}
+	return t.CancelProtectiveOrders(symbol, "")
+}
+
+// FormatQuantity 按Bybit的qtyStep格式化数量（交易规则来自Bybit，与行情数据源无关）
+func (t *BybitTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	info, err := market.GetSymbolInfoFrom(market.SourceBybit, symbol)
+	if err != nil {
+		return fmt.Sprintf("%.3f", quantity), nil
+	}
+	return info.FormatQuantity(quantity), nil
+}
+
+// formatPrice 按Bybit的tickSize格式化价格
+func (t *BybitTrader) formatPrice(symbol string, price float64) (string, error) {
+	info, err := market.GetSymbolInfoFrom(market.SourceBybit, symbol)
+	if err != nil {
+		return fmt.Sprintf("%.8f", price), nil
+	}
+	return info.FormatPrice(price), nil
+}
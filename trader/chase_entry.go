@@ -0,0 +1,180 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/market"
+	"strings"
+	"time"
+)
+
+// 追价开仓默认参数
+const (
+	defaultChaseReprices        = 3                // 首次挂单后最多改价次数
+	defaultChaseRepriceInterval = 10 * time.Second // 每次挂单等待成交的时间
+	executionDeadlineFraction   = 0.5              // 开仓执行中的等待（追价、拆单）最晚到周期开始后扫描间隔的一半
+)
+
+// EntryExecutionOptions 开仓执行参数
+type EntryExecutionOptions struct {
+	Chase           bool          // 市价开仓改为只做maker的限价单追价（交易器不支持时直接市价）
+	MaxReprices     int           // 首次挂单后最多改价次数（0使用默认值3）
+	RepriceInterval time.Duration // 每次挂单等待成交的时间（0使用默认值10秒）
+
+	MaxVolumeFraction float64       // 单笔不超过平均1分钟成交额的比例（0表示不按成交额拆单）
+	MaxDepthFraction  float64       // 单笔不超过吃单一侧±0.5%深度的比例（0表示不按深度拆单）
+	SliceInterval     time.Duration // 拆单的间隔（0使用默认值30秒）
+	MaxSlices         int           // 最多拆分笔数（0使用默认值5）
+}
+
+// postOnlyPlacer 支持只做maker限价单的交易器（币安、Bybit、OKX、Hyperliquid）：
+// 会立即成交的订单被交易所拒绝或撤销，保证按maker费率成交
+type postOnlyPlacer interface {
+	PlacePostOnlyEntry(symbol string, positionSide string, quantity float64, leverage int, price float64) (map[string]interface{}, error)
+}
+
+// executionWait 开仓执行中的等待：trader停止或超过本周期执行截止时间时立即返回false。
+// 等待期间照常维护移动止损、限价开仓单和止损止盈联动（与主循环在同一goroutine，否则会停顿到执行结束）
+func (at *AutoTrader) executionWait(d time.Duration) bool {
+	if !at.executionDeadline.IsZero() && time.Now().Add(d).After(at.executionDeadline) {
+		log.Printf("  ⏱ 已到本周期开仓执行截止时间，不再等待")
+		return false
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	maintenance := time.NewTicker(trailingStopCheckInterval)
+	defer maintenance.Stop()
+	for {
+		select {
+		case <-at.runCtx.Done():
+			return false
+		case <-timer.C:
+			return true
+		case <-maintenance.C:
+			at.maintainPositions()
+		}
+	}
+}
+
+// openPosition 开仓：启用chase时先在盘口挂只做maker的限价单追价，否则直接市价成交（positionBefore为下单前的持仓数量，分批开仓时不为0）
+func (at *AutoTrader) openPosition(symbol, side string, quantity float64, leverage int, positionBefore float64) (map[string]interface{}, error) {
+	if pp, ok := at.trader.(postOnlyPlacer); ok && at.config.EntryExecution.Chase {
+		return at.chaseEntry(pp, symbol, side, quantity, leverage, positionBefore)
+	}
+	return at.openMarket(symbol, side, quantity, leverage)
+}
+
+// openMarket 市价开仓
+func (at *AutoTrader) openMarket(symbol, side string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if side == "short" {
+		return at.trader.OpenShort(symbol, quantity, leverage)
+	}
+	return at.trader.OpenLong(symbol, quantity, leverage)
+}
+
+// chaseEntry 追价开仓：在买一（多）/卖一（空）挂只做maker的限价单，等待后撤销未成交部分并按最新盘口重新挂单，
+// 改价次数用完后剩余部分市价成交。返回合并后的成交结果（币安订单格式，含executedQty和avgPrice）
+func (at *AutoTrader) chaseEntry(pp postOnlyPlacer, symbol, side string, quantity float64, leverage int, positionBefore float64) (map[string]interface{}, error) {
+	reprices := at.config.EntryExecution.MaxReprices
+	if reprices <= 0 {
+		reprices = defaultChaseReprices
+	}
+	interval := at.config.EntryExecution.RepriceInterval
+	if interval <= 0 {
+		interval = defaultChaseRepriceInterval
+	}
+
+	var lastOrderID int64
+	filled, notional := 0.0, 0.0
+	for attempt, waited := 0, true; attempt <= reprices && waited; attempt++ {
+		remaining := quantity - filled
+		if remaining <= quantity*(1-orderFillTolerance) {
+			break
+		}
+		depth, err := market.GetDepthContext(at.runCtx, symbol)
+		if err != nil {
+			log.Printf("  ⚠ 获取 %s 盘口失败，停止追价: %v", symbol, err)
+			break
+		}
+		price := depth.BestBid
+		if side == "short" {
+			price = depth.BestAsk
+		}
+
+		order, err := pp.PlacePostOnlyEntry(symbol, strings.ToUpper(side), remaining, leverage, price)
+		if err != nil {
+			log.Printf("  ⚠ 只做maker挂单失败 (%d/%d): %v", attempt+1, reprices+1, err)
+			continue
+		}
+		o := newTrackedOrder(symbol, side, remaining, order)
+		lastOrderID = o.OrderID
+		if !o.terminal() {
+			waited = at.executionWait(interval)
+			if o.OrderID != 0 {
+				// 订单可能已经全部成交，撤单失败不影响后续的成交确认
+				if err := at.trader.CancelOrder(symbol, o.OrderID); err != nil {
+					log.Printf("  ⚠ 撤销追价挂单 %d 失败: %v", o.OrderID, err)
+				}
+			}
+		}
+		at.awaitMarketFill(o, positionBefore+filled)
+		if o.Filled > 0 {
+			fillPrice := o.AvgPrice
+			if fillPrice <= 0 {
+				fillPrice = price
+			}
+			filled += o.Filled
+			notional += o.Filled * fillPrice
+		}
+		log.Printf("  🎯 追价挂单 %d/%d @ %.6g: %s", attempt+1, reprices+1, price, o.fillSummary())
+	}
+
+	makerFilled := filled
+	if at.runCtx.Err() != nil {
+		// trader停止中，剩余部分不再市价开仓
+		if filled == 0 {
+			return nil, fmt.Errorf("trader已停止，追价开仓未成交")
+		}
+		log.Printf("  ⏹ trader停止，保留已成交的 %.4f，剩余部分不再开仓", filled)
+	} else if remaining := quantity - filled; remaining > quantity*(1-orderFillTolerance) {
+		log.Printf("  ⏩ 追价结束，剩余 %.4f 市价成交", remaining)
+		order, err := at.openMarket(symbol, side, remaining, leverage)
+		if err != nil {
+			if filled == 0 {
+				return nil, err
+			}
+			log.Printf("  ⚠ 剩余部分市价开仓失败，保留已成交的 %.4f: %v", filled, err)
+		} else {
+			o := newTrackedOrder(symbol, side, remaining, order)
+			at.awaitMarketFill(o, positionBefore+filled)
+			lastOrderID = o.OrderID
+			if o.Filled > 0 {
+				fillPrice := o.AvgPrice
+				if fillPrice <= 0 {
+					fillPrice, _ = at.trader.GetMarketPrice(symbol)
+				}
+				filled += o.Filled
+				notional += o.Filled * fillPrice
+			}
+		}
+	}
+	log.Printf("  🎯 追价开仓: maker成交 %.4f, 市价成交 %.4f / %.4f", makerFilled, filled-makerFilled, quantity)
+
+	result := map[string]interface{}{
+		"orderId":     lastOrderID,
+		"symbol":      symbol,
+		"executedQty": filled,
+	}
+	switch {
+	case filled >= quantity*orderFillTolerance:
+		result["status"] = "FILLED"
+	case filled > 0:
+		result["status"] = "CANCELED"
+	default:
+		result["status"] = "EXPIRED"
+	}
+	if filled > 0 {
+		result["avgPrice"] = notional / filled
+	}
+	return result, nil
+}
@@ -0,0 +1,57 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxContextSnapshots 保留的上下文快照数量（超出后删除最旧的）
+const maxContextSnapshots = 200
+
+// contextSnapshotDir 上下文快照目录（放在子目录中，避免被决策日志读取）
+func contextSnapshotDir(logDir string) string {
+	return filepath.Join(logDir, "snapshots")
+}
+
+// saveContextSnapshot 保存本周期AI看到的完整上下文，返回文件路径（失败时返回空字符串）
+func (at *AutoTrader) saveContextSnapshot(ctx *decision.Context) string {
+	path := filepath.Join(at.snapshotDir, fmt.Sprintf("context_%s.json", time.Now().Format("20060102_150405")))
+	if err := ctx.Save(path); err != nil {
+		log.Printf("⚠ [%s] 保存上下文快照失败: %v", at.name, err)
+		return ""
+	}
+	at.pruneContextSnapshots()
+	return path
+}
+
+// pruneContextSnapshots 删除超出保留数量的旧快照
+func (at *AutoTrader) pruneContextSnapshots() {
+	entries, err := os.ReadDir(at.snapshotDir)
+	if err != nil {
+		return
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), "context_") && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) <= maxContextSnapshots {
+		return
+	}
+
+	// 文件名包含时间戳，按名称排序即按时间排序
+	sort.Strings(names)
+	for _, name := range names[:len(names)-maxContextSnapshots] {
+		if err := os.Remove(filepath.Join(at.snapshotDir, name)); err != nil {
+			log.Printf("⚠ [%s] 删除旧上下文快照失败: %v", at.name, err)
+		}
+	}
+}
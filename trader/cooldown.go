@@ -0,0 +1,26 @@
+package trader
+
+import "time"
+
+// closeHistoryRetention 平仓记录保留时长（远大于任何合理的冷却时间）
+const closeHistoryRetention = 24 * time.Hour
+
+// recordClose 记录币种平仓时间并保存（用于重新开仓冷却）
+func (at *AutoTrader) recordClose(symbol string) {
+	at.lastCloseTimes[symbol] = time.Now().UnixMilli()
+	at.savePositionState()
+}
+
+// recentCloses 最近的平仓记录（同时清理过期记录）
+func (at *AutoTrader) recentCloses() map[string]int64 {
+	cutoff := time.Now().Add(-closeHistoryRetention).UnixMilli()
+	closes := make(map[string]int64, len(at.lastCloseTimes))
+	for symbol, t := range at.lastCloseTimes {
+		if t < cutoff {
+			delete(at.lastCloseTimes, symbol)
+			continue
+		}
+		closes[symbol] = t
+	}
+	return closes
+}
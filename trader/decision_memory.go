@@ -0,0 +1,75 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDecisionMemory  = 10 // 默认展示最近10条决策
+	decisionMemoryLookback = 50 // 最多回溯50个周期查找已执行的决策
+)
+
+// buildDecisionMemory 从决策日志中提取最近执行的决策及其实际盈亏，供AI回顾
+func (at *AutoTrader) buildDecisionMemory(performance *logger.PerformanceAnalysis) []decision.DecisionMemory {
+	limit := at.config.DecisionMemory
+	if limit < 0 {
+		return nil
+	}
+	if limit == 0 {
+		limit = defaultDecisionMemory
+	}
+
+	records, err := at.decisionLogger.GetLatestRecords(decisionMemoryLookback)
+	if err != nil {
+		log.Printf("⚠️  读取历史决策失败: %v", err)
+		return nil
+	}
+
+	// 平仓动作的实际盈亏（按 symbol_side_平仓时间 匹配）
+	realizedPnL := make(map[string]float64)
+	if performance != nil {
+		for _, trade := range performance.RecentTrades {
+			realizedPnL[tradeKey(trade.Symbol, trade.Side, trade.CloseTime)] += trade.PnL
+		}
+	}
+
+	var memory []decision.DecisionMemory
+	for _, record := range records {
+		for _, action := range record.Decisions {
+			if action.Action == "hold" || action.Action == "wait" {
+				continue
+			}
+
+			m := decision.DecisionMemory{
+				Time:      action.Timestamp.Format("01-02 15:04"),
+				Symbol:    action.Symbol,
+				Action:    action.Action,
+				Price:     action.Price,
+				Reasoning: action.Reasoning,
+				Error:     action.Error,
+			}
+			if strings.HasPrefix(action.Action, "close_") || strings.HasPrefix(action.Action, "reduce_") {
+				side := action.Action[strings.LastIndex(action.Action, "_")+1:]
+				if pnl, exists := realizedPnL[tradeKey(action.Symbol, side, action.Timestamp)]; exists {
+					m.RealizedPnL = &pnl
+				}
+			}
+			memory = append(memory, m)
+		}
+	}
+
+	if len(memory) > limit {
+		memory = memory[len(memory)-limit:]
+	}
+	return memory
+}
+
+// tradeKey 平仓记录的匹配key
+func tradeKey(symbol, side string, closeTime time.Time) string {
+	return fmt.Sprintf("%s_%s_%d", symbol, side, closeTime.UnixNano())
+}
@@ -0,0 +1,28 @@
+package trader
+
+import (
+	"context"
+	"time"
+)
+
+// exchangeStopGrace Stop后交易所请求继续可用的时间：正在执行的开仓还能挂上止损止盈（或市价平仓），
+// 之后中止仍未返回的请求，卡住的交易所接口不会拖住停止
+const exchangeStopGrace = 15 * time.Second
+
+// contextBinder 请求支持context的交易器（币安、Bybit、OKX、Hyperliquid、Aster）
+type contextBinder interface {
+	// SetContext 设置请求使用的context，取消后进行中的请求立即返回
+	SetContext(ctx context.Context)
+}
+
+// sleepContext 等待d，ctx取消时提前返回false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
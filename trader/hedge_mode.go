@@ -0,0 +1,55 @@
+package trader
+
+import (
+	"log"
+	"strings"
+)
+
+// hedgeModeTrader 支持双向持仓模式的交易器（币安、Bybit、OKX）
+type hedgeModeTrader interface {
+	// SetHedgeMode 切换账户持仓模式（true=双向持仓）
+	SetHedgeMode(enabled bool) error
+}
+
+// protectiveOrderCanceller 能只撤销止损止盈单的交易器（币安、Bybit、OKX、Hyperliquid），未实现的交易器撤单时会一并撤掉限价开仓单
+type protectiveOrderCanceller interface {
+	// CancelProtectiveOrders 只取消该币种的止损止盈单（positionSide为LONG/SHORT，空表示不限方向）
+	CancelProtectiveOrders(symbol string, positionSide string) error
+}
+
+// cancelProtectiveOrders 重新设置止损止盈前撤销旧挂单：
+// 双向持仓模式下只撤该方向，避免误撤另一方向持仓的止损止盈单
+func (at *AutoTrader) cancelProtectiveOrders(symbol, side string) error {
+	if pc, ok := at.trader.(protectiveOrderCanceller); ok {
+		positionSide := ""
+		if at.config.HedgeMode {
+			positionSide = strings.ToUpper(side)
+		}
+		return pc.CancelProtectiveOrders(symbol, positionSide)
+	}
+	if err := at.trader.CancelAllOrders(symbol); err != nil {
+		return err
+	}
+	at.forgetCancelledEntries(symbol)
+	return nil
+}
+
+// forgetCancelledEntries 交易器开平仓或撤单时撤销了该币种所有挂单（不支持只撤止损止盈单），
+// 同步移除已被撤掉的限价开仓单，避免继续跟踪不存在的订单
+func (at *AutoTrader) forgetCancelledEntries(symbol string) {
+	if _, ok := at.trader.(protectiveOrderCanceller); ok {
+		return
+	}
+	removed := false
+	for posKey, entry := range at.pendingEntries {
+		if entry.Symbol != symbol {
+			continue
+		}
+		log.Printf("  ⚠ %s %s 限价开仓单 @ %.4f 已随该币种所有挂单一起被撤销", entry.Symbol, entry.Side, entry.EntryPrice)
+		delete(at.pendingEntries, posKey)
+		removed = true
+	}
+	if removed {
+		at.savePositionState()
+	}
+}
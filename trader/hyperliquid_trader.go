@@ -1,28 +1,47 @@
 package trader
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
+	"net/http"
+	"nofx/market"
+	"nofx/proxy"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sonirico/go-hyperliquid"
 )
 
-// HyperliquidTrader Hyperliquid交易器
+// Hyperliquid下单规则
+const (
+	hyperliquidSlippage    = 0.01 // 市价单用IOC限价单模拟，价格偏离中间价1%保证成交
+	hyperliquidMinNotional = 10.0 // 最小下单金额（USDC）
+	hyperliquidSigFigs     = 5    // 价格最多5位有效数字
+	hyperliquidMaxDecimals = 6    // 永续合约价格小数位数上限为 6 - szDecimals
+)
+
+// HyperliquidTrader Hyperliquid交易器（单向持仓，USDC保证金）。
+// 统一接口中的币种沿用行情数据源的写法（BTCUSDT、1000PEPEUSDT），在这里换算为Hyperliquid的币种名（BTC、kPEPE）
 type HyperliquidTrader struct {
 	exchange   *hyperliquid.Exchange
 	ctx        context.Context
 	walletAddr string
+	apiURL     string
+	httpClient *http.Client
 	meta       *hyperliquid.Meta // 缓存meta信息（包含精度等）
 }
 
 // NewHyperliquidTrader 创建Hyperliquid交易器
+// walletAddr为空时使用私钥对应的地址；使用API钱包（agent）时需填写主账户地址
 func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool) (*HyperliquidTrader, error) {
-	// 解析私钥
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	// 解析私钥（兼容带0x前缀的写法）
+	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(privateKeyHex, "0x"))
 	if err != nil {
 		return nil, fmt.Errorf("解析私钥失败: %w", err)
 	}
@@ -33,16 +52,16 @@ func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool)
 		apiURL = hyperliquid.TestnetAPIURL
 	}
 
-	// // 从私钥生成钱包地址
-	// pubKey := privateKey.Public()
-	// publicKeyECDSA, ok := pubKey.(*ecdsa.PublicKey)
-	// if !ok {
-	// 	return nil, fmt.Errorf("无法转换公钥")
-	// }
-	// walletAddr := crypto.PubkeyToAddress(*publicKeyECDSA).Hex()
+	// 未配置钱包地址时从私钥生成
+	if walletAddr == "" {
+		walletAddr = crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+	}
 
 	ctx := context.Background()
 
+	// SDK使用默认Transport，按API主机选择交易所代理
+	proxy.Register(proxy.KindExchange, apiURL)
+
 	// 创建Exchange客户端（Exchange包含Info功能）
 	exchange := hyperliquid.NewExchange(
 		ctx,
@@ -54,26 +73,31 @@ func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool)
 		nil,        // SpotMeta will be fetched automatically
 	)
 
-	log.Printf("✓ Hyperliquid交易器初始化成功 (testnet=%v, wallet=%s)", testnet, walletAddr)
-
 	// 获取meta信息（包含精度等配置）
 	meta, err := exchange.Info().Meta(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("获取meta信息失败: %w", err)
 	}
 
+	log.Printf("✓ Hyperliquid交易器初始化成功 (testnet=%v, wallet=%s, %d个永续合约)", testnet, walletAddr, len(meta.Universe))
+
 	return &HyperliquidTrader{
 		exchange:   exchange,
 		ctx:        ctx,
 		walletAddr: walletAddr,
+		apiURL:     apiURL,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
 		meta:       meta,
 	}, nil
 }
 
+// SetContext 设置请求使用的context，取消后进行中的请求立即返回
+func (t *HyperliquidTrader) SetContext(ctx context.Context) {
+	t.ctx = ctx
+}
+
 // GetBalance 获取账户余额
 func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
-	log.Printf("🔄 正在调用Hyperliquid API获取账户余额...")
-
 	// 获取账户状态
 	accountState, err := t.exchange.Info().UserState(t.ctx, t.walletAddr)
 	if err != nil {
@@ -81,32 +105,22 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 		return nil, fmt.Errorf("获取账户信息失败: %w", err)
 	}
 
-	// 解析余额信息（MarginSummary字段都是string）
-	result := make(map[string]interface{})
-
-	// 🔍 调试：打印API返回的完整CrossMarginSummary结构
-	summaryJSON, _ := json.MarshalIndent(accountState.MarginSummary, "  ", "  ")
-	log.Printf("🔍 [DEBUG] Hyperliquid API CrossMarginSummary完整数据:")
-	log.Printf("%s", string(summaryJSON))
-
 	accountValue, _ := strconv.ParseFloat(accountState.MarginSummary.AccountValue, 64)
 	totalMarginUsed, _ := strconv.ParseFloat(accountState.MarginSummary.TotalMarginUsed, 64)
 
-	// ⚠️ 关键修复：从所有持仓中累加真正的未实现盈亏
+	// 从所有持仓中累加未实现盈亏
 	totalUnrealizedPnl := 0.0
 	for _, assetPos := range accountState.AssetPositions {
 		unrealizedPnl, _ := strconv.ParseFloat(assetPos.Position.UnrealizedPnl, 64)
 		totalUnrealizedPnl += unrealizedPnl
 	}
 
-	// ✅ 正确理解Hyperliquid字段：
 	// AccountValue = 总账户净值（已包含空闲资金+持仓价值+未实现盈亏）
-	// TotalMarginUsed = 持仓占用的保证金（已包含在AccountValue中，仅用于显示）
-	//
-	// 为了兼容auto_trader.go的计算逻辑（totalEquity = totalWalletBalance + totalUnrealizedProfit）
-	// 需要返回"不包含未实现盈亏的钱包余额"
+	// TotalMarginUsed = 持仓占用的保证金（已包含在AccountValue中）
+	// auto_trader按 totalEquity = totalWalletBalance + totalUnrealizedProfit 计算，需要返回不含未实现盈亏的钱包余额
 	walletBalanceWithoutUnrealized := accountValue - totalUnrealizedPnl
 
+	result := make(map[string]interface{})
 	result["totalWalletBalance"] = walletBalanceWithoutUnrealized // 钱包余额（不含未实现盈亏）
 	result["availableBalance"] = accountValue - totalMarginUsed   // 可用余额（总净值 - 占用保证金）
 	result["totalUnrealizedProfit"] = totalUnrealizedPnl          // 未实现盈亏
@@ -121,7 +135,7 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 	return result, nil
 }
 
-// GetPositions 获取所有持仓
+// GetPositions 获取所有持仓（币种和数量按行情数据源的写法换算，如kPEPE -> 1000PEPEUSDT）
 func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 	// 获取账户状态
 	accountState, err := t.exchange.Info().UserState(t.ctx, t.walletAddr)
@@ -142,20 +156,19 @@ func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 			continue // 跳过无持仓的
 		}
 
-		posMap := make(map[string]interface{})
+		symbol := market.Canonical(position.Coin)
+		_, multiplier := t.coinOf(symbol)
 
-		// 标准化symbol格式（Hyperliquid使用如"BTC"，我们转换为"BTCUSDT"）
-		symbol := position.Coin + "USDT"
+		posMap := make(map[string]interface{})
 		posMap["symbol"] = symbol
 
 		// 持仓数量和方向
 		if posAmt > 0 {
 			posMap["side"] = "long"
-			posMap["positionAmt"] = posAmt
 		} else {
 			posMap["side"] = "short"
-			posMap["positionAmt"] = -posAmt // 转为正数
 		}
+		posMap["positionAmt"] = math.Abs(posAmt) / multiplier
 
 		// 价格信息（EntryPx和LiquidationPx是指针类型）
 		var entryPrice, liquidationPx float64
@@ -169,17 +182,14 @@ func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 		positionValue, _ := strconv.ParseFloat(position.PositionValue, 64)
 		unrealizedPnl, _ := strconv.ParseFloat(position.UnrealizedPnl, 64)
 
-		// 计算mark price（positionValue / abs(posAmt)）
-		var markPrice float64
-		if posAmt != 0 {
-			markPrice = positionValue / absFloat(posAmt)
-		}
+		// 标记价格 = 持仓价值 / 持仓数量
+		markPrice := positionValue / math.Abs(posAmt)
 
-		posMap["entryPrice"] = entryPrice
-		posMap["markPrice"] = markPrice
+		posMap["entryPrice"] = entryPrice * multiplier
+		posMap["markPrice"] = markPrice * multiplier
 		posMap["unRealizedProfit"] = unrealizedPnl
 		posMap["leverage"] = float64(position.Leverage.Value)
-		posMap["liquidationPrice"] = liquidationPx
+		posMap["liquidationPrice"] = liquidationPx * multiplier
 
 		result = append(result, posMap)
 	}
@@ -187,10 +197,9 @@ func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 	return result, nil
 }
 
-// SetLeverage 设置杠杆
+// SetLeverage 设置杠杆（逐仓）
 func (t *HyperliquidTrader) SetLeverage(symbol string, leverage int) error {
-	// Hyperliquid symbol格式（去掉USDT后缀）
-	coin := convertSymbolToHyperliquid(symbol)
+	coin, _ := t.coinOf(symbol)
 
 	// 调用UpdateLeverage (leverage int, name string, isCross bool)
 	_, err := t.exchange.UpdateLeverage(t.ctx, leverage, coin, false) // false = 逐仓模式
@@ -204,291 +213,316 @@ func (t *HyperliquidTrader) SetLeverage(symbol string, leverage int) error {
 
 // OpenLong 开多仓
 func (t *HyperliquidTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
-	// 先取消该币种的所有委托单
-	if err := t.CancelAllOrders(symbol); err != nil {
-		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
+	order, err := t.marketOrder(symbol, true, quantity, leverage, false)
+	if err != nil {
+		return nil, fmt.Errorf("开多仓失败: %w", err)
 	}
+	log.Printf("✓ 开多仓成功: %s 数量: %v 均价: %v", symbol, order["executedQty"], order["avgPrice"])
+	return order, nil
+}
 
-	// 设置杠杆
-	if err := t.SetLeverage(symbol, leverage); err != nil {
-		return nil, err
+// OpenShort 开空仓
+func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	order, err := t.marketOrder(symbol, false, quantity, leverage, false)
+	if err != nil {
+		return nil, fmt.Errorf("开空仓失败: %w", err)
 	}
+	log.Printf("✓ 开空仓成功: %s 数量: %v 均价: %v", symbol, order["executedQty"], order["avgPrice"])
+	return order, nil
+}
 
-	// Hyperliquid symbol格式
-	coin := convertSymbolToHyperliquid(symbol)
+// marketOrder 市价单（IOC限价单，价格偏离中间价hyperliquidSlippage）：
+// 开仓前清理旧的止损止盈单并设置杠杆，平仓为只减仓单；未成交时返回交易所给出的原因
+func (t *HyperliquidTrader) marketOrder(symbol string, isBuy bool, quantity float64, leverage int, reduceOnly bool) (map[string]interface{}, error) {
+	coin, multiplier := t.coinOf(symbol)
+	if !reduceOnly {
+		if err := t.CancelProtectiveOrders(symbol, ""); err != nil {
+			log.Printf("  ⚠ 取消旧委托单失败: %v", err)
+		}
+		if err := t.SetLeverage(symbol, leverage); err != nil {
+			return nil, err
+		}
+	}
 
-	// 获取当前价格（用于市价单）
 	price, err := t.GetMarketPrice(symbol)
 	if err != nil {
 		return nil, err
 	}
+	mid := price / multiplier
 
-	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
-	log.Printf("  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(coin))
+	// 开仓数量向下取整（避免超出保证金），平仓四舍五入（持仓数量本身就是szDecimals精度）
+	size := t.roundSize(coin, quantity*multiplier, !reduceOnly)
+	if size <= 0 {
+		return nil, fmt.Errorf("%s 数量 %.8f 按精度取整后为0", symbol, quantity)
+	}
+	if !reduceOnly && size*mid < hyperliquidMinNotional {
+		return nil, fmt.Errorf("%s 下单金额 %.2f 低于最小金额 %.0f USDC", symbol, size*mid, hyperliquidMinNotional)
+	}
 
-	// ⚠️ 关键：价格也需要处理为5位有效数字
-	aggressivePrice := t.roundPriceToSigfigs(price * 1.01)
-	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*1.01, aggressivePrice)
+	limitPrice := mid * (1 + hyperliquidSlippage)
+	if !isBuy {
+		limitPrice = mid * (1 - hyperliquidSlippage)
+	}
+	limitPrice = t.roundPrice(coin, limitPrice)
 
-	// 创建市价买入订单（使用IOC limit order with aggressive price）
-	order := hyperliquid.CreateOrderRequest{
+	status, err := t.exchange.Order(t.ctx, hyperliquid.CreateOrderRequest{
 		Coin:  coin,
-		IsBuy: true,
-		Size:  roundedQuantity, // 使用四舍五入后的数量
-		Price: aggressivePrice, // 使用处理后的价格
+		IsBuy: isBuy,
+		Size:  size,
+		Price: limitPrice,
 		OrderType: hyperliquid.OrderType{
 			Limit: &hyperliquid.LimitOrderType{
 				Tif: hyperliquid.TifIoc, // Immediate or Cancel (类似市价单)
 			},
 		},
-		ReduceOnly: false,
-	}
-
-	_, err = t.exchange.Order(t.ctx, order, nil)
+		ReduceOnly: reduceOnly,
+	}, nil)
 	if err != nil {
-		return nil, fmt.Errorf("开多仓失败: %w", err)
+		return nil, err
 	}
+	return t.orderResult(symbol, multiplier, status)
+}
 
-	log.Printf("✓ 开多仓成功: %s 数量: %.4f", symbol, roundedQuantity)
+// hyperliquidOrderStatus 下单结果（按API的JSON格式解析，不依赖SDK的结构体字段）
+type hyperliquidOrderStatus struct {
+	Resting *struct {
+		Oid int64 `json:"oid"`
+	} `json:"resting"`
+	Filled *struct {
+		Oid     int64  `json:"oid"`
+		TotalSz string `json:"totalSz"`
+		AvgPx   string `json:"avgPx"`
+	} `json:"filled"`
+	Error string `json:"error"`
+}
+
+// orderResult 转换下单结果（orderId、status、成交数量和均价，IOC未成交或被拒绝时返回错误）
+func (t *HyperliquidTrader) orderResult(symbol string, multiplier float64, status interface{}) (map[string]interface{}, error) {
+	var parsed hyperliquidOrderStatus
+	if data, err := json.Marshal(status); err == nil {
+		_ = json.Unmarshal(data, &parsed)
+	}
 
 	result := make(map[string]interface{})
-	result["orderId"] = 0 // Hyperliquid没有返回order ID
 	result["symbol"] = symbol
-	result["status"] = "FILLED"
-
+	switch {
+	case parsed.Error != "":
+		return nil, fmt.Errorf("交易所拒绝: %s", parsed.Error)
+	case parsed.Filled != nil:
+		size, _ := strconv.ParseFloat(parsed.Filled.TotalSz, 64)
+		avgPrice, _ := strconv.ParseFloat(parsed.Filled.AvgPx, 64)
+		result["orderId"] = parsed.Filled.Oid
+		result["status"] = "FILLED"
+		result["executedQty"] = size / multiplier
+		result["avgPrice"] = avgPrice * multiplier
+	case parsed.Resting != nil:
+		result["orderId"] = parsed.Resting.Oid
+		result["status"] = "NEW"
+	default:
+		return nil, fmt.Errorf("未成交（IOC订单已取消）")
+	}
 	return result, nil
 }
 
-// OpenShort 开空仓
-func (t *HyperliquidTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
-	// 先取消该币种的所有委托单
-	if err := t.CancelAllOrders(symbol); err != nil {
-		log.Printf("  ⚠ 取消旧委托单失败: %v", err)
-	}
+// PlaceLimitEntry 挂限价开仓单（GTC，成交后由调用方设置止损止盈）
+func (t *HyperliquidTrader) PlaceLimitEntry(symbol string, positionSide string, quantity float64, leverage int, price float64) (map[string]interface{}, error) {
+	return t.placeLimitOrder(symbol, positionSide, quantity, leverage, price, hyperliquid.TifGtc)
+}
+
+// PlacePostOnlyEntry 挂只做maker的限价开仓单（ALO，会立即成交时交易所拒绝下单）
+func (t *HyperliquidTrader) PlacePostOnlyEntry(symbol string, positionSide string, quantity float64, leverage int, price float64) (map[string]interface{}, error) {
+	return t.placeLimitOrder(symbol, positionSide, quantity, leverage, price, hyperliquid.TifAlo)
+}
+
+// placeLimitOrder 挂限价开仓单
+func (t *HyperliquidTrader) placeLimitOrder(symbol string, positionSide string, quantity float64, leverage int, price float64, tif hyperliquid.Tif) (map[string]interface{}, error) {
+	// 不取消已有委托：已有持仓的止损止盈单需要保留
 
 	// 设置杠杆
 	if err := t.SetLeverage(symbol, leverage); err != nil {
 		return nil, err
 	}
 
-	// Hyperliquid symbol格式
-	coin := convertSymbolToHyperliquid(symbol)
-
-	// 获取当前价格
-	price, err := t.GetMarketPrice(symbol)
-	if err != nil {
-		return nil, err
+	coin, multiplier := t.coinOf(symbol)
+	size := t.roundSize(coin, quantity*multiplier, true)
+	limitPrice := t.roundPrice(coin, price/multiplier)
+	if size*limitPrice < hyperliquidMinNotional {
+		return nil, fmt.Errorf("%s 下单金额 %.2f 低于最小金额 %.0f USDC", symbol, size*limitPrice, hyperliquidMinNotional)
 	}
 
-	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
-	log.Printf("  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(coin))
-
-	// ⚠️ 关键：价格也需要处理为5位有效数字
-	aggressivePrice := t.roundPriceToSigfigs(price * 0.99)
-	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*0.99, aggressivePrice)
-
-	// 创建市价卖出订单
-	order := hyperliquid.CreateOrderRequest{
+	status, err := t.exchange.Order(t.ctx, hyperliquid.CreateOrderRequest{
 		Coin:  coin,
-		IsBuy: false,
-		Size:  roundedQuantity, // 使用四舍五入后的数量
-		Price: aggressivePrice, // 使用处理后的价格
+		IsBuy: positionSide == "LONG",
+		Size:  size,
+		Price: limitPrice,
 		OrderType: hyperliquid.OrderType{
 			Limit: &hyperliquid.LimitOrderType{
-				Tif: hyperliquid.TifIoc,
+				Tif: tif, // GTC挂单直到成交或取消，ALO只做maker
 			},
 		},
 		ReduceOnly: false,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("挂限价开仓单失败: %w", err)
 	}
-
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	result, err := t.orderResult(symbol, multiplier, status)
 	if err != nil {
-		return nil, fmt.Errorf("开空仓失败: %w", err)
+		return nil, fmt.Errorf("挂限价开仓单失败: %w", err)
 	}
 
-	log.Printf("✓ 开空仓成功: %s 数量: %.4f", symbol, roundedQuantity)
-
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
-
+	log.Printf("✓ 限价开仓单已挂出: %s %s 数量: %v 价格: %v (oid=%v)", coin, positionSide, size, limitPrice, result["orderId"])
 	return result, nil
 }
 
-// CloseLong 平多仓
+// CloseLong 平多仓（quantity=0表示全部平仓）
 func (t *HyperliquidTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closePosition(symbol, "long", quantity)
+}
+
+// CloseShort 平空仓（quantity=0表示全部平仓）
+func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closePosition(symbol, "short", quantity)
+}
+
+// closePosition 市价只减仓平仓，平仓后取消该币种的止损止盈单（保留限价开仓单）
+func (t *HyperliquidTrader) closePosition(symbol, side string, quantity float64) (map[string]interface{}, error) {
+	name := "平多仓"
+	if side == "short" {
+		name = "平空仓"
+	}
+
 	// 如果数量为0，获取当前持仓数量
 	if quantity == 0 {
 		positions, err := t.GetPositions()
 		if err != nil {
 			return nil, err
 		}
-
 		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "long" {
+			if pos["symbol"] == symbol && pos["side"] == side {
 				quantity = pos["positionAmt"].(float64)
 				break
 			}
 		}
-
 		if quantity == 0 {
+			if side == "short" {
+				return nil, fmt.Errorf("没有找到 %s 的空仓", symbol)
+			}
 			return nil, fmt.Errorf("没有找到 %s 的多仓", symbol)
 		}
 	}
 
-	// Hyperliquid symbol格式
-	coin := convertSymbolToHyperliquid(symbol)
-
-	// 获取当前价格
-	price, err := t.GetMarketPrice(symbol)
-	if err != nil {
-		return nil, err
-	}
-
-	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
-	log.Printf("  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(coin))
-
-	// ⚠️ 关键：价格也需要处理为5位有效数字
-	aggressivePrice := t.roundPriceToSigfigs(price * 0.99)
-	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*0.99, aggressivePrice)
-
-	// 创建平仓订单（卖出 + ReduceOnly）
-	order := hyperliquid.CreateOrderRequest{
-		Coin:  coin,
-		IsBuy: false,
-		Size:  roundedQuantity, // 使用四舍五入后的数量
-		Price: aggressivePrice, // 使用处理后的价格
-		OrderType: hyperliquid.OrderType{
-			Limit: &hyperliquid.LimitOrderType{
-				Tif: hyperliquid.TifIoc,
-			},
-		},
-		ReduceOnly: true, // 只平仓，不开新仓
-	}
-
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	order, err := t.marketOrder(symbol, side == "short", quantity, 0, true)
 	if err != nil {
-		return nil, fmt.Errorf("平多仓失败: %w", err)
+		return nil, fmt.Errorf("%s失败: %w", name, err)
 	}
+	log.Printf("✓ %s成功: %s 数量: %v 均价: %v", name, symbol, order["executedQty"], order["avgPrice"])
 
-	log.Printf("✓ 平多仓成功: %s 数量: %.4f", symbol, roundedQuantity)
-
-	// 平仓后取消该币种的所有挂单
-	if err := t.CancelAllOrders(symbol); err != nil {
+	if err := t.CancelProtectiveOrders(symbol, ""); err != nil {
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
-
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
-
-	return result, nil
+	return order, nil
 }
 
-// CloseShort 平空仓
-func (t *HyperliquidTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
-	// 如果数量为0，获取当前持仓数量
-	if quantity == 0 {
-		positions, err := t.GetPositions()
-		if err != nil {
-			return nil, err
-		}
-
-		for _, pos := range positions {
-			if pos["symbol"] == symbol && pos["side"] == "short" {
-				quantity = pos["positionAmt"].(float64)
-				break
-			}
-		}
+// hyperliquidOpenOrder 挂单详情（frontendOpenOrders接口，包含是否为触发单）
+type hyperliquidOpenOrder struct {
+	Coin       string `json:"coin"`
+	Oid        int64  `json:"oid"`
+	IsTrigger  bool   `json:"isTrigger"`
+	ReduceOnly bool   `json:"reduceOnly"`
+}
 
-		if quantity == 0 {
-			return nil, fmt.Errorf("没有找到 %s 的空仓", symbol)
+// openOrders 获取该币种的挂单
+func (t *HyperliquidTrader) openOrders(coin string) ([]hyperliquidOpenOrder, error) {
+	var orders []hyperliquidOpenOrder
+	request := map[string]string{"type": "frontendOpenOrders", "user": t.walletAddr}
+	if err := t.info(request, &orders); err != nil {
+		return nil, fmt.Errorf("获取挂单失败: %w", err)
+	}
+	result := orders[:0]
+	for _, order := range orders {
+		if order.Coin == coin {
+			result = append(result, order)
 		}
 	}
+	return result, nil
+}
 
-	// Hyperliquid symbol格式
-	coin := convertSymbolToHyperliquid(symbol)
-
-	// 获取当前价格
-	price, err := t.GetMarketPrice(symbol)
+// info 请求/info接口（只读查询，不需要签名）
+func (t *HyperliquidTrader) info(request interface{}, result interface{}) error {
+	body, err := json.Marshal(request)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
-	log.Printf("  📏 数量精度处理: %.8f -> %.8f (szDecimals=%d)", quantity, roundedQuantity, t.getSzDecimals(coin))
-
-	// ⚠️ 关键：价格也需要处理为5位有效数字
-	aggressivePrice := t.roundPriceToSigfigs(price * 1.01)
-	log.Printf("  💰 价格精度处理: %.8f -> %.8f (5位有效数字)", price*1.01, aggressivePrice)
-
-	// 创建平仓订单（买入 + ReduceOnly）
-	order := hyperliquid.CreateOrderRequest{
-		Coin:  coin,
-		IsBuy: true,
-		Size:  roundedQuantity, // 使用四舍五入后的数量
-		Price: aggressivePrice, // 使用处理后的价格
-		OrderType: hyperliquid.OrderType{
-			Limit: &hyperliquid.LimitOrderType{
-				Tif: hyperliquid.TifIoc,
-			},
-		},
-		ReduceOnly: true,
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodPost, t.apiURL+"/info", bytes.NewReader(body))
+	if err != nil {
+		return err
 	}
-
-	_, err = t.exchange.Order(t.ctx, order, nil)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("平空仓失败: %w", err)
+		return err
 	}
-
-	log.Printf("✓ 平空仓成功: %s 数量: %.4f", symbol, roundedQuantity)
-
-	// 平仓后取消该币种的所有挂单
-	if err := t.CancelAllOrders(symbol); err != nil {
-		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
 	}
-
-	result := make(map[string]interface{})
-	result["orderId"] = 0
-	result["symbol"] = symbol
-	result["status"] = "FILLED"
-
-	return result, nil
+	return json.NewDecoder(resp.Body).Decode(result)
 }
 
 // CancelAllOrders 取消该币种的所有挂单
 func (t *HyperliquidTrader) CancelAllOrders(symbol string) error {
-	coin := convertSymbolToHyperliquid(symbol)
-
-	// 获取所有挂单
-	openOrders, err := t.exchange.Info().OpenOrders(t.ctx, t.walletAddr)
+	coin, _ := t.coinOf(symbol)
+	orders, err := t.openOrders(coin)
 	if err != nil {
-		return fmt.Errorf("获取挂单失败: %w", err)
+		return err
+	}
+	for _, order := range orders {
+		if _, err := t.exchange.Cancel(t.ctx, coin, order.Oid); err != nil {
+			return fmt.Errorf("取消订单失败 (oid=%d): %w", order.Oid, err)
+		}
 	}
 
-	// 取消该币种的所有挂单
-	for _, order := range openOrders {
-		if order.Coin == coin {
-			_, err := t.exchange.Cancel(t.ctx, coin, order.Oid)
-			if err != nil {
-				log.Printf("  ⚠ 取消订单失败 (oid=%d): %v", order.Oid, err)
-			}
+	log.Printf("  ✓ 已取消 %s 的 %d 个挂单", symbol, len(orders))
+	return nil
+}
+
+// CancelProtectiveOrders 只取消该币种的止损止盈单（只减仓的触发单），保留限价开仓单。
+// Hyperliquid为单向持仓，positionSide不影响结果
+func (t *HyperliquidTrader) CancelProtectiveOrders(symbol string, positionSide string) error {
+	coin, _ := t.coinOf(symbol)
+	orders, err := t.openOrders(coin)
+	if err != nil {
+		return err
+	}
+	cancelled := 0
+	for _, order := range orders {
+		if !order.IsTrigger || !order.ReduceOnly {
+			continue
+		}
+		if _, err := t.exchange.Cancel(t.ctx, coin, order.Oid); err != nil {
+			return fmt.Errorf("取消订单失败 (oid=%d): %w", order.Oid, err)
 		}
+		cancelled++
 	}
 
-	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	log.Printf("  ✓ 已取消 %s 的 %d 个止损止盈单", symbol, cancelled)
 	return nil
 }
 
-// GetMarketPrice 获取市场价格
+// CancelOrder 取消指定挂单
+func (t *HyperliquidTrader) CancelOrder(symbol string, orderID int64) error {
+	coin, _ := t.coinOf(symbol)
+	if _, err := t.exchange.Cancel(t.ctx, coin, orderID); err != nil {
+		return fmt.Errorf("取消订单失败 (oid=%d): %w", orderID, err)
+	}
+
+	log.Printf("  ✓ 已取消 %s 订单 %d", symbol, orderID)
+	return nil
+}
+
+// GetMarketPrice 获取市场价格（中间价，倍数合约换算为统一价格）
 func (t *HyperliquidTrader) GetMarketPrice(symbol string) (float64, error) {
-	coin := convertSymbolToHyperliquid(symbol)
+	coin, multiplier := t.coinOf(symbol)
 
 	// 获取所有市场价格
 	allMids, err := t.exchange.Info().AllMids(t.ctx)
@@ -497,185 +531,142 @@ func (t *HyperliquidTrader) GetMarketPrice(symbol string) (float64, error) {
 	}
 
 	// 查找对应币种的价格（allMids是map[string]string）
-	if priceStr, ok := allMids[coin]; ok {
-		priceFloat, err := strconv.ParseFloat(priceStr, 64)
-		if err == nil {
-			return priceFloat, nil
-		}
+	priceStr, ok := allMids[coin]
+	if !ok {
+		return 0, fmt.Errorf("未找到 %s 的价格", symbol)
+	}
+	price, err := strconv.ParseFloat(priceStr, 64)
+	if err != nil {
 		return 0, fmt.Errorf("价格格式错误: %v", err)
 	}
-
-	return 0, fmt.Errorf("未找到 %s 的价格", symbol)
+	return price * multiplier, nil
 }
 
 // SetStopLoss 设置止损单
 func (t *HyperliquidTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
-	coin := convertSymbolToHyperliquid(symbol)
-
-	isBuy := positionSide == "SHORT" // 空仓止损=买入，多仓止损=卖出
-
-	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
-
-	// ⚠️ 关键：价格也需要处理为5位有效数字
-	roundedStopPrice := t.roundPriceToSigfigs(stopPrice)
-
-	// 创建止损单（Trigger Order）
-	order := hyperliquid.CreateOrderRequest{
-		Coin:  coin,
-		IsBuy: isBuy,
-		Size:  roundedQuantity,  // 使用四舍五入后的数量
-		Price: roundedStopPrice, // 使用处理后的价格
-		OrderType: hyperliquid.OrderType{
-			Trigger: &hyperliquid.TriggerOrderType{
-				TriggerPx: roundedStopPrice,
-				IsMarket:  true,
-				Tpsl:      "sl", // stop loss
-			},
-		},
-		ReduceOnly: true,
-	}
-
-	_, err := t.exchange.Order(t.ctx, order, nil)
-	if err != nil {
+	if err := t.placeTrigger(symbol, positionSide, quantity, stopPrice, "sl"); err != nil {
 		return fmt.Errorf("设置止损失败: %w", err)
 	}
-
-	log.Printf("  止损价设置: %.4f", roundedStopPrice)
+	log.Printf("  止损价设置: %.4f", stopPrice)
 	return nil
 }
 
 // SetTakeProfit 设置止盈单
 func (t *HyperliquidTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
-	coin := convertSymbolToHyperliquid(symbol)
-
-	isBuy := positionSide == "SHORT" // 空仓止盈=买入，多仓止盈=卖出
-
-	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
+	if err := t.placeTrigger(symbol, positionSide, quantity, takeProfitPrice, "tp"); err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
+	}
+	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
 
-	// ⚠️ 关键：价格也需要处理为5位有效数字
-	roundedTakeProfitPrice := t.roundPriceToSigfigs(takeProfitPrice)
+// placeTrigger 下只减仓的触发市价单（tpsl为"sl"或"tp"，空仓的止损止盈为买入，多仓为卖出）
+func (t *HyperliquidTrader) placeTrigger(symbol, positionSide string, quantity, triggerPrice float64, tpsl string) error {
+	coin, multiplier := t.coinOf(symbol)
+	size := t.roundSize(coin, quantity*multiplier, false)
+	price := t.roundPrice(coin, triggerPrice/multiplier)
 
-	// 创建止盈单（Trigger Order）
-	order := hyperliquid.CreateOrderRequest{
+	status, err := t.exchange.Order(t.ctx, hyperliquid.CreateOrderRequest{
 		Coin:  coin,
-		IsBuy: isBuy,
-		Size:  roundedQuantity,        // 使用四舍五入后的数量
-		Price: roundedTakeProfitPrice, // 使用处理后的价格
+		IsBuy: positionSide == "SHORT",
+		Size:  size,
+		Price: price,
 		OrderType: hyperliquid.OrderType{
 			Trigger: &hyperliquid.TriggerOrderType{
-				TriggerPx: roundedTakeProfitPrice,
+				TriggerPx: price,
 				IsMarket:  true,
-				Tpsl:      "tp", // take profit
+				Tpsl:      tpsl,
 			},
 		},
 		ReduceOnly: true,
-	}
-
-	_, err := t.exchange.Order(t.ctx, order, nil)
+	}, nil)
 	if err != nil {
-		return fmt.Errorf("设置止盈失败: %w", err)
+		return err
 	}
-
-	log.Printf("  止盈价设置: %.4f", roundedTakeProfitPrice)
-	return nil
+	_, err = t.orderResult(symbol, multiplier, status)
+	return err
 }
 
-// FormatQuantity 格式化数量到正确的精度
+// FormatQuantity 格式化数量到正确的精度（向下取整到szDecimals）
 func (t *HyperliquidTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
-	coin := convertSymbolToHyperliquid(symbol)
-	szDecimals := t.getSzDecimals(coin)
+	coin, multiplier := t.coinOf(symbol)
+	size := t.roundSize(coin, quantity*multiplier, true) / multiplier
+	return strconv.FormatFloat(size, 'f', -1, 64), nil
+}
 
-	// 使用szDecimals格式化数量
-	formatStr := fmt.Sprintf("%%.%df", szDecimals)
-	return fmt.Sprintf(formatStr, quantity), nil
+// coinOf 币种对应的Hyperliquid币种名和倍数：
+// BTCUSDT -> BTC；1000PEPEUSDT -> kPEPE（Hyperliquid的k前缀即1000倍）；
+// 只有无前缀合约时按倍数换算（数量×倍数、价格÷倍数）
+func (t *HyperliquidTrader) coinOf(symbol string) (string, float64) {
+	normalized := market.Normalize(symbol)
+	coin := strings.TrimSuffix(normalized, "USDT")
+	if t.listed(coin) {
+		return coin, 1
+	}
+	if rest := strings.TrimPrefix(coin, "1000"); rest != coin && t.listed("k"+rest) {
+		return "k" + rest, 1
+	}
+	base, multiplier := market.BaseAsset(normalized), market.Multiplier(normalized)
+	if t.listed(base) {
+		return base, multiplier
+	}
+	if t.listed("k" + base) {
+		return "k" + base, multiplier / 1000
+	}
+	return coin, 1
 }
 
-// getSzDecimals 获取币种的数量精度
-func (t *HyperliquidTrader) getSzDecimals(coin string) int {
+// szDecimals 币种在meta.Universe中的数量精度（ok为false表示币种不存在）
+func (t *HyperliquidTrader) szDecimals(coin string) (decimals int, ok bool) {
 	if t.meta == nil {
-		log.Printf("⚠️  meta信息为空，使用默认精度4")
-		return 4 // 默认精度
+		return 0, false
 	}
-
-	// 在meta.Universe中查找对应的币种
 	for _, asset := range t.meta.Universe {
 		if asset.Name == coin {
-			return asset.SzDecimals
+			return asset.SzDecimals, true
 		}
 	}
+	return 0, false
+}
 
+// listed 币种是否在Hyperliquid上线
+func (t *HyperliquidTrader) listed(coin string) bool {
+	_, ok := t.szDecimals(coin)
+	return ok
+}
+
+// getSzDecimals 获取币种的数量精度
+func (t *HyperliquidTrader) getSzDecimals(coin string) int {
+	if decimals, ok := t.szDecimals(coin); ok {
+		return decimals
+	}
 	log.Printf("⚠️  未找到 %s 的精度信息，使用默认精度4", coin)
 	return 4 // 默认精度
 }
 
-// roundToSzDecimals 将数量四舍五入到正确的精度
-func (t *HyperliquidTrader) roundToSzDecimals(coin string, quantity float64) float64 {
-	szDecimals := t.getSzDecimals(coin)
-
-	// 计算倍数（10^szDecimals）
-	multiplier := 1.0
-	for i := 0; i < szDecimals; i++ {
-		multiplier *= 10.0
+// roundSize 数量取整到szDecimals（floor为true时向下取整）
+func (t *HyperliquidTrader) roundSize(coin string, size float64, floor bool) float64 {
+	scale := math.Pow(10, float64(t.getSzDecimals(coin)))
+	if floor {
+		return math.Floor(size*scale+1e-9) / scale
 	}
-
-	// 四舍五入
-	return float64(int(quantity*multiplier+0.5)) / multiplier
+	return math.Round(size*scale) / scale
 }
 
-// roundPriceToSigfigs 将价格四舍五入到5位有效数字
-// Hyperliquid要求价格使用5位有效数字（significant figures）
-func (t *HyperliquidTrader) roundPriceToSigfigs(price float64) float64 {
-	if price == 0 {
+// roundPrice 价格取整：最多5位有效数字（整数价格不受限制），且小数位数不超过 6 - szDecimals
+func (t *HyperliquidTrader) roundPrice(coin string, price float64) float64 {
+	if price <= 0 {
 		return 0
 	}
-
-	const sigfigs = 5 // Hyperliquid标准：5位有效数字
-
-	// 计算价格的数量级
-	var magnitude float64
-	if price < 0 {
-		magnitude = -price
+	if price < math.Pow(10, hyperliquidSigFigs) {
+		price, _ = strconv.ParseFloat(strconv.FormatFloat(price, 'g', hyperliquidSigFigs, 64), 64)
 	} else {
-		magnitude = price
-	}
-
-	// 计算需要的倍数
-	multiplier := 1.0
-	for magnitude >= 10 {
-		magnitude /= 10
-		multiplier /= 10
-	}
-	for magnitude < 1 {
-		magnitude *= 10
-		multiplier *= 10
-	}
-
-	// 应用有效数字精度
-	for i := 0; i < sigfigs-1; i++ {
-		multiplier *= 10
+		price = math.Round(price)
 	}
-
-	// 四舍五入
-	rounded := float64(int(price*multiplier+0.5)) / multiplier
-	return rounded
-}
-
-// convertSymbolToHyperliquid 将标准symbol转换为Hyperliquid格式
-// 例如: "BTCUSDT" -> "BTC"
-func convertSymbolToHyperliquid(symbol string) string {
-	// 去掉USDT后缀
-	if len(symbol) > 4 && symbol[len(symbol)-4:] == "USDT" {
-		return symbol[:len(symbol)-4]
-	}
-	return symbol
-}
-
-// absFloat 返回浮点数的绝对值
-func absFloat(x float64) float64 {
-	if x < 0 {
-		return -x
+	decimals := hyperliquidMaxDecimals - t.getSzDecimals(coin)
+	if decimals < 0 {
+		decimals = 0
 	}
-	return x
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(price*scale) / scale
 }
@@ -15,6 +15,9 @@ type Trader interface {
 	// OpenShort 开空仓
 	OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error)
 
+	// PlaceLimitEntry 挂限价开仓单（positionSide: "LONG"/"SHORT"），成交前不占用仓位
+	PlaceLimitEntry(symbol string, positionSide string, quantity float64, leverage int, price float64) (map[string]interface{}, error)
+
 	// CloseLong 平多仓（quantity=0表示全部平仓）
 	CloseLong(symbol string, quantity float64) (map[string]interface{}, error)
 
@@ -36,6 +39,9 @@ type Trader interface {
 	// CancelAllOrders 取消该币种的所有挂单
 	CancelAllOrders(symbol string) error
 
+	// CancelOrder 取消指定挂单
+	CancelOrder(symbol string, orderID int64) error
+
 	// FormatQuantity 格式化数量到正确的精度
 	FormatQuantity(symbol string, quantity float64) (string, error)
 }
@@ -0,0 +1,90 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"strings"
+	"time"
+)
+
+// enforceInvalidationRules 检查持仓的invalidation_rule，触发后自动平仓或标记给AI（由invalidation_action决定）
+// 退出计划在构建交易上下文时才清理，只检查交易所当前仍持有的仓位（已被止损/止盈平掉的跳过）
+func (at *AutoTrader) enforceInvalidationRules(record *logger.DecisionRecord) {
+	var live map[string]bool
+	for posKey, plan := range at.positionExitPlans {
+		if plan.InvalidationRule == nil || plan.InvalidationTriggered != "" {
+			continue
+		}
+		if live == nil {
+			positions, err := at.trader.GetPositions()
+			if err != nil {
+				log.Printf("⚠ 失效条件检查获取持仓失败: %v", err)
+				return
+			}
+			live = make(map[string]bool, len(positions))
+			for _, pos := range positions {
+				symbol, _ := pos["symbol"].(string)
+				side, _ := pos["side"].(string)
+				live[symbol+"_"+side] = true
+			}
+		}
+		if !live[posKey] {
+			continue
+		}
+
+		sep := strings.LastIndex(posKey, "_")
+		if sep < 0 {
+			continue
+		}
+		symbol, side := posKey[:sep], posKey[sep+1:]
+
+		data, err := market.GetContext(at.runCtx, symbol)
+		if err != nil {
+			log.Printf("⚠ 失效条件检查获取行情失败 (%s): %v", symbol, err)
+			continue
+		}
+		triggered, current, err := plan.InvalidationRule.Evaluate(data)
+		if err != nil {
+			log.Printf("⚠ 失效条件检查失败 (%s %s): %v", symbol, side, err)
+			continue
+		}
+		if !triggered {
+			continue
+		}
+
+		reason := fmt.Sprintf("invalidation rule triggered: %s (current %.4f)", plan.InvalidationRule, current)
+		log.Printf("🚨 %s %s %s", symbol, side, reason)
+
+		if at.config.InvalidationAction == "flag" {
+			plan.InvalidationTriggered = reason
+			at.savePositionState()
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🚨 %s %s flagged: %s", symbol, side, reason))
+			continue
+		}
+
+		// 自动平仓
+		dec := &decision.Decision{
+			Symbol:    symbol,
+			Action:    "close_" + side,
+			Reasoning: reason,
+		}
+		actionRecord := logger.DecisionAction{
+			Action:    dec.Action,
+			Symbol:    symbol,
+			Timestamp: time.Now(),
+			Reasoning: reason,
+		}
+		if err := at.executeDecisionWithRecord(dec, &actionRecord); err != nil {
+			log.Printf("❌ 失效条件自动平仓失败 (%s %s): %v", symbol, side, err)
+			actionRecord.Error = err.Error()
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s auto-close failed: %v", symbol, dec.Action, err))
+		} else {
+			actionRecord.Success = true
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s auto-closed (%s)", symbol, dec.Action, reason))
+		}
+		record.Decisions = append(record.Decisions, actionRecord)
+	}
+}
@@ -0,0 +1,283 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pendingEntry 未成交的限价开仓单（成交后按Plan设置止损止盈）
+type pendingEntry struct {
+	OrderID         int64                  `json:"order_id"`
+	Symbol          string                 `json:"symbol"`
+	Side            string                 `json:"side"` // "long" or "short"
+	Quantity        float64                `json:"quantity"`
+	EntryPrice      float64                `json:"entry_price"`
+	PositionSizeUSD float64                `json:"position_size_usd"`
+	ExpiresAt       int64                  `json:"expires_at"` // 过期时间（毫秒）
+	Plan            *decision.PositionInfo `json:"plan"`       // 成交后使用的退出计划
+	Filled          float64                `json:"filled,omitempty"`
+	State           OrderState             `json:"state,omitempty"`
+}
+
+// order 挂单的生命周期跟踪（旧状态文件中没有State时为submitted）
+func (e *pendingEntry) order() *trackedOrder {
+	state := e.State
+	if state == "" {
+		state = OrderSubmitted
+	}
+	return &trackedOrder{
+		OrderID:   e.OrderID,
+		Symbol:    e.Symbol,
+		Side:      e.Side,
+		Requested: e.Quantity,
+		Filled:    e.Filled,
+		State:     state,
+	}
+}
+
+// exitPlanFromDecision 开仓决策中的退出计划
+func exitPlanFromDecision(dec *decision.Decision) *decision.PositionInfo {
+	return &decision.PositionInfo{
+		StopLoss:              dec.StopLoss,
+		TakeProfit:            dec.TakeProfit,
+		InvalidationCondition: dec.InvalidationCondition,
+		Confidence:            dec.Confidence,
+		RiskUSD:               dec.RiskUSD,
+		TrailingActivation:    dec.TrailingActivation,
+		TrailingCallbackRate:  dec.TrailingCallbackRate,
+		InvalidationRule:      dec.InvalidationRule,
+	}
+}
+
+// checkNoPendingEntry 同方向已有未成交的限价开仓单时拒绝再次开仓（防止成交后仓位叠加）
+func (at *AutoTrader) checkNoPendingEntry(symbol, side string) error {
+	if entry, exists := at.pendingEntries[symbol+"_"+side]; exists {
+		return fmt.Errorf("❌ %s already has a pending %s limit entry @ %.4f, rejecting open to prevent position stacking overflow", symbol, side, entry.EntryPrice)
+	}
+	return nil
+}
+
+// placeLimitEntry 挂限价开仓单，成交前不设置止损止盈
+func (at *AutoTrader) placeLimitEntry(dec *decision.Decision, actionRecord *logger.DecisionAction, side string) error {
+	quantity := dec.PositionSizeUSD / dec.EntryPrice
+	actionRecord.Quantity = quantity
+	actionRecord.Price = dec.EntryPrice
+
+	order, err := at.trader.PlaceLimitEntry(dec.Symbol, strings.ToUpper(side), quantity, dec.Leverage, dec.EntryPrice)
+	if err != nil {
+		return err
+	}
+	orderID := orderIDOf(order)
+	actionRecord.OrderID = orderID
+
+	expiry := dec.EntryExpiry()
+	at.pendingEntries[dec.Symbol+"_"+side] = &pendingEntry{
+		OrderID:         orderID,
+		Symbol:          dec.Symbol,
+		Side:            side,
+		Quantity:        quantity,
+		EntryPrice:      dec.EntryPrice,
+		PositionSizeUSD: dec.PositionSizeUSD,
+		ExpiresAt:       time.Now().Add(expiry).UnixMilli(),
+		Plan:            exitPlanFromDecision(dec),
+	}
+	at.savePositionState()
+
+	log.Printf("  ⏳ Limit entry placed, Order ID: %d, Quantity: %.4f @ %.4f, expires in %v", orderID, quantity, dec.EntryPrice, expiry)
+	return nil
+}
+
+// checkPendingEntries 检查限价开仓单的成交情况（按持仓数量计算已成交部分）：
+// 首次成交时设置止损止盈，继续成交时按新的持仓数量重新挂单，全部成交后不再跟踪；
+// 被交易所撤销/拒绝或过期时结束跟踪，已成交的部分保留为正常持仓。返回执行日志
+func (at *AutoTrader) checkPendingEntries() []string {
+	if len(at.pendingEntries) == 0 {
+		return nil
+	}
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠ 检查限价开仓单时获取持仓失败: %v", err)
+		return nil
+	}
+	filled := make(map[string]float64) // symbol_side -> 持仓数量
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		quantity, _ := pos["positionAmt"].(float64)
+		filled[symbol+"_"+side] = math.Abs(quantity)
+	}
+
+	var events []string
+	changed := false
+	now := time.Now()
+	for posKey, entry := range at.pendingEntries {
+		o := entry.order()
+		previous := o.Filled
+		if quantity := filled[posKey]; quantity > previous {
+			o.applyFilled(quantity, false)
+		}
+		if qr, ok := at.trader.(orderStatusQuerier); ok && entry.OrderID != 0 {
+			if result, err := qr.GetOrderStatus(entry.Symbol, entry.OrderID); err == nil {
+				// 成交数量以持仓为准，这里只取状态
+				delete(result, "executedQty")
+				o.applyResult(result)
+			} else {
+				log.Printf("⚠ 查询限价单 %d 状态失败 (%s %s): %v", entry.OrderID, entry.Symbol, entry.Side, err)
+			}
+		}
+
+		if o.Filled > previous {
+			events = append(events, at.protectLimitFill(entry, previous, o.Filled))
+			if _, pending := at.pendingEntries[posKey]; !pending {
+				continue
+			}
+		}
+		if o.State != entry.State || o.Filled != entry.Filled {
+			entry.State = o.State
+			entry.Filled = o.Filled
+			changed = true
+		}
+
+		switch {
+		case o.State == OrderFilled:
+			delete(at.pendingEntries, posKey)
+		case o.terminal():
+			delete(at.pendingEntries, posKey)
+			if o.Filled > 0 {
+				events = append(events, fmt.Sprintf("⚠ %s %s limit entry %s after a partial fill (%s), the filled part is kept as a position",
+					entry.Symbol, entry.Side, o.State, o.fillSummary()))
+			} else {
+				events = append(events, fmt.Sprintf("❌ %s %s limit entry @ %.4f %s by the exchange",
+					entry.Symbol, entry.Side, entry.EntryPrice, o.State))
+			}
+		case now.UnixMilli() >= entry.ExpiresAt:
+			if entry.OrderID != 0 {
+				if err := at.trader.CancelOrder(entry.Symbol, entry.OrderID); err != nil {
+					log.Printf("⚠ 撤销过期限价单失败 (%s %s): %v", entry.Symbol, entry.Side, err)
+				}
+			} else {
+				log.Printf("⚠ %s %s 限价单没有订单ID，无法撤单，请手动检查", entry.Symbol, entry.Side)
+			}
+			delete(at.pendingEntries, posKey)
+			if o.Filled == 0 {
+				events = append(events, fmt.Sprintf("⌛ %s %s limit entry @ %.4f expired unfilled, order cancelled",
+					entry.Symbol, entry.Side, entry.EntryPrice))
+				break
+			}
+			o.transition(OrderCancelled, o.Filled, 0)
+			// 撤单前可能又有成交，按撤单后的持仓重新挂止损止盈
+			if quantity, err := at.positionQuantity(entry.Symbol, entry.Side); err == nil && quantity > o.Filled {
+				at.protectLimitFill(entry, o.Filled, quantity)
+				o.Filled = quantity
+			}
+			events = append(events, fmt.Sprintf("⌛ %s %s limit entry expired partially filled (%s), remainder cancelled",
+				entry.Symbol, entry.Side, o.fillSummary()))
+		}
+	}
+
+	if changed || len(events) > 0 {
+		at.savePositionState()
+		for _, event := range events {
+			log.Println(event)
+		}
+	}
+	return events
+}
+
+// protectLimitFill 限价单新成交的部分：首次成交时记录持仓并设置止损止盈，之后按新的持仓数量重新挂单
+func (at *AutoTrader) protectLimitFill(entry *pendingEntry, previous, quantity float64) string {
+	posKey := entry.Symbol + "_" + entry.Side
+	plan := entry.Plan
+	plan.Quantity = quantity
+
+	if previous == 0 {
+		if _, exists := at.positionFirstSeenTime[posKey]; !exists {
+			at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
+		}
+		at.positionExitPlans[posKey] = plan
+		at.savePositionState()
+		if err := at.protectNewPosition(entry.Symbol, entry.Side, quantity, plan); err != nil {
+			// 已平仓，剩余部分不再成交
+			at.cancelPendingRemainder(entry)
+			return fmt.Sprintf("❌ %s %s limit entry filled (%.4f @ %.4f): %v",
+				entry.Symbol, entry.Side, quantity, entry.EntryPrice, err)
+		}
+		return fmt.Sprintf("✓ %s %s limit entry filled %.4f / %.4f @ %.4f, stop loss and take profit placed",
+			entry.Symbol, entry.Side, quantity, entry.Quantity, entry.EntryPrice)
+	}
+
+	if err := at.resizeProtection(entry.Symbol, entry.Side, quantity, plan); err != nil {
+		// 止损挂不上时已市价平仓，剩余部分不再成交
+		if _, open := at.positionExitPlans[posKey]; !open {
+			at.cancelPendingRemainder(entry)
+		}
+		return fmt.Sprintf("❌ %s %s limit entry filled further (%.4f / %.4f) but protection could not be updated: %v",
+			entry.Symbol, entry.Side, quantity, entry.Quantity, err)
+	}
+	return fmt.Sprintf("✓ %s %s limit entry filled further (%.4f / %.4f), stop loss and take profit resized",
+		entry.Symbol, entry.Side, quantity, entry.Quantity)
+}
+
+// cancelPendingRemainder 撤销限价开仓单未成交的部分并停止跟踪
+func (at *AutoTrader) cancelPendingRemainder(entry *pendingEntry) {
+	if entry.OrderID != 0 {
+		if err := at.trader.CancelOrder(entry.Symbol, entry.OrderID); err != nil {
+			log.Printf("⚠ 撤销限价单剩余部分失败 (%s %s): %v", entry.Symbol, entry.Side, err)
+		}
+	}
+	delete(at.pendingEntries, entry.Symbol+"_"+entry.Side)
+}
+
+// pendingEntryList 未成交限价单（供AI参考）
+func (at *AutoTrader) pendingEntryList() []decision.PendingEntry {
+	if len(at.pendingEntries) == 0 {
+		return nil
+	}
+
+	now := time.Now().UnixMilli()
+	entries := make([]decision.PendingEntry, 0, len(at.pendingEntries))
+	for _, entry := range at.pendingEntries {
+		remaining := int(math.Ceil(float64(entry.ExpiresAt-now) / float64(time.Minute.Milliseconds())))
+		if remaining < 0 {
+			remaining = 0
+		}
+		entries = append(entries, decision.PendingEntry{
+			Symbol:           entry.Symbol,
+			Side:             entry.Side,
+			EntryPrice:       entry.EntryPrice,
+			PositionSizeUSD:  entry.PositionSizeUSD,
+			StopLoss:         entry.Plan.StopLoss,
+			TakeProfit:       entry.Plan.TakeProfit,
+			ExpiresInMinutes: remaining,
+			FilledQuantity:   entry.Filled,
+			Quantity:         entry.Quantity,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Symbol != entries[j].Symbol {
+			return entries[i].Symbol < entries[j].Symbol
+		}
+		return entries[i].Side < entries[j].Side
+	})
+	return entries
+}
+
+// orderIDOf 从下单结果中取订单ID（币安为int64，Aster的JSON解析结果为float64）
+func orderIDOf(order map[string]interface{}) int64 {
+	switch id := order["orderId"].(type) {
+	case int64:
+		return id
+	case int:
+		return int64(id)
+	case float64:
+		return int64(id)
+	}
+	return 0
+}
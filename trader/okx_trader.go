@@ -0,0 +1,833 @@
+package trader
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"nofx/market"
+	"nofx/proxy"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OKX接口参数
+const (
+	okxHost       = "www.okx.com"
+	okxMarginMode = "isolated" // 逐仓（与币安一致）
+)
+
+func init() {
+	proxy.Register(proxy.KindExchange, okxHost)
+}
+
+// okxInstrument OKX永续合约规则（下单数量单位为张，一张 = ctVal 个基础币种）
+type okxInstrument struct {
+	InstID string
+	CtVal  float64 // 合约面值（基础币种数量）
+	LotSz  float64 // 下单数量步进（张）
+	MinSz  float64 // 最小下单数量（张）
+	TickSz float64 // 价格步进
+}
+
+// OKXTrader OKX USDT永续合约交易器（v5接口，HMAC签名）。
+// 统一接口中的币种和数量沿用行情数据源的写法（BTCUSDT、1000PEPEUSDT，数量为基础币种个数），
+// 在这里换算为OKX的instId（BTC-USDT-SWAP、PEPE-USDT-SWAP）和张数
+type OKXTrader struct {
+	apiKey     string
+	secretKey  string
+	passphrase string
+	baseURL    string
+	client     *http.Client
+	ctx        context.Context // 请求使用的context（见SetContext）
+
+	// 模拟盘：请求带x-simulated-trading头（主机相同，API Key需在模拟交易中单独创建）
+	simulated bool
+
+	// 持仓模式：long_short_mode（双向，下单需指定posSide）或net_mode（单向）
+	longShortMode bool
+
+	mu          sync.RWMutex
+	instruments map[string]*okxInstrument // instId -> 合约规则
+
+	idMu      sync.Mutex
+	lastClOrd int64
+}
+
+// NewOKXTrader 创建OKX交易器（读取账户当前的持仓模式，testnet为true时使用模拟盘）
+func NewOKXTrader(apiKey, secretKey, passphrase string, testnet bool) (*OKXTrader, error) {
+	t := &OKXTrader{
+		apiKey:     apiKey,
+		secretKey:  secretKey,
+		passphrase: passphrase,
+		baseURL:    "https://" + okxHost,
+		client:     &http.Client{Timeout: 15 * time.Second},
+		ctx:        context.Background(),
+		simulated:  testnet,
+	}
+
+	var config []struct {
+		PosMode string `json:"posMode"`
+	}
+	if err := t.request(http.MethodGet, "/api/v5/account/config", nil, &config); err != nil {
+		return nil, fmt.Errorf("获取OKX账户配置失败: %w", err)
+	}
+	if len(config) > 0 {
+		t.longShortMode = config[0].PosMode == "long_short_mode"
+	}
+	log.Printf("  ✓ OKX持仓模式: %s", t.posModeName())
+	return t, nil
+}
+
+// SetContext 设置请求使用的context，取消后进行中的请求立即返回
+func (t *OKXTrader) SetContext(ctx context.Context) {
+	t.ctx = ctx
+}
+
+// okxError OKX接口返回的业务错误
+type okxError struct {
+	Code    string
+	Message string
+}
+
+func (e *okxError) Error() string {
+	return fmt.Sprintf("OKX错误 %s: %s", e.Code, e.Message)
+}
+
+// request 发送签名请求并解析data字段：
+// 签名 = Base64(HMAC_SHA256(timestamp + method + requestPath(含querystring) + body))
+func (t *OKXTrader) request(method, path string, params interface{}, result interface{}) error {
+	requestPath := path
+	var body []byte
+	if method == http.MethodGet {
+		if query, ok := params.(url.Values); ok && len(query) > 0 {
+			requestPath += "?" + query.Encode()
+		}
+	} else if params != nil {
+		var err error
+		body, err = json.Marshal(params)
+		if err != nil {
+			return err
+		}
+	}
+
+	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	mac := hmac.New(sha256.New, []byte(t.secretKey))
+	mac.Write([]byte(timestamp + method + requestPath + string(body)))
+
+	req, err := http.NewRequestWithContext(t.ctx, method, t.baseURL+requestPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("OK-ACCESS-KEY", t.apiKey)
+	req.Header.Set("OK-ACCESS-SIGN", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+	req.Header.Set("OK-ACCESS-PASSPHRASE", t.passphrase)
+	if t.simulated {
+		req.Header.Set("x-simulated-trading", "1")
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Code string          `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("解析OKX响应失败 (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if envelope.Code != "0" {
+		// 下单类接口的具体原因在data[].sMsg中
+		var items []struct {
+			SCode string `json:"sCode"`
+			SMsg  string `json:"sMsg"`
+		}
+		if json.Unmarshal(envelope.Data, &items) == nil && len(items) > 0 && items[0].SCode != "" && items[0].SCode != "0" {
+			return &okxError{Code: items[0].SCode, Message: items[0].SMsg}
+		}
+		return &okxError{Code: envelope.Code, Message: envelope.Msg}
+	}
+	if result == nil || len(envelope.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(envelope.Data, result)
+}
+
+// posModeName 持仓模式名称
+func (t *OKXTrader) posModeName() string {
+	if t.longShortMode {
+		return "双向持仓(long_short_mode)"
+	}
+	return "单向持仓(net_mode)"
+}
+
+// posSide 下单的posSide参数（单向持仓为net）
+func (t *OKXTrader) posSide(positionSide string) string {
+	if !t.longShortMode {
+		return "net"
+	}
+	return strings.ToLower(positionSide)
+}
+
+// nextClOrdID 生成递增的数字客户端订单ID，作为统一接口的int64订单ID
+func (t *OKXTrader) nextClOrdID() int64 {
+	t.idMu.Lock()
+	defer t.idMu.Unlock()
+	id := time.Now().UnixMilli() * 1000
+	if id <= t.lastClOrd {
+		id = t.lastClOrd + 1
+	}
+	t.lastClOrd = id
+	return id
+}
+
+// loadInstruments 加载所有USDT永续合约规则
+func (t *OKXTrader) loadInstruments() error {
+	var list []struct {
+		InstID    string `json:"instId"`
+		CtVal     string `json:"ctVal"`
+		LotSz     string `json:"lotSz"`
+		MinSz     string `json:"minSz"`
+		TickSz    string `json:"tickSz"`
+		SettleCcy string `json:"settleCcy"`
+	}
+	query := url.Values{"instType": {"SWAP"}}
+	if err := t.request(http.MethodGet, "/api/v5/public/instruments", query, &list); err != nil {
+		return fmt.Errorf("获取OKX合约规则失败: %w", err)
+	}
+
+	instruments := make(map[string]*okxInstrument, len(list))
+	for _, item := range list {
+		if item.SettleCcy != "USDT" {
+			continue
+		}
+		inst := &okxInstrument{InstID: item.InstID}
+		inst.CtVal, _ = strconv.ParseFloat(item.CtVal, 64)
+		inst.LotSz, _ = strconv.ParseFloat(item.LotSz, 64)
+		inst.MinSz, _ = strconv.ParseFloat(item.MinSz, 64)
+		inst.TickSz, _ = strconv.ParseFloat(item.TickSz, 64)
+		instruments[item.InstID] = inst
+	}
+
+	t.mu.Lock()
+	t.instruments = instruments
+	t.mu.Unlock()
+	return nil
+}
+
+// instrument 币种对应的OKX合约和倍数：优先同名合约（BTCUSDT -> BTC-USDT-SWAP），
+// 否则去掉倍数前缀（1000PEPEUSDT -> PEPE-USDT-SWAP，数量×1000、价格÷1000）
+func (t *OKXTrader) instrument(symbol string) (*okxInstrument, float64, error) {
+	t.mu.RLock()
+	loaded := t.instruments != nil
+	t.mu.RUnlock()
+	if !loaded {
+		if err := t.loadInstruments(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	normalized := market.Normalize(symbol)
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if inst, ok := t.instruments[strings.TrimSuffix(normalized, "USDT")+"-USDT-SWAP"]; ok {
+		return inst, 1, nil
+	}
+	if inst, ok := t.instruments[market.BaseAsset(normalized)+"-USDT-SWAP"]; ok {
+		return inst, market.Multiplier(normalized), nil
+	}
+	return nil, 0, fmt.Errorf("OKX没有 %s 的USDT永续合约", symbol)
+}
+
+// okxSymbol OKX合约对应的统一币种（按行情数据源的写法，如PEPE-USDT-SWAP -> 1000PEPEUSDT）
+func okxSymbol(instID string) string {
+	return market.Canonical(strings.TrimSuffix(instID, "-USDT-SWAP") + "USDT")
+}
+
+// contracts 基础币种数量换算为张数（向下取整到lotSz）
+func (t *OKXTrader) contracts(symbol string, quantity float64) (string, *okxInstrument, float64, error) {
+	inst, multiplier, err := t.instrument(symbol)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	if inst.CtVal <= 0 || inst.LotSz <= 0 {
+		return "", nil, 0, fmt.Errorf("%s 合约规则无效", inst.InstID)
+	}
+	sz := math.Floor(quantity*multiplier/inst.CtVal/inst.LotSz+1e-9) * inst.LotSz
+	if sz < inst.MinSz {
+		return "", nil, 0, fmt.Errorf("%s 数量 %.8f 不足最小下单量 %g 张（每张 %g）", symbol, quantity, inst.MinSz, inst.CtVal)
+	}
+	return strconv.FormatFloat(sz, 'f', stepDecimals(inst.LotSz), 64), inst, multiplier, nil
+}
+
+// okxPrice 统一价格换算为OKX价格（倍数合约÷倍数，按tickSz取整）
+func okxPrice(inst *okxInstrument, multiplier, price float64) string {
+	price /= multiplier
+	if inst.TickSz > 0 {
+		price = math.Round(price/inst.TickSz) * inst.TickSz
+	}
+	return strconv.FormatFloat(price, 'f', stepDecimals(inst.TickSz), 64)
+}
+
+// stepDecimals 步进值的小数位数
+func stepDecimals(step float64) int {
+	if step <= 0 || step >= 1 {
+		return 0
+	}
+	return int(math.Ceil(-math.Log10(step) - 1e-9))
+}
+
+// GetBalance 获取USDT余额（字段名与币安一致）
+func (t *OKXTrader) GetBalance() (map[string]interface{}, error) {
+	var data []struct {
+		Details []struct {
+			Ccy     string `json:"ccy"`
+			CashBal string `json:"cashBal"`
+			AvailEq string `json:"availEq"`
+			Upl     string `json:"upl"`
+		} `json:"details"`
+	}
+	if err := t.request(http.MethodGet, "/api/v5/account/balance", url.Values{"ccy": {"USDT"}}, &data); err != nil {
+		return nil, fmt.Errorf("获取账户信息失败: %w", err)
+	}
+
+	balance := map[string]interface{}{"totalWalletBalance": 0.0, "availableBalance": 0.0, "totalUnrealizedProfit": 0.0}
+	for _, account := range data {
+		for _, detail := range account.Details {
+			if detail.Ccy != "USDT" {
+				continue
+			}
+			balance["totalWalletBalance"], _ = strconv.ParseFloat(detail.CashBal, 64)
+			balance["availableBalance"], _ = strconv.ParseFloat(detail.AvailEq, 64)
+			balance["totalUnrealizedProfit"], _ = strconv.ParseFloat(detail.Upl, 64)
+			log.Printf("✓ OKX API返回: 总余额=%s, 可用=%s, 未实现盈亏=%s", detail.CashBal, detail.AvailEq, detail.Upl)
+		}
+	}
+	return balance, nil
+}
+
+// GetPositions 获取所有USDT永续合约持仓（张数换算为基础币种数量，空仓为负数）
+func (t *OKXTrader) GetPositions() ([]map[string]interface{}, error) {
+	var data []struct {
+		InstID  string `json:"instId"`
+		PosSide string `json:"posSide"`
+		Pos     string `json:"pos"`
+		AvgPx   string `json:"avgPx"`
+		MarkPx  string `json:"markPx"`
+		Upl     string `json:"upl"`
+		Lever   string `json:"lever"`
+		LiqPx   string `json:"liqPx"`
+	}
+	if err := t.request(http.MethodGet, "/api/v5/account/positions", url.Values{"instType": {"SWAP"}}, &data); err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	var positions []map[string]interface{}
+	for _, pos := range data {
+		contracts, _ := strconv.ParseFloat(pos.Pos, 64)
+		if contracts == 0 || !strings.HasSuffix(pos.InstID, "-USDT-SWAP") {
+			continue
+		}
+		symbol := okxSymbol(pos.InstID)
+		inst, multiplier, err := t.instrument(symbol)
+		if err != nil {
+			log.Printf("  ⚠ %v", err)
+			continue
+		}
+
+		// 双向持仓按posSide判断方向（张数为正），单向持仓按正负判断
+		short := pos.PosSide == "short" || (pos.PosSide == "net" && contracts < 0)
+		amount := math.Abs(contracts) * inst.CtVal / multiplier
+		posMap := make(map[string]interface{})
+		posMap["symbol"] = symbol
+		if short {
+			posMap["side"] = "short"
+			posMap["positionAmt"] = -amount
+		} else {
+			posMap["side"] = "long"
+			posMap["positionAmt"] = amount
+		}
+		entry, _ := strconv.ParseFloat(pos.AvgPx, 64)
+		mark, _ := strconv.ParseFloat(pos.MarkPx, 64)
+		liq, _ := strconv.ParseFloat(pos.LiqPx, 64)
+		posMap["entryPrice"] = entry * multiplier
+		posMap["markPrice"] = mark * multiplier
+		posMap["liquidationPrice"] = liq * multiplier
+		posMap["unRealizedProfit"], _ = strconv.ParseFloat(pos.Upl, 64)
+		posMap["leverage"], _ = strconv.ParseFloat(pos.Lever, 64)
+		positions = append(positions, posMap)
+	}
+	return positions, nil
+}
+
+// SetLeverage 设置逐仓杠杆（双向持仓模式下多空分别设置）
+func (t *OKXTrader) SetLeverage(symbol string, leverage int) error {
+	inst, _, err := t.instrument(symbol)
+	if err != nil {
+		return err
+	}
+	sides := []string{""}
+	if t.longShortMode {
+		sides = []string{"long", "short"}
+	}
+	for _, side := range sides {
+		params := map[string]string{"instId": inst.InstID, "lever": strconv.Itoa(leverage), "mgnMode": okxMarginMode}
+		if side != "" {
+			params["posSide"] = side
+		}
+		if err := t.request(http.MethodPost, "/api/v5/account/set-leverage", params, nil); err != nil {
+			return fmt.Errorf("设置杠杆失败: %w", err)
+		}
+	}
+	log.Printf("  ✓ %s 杠杆已设置为 %dx", symbol, leverage)
+	return nil
+}
+
+// SetHedgeMode 切换持仓模式（true=双向持仓long_short_mode）
+func (t *OKXTrader) SetHedgeMode(enabled bool) error {
+	mode := "net_mode"
+	if enabled {
+		mode = "long_short_mode"
+	}
+	if err := t.request(http.MethodPost, "/api/v5/account/set-position-mode", map[string]string{"posMode": mode}, nil); err != nil {
+		return fmt.Errorf("切换持仓模式失败（有持仓或挂单时无法切换）: %w", err)
+	}
+	t.longShortMode = enabled
+	log.Printf("  ✓ 已切换为%s", t.posModeName())
+	return nil
+}
+
+// placeOrder 下单（clOrdId为生成的数字ID，作为统一接口返回的orderId）
+func (t *OKXTrader) placeOrder(symbol string, params map[string]interface{}) (map[string]interface{}, error) {
+	orderID := t.nextClOrdID()
+	params["tdMode"] = okxMarginMode
+	params["clOrdId"] = strconv.FormatInt(orderID, 10)
+
+	var data []struct {
+		OrdID string `json:"ordId"`
+	}
+	if err := t.request(http.MethodPost, "/api/v5/trade/order", params, &data); err != nil {
+		return nil, err
+	}
+
+	order := make(map[string]interface{})
+	order["orderId"] = orderID
+	if len(data) > 0 {
+		order["exchangeOrderId"] = data[0].OrdID
+	}
+	order["symbol"] = symbol
+	order["status"] = "NEW"
+	return order, nil
+}
+
+// OpenLong 开多仓
+func (t *OKXTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openMarket(symbol, "LONG", quantity, leverage)
+}
+
+// OpenShort 开空仓
+func (t *OKXTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openMarket(symbol, "SHORT", quantity, leverage)
+}
+
+// openMarket 市价开仓（先清理该方向旧的止损止盈单）
+func (t *OKXTrader) openMarket(symbol, positionSide string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if err := t.cancelOrders(symbol, positionSide); err != nil {
+		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+	sz, inst, _, err := t.contracts(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	side := "buy"
+	if positionSide == "SHORT" {
+		side = "sell"
+	}
+	order, err := t.placeOrder(symbol, map[string]interface{}{
+		"instId":  inst.InstID,
+		"side":    side,
+		"posSide": t.posSide(positionSide),
+		"ordType": "market",
+		"sz":      sz,
+	})
+	if err != nil {
+		if positionSide == "SHORT" {
+			return nil, fmt.Errorf("开空仓失败: %w", err)
+		}
+		return nil, fmt.Errorf("开多仓失败: %w", err)
+	}
+
+	if positionSide == "SHORT" {
+		log.Printf("✓ 开空仓成功: %s 数量: %s张", inst.InstID, sz)
+	} else {
+		log.Printf("✓ 开多仓成功: %s 数量: %s张", inst.InstID, sz)
+	}
+	log.Printf("  订单ID: %d", order["orderId"])
+	return order, nil
+}
+
+// PlaceLimitEntry 挂限价开仓单（GTC，成交后由调用方设置止损止盈）
+func (t *OKXTrader) PlaceLimitEntry(symbol string, positionSide string, quantity float64, leverage int, price float64) (map[string]interface{}, error) {
+	return t.placeLimitOrder(symbol, positionSide, quantity, leverage, price, "limit")
+}
+
+// PlacePostOnlyEntry 挂只做maker的限价开仓单（post_only，会立即成交时交易所撤销订单）
+func (t *OKXTrader) PlacePostOnlyEntry(symbol string, positionSide string, quantity float64, leverage int, price float64) (map[string]interface{}, error) {
+	return t.placeLimitOrder(symbol, positionSide, quantity, leverage, price, "post_only")
+}
+
+// placeLimitOrder 挂限价开仓单
+func (t *OKXTrader) placeLimitOrder(symbol string, positionSide string, quantity float64, leverage int, price float64, ordType string) (map[string]interface{}, error) {
+	// 不取消已有委托：同币种反方向持仓的止损止盈单需要保留
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+	sz, inst, multiplier, err := t.contracts(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	px := okxPrice(inst, multiplier, price)
+
+	side := "buy"
+	if positionSide == "SHORT" {
+		side = "sell"
+	}
+	order, err := t.placeOrder(symbol, map[string]interface{}{
+		"instId":  inst.InstID,
+		"side":    side,
+		"posSide": t.posSide(positionSide),
+		"ordType": ordType,
+		"sz":      sz,
+		"px":      px,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("挂限价开仓单失败: %w", err)
+	}
+
+	log.Printf("✓ 限价开仓单已挂出: %s %s 数量: %s张 价格: %s", inst.InstID, positionSide, sz, px)
+	log.Printf("  订单ID: %d", order["orderId"])
+	return order, nil
+}
+
+// CloseLong 平多仓（quantity=0表示全部平仓）
+func (t *OKXTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closeMarket(symbol, "LONG", quantity)
+}
+
+// CloseShort 平空仓（quantity=0表示全部平仓）
+func (t *OKXTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closeMarket(symbol, "SHORT", quantity)
+}
+
+// closeMarket 市价平仓，平仓后取消该方向的止损止盈单
+func (t *OKXTrader) closeMarket(symbol, positionSide string, quantity float64) (map[string]interface{}, error) {
+	side, posSide := "sell", "long"
+	if positionSide == "SHORT" {
+		side, posSide = "buy", "short"
+	}
+
+	if quantity == 0 {
+		positions, err := t.GetPositions()
+		if err != nil {
+			return nil, err
+		}
+		for _, pos := range positions {
+			if pos["symbol"] == symbol && pos["side"] == posSide {
+				quantity = math.Abs(pos["positionAmt"].(float64))
+				break
+			}
+		}
+		if quantity == 0 {
+			if posSide == "short" {
+				return nil, fmt.Errorf("没有找到 %s 的空仓", symbol)
+			}
+			return nil, fmt.Errorf("没有找到 %s 的多仓", symbol)
+		}
+	}
+
+	sz, inst, _, err := t.contracts(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	params := map[string]interface{}{
+		"instId":  inst.InstID,
+		"side":    side,
+		"posSide": t.posSide(positionSide),
+		"ordType": "market",
+		"sz":      sz,
+	}
+	if !t.longShortMode {
+		params["reduceOnly"] = true // 双向持仓模式下平仓方向由posSide决定，不接受reduceOnly
+	}
+	order, err := t.placeOrder(symbol, params)
+	if err != nil {
+		if posSide == "short" {
+			return nil, fmt.Errorf("平空仓失败: %w", err)
+		}
+		return nil, fmt.Errorf("平多仓失败: %w", err)
+	}
+
+	if posSide == "short" {
+		log.Printf("✓ 平空仓成功: %s 数量: %s张", inst.InstID, sz)
+	} else {
+		log.Printf("✓ 平多仓成功: %s 数量: %s张", inst.InstID, sz)
+	}
+
+	if err := t.cancelOrders(symbol, positionSide); err != nil {
+		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	}
+	return order, nil
+}
+
+// GetMarketPrice 获取最新成交价（倍数合约换算为统一价格）
+func (t *OKXTrader) GetMarketPrice(symbol string) (float64, error) {
+	inst, multiplier, err := t.instrument(symbol)
+	if err != nil {
+		return 0, err
+	}
+	var data []struct {
+		Last string `json:"last"`
+	}
+	if err := t.request(http.MethodGet, "/api/v5/market/ticker", url.Values{"instId": {inst.InstID}}, &data); err != nil {
+		return 0, fmt.Errorf("获取价格失败: %w", err)
+	}
+	if len(data) == 0 {
+		return 0, fmt.Errorf("未找到价格")
+	}
+	price, err := strconv.ParseFloat(data[0].Last, 64)
+	if err != nil {
+		return 0, err
+	}
+	return price * multiplier, nil
+}
+
+// SetStopLoss 设置止损单（条件委托，触发后市价平仓，按最新成交价触发）
+func (t *OKXTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	if err := t.placeAlgo(symbol, positionSide, quantity, map[string]float64{"sl": stopPrice}); err != nil {
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
+	log.Printf("  止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// SetTakeProfit 设置止盈单（条件委托，触发后市价平仓，按最新成交价触发）
+func (t *OKXTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	if err := t.placeAlgo(symbol, positionSide, quantity, map[string]float64{"tp": takeProfitPrice}); err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
+	}
+	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
+
+// SetProtection 同时设置止损和止盈（OCO委托：一个触发后OKX自动撤销另一个，价格为0的一项不设置）
+func (t *OKXTrader) SetProtection(symbol string, positionSide string, quantity, stopLoss, takeProfit float64) error {
+	triggers := make(map[string]float64)
+	if stopLoss > 0 {
+		triggers["sl"] = stopLoss
+	}
+	if takeProfit > 0 {
+		triggers["tp"] = takeProfit
+	}
+	if len(triggers) == 0 {
+		return nil
+	}
+	if err := t.placeAlgo(symbol, positionSide, quantity, triggers); err != nil {
+		return fmt.Errorf("设置止损止盈失败: %w", err)
+	}
+	log.Printf("  止损止盈设置(OCO): 止损 %.4f, 止盈 %.4f", stopLoss, takeProfit)
+	return nil
+}
+
+// placeAlgo 下平仓条件委托（triggers的键为"sl"或"tp"，两项都有时为OCO委托，委托价-1表示市价）
+func (t *OKXTrader) placeAlgo(symbol, positionSide string, quantity float64, triggers map[string]float64) error {
+	sz, inst, multiplier, err := t.contracts(symbol, quantity)
+	if err != nil {
+		return err
+	}
+	side := "sell"
+	if positionSide == "SHORT" {
+		side = "buy"
+	}
+	params := map[string]interface{}{
+		"instId":      inst.InstID,
+		"tdMode":      okxMarginMode,
+		"side":        side,
+		"posSide":     t.posSide(positionSide),
+		"ordType":     "conditional",
+		"sz":          sz,
+		"algoClOrdId": strconv.FormatInt(t.nextClOrdID(), 10),
+	}
+	if len(triggers) > 1 {
+		params["ordType"] = "oco"
+	}
+	for kind, triggerPrice := range triggers {
+		params[kind+"TriggerPx"] = okxPrice(inst, multiplier, triggerPrice)
+		params[kind+"OrdPx"] = "-1"
+		params[kind+"TriggerPxType"] = "last"
+	}
+	if !t.longShortMode {
+		params["reduceOnly"] = true
+	}
+	return t.request(http.MethodPost, "/api/v5/trade/order-algo", params, nil)
+}
+
+// CancelAllOrders 取消该币种的所有挂单和条件委托
+func (t *OKXTrader) CancelAllOrders(symbol string) error {
+	inst, _, err := t.instrument(symbol)
+	if err != nil {
+		return err
+	}
+
+	var pending []struct {
+		OrdID string `json:"ordId"`
+	}
+	if err := t.request(http.MethodGet, "/api/v5/trade/orders-pending", url.Values{"instType": {"SWAP"}, "instId": {inst.InstID}}, &pending); err != nil {
+		return fmt.Errorf("获取挂单失败: %w", err)
+	}
+	for _, order := range pending {
+		params := map[string]string{"instId": inst.InstID, "ordId": order.OrdID}
+		if err := t.request(http.MethodPost, "/api/v5/trade/cancel-order", params, nil); err != nil {
+			return fmt.Errorf("取消挂单失败: %w", err)
+		}
+	}
+	if err := t.cancelAlgos(inst.InstID, ""); err != nil {
+		return err
+	}
+
+	log.Printf("  ✓ 已取消 %s 的所有挂单", symbol)
+	return nil
+}
+
+// CancelOrder 取消指定挂单（orderID为下单时生成的clOrdId）
+func (t *OKXTrader) CancelOrder(symbol string, orderID int64) error {
+	inst, _, err := t.instrument(symbol)
+	if err != nil {
+		return err
+	}
+	params := map[string]string{"instId": inst.InstID, "clOrdId": strconv.FormatInt(orderID, 10)}
+	if err := t.request(http.MethodPost, "/api/v5/trade/cancel-order", params, nil); err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+	log.Printf("  ✓ 已取消 %s 订单 %d", symbol, orderID)
+	return nil
+}
+
+// GetOrderStatus 查询订单状态（orderID为下单时生成的clOrdId，状态和数量换算为币安的写法）
+func (t *OKXTrader) GetOrderStatus(symbol string, orderID int64) (map[string]interface{}, error) {
+	inst, multiplier, err := t.instrument(symbol)
+	if err != nil {
+		return nil, err
+	}
+	var data []struct {
+		State     string `json:"state"`
+		AccFillSz string `json:"accFillSz"`
+		AvgPx     string `json:"avgPx"`
+	}
+	query := url.Values{"instId": {inst.InstID}, "clOrdId": {strconv.FormatInt(orderID, 10)}}
+	if err := t.request(http.MethodGet, "/api/v5/trade/order", query, &data); err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("未找到订单 %d", orderID)
+	}
+
+	status := "NEW"
+	switch data[0].State {
+	case "partially_filled":
+		status = "PARTIALLY_FILLED"
+	case "filled":
+		status = "FILLED"
+	case "canceled", "mmp_canceled":
+		status = "CANCELED"
+	}
+	filled, _ := strconv.ParseFloat(data[0].AccFillSz, 64)
+	avgPrice, _ := strconv.ParseFloat(data[0].AvgPx, 64)
+	return map[string]interface{}{
+		"orderId":     orderID,
+		"symbol":      symbol,
+		"status":      status,
+		"executedQty": filled * inst.CtVal / multiplier,
+		"avgPrice":    avgPrice * multiplier,
+	}, nil
+}
+
+// CancelProtectiveOrders 只取消该币种的止损止盈条件委托（positionSide为空时不限方向），保留限价开仓单
+func (t *OKXTrader) CancelProtectiveOrders(symbol string, positionSide string) error {
+	inst, _, err := t.instrument(symbol)
+	if err != nil {
+		return err
+	}
+	posSide := ""
+	if positionSide != "" && t.longShortMode {
+		posSide = t.posSide(positionSide)
+	}
+	return t.cancelAlgos(inst.InstID, posSide)
+}
+
+// cancelAlgos 取消条件委托和OCO委托（posSide为空时不限方向）
+func (t *OKXTrader) cancelAlgos(instID, posSide string) error {
+	var pending []struct {
+		AlgoID  string `json:"algoId"`
+		PosSide string `json:"posSide"`
+	}
+	query := url.Values{"ordType": {"conditional,oco"}, "instType": {"SWAP"}, "instId": {instID}}
+	if err := t.request(http.MethodGet, "/api/v5/trade/orders-algo-pending", query, &pending); err != nil {
+		return fmt.Errorf("获取条件委托失败: %w", err)
+	}
+
+	var cancels []map[string]string
+	for _, algo := range pending {
+		if posSide != "" && algo.PosSide != posSide {
+			continue
+		}
+		cancels = append(cancels, map[string]string{"algoId": algo.AlgoID, "instId": instID})
+	}
+	if len(cancels) > 0 {
+		if err := t.request(http.MethodPost, "/api/v5/trade/cancel-algos", cancels, nil); err != nil {
+			return fmt.Errorf("取消条件委托失败: %w", err)
+		}
+	}
+	log.Printf("  ✓ 已取消 %s 的 %d 个止损止盈单", instID, len(cancels))
+	return nil
+}
+
+// cancelOrders 开平仓时清理止损止盈单：双向持仓只撤该方向，限价开仓单始终保留
+func (t *OKXTrader) cancelOrders(symbol, positionSide string) error {
+	if t.longShortMode {
+		return t.CancelProtectiveOrders(symbol, positionSide)
+	}
+	return t.CancelProtectiveOrders(symbol, "")
+}
+
+// FormatQuantity 按OKX合约面值和张数步进格式化数量（返回基础币种数量，向下取整到整张）
+func (t *OKXTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	inst, multiplier, err := t.instrument(symbol)
+	if err != nil {
+		return "", err
+	}
+	if inst.CtVal <= 0 || inst.LotSz <= 0 {
+		return fmt.Sprintf("%.3f", quantity), nil
+	}
+	step := inst.LotSz * inst.CtVal / multiplier
+	steps := math.Floor(quantity/step + 1e-9)
+	return strconv.FormatFloat(steps*step, 'f', stepDecimals(step), 64), nil
+}
@@ -0,0 +1,183 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// OrderState 订单生命周期状态
+type OrderState string
+
+const (
+	OrderSubmitted       OrderState = "submitted"        // 已提交，尚未成交
+	OrderPartiallyFilled OrderState = "partially_filled" // 部分成交，剩余部分仍在挂单
+	OrderFilled          OrderState = "filled"           // 全部成交
+	OrderCancelled       OrderState = "cancelled"        // 已撤销（可能部分成交，见Filled）
+	OrderRejected        OrderState = "rejected"         // 被交易所拒绝或未成交即失效
+)
+
+// orderTransitions 允许的状态转换（filled、cancelled、rejected为终态）
+var orderTransitions = map[OrderState][]OrderState{
+	OrderSubmitted:       {OrderPartiallyFilled, OrderFilled, OrderCancelled, OrderRejected},
+	OrderPartiallyFilled: {OrderPartiallyFilled, OrderFilled, OrderCancelled},
+}
+
+// 成交确认参数
+const (
+	orderFillTolerance = 0.999                  // 成交数量达到请求数量的99.9%视为全部成交（交易所按精度截断数量）
+	orderStatusPolls   = 3                      // 市价单未立即返回终态时查询订单状态的次数
+	orderStatusDelay   = 500 * time.Millisecond // 两次查询的间隔
+)
+
+// orderStatusQuerier 能按订单ID查询成交状态的交易器（币安、Bybit、OKX）：
+// 返回status（币安订单状态）、executedQty（已成交数量）和avgPrice（成交均价）
+type orderStatusQuerier interface {
+	GetOrderStatus(symbol string, orderID int64) (map[string]interface{}, error)
+}
+
+// trackedOrder 跟踪中的订单（开仓或平仓）
+type trackedOrder struct {
+	OrderID   int64      `json:"order_id"`
+	Symbol    string     `json:"symbol"`
+	Side      string     `json:"side"` // 持仓方向 "long" 或 "short"
+	Requested float64    `json:"requested"`
+	Filled    float64    `json:"filled"`
+	AvgPrice  float64    `json:"avg_price,omitempty"`
+	State     OrderState `json:"state"`
+}
+
+// newTrackedOrder 根据下单结果创建订单跟踪（结果中没有成交信息时为submitted）
+func newTrackedOrder(symbol, side string, requested float64, result map[string]interface{}) *trackedOrder {
+	o := &trackedOrder{
+		OrderID:   orderIDOf(result),
+		Symbol:    symbol,
+		Side:      side,
+		Requested: requested,
+		State:     OrderSubmitted,
+	}
+	o.applyResult(result)
+	return o
+}
+
+// terminal 是否已到终态
+func (o *trackedOrder) terminal() bool {
+	_, ok := orderTransitions[o.State]
+	return !ok
+}
+
+// transition 转换状态（不允许的转换被忽略并返回false，已成交数量只增不减）
+func (o *trackedOrder) transition(state OrderState, filled, avgPrice float64) bool {
+	allowed := false
+	for _, next := range orderTransitions[o.State] {
+		if next == state {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return false
+	}
+	if state != o.State {
+		log.Printf("  📋 订单 %d (%s %s): %s → %s, 成交 %.4f / %.4f", o.OrderID, o.Symbol, o.Side, o.State, state, math.Max(filled, o.Filled), o.Requested)
+	}
+	o.State = state
+	if filled > o.Filled {
+		o.Filled = filled
+	}
+	if avgPrice > 0 {
+		o.AvgPrice = avgPrice
+	}
+	return true
+}
+
+// applyResult 按交易所返回的订单状态（币安写法）更新
+func (o *trackedOrder) applyResult(result map[string]interface{}) {
+	status, _ := result["status"].(string)
+	filled, _ := result["executedQty"].(float64)
+	avgPrice, _ := result["avgPrice"].(float64)
+
+	switch status {
+	case "FILLED":
+		o.transition(OrderFilled, filled, avgPrice)
+	case "PARTIALLY_FILLED":
+		o.transition(OrderPartiallyFilled, filled, avgPrice)
+	case "CANCELED", "EXPIRED":
+		o.transition(OrderCancelled, filled, avgPrice)
+	case "REJECTED":
+		o.transition(OrderRejected, filled, avgPrice)
+	default:
+		if filled > 0 {
+			o.transition(OrderPartiallyFilled, filled, avgPrice)
+		}
+	}
+	// FILLED但没有返回成交数量的交易器（Aster等）按请求数量计
+	if o.State == OrderFilled && o.Filled == 0 {
+		o.Filled = o.Requested
+	}
+}
+
+// applyFilled 按持仓变化得到的成交数量更新（市价单剩余部分不会挂单，视为撤销）
+func (o *trackedOrder) applyFilled(filled float64, final bool) {
+	switch {
+	case filled >= o.Requested*orderFillTolerance:
+		o.transition(OrderFilled, filled, 0)
+	case filled > 0 && final:
+		o.transition(OrderCancelled, filled, 0)
+	case filled > 0:
+		o.transition(OrderPartiallyFilled, filled, 0)
+	case final:
+		o.transition(OrderRejected, 0, 0)
+	}
+}
+
+// awaitMarketFill 确认市价单的成交结果：
+// 支持查询订单的交易器轮询订单状态，否则（或仍未到终态时）按下单前后的持仓数量差计算成交数量
+func (at *AutoTrader) awaitMarketFill(o *trackedOrder, positionBefore float64) {
+	if qr, ok := at.trader.(orderStatusQuerier); ok && o.OrderID != 0 {
+		for i := 0; i < orderStatusPolls && !o.terminal(); i++ {
+			time.Sleep(orderStatusDelay)
+			result, err := qr.GetOrderStatus(o.Symbol, o.OrderID)
+			if err != nil {
+				log.Printf("  ⚠ 查询订单 %d 状态失败: %v", o.OrderID, err)
+				break
+			}
+			o.applyResult(result)
+		}
+	}
+	if o.terminal() {
+		return
+	}
+
+	positionAfter, err := at.positionQuantity(o.Symbol, o.Side)
+	if err != nil {
+		log.Printf("  ⚠ 确认订单 %d 成交时获取持仓失败，按请求数量计: %v", o.OrderID, err)
+		o.applyFilled(o.Requested, true)
+		return
+	}
+	o.applyFilled(math.Abs(positionAfter-positionBefore), true)
+}
+
+// positionQuantity 当前持仓数量（没有持仓时为0）
+func (at *AutoTrader) positionQuantity(symbol, side string) (float64, error) {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return 0, err
+	}
+	for _, pos := range positions {
+		if pos["symbol"] == symbol && pos["side"] == side {
+			quantity, _ := pos["positionAmt"].(float64)
+			return math.Abs(quantity), nil
+		}
+	}
+	return 0, nil
+}
+
+// fillSummary 成交结果的日志描述
+func (o *trackedOrder) fillSummary() string {
+	if o.AvgPrice > 0 {
+		return fmt.Sprintf("%s %.4f / %.4f @ %.4f", o.State, o.Filled, o.Requested, o.AvgPrice)
+	}
+	return fmt.Sprintf("%s %.4f / %.4f", o.State, o.Filled, o.Requested)
+}
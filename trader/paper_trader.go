@@ -0,0 +1,643 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"nofx/market"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 模拟交易默认参数（与币安合约普通用户费率一致）
+const (
+	defaultPaperTakerFeePct    = 0.05 // 市价单、止损止盈单手续费（%）
+	defaultPaperMakerFeePct    = 0.02 // 限价挂单成交手续费（%）
+	defaultPaperSlippagePct    = 0.02 // 市价成交滑点（%）
+	paperMaintenanceMarginRate = 0.005
+	paperFundingInterval       = 8 * time.Hour   // 资金费结算间隔（UTC 0/8/16点）
+	paperSyncInterval          = 5 * time.Second // 两次按行情撮合之间的最小间隔
+	paperMaxCatchUpCandles     = 1000            // 补撮合时最多回看的1分钟K线数
+)
+
+// PaperOptions 模拟交易参数（0值使用默认值）
+type PaperOptions struct {
+	TakerFeePct float64 // 市价单手续费（%，默认0.05）
+	MakerFeePct float64 // 限价挂单手续费（%，默认0.02）
+	SlippagePct float64 // 市价成交滑点（%，默认0.02）
+}
+
+// paperPosition 模拟持仓（逐仓）
+type paperPosition struct {
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"` // "long" 或 "short"
+	Quantity   float64 `json:"quantity"`
+	EntryPrice float64 `json:"entry_price"`
+	Leverage   int     `json:"leverage"`
+	StopLoss   float64 `json:"stop_loss,omitempty"`
+	TakeProfit float64 `json:"take_profit,omitempty"`
+	OpenedAt   int64   `json:"opened_at"` // 毫秒
+}
+
+// paperOrder 未成交的模拟限价开仓单
+type paperOrder struct {
+	ID       int64   `json:"id"`
+	Symbol   string  `json:"symbol"`
+	Side     string  `json:"side"` // "long" 或 "short"
+	Quantity float64 `json:"quantity"`
+	Price    float64 `json:"price"`
+	Leverage int     `json:"leverage"`
+	PlacedAt int64   `json:"placed_at"` // 毫秒
+}
+
+// paperAccount 模拟账户（落盘保存，重启后继续）
+type paperAccount struct {
+	WalletBalance float64                   `json:"wallet_balance"` // 钱包余额（已含已实现盈亏、手续费和资金费）
+	Positions     map[string]*paperPosition `json:"positions"`      // symbol_side -> 持仓
+	Orders        []*paperOrder             `json:"orders,omitempty"`
+	Leverage      map[string]int            `json:"leverage,omitempty"` // symbol -> 杠杆
+	NextOrderID   int64                     `json:"next_order_id"`
+	LastMatched   int64                     `json:"last_matched"` // 最近一次按行情撮合的时间（毫秒）
+	LastFunding   int64                     `json:"last_funding"` // 最近一次结算资金费的时间点（毫秒）
+
+	RealizedPnL float64 `json:"realized_pnl"` // 累计已实现盈亏（不含手续费和资金费）
+	FeesPaid    float64 `json:"fees_paid"`    // 累计手续费
+	FundingPaid float64 `json:"funding_paid"` // 累计资金费（正数为支付）
+	Trades      int     `json:"trades"`       // 成交笔数
+}
+
+// PaperTrader 模拟交易器：按实时行情撮合市价单、限价单、止损止盈、强平，并按资金费率结算，不下真实订单
+type PaperTrader struct {
+	mu        sync.Mutex
+	account   paperAccount
+	options   PaperOptions
+	stateFile string
+	lastSync  time.Time
+}
+
+// NewPaperTrader 创建模拟交易器（stateFile已存在时恢复账户，否则以initialBalance开始）
+func NewPaperTrader(initialBalance float64, stateFile string, options PaperOptions) (*PaperTrader, error) {
+	if options.TakerFeePct == 0 {
+		options.TakerFeePct = defaultPaperTakerFeePct
+	}
+	if options.MakerFeePct == 0 {
+		options.MakerFeePct = defaultPaperMakerFeePct
+	}
+	if options.SlippagePct == 0 {
+		options.SlippagePct = defaultPaperSlippagePct
+	}
+	t := &PaperTrader{options: options, stateFile: stateFile}
+
+	data, err := os.ReadFile(stateFile)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &t.account); err != nil {
+			return nil, fmt.Errorf("解析模拟账户失败: %w", err)
+		}
+		log.Printf("📂 已恢复模拟账户: 余额 %.2f USDT, %d个持仓, %d个挂单", t.account.WalletBalance, len(t.account.Positions), len(t.account.Orders))
+	case os.IsNotExist(err):
+		now := time.Now().UnixMilli()
+		t.account = paperAccount{WalletBalance: initialBalance, NextOrderID: 1, LastMatched: now, LastFunding: now}
+	default:
+		return nil, fmt.Errorf("读取模拟账户失败: %w", err)
+	}
+	if t.account.Positions == nil {
+		t.account.Positions = make(map[string]*paperPosition)
+	}
+	if t.account.Leverage == nil {
+		t.account.Leverage = make(map[string]int)
+	}
+	return t, nil
+}
+
+// paperStateFile 模拟账户文件路径（与持仓状态放在同一子目录）
+func paperStateFile(logDir string) string {
+	return filepath.Join(logDir, "state", "paper_account.json")
+}
+
+// GetBalance 获取模拟账户余额（先按最新行情撮合）
+func (t *PaperTrader) GetBalance() (map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sync()
+
+	unrealized, margin := 0.0, 0.0
+	for _, pos := range t.account.Positions {
+		mark := t.markPrice(pos.Symbol, pos.EntryPrice)
+		unrealized += pos.pnl(mark)
+		margin += pos.Quantity * pos.EntryPrice / float64(pos.Leverage)
+	}
+	for _, order := range t.account.Orders {
+		margin += order.Quantity * order.Price / float64(order.Leverage)
+	}
+	available := t.account.WalletBalance + math.Min(unrealized, 0) - margin
+	if available < 0 {
+		available = 0
+	}
+
+	return map[string]interface{}{
+		"totalWalletBalance":    t.account.WalletBalance,
+		"availableBalance":      available,
+		"totalUnrealizedProfit": unrealized,
+		"paperRealizedPnl":      t.account.RealizedPnL,
+		"paperFeesPaid":         t.account.FeesPaid,
+		"paperFundingPaid":      t.account.FundingPaid,
+		"paperTrades":           t.account.Trades,
+	}, nil
+}
+
+// GetPositions 获取模拟持仓（格式与币安一致，空仓数量为负）
+func (t *PaperTrader) GetPositions() ([]map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sync()
+
+	keys := make([]string, 0, len(t.account.Positions))
+	for key := range t.account.Positions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var result []map[string]interface{}
+	for _, key := range keys {
+		pos := t.account.Positions[key]
+		mark := t.markPrice(pos.Symbol, pos.EntryPrice)
+		amount := pos.Quantity
+		if pos.Side == "short" {
+			amount = -amount
+		}
+		result = append(result, map[string]interface{}{
+			"symbol":           pos.Symbol,
+			"side":             pos.Side,
+			"positionAmt":      amount,
+			"entryPrice":       pos.EntryPrice,
+			"markPrice":        mark,
+			"unRealizedProfit": pos.pnl(mark),
+			"leverage":         float64(pos.Leverage),
+			"liquidationPrice": pos.liquidationPrice(),
+		})
+	}
+	return result, nil
+}
+
+// OpenLong 模拟市价开多
+func (t *PaperTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openMarket(symbol, "long", quantity, leverage)
+}
+
+// OpenShort 模拟市价开空
+func (t *PaperTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openMarket(symbol, "short", quantity, leverage)
+}
+
+// openMarket 按最新价加滑点成交，收取taker手续费
+func (t *PaperTrader) openMarket(symbol, side string, quantity float64, leverage int) (map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sync()
+
+	price, err := market.GetPrice(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s 价格失败: %w", symbol, err)
+	}
+	fill := t.slipped(price, side == "long")
+	if err := t.checkMargin(quantity*fill/float64(leverage) + quantity*fill*t.options.TakerFeePct/100); err != nil {
+		return nil, err
+	}
+	t.fillEntry(symbol, side, quantity, fill, leverage, t.options.TakerFeePct, time.Now().UnixMilli())
+	log.Printf("📝 [模拟] 开%s %s: %.4f @ %.4f（%dx）", sideName(side), symbol, quantity, fill, leverage)
+	return t.orderResult(symbol, fill, quantity, "FILLED"), nil
+}
+
+// PlaceLimitEntry 模拟限价开仓单：价格已可成交时立即按市价成交，否则挂单等待行情触及
+func (t *PaperTrader) PlaceLimitEntry(symbol string, positionSide string, quantity float64, leverage int, price float64) (map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sync()
+
+	side := strings.ToLower(positionSide)
+	current, err := market.GetPrice(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s 价格失败: %w", symbol, err)
+	}
+	if err := t.checkMargin(quantity * price / float64(leverage)); err != nil {
+		return nil, err
+	}
+	if (side == "long" && current <= price) || (side == "short" && current >= price) {
+		fill := t.slipped(current, side == "long")
+		t.fillEntry(symbol, side, quantity, fill, leverage, t.options.TakerFeePct, time.Now().UnixMilli())
+		log.Printf("📝 [模拟] 限价单立即成交 开%s %s: %.4f @ %.4f", sideName(side), symbol, quantity, fill)
+		return t.orderResult(symbol, fill, quantity, "FILLED"), nil
+	}
+
+	order := &paperOrder{
+		ID:       t.account.NextOrderID,
+		Symbol:   symbol,
+		Side:     side,
+		Quantity: quantity,
+		Price:    price,
+		Leverage: leverage,
+		PlacedAt: time.Now().UnixMilli(),
+	}
+	t.account.NextOrderID++
+	t.account.Orders = append(t.account.Orders, order)
+	t.save()
+	log.Printf("📝 [模拟] 挂限价开%s单 %s: %.4f @ %.4f", sideName(side), symbol, quantity, price)
+	result := t.orderResult(symbol, price, quantity, "NEW")
+	result["orderId"] = order.ID
+	return result, nil
+}
+
+// CloseLong 模拟市价平多（quantity=0表示全部平仓）
+func (t *PaperTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closeMarket(symbol, "long", quantity)
+}
+
+// CloseShort 模拟市价平空（quantity=0表示全部平仓）
+func (t *PaperTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closeMarket(symbol, "short", quantity)
+}
+
+// closeMarket 按最新价加滑点平仓，收取taker手续费
+func (t *PaperTrader) closeMarket(symbol, side string, quantity float64) (map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sync()
+
+	pos := t.account.Positions[symbol+"_"+side]
+	if pos == nil {
+		return nil, fmt.Errorf("没有找到 %s 的%s仓", symbol, sideName(side))
+	}
+	price, err := market.GetPrice(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s 价格失败: %w", symbol, err)
+	}
+	if quantity <= 0 || quantity > pos.Quantity {
+		quantity = pos.Quantity
+	}
+	fill := t.slipped(price, side == "short")
+	t.fillExit(pos, quantity, fill, t.options.TakerFeePct, "平仓")
+	return t.orderResult(symbol, fill, quantity, "FILLED"), nil
+}
+
+// SetLeverage 设置模拟杠杆（对之后的开仓生效）
+func (t *PaperTrader) SetLeverage(symbol string, leverage int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.account.Leverage[symbol] = leverage
+	return nil
+}
+
+// GetMarketPrice 获取最新价格（实时行情）
+func (t *PaperTrader) GetMarketPrice(symbol string) (float64, error) {
+	return market.GetPrice(symbol)
+}
+
+// SetStopLoss 设置模拟止损（作用于整个持仓，按1分钟K线和最新价触发）
+func (t *PaperTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pos := t.account.Positions[symbol+"_"+strings.ToLower(positionSide)]
+	if pos == nil {
+		return fmt.Errorf("没有找到 %s %s 持仓", symbol, positionSide)
+	}
+	pos.StopLoss = stopPrice
+	t.save()
+	return nil
+}
+
+// SetTakeProfit 设置模拟止盈（作用于整个持仓）
+func (t *PaperTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pos := t.account.Positions[symbol+"_"+strings.ToLower(positionSide)]
+	if pos == nil {
+		return fmt.Errorf("没有找到 %s %s 持仓", symbol, positionSide)
+	}
+	pos.TakeProfit = takeProfitPrice
+	t.save()
+	return nil
+}
+
+// CancelAllOrders 撤销该币种的所有挂单（止损止盈和限价开仓单）
+func (t *PaperTrader) CancelAllOrders(symbol string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, pos := range t.account.Positions {
+		if pos.Symbol == symbol {
+			pos.StopLoss, pos.TakeProfit = 0, 0
+		}
+	}
+	kept := t.account.Orders[:0]
+	for _, order := range t.account.Orders {
+		if order.Symbol != symbol {
+			kept = append(kept, order)
+		}
+	}
+	t.account.Orders = kept
+	t.save()
+	return nil
+}
+
+// CancelOrder 撤销指定的限价开仓单
+func (t *PaperTrader) CancelOrder(symbol string, orderID int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, order := range t.account.Orders {
+		if order.ID == orderID && order.Symbol == symbol {
+			t.account.Orders = append(t.account.Orders[:i], t.account.Orders[i+1:]...)
+			t.save()
+			return nil
+		}
+	}
+	return fmt.Errorf("没有找到 %s 的挂单 %d", symbol, orderID)
+}
+
+// FormatQuantity 按交易规则的数量步进格式化（没有交易规则时保留3位小数）
+func (t *PaperTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	if info, err := market.GetSymbolInfo(symbol); err == nil && info != nil {
+		return info.FormatQuantity(info.FloorQuantity(quantity)), nil
+	}
+	return strconv.FormatFloat(quantity, 'f', 3, 64), nil
+}
+
+// sync 按行情撮合：补上次撮合以来的1分钟K线（限价单成交、止损止盈、强平），再按最新价检查一次，最后结算资金费
+// 调用方需持有锁；两次撮合至少间隔paperSyncInterval
+func (t *PaperTrader) sync() {
+	now := time.Now()
+	if now.Sub(t.lastSync) < paperSyncInterval {
+		return
+	}
+	t.lastSync = now
+
+	for _, symbol := range t.activeSymbols() {
+		since := t.account.LastMatched
+		minutes := int(now.Sub(time.UnixMilli(since)).Minutes()) + 1
+		if minutes > paperMaxCatchUpCandles {
+			log.Printf("⚠ [模拟] %s 距上次撮合%d分钟，只回看最近%d根1分钟K线", symbol, minutes, paperMaxCatchUpCandles)
+			minutes = paperMaxCatchUpCandles
+		}
+		klines, err := market.GetKlines(symbol, market.Interval1m, minutes)
+		if err != nil {
+			log.Printf("⚠ [模拟] 获取 %s K线失败，本次只按最新价撮合: %v", symbol, err)
+		}
+		for _, k := range klines {
+			if k.CloseTime >= since {
+				t.match(symbol, k.OpenTime, k.High, k.Low)
+			}
+		}
+		if price, err := market.GetPrice(symbol); err == nil {
+			t.match(symbol, now.UnixMilli(), price, price)
+		}
+	}
+	t.account.LastMatched = now.UnixMilli()
+	t.settleFunding(now)
+	t.save()
+}
+
+// activeSymbols 有持仓或挂单的币种
+func (t *PaperTrader) activeSymbols() []string {
+	seen := make(map[string]bool)
+	var symbols []string
+	add := func(symbol string) {
+		if !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	for _, pos := range t.account.Positions {
+		add(pos.Symbol)
+	}
+	for _, order := range t.account.Orders {
+		add(order.Symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// match 用一段行情（K线的最高/最低价，或最新价）撮合该币种的挂单和持仓
+// 只使用下单/开仓之后开始的K线；同一根K线同时触及止损和止盈时按止损处理（保守）
+func (t *PaperTrader) match(symbol string, at int64, high, low float64) {
+	kept := t.account.Orders[:0]
+	for _, order := range t.account.Orders {
+		touched := (order.Side == "long" && low <= order.Price) || (order.Side == "short" && high >= order.Price)
+		if order.Symbol != symbol || at < order.PlacedAt || !touched {
+			kept = append(kept, order)
+			continue
+		}
+		t.fillEntry(symbol, order.Side, order.Quantity, order.Price, order.Leverage, t.options.MakerFeePct, at)
+		log.Printf("📝 [模拟] 限价单成交 开%s %s: %.4f @ %.4f", sideName(order.Side), symbol, order.Quantity, order.Price)
+	}
+	t.account.Orders = kept
+
+	for _, side := range []string{"long", "short"} {
+		pos := t.account.Positions[symbol+"_"+side]
+		if pos == nil || at < pos.OpenedAt {
+			continue
+		}
+		liquidation := pos.liquidationPrice()
+		if side == "long" {
+			stop := math.Max(pos.StopLoss, liquidation)
+			switch {
+			case low <= stop && stop == liquidation:
+				t.fillExit(pos, pos.Quantity, liquidation, t.options.TakerFeePct, "强平")
+			case low <= stop:
+				t.fillExit(pos, pos.Quantity, t.slipped(pos.StopLoss, false), t.options.TakerFeePct, "止损")
+			case pos.TakeProfit > 0 && high >= pos.TakeProfit:
+				t.fillExit(pos, pos.Quantity, pos.TakeProfit, t.options.TakerFeePct, "止盈")
+			}
+			continue
+		}
+		stop := liquidation
+		if pos.StopLoss > 0 {
+			stop = math.Min(pos.StopLoss, liquidation)
+		}
+		switch {
+		case high >= stop && stop == liquidation:
+			t.fillExit(pos, pos.Quantity, liquidation, t.options.TakerFeePct, "强平")
+		case high >= stop:
+			t.fillExit(pos, pos.Quantity, t.slipped(pos.StopLoss, true), t.options.TakerFeePct, "止损")
+		case pos.TakeProfit > 0 && low <= pos.TakeProfit:
+			t.fillExit(pos, pos.Quantity, pos.TakeProfit, t.options.TakerFeePct, "止盈")
+		}
+	}
+}
+
+// settleFunding 结算上次结算以来经过的每个资金费时间点（按结算时的标记价格和当前资金费率）
+func (t *PaperTrader) settleFunding(now time.Time) {
+	last := time.UnixMilli(t.account.LastFunding)
+	next := last.Truncate(paperFundingInterval).Add(paperFundingInterval)
+	for ; !next.After(now); next = next.Add(paperFundingInterval) {
+		for _, pos := range t.account.Positions {
+			if pos.OpenedAt > next.UnixMilli() {
+				continue
+			}
+			premium, err := market.GetPremiumIndex(pos.Symbol)
+			if err != nil {
+				log.Printf("⚠ [模拟] 获取 %s 资金费率失败，跳过本次结算: %v", pos.Symbol, err)
+				continue
+			}
+			payment := pos.Quantity * premium.MarkPrice * premium.FundingRate
+			if pos.Side == "short" {
+				payment = -payment
+			}
+			t.account.WalletBalance -= payment
+			t.account.FundingPaid += payment
+			log.Printf("📝 [模拟] %s %s 资金费 %+.4f USDT（费率 %.4f%%）", pos.Symbol, sideName(pos.Side), -payment, premium.FundingRate*100)
+		}
+	}
+	t.account.LastFunding = now.UnixMilli()
+}
+
+// fillEntry 开仓成交（已有同方向持仓时加仓，按数量加权计算开仓均价）。
+// filledAt为成交时间（毫秒）：补撮合K线时为成交所在K线的时间，之后的K线继续撮合止损止盈
+func (t *PaperTrader) fillEntry(symbol, side string, quantity, price float64, leverage int, feePct float64, filledAt int64) {
+	if leverage <= 0 {
+		leverage = t.account.Leverage[symbol]
+	}
+	if leverage <= 0 {
+		leverage = 1
+	}
+	fee := quantity * price * feePct / 100
+	t.account.WalletBalance -= fee
+	t.account.FeesPaid += fee
+	t.account.Trades++
+
+	key := symbol + "_" + side
+	if pos := t.account.Positions[key]; pos != nil {
+		total := pos.Quantity + quantity
+		pos.EntryPrice = (pos.EntryPrice*pos.Quantity + price*quantity) / total
+		pos.Quantity = total
+		pos.Leverage = leverage
+	} else {
+		t.account.Positions[key] = &paperPosition{
+			Symbol:     symbol,
+			Side:       side,
+			Quantity:   quantity,
+			EntryPrice: price,
+			Leverage:   leverage,
+			OpenedAt:   filledAt,
+		}
+	}
+	t.save()
+}
+
+// fillExit 平仓成交（全部平仓时删除持仓）
+func (t *PaperTrader) fillExit(pos *paperPosition, quantity, price, feePct float64, reason string) {
+	pnl := (price - pos.EntryPrice) * quantity
+	if pos.Side == "short" {
+		pnl = -pnl
+	}
+	fee := quantity * price * feePct / 100
+	t.account.WalletBalance += pnl - fee
+	t.account.RealizedPnL += pnl
+	t.account.FeesPaid += fee
+	t.account.Trades++
+
+	pos.Quantity -= quantity
+	if pos.Quantity <= 1e-12 {
+		delete(t.account.Positions, pos.Symbol+"_"+pos.Side)
+	}
+	log.Printf("📝 [模拟] %s %s %s: %.4f @ %.4f, 盈亏 %+.2f USDT, 手续费 %.4f", reason, pos.Symbol, sideName(pos.Side), quantity, price, pnl, fee)
+	t.save()
+}
+
+// checkMargin 可用保证金是否足够（调用方需持有锁）
+func (t *PaperTrader) checkMargin(required float64) error {
+	used, unrealized := 0.0, 0.0
+	for _, pos := range t.account.Positions {
+		used += pos.Quantity * pos.EntryPrice / float64(pos.Leverage)
+		unrealized += pos.pnl(t.markPrice(pos.Symbol, pos.EntryPrice))
+	}
+	for _, order := range t.account.Orders {
+		used += order.Quantity * order.Price / float64(order.Leverage)
+	}
+	available := t.account.WalletBalance + math.Min(unrealized, 0) - used
+	if required > available {
+		return fmt.Errorf("模拟账户保证金不足: 需要 %.2f USDT，可用 %.2f USDT", required, available)
+	}
+	return nil
+}
+
+// markPrice 标记价格（获取失败时使用fallback）
+func (t *PaperTrader) markPrice(symbol string, fallback float64) float64 {
+	if premium, err := market.GetPremiumIndex(symbol); err == nil && premium.MarkPrice > 0 {
+		return premium.MarkPrice
+	}
+	if price, err := market.GetPrice(symbol); err == nil {
+		return price
+	}
+	return fallback
+}
+
+// slipped 市价成交价（买入加滑点，卖出减滑点）
+func (t *PaperTrader) slipped(price float64, buy bool) float64 {
+	if buy {
+		return price * (1 + t.options.SlippagePct/100)
+	}
+	return price * (1 - t.options.SlippagePct/100)
+}
+
+// orderResult 订单结果（格式与币安一致）
+func (t *PaperTrader) orderResult(symbol string, price, quantity float64, status string) map[string]interface{} {
+	id := t.account.NextOrderID
+	t.account.NextOrderID++
+	return map[string]interface{}{
+		"orderId":     id,
+		"symbol":      symbol,
+		"status":      status,
+		"avgPrice":    price,
+		"executedQty": quantity,
+	}
+}
+
+// save 保存模拟账户（失败只打印日志）
+func (t *PaperTrader) save() {
+	data, err := json.MarshalIndent(t.account, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(t.stateFile), 0755); err != nil {
+		log.Printf("⚠ [模拟] 创建状态目录失败: %v", err)
+		return
+	}
+	tmp := t.stateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		log.Printf("⚠ [模拟] 保存模拟账户失败: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, t.stateFile); err != nil {
+		log.Printf("⚠ [模拟] 保存模拟账户失败: %v", err)
+	}
+}
+
+// pnl 按标记价格计算的未实现盈亏
+func (p *paperPosition) pnl(mark float64) float64 {
+	if p.Side == "short" {
+		return (p.EntryPrice - mark) * p.Quantity
+	}
+	return (mark - p.EntryPrice) * p.Quantity
+}
+
+// liquidationPrice 逐仓强平价格（维持保证金率按0.5%估算）
+func (p *paperPosition) liquidationPrice() float64 {
+	lev := float64(p.Leverage)
+	if p.Side == "short" {
+		return p.EntryPrice * (1 + 1/lev - paperMaintenanceMarginRate)
+	}
+	return p.EntryPrice * (1 - 1/lev + paperMaintenanceMarginRate)
+}
+
+// sideName 方向的中文名称
+func sideName(side string) string {
+	if side == "short" {
+		return "空"
+	}
+	return "多"
+}
@@ -0,0 +1,91 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"os"
+	"path/filepath"
+)
+
+// positionState 持仓的开仓计划与跟踪状态（落盘保存，重启后AI仍能看到原始的止损、止盈和失效条件）
+type positionState struct {
+	FirstSeenTime  map[string]int64                  `json:"first_seen_time"`            // symbol_side -> 开仓时间（毫秒）
+	ExitPlans      map[string]*decision.PositionInfo `json:"exit_plans"`                 // symbol_side -> 开仓时的退出计划
+	TrailingPeaks  map[string]float64                `json:"trailing_peaks,omitempty"`   // symbol_side -> 移动止损最优价格
+	LastCloseTimes map[string]int64                  `json:"last_close_times,omitempty"` // symbol -> 最近平仓时间（毫秒）
+	PendingEntries map[string]*pendingEntry          `json:"pending_entries,omitempty"`  // symbol_side -> 未成交的限价开仓单
+}
+
+// positionStateFile 持仓状态文件路径（放在子目录中，避免被决策日志读取）
+func positionStateFile(logDir string) string {
+	return filepath.Join(logDir, "state", "positions.json")
+}
+
+// loadPositionState 从文件恢复持仓状态（文件不存在时保持空状态）
+func (at *AutoTrader) loadPositionState() {
+	data, err := os.ReadFile(at.stateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("⚠ [%s] 读取持仓状态失败: %v", at.name, err)
+		}
+		return
+	}
+
+	var state positionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Printf("⚠ [%s] 解析持仓状态失败: %v", at.name, err)
+		return
+	}
+
+	for key, t := range state.FirstSeenTime {
+		at.positionFirstSeenTime[key] = t
+	}
+	for key, plan := range state.ExitPlans {
+		at.positionExitPlans[key] = plan
+	}
+	for key, peak := range state.TrailingPeaks {
+		at.trailingPeaks[key] = peak
+	}
+	for symbol, t := range state.LastCloseTimes {
+		at.lastCloseTimes[symbol] = t
+	}
+	for key, entry := range state.PendingEntries {
+		at.pendingEntries[key] = entry
+	}
+	if len(state.ExitPlans) > 0 {
+		log.Printf("📂 [%s] 已恢复%d个持仓的退出计划", at.name, len(state.ExitPlans))
+	}
+}
+
+// savePositionState 保存持仓状态（开仓、平仓、止损调整后调用）
+func (at *AutoTrader) savePositionState() {
+	if err := at.writePositionState(); err != nil {
+		log.Printf("⚠ [%s] 保存持仓状态失败: %v", at.name, err)
+	}
+}
+
+func (at *AutoTrader) writePositionState() error {
+	data, err := json.MarshalIndent(positionState{
+		FirstSeenTime:  at.positionFirstSeenTime,
+		ExitPlans:      at.positionExitPlans,
+		TrailingPeaks:  at.trailingPeaks,
+		LastCloseTimes: at.lastCloseTimes,
+		PendingEntries: at.pendingEntries,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(at.stateFile), 0755); err != nil {
+		return fmt.Errorf("创建状态目录失败: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免写入中断导致文件损坏
+	tmp := at.stateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, at.stateFile)
+}
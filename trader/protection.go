@@ -0,0 +1,221 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"strings"
+	"time"
+)
+
+// protectionGracePeriod 开仓后的保护期：交易器的持仓查询可能有缓存，
+// 期间查不到持仓不视为已平仓，避免误撤新持仓的止损止盈单
+const protectionGracePeriod = time.Minute
+
+// ocoProtector 支持交易所原生OCO止损止盈的交易器（OKX）：两单一起提交，一个触发后交易所自动撤销另一个
+type ocoProtector interface {
+	// SetProtection 同时设置止损和止盈（价格为0的一项不设置）
+	SetProtection(symbol string, positionSide string, quantity, stopLoss, takeProfit float64) error
+}
+
+// placeProtection 在交易所挂只减仓的止损止盈单（支持OCO的交易器一次提交），以及交易所移动止损单。
+// 止损单重试一次仍失败时返回错误；止盈单失败只记录日志
+func (at *AutoTrader) placeProtection(symbol, side string, quantity float64, plan *decision.PositionInfo) error {
+	if err := at.placeStopAndTarget(symbol, side, quantity, plan); err != nil {
+		return err
+	}
+	if plan.TrailingOnExchange {
+		at.placeExchangeTrailingStop(symbol, side, quantity, plan)
+	}
+	return nil
+}
+
+// placeStopAndTarget 挂止损止盈单
+func (at *AutoTrader) placeStopAndTarget(symbol, side string, quantity float64, plan *decision.PositionInfo) error {
+	positionSide := strings.ToUpper(side)
+	if op, ok := at.trader.(ocoProtector); ok && plan.StopLoss > 0 && plan.TakeProfit > 0 {
+		err := op.SetProtection(symbol, positionSide, quantity, plan.StopLoss, plan.TakeProfit)
+		if err == nil {
+			return nil
+		}
+		log.Printf("  ⚠ OCO止损止盈下单失败，改为分别下单: %v", err)
+	}
+
+	if plan.StopLoss > 0 {
+		if err := at.trader.SetStopLoss(symbol, positionSide, quantity, plan.StopLoss); err != nil {
+			log.Printf("  ⚠ 设置止损失败，重试: %v", err)
+			if err := at.trader.SetStopLoss(symbol, positionSide, quantity, plan.StopLoss); err != nil {
+				return fmt.Errorf("止损单 @ %.4f 下单失败: %w", plan.StopLoss, err)
+			}
+		}
+	}
+	if plan.TakeProfit > 0 {
+		if err := at.trader.SetTakeProfit(symbol, positionSide, quantity, plan.TakeProfit); err != nil {
+			log.Printf("  ⚠ 设置止盈失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// protectNewPosition 新开仓位立即挂止损止盈，止损单挂不上时市价平仓（不留下没有止损的持仓）
+func (at *AutoTrader) protectNewPosition(symbol, side string, quantity float64, plan *decision.PositionInfo) error {
+	at.chooseTrailingMode(plan)
+	at.savePositionState()
+	if err := at.placeProtection(symbol, side, quantity, plan); err != nil {
+		return at.emergencyClose(symbol, side, err)
+	}
+	return nil
+}
+
+// emergencyClose 止损单挂不上时市价平仓，返回包含原因的错误
+func (at *AutoTrader) emergencyClose(symbol, side string, err error) error {
+	log.Printf("  ❌ %s %s 止损单下单失败，市价平仓: %v", symbol, side, err)
+	if closeErr := at.closeTrailingPosition(symbol, side); closeErr != nil {
+		return fmt.Errorf("%v；市价平仓也失败，持仓没有止损保护: %w", err, closeErr)
+	}
+	return fmt.Errorf("%v；已市价平仓", err)
+}
+
+// resizeProtection 持仓数量变化后（分批成交、部分平仓）按新的数量重新挂止损止盈单
+func (at *AutoTrader) resizeProtection(symbol, side string, quantity float64, plan *decision.PositionInfo) error {
+	return at.replaceProtection(symbol, side, quantity, plan, nil)
+}
+
+// replaceProtection 撤销旧止损止盈单后重新挂单（下单接口不返回订单ID，无法先挂新单再按ID撤旧单）。
+// 撤单失败时保留旧挂单；新止损单挂不上时恢复previous的止损价，仍失败则市价平仓，不留下没有止损的持仓
+func (at *AutoTrader) replaceProtection(symbol, side string, quantity float64, plan, previous *decision.PositionInfo) error {
+	if err := at.cancelProtectiveOrders(symbol, side); err != nil {
+		return fmt.Errorf("取消旧止损止盈单失败: %w", err)
+	}
+	err := at.placeProtection(symbol, side, quantity, plan)
+	if err == nil {
+		return nil
+	}
+	if previous != nil && previous.StopLoss > 0 && previous.StopLoss != plan.StopLoss {
+		log.Printf("  ⚠ %s %s 新止损单下单失败，恢复原止损 %.4f: %v", symbol, side, previous.StopLoss, err)
+		if restoreErr := at.placeProtection(symbol, side, quantity, previous); restoreErr == nil {
+			return fmt.Errorf("止损未更新，已恢复原止损 %.4f: %w", previous.StopLoss, err)
+		}
+	}
+	return at.emergencyClose(symbol, side, err)
+}
+
+// restoreProtection 启动时为已有持仓重新挂止损止盈（上次运行可能在开仓后、挂单前中断）
+func (at *AutoTrader) restoreProtection() {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠ [%s] 恢复止损止盈时获取持仓失败: %v", at.name, err)
+		return
+	}
+
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		quantity, _ := pos["positionAmt"].(float64)
+		quantity = math.Abs(quantity)
+		if quantity == 0 {
+			continue
+		}
+
+		plan, exists := at.positionExitPlans[symbol+"_"+side]
+		if !exists {
+			log.Printf("⚠ [%s] %s %s 没有保存的退出计划，无法恢复止损止盈，请手动检查", at.name, symbol, side)
+			continue
+		}
+		if err := at.cancelProtectiveOrders(symbol, side); err != nil {
+			log.Printf("⚠ [%s] 撤销 %s %s 旧止损止盈单失败: %v", at.name, symbol, side, err)
+			continue
+		}
+		if err := at.placeProtection(symbol, side, quantity, plan); err != nil {
+			log.Printf("❌ [%s] %s %s 恢复止损失败，持仓没有止损保护: %v", at.name, symbol, side, err)
+			continue
+		}
+		log.Printf("🛡 [%s] %s %s 已恢复止损止盈: 止损 %.4f, 止盈 %.4f", at.name, symbol, side, plan.StopLoss, plan.TakeProfit)
+	}
+}
+
+// linkProtectiveOrders 止损止盈联动：持仓在交易所被其中一单平掉后，撤销剩下的另一单
+// （不支持原生OCO的交易所两单互相独立，残留的只减仓单会平掉之后的新持仓）
+func (at *AutoTrader) linkProtectiveOrders() {
+	if len(at.positionExitPlans) == 0 {
+		return
+	}
+
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠ 止损止盈联动检查时获取持仓失败: %v", err)
+		return
+	}
+	open := make(map[string]bool)
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		open[symbol+"_"+side] = true
+	}
+	if at.releaseClosedPositions(open) {
+		at.savePositionState()
+	}
+}
+
+// releaseClosedPositions 处理已不在持仓列表中的记录（保护期内的除外）：
+// 撤销残留的止损止盈单并清理记录，返回是否有变化
+func (at *AutoTrader) releaseClosedPositions(open map[string]bool) bool {
+	cutoff := time.Now().Add(-protectionGracePeriod).UnixMilli()
+	changed := false
+	for posKey, firstSeen := range at.positionFirstSeenTime {
+		if open[posKey] || firstSeen > cutoff {
+			continue
+		}
+		if _, protected := at.positionExitPlans[posKey]; protected {
+			symbol, side := splitPositionKey(posKey)
+			if at.otherSideTracked(symbol, side) {
+				// 单向持仓模式按币种撤单，会撤掉另一方向新持仓的止损止盈单
+				log.Printf("🔗 %s %s 已在交易所平仓，同币种另一方向仍有持仓，单向持仓模式下跳过撤单", symbol, side)
+			} else {
+				log.Printf("🔗 %s %s 已在交易所平仓，撤销剩余的止损止盈单", symbol, side)
+				if err := at.cancelProtectiveOrders(symbol, side); err != nil {
+					log.Printf("⚠ 撤销 %s %s 剩余止损止盈单失败: %v", symbol, side, err)
+					continue
+				}
+			}
+		}
+		at.forgetClosedPosition(posKey)
+		changed = true
+	}
+	return changed
+}
+
+// otherSideTracked 单向持仓模式下同币种另一方向是否有持仓或限价开仓单（双向持仓模式按方向撤单，不受影响）
+func (at *AutoTrader) otherSideTracked(symbol, side string) bool {
+	if at.config.HedgeMode {
+		return false
+	}
+	other := symbol + "_long"
+	if side == "long" {
+		other = symbol + "_short"
+	}
+	_, open := at.positionFirstSeenTime[other]
+	_, pending := at.pendingEntries[other]
+	return open || pending
+}
+
+// forgetClosedPosition 清理已平仓持仓的记录：止损/止盈等在交易所触发的平仓也计入冷却（主动平仓时已记录）
+func (at *AutoTrader) forgetClosedPosition(posKey string) {
+	symbol, _ := splitPositionKey(posKey)
+	if at.lastCloseTimes[symbol] < at.positionFirstSeenTime[posKey] {
+		at.lastCloseTimes[symbol] = time.Now().UnixMilli()
+	}
+	delete(at.positionFirstSeenTime, posKey)
+	delete(at.positionExitPlans, posKey)
+	delete(at.trailingPeaks, posKey)
+}
+
+// splitPositionKey 拆分 symbol_side 格式的持仓键
+func splitPositionKey(posKey string) (symbol, side string) {
+	sep := strings.LastIndex(posKey, "_")
+	if sep < 0 {
+		return posKey, ""
+	}
+	return posKey[:sep], posKey[sep+1:]
+}
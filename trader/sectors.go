@@ -0,0 +1,22 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/pool"
+)
+
+// symbolSectors 持仓和候选币种所属板块（未收录板块的币种不在结果中）
+func symbolSectors(positions []decision.PositionInfo, candidates []decision.CandidateCoin) map[string]string {
+	sectors := make(map[string]string)
+	for _, pos := range positions {
+		if sector := pool.Sector(pos.Symbol); sector != "" {
+			sectors[pos.Symbol] = sector
+		}
+	}
+	for _, coin := range candidates {
+		if sector := pool.Sector(coin.Symbol); sector != "" {
+			sectors[coin.Symbol] = sector
+		}
+	}
+	return sectors
+}
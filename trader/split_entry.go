@@ -0,0 +1,148 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"time"
+)
+
+// 拆单默认参数
+const (
+	defaultSliceInterval = 30 * time.Second // 两笔之间的间隔
+	defaultMaxSlices     = 5                // 最多拆分笔数
+	sliceVolumeMinutes   = 15               // 计算平均1分钟成交额使用的K线数量
+)
+
+// entrySlices 开仓需要拆分的笔数：仓位价值超过单笔上限（平均1分钟成交额、吃单一侧±0.5%深度的配置比例，取较小值）时
+// 按上限拆分，最多MaxSlices笔。未配置比例或获取行情失败时不拆单
+func (at *AutoTrader) entrySlices(symbol, side string, positionSizeUSD float64) int {
+	opts := at.config.EntryExecution
+	limit := math.Inf(1)
+	if opts.MaxVolumeFraction > 0 {
+		if volume, err := market.GetMinuteVolumeUSD(at.runCtx, symbol, sliceVolumeMinutes); err != nil {
+			log.Printf("  ⚠ 获取 %s 1分钟成交额失败: %v", symbol, err)
+		} else if volume > 0 {
+			limit = math.Min(limit, volume*opts.MaxVolumeFraction)
+		}
+	}
+	if opts.MaxDepthFraction > 0 {
+		if depth, err := market.GetDepthContext(at.runCtx, symbol); err != nil {
+			log.Printf("  ⚠ 获取 %s 盘口深度失败: %v", symbol, err)
+		} else {
+			// 多单吃卖盘，空单吃买盘
+			book := depth.AskDepthUSD
+			if side == "short" {
+				book = depth.BidDepthUSD
+			}
+			if book > 0 {
+				limit = math.Min(limit, book*opts.MaxDepthFraction)
+			}
+		}
+	}
+	if math.IsInf(limit, 1) || positionSizeUSD <= limit {
+		return 1
+	}
+
+	maxSlices := opts.MaxSlices
+	if maxSlices <= 0 {
+		maxSlices = defaultMaxSlices
+	}
+	slices := int(math.Ceil(positionSizeUSD / limit))
+	if slices > maxSlices {
+		slices = maxSlices
+	}
+	if slices > 1 {
+		log.Printf("  🧩 %s 仓位 %.0f USDT 超过单笔上限 %.0f USDT，拆分为 %d 笔", symbol, positionSizeUSD, limit, slices)
+	}
+	return slices
+}
+
+// splitEntry 分批开仓：每隔SliceInterval开一笔（剩余数量平均分到剩余笔数），第一笔成交后立即挂止损止盈，
+// 之后每笔成交按累计数量重新挂单。中途下单失败、trader停止或到执行截止时间时停止，保留已成交的部分。
+// 交易器开仓时会撤销该方向的止损止盈单，因此结束时只要有成交就按最终数量重新挂单，挂不上则市价平仓
+func (at *AutoTrader) splitEntry(symbol, side string, quantity float64, leverage, slices int, plan *decision.PositionInfo, actionRecord *logger.DecisionAction) error {
+	interval := at.config.EntryExecution.SliceInterval
+	if interval <= 0 {
+		interval = defaultSliceInterval
+	}
+	posKey := symbol + "_" + side
+	referencePrice := actionRecord.Price
+
+	filled, notional := 0.0, 0.0
+	unprotected := false // 上次挂止损止盈之后又下过开仓单
+	for i := 0; i < slices; i++ {
+		if i > 0 && !at.executionWait(interval) {
+			log.Printf("  ⏹ 第%d/%d笔前停止拆单，保留已成交的 %.4f", i+1, slices, filled)
+			break
+		}
+		sliceQty := (quantity - filled) / float64(slices-i)
+		order, err := at.openPosition(symbol, side, sliceQty, leverage, filled)
+		unprotected = unprotected || filled > 0
+		if err != nil {
+			if filled == 0 {
+				delete(at.positionFirstSeenTime, posKey)
+				delete(at.positionExitPlans, posKey)
+				at.savePositionState()
+				return err
+			}
+			log.Printf("  ⚠ 第%d/%d笔开仓失败，停止拆单，保留已成交的 %.4f: %v", i+1, slices, filled, err)
+			break
+		}
+		at.forgetCancelledEntries(symbol)
+
+		o := newTrackedOrder(symbol, side, sliceQty, order)
+		at.awaitMarketFill(o, filled)
+		actionRecord.OrderID = o.OrderID
+		log.Printf("  🧩 第%d/%d笔: %s", i+1, slices, o.fillSummary())
+		if o.Filled == 0 {
+			continue
+		}
+
+		price := o.AvgPrice
+		if price <= 0 {
+			price = referencePrice
+		}
+		first := filled == 0
+		filled += o.Filled
+		notional += o.Filled * price
+		plan.Quantity = filled
+		if first {
+			// 止损单挂不上时已市价平仓，不再继续
+			if err := at.protectNewPosition(symbol, side, filled, plan); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := at.resizeProtection(symbol, side, filled, plan); err != nil {
+			// 止损挂不上时已市价平仓
+			if _, open := at.positionExitPlans[posKey]; !open {
+				return err
+			}
+			log.Printf("  ❌ %v", err)
+			continue
+		}
+		unprotected = false
+		at.savePositionState()
+	}
+
+	if filled == 0 {
+		delete(at.positionFirstSeenTime, posKey)
+		delete(at.positionExitPlans, posKey)
+		at.savePositionState()
+		return fmt.Errorf("split entry: none of %d slices filled", slices)
+	}
+	if unprotected {
+		if err := at.resizeProtection(symbol, side, filled, plan); err != nil {
+			return err
+		}
+		at.savePositionState()
+	}
+	actionRecord.Quantity = filled
+	actionRecord.Price = notional / filled
+	log.Printf("  ✓ 分%d笔开仓完成: %.4f / %.4f @ %.4f", slices, filled, quantity, actionRecord.Price)
+	return nil
+}
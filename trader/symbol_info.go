@@ -0,0 +1,7 @@
+package trader
+
+// leverageBracketLoader 能加载杠杆分层的交易器（目前仅币安，接口需要API Key）
+type leverageBracketLoader interface {
+	// LoadLeverageBrackets 加载所有交易对的杠杆分层并写入market.SymbolInfo
+	LoadLeverageBrackets() error
+}
@@ -0,0 +1,248 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/decision"
+	"nofx/market"
+	"strings"
+	"time"
+)
+
+// trailingStopCheckInterval 移动止损检查间隔（远短于AI决策周期）
+const trailingStopCheckInterval = 15 * time.Second
+
+// 规则移动止损的默认参数
+const (
+	defaultTrailingActivationR = 2.0
+	defaultTrailingATRInterval = market.Interval1h
+	trailingATRPeriod          = 14
+)
+
+// TrailingStopOptions 移动止损管理参数
+type TrailingStopOptions struct {
+	OnExchange  bool            // AI设置的移动止损使用交易所原生移动止损单（交易器不支持或下单失败时本地跟踪）
+	ActivationR float64         // 规则跟踪：浮盈达到N倍初始风险后激活（0使用默认值2）
+	ATRMultiple float64         // 规则跟踪：止损与最优价的距离为N倍ATR（0表示不启用规则）
+	ATRInterval market.Interval // 规则跟踪的ATR周期（空使用默认值1h）
+}
+
+// trailingStopPlacer 支持交易所原生移动止损单的交易器（币安）
+type trailingStopPlacer interface {
+	// SetTrailingStop 价格到达activationPrice后按callbackRate%回调触发，市价平仓
+	SetTrailingStop(symbol string, positionSide string, quantity, activationPrice, callbackRate float64) error
+}
+
+// chooseTrailingMode 新开仓位选择移动止损的执行方式：
+// AI设置了回调比例且配置为exchange时交给交易所；没有设置时按配置规则（盈利N倍R后按ATR跟踪）本地维护
+func (at *AutoTrader) chooseTrailingMode(plan *decision.PositionInfo) {
+	if plan.TrailingCallbackRate > 0 {
+		_, ok := at.trader.(trailingStopPlacer)
+		plan.TrailingOnExchange = ok && at.config.TrailingStop.OnExchange
+		return
+	}
+	if at.config.TrailingStop.ATRMultiple > 0 && plan.StopLoss > 0 {
+		plan.TrailingATRMultiple = at.config.TrailingStop.ATRMultiple
+	}
+}
+
+// placeExchangeTrailingStop 挂交易所移动止损单，失败时改为本地跟踪
+func (at *AutoTrader) placeExchangeTrailingStop(symbol, side string, quantity float64, plan *decision.PositionInfo) {
+	tp, ok := at.trader.(trailingStopPlacer)
+	if ok {
+		err := tp.SetTrailingStop(symbol, strings.ToUpper(side), quantity, plan.TrailingActivation, plan.TrailingCallbackRate)
+		if err == nil {
+			return
+		}
+		log.Printf("  ⚠ 交易所移动止损单下单失败，改为本地跟踪: %v", err)
+	}
+	plan.TrailingOnExchange = false
+	at.savePositionState()
+}
+
+// updateTrailingStops 维护本地跟踪的移动止损：
+// 价格到达激活价后跟踪最优价格，按回调比例（AI设置）或ATR倍数（配置规则）上移（空仓下移）止损，止损只收紧不放宽
+func (at *AutoTrader) updateTrailingStops() {
+	var entryPrices map[string]float64 // 规则跟踪首次计算激活价时才获取持仓
+	for posKey, plan := range at.positionExitPlans {
+		if plan.TrailingOnExchange || (plan.TrailingCallbackRate <= 0 && plan.TrailingATRMultiple <= 0) {
+			continue
+		}
+		symbol, side := splitPositionKey(posKey)
+
+		// 规则跟踪的激活价：入场价 ± activation_r × 初始风险（入场价到止损价的距离）
+		if plan.TrailingCallbackRate <= 0 && plan.TrailingActivation <= 0 {
+			if entryPrices == nil {
+				entryPrices = at.entryPrices()
+			}
+			entry, ok := entryPrices[posKey]
+			if !ok {
+				continue
+			}
+			plan.TrailingActivation = at.ruleActivation(side, entry, plan.StopLoss)
+			at.savePositionState()
+			log.Printf("🎯 %s %s 规则移动止损: 浮盈达到 %.4f 后按 %g×ATR 跟踪", symbol, side, plan.TrailingActivation, plan.TrailingATRMultiple)
+		}
+
+		price, err := at.trader.GetMarketPrice(symbol)
+		if err != nil {
+			log.Printf("⚠ 移动止损获取价格失败 (%s): %v", symbol, err)
+			continue
+		}
+
+		peak, activated := at.trailingPeaks[posKey]
+		if !activated {
+			if (side == "long" && price < plan.TrailingActivation) || (side == "short" && price > plan.TrailingActivation) {
+				continue
+			}
+			peak = price
+			log.Printf("🎯 %s %s 移动止损已激活: 价格 %.4f, %s", symbol, side, price, trailingDescription(plan))
+		}
+
+		// 更新最优价格并计算新的止损价
+		var distance float64
+		if side == "long" {
+			peak = math.Max(peak, price)
+		} else {
+			peak = math.Min(peak, price)
+		}
+		if plan.TrailingCallbackRate > 0 {
+			distance = peak * plan.TrailingCallbackRate / 100
+		} else {
+			atr, err := market.GetATR(symbol, at.ruleATRInterval(), trailingATRPeriod)
+			if err != nil {
+				log.Printf("⚠ 移动止损获取ATR失败 (%s): %v", symbol, err)
+				continue
+			}
+			distance = atr * plan.TrailingATRMultiple
+		}
+
+		var newStop float64
+		var triggered, improved bool
+		if side == "long" {
+			newStop = peak - distance
+			triggered = price <= newStop
+			improved = newStop > plan.StopLoss
+		} else {
+			newStop = peak + distance
+			triggered = price >= newStop
+			improved = plan.StopLoss <= 0 || newStop < plan.StopLoss
+		}
+		at.trailingPeaks[posKey] = peak
+		if !activated {
+			at.savePositionState()
+		}
+
+		// 价格已回撤到止损价之外，交易所会拒绝止损单，直接市价平仓
+		if triggered {
+			log.Printf("🎯 %s %s 移动止损触发: 价格 %.4f, 最优价 %.4f", symbol, side, price, peak)
+			if err := at.closeTrailingPosition(symbol, side); err != nil {
+				log.Printf("❌ 移动止损平仓失败 (%s %s): %v", symbol, side, err)
+			}
+			continue
+		}
+
+		if !improved {
+			continue
+		}
+		if err := at.moveStopLoss(symbol, side, plan, newStop); err != nil {
+			log.Printf("⚠ 移动止损更新失败 (%s %s): %v", symbol, side, err)
+			continue
+		}
+		log.Printf("🎯 %s %s 止损收紧: %.4f → %.4f (最优价 %.4f)", symbol, side, plan.StopLoss, newStop, peak)
+		plan.StopLoss = newStop
+		at.savePositionState()
+	}
+}
+
+// entryPrices 当前持仓的入场价（symbol_side -> price）
+func (at *AutoTrader) entryPrices() map[string]float64 {
+	prices := make(map[string]float64)
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		log.Printf("⚠ 移动止损获取持仓失败: %v", err)
+		return prices
+	}
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		if entry, _ := pos["entryPrice"].(float64); entry > 0 {
+			prices[symbol+"_"+side] = entry
+		}
+	}
+	return prices
+}
+
+// ruleActivation 规则跟踪的激活价（多仓在入场价上方，空仓在下方）
+func (at *AutoTrader) ruleActivation(side string, entry, stopLoss float64) float64 {
+	r := at.config.TrailingStop.ActivationR
+	if r <= 0 {
+		r = defaultTrailingActivationR
+	}
+	risk := math.Abs(entry - stopLoss)
+	if side == "short" {
+		return entry - risk*r
+	}
+	return entry + risk*r
+}
+
+// ruleATRInterval 规则跟踪使用的ATR周期
+func (at *AutoTrader) ruleATRInterval() market.Interval {
+	if at.config.TrailingStop.ATRInterval != "" {
+		return at.config.TrailingStop.ATRInterval
+	}
+	return defaultTrailingATRInterval
+}
+
+// trailingDescription 移动止损距离的日志描述
+func trailingDescription(plan *decision.PositionInfo) string {
+	if plan.TrailingCallbackRate > 0 {
+		return fmt.Sprintf("回调 %.2f%%", plan.TrailingCallbackRate)
+	}
+	return fmt.Sprintf("距离 %g×ATR", plan.TrailingATRMultiple)
+}
+
+// moveStopLoss 按当前持仓数量重新挂止损单（同时恢复止盈单），新止损挂不上时恢复原止损或市价平仓
+func (at *AutoTrader) moveStopLoss(symbol, side string, plan *decision.PositionInfo, stopPrice float64) error {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return err
+	}
+	quantity := 0.0
+	for _, pos := range positions {
+		if pos["symbol"] == symbol && pos["side"] == side {
+			quantity = math.Abs(pos["positionAmt"].(float64))
+			break
+		}
+	}
+	if quantity == 0 {
+		return fmt.Errorf("没有找到 %s 的%s仓", symbol, side)
+	}
+
+	moved := *plan
+	moved.StopLoss = stopPrice
+	return at.replaceProtection(symbol, side, quantity, &moved, plan)
+}
+
+// closeTrailingPosition 移动止损触发后市价全部平仓
+func (at *AutoTrader) closeTrailingPosition(symbol, side string) error {
+	var err error
+	if side == "long" {
+		_, err = at.trader.CloseLong(symbol, 0)
+	} else {
+		_, err = at.trader.CloseShort(symbol, 0)
+	}
+	if err != nil {
+		return err
+	}
+	at.forgetCancelledEntries(symbol)
+	// 立即落盘，否则重启后会恢复已平仓持仓的移动止损计划
+	posKey := symbol + "_" + side
+	delete(at.positionExitPlans, posKey)
+	delete(at.trailingPeaks, posKey)
+	delete(at.positionFirstSeenTime, posKey)
+	at.savePositionState()
+	at.recordClose(symbol)
+	return nil
+}
@@ -0,0 +1,195 @@
+package whale
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认设置
+const (
+	defaultMaxTransfers = 3           // 每个币种展示的转账笔数
+	window              = time.Hour   // 统计最近1小时的转账
+	cacheTTL            = time.Minute // 全市场转账缓存时长（免费API每分钟限10次）
+)
+
+// 转账方向（相对交易所）
+const (
+	DirectionInflow  = "inflow"  // 从非交易所地址转入交易所（潜在卖压）
+	DirectionOutflow = "outflow" // 从交易所转出到非交易所地址（提币囤币）
+	DirectionOther   = "other"   // 交易所之间或钱包之间的转账
+)
+
+// Transfer 大额链上转账
+type Transfer struct {
+	Coin      string    `json:"coin"` // 币种代码（如 BTC）
+	Amount    float64   `json:"amount"`
+	AmountUSD float64   `json:"amount_usd"`
+	From      string    `json:"from"` // 发送方（交易所名称，未知钱包为 "unknown"）
+	To        string    `json:"to"`   // 接收方
+	Direction string    `json:"direction"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SymbolFlow 单个币种最近1小时的大额转账
+type SymbolFlow struct {
+	Symbol     string     `json:"symbol"`
+	Transfers  []Transfer `json:"transfers"`   // 交易所流入/流出中金额最大的几笔（新→旧）
+	InflowUSD  float64    `json:"inflow_usd"`  // 流入交易所总额
+	OutflowUSD float64    `json:"outflow_usd"` // 流出交易所总额
+	Inflows    int        `json:"inflows"`
+	Outflows   int        `json:"outflows"`
+	FetchedAt  time.Time  `json:"fetched_at"` // 获取时间（计算转账距今时长，回放时保持一致）
+}
+
+// Provider 大额转账数据源，返回since之后所有币种的大额转账
+type Provider interface {
+	Name() string
+	Transfers(since time.Time) ([]Transfer, error)
+}
+
+var (
+	mu           sync.Mutex
+	provider     Provider
+	maxTransfers = defaultMaxTransfers
+	cached       []Transfer
+	cachedAt     time.Time
+)
+
+// SetProvider 设置大额转账数据源（nil表示禁用），transfers为0时使用默认值
+func SetProvider(p Provider, transfers int) {
+	mu.Lock()
+	defer mu.Unlock()
+	provider = p
+	maxTransfers = defaultMaxTransfers
+	if transfers > 0 {
+		maxTransfers = transfers
+	}
+	cached, cachedAt = nil, time.Time{}
+}
+
+// Enabled 是否配置了大额转账数据源
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return provider != nil
+}
+
+// Get 获取币种（BTCUSDT格式）最近1小时的交易所流入/流出（未配置数据源时返回nil，没有相关转账的币种不在结果中）
+func Get(symbols []string) (map[string]*SymbolFlow, error) {
+	mu.Lock()
+	p := provider
+	limit := maxTransfers
+	now := time.Now()
+	transfers := cached
+	fresh := now.Sub(cachedAt) < cacheTTL
+	mu.Unlock()
+
+	if p == nil {
+		return nil, nil
+	}
+
+	if !fresh {
+		fetched, err := p.Transfers(now.Add(-window))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Name(), err)
+		}
+		transfers = fetched
+		mu.Lock()
+		cached, cachedAt = fetched, now
+		mu.Unlock()
+	}
+
+	codeToSymbol := make(map[string]string, len(symbols))
+	for _, symbol := range symbols {
+		codeToSymbol[strings.TrimSuffix(strings.ToUpper(symbol), "USDT")] = symbol
+	}
+
+	bySymbol := make(map[string][]Transfer)
+	for _, t := range transfers {
+		if symbol, ok := codeToSymbol[strings.ToUpper(t.Coin)]; ok && now.Sub(t.Timestamp) < window {
+			bySymbol[symbol] = append(bySymbol[symbol], t)
+		}
+	}
+
+	result := make(map[string]*SymbolFlow)
+	for symbol, items := range bySymbol {
+		if flow := summarize(symbol, items, limit, now); flow != nil {
+			result[symbol] = flow
+		}
+	}
+	return result, nil
+}
+
+// summarize 汇总交易所流入/流出并保留金额最大的limit笔（没有流入/流出时返回nil）
+func summarize(symbol string, items []Transfer, limit int, now time.Time) *SymbolFlow {
+	flow := &SymbolFlow{Symbol: symbol, FetchedAt: now}
+	var notable []Transfer
+	for _, t := range items {
+		switch t.Direction {
+		case DirectionInflow:
+			flow.InflowUSD += t.AmountUSD
+			flow.Inflows++
+		case DirectionOutflow:
+			flow.OutflowUSD += t.AmountUSD
+			flow.Outflows++
+		default:
+			continue
+		}
+		notable = append(notable, t)
+	}
+	if len(notable) == 0 {
+		return nil
+	}
+
+	sort.Slice(notable, func(i, j int) bool { return notable[i].AmountUSD > notable[j].AmountUSD })
+	if len(notable) > limit {
+		notable = notable[:limit]
+	}
+	sort.Slice(notable, func(i, j int) bool { return notable[i].Timestamp.After(notable[j].Timestamp) })
+	flow.Transfers = notable
+	return flow
+}
+
+// Format 大额转账描述（交易所净流入通常意味着潜在卖压，净流出意味着囤币）
+func Format(f *SymbolFlow) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Whale transfers (last %s): exchange inflow %s (%d) | outflow %s (%d) | net %s\n",
+		formatWindow(window), formatUSD(f.InflowUSD), f.Inflows, formatUSD(f.OutflowUSD), f.Outflows, formatSignedUSD(f.InflowUSD-f.OutflowUSD)))
+	for _, t := range f.Transfers {
+		sb.WriteString(fmt.Sprintf("- %dm ago: %.0f %s (%s) %s → %s [%s]\n",
+			int(f.FetchedAt.Sub(t.Timestamp).Minutes()), t.Amount, strings.ToUpper(t.Coin), formatUSD(t.AmountUSD), t.From, t.To, t.Direction))
+	}
+	return sb.String()
+}
+
+// formatWindow 统计窗口简写
+func formatWindow(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dm", int(d.Minutes()))
+}
+
+// formatUSD 金额简写（$1.2M）
+func formatUSD(v float64) string {
+	switch {
+	case v >= 1e9:
+		return fmt.Sprintf("$%.2fB", v/1e9)
+	case v >= 1e6:
+		return fmt.Sprintf("$%.1fM", v/1e6)
+	case v >= 1e3:
+		return fmt.Sprintf("$%.0fK", v/1e3)
+	}
+	return fmt.Sprintf("$%.0f", v)
+}
+
+// formatSignedUSD 带正负号的金额简写（正值为净流入）
+func formatSignedUSD(v float64) string {
+	if v < 0 {
+		return "-" + formatUSD(-v)
+	}
+	return "+" + formatUSD(v)
+}
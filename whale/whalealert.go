@@ -0,0 +1,119 @@
+package whale
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Whale Alert API
+const (
+	whaleAlertURL      = "https://api.whale-alert.io/v1/transactions"
+	whaleAlertTimeout  = 10 * time.Second
+	whaleAlertMinValue = 500000 // 免费账户允许的最小金额（USD）
+	whaleAlertPageSize = 100
+)
+
+// WhaleAlert Whale Alert大额转账数据源（需要API Key，免费账户只能查询最近1小时）
+type WhaleAlert struct {
+	apiKey   string
+	minValue int
+	client   *http.Client
+}
+
+// NewWhaleAlert 创建Whale Alert数据源，minValueUSD为0或低于免费账户下限时使用下限
+func NewWhaleAlert(apiKey string, minValueUSD int) *WhaleAlert {
+	if minValueUSD < whaleAlertMinValue {
+		minValueUSD = whaleAlertMinValue
+	}
+	return &WhaleAlert{
+		apiKey:   apiKey,
+		minValue: minValueUSD,
+		client:   &http.Client{Timeout: whaleAlertTimeout},
+	}
+}
+
+// Name 数据源名称
+func (w *WhaleAlert) Name() string {
+	return "whale_alert"
+}
+
+// Transfers 一次请求获取since之后所有币种的大额转账（只取第一页，金额门槛足够高时一小时内不会超过100笔）
+func (w *WhaleAlert) Transfers(since time.Time) ([]Transfer, error) {
+	params := url.Values{}
+	params.Set("api_key", w.apiKey)
+	params.Set("min_value", strconv.Itoa(w.minValue))
+	params.Set("start", strconv.FormatInt(since.Unix(), 10))
+	params.Set("limit", strconv.Itoa(whaleAlertPageSize))
+
+	resp, err := w.client.Get(whaleAlertURL + "?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("请求失败 (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+
+	type party struct {
+		Owner     string `json:"owner"`
+		OwnerType string `json:"owner_type"`
+	}
+	var result struct {
+		Result       string `json:"result"`
+		Message      string `json:"message"`
+		Transactions []struct {
+			Symbol    string  `json:"symbol"`
+			Timestamp int64   `json:"timestamp"`
+			Amount    float64 `json:"amount"`
+			AmountUSD float64 `json:"amount_usd"`
+			From      party   `json:"from"`
+			To        party   `json:"to"`
+		} `json:"transactions"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if result.Result != "success" {
+		return nil, fmt.Errorf("请求失败: %s", result.Message)
+	}
+
+	name := func(p party) string {
+		if p.Owner == "" {
+			return "unknown"
+		}
+		return p.Owner
+	}
+
+	transfers := make([]Transfer, 0, len(result.Transactions))
+	for _, tx := range result.Transactions {
+		fromExchange := tx.From.OwnerType == "exchange"
+		toExchange := tx.To.OwnerType == "exchange"
+		direction := DirectionOther
+		switch {
+		case toExchange && !fromExchange:
+			direction = DirectionInflow
+		case fromExchange && !toExchange:
+			direction = DirectionOutflow
+		}
+		transfers = append(transfers, Transfer{
+			Coin:      tx.Symbol,
+			Amount:    tx.Amount,
+			AmountUSD: tx.AmountUSD,
+			From:      name(tx.From),
+			To:        name(tx.To),
+			Direction: direction,
+			Timestamp: time.Unix(tx.Timestamp, 0),
+		})
+	}
+	return transfers, nil
+}